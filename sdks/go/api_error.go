@@ -0,0 +1,111 @@
+package torm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBody caps the response body kept on an APIError
+const maxErrorBody = 64 << 10
+
+// APIError is returned when the server answered a request with a failure
+// status. Callers branch on StatusCode with errors.As; Message and Details
+// come from a JSON error body such as {"error": "...", "field": "email"}.
+type APIError struct {
+	Operation  string // The SDK operation, such as "create", when known
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+	Message    string                 // The server's error message, if it sent one
+	Details    map[string]interface{} // The other fields of a JSON error body
+	RawBody    []byte                 // The response body, capped at 64 KiB
+	Attempts   []AttemptInfo          // Oldest first, capped by RetryOptions.MaxHistory
+}
+
+func (e *APIError) Error() string {
+	op := e.Operation
+	if op == "" {
+		op = e.Method + " " + e.Path
+	}
+	msg := fmt.Sprintf("%s failed with status %d%s", op, e.StatusCode, summarizeAttempts(e.Attempts))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap marks 429 and 503 as ErrOverloaded
+func (e *APIError) Unwrap() error {
+	if e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable {
+		return ErrOverloaded
+	}
+	return nil
+}
+
+// responseError builds the APIError for a failed response, reading what is
+// left of its body
+func responseError(operation string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return readResponseError(operation, resp, body)
+}
+
+// readResponseError is responseError for a body the caller already read
+func readResponseError(operation string, resp *http.Response, body []byte) *APIError {
+	e := newAPIError(operation, resp.StatusCode, resp.Status, body)
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.Path = resp.Request.URL.Path
+	}
+	return e
+}
+
+// apiError builds the APIError for a failed buffered response
+func (r *bufferedResponse) apiError(operation string) *APIError {
+	e := newAPIError(operation, r.statusCode, r.status, r.body)
+	e.Method, e.Path = r.method, r.path
+	return e
+}
+
+func newAPIError(operation string, status int, statusText string, body []byte) *APIError {
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	e := &APIError{Operation: operation, StatusCode: status, Status: statusText}
+	if len(body) == 0 {
+		return e
+	}
+	e.RawBody = append([]byte(nil), body...)
+
+	var detail map[string]interface{}
+	if err := json.Unmarshal(body, &detail); err != nil {
+		e.Message = strings.TrimSpace(string(body))
+		if len(e.Message) > 512 {
+			e.Message = e.Message[:512] + "..."
+		}
+		return e
+	}
+	// Some servers nest the error: {"error": {"message": "...", "code": ...}}
+	if nested, ok := detail["error"].(map[string]interface{}); ok {
+		delete(detail, "error")
+		for key, value := range nested {
+			if _, taken := detail[key]; !taken {
+				detail[key] = value
+			}
+		}
+	}
+	for _, key := range []string{"error", "message"} {
+		if msg, ok := detail[key].(string); ok && msg != "" {
+			e.Message = msg
+			delete(detail, key)
+			break
+		}
+	}
+	if len(detail) > 0 {
+		e.Details = detail
+	}
+	return e
+}
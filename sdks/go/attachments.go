@@ -0,0 +1,153 @@
+package torm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"time"
+)
+
+// GetAttachmentURL returns a signed download URL for a file-like field and
+// the time it expires. The expiry is zero when the server does not report one.
+func (c *Collection[T]) GetAttachmentURL(id, field string) (_ string, _ time.Time, err error) {
+	timer := c.timeOp(OpFindByID).target(id).as("GetAttachmentURL")
+	defer timer.end(&err)
+
+	if err := c.checkAttachments(); err != nil {
+		return "", time.Time{}, err
+	}
+
+	resp, err := c.client.request("GET", c.attachmentPath(id, field)+"/url", nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, c.attachmentError("get attachment url", resp)
+	}
+	c.client.capabilities.record(CapabilityAttachments, true)
+
+	var result struct {
+		URL       string `json:"url"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode attachment url: %w", err)
+	}
+
+	var expiresAt time.Time
+	if result.ExpiresAt != "" {
+		expiresAt, err = time.Parse(time.RFC3339, result.ExpiresAt)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("invalid attachment expiry %q: %w", result.ExpiresAt, err)
+		}
+	}
+
+	return result.URL, expiresAt, nil
+}
+
+// UploadAttachment streams r to a file-like field as multipart data. The
+// content is never buffered in full, so arbitrarily large readers are fine.
+func (c *Collection[T]) UploadAttachment(id, field string, r io.Reader, contentType string) (err error) {
+	timer := c.timeOp(OpUpdate).target(id).as("UploadAttachment")
+	defer timer.end(&err)
+
+	if err := c.checkAttachments(); err != nil {
+		return err
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	pr, pw := io.Pipe()
+	// Unblocks the writer if the request ends before the body is consumed
+	defer pr.Close()
+
+	mw := multipart.NewWriter(pw)
+	go func() {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, field))
+		header.Set("Content-Type", contentType)
+
+		part, err := mw.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp, err := c.client.requestWithHeader("POST", c.attachmentPath(id, field), pr, confirmed(http.Header{"Content-Type": {mw.FormDataContentType()}}, c.confirm))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !isSuccess(resp.StatusCode) {
+		return c.attachmentError("upload attachment", resp)
+	}
+	c.client.capabilities.record(CapabilityAttachments, true)
+
+	return nil
+}
+
+// DeleteAttachment removes the file stored in a file-like field
+func (c *Collection[T]) DeleteAttachment(id, field string) (err error) {
+	timer := c.timeOp(OpDelete).target(id).as("DeleteAttachment")
+	defer timer.end(&err)
+
+	if err := c.checkAttachments(); err != nil {
+		return err
+	}
+
+	resp, err := c.client.requestJSON("DELETE", c.attachmentPath(id, field), nil, confirmed(nil, c.confirm))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !isSuccess(resp.StatusCode) {
+		return c.attachmentError("delete attachment", resp)
+	}
+	c.client.capabilities.record(CapabilityAttachments, true)
+
+	return nil
+}
+
+func (c *Collection[T]) attachmentPath(id, field string) string {
+	return c.client.apiPath(c.collection, id, "attachments", field)
+}
+
+// checkAttachments fails fast once the server is known to lack attachments
+func (c *Collection[T]) checkAttachments() error {
+	if supported, known := c.client.capabilities.lookup(CapabilityAttachments); known && !supported {
+		return fmt.Errorf("attachments: %w", ErrNotSupported)
+	}
+	return nil
+}
+
+// attachmentError converts a failed attachment response. A 404 that is not
+// about the document itself means the server has no attachment endpoints.
+func (c *Collection[T]) attachmentError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+	case http.StatusNotFound:
+		if bytes.Contains(bytes.ToLower(body), []byte("document not found")) {
+			return fmt.Errorf("%s: %w", op, ErrNotFound)
+		}
+	default:
+		return readResponseError(op, resp, body)
+	}
+
+	c.client.capabilities.record(CapabilityAttachments, false)
+	return fmt.Errorf("%s: %w", op, ErrNotSupported)
+}
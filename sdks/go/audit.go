@@ -0,0 +1,230 @@
+package torm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoHistory is returned when the audit trail does not cover a document
+// at the requested time
+var ErrNoHistory = errors.New("torm: no audit history")
+
+// AuditEntry records one write to a document: its state before and after
+type AuditEntry struct {
+	ID         string                 `json:"id"`
+	Collection string                 `json:"collection"`
+	DocID      string                 `json:"doc_id"`
+	Op         Operation              `json:"op"`
+	Before     map[string]interface{} `json:"before,omitempty"` // Nil when the trail starts with this entry, or for creates
+	After      map[string]interface{} `json:"after,omitempty"`  // Nil for deletes
+	At         time.Time              `json:"at"`
+}
+
+// AuditOptions configures WithAudit
+type AuditOptions struct {
+	// Collection stores the entries (default "<collection>_audit")
+	Collection string
+	// Strict returns audit failures from the write that caused them. The
+	// write itself has already succeeded. Otherwise failures go to OnError.
+	Strict  bool
+	OnError func(entry AuditEntry, err error)
+	// Redact masks sensitive fields (see WithRedaction) in the recorded
+	// states. AsOf then returns the masked values.
+	Redact bool
+}
+
+// WithAudit records every write the collection makes in an audit
+// collection, so earlier states can be read back with AsOf and DiffBetween
+func WithAudit(opts AuditOptions) CollectionOption {
+	return func(o *collectionOptions) {
+		if opts.Collection == "" {
+			opts.Collection = o.collection + "_audit"
+		}
+		o.audit = &auditTrail{opts: opts}
+	}
+}
+
+type auditTrail struct {
+	opts AuditOptions
+	seq  atomic.Int64
+}
+
+// entryID orders a document's entries by time, then by the order this
+// process recorded them
+func (a *auditTrail) entryID(collection, id string, at time.Time) string {
+	return fmt.Sprintf("audit:%s:%s:%020d:%06d", collection, id, at.UnixNano(), a.seq.Add(1)%1000000)
+}
+
+// auditWrite records a write in the audit trail. Before is the state the
+// previous entry left, so the trail is self-contained.
+func (c *Collection[T]) auditWrite(op Operation, id string, doc map[string]interface{}) error {
+	a := c.options.audit
+	if a == nil || id == "" {
+		return nil
+	}
+
+	at := c.client.now()
+	entry := AuditEntry{
+		ID:         a.entryID(c.collection, id, at),
+		Collection: c.collection,
+		DocID:      id,
+		Op:         op,
+		At:         at,
+	}
+	if doc != nil {
+		entry.After = deepCopyDoc(doc)
+		if a.opts.Redact {
+			entry.After = c.redactor().doc(entry.After)
+		}
+	}
+
+	err := c.recordAudit(&entry)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("audit %s of %s failed: %w", op, id, err)
+	if a.opts.Strict {
+		return err
+	}
+	if a.opts.OnError != nil {
+		return protect("audit error handler", c.collection, id, func() { a.opts.OnError(entry, err) })
+	}
+	return nil
+}
+
+func (c *Collection[T]) recordAudit(entry *AuditEntry) error {
+	entries, err := c.AuditTrail(entry.DocID)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		entry.Before = entries[len(entries)-1].After
+	}
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	_, err = c.auditModel().Create(doc)
+	return err
+}
+
+func (c *Collection[T]) auditModel() *Model {
+	return c.client.Model(c.options.audit.opts.Collection, nil).WithDefaultOrder("id", Asc)
+}
+
+// AuditTrail returns the audit entries of a document, oldest first
+func (c *Collection[T]) AuditTrail(id string) ([]AuditEntry, error) {
+	if c.options.audit == nil {
+		return nil, fmt.Errorf("%w: collection %s has no audit trail", ErrNotSupported, c.collection)
+	}
+	docs, err := c.auditModel().Query().Where("doc_id", id).Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit trail of %s: %w", id, err)
+	}
+
+	entries := make([]AuditEntry, 0, len(docs))
+	for _, doc := range docs {
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry %v: %w", doc["id"], err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// AsOf returns the document as it was at t: the state the last entry at or
+// before t left, or the state recorded before the first entry after t. It
+// returns nil when the document was deleted at t, and ErrNoHistory when the
+// trail does not reach back to t.
+func (c *Collection[T]) AsOf(id string, t time.Time) (map[string]interface{}, error) {
+	entries, err := c.AuditTrail(id)
+	if err != nil {
+		return nil, err
+	}
+	return stateAt(entries, id, t)
+}
+
+func stateAt(entries []AuditEntry, id string, t time.Time) (map[string]interface{}, error) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].At.After(t) {
+			return entries[i].After, nil
+		}
+	}
+	// Reverse-apply the earliest entry when it knows the state it replaced
+	if len(entries) > 0 && entries[0].Before != nil {
+		return entries[0].Before, nil
+	}
+	return nil, fmt.Errorf("%w: %s before %s", ErrNoHistory, id, t.Format(time.RFC3339))
+}
+
+// ChangeKind is how a field changed between two states
+type ChangeKind string
+
+const (
+	FieldAdded    ChangeKind = "added"
+	FieldRemoved  ChangeKind = "removed"
+	FieldModified ChangeKind = "modified"
+)
+
+// FieldChange is a top-level field that differs between two states
+type FieldChange struct {
+	Field  string
+	Kind   ChangeKind
+	Before interface{} // Nil when added
+	After  interface{} // Nil when removed
+}
+
+// DiffBetween returns the fields of a document that changed between t1 and
+// t2, sorted by name. A document that did not exist at one of the times
+// counts as empty.
+func (c *Collection[T]) DiffBetween(id string, t1, t2 time.Time) ([]FieldChange, error) {
+	entries, err := c.AuditTrail(id)
+	if err != nil {
+		return nil, err
+	}
+	before, err := stateAt(entries, id, t1)
+	if err != nil {
+		return nil, err
+	}
+	after, err := stateAt(entries, id, t2)
+	if err != nil {
+		return nil, err
+	}
+	return diffFields(before, after), nil
+}
+
+func diffFields(before, after map[string]interface{}) []FieldChange {
+	var changes []FieldChange
+	for field, old := range before {
+		value, ok := after[field]
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{Field: field, Kind: FieldRemoved, Before: old})
+		case !reflect.DeepEqual(old, value):
+			changes = append(changes, FieldChange{Field: field, Kind: FieldModified, Before: old, After: value})
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, Kind: FieldAdded, After: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
@@ -0,0 +1,34 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// TokenProvider returns the bearer token for a request, such as a token
+// refreshed from a secrets manager. It is called before every request.
+type TokenProvider func(ctx context.Context) (string, error)
+
+// authorize adds the configured credentials to req. A TokenProvider error
+// fails the request before anything is sent.
+func (c *Client) authorize(req *http.Request) error {
+	if c.apiKey != "" {
+		req.Header.Set(c.apiKeyHeader, c.apiKey)
+	}
+	token := c.authToken
+	if c.tokenProvider != nil {
+		var err error
+		perr := protect("token provider", "", "", func() { token, err = c.tokenProvider(req.Context()) })
+		if perr != nil {
+			return perr
+		}
+		if err != nil {
+			return fmt.Errorf("token provider failed: %w", err)
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
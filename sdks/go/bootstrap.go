@@ -0,0 +1,226 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BootstrapStage is a step of Bootstrap. Stages run in the order declared.
+type BootstrapStage string
+
+const (
+	StageLock       BootstrapStage = "lock"       // The migration lock held around the run
+	StageSchemas    BootstrapStage = "schemas"    // Collections created with their schemas
+	StageIndexes    BootstrapStage = "indexes"    // Indexes ensured
+	StageMigrations BootstrapStage = "migrations" // Pending migrations applied
+	StageSeeds      BootstrapStage = "seeds"      // Empty collections seeded
+)
+
+// BootstrapOutcome is what happened to an item during Bootstrap
+type BootstrapOutcome string
+
+const (
+	BootstrapCreated BootstrapOutcome = "created" // A collection or index that did not exist
+	BootstrapApplied BootstrapOutcome = "applied" // A migration or seeder that ran
+	BootstrapSkipped BootstrapOutcome = "skipped" // Already in place, or not supported by the server
+)
+
+// Seeder fills an empty collection. It runs only while the model's
+// collection has no documents, so a seeder that failed part way is not
+// run again once it wrote anything.
+type Seeder struct {
+	Name  string // Reported name (default the model's collection)
+	Model *Model
+	Seed  func(ctx context.Context, client *Client) error
+}
+
+// BootstrapSpec lists what a service needs in place before it serves
+type BootstrapSpec struct {
+	Models     []*Model          // Collections to create, sending their schemas as settings
+	Indexes    []IndexSpec       // Indexes to ensure
+	Migrations *MigrationManager // Migrations to apply, if any
+	Seeders    []Seeder          // Seeders of empty collections
+
+	LockTTL  time.Duration // Lease on the migration lock (default 10 minutes)
+	LockWait time.Duration // How long to wait for another process's lock (default 1 minute)
+}
+
+// BootstrapItem reports one item of a Bootstrap run
+type BootstrapItem struct {
+	Stage   BootstrapStage
+	Item    string // Collection, index, migration id or seeder name
+	Outcome BootstrapOutcome
+	Reason  string // Why a skipped item was skipped
+}
+
+// BootstrapReport is the result of Bootstrap
+type BootstrapReport struct {
+	Owner    string // Holder of the migration lock during the run
+	Items    []BootstrapItem
+	Duration time.Duration
+}
+
+// Names returns the items of a stage with the given outcome, in run order
+func (r *BootstrapReport) Names(stage BootstrapStage, outcome BootstrapOutcome) []string {
+	names := make([]string, 0)
+	for _, item := range r.Items {
+		if item.Stage == stage && item.Outcome == outcome {
+			names = append(names, item.Item)
+		}
+	}
+	return names
+}
+
+// Changed reports whether the run created or applied anything
+func (r *BootstrapReport) Changed() bool {
+	for _, item := range r.Items {
+		if item.Outcome != BootstrapSkipped {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *BootstrapReport) add(stage BootstrapStage, item string, outcome BootstrapOutcome, reason string) {
+	r.Items = append(r.Items, BootstrapItem{Stage: stage, Item: item, Outcome: outcome, Reason: reason})
+}
+
+// BootstrapError identifies the stage and item where Bootstrap stopped
+type BootstrapError struct {
+	Stage BootstrapStage
+	Item  string
+	Err   error
+}
+
+func (e *BootstrapError) Error() string {
+	if e.Item == "" {
+		return fmt.Sprintf("bootstrap %s: %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("bootstrap %s %s: %v", e.Stage, e.Item, e.Err)
+}
+
+func (e *BootstrapError) Unwrap() error {
+	return e.Err
+}
+
+// Bootstrap brings the server to the state spec describes: it creates the
+// models' collections, ensures the indexes, applies pending migrations and
+// seeds empty collections, in that order. Every replica of a service can
+// call it at startup: the whole run holds the migration lock, and each
+// stage skips what is already in place, so a second run changes nothing.
+// Collections and indexes on servers without the endpoints are reported as
+// skipped.
+//
+// The report lists every item reached. A failure stops the run with a
+// *BootstrapError naming the stage and item; what ran before it stays done
+// and is skipped next time.
+func (c *Client) Bootstrap(ctx context.Context, spec BootstrapSpec) (*BootstrapReport, error) {
+	if spec.LockTTL <= 0 {
+		spec.LockTTL = 10 * time.Minute
+	}
+	if spec.LockWait <= 0 {
+		spec.LockWait = time.Minute
+	}
+
+	start := c.now()
+	report := &BootstrapReport{Owner: lockOwner()}
+	release, err := c.acquireMigrationLock(ctx, report.Owner, spec.LockTTL, spec.LockWait)
+	if err != nil {
+		return report, &BootstrapError{Stage: StageLock, Err: err}
+	}
+
+	err = c.bootstrap(ctx, spec, report)
+	if releaseErr := release(); releaseErr != nil && err == nil {
+		err = &BootstrapError{Stage: StageLock, Err: releaseErr}
+	}
+	report.Duration = c.now().Sub(start)
+	return report, err
+}
+
+func (c *Client) bootstrap(ctx context.Context, spec BootstrapSpec, report *BootstrapReport) error {
+	for _, model := range spec.Models {
+		if err := ctx.Err(); err != nil {
+			return &BootstrapError{Stage: StageSchemas, Item: model.collection, Err: err}
+		}
+		var opts *EnsureCollectionOptions
+		if len(model.schema) > 0 {
+			opts = &EnsureCollectionOptions{Settings: map[string]interface{}{"schema": model.schema}}
+		}
+		created, err := c.EnsureCollection(model.collection, opts)
+		if err := ensured(report, StageSchemas, model.collection, created, err); err != nil {
+			return err
+		}
+	}
+
+	for _, index := range spec.Indexes {
+		item := index.Collection + "." + index.Name
+		if err := ctx.Err(); err != nil {
+			return &BootstrapError{Stage: StageIndexes, Item: item, Err: err}
+		}
+		created, err := c.EnsureIndex(index)
+		if err := ensured(report, StageIndexes, item, created, err); err != nil {
+			return err
+		}
+	}
+
+	if spec.Migrations != nil {
+		result, err := spec.Migrations.migrate(ctx)
+		if result != nil {
+			for _, migration := range result.Migrations {
+				switch migration.Outcome {
+				case OutcomeApplied:
+					report.add(StageMigrations, migration.ID, BootstrapApplied, "")
+				case OutcomeSkipped:
+					report.add(StageMigrations, migration.ID, BootstrapSkipped, "already applied")
+				}
+			}
+		}
+		if err != nil {
+			item := ""
+			if result != nil {
+				item = result.StoppedAt
+			}
+			return &BootstrapError{Stage: StageMigrations, Item: item, Err: err}
+		}
+	}
+
+	for _, seeder := range spec.Seeders {
+		name := seeder.Name
+		if name == "" {
+			name = seeder.Model.collection
+		}
+		if err := ctx.Err(); err != nil {
+			return &BootstrapError{Stage: StageSeeds, Item: name, Err: err}
+		}
+		n, err := seeder.Model.Count()
+		if err != nil {
+			return &BootstrapError{Stage: StageSeeds, Item: name, Err: err}
+		}
+		if n > 0 {
+			report.add(StageSeeds, name, BootstrapSkipped, "collection not empty")
+			continue
+		}
+		if err := seeder.Seed(ctx, c); err != nil {
+			return &BootstrapError{Stage: StageSeeds, Item: name, Err: err}
+		}
+		report.add(StageSeeds, name, BootstrapApplied, "")
+	}
+	return nil
+}
+
+// ensured reports the outcome of an EnsureCollection or EnsureIndex call
+func ensured(report *BootstrapReport, stage BootstrapStage, item string, created bool, err error) error {
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		report.add(stage, item, BootstrapSkipped, "not supported by server")
+	case err != nil:
+		return &BootstrapError{Stage: stage, Item: item, Err: err}
+	case created:
+		report.add(stage, item, BootstrapCreated, "")
+	default:
+		report.add(stage, item, BootstrapSkipped, "exists")
+	}
+	return nil
+}
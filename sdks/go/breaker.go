@@ -0,0 +1,208 @@
+package torm
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerOptions configures the client's circuit breaker, see
+// ClientOptions.Breaker
+type BreakerOptions struct {
+	// FailureThreshold is how many consecutive failed requests open the
+	// circuit (default 5). Failures are counted as for State: transport
+	// errors, 5xx and 429. Each retry and failover attempt counts, and a
+	// call stops retrying once the circuit opens.
+	FailureThreshold int
+	// OpenDuration is how long an open circuit fails requests fast before
+	// letting probes through (default 30s)
+	OpenDuration time.Duration
+	// HalfOpenProbes is how many requests a half-open circuit lets through,
+	// all of which must succeed to close it (default 1). While other
+	// probes are in flight, the last is kept for a PriorityHigh request.
+	HalfOpenProbes int
+}
+
+// BreakerState is the state of the client's circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Requests are sent
+	BreakerOpen                         // Requests fail fast with ErrCircuitOpen
+	BreakerHalfOpen                     // A few probes are sent; the rest fail fast
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// breaker is a client-wide circuit breaker. A nil breaker lets every
+// request through.
+type breaker struct {
+	opts BreakerOptions
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int       // Consecutive failures while closed
+	openedAt  time.Time // When the circuit last opened
+	probes    int       // Probes let through while half-open
+	succeeded int       // Probes that succeeded while half-open
+
+	shed shedCounters
+}
+
+func newBreaker(opts *BreakerOptions) *breaker {
+	if opts == nil {
+		return nil
+	}
+	b := &breaker{opts: *opts}
+	if b.opts.FailureThreshold <= 0 {
+		b.opts.FailureThreshold = 5
+	}
+	if b.opts.OpenDuration <= 0 {
+		b.opts.OpenDuration = 30 * time.Second
+	}
+	if b.opts.HalfOpenProbes <= 0 {
+		b.opts.HalfOpenProbes = 1
+	}
+	return b
+}
+
+// BreakerState returns the state of the circuit breaker; BreakerClosed
+// without ClientOptions.Breaker. An open circuit reads as half-open once
+// its OpenDuration is over.
+func (c *Client) BreakerState() BreakerState {
+	b := c.breaker
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(c.now())
+	return b.state
+}
+
+// ResetBreaker closes the circuit breaker, forgetting past failures
+func (c *Client) ResetBreaker() {
+	b := c.breaker
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.close()
+}
+
+// admit fails req fast while the circuit is open, and sheds background
+// requests while it is half-open or one failure away from opening.
+// Otherwise it returns the function to call once req is done, which frees
+// its probe slot.
+func (b *breaker) admit(req *http.Request, priority Priority, now time.Time) (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(now)
+	if priority == PriorityBackground && (b.state == BreakerHalfOpen ||
+		(b.state == BreakerClosed && b.failures > 0 && b.failures >= b.opts.FailureThreshold-1)) {
+		b.shed[priority].Add(1)
+		return nil, fmt.Errorf("%w: %s %s of %s priority", ErrLoadShed, req.Method, req.URL.Path, priority)
+	}
+	switch b.state {
+	case BreakerOpen:
+		return nil, fmt.Errorf("%w: %s %s", ErrCircuitOpen, req.Method, req.URL.Path)
+	case BreakerHalfOpen:
+		// The last slot waits for a high priority request while other
+		// probes are in flight
+		last := b.probes == b.opts.HalfOpenProbes-1 && b.probes > b.succeeded
+		if b.probes >= b.opts.HalfOpenProbes || (last && priority != PriorityHigh) {
+			return nil, fmt.Errorf("%w: %s %s while probing", ErrCircuitOpen, req.Method, req.URL.Path)
+		}
+		b.probes++
+		opened := b.openedAt
+		return func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			// A probe that was answered without reaching the server,
+			// e.g. by middleware, leaves its slot to another
+			if b.state == BreakerHalfOpen && b.openedAt.Equal(opened) && b.probes > b.succeeded {
+				b.probes--
+			}
+		}, nil
+	}
+	return func() {}, nil
+}
+
+// shedLast sheds the last retry attempt of a background request
+func (b *breaker) shedLast(req *http.Request, priority Priority) error {
+	if b == nil || priority != PriorityBackground {
+		return nil
+	}
+	b.shed[priority].Add(1)
+	return fmt.Errorf("%w: %s %s of %s priority before its last retry", ErrLoadShed, req.Method, req.URL.Path, priority)
+}
+
+// shedStats counts the requests shed, by priority
+func (b *breaker) shedStats() map[Priority]int64 {
+	if b == nil {
+		return map[Priority]int64{}
+	}
+	return b.shed.stats()
+}
+
+// record counts the outcome of a request that reached the server
+func (b *breaker) record(failed bool, now time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(now)
+	switch b.state {
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		if b.failures++; b.failures >= b.opts.FailureThreshold {
+			b.open(now)
+		}
+	case BreakerHalfOpen:
+		if failed {
+			b.open(now)
+			return
+		}
+		if b.succeeded++; b.succeeded >= b.opts.HalfOpenProbes {
+			b.close()
+		}
+	}
+}
+
+// advance moves an open circuit to half-open once its time is up
+func (b *breaker) advance(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.opts.OpenDuration {
+		b.state = BreakerHalfOpen
+		b.probes, b.succeeded = 0, 0
+	}
+}
+
+func (b *breaker) open(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.failures = 0
+}
+
+func (b *breaker) close() {
+	b.state = BreakerClosed
+	b.failures, b.probes, b.succeeded = 0, 0, 0
+}
@@ -0,0 +1,75 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+)
+
+// BulkResult reports a bulk write item by item
+type BulkResult[T Document] struct {
+	Created []T           // Stored documents, in input order
+	Failed  []BulkFailure // Items that failed, in input order
+	// DeadLettered counts failed items captured by the collection's
+	// dead-letter sink; see WithDeadLetter
+	DeadLettered int
+}
+
+// BulkFailure is one item a bulk write failed on
+type BulkFailure struct {
+	Index int // Position in the input
+	ID    string
+	Err   error
+}
+
+// WithBulkConcurrency runs the creates of CreateMany with an adaptive
+// worker pool instead of one at a time
+func WithBulkConcurrency(cfg AdaptiveConcurrency) CollectionOption {
+	return func(o *collectionOptions) {
+		o.bulkConcurrency = &cfg
+	}
+}
+
+// CreateMany creates the items, applying the collection's conflict policy.
+// They are created one at a time unless WithBulkConcurrency is set, and
+// reported in input order either way. Failed items are reported, and
+// captured by the dead-letter sink when one is set, without stopping the
+// run. The context is checked before each item; on cancellation the
+// partial result is returned with the context's error.
+func (c *Collection[T]) CreateMany(ctx context.Context, items []T) (*BulkResult[T], error) {
+	c = c.prioritizedBy(ctx)
+	created := make([]T, len(items))
+	failed := make([]error, len(items))
+	started := make([]bool, len(items))
+	limiter := newAdaptiveLimiter(c.options.bulkConcurrency, c.client.now)
+	limiter.run(len(items), func(i int) error {
+		if ctx.Err() != nil {
+			return nil
+		}
+		started[i] = true
+		created[i], failed[i] = c.Create(items[i])
+		return failed[i]
+	})
+
+	result := &BulkResult[T]{Created: make([]T, 0, len(items))}
+	var canceled error
+	for i, item := range items {
+		if !started[i] {
+			canceled = ctx.Err()
+			continue
+		}
+		if failed[i] == nil {
+			result.Created = append(result.Created, created[i])
+			continue
+		}
+
+		id := item.GetID()
+		err := fmt.Errorf("create many, item %d: %w", i, failed[i])
+		entry := newDeadLetter(c.collection, OpCreate, item.ToMap(), id, err, c.client.now()).redacted(c.redactor(), c.options.deadLetter)
+		captured, err := captureDeadLetter(c.options.deadLetter, entry, err)
+		if captured {
+			result.DeadLettered++
+		}
+		result.Failed = append(result.Failed, BulkFailure{Index: i, ID: id, Err: err})
+	}
+	return result, canceled
+}
@@ -0,0 +1,156 @@
+package torm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Capability names an optional server feature
+type Capability string
+
+const (
+	CapabilityAttachments     Capability = "attachments"
+	CapabilityKeyListing      Capability = "key_listing"
+	CapabilityServerSideCount Capability = "count"       // GET /api/{collection}/count
+	CapabilityBulkDelete      Capability = "bulk_delete" // Deleting every match of a query in one request
+	CapabilityDistinct        Capability = "distinct"    // Distinct values of a field
+	CapabilityWatchSSE        Capability = "watch_sse"   // Change streams over server-sent events
+	CapabilityIndexes         Capability = "indexes"     // POST /api/{collection}/_indexes
+	CapabilitySchema          Capability = "schema"      // Schemas enforced by the server
+)
+
+// MissingCapabilitiesError is returned by RequireCapabilities with every
+// capability the server lacks
+type MissingCapabilitiesError struct {
+	Missing []Capability
+}
+
+func (e *MissingCapabilitiesError) Error() string {
+	names := make([]string, len(e.Missing))
+	for i, capability := range e.Missing {
+		names[i] = string(capability)
+	}
+	return fmt.Sprintf("torm: server lacks required capabilities: %s", strings.Join(names, ", "))
+}
+
+// Unwrap makes errors.Is(err, ErrNotSupported) match
+func (e *MissingCapabilitiesError) Unwrap() error {
+	return ErrNotSupported
+}
+
+// Supports reports whether the server supports a capability. Support seen
+// on earlier calls wins; otherwise the capabilities the server advertises
+// in its info document are used. Capabilities that are neither seen nor
+// advertised are reported as unsupported.
+func (c *Client) Supports(capability Capability) bool {
+	supported, _ := c.supports(capability)
+	return supported
+}
+
+// RequireCapabilities checks every capability and returns a single
+// *MissingCapabilitiesError listing those the server lacks, or the error
+// reading the server's info document. It suits service startup.
+func (c *Client) RequireCapabilities(capabilities ...Capability) error {
+	var missing []Capability
+	for _, capability := range capabilities {
+		supported, err := c.supports(capability)
+		if err != nil {
+			return fmt.Errorf("require capabilities: %w", err)
+		}
+		if !supported {
+			missing = append(missing, capability)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingCapabilitiesError{Missing: missing}
+	}
+	return nil
+}
+
+func (c *Client) supports(capability Capability) (bool, error) {
+	if supported, known := c.capabilities.lookup(capability); known {
+		return supported, nil
+	}
+	if advertised, read := c.capabilities.advertisedSet(); read {
+		return advertised[capability], nil
+	}
+
+	info, err := c.Info()
+	if err != nil {
+		return false, err
+	}
+	advertised := parseCapabilities(info)
+	c.capabilities.advertise(advertised)
+	return advertised[capability], nil
+}
+
+// parseCapabilities reads the capabilities an info document advertises,
+// either as a list of names or as an object of flags, under "capabilities"
+// or "features"
+func parseCapabilities(info map[string]interface{}) map[Capability]bool {
+	advertised := make(map[Capability]bool)
+	for _, key := range []string{"capabilities", "features"} {
+		switch flags := info[key].(type) {
+		case []interface{}:
+			for _, name := range flags {
+				if name, ok := name.(string); ok {
+					advertised[Capability(name)] = true
+				}
+			}
+		case map[string]interface{}:
+			for name, on := range flags {
+				if on, ok := on.(bool); ok {
+					advertised[Capability(name)] = on
+				}
+			}
+		}
+	}
+	return advertised
+}
+
+// capabilityRegistry remembers which optional features the server has been
+// seen to support, so unsupported endpoints are not probed on every call
+type capabilityRegistry struct {
+	mu         sync.RWMutex
+	known      map[Capability]bool
+	advertised map[Capability]bool // From the info document, nil until read
+}
+
+// lookup returns whether a capability is supported and whether that is known yet
+func (r *capabilityRegistry) lookup(capability Capability) (supported, known bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	supported, known = r.known[capability]
+	return supported, known
+}
+
+// record stores the observed support for a capability
+func (r *capabilityRegistry) record(capability Capability, supported bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.known == nil {
+		r.known = make(map[Capability]bool)
+	}
+	r.known[capability] = supported
+}
+
+// advertisedSet returns the advertised capabilities and whether they have
+// been read. Advertised support does not count as observed, so endpoints
+// are still probed as before.
+func (r *capabilityRegistry) advertisedSet() (map[Capability]bool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.advertised, r.advertised != nil
+}
+
+// advertise stores the capabilities read from the info document
+func (r *capabilityRegistry) advertise(advertised map[Capability]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.advertised = advertised
+}
@@ -0,0 +1,202 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLeaseLost is returned by Renew and Release when the document is no
+// longer claimed by the owner, because the lease ran out or another
+// worker claimed it since
+var ErrLeaseLost = errors.New("torm: lease lost")
+
+// ClaimOptions configures Claim, Renew and Release
+type ClaimOptions struct {
+	Owner         string        // Identifies the worker; required
+	LeaseDuration time.Duration // How long a claim holds (default 5 minutes)
+	OwnerField    string        // Field holding the owner (default "owner")
+	LeaseField    string        // Field holding the lease expiry, RFC 3339 in UTC (default "leaseUntil")
+	PageSize      int           // Candidates read per query (default 50)
+}
+
+func (o ClaimOptions) withDefaults() (ClaimOptions, error) {
+	if o.Owner == "" {
+		return o, fmt.Errorf("claim needs an owner")
+	}
+	if o.LeaseDuration <= 0 {
+		o.LeaseDuration = 5 * time.Minute
+	}
+	if o.OwnerField == "" {
+		o.OwnerField = "owner"
+	}
+	if o.LeaseField == "" {
+		o.LeaseField = "leaseUntil"
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = 50
+	}
+	return o, nil
+}
+
+// claimable reports whether a document has no lease or an expired one
+func (o ClaimOptions) claimable(doc map[string]interface{}, now time.Time) bool {
+	return !leaseHeld(doc[o.LeaseField], now)
+}
+
+func leaseHeld(value interface{}, now time.Time) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		if v == "" {
+			return false
+		}
+		until, err := time.Parse(time.RFC3339Nano, v)
+		// A lease that cannot be read is left alone rather than stolen
+		return err != nil || now.Before(until)
+	}
+	return true
+}
+
+// Claim claims up to n documents matching filters for opts.Owner, for use
+// as a job queue. A document is claimable while it has no lease or its
+// lease has expired, so jobs of workers that died become claimable again.
+// Each claim is a versioned write of the owner and lease fields; documents
+// another worker claimed first are skipped. Servers that send ETags make
+// claims atomic; for others the document is read again just before the
+// write.
+func (c *Collection[T]) Claim(ctx context.Context, n int, filters []QueryFilter, opts ClaimOptions) (_ []T, err error) {
+	timer := c.timeOp(OpUpdate).as("Claim")
+	defer timer.end(&err)
+	c = c.traced(ctx, timer)
+	opts, err = opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]T, 0, n)
+	lastID := ""
+	for len(claimed) < n {
+		if err := ctx.Err(); err != nil {
+			return claimed, err
+		}
+		qb := c.model().Query().Sort("id", Asc).Limit(opts.PageSize)
+		qb.Match(filters...)
+		if lastID != "" {
+			qb.Filter("id", Gt, lastID)
+		}
+		candidates, err := qb.Exec()
+		if err != nil {
+			return claimed, err
+		}
+
+		now := c.client.now()
+		for _, candidate := range candidates {
+			lastID = fmt.Sprintf("%v", candidate["id"])
+			if len(claimed) == n {
+				break
+			}
+			if !opts.claimable(candidate, now) {
+				continue
+			}
+			model, ok, err := c.claimOne(lastID, opts)
+			if err != nil {
+				return claimed, err
+			}
+			if ok {
+				claimed = append(claimed, model)
+			}
+		}
+		if len(candidates) < opts.PageSize {
+			break
+		}
+	}
+	return claimed, nil
+}
+
+// claimOne claims a document, reporting false when it was lost to another
+// worker
+func (c *Collection[T]) claimOne(id string, opts ClaimOptions) (T, bool, error) {
+	var zero T
+	current, err := c.readVersion(id)
+	if errors.Is(err, ErrNotFound) {
+		return zero, false, nil
+	}
+	if err != nil {
+		return zero, false, err
+	}
+	now := c.client.now()
+	if !opts.claimable(current.doc, now) {
+		return zero, false, nil
+	}
+
+	data := cloneDoc(current.doc)
+	data[opts.OwnerField] = opts.Owner
+	data[opts.LeaseField] = now.Add(opts.LeaseDuration).UTC().Format(time.RFC3339Nano)
+	if err := c.writeLease(id, current, data); err != nil {
+		if errors.Is(err, ErrVersionConflict) {
+			return zero, false, nil
+		}
+		return zero, false, err
+	}
+
+	model := c.factory()
+	if err := hydrate(data, &model); err != nil {
+		return zero, false, err
+	}
+	return model, true, nil
+}
+
+// Renew extends the owner's lease on a claimed document by LeaseDuration
+func (c *Collection[T]) Renew(id string, opts ClaimOptions) error {
+	return c.updateLease(id, opts, func(data map[string]interface{}, opts ClaimOptions, now time.Time) {
+		data[opts.LeaseField] = now.Add(opts.LeaseDuration).UTC().Format(time.RFC3339Nano)
+	})
+}
+
+// Release ends the owner's claim on a document, clearing the owner and
+// lease fields and storing the fields of result, such as a job's outcome
+func (c *Collection[T]) Release(id string, result map[string]interface{}, opts ClaimOptions) error {
+	return c.updateLease(id, opts, func(data map[string]interface{}, opts ClaimOptions, _ time.Time) {
+		for field, value := range result {
+			data[field] = value
+		}
+		delete(data, opts.OwnerField)
+		delete(data, opts.LeaseField)
+	})
+}
+
+// updateLease changes a document the owner holds a valid lease on
+func (c *Collection[T]) updateLease(id string, opts ClaimOptions, change func(data map[string]interface{}, opts ClaimOptions, now time.Time)) error {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+	current, err := c.readVersion(id)
+	if err != nil {
+		return err
+	}
+	now := c.client.now()
+	if current.doc[opts.OwnerField] != opts.Owner || !leaseHeld(current.doc[opts.LeaseField], now) {
+		return fmt.Errorf("%w: %s is not held by %s", ErrLeaseLost, id, opts.Owner)
+	}
+
+	data := cloneDoc(current.doc)
+	change(data, opts, now)
+	err = c.writeLease(id, current, data)
+	if errors.Is(err, ErrVersionConflict) {
+		return fmt.Errorf("%w: %s changed while updating the lease", ErrLeaseLost, id)
+	}
+	return err
+}
+
+// writeLease stores a lease change if the document is still the version read
+func (c *Collection[T]) writeLease(id string, read *versionedDoc, data map[string]interface{}) error {
+	if err := c.options.checkGuard(OpUpdate, data); err != nil {
+		return err
+	}
+	_, err := c.putVersion(id, read, data)
+	return err
+}
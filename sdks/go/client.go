@@ -3,10 +3,15 @@ package torm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,12 +20,182 @@ type Client struct {
 	BaseURL string
 	Timeout time.Duration
 	client  *http.Client
+	clock   func() time.Time
+
+	endpoints []string // BaseURL first, then the other BaseURLs
+	failover  *failover
+	retry     RetryOptions
+	retries   retryCounters
+	latency   *latencyTracker
+
+	pathPrefix   string
+	apiVersion   string
+	healthAtRoot bool
+
+	strictResponses    bool
+	legacyErrorStrings bool
+	pagination         PaginationOptions
+	microCacheOptions  MicroCacheOptions
+	microCache         microCache
+
+	capabilities capabilityRegistry
+	health       *healthTracker
+	breaker      *breaker
+	invalidation *invalidationHub
+
+	keyIndex   bool
+	keyIndexMu sync.Mutex
+
+	defaultOrder *QuerySort // Nil keeps server order
+
+	logger          *slog.Logger
+	tracer          Tracer
+	debugBodies     bool            // See ClientOptions.DebugBodies
+	logRedact       map[string]bool // See ClientOptions.RedactFields
+	redactQuery     QueryRedactor
+	slowThreshold   time.Duration
+	onSlowOperation func(SlowOperation)
+	slowLog         *slowLog
+
+	debug       bool
+	cursors     cursorRegistry
+	cursorIdle  time.Duration
+	cursorCheck time.Duration
+
+	writeConfirmation string // See WriteProtected
+
+	authToken     string
+	tokenProvider TokenProvider
+	apiKey        string
+	apiKeyHeader  string
+
+	mu               sync.Mutex
+	retentions       []retentionTarget
+	integrityChecks  []IntegrityCheck
+	integrityHooks   []func(IntegrityResult)
+	auditCollections []string          // See Maintenance
+	deadLetterStores []DeadLetterStore // See Maintenance
+	readCaches       []namedReadCache  // See DebugSnapshot
+	watchers         map[string]int    // Active watchers by collection
+	middleware       []Middleware      // See Use
 }
 
 // ClientOptions configuration for creating a new client
 type ClientOptions struct {
 	BaseURL string
+	// BaseURLs lists servers to use instead of BaseURL. Requests go to the
+	// first; after a transport error or 5xx they move on to the next, which
+	// later requests then start at. Requests other than GET, HEAD, PUT,
+	// DELETE and OPTIONS move on only when the connection failed. Without
+	// Retry each endpoint is tried once; with it, attempts go round the
+	// endpoints up to Retry.MaxAttempts. See LastEndpoint.
+	BaseURLs []string
+	// FailbackInterval is how long requests stay on another endpoint
+	// before one is sent to the first of BaseURLs again, to see whether it
+	// recovered (default 30s)
+	FailbackInterval time.Duration
+	// Timeout bounds each attempt (default 5s). It becomes the Timeout of
+	// the http.Client the SDK builds. With HTTPClient it applies only when
+	// HTTPClient.Timeout is zero, through the request context, and never
+	// changes the caller's client. AdaptiveTimeout and the contexts given
+	// to methods that take one bound attempts too; the earliest deadline
+	// wins.
 	Timeout time.Duration
+	// HTTPClient sends every request, used as is, so its transport, proxy,
+	// TLS settings and Timeout are kept. Transport is ignored when it is set.
+	HTTPClient *http.Client
+	// Transport is the RoundTripper of the http.Client the SDK builds when
+	// HTTPClient is nil (default http.DefaultTransport)
+	Transport http.RoundTripper
+
+	// AuthToken is sent on every request as "Authorization: Bearer <token>"
+	AuthToken string
+	// TokenProvider supplies the bearer token before each request instead
+	// of AuthToken. Its error fails the call without sending anything.
+	TokenProvider TokenProvider
+	APIKey        string // Sent on every request in APIKeyHeader
+	APIKeyHeader  string // Header carrying APIKey (default "X-API-Key")
+
+	Retry RetryOptions     // How failed requests are retried (default no retries)
+	Clock func() time.Time // Time source for cutoffs, e.g. a fake in tests (default time.Now)
+	// AdaptiveTimeout derives each attempt's timeout from observed latency,
+	// falling back to Timeout until enough has been observed
+	AdaptiveTimeout *AdaptiveTimeout
+
+	PathPrefix   string // Prepended to every path, e.g. "/toonstore" behind a gateway
+	APIVersion   string // Injected after /api, e.g. "v2" gives /api/v2/users
+	HealthAtRoot bool   // Keep Health and Info at the server root, ignoring PathPrefix
+
+	StrictResponses bool // Fail with a ContractError on unexpected response shapes
+
+	// LegacyErrorStrings returns transport errors from Collection and
+	// MigrationManager unwrapped, as the resty-based client did, and leaves
+	// errors without their OpError. See Deprecations.
+	LegacyErrorStrings bool
+
+	Pagination PaginationOptions // How Find follows paginated listings
+	Health     HealthOptions     // How State is derived from request outcomes
+	MicroCache MicroCacheOptions // How long parameterless reads are shared between callers
+
+	// Breaker fails requests fast with ErrCircuitOpen after consecutive
+	// failures, instead of waiting out a down server (default none). It
+	// also sheds PriorityBackground requests while the server recovers.
+	Breaker *BreakerOptions
+
+	// KeyIndex maintains a per-namespace index of keys written through
+	// SetKey and DeleteKey, so ListKeys works on servers that cannot list keys
+	KeyIndex bool
+
+	// DefaultOrder is appended as the final sort key of every query and
+	// listing, so results come back in the same order on every run: it
+	// orders unsorted queries and breaks ties of sorted ones (default id
+	// ascending)
+	DefaultOrder *QuerySort
+	// ServerOrder leaves results in the order the server returns them,
+	// disabling DefaultOrder
+	ServerOrder bool
+
+	// Logger receives warnings such as slow operations, and every request
+	// at debug level: method, path, status, duration and retries, never
+	// headers (default none)
+	Logger *slog.Logger
+	// DebugBodies adds request and response bodies to the debug log, as
+	// JSON with the values of RedactFields masked
+	DebugBodies bool
+	// RedactFields names the fields masked in logged bodies, e.g.
+	// "password" or "email", at any depth and in any case. Filters on
+	// them have their values masked too.
+	RedactFields []string
+	// RedactQuery masks filter values in query strings, which errors and
+	// the slow-operation log show; see RedactFields
+	RedactQuery QueryRedactor
+
+	// Tracer starts a span around every Collection, Model and QueryBuilder
+	// operation, and propagates it to the server in request headers
+	// (default none)
+	Tracer Tracer
+
+	// SlowThreshold reports operations taking at least this long through
+	// Logger, OnSlowOperation and SlowOperations (default 0, disabled).
+	// WithSlowThreshold overrides it per collection.
+	SlowThreshold   time.Duration
+	OnSlowOperation func(SlowOperation)
+	SlowLogSize     int // Slow operations kept for SlowOperations (default 100)
+
+	// Debug captures extra diagnostics, such as the stack that created an
+	// iterator later closed as leaked
+	Debug bool
+	// CursorIdleTimeout closes iterators and channel producers unused for
+	// this long, logging them as leaked (default 5 minutes)
+	CursorIdleTimeout time.Duration
+	// CursorCheckInterval is how often idle cursors are looked for (default 1s)
+	CursorCheckInterval time.Duration
+
+	// WriteConfirmation makes the client write-protected: writes fail with
+	// ErrWriteProtected unless made through a collection or model confirmed
+	// with this token, see Collection.Confirm. Writes that cannot be
+	// confirmed, such as keys and migrations, always fail.
+	WriteConfirmation string
 }
 
 // NewClient creates a new TORM client
@@ -29,25 +204,109 @@ func NewClient(opts *ClientOptions) *Client {
 		opts = &ClientOptions{}
 	}
 
-	baseURL := opts.BaseURL
+	var endpoints []string
+	for _, endpoint := range opts.BaseURLs {
+		if endpoint = strings.TrimRight(endpoint, "/"); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	baseURL := strings.TrimRight(opts.BaseURL, "/")
+	if len(endpoints) > 0 {
+		baseURL = endpoints[0]
+	}
 	if baseURL == "" {
 		baseURL = "http://localhost:3001"
 	}
+	if len(endpoints) == 0 {
+		endpoints = []string{baseURL}
+	}
+
+	pathPrefix := strings.Trim(opts.PathPrefix, "/")
+	if pathPrefix != "" {
+		pathPrefix = "/" + pathPrefix
+	}
 
 	timeout := opts.Timeout
 	if timeout == 0 {
 		timeout = 5 * time.Second
 	}
+	cursorIdle := opts.CursorIdleTimeout
+	if cursorIdle <= 0 {
+		cursorIdle = defaultCursorIdleTimeout
+	}
+	cursorCheck := opts.CursorCheckInterval
+	if cursorCheck <= 0 {
+		cursorCheck = defaultCursorCheck
+	}
+
+	// Adaptive timeouts bound each request through its context instead
+	clientTimeout := timeout
+	if opts.AdaptiveTimeout != nil {
+		clientTimeout = 0
+	}
+	apiKeyHeader := opts.APIKeyHeader
+	if apiKeyHeader == "" {
+		apiKeyHeader = "X-API-Key"
+	}
+	httpClient := opts.HTTPClient
+	latency := newLatencyTracker(opts.AdaptiveTimeout, timeout)
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: clientTimeout, Transport: opts.Transport}
+	} else if httpClient.Timeout == 0 {
+		latency.bound = true
+	} else if opts.Timeout == 0 {
+		timeout = httpClient.Timeout
+	}
 
 	return &Client{
-		BaseURL: baseURL,
-		Timeout: timeout,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		BaseURL:      baseURL,
+		Timeout:      timeout,
+		client:       httpClient,
+		clock:        opts.Clock,
+		endpoints:    endpoints,
+		failover:     newFailover(opts.FailbackInterval),
+		retry:        opts.Retry,
+		latency:      latency,
+		pathPrefix:   pathPrefix,
+		apiVersion:   strings.Trim(opts.APIVersion, "/"),
+		healthAtRoot: opts.HealthAtRoot,
+
+		strictResponses:    opts.StrictResponses,
+		legacyErrorStrings: opts.LegacyErrorStrings,
+		pagination:         opts.Pagination,
+		microCacheOptions:  opts.MicroCache,
+		health:             newHealthTracker(opts.Health),
+		breaker:            newBreaker(opts.Breaker),
+		invalidation:       newInvalidationHub(),
+		keyIndex:           opts.KeyIndex,
+		defaultOrder:       clientDefaultOrder(opts),
+		logger:             opts.Logger,
+		tracer:             opts.Tracer,
+		debugBodies:        opts.DebugBodies,
+		logRedact:          logRedactFields(opts.RedactFields),
+		redactQuery:        opts.RedactQuery,
+		slowThreshold:      opts.SlowThreshold,
+		onSlowOperation:    opts.OnSlowOperation,
+		slowLog:            newSlowLog(opts.SlowLogSize),
+		debug:              opts.Debug,
+		cursorIdle:         cursorIdle,
+		cursorCheck:        cursorCheck,
+		writeConfirmation:  opts.WriteConfirmation,
+		authToken:          opts.AuthToken,
+		tokenProvider:      opts.TokenProvider,
+		apiKey:             opts.APIKey,
+		apiKeyHeader:       apiKeyHeader,
 	}
 }
 
+// NewClientFromURL creates a client for baseURL with the 30s timeout of the
+// original string constructor.
+//
+// Deprecated: Use NewClient(&ClientOptions{BaseURL: baseURL}).
+func NewClientFromURL(baseURL string) *Client {
+	return NewClient(&ClientOptions{BaseURL: baseURL, Timeout: 30 * time.Second})
+}
+
 // Model creates a new model for the specified collection
 func (c *Client) Model(name string, schema map[string]ValidationRule) *Model {
 	return &Model{
@@ -56,12 +315,19 @@ func (c *Client) Model(name string, schema map[string]ValidationRule) *Model {
 		collection: name,
 		schema:     schema,
 		validate:   true,
+
+		defaultOrder: c.defaultOrder,
 	}
 }
 
-// Health checks server health
+// Health checks server health. Results are shared as configured by
+// ClientOptions.MicroCache.
 func (c *Client) Health() (map[string]interface{}, error) {
-	resp, err := c.client.Get(c.BaseURL + "/health")
+	return c.sharedDoc("health", c.microCacheOptions.Health, c.fetchHealth)
+}
+
+func (c *Client) fetchHealth() (map[string]interface{}, error) {
+	resp, err := c.requestRoot(context.Background(), c.rootPath("/health"))
 	if err != nil {
 		return nil, fmt.Errorf("health check failed: %w", err)
 	}
@@ -75,9 +341,14 @@ func (c *Client) Health() (map[string]interface{}, error) {
 	return result, nil
 }
 
-// Info gets server information
+// Info gets server information. Results are shared as configured by
+// ClientOptions.MicroCache.
 func (c *Client) Info() (map[string]interface{}, error) {
-	resp, err := c.client.Get(c.BaseURL + "/")
+	return c.sharedDoc("info", c.microCacheOptions.Info, c.fetchInfo)
+}
+
+func (c *Client) fetchInfo() (map[string]interface{}, error) {
+	resp, err := c.requestRoot(context.Background(), c.rootPath("/"))
 	if err != nil {
 		return nil, fmt.Errorf("info request failed: %w", err)
 	}
@@ -91,10 +362,59 @@ func (c *Client) Info() (map[string]interface{}, error) {
 	return result, nil
 }
 
-// request makes an HTTP request
+// requestRoot sends a GET to an endpoint outside /api, such as /health,
+// trying the client's endpoints in order like any other request. It is not
+// retried, so a health check reports each endpoint as it finds it.
+func (c *Client) requestRoot(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	return c.intercept(req, func(req *http.Request) (*http.Response, error) {
+		return c.doWithFailover(req, path)
+	})
+}
+
+// now returns the current time from the configured clock
+func (c *Client) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// apiPath builds the path of an API endpoint, escaping each segment
+func (c *Client) apiPath(segments ...string) string {
+	var path strings.Builder
+	path.WriteString(c.pathPrefix)
+	path.WriteString("/api")
+	if c.apiVersion != "" {
+		path.WriteString("/" + c.apiVersion)
+	}
+	for _, segment := range segments {
+		path.WriteString("/" + url.PathEscape(segment))
+	}
+	return path.String()
+}
+
+// rootPath builds the path of an endpoint outside /api, such as /health
+func (c *Client) rootPath(path string) string {
+	if c.healthAtRoot {
+		return path
+	}
+	return c.pathPrefix + path
+}
+
+// request makes an HTTP request with a JSON body
 func (c *Client) request(method, path string, body interface{}) (*http.Response, error) {
-	url := c.BaseURL + path
+	return c.requestJSON(method, path, body, nil)
+}
 
+// requestJSON is request with extra request headers
+func (c *Client) requestJSON(method, path string, body interface{}, header http.Header) (*http.Response, error) {
 	var reqBody io.Reader
 	if body != nil {
 		jsonData, err := json.Marshal(body)
@@ -104,17 +424,43 @@ func (c *Client) request(method, path string, body interface{}) (*http.Response,
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	all := http.Header{"Content-Type": {"application/json"}}
+	for name, values := range header {
+		all[http.CanonicalHeaderKey(name)] = values
 	}
+	return c.requestWithHeader(method, path, reqBody, all)
+}
 
-	req.Header.Set("Content-Type", "application/json")
+// requestStream makes an HTTP request with a raw body of the given content type
+func (c *Client) requestStream(method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	return c.requestWithHeader(method, path, body, http.Header{"Content-Type": {contentType}})
+}
 
-	resp, err := c.client.Do(req)
+// requestWithHeader makes an HTTP request with the given headers
+func (c *Client) requestWithHeader(method, path string, body io.Reader, header http.Header) (*http.Response, error) {
+	return c.requestWithContext(context.Background(), method, path, body, header)
+}
+
+// requestWithContext is requestWithHeader bound to ctx
+func (c *Client) requestWithContext(ctx context.Context, method, path string, body io.Reader, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	return resp, nil
+	for name, values := range header {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if err := c.checkWrite(req); err != nil {
+		return nil, err
+	}
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	return c.intercept(req, func(req *http.Request) (*http.Response, error) {
+		if c.retrying(req) {
+			return c.doWithRetry(req, path)
+		}
+		return c.doWithFailover(req, path)
+	})
 }
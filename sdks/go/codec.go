@@ -0,0 +1,97 @@
+package torm
+
+import "fmt"
+
+// DocumentCodec rewrites documents between the form the application sees
+// and the form stored on the server, e.g. to add and strip an envelope.
+// Codecs must keep "id" at the top level of the stored form.
+type DocumentCodec interface {
+	EncodeDoc(doc map[string]interface{}) map[string]interface{}
+	DecodeDoc(doc map[string]interface{}) (map[string]interface{}, error)
+}
+
+// codecChain applies codecs in order on write and in reverse on read
+type codecChain []DocumentCodec
+
+func (chain codecChain) encode(doc map[string]interface{}) (map[string]interface{}, error) {
+	doc, err := tagTypes(doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, codec := range chain {
+		if fields, ok := codec.(*fieldCodecs); ok {
+			encoded, err := fields.encodeDoc(doc)
+			if err != nil {
+				return nil, err
+			}
+			doc = encoded
+			continue
+		}
+		if err := protect("codec", "", docID(doc), func() { doc = codec.EncodeDoc(doc) }); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func (chain codecChain) decode(doc map[string]interface{}) (map[string]interface{}, error) {
+	for i := len(chain) - 1; i >= 0; i-- {
+		var decoded map[string]interface{}
+		var err error
+		if panicked := protect("codec", "", docID(doc), func() { decoded, err = chain[i].DecodeDoc(doc) }); panicked != nil {
+			return nil, panicked
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode document %v: %w", doc["id"], err)
+		}
+		doc = decoded
+	}
+	return doc, nil
+}
+
+// decodeAll decodes a list of documents in place
+func (chain codecChain) decodeAll(docs []map[string]interface{}) error {
+	for i, doc := range docs {
+		decoded, err := chain.decode(doc)
+		if err != nil {
+			return err
+		}
+		docs[i] = decoded
+	}
+	return nil
+}
+
+// WithCodec adds codecs applied to every document the collection writes and
+// reads. Codecs chain: the first added is the innermost, closest to the
+// application form. Filters, sorting and projection on fields other than id
+// are then evaluated on the client, against the decoded form.
+func WithCodec(codecs ...DocumentCodec) CollectionOption {
+	return func(o *collectionOptions) {
+		o.codecs = append(o.codecs, codecs...)
+	}
+}
+
+// WithCodec adds codecs to a model, as the collection option does
+func (m *Model) WithCodec(codecs ...DocumentCodec) *Model {
+	m.codecs = append(m.codecs, codecs...)
+	return m
+}
+
+// model returns a map-based model sharing the collection's codecs, read
+// repair, default order and sort defaults, dead-letter sink and slow
+// threshold
+func (c *Collection[T]) model() *Model {
+	m := c.client.Model(c.collection, nil).WithCodec(c.options.codecs...)
+	m.readRepair = c.options.readRepair
+	m.deadLetter = c.options.deadLetter
+	m.slowThreshold = c.options.slowThreshold
+	m.redact = c.options.redact
+	m.confirm = c.confirm
+	m.trace = c.trace
+	m.parity = c.options.parity
+	m.priority = c.priority
+	m.sanitizer = c.options.sanitizer
+	m.sortDefaults = c.options.sortDefaults
+	c.options.applyOrder(m)
+	return m
+}
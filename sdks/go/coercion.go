@@ -0,0 +1,234 @@
+package torm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CoercionPolicy decides how a query treats stored values that cannot be
+// coerced to a field's hinted type
+type CoercionPolicy int
+
+const (
+	// CoercionExclude drops documents whose value cannot be coerced from
+	// filters on the field, and sorts them last (default)
+	CoercionExclude CoercionPolicy = iota
+	// CoercionCompareAsString compares such values as they are stored,
+	// as queries without hints do
+	CoercionCompareAsString
+)
+
+// coercion is the type a field's values are converted to before comparing
+type coercion struct {
+	Type    string   `json:"type"` // number or time
+	Layouts []string `json:"layouts,omitempty"`
+}
+
+// CoerceNumeric compares the fields as numbers, so "30" and 30 are equal
+// and both greater than 4. Filters and sorts on coerced fields are
+// evaluated on the client; the hints are also sent in the query for
+// servers that support them.
+func (qb *QueryBuilder) CoerceNumeric(fields ...string) *QueryBuilder {
+	for _, field := range fields {
+		qb.coerce(field, coercion{Type: "number"})
+	}
+	return qb
+}
+
+// CoerceTime compares the field as instants, parsing text with the first
+// layout that fits (default time.RFC3339). Filter values may be time.Time
+// or text in one of the layouts.
+func (qb *QueryBuilder) CoerceTime(field string, layouts ...string) *QueryBuilder {
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+	return qb.coerce(field, coercion{Type: "time", Layouts: layouts})
+}
+
+// OnCoercionFailure sets how values that cannot be coerced are treated
+func (qb *QueryBuilder) OnCoercionFailure(policy CoercionPolicy) *QueryBuilder {
+	qb.coercionPolicy = policy
+	return qb
+}
+
+// WithAutoCoerce makes the model's queries compare the fields its schema
+// types as int or float numerically, whatever form they are stored in
+func (m *Model) WithAutoCoerce() *Model {
+	m.autoCoerce = true
+	return m
+}
+
+func (qb *QueryBuilder) coerce(field string, c coercion) *QueryBuilder {
+	if qb.coercions == nil {
+		qb.coercions = make(map[string]coercion)
+	}
+	qb.coercions[field] = c
+	return qb
+}
+
+// numericFields returns numeric coercions for the schema's number fields
+func numericFields(schema map[string]ValidationRule) map[string]coercion {
+	var fields map[string]coercion
+	for field, rules := range schema {
+		if rules.Type == "int" || rules.Type == "float" {
+			if fields == nil {
+				fields = make(map[string]coercion)
+			}
+			fields[field] = coercion{Type: "number"}
+		}
+	}
+	return fields
+}
+
+// value converts a stored or filter value, returning a float64 or a
+// time.Time
+func (c coercion) value(v interface{}) (interface{}, bool) {
+	switch c.Type {
+	case "number":
+		if f, ok := toFloat64(v); ok {
+			return f, true
+		}
+		if s, ok := v.(string); ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f, true
+			}
+		}
+	case "time":
+		switch t := v.(type) {
+		case time.Time:
+			return t, true
+		case string:
+			for _, layout := range c.Layouts {
+				if parsed, err := time.Parse(layout, strings.TrimSpace(t)); err == nil {
+					return parsed, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// compareCoerced compares two values converted by the same coercion
+func compareCoerced(a, b interface{}) int {
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+// coercedSort reports whether the sort field is coerced, so the client sorts
+func (qb *QueryBuilder) coercedSort() bool {
+	_, ok := qb.coercions[qb.sortField.Field]
+	return ok
+}
+
+// isCoercedComparison reports whether a filter is evaluated on coerced values
+func (qb *QueryBuilder) isCoercedComparison(filter QueryFilter) bool {
+	if _, ok := qb.coercions[filter.Field]; !ok {
+		return false
+	}
+	return filter.Operator != Contains
+}
+
+// matchesCoerced evaluates a filter on coerced values. ok is false when the
+// stored value cannot be coerced and the policy compares it as stored.
+func (qb *QueryBuilder) matchesCoerced(docValue interface{}, filter QueryFilter) (matched, ok bool) {
+	c := qb.coercions[filter.Field]
+	got, coerced := c.value(docValue)
+	if !coerced {
+		return false, qb.coercionPolicy == CoercionExclude
+	}
+
+	// Filter values were checked by validateCoercions
+	equal := func(value interface{}) bool {
+		want, _ := c.value(value)
+		return compareCoerced(got, want) == 0
+	}
+	switch filter.Operator {
+	case In, NotIn:
+		found := false
+		for _, item := range filter.Value.([]interface{}) {
+			found = found || equal(item)
+		}
+		return found == (filter.Operator == In), true
+	case Eq:
+		return equal(filter.Value), true
+	case Ne:
+		return !equal(filter.Value), true
+	}
+
+	want, _ := c.value(filter.Value)
+	cmp := compareCoerced(got, want)
+	switch filter.Operator {
+	case Gt:
+		return cmp > 0, true
+	case Gte:
+		return cmp >= 0, true
+	case Lt:
+		return cmp < 0, true
+	case Lte:
+		return cmp <= 0, true
+	}
+	return false, true
+}
+
+// compareForSort orders two stored values of a coerced field. Values that
+// cannot be coerced sort last under CoercionExclude, in either order.
+func (qb *QueryBuilder) compareForSort(c coercion, a, b interface{}, ascending bool) (cmp int, ok bool) {
+	aValue, aOk := c.value(a)
+	bValue, bOk := c.value(b)
+	switch {
+	case aOk && bOk:
+		return compareCoerced(aValue, bValue), true
+	case qb.coercionPolicy != CoercionExclude:
+		return 0, false
+	case aOk == bOk:
+		return 0, true
+	}
+
+	// Flip the result for descending sorts so uncoercible values stay last
+	cmp = -1
+	if !aOk {
+		cmp = 1
+	}
+	if !ascending {
+		cmp = -cmp
+	}
+	return cmp, true
+}
+
+// validateCoercions rejects filter values that cannot be coerced, since no
+// document could be compared with them
+func (qb *QueryBuilder) validateCoercions() error {
+	for _, filter := range qb.filters {
+		if !qb.isCoercedComparison(filter) {
+			continue
+		}
+		c := qb.coercions[filter.Field]
+		values := []interface{}{filter.Value}
+		if filter.Operator == In || filter.Operator == NotIn {
+			items, ok := filter.Value.([]interface{})
+			if !ok {
+				return fmt.Errorf("%w: operator %s needs a list on field '%s'", ErrInvalidFilter, filter.Operator, filter.Field)
+			}
+			values = items
+		}
+		for _, value := range values {
+			if _, ok := c.value(value); !ok {
+				return fmt.Errorf("%w: value %v of field '%s' is not a %s", ErrInvalidFilter, value, filter.Field, c.Type)
+			}
+		}
+	}
+	return nil
+}
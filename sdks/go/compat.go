@@ -0,0 +1,91 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Deprecation describes an API kept for compatibility and what replaces it
+type Deprecation struct {
+	API         string // The deprecated API
+	Replacement string // What to use instead
+	Note        string // Behaviour that differs between the two, if any
+}
+
+// deprecations lists the compatibility shims of this release
+var deprecations = []Deprecation{
+	{
+		API:         "NewClientFromURL(baseURL string)",
+		Replacement: "NewClient(&ClientOptions{BaseURL: baseURL})",
+		Note:        "NewClientFromURL keeps the 30s timeout of the old string constructor; ClientOptions.Timeout defaults to 5s, so set it to keep the old behaviour.",
+	},
+	{
+		API:         "Model interface (the constraint of Collection[T])",
+		Replacement: "Document",
+		Note:        "Types implementing GetID, SetID and ToMap need no change. Model names the schema-driven struct, so no alias can keep the old name; code that spells out the constraint must say Document.",
+	},
+	{
+		API:         "resty transport errors from Collection and MigrationManager",
+		Replacement: "errors wrapped as \"request failed: ...\"",
+		Note:        "Set ClientOptions.LegacyErrorStrings while code still matches on the unwrapped text. Prefer errors.Is and the error category helpers.",
+	},
+	{
+		API:         "Collection[T].Query(query map[string]interface{})",
+		Replacement: "Query().Filter(field, operator, value).Exec()",
+		Note:        "The package github.com/toonstore/torm-go/legacy keeps the old NewClient(baseURL), Model and map-based Query, so old code builds by changing its import path alone.",
+	},
+}
+
+// Deprecations reports the deprecated APIs and behaviours of the SDK, so
+// tooling can flag callers before the shims are removed
+func Deprecations() []Deprecation {
+	return append([]Deprecation(nil), deprecations...)
+}
+
+// bufferedResponse is a response read in full. It has the accessors the
+// resty-based Collection code was written against, so that code runs on the
+// same request path as Model.
+type bufferedResponse struct {
+	method     string
+	path       string
+	status     string
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+func (r *bufferedResponse) StatusCode() int { return r.statusCode }
+
+func (r *bufferedResponse) Status() string { return r.status }
+
+func (r *bufferedResponse) Body() []byte { return r.body }
+
+func (r *bufferedResponse) IsSuccess() bool { return r.statusCode >= 200 && r.statusCode < 300 }
+
+// Header returns the response headers
+func (r *bufferedResponse) Header() http.Header { return r.header }
+
+// call makes a JSON request and reads the whole response
+func (c *Client) call(method, path string, body interface{}) (*bufferedResponse, error) {
+	return c.callWithHeader(method, path, body, nil)
+}
+
+// callWithHeader is call with extra request headers
+func (c *Client) callWithHeader(method, path string, body interface{}, header http.Header) (*bufferedResponse, error) {
+	resp, err := c.requestJSON(method, path, body, header)
+	if err != nil {
+		if inner := errors.Unwrap(err); c.legacyErrorStrings && inner != nil {
+			return nil, inner
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return &bufferedResponse{method: method, path: path, status: resp.Status, statusCode: resp.StatusCode, header: resp.Header, body: data}, nil
+}
@@ -0,0 +1,198 @@
+package torm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// emailRegexp is emailPattern compiled once for compiled schemas
+var emailRegexp = regexp.MustCompile(emailPattern)
+
+// CreateOptions configures a single create
+type CreateOptions struct {
+	Validation ValidationProfile // How the schema is checked (default ValidationFastFail)
+}
+
+// CompileSchema prepares the model's schema for repeated validation:
+// patterns are compiled once, and each field's rules become a fixed list of
+// checks run in name order. Validation results are identical to the
+// uncompiled schema. Compile after the schema is final.
+func (m *Model) CompileSchema() *Model {
+	if m.schema == nil {
+		return m
+	}
+
+	compiled := &compiledSchema{fields: make([]compiledField, 0, len(m.schema))}
+	for field, rules := range m.schema {
+		compiled.fields = append(compiled.fields, compileField(field, rules))
+	}
+	sort.Slice(compiled.fields, func(i, j int) bool {
+		return compiled.fields[i].name < compiled.fields[j].name
+	})
+	m.compiled = compiled
+	return m
+}
+
+// CreateWith creates a document with per-call options
+func (m *Model) CreateWith(data map[string]interface{}, opts CreateOptions) (*WriteResult, error) {
+	return m.create(data, opts)
+}
+
+// compiledSchema is a schema resolved into checks
+type compiledSchema struct {
+	fields []compiledField
+}
+
+// fieldCheck checks a value, returning it in canonical form
+type fieldCheck func(value interface{}) (interface{}, error)
+
+type compiledField struct {
+	name     string
+	required bool
+	coerce   bool // The first check returns the value to store
+	checks   []fieldCheck
+}
+
+func (s *compiledSchema) validate(data map[string]interface{}, partial bool, profile ValidationProfile) error {
+	var errs []error
+	for i := range s.fields {
+		if err := s.fields[i].validate(data, partial); err != nil {
+			if profile == ValidationFastFail {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return joinValidation(errs)
+}
+
+func (f *compiledField) validate(data map[string]interface{}, partial bool) error {
+	value, exists := data[f.name]
+	if !exists {
+		if f.required && !partial {
+			return fmt.Errorf("%w: field '%s' is required", ErrValidation, f.name)
+		}
+		return nil
+	}
+
+	for i, check := range f.checks {
+		checked, err := check(value)
+		if err != nil {
+			return err
+		}
+		if i == 0 && f.coerce {
+			data[f.name] = checked
+			value = checked
+		}
+	}
+	return nil
+}
+
+// compileField resolves a rule into checks in the order validateField
+// applies them
+func compileField(field string, rules ValidationRule) compiledField {
+	compiled := compiledField{name: field, required: rules.Required}
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: field '%s' "+format, append([]interface{}{ErrValidation, field}, args...)...)
+	}
+
+	if rules.Type == "decimal" {
+		compiled.coerce = rules.Coerce
+		compiled.checks = append(compiled.checks, func(value interface{}) (interface{}, error) {
+			normalized, err := rules.checkDecimal(value)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			if rules.Coerce {
+				return normalized, nil
+			}
+			return value, nil
+		})
+	} else if rules.Type != "" {
+		typ := rules.Type
+		compiled.checks = append(compiled.checks, func(value interface{}) (interface{}, error) {
+			if err := checkType(value, typ); err != nil {
+				return nil, fail("%v", err)
+			}
+			return value, nil
+		})
+	}
+
+	if check := compileStringRules(rules, fail); check != nil {
+		compiled.checks = append(compiled.checks, check)
+	}
+
+	if rules.Min != nil || rules.Max != nil {
+		min, max := rules.Min, rules.Max
+		compiled.checks = append(compiled.checks, func(value interface{}) (interface{}, error) {
+			num, ok := toFloat64(value)
+			if !ok {
+				return value, nil
+			}
+			if min != nil && num < *min {
+				return nil, fail("must be at least %v", *min)
+			}
+			if max != nil && num > *max {
+				return nil, fail("must be at most %v", *max)
+			}
+			return value, nil
+		})
+	}
+
+	if rules.Validate != nil {
+		custom := rules.Validate
+		compiled.checks = append(compiled.checks, func(value interface{}) (interface{}, error) {
+			valid := false
+			if err := protect("validator of "+field, "", "", func() { valid = custom(value) }); err != nil {
+				return nil, err
+			}
+			if !valid {
+				return nil, fail("failed custom validation")
+			}
+			return value, nil
+		})
+	}
+
+	return compiled
+}
+
+// compileStringRules returns one check for a rule's string constraints, or
+// nil when it has none
+func compileStringRules(rules ValidationRule, fail func(string, ...interface{}) error) fieldCheck {
+	if rules.MinLength == nil && rules.MaxLength == nil && !rules.Email && !rules.URL && rules.Pattern == "" {
+		return nil
+	}
+
+	minLength, maxLength := rules.MinLength, rules.MaxLength
+	email, url := rules.Email, rules.URL
+	var pattern *regexp.Regexp
+	invalidPattern := false
+	if rules.Pattern != "" {
+		compiled, err := regexp.Compile(rules.Pattern)
+		pattern, invalidPattern = compiled, err != nil
+	}
+
+	return func(value interface{}) (interface{}, error) {
+		str, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		if minLength != nil && len(str) < *minLength {
+			return nil, fail("must be at least %d characters", *minLength)
+		}
+		if maxLength != nil && len(str) > *maxLength {
+			return nil, fail("must be at most %d characters", *maxLength)
+		}
+		if email && !emailRegexp.MatchString(str) {
+			return nil, fail("must be a valid email")
+		}
+		if url && !isURL(str) {
+			return nil, fail("must be a valid URL")
+		}
+		if invalidPattern || (pattern != nil && !pattern.MatchString(str)) {
+			return nil, fail("does not match pattern")
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,125 @@
+package torm
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOverloaded marks requests the server rejected with 429 or 503
+var ErrOverloaded = errors.New("torm: server overloaded")
+
+// AdaptiveConcurrency sizes the worker pool of a bulk operation from server
+// feedback. The pool starts at Min and grows by one each time a full
+// window of requests succeeds within LatencyTarget; a slow response or a
+// 429/503 halves it (AIMD). Latency is measured with the client's clock.
+type AdaptiveConcurrency struct {
+	Min           int               // Floor and starting size (default 1)
+	Max           int               // Hard ceiling (default 16)
+	LatencyTarget time.Duration     // Slower responses count as congestion (default 1s)
+	OnChange      func(current int) // Metrics hook, called whenever the size changes
+}
+
+// adaptiveLimiter bounds in-flight requests with an AIMD limit
+type adaptiveLimiter struct {
+	cfg   AdaptiveConcurrency
+	clock func() time.Time
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	inFlight  int
+	successes int // Fast successes since the limit last changed
+	epoch     int // Bumped on every change so one burst of failures backs off once
+}
+
+func newAdaptiveLimiter(cfg *AdaptiveConcurrency, clock func() time.Time) *adaptiveLimiter {
+	l := &adaptiveLimiter{clock: clock}
+	if cfg != nil {
+		l.cfg = *cfg
+		if l.cfg.Min <= 0 {
+			l.cfg.Min = 1
+		}
+		if l.cfg.Max <= 0 {
+			l.cfg.Max = 16
+		}
+		if l.cfg.Max < l.cfg.Min {
+			l.cfg.Max = l.cfg.Min
+		}
+		if l.cfg.LatencyTarget <= 0 {
+			l.cfg.LatencyTarget = time.Second
+		}
+	} else {
+		// Without configuration bulk operations run one request at a time
+		l.cfg = AdaptiveConcurrency{Min: 1, Max: 1, LatencyTarget: time.Second}
+	}
+	l.cond = sync.NewCond(&l.mu)
+	l.limit = l.cfg.Min
+	return l
+}
+
+// run calls do for 0..n-1, starting them in order with at most the current
+// limit in flight, and returns once all have finished
+func (l *adaptiveLimiter) run(n int, do func(i int) error) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		epoch := l.acquire()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := l.clock()
+			err := do(i)
+			l.release(epoch, l.clock().Sub(start), err)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (l *adaptiveLimiter) acquire() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+	return l.epoch
+}
+
+func (l *adaptiveLimiter) release(epoch int, latency time.Duration, err error) {
+	l.mu.Lock()
+	l.inFlight--
+
+	next := l.limit
+	switch {
+	case errors.Is(err, ErrOverloaded) || latency > l.cfg.LatencyTarget:
+		// Requests started before the last change report stale congestion
+		if epoch == l.epoch {
+			next = l.limit / 2
+		}
+	case err == nil:
+		l.successes++
+		if l.successes >= l.limit {
+			next = l.limit + 1
+		}
+	}
+	if next < l.cfg.Min {
+		next = l.cfg.Min
+	}
+	if next > l.cfg.Max {
+		next = l.cfg.Max
+	}
+
+	changed := next != l.limit
+	if changed {
+		l.limit = next
+		l.successes = 0
+		l.epoch++
+	}
+	l.cond.Broadcast()
+	l.mu.Unlock()
+
+	if changed && l.cfg.OnChange != nil {
+		l.cfg.OnChange(next)
+	}
+}
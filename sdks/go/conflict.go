@@ -0,0 +1,98 @@
+package torm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrConflict matches any conflict reported by the server (HTTP 409)
+var ErrConflict = errors.New("torm: conflict")
+
+// ConflictError carries the server's detail for an HTTP 409 response
+type ConflictError struct {
+	Collection string
+	Field      string // Field that violated a unique constraint, when reported
+	ExistingID string // Id of the document already holding the value, when reported
+	Message    string
+
+	api *APIError
+}
+
+func (e *ConflictError) Error() string {
+	msg := fmt.Sprintf("conflict in collection %s", e.Collection)
+	if e.Field != "" {
+		msg += fmt.Sprintf(" on field '%s'", e.Field)
+	}
+	if e.ExistingID != "" {
+		msg += fmt.Sprintf(" with existing document %s", e.ExistingID)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrConflict) match
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Unwrap returns the APIError of the 409 response, so errors.As finds it
+func (e *ConflictError) Unwrap() error {
+	if e.api == nil {
+		return nil
+	}
+	return e.api
+}
+
+// ConflictPolicy controls how Create reacts to a conflict
+type ConflictPolicy int
+
+const (
+	// OnConflictError returns the *ConflictError to the caller
+	OnConflictError ConflictPolicy = iota
+	// OnConflictIgnore leaves the stored document untouched and returns the input
+	OnConflictIgnore
+	// OnConflictOverwrite replaces the stored document with the input
+	OnConflictOverwrite
+)
+
+// WithOnConflict sets the conflict policy used by Create
+func WithOnConflict(policy ConflictPolicy) CollectionOption {
+	return func(o *collectionOptions) {
+		o.onConflict = policy
+	}
+}
+
+// parseConflict builds a ConflictError from a 409 response, whose body may
+// or may not be structured JSON
+func parseConflict(collection string, api *APIError) *ConflictError {
+	conflict := &ConflictError{Collection: collection, api: api}
+	body := api.RawBody
+
+	var detail map[string]interface{}
+	if err := json.Unmarshal(body, &detail); err != nil {
+		conflict.Message = strings.TrimSpace(string(body))
+		return conflict
+	}
+
+	for _, key := range []string{"error", "message"} {
+		if msg, ok := detail[key].(string); ok && msg != "" {
+			conflict.Message = msg
+			break
+		}
+	}
+	if field, ok := detail["field"].(string); ok {
+		conflict.Field = field
+	}
+	for _, key := range []string{"existing_id", "existingId", "id"} {
+		if id, ok := detail[key].(string); ok && id != "" {
+			conflict.ExistingID = id
+			break
+		}
+	}
+
+	return conflict
+}
@@ -0,0 +1,150 @@
+package torm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// ContractError is returned in strict mode when a response does not have
+// the shape the SDK expects from the server
+type ContractError struct {
+	Operation string // create, update, find, list, query, count or delete
+	Key       string // Offending key, empty when the body itself is malformed
+	Problem   string // e.g. "is missing" or "should be array, got string"
+	Snippet   string // Start of the response body
+}
+
+func (e *ContractError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("torm: %s response violates contract: %s (body: %s)", e.Operation, e.Problem, e.Snippet)
+	}
+	return fmt.Sprintf("torm: %s response violates contract: key '%s' %s (body: %s)", e.Operation, e.Key, e.Problem, e.Snippet)
+}
+
+// WithStrictResponses validates every response of the collection against the
+// expected shape and fails with a ContractError instead of decoding zero
+// values. ClientOptions.StrictResponses enables the same for a whole client.
+func WithStrictResponses() CollectionOption {
+	return func(o *collectionOptions) {
+		o.strictResponses = true
+	}
+}
+
+// jsonKind is the JSON type of a response value
+type jsonKind string
+
+const (
+	jsonBoolean jsonKind = "boolean"
+	jsonString  jsonKind = "string"
+	jsonNumber  jsonKind = "number"
+	jsonObject  jsonKind = "object"
+	jsonArray   jsonKind = "array"
+	jsonNull    jsonKind = "null"
+)
+
+// responseShape lists the keys a response must carry and their kinds.
+// Unknown extra keys are always allowed.
+type responseShape map[string]jsonKind
+
+var (
+	writeShape  = responseShape{"success": jsonBoolean, "id": jsonString, "data": jsonObject}
+	listShape   = responseShape{"documents": jsonArray, "count": jsonNumber}
+	countShape  = responseShape{"count": jsonNumber}
+	deleteShape = responseShape{"success": jsonBoolean}
+	// A single document only has to be an object
+	documentShape = responseShape{}
+)
+
+// checkContract validates a response body against a shape
+func checkContract(operation string, body []byte, shape responseShape) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return &ContractError{Operation: operation, Problem: "body is not a JSON object", Snippet: bodySnippet(body)}
+	}
+
+	keys := make([]string, 0, len(shape))
+	for key := range shape {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, ok := fields[key]
+		if !ok {
+			return &ContractError{Operation: operation, Key: key, Problem: "is missing", Snippet: bodySnippet(body)}
+		}
+		if got := kindOf(value); got != shape[key] {
+			return &ContractError{
+				Operation: operation,
+				Key:       key,
+				Problem:   fmt.Sprintf("should be %s, got %s", shape[key], got),
+				Snippet:   bodySnippet(body),
+			}
+		}
+	}
+
+	return nil
+}
+
+func kindOf(value interface{}) jsonKind {
+	switch value.(type) {
+	case bool:
+		return jsonBoolean
+	case string:
+		return jsonString
+	case float64:
+		return jsonNumber
+	case map[string]interface{}:
+		return jsonObject
+	case []interface{}:
+		return jsonArray
+	default:
+		return jsonNull
+	}
+}
+
+// bodySnippet shortens a body for error messages
+func bodySnippet(body []byte) string {
+	const max = 200
+	if len(body) > max {
+		return string(body[:max]) + "..."
+	}
+	return string(body)
+}
+
+// decodeResponse reads a JSON response into result, checking its shape
+// first when the client is strict. Responses without a document (202, 204
+// or an empty body) leave result untouched.
+func (c *Client) decodeResponse(operation string, resp *http.Response, shape responseShape, result interface{}) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Accepted and empty responses leave result untouched
+	if !hasDocument(resp.StatusCode, body) {
+		return nil
+	}
+
+	if c.strictResponses {
+		if err := checkContract(operation, body, shape); err != nil {
+			return err
+		}
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// checkContract validates a collection response when strict mode is on
+func (c *Collection[T]) checkContract(operation string, body []byte, shape responseShape) error {
+	if !c.options.strictResponses && !c.client.strictResponses {
+		return nil
+	}
+	return checkContract(operation, body, shape)
+}
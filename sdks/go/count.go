@@ -0,0 +1,270 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CountConsistency is how current a count must be
+type CountConsistency string
+
+const (
+	// CountShared may return a result shared with other callers, as
+	// configured by WithMicroCache (the default)
+	CountShared CountConsistency = ""
+	// CountFresh asks the server every time
+	CountFresh CountConsistency = "fresh"
+	// CountExact counts the documents a query returns instead of trusting
+	// the server's count endpoint
+	CountExact CountConsistency = "exact"
+)
+
+// CountOptions configures CountWith. Count is CountWith with only
+// IncludeDeleted set.
+type CountOptions struct {
+	Filters []QueryFilter // Only count matching documents
+	// IncludeDeleted counts soft-deleted documents too. Without it
+	// documents with DeletedField set are skipped, which needs an exact count.
+	IncludeDeleted bool
+	DeletedField   string // Soft-delete marker field (default "deletedAt")
+	Consistency    CountConsistency
+}
+
+// endpoint reports whether the server's count endpoint answers the count
+func (o CountOptions) endpoint() bool {
+	return len(o.Filters) == 0 && o.IncludeDeleted && o.Consistency != CountExact
+}
+
+func (o CountOptions) deletedField() string {
+	if o.DeletedField == "" {
+		return "deletedAt"
+	}
+	return o.DeletedField
+}
+
+// counter is the one implementation behind the Count methods of
+// Collection and Model
+type counter struct {
+	client     *Client
+	collection string
+	window     time.Duration       // Sharing of endpoint results, see WithMicroCache
+	endpoint   func() (int, error) // The server's count of the whole collection
+	query      func() *QueryBuilder
+}
+
+func (k counter) count(ctx context.Context, opts CountOptions) (int, error) {
+	if opts.endpoint() {
+		if opts.Consistency == CountFresh {
+			return k.endpoint()
+		}
+		return k.client.sharedCount(k.collection, k.window, k.endpoint)
+	}
+	qb := k.query()
+	qb.Match(opts.Filters...)
+	return countQuery(ctx, qb, opts)
+}
+
+// countQuery counts the results of a query a page at a time, fetching only
+// the fields needed. Errors name the query as given.
+func countQuery(ctx context.Context, qb *QueryBuilder, opts CountOptions) (int, error) {
+	counted := *qb
+	deleted := opts.deletedField()
+	if opts.IncludeDeleted {
+		counted.fields = []string{"id"}
+	} else {
+		counted.fields = []string{deleted}
+	}
+	skip, remaining := 0, -1
+	if qb.skipVal != nil {
+		skip = *qb.skipVal
+	}
+	if qb.limitVal != nil {
+		remaining = *qb.limitVal
+	}
+
+	n := 0
+	for remaining != 0 {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		size := iterPageSize
+		if remaining > 0 && remaining < size {
+			size = remaining
+		}
+		docs, err := counted.pageAt(skip, size).exec()
+		if err != nil {
+			return n, fmt.Errorf("%w (query: %s)", err, qb)
+		}
+		for _, doc := range docs {
+			if value, ok := doc[deleted]; !opts.IncludeDeleted && ok && value != nil && value != "" {
+				continue
+			}
+			n++
+		}
+		if len(docs) < size {
+			break
+		}
+		skip += size
+		if remaining > 0 {
+			remaining -= size
+		}
+	}
+	return n, nil
+}
+
+// CountWith counts the collection's documents as configured by opts. The
+// server's count endpoint is used when it can answer; filters, skipping
+// soft-deleted documents and CountExact count query results instead.
+func (c *Collection[T]) CountWith(ctx context.Context, opts CountOptions) (n int, err error) {
+	timer := c.timeOp(OpCount).counting(func() int { return n })
+	defer timer.end(&err)
+	return c.traced(ctx, timer).counter().count(ctx, opts)
+}
+
+// CountWith counts the model's documents as configured by opts; see
+// Collection.CountWith
+func (m *Model) CountWith(ctx context.Context, opts CountOptions) (n int, err error) {
+	timer := m.client.timeOp(OpCount, m.collection, m.slowThreshold).counting(func() int { return n })
+	defer timer.end(&err)
+	return m.traced(ctx, timer).counter().count(ctx, opts)
+}
+
+func (c *Collection[T]) counter() counter {
+	return counter{client: c.client, collection: c.collection, window: c.countSharing(), endpoint: c.count, query: c.model().Query}
+}
+
+func (m *Model) counter() counter {
+	return counter{client: m.client, collection: m.collection, window: m.countSharing(), endpoint: m.count, query: m.Query}
+}
+
+// CountReport compares the server's count of a collection with the
+// documents its listing returns
+type CountReport struct {
+	Collection string
+	Server     int  // What the count endpoint reports
+	Listed     int  // Documents the listing returns
+	Delta      int  // Listed - Server
+	Diverged   bool // The two disagree, e.g. because a server index is corrupt
+	Sampled    bool // Listed was found by probing, see VerifyCountOptions
+	Requests   int  // Listing requests made
+}
+
+func (r *CountReport) String() string {
+	if !r.Diverged {
+		return fmt.Sprintf("count of %s agrees: %d", r.Collection, r.Server)
+	}
+	return fmt.Sprintf("count of %s diverges: server reports %d, listing has %d (delta %+d)", r.Collection, r.Server, r.Listed, r.Delta)
+}
+
+// VerifyCountOptions configures VerifyCount
+type VerifyCountOptions struct {
+	// Sampled finds the length of the listing by probing single documents
+	// at chosen offsets, a few requests even for large collections, instead
+	// of reading every page
+	Sampled bool
+}
+
+// VerifyCount is a diagnostic comparing the server's count endpoint with
+// an exact count of the documents the listing returns, soft-deleted ones
+// included, to detect corrupt server indexes
+func (c *Collection[T]) VerifyCount(ctx context.Context, opts VerifyCountOptions) (*CountReport, error) {
+	c = c.prioritizedBy(ctx)
+	return verifyCount(ctx, c.collection, c.count, c.model().Query, opts)
+}
+
+// VerifyCount compares the model's count endpoint with its listing; see
+// Collection.VerifyCount
+func (m *Model) VerifyCount(ctx context.Context, opts VerifyCountOptions) (*CountReport, error) {
+	m = m.prioritizedBy(ctx)
+	return verifyCount(ctx, m.collection, m.count, m.Query, opts)
+}
+
+func verifyCount(ctx context.Context, collection string, endpoint func() (int, error), query func() *QueryBuilder, opts VerifyCountOptions) (*CountReport, error) {
+	server, err := endpoint()
+	if err != nil {
+		return nil, fmt.Errorf("count of %s failed: %w", collection, err)
+	}
+	report := &CountReport{Collection: collection, Server: server, Sampled: opts.Sampled}
+
+	if opts.Sampled {
+		report.Listed, report.Requests, err = probeLength(ctx, query, server)
+	} else {
+		report.Listed, report.Requests, err = readLength(ctx, query())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing of %s failed: %w", collection, err)
+	}
+	report.Delta = report.Listed - report.Server
+	report.Diverged = report.Delta != 0
+	return report, nil
+}
+
+// readLength counts every document of the listing
+func readLength(ctx context.Context, qb *QueryBuilder) (int, int, error) {
+	n, err := countQuery(ctx, qb, CountOptions{IncludeDeleted: true})
+	return n, n/iterPageSize + 1, err
+}
+
+// probeLength finds the length of the listing by checking for a document
+// at an offset: around the expected length first, then by galloping and
+// binary search when it is wrong
+func probeLength(ctx context.Context, query func() *QueryBuilder, expected int) (int, int, error) {
+	requests := 0
+	exists := func(offset int) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		requests++
+		docs, err := query().Select("id").pageAt(offset, 1).Exec()
+		return len(docs) > 0, err
+	}
+
+	// lo is an offset known to hold a document (-1 for none), hi one known
+	// to be past the end
+	lo, hi := -1, -1
+	atEnd, err := exists(expected)
+	if err != nil {
+		return 0, requests, err
+	}
+	if atEnd {
+		lo = expected
+		for step := expected + 1; hi < 0; step *= 2 {
+			found, err := exists(lo + step)
+			if err != nil {
+				return 0, requests, err
+			}
+			if found {
+				lo += step
+			} else {
+				hi = lo + step
+			}
+		}
+	} else {
+		hi = expected
+		if expected > 0 {
+			found, err := exists(expected - 1)
+			if err != nil {
+				return 0, requests, err
+			}
+			if found {
+				return expected, requests, nil
+			}
+			hi = expected - 1
+		}
+	}
+
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		found, err := exists(mid)
+		if err != nil {
+			return 0, requests, err
+		}
+		if found {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo + 1, requests, nil
+}
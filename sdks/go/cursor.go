@@ -0,0 +1,197 @@
+package torm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCursorMismatch is returned when a cursor is used with a query of
+	// another shape than the one that produced it; the error says what changed
+	ErrCursorMismatch = errors.New("torm: cursor does not match query")
+	// ErrCursorExpired is returned for a cursor past its PageOptions.TTL
+	ErrCursorExpired = errors.New("torm: cursor expired")
+	// ErrInvalidCursor is returned for a cursor that cannot be decoded, was
+	// altered, or has a format version this SDK does not read
+	ErrInvalidCursor = errors.New("torm: invalid cursor")
+)
+
+// cursorVersion is the format of the cursors ExecPage writes
+const cursorVersion = 1
+
+// PageOptions configures ExecPage
+type PageOptions struct {
+	Size   int           // Documents per page (default the query's Limit, else 50)
+	Cursor string        // Next cursor of the previous page; empty for the first page
+	TTL    time.Duration // How long the returned cursor stays usable (0 never expires)
+}
+
+// Page is one page of ExecPage
+type Page struct {
+	Documents []map[string]interface{}
+	// Next is the cursor of the following page, empty on the last page.
+	// It is URL-safe.
+	Next string
+}
+
+// cursorState is what a cursor carries. The query shape is kept as
+// fingerprints so that filter values never appear in the cursor.
+type cursorState struct {
+	Version    int    `json:"v"`
+	Collection string `json:"c"`
+	Filters    string `json:"f"`
+	Sort       string `json:"s"`
+	Fields     string `json:"p"`
+	Offset     int    `json:"o"`
+	Expires    int64  `json:"e,omitempty"` // Unix seconds
+}
+
+// ExecPage executes the query one page at a time. The cursor of each page
+// records the shape of the query (filters, sort and projection); using it
+// with a query of another shape fails with ErrCursorMismatch rather than
+// returning pages of a different result. ExecPage replaces any Skip.
+func (qb *QueryBuilder) ExecPage(opts PageOptions) (*Page, error) {
+	size := opts.Size
+	if size <= 0 && qb.limitVal != nil {
+		size = *qb.limitVal
+	}
+	if size <= 0 {
+		size = 50
+	}
+
+	shape := qb.cursorShape()
+	offset := 0
+	if opts.Cursor != "" {
+		state, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if err := state.matches(shape); err != nil {
+			return nil, err
+		}
+		if state.Expires != 0 && !qb.client.now().Before(time.Unix(state.Expires, 0)) {
+			return nil, fmt.Errorf("%w: at %s", ErrCursorExpired, time.Unix(state.Expires, 0).UTC().Format(time.RFC3339))
+		}
+		offset = state.Offset
+	}
+
+	page := *qb
+	page.Skip(offset)
+	page.Limit(size + 1) // One more tells whether a next page exists
+	docs, err := page.Exec()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Page{Documents: docs}
+	if len(docs) > size {
+		result.Documents = docs[:size]
+		next := shape
+		next.Offset = offset + size
+		if opts.TTL > 0 {
+			next.Expires = qb.client.now().Add(opts.TTL).Unix()
+		}
+		if result.Next, err = next.encode(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// cursorShape fingerprints the parts of the query a cursor depends on
+func (qb *QueryBuilder) cursorShape() cursorState {
+	filters := make([]string, len(qb.filters))
+	for i, filter := range qb.filters {
+		raw, _ := json.Marshal(filter)
+		filters[i] = string(raw)
+	}
+	sort.Strings(filters) // Filters combine with AND, so their order is irrelevant
+
+	sortKeys := make([]string, 0, 2)
+	for _, key := range qb.sortKeys() {
+		sortKeys = append(sortKeys, key.Field+" "+string(key.Order)+key.options())
+	}
+
+	fields := append([]string(nil), qb.fields...)
+	sort.Strings(fields)
+
+	return cursorState{
+		Version:    cursorVersion,
+		Collection: qb.collection,
+		Filters:    fingerprint(filters),
+		Sort:       strings.Join(sortKeys, ", "),
+		Fields:     fingerprint(uniqueFields(fields)),
+	}
+}
+
+func fingerprint(parts []string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// matches describes how the query differs from the one the cursor was
+// made for
+func (s cursorState) matches(shape cursorState) error {
+	var changes []string
+	if s.Collection != shape.Collection {
+		changes = append(changes, fmt.Sprintf("collection changed from %s to %s", s.Collection, shape.Collection))
+	}
+	if s.Filters != shape.Filters {
+		changes = append(changes, "filters changed")
+	}
+	if s.Sort != shape.Sort {
+		changes = append(changes, fmt.Sprintf("sort changed from %q to %q", s.Sort, shape.Sort))
+	}
+	if s.Fields != shape.Fields {
+		changes = append(changes, "selected fields changed")
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%w: %s", ErrCursorMismatch, strings.Join(changes, ", "))
+	}
+	return nil
+}
+
+// encode writes the state as base64url JSON followed by a checksum that
+// catches edited cursors. The checksum is not a signature: cursors carry
+// nothing a client could not request directly.
+func (s cursorState) encode() (string, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(append(raw, sum[:6]...)), nil
+}
+
+func decodeCursor(cursor string) (cursorState, error) {
+	var state cursorState
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) <= 6 {
+		return state, fmt.Errorf("%w: not a cursor", ErrInvalidCursor)
+	}
+	raw, sum := data[:len(data)-6], data[len(data)-6:]
+	if want := sha256.Sum256(raw); !bytes.Equal(sum, want[:6]) {
+		return state, fmt.Errorf("%w: checksum mismatch", ErrInvalidCursor)
+	}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return state, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if state.Version != cursorVersion {
+		return state, fmt.Errorf("%w: format version %d, expected %d", ErrInvalidCursor, state.Version, cursorVersion)
+	}
+	if state.Offset < 0 {
+		return state, fmt.Errorf("%w: negative offset", ErrInvalidCursor)
+	}
+	return state, nil
+}
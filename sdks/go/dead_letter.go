@@ -0,0 +1,373 @@
+package torm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter is an item a bulk operation failed to write, kept so it can
+// be inspected and replayed
+type DeadLetter struct {
+	ID         string                 `json:"id"`
+	Collection string                 `json:"collection"`
+	Operation  Operation              `json:"operation"` // OpCreate, OpUpdate or OpDelete
+	DocID      string                 `json:"doc_id,omitempty"`
+	Doc        map[string]interface{} `json:"doc,omitempty"` // Application form of the document; see WithRedaction
+	Error      string                 `json:"error"`
+	Attempts   int                    `json:"attempts"` // Writes tried so far, including replays
+	FailedAt   time.Time              `json:"failed_at"`
+}
+
+// DeadLetterSink receives the items bulk operations fail to write
+type DeadLetterSink interface {
+	Capture(entry DeadLetter) error
+}
+
+// DeadLetterStore is a sink that can also list and remove its entries, as
+// ReplayDeadLetters needs. Capturing an entry with an existing ID
+// replaces it.
+type DeadLetterStore interface {
+	DeadLetterSink
+	List() ([]DeadLetter, error)
+	Remove(id string) error
+}
+
+// WithDeadLetter captures the items the collection's bulk operations fail
+// to write (CreateMany and DeleteWhere) in sink, so they survive the
+// process and can be replayed with ReplayDeadLetters
+func WithDeadLetter(sink DeadLetterSink) CollectionOption {
+	return func(o *collectionOptions) {
+		o.deadLetter = sink
+	}
+}
+
+// WithDeadLetter captures the documents ImportSnapshot fails to write in
+// sink. The import then continues past them instead of stopping.
+func (m *Model) WithDeadLetter(sink DeadLetterSink) *Model {
+	m.deadLetter = sink
+	return m
+}
+
+// ReplayReport summarizes a ReplayDeadLetters run
+type ReplayReport struct {
+	Replayed int      // Items written and removed from the store
+	Failed   int      // Items that failed again; their attempt counts were raised
+	Errors   []string // Error of each failed item
+}
+
+// ReplayDeadLetters retries the dead-lettered items of the collection that
+// filter accepts (all when nil), removing each one that succeeds. Creates
+// are replayed as upserts, so replaying twice is harmless.
+func (c *Collection[T]) ReplayDeadLetters(ctx context.Context, filter func(DeadLetter) bool) (*ReplayReport, error) {
+	c = c.prioritizedBy(ctx)
+	return replayDeadLetters(ctx, c.options.deadLetter, c.collection, c.client.now, filter, func(entry DeadLetter) error {
+		switch entry.Operation {
+		case OpDelete:
+			return c.Delete(entry.DocID)
+		case OpUpdate:
+			_, err := c.replace(entry.DocID, entry.Doc, nil)
+			return err
+		}
+		raw, err := json.Marshal(entry.Doc)
+		if err != nil {
+			return err
+		}
+		data := c.factory()
+		if err := hydrateJSON(raw, &data); err != nil {
+			return fmt.Errorf("dead-lettered document does not fit the model: %w", err)
+		}
+		_, err = c.Upsert(data)
+		return err
+	})
+}
+
+// ReplayDeadLetters retries the dead-lettered items of the model's
+// collection, as the collection method does
+func (m *Model) ReplayDeadLetters(ctx context.Context, filter func(DeadLetter) bool) (*ReplayReport, error) {
+	m = m.prioritizedBy(ctx)
+	return replayDeadLetters(ctx, m.deadLetter, m.collection, m.client.now, filter, func(entry DeadLetter) error {
+		switch entry.Operation {
+		case OpDelete:
+			_, err := m.Delete(entry.DocID)
+			return err
+		case OpUpdate:
+			_, err := m.Update(entry.DocID, entry.Doc)
+			return err
+		}
+		return m.importDocument(entry.Doc)
+	})
+}
+
+func replayDeadLetters(ctx context.Context, sink DeadLetterSink, collection string, now func() time.Time,
+	filter func(DeadLetter) bool, apply func(DeadLetter) error) (*ReplayReport, error) {
+	store, ok := sink.(DeadLetterStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: dead-letter sink cannot be listed", ErrNotSupported)
+	}
+	entries, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	report := &ReplayReport{}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if entry.Collection != collection {
+			continue
+		}
+		if filter != nil {
+			accepted := false
+			if err := protect("dead letter filter", collection, entry.DocID, func() { accepted = filter(entry) }); err != nil {
+				return report, err
+			}
+			if !accepted {
+				continue
+			}
+		}
+
+		if err := apply(entry); err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", entry.ID, err))
+			entry.Attempts++
+			entry.Error = err.Error()
+			entry.FailedAt = now()
+			if err := store.Capture(entry); err != nil {
+				return report, fmt.Errorf("failed to update dead letter %s: %w", entry.ID, err)
+			}
+			continue
+		}
+		if err := store.Remove(entry.ID); err != nil {
+			return report, fmt.Errorf("failed to remove dead letter %s: %w", entry.ID, err)
+		}
+		report.Replayed++
+	}
+	return report, nil
+}
+
+// newDeadLetter describes a failed write. Entries for the same document
+// and operation share an ID, so a later failure replaces an earlier one.
+func newDeadLetter(collection string, op Operation, doc map[string]interface{}, id string, cause error, at time.Time) DeadLetter {
+	key := id
+	if key == "" {
+		raw, _ := json.Marshal(doc)
+		sum := sha256.Sum256(raw)
+		key = hex.EncodeToString(sum[:8])
+	}
+	return DeadLetter{
+		ID:         fmt.Sprintf("deadletter:%s:%s:%s", collection, op, key),
+		Collection: collection,
+		Operation:  op,
+		DocID:      id,
+		Doc:        doc,
+		Error:      cause.Error(),
+		Attempts:   1,
+		FailedAt:   at,
+	}
+}
+
+// redacted masks the sensitive values the error echoes. The document is
+// only masked for sinks that cannot be replayed from, as replaying needs
+// it whole.
+func (d DeadLetter) redacted(r redactor, sink DeadLetterSink) DeadLetter {
+	d.Error = r.text(d.Error, d.Doc)
+	if _, replayable := sink.(DeadLetterStore); !replayable {
+		d.Doc = r.doc(d.Doc)
+	}
+	return d
+}
+
+// captureDeadLetter stores a failed write in sink, returning the error to
+// report for the item: the write's own, joined with the capture failure
+func captureDeadLetter(sink DeadLetterSink, entry DeadLetter, cause error) (bool, error) {
+	if sink == nil {
+		return false, cause
+	}
+	if err := sink.Capture(entry); err != nil {
+		return false, errors.Join(cause, fmt.Errorf("failed to dead-letter: %w", err))
+	}
+	return true, cause
+}
+
+// DeadLetterCollection keeps dead letters as documents of a ToonStore
+// collection, e.g. "dead_letters"
+func DeadLetterCollection(client *Client, collection string) DeadLetterStore {
+	model := client.Model(collection, nil).WithDefaultOrder("failed_at", Asc)
+	return &deadLetterCollection{model: model}
+}
+
+type deadLetterCollection struct {
+	model *Model
+}
+
+func (d *deadLetterCollection) Capture(entry DeadLetter) error {
+	doc, err := deadLetterDoc(entry)
+	if err != nil {
+		return err
+	}
+	return d.model.importDocument(doc)
+}
+
+func (d *deadLetterCollection) List() ([]DeadLetter, error) {
+	docs, err := d.model.Find()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]DeadLetter, 0, len(docs))
+	for _, doc := range docs {
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var entry DeadLetter
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter %v: %w", doc["id"], err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (d *deadLetterCollection) Remove(id string) error {
+	_, err := d.model.Delete(id)
+	return err
+}
+
+func deadLetterDoc(entry DeadLetter) (map[string]interface{}, error) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	err = json.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+// DeadLetterFile keeps dead letters as JSON lines in a local file.
+// Captures append; removals rewrite the file.
+func DeadLetterFile(path string) DeadLetterStore {
+	return &deadLetterFile{path: path}
+}
+
+type deadLetterFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (d *deadLetterFile) Capture(entry DeadLetter) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file, err := os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write dead-letter file: %w", err)
+	}
+	return file.Close()
+}
+
+func (d *deadLetterFile) List() ([]DeadLetter, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.read()
+}
+
+// read returns the latest entry of each ID, in the order IDs first appear
+func (d *deadLetterFile) read() ([]DeadLetter, error) {
+	data, err := os.ReadFile(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter file: %w", err)
+	}
+
+	var entries []DeadLetter
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode dead-letter line %d: %w", line, err)
+		}
+		if i, seen := index[entry.ID]; seen {
+			entries[i] = entry
+			continue
+		}
+		index[entry.ID] = len(entries)
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func (d *deadLetterFile) Remove(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries, err := d.read()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if entry.ID == id {
+			continue
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+
+	// Write then rename so a crash never leaves a torn file behind
+	tmp := d.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write dead-letter file: %w", err)
+	}
+	return os.Rename(tmp, d.path)
+}
+
+// DeadLetterWriter writes dead letters as JSON lines to w. It cannot be
+// replayed from; use DeadLetterFile for that.
+func DeadLetterWriter(w io.Writer) DeadLetterSink {
+	return &deadLetterWriter{w: w}
+}
+
+type deadLetterWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (d *deadLetterWriter) Capture(entry DeadLetter) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err = d.w.Write(append(line, '\n'))
+	return err
+}
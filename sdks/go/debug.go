@@ -0,0 +1,255 @@
+package torm
+
+import (
+	"net/url"
+	"time"
+)
+
+// DebugSnapshot is a dump of the client's internal state for debug
+// endpoints and incident reports. It marshals to JSON as is. Secrets are
+// left out: Auth only names the schemes in use, endpoints lose their user
+// info, queries are redacted as in errors (see ClientOptions.RedactQuery)
+// and slow operations keep the category of their error rather than its
+// message, which may echo document values.
+type DebugSnapshot struct {
+	At             time.Time                     `json:"at"`
+	Endpoints      []string                      `json:"endpoints"`
+	LastEndpoint   string                        `json:"last_endpoint,omitempty"` // See Client.LastEndpoint
+	Auth           []string                      `json:"auth"`                    // "bearer", "api_key" or "token_provider"
+	Health         DebugHealth                   `json:"health"`
+	Retries        RetryStats                    `json:"retries"`
+	Timeouts       map[RequestClass]DebugTimeout `json:"timeouts"`    // Empty without AdaptiveTimeout
+	ReadCaches     map[string]DebugReadCache     `json:"read_caches"` // By collection
+	Cursors        []DebugCursor                 `json:"cursors"`
+	Watchers       map[string]int                `json:"watchers"` // Active watchers by collection
+	Capabilities   DebugCapabilities             `json:"capabilities"`
+	Invalidation   DebugInvalidation             `json:"invalidation"`
+	SlowOperations []DebugSlowOperation          `json:"slow_operations"` // Oldest first
+}
+
+// DebugHealth is the client's view of the backend's health, see State
+type DebugHealth struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Breaker             string `json:"breaker,omitempty"` // Empty without ClientOptions.Breaker, see BreakerState
+}
+
+// DebugTimeout is the latency tracked for a request class, in milliseconds
+type DebugTimeout struct {
+	Samples   int     `json:"samples"`
+	MeanMs    float64 `json:"mean_ms"`
+	P95Ms     float64 `json:"p95_ms"`
+	TimeoutMs float64 `json:"timeout_ms"`
+	Adaptive  bool    `json:"adaptive"`
+}
+
+// DebugReadCache is the state of the read caches of a collection
+type DebugReadCache struct {
+	Entries     int     `json:"entries"`
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	Evictions   int64   `json:"evictions"`
+	Revalidated int64   `json:"revalidated"`
+	HitRate     float64 `json:"hit_rate"` // Hits over lookups, 0 before the first
+}
+
+// DebugCursor is an open iterator or channel producer, see OpenCursors
+type DebugCursor struct {
+	ID         int64      `json:"id"`
+	Kind       CursorKind `json:"kind"`
+	Collection string     `json:"collection"`
+	Query      string     `json:"query"`
+	IdleMs     int64      `json:"idle_ms"`
+}
+
+// DebugCapabilities lists the optional server features the client knows
+// about: those observed on calls, and those the info document advertised
+// if it has been read
+type DebugCapabilities struct {
+	Observed   map[Capability]bool `json:"observed"`
+	Advertised map[Capability]bool `json:"advertised,omitempty"`
+}
+
+// DebugInvalidation reports the invalidation events of the client
+type DebugInvalidation struct {
+	Published int64 `json:"published"`
+	Dropped   int64 `json:"dropped"`
+	Failed    int64 `json:"failed"`
+	Queued    int   `json:"queued"` // Events waiting to be published
+}
+
+// DebugSlowOperation is an entry of the slow-operation log
+type DebugSlowOperation struct {
+	Operation  Operation `json:"operation"`
+	Collection string    `json:"collection"`
+	DurationMs float64   `json:"duration_ms"`
+	Query      string    `json:"query,omitempty"`
+	Attempts   int       `json:"attempts"`
+	Error      Category  `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// DebugSnapshot gathers the state of the client's subsystems. Each is read
+// under its own lock, briefly, and nothing is fetched from the server, so
+// it is safe to call while the client is busy. The parts are read one
+// after another, not at a single instant.
+func (c *Client) DebugSnapshot() DebugSnapshot {
+	now := c.now()
+	snapshot := DebugSnapshot{
+		At:             now,
+		Endpoints:      make([]string, len(c.endpoints)),
+		Auth:           make([]string, 0),
+		Retries:        c.retryStats(),
+		Timeouts:       make(map[RequestClass]DebugTimeout),
+		ReadCaches:     make(map[string]DebugReadCache),
+		Cursors:        make([]DebugCursor, 0),
+		Watchers:       make(map[string]int),
+		SlowOperations: make([]DebugSlowOperation, 0),
+	}
+
+	for i, endpoint := range c.endpoints {
+		snapshot.Endpoints[i] = redactEndpoint(endpoint)
+	}
+	if last := c.LastEndpoint(); last != "" {
+		snapshot.LastEndpoint = redactEndpoint(last)
+	}
+	if c.authToken != "" {
+		snapshot.Auth = append(snapshot.Auth, "bearer")
+	}
+	if c.tokenProvider != nil {
+		snapshot.Auth = append(snapshot.Auth, "token_provider")
+	}
+	if c.apiKey != "" {
+		snapshot.Auth = append(snapshot.Auth, "api_key")
+	}
+
+	c.health.mu.Lock()
+	snapshot.Health = DebugHealth{State: c.health.state.String(), ConsecutiveFailures: c.health.failures}
+	c.health.mu.Unlock()
+	if c.breaker != nil {
+		snapshot.Health.Breaker = c.BreakerState().String()
+	}
+
+	for class, stats := range c.latency.stats() {
+		snapshot.Timeouts[class] = DebugTimeout{
+			Samples:   stats.Samples,
+			MeanMs:    milliseconds(stats.Mean),
+			P95Ms:     milliseconds(stats.P95),
+			TimeoutMs: milliseconds(stats.Timeout),
+			Adaptive:  stats.Adaptive,
+		}
+	}
+
+	c.mu.Lock()
+	caches := append([]namedReadCache(nil), c.readCaches...)
+	for collection, n := range c.watchers {
+		snapshot.Watchers[collection] = n
+	}
+	c.mu.Unlock()
+	for _, cache := range caches {
+		stats := cache.cache.stats()
+		total := snapshot.ReadCaches[cache.collection]
+		total.Entries += stats.Entries
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Revalidated += stats.Revalidated
+		if lookups := total.Hits + total.Misses; lookups > 0 {
+			total.HitRate = float64(total.Hits) / float64(lookups)
+		}
+		snapshot.ReadCaches[cache.collection] = total
+	}
+
+	for _, cursor := range c.OpenCursors() {
+		snapshot.Cursors = append(snapshot.Cursors, DebugCursor{
+			ID:         cursor.ID,
+			Kind:       cursor.Kind,
+			Collection: cursor.Collection,
+			Query:      cursor.Query,
+			IdleMs:     now.Sub(cursor.LastActive).Milliseconds(),
+		})
+	}
+
+	snapshot.Capabilities.Observed, snapshot.Capabilities.Advertised = c.capabilities.snapshot()
+
+	invalidation := c.InvalidationStats()
+	snapshot.Invalidation = DebugInvalidation{Published: invalidation.Published, Dropped: invalidation.Dropped, Failed: invalidation.Failed}
+	c.invalidation.mu.Lock()
+	snapshot.Invalidation.Queued = len(c.invalidation.queue)
+	c.invalidation.mu.Unlock()
+
+	for _, op := range c.SlowOperations() {
+		entry := DebugSlowOperation{
+			Operation:  op.Operation,
+			Collection: op.Collection,
+			DurationMs: milliseconds(op.Duration),
+			Query:      op.Query,
+			Attempts:   op.Attempts,
+			At:         op.At,
+		}
+		if op.Err != nil {
+			entry.Error = ErrorCategory(op.Err)
+		}
+		snapshot.SlowOperations = append(snapshot.SlowOperations, entry)
+	}
+	return snapshot
+}
+
+// namedReadCache is a collection's read cache, registered for DebugSnapshot
+type namedReadCache struct {
+	collection string
+	cache      *readCache
+}
+
+func (c *Client) registerReadCache(collection string, cache *readCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readCaches = append(c.readCaches, namedReadCache{collection: collection, cache: cache})
+}
+
+// trackWatcher counts a watcher of collection starting (1) or ending (-1)
+func (c *Client) trackWatcher(collection string, delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchers == nil {
+		c.watchers = make(map[string]int)
+	}
+	c.watchers[collection] += delta
+	if c.watchers[collection] <= 0 {
+		delete(c.watchers, collection)
+	}
+}
+
+// snapshot copies the observed and advertised capabilities, so callers
+// cannot change the registry
+func (r *capabilityRegistry) snapshot() (observed, advertised map[Capability]bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	observed = make(map[Capability]bool, len(r.known))
+	for capability, supported := range r.known {
+		observed[capability] = supported
+	}
+	if r.advertised != nil {
+		advertised = make(map[Capability]bool, len(r.advertised))
+		for capability, supported := range r.advertised {
+			advertised[capability] = supported
+		}
+	}
+	return observed, advertised
+}
+
+// redactEndpoint drops the user info of a base URL, which may hold a
+// password
+func redactEndpoint(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.User == nil {
+		return endpoint
+	}
+	u.User = nil
+	return u.String()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
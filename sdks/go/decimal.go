@@ -0,0 +1,168 @@
+package torm
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var decimalPattern = regexp.MustCompile(`^[+-]?\d+(\.\d+)?$`)
+
+// NormalizeDecimal converts a decimal string or number into its canonical
+// string form. With scale >= 0 the result has exactly scale decimal places
+// ("12.5" → "12.50"); values needing more places are rejected rather than
+// rounded. A negative scale keeps the minimal number of places.
+func NormalizeDecimal(value interface{}, scale int) (string, error) {
+	r, ok := parseDecimal(value)
+	if !ok {
+		return "", fmt.Errorf("invalid decimal %v", value)
+	}
+
+	places := decimalPlaces(r)
+	if scale >= 0 {
+		if places > scale {
+			return "", fmt.Errorf("decimal %v has more than %d decimal places", value, scale)
+		}
+		places = scale
+	}
+
+	return r.FloatString(places), nil
+}
+
+// parseDecimal reads a decimal string or number exactly
+func parseDecimal(value interface{}) (*big.Rat, bool) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = strings.TrimSpace(v)
+		if !decimalPattern.MatchString(s) {
+			return nil, false
+		}
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int:
+		s = strconv.Itoa(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case int32:
+		s = strconv.FormatInt(int64(v), 10)
+	default:
+		return nil, false
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	return r, ok
+}
+
+// decimalPlaces returns the fewest decimal places that represent r exactly.
+// Values from parseDecimal always have a power-of-ten denominator.
+func decimalPlaces(r *big.Rat) int {
+	denom := new(big.Int).Set(r.Denom())
+	ten := big.NewInt(10)
+	places := 0
+	for denom.Cmp(big.NewInt(1)) > 0 {
+		denom.Quo(denom, ten)
+		places++
+	}
+	return places
+}
+
+// checkDecimal validates a value against a decimal rule and returns its
+// canonical form
+func (rules ValidationRule) checkDecimal(value interface{}) (string, error) {
+	if _, isString := value.(string); !isString && !rules.Coerce {
+		return "", fmt.Errorf("must be a decimal string")
+	}
+
+	r, ok := parseDecimal(value)
+	if !ok {
+		return "", fmt.Errorf("must be a decimal")
+	}
+
+	scale := -1
+	if rules.Scale != nil {
+		scale = *rules.Scale
+		if decimalPlaces(r) > scale {
+			return "", fmt.Errorf("must have at most %d decimal places", scale)
+		}
+	}
+
+	if rules.MinDecimal != "" {
+		min, ok := parseDecimal(rules.MinDecimal)
+		if !ok {
+			return "", fmt.Errorf("has invalid minimum %q", rules.MinDecimal)
+		}
+		if r.Cmp(min) < 0 {
+			return "", fmt.Errorf("must be at least %s", rules.MinDecimal)
+		}
+	}
+	if rules.MaxDecimal != "" {
+		max, ok := parseDecimal(rules.MaxDecimal)
+		if !ok {
+			return "", fmt.Errorf("has invalid maximum %q", rules.MaxDecimal)
+		}
+		if r.Cmp(max) > 0 {
+			return "", fmt.Errorf("must be at most %s", rules.MaxDecimal)
+		}
+	}
+
+	return NormalizeDecimal(value, scale)
+}
+
+// decimalFields lists the schema fields declared as decimals
+func decimalFields(schema map[string]ValidationRule) map[string]bool {
+	var fields map[string]bool
+	for field, rules := range schema {
+		if rules.Type == "decimal" {
+			if fields == nil {
+				fields = make(map[string]bool)
+			}
+			fields[field] = true
+		}
+	}
+	return fields
+}
+
+// matchesDecimal evaluates a comparison numerically. ok is false when the
+// operator or either value cannot be compared as decimals.
+func matchesDecimal(docValue interface{}, operator QueryOperator, filterValue interface{}) (matched, ok bool) {
+	a, aOk := parseDecimal(docValue)
+	b, bOk := parseDecimal(filterValue)
+	if !aOk || !bOk {
+		return false, false
+	}
+
+	cmp := a.Cmp(b)
+	switch operator {
+	case Eq:
+		return cmp == 0, true
+	case Ne:
+		return cmp != 0, true
+	case Gt:
+		return cmp > 0, true
+	case Gte:
+		return cmp >= 0, true
+	case Lt:
+		return cmp < 0, true
+	case Lte:
+		return cmp <= 0, true
+	}
+	return false, false
+}
+
+// isDecimalComparison reports whether a filter must be evaluated numerically
+// on the client, because servers compare decimal strings lexically
+func (qb *QueryBuilder) isDecimalComparison(filter QueryFilter) bool {
+	if !qb.decimals[filter.Field] {
+		return false
+	}
+	switch filter.Operator {
+	case Eq, Ne, Gt, Gte, Lt, Lte:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeleteWhereOptions configures DeleteWhere
+type DeleteWhereOptions struct {
+	BatchSize int                                   // Deletes between progress reports (default 100)
+	PageSize  int                                   // Documents fetched per page while matching (default 1000)
+	Match     func(doc map[string]interface{}) bool // Extra client-side predicate
+	DryRun    bool                                  // Report matches without deleting anything
+	Progress  func(DeleteProgress)                  // Called after every batch
+	// Concurrency deletes each batch with an adaptive worker pool; nil
+	// deletes one document at a time
+	Concurrency *AdaptiveConcurrency
+}
+
+// DeleteProgress reports how far a DeleteWhere has come
+type DeleteProgress struct {
+	Matched int
+	Deleted int
+	Failed  int
+}
+
+// DeleteReport summarizes a DeleteWhere
+type DeleteReport struct {
+	Matched    int
+	Deleted    int
+	FailedIDs  []string
+	MatchedIDs []string // Only filled in dry runs
+	// DeadLettered counts failed deletes captured by the collection's
+	// dead-letter sink; see WithDeadLetter
+	DeadLettered int
+}
+
+// DeleteWhere deletes every document matching filters (and opts.Match) in
+// batches. Documents that fail to delete or are rejected by the guard are
+// reported in FailedIDs without stopping the run. The context is checked
+// between batches; on cancellation the partial report is returned with the
+// context's error.
+func (c *Collection[T]) DeleteWhere(ctx context.Context, filters map[string]interface{}, opts *DeleteWhereOptions) (_ *DeleteReport, err error) {
+	timer := c.timeOp(OpDelete).as("DeleteWhere")
+	defer timer.end(&err)
+	c = c.traced(ctx, timer)
+	if opts == nil {
+		opts = &DeleteWhereOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = 1000
+	}
+
+	// Matching finishes before deleting so pagination never skips documents
+	matched := make([]map[string]interface{}, 0)
+	lastID := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return &DeleteReport{}, err
+		}
+
+		qb := c.model().Query().
+			Sort("id", Asc).
+			Limit(pageSize)
+		for field, value := range filters {
+			qb.Where(field, value)
+		}
+		if lastID != "" {
+			qb.Filter("id", Gt, lastID)
+		}
+
+		docs, err := qb.Exec()
+		if err != nil {
+			return &DeleteReport{}, err
+		}
+
+		for _, doc := range docs {
+			lastID = fmt.Sprintf("%v", doc["id"])
+			if opts.Match == nil {
+				matched = append(matched, doc)
+				continue
+			}
+			match := false
+			if err := protect("delete match", c.collection, docID(doc), func() { match = opts.Match(doc) }); err != nil {
+				return &DeleteReport{}, err
+			}
+			if match {
+				matched = append(matched, doc)
+			}
+		}
+
+		if len(docs) < pageSize {
+			break
+		}
+	}
+
+	report := &DeleteReport{Matched: len(matched)}
+	if opts.DryRun {
+		report.MatchedIDs = make([]string, len(matched))
+		for i, doc := range matched {
+			report.MatchedIDs[i] = fmt.Sprintf("%v", doc["id"])
+		}
+		return report, nil
+	}
+
+	model := c.model()
+	limiter := newAdaptiveLimiter(opts.Concurrency, c.client.now)
+	for start := 0; start < len(matched); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		end := start + batchSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+
+		batch := matched[start:end]
+		failed := make([]error, len(batch))
+		limiter.run(len(batch), func(i int) error {
+			doc := batch[i]
+			if err := c.options.checkGuard(OpDelete, doc); err != nil {
+				failed[i] = err
+				return nil
+			}
+			id := fmt.Sprintf("%v", doc["id"])
+			_, err := model.Delete(id)
+			if err == nil {
+				err = c.deleted(id)
+			}
+			failed[i] = err
+			return err
+		})
+
+		for i, doc := range batch {
+			if failed[i] == nil {
+				report.Deleted++
+				continue
+			}
+			id := fmt.Sprintf("%v", doc["id"])
+			report.FailedIDs = append(report.FailedIDs, id)
+			entry := newDeadLetter(c.collection, OpDelete, doc, id, failed[i], c.client.now()).redacted(c.redactor(), c.options.deadLetter)
+			if captured, err := captureDeadLetter(c.options.deadLetter, entry, failed[i]); captured {
+				report.DeadLettered++
+			} else if c.options.deadLetter != nil {
+				return report, err
+			}
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(DeleteProgress{
+				Matched: report.Matched,
+				Deleted: report.Deleted,
+				Failed:  len(report.FailedIDs),
+			})
+		}
+	}
+
+	return report, nil
+}
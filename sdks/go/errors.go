@@ -0,0 +1,192 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ErrNotFound is returned when the document an operation names does not
+// exist. The error wraps it with the collection and id.
+var ErrNotFound = errors.New("torm: document not found")
+
+// notFound wraps ErrNotFound with the document it was looking for. The
+// operation is filled in by wrapOp.
+func notFound(collection, id string) error {
+	return &OpError{Collection: collection, ID: id, Err: ErrNotFound}
+}
+
+// ErrCircuitOpen is returned when a circuit breaker fails a request fast
+// instead of sending it to a server known to be down
+var ErrCircuitOpen = errors.New("torm: circuit open")
+
+// Category groups errors for handling decisions, logs and metric labels
+type Category string
+
+const (
+	CategoryNone        Category = ""             // No error
+	CategoryValidation  Category = "validation"   // The request or document is invalid
+	CategoryNotFound    Category = "not_found"    // The document does not exist
+	CategoryConflict    Category = "conflict"     // The write collides with stored data
+	CategoryForbidden   Category = "forbidden"    // A guard or the server refused access
+	CategoryTimeout     Category = "timeout"      // The request or the caller's deadline ran out
+	CategoryCanceled    Category = "canceled"     // The caller canceled the operation
+	CategoryOverloaded  Category = "overloaded"   // The server asked to back off (429, 503)
+	CategoryCircuitOpen Category = "circuit_open" // A circuit breaker failed the request fast
+	CategoryLoadShed    Category = "load_shed"    // A low-priority request was dropped unsent, see Priority
+	CategoryUnsupported Category = "unsupported"  // The server lacks the feature
+	CategoryContract    Category = "contract"     // The server or a model broke the expected shape
+	CategoryLimit       Category = "limit"        // A size or page limit was exceeded
+	CategoryUsage       Category = "usage"        // The SDK was called incorrectly
+	CategoryServer      Category = "server"       // The server failed (5xx)
+	CategoryRejected    Category = "rejected"     // The server refused the request (other 4xx)
+	CategoryNetwork     Category = "network"      // The server could not be reached
+	CategoryPanic       Category = "panic"        // A callback supplied to the SDK panicked
+	CategoryUnknown     Category = "unknown"      // Not produced by the SDK
+)
+
+// ErrorCategory classifies an error, looking through every wrapping layer.
+// When several causes are joined, the first matching check below wins:
+// panics in callbacks, cancellation, open circuits, shed requests and SDK
+// errors before raw HTTP statuses, timeouts and network failures.
+func ErrorCategory(err error) Category {
+	if err == nil {
+		return CategoryNone
+	}
+
+	var panicked *PanicError
+	var guard *GuardError
+	var contract *ContractError
+	var hydration *HydrationError
+	var fidelity *FidelityError
+	var chunk *ChunkError
+	var slugs *SlugExhaustedError
+	var api *APIError
+
+	switch {
+	case errors.As(err, &panicked):
+		return CategoryPanic
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled
+	case errors.Is(err, ErrCircuitOpen):
+		return CategoryCircuitOpen
+	case errors.Is(err, ErrLoadShed):
+		return CategoryLoadShed
+	case errors.As(err, &guard), errors.Is(err, ErrWriteProtected):
+		return CategoryForbidden
+	case errors.As(err, &contract), errors.As(err, &hydration), errors.As(err, &fidelity), errors.Is(err, ErrMissingID),
+		errors.Is(err, ErrUnknownType):
+		return CategoryContract
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrInvalidFilter), errors.Is(err, ErrInvalidHint), errors.As(err, &chunk),
+		errors.Is(err, ErrSyntheticField):
+		return CategoryValidation
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrNoHistory):
+		return CategoryNotFound
+	case errors.Is(err, ErrConflict), errors.As(err, &slugs), errors.Is(err, ErrVersionConflict),
+		errors.Is(err, ErrLeaseLost), errors.Is(err, ErrMigrationLocked):
+		return CategoryConflict
+	case errors.Is(err, ErrCursorIdle):
+		return CategoryTimeout
+	case errors.Is(err, ErrOverloaded):
+		return CategoryOverloaded
+	case errors.Is(err, ErrNotSupported), errors.Is(err, ErrNoStatusLocation), errors.Is(err, ErrForeignStatusLocation):
+		return CategoryUnsupported
+	case errors.Is(err, ErrLookupTooLarge), errors.Is(err, ErrIndexTooLarge), errors.Is(err, ErrTruncatedResult),
+		errors.Is(err, ErrMirrorQueueFull), errors.Is(err, ErrSpillTooLarge):
+		return CategoryLimit
+	case errors.Is(err, ErrQueryExists), errors.Is(err, ErrUnknownQuery), errors.Is(err, ErrCheckpointMismatch),
+		errors.Is(err, ErrUnknownTemplate), errors.Is(err, ErrCursorMismatch), errors.Is(err, ErrCursorExpired),
+		errors.Is(err, ErrInvalidCursor), errors.Is(err, ErrDuplicateKey), errors.Is(err, ErrEnvironmentExists),
+		errors.Is(err, ErrUnknownEnvironment):
+		return CategoryUsage
+	case errors.As(err, &api):
+		return statusCategory(api.StatusCode)
+	case isTimeout(err):
+		return CategoryTimeout
+	case isNetwork(err):
+		return CategoryNetwork
+	}
+	return CategoryUnknown
+}
+
+// statusCategory classifies an HTTP status the SDK has no specific error for
+func statusCategory(status int) Category {
+	switch {
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return CategoryValidation
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return CategoryForbidden
+	case status == http.StatusNotFound:
+		return CategoryNotFound
+	case status == http.StatusConflict || status == http.StatusPreconditionFailed:
+		return CategoryConflict
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return CategoryTimeout
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		return CategoryOverloaded
+	case status == http.StatusNotImplemented:
+		return CategoryUnsupported
+	case status >= 500:
+		return CategoryServer
+	case status >= 400:
+		return CategoryRejected
+	}
+	return CategoryUnknown
+}
+
+// isTimeout reports deadlines of the caller, the HTTP client and the server
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isNetwork(err error) bool {
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsRetryable reports whether repeating the operation later may succeed:
+// the server was overloaded, failed, timed out or could not be reached.
+// Open circuits are not retryable until the breaker lets requests through,
+// and retry loops should still stop once their own context is done.
+func IsRetryable(err error) bool {
+	switch ErrorCategory(err) {
+	case CategoryOverloaded, CategoryServer, CategoryNetwork, CategoryTimeout:
+		return true
+	}
+	return false
+}
+
+// IsNotFound reports whether err means the document does not exist
+func IsNotFound(err error) bool {
+	return ErrorCategory(err) == CategoryNotFound
+}
+
+// IsValidation reports whether err means the request or document is invalid
+func IsValidation(err error) bool {
+	return ErrorCategory(err) == CategoryValidation
+}
+
+// IsConflict reports whether err means the write collided with stored data
+func IsConflict(err error) bool {
+	return ErrorCategory(err) == CategoryConflict
+}
+
+// IsTimeout reports whether err means a deadline ran out
+func IsTimeout(err error) bool {
+	return ErrorCategory(err) == CategoryTimeout
+}
+
+// IsCircuitOpen reports whether err means a circuit breaker failed the
+// request fast
+func IsCircuitOpen(err error) bool {
+	return ErrorCategory(err) == CategoryCircuitOpen
+}
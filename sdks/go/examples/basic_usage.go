@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
@@ -8,7 +9,7 @@ import (
 )
 
 func main() {
-	fmt.Println("🚀 TORM Go SDK - Basic Usage Example\n")
+	fmt.Print("🚀 TORM Go SDK - Basic Usage Example\n\n")
 
 	// 1. Connect to TORM server
 	fmt.Println("Connecting to TORM server...")
@@ -21,7 +22,8 @@ func main() {
 	if err != nil {
 		log.Fatalf("❌ Failed to connect: %v", err)
 	}
-	fmt.Printf("✅ Connected! Status: %v\n\n", health["status"])
+	fmt.Printf("✅ Connected! Status: %v\n", health["status"])
+	fmt.Printf("Server-side count: %v\n\n", client.Supports(torm.CapabilityServerSideCount))
 
 	// 2. Define User model with validation
 	fmt.Println("Defining User model...")
@@ -45,7 +47,7 @@ func main() {
 			Type: "bool",
 		},
 	})
-	fmt.Println("✅ User model defined\n")
+	fmt.Print("✅ User model defined\n\n")
 
 	// 3. Create users
 	fmt.Println("Creating users...")
@@ -110,7 +112,7 @@ func main() {
 	} else if user != nil {
 		fmt.Printf("✅ Found: %v\n\n", user["name"])
 	} else {
-		fmt.Println("❌ User not found\n")
+		fmt.Print("❌ User not found\n\n")
 	}
 
 	// 6. Query with filters
@@ -162,7 +164,7 @@ func main() {
 	if err != nil {
 		fmt.Printf("✅ Validation caught error: %v\n\n", err)
 	} else {
-		fmt.Println("❌ Validation didn't catch invalid email\n")
+		fmt.Print("❌ Validation didn't catch invalid email\n\n")
 	}
 
 	// 10. Delete user
@@ -171,20 +173,20 @@ func main() {
 	if err != nil {
 		log.Printf("❌ Failed to delete user: %v\n", err)
 	} else if success {
-		fmt.Println("✅ User deleted successfully\n")
+		fmt.Print("✅ User deleted successfully\n\n")
 	} else {
-		fmt.Println("❌ Failed to delete user\n")
+		fmt.Print("❌ Failed to delete user\n\n")
 	}
 
 	// 11. Verify deletion
 	fmt.Println("Verifying deletion...")
-	user, err = User.FindByID("user:charlie")
-	if err != nil {
+	_, err = User.FindByID("user:charlie")
+	if errors.Is(err, torm.ErrNotFound) {
+		fmt.Print("✅ User successfully deleted\n\n")
+	} else if err != nil {
 		log.Printf("❌ Failed to verify: %v\n", err)
-	} else if user == nil {
-		fmt.Println("✅ User successfully deleted\n")
 	} else {
-		fmt.Println("❌ User still exists\n")
+		fmt.Print("❌ User still exists\n\n")
 	}
 
 	fmt.Println("🎉 Example completed!")
@@ -0,0 +1,33 @@
+package torm
+
+// QueryPlan describes how a query will run, as returned by Explain
+type QueryPlan struct {
+	Collection string
+	Payload    map[string]interface{} // Request body sent to the server
+	Sort       []QuerySort            // Keys results are sorted by, in order, with their resolved options
+	// DefaultOrder is the key added by the default order, either as the
+	// only key of an unsorted query or as a tie-breaker; nil when none is
+	DefaultOrder *QuerySort
+	// PagesLocally reports that skip and limit are applied after
+	// client-side filtering, so the server returns every match
+	PagesLocally bool
+	// Hints are the server options of the query, also in the payload
+	Hints map[string]interface{}
+}
+
+// Explain describes the query without running it
+func (qb *QueryBuilder) Explain() *QueryPlan {
+	pageLocally := qb.pagesLocally()
+	plan := &QueryPlan{
+		Collection:   qb.collection,
+		Payload:      qb.payload(pageLocally),
+		Sort:         qb.sortKeys(),
+		PagesLocally: pageLocally,
+		Hints:        qb.Hints(),
+	}
+	if injected := qb.injectedOrder(); injected != nil {
+		order := qb.resolveSort(*injected)
+		plan.DefaultOrder = &order
+	}
+	return plan
+}
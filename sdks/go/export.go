@@ -0,0 +1,340 @@
+package torm
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// ExportOptions configures a snapshot export
+type ExportOptions struct {
+	PageSize        int             // Documents fetched per query page (default 500)
+	Filters         []QueryFilter   // Optional filters restricting the export
+	Partition       *Partition      // Export only this partition; see Model.Partitions
+	CheckpointEvery int             // Persist progress every N documents (0 disables checkpoints)
+	Checkpoints     CheckpointStore // Where progress is persisted
+	Resume          bool            // Continue from the stored checkpoint (ExportSnapshotFile only)
+	// EncodedFields writes fields stored through a field codec in their
+	// stored form instead of decoding them; ImportSnapshot reads either
+	EncodedFields bool
+	// ChunkSize follows every N documents with a trailer line
+	// {"_chunk": k, "count": n, "sha256": "..."} hashing their lines, which
+	// ImportSnapshot verifies. Checkpoints then fall on chunk boundaries,
+	// CheckpointEvery being rounded up to a multiple of ChunkSize. Chunks of
+	// up to 1000 documents are verified before any of them is imported.
+	ChunkSize int
+	// Redacted masks sensitive fields (see WithRedaction), for exports
+	// meant to be read rather than restored
+	Redacted bool
+}
+
+// ExportCheckpoint records how far an export has progressed
+type ExportCheckpoint struct {
+	Collection string     `json:"collection"`
+	Partition  *Partition `json:"partition,omitempty"`
+	LastID     string     `json:"last_id"`
+	Documents  int        `json:"documents"`
+	Bytes      int64      `json:"bytes"`
+	Chunks     int        `json:"chunks,omitempty"` // Chunk trailers written
+	SHA256     string     `json:"sha256"`           // Hash of the first Bytes bytes of the output
+	Complete   bool       `json:"complete"`
+}
+
+// CheckpointStore persists export checkpoints
+type CheckpointStore interface {
+	// Load returns the stored checkpoint, or nil when there is none
+	Load() (*ExportCheckpoint, error)
+	Save(checkpoint *ExportCheckpoint) error
+}
+
+// ErrCheckpointMismatch is returned when a resume target does not match its checkpoint
+var ErrCheckpointMismatch = errors.New("torm: export target does not match checkpoint")
+
+// FileCheckpoint stores checkpoints as JSON in a local file
+func FileCheckpoint(path string) CheckpointStore {
+	return &fileCheckpoint{path: path}
+}
+
+type fileCheckpoint struct {
+	path string
+}
+
+func (f *fileCheckpoint) Load() (*ExportCheckpoint, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint ExportCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+func (f *fileCheckpoint) Save(checkpoint *ExportCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so a crash never leaves a torn checkpoint behind
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// KeyCheckpoint stores checkpoints under a key of the keys API
+func KeyCheckpoint(client *Client, key string) CheckpointStore {
+	return &keyCheckpoint{client: client, key: key}
+}
+
+type keyCheckpoint struct {
+	client *Client
+	key    string
+}
+
+func (k *keyCheckpoint) Load() (*ExportCheckpoint, error) {
+	value, found, err := k.client.GetKey(k.key)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var checkpoint ExportCheckpoint
+	if err := json.Unmarshal([]byte(value), &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+func (k *keyCheckpoint) Save(checkpoint *ExportCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return k.client.SetKey(k.key, string(data))
+}
+
+// ExportSnapshot writes every document of the collection to w as JSON lines,
+// paging through the collection in id order. Each page is written through
+// to w before the next is fetched, so a slow writer slows the export down
+// instead of the output piling up in memory.
+func (m *Model) ExportSnapshot(w io.Writer, opts ExportOptions) (*ExportCheckpoint, error) {
+	return m.exportSnapshot(w, sha256.New(), &ExportCheckpoint{Collection: m.collection, Partition: opts.Partition}, opts)
+}
+
+// ExportSnapshotFile exports the collection to a file. With opts.Resume set and
+// a stored checkpoint, the file is verified against the checkpoint's length and
+// hash, truncated to the checkpointed length, and the export continues from there.
+func (m *Model) ExportSnapshotFile(path string, opts ExportOptions) (*ExportCheckpoint, error) {
+	var checkpoint *ExportCheckpoint
+	if opts.Resume && opts.Checkpoints != nil {
+		stored, err := opts.Checkpoints.Load()
+		if err != nil {
+			return nil, err
+		}
+		checkpoint = stored
+	}
+
+	if checkpoint == nil {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer file.Close()
+
+		return m.ExportSnapshot(file, opts)
+	}
+
+	if checkpoint.Collection != m.collection {
+		return nil, fmt.Errorf("%w: checkpoint belongs to collection %s", ErrCheckpointMismatch, checkpoint.Collection)
+	}
+	if !samePartition(checkpoint.Partition, opts.Partition) {
+		return nil, fmt.Errorf("%w: checkpoint belongs to %v", ErrCheckpointMismatch, checkpoint.Partition)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer file.Close()
+
+	// Re-hash the checkpointed prefix to verify it and to continue the running hash
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(file, checkpoint.Bytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export file: %w", err)
+	}
+	if n != checkpoint.Bytes {
+		return nil, fmt.Errorf("%w: file has %d bytes, checkpoint expects %d", ErrCheckpointMismatch, n, checkpoint.Bytes)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != checkpoint.SHA256 {
+		return nil, fmt.Errorf("%w: content hash %s, checkpoint expects %s", ErrCheckpointMismatch, sum, checkpoint.SHA256)
+	}
+
+	if checkpoint.Complete {
+		return checkpoint, nil
+	}
+
+	// Drop anything written after the checkpoint
+	if err := file.Truncate(checkpoint.Bytes); err != nil {
+		return nil, fmt.Errorf("failed to truncate export file: %w", err)
+	}
+	if _, err := file.Seek(checkpoint.Bytes, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek export file: %w", err)
+	}
+
+	return m.exportSnapshot(file, h, checkpoint, opts)
+}
+
+// scannedPage is a query page as the server returned it, before the
+// client-side filtering of exec
+type scannedPage struct {
+	documents int
+	lastID    string
+	local     bool // The server returned every match, paged client-side
+}
+
+func (m *Model) exportSnapshot(w io.Writer, h hash.Hash, checkpoint *ExportCheckpoint, opts ExportOptions) (*ExportCheckpoint, error) {
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = 500
+	}
+
+	out := bufio.NewWriter(io.MultiWriter(w, h))
+
+	checkpointEvery := opts.CheckpointEvery
+	var chunk *chunkWriter
+	if opts.ChunkSize > 0 {
+		chunk = newChunkWriter(opts.ChunkSize)
+		if checkpointEvery > 0 {
+			checkpointEvery = (checkpointEvery + opts.ChunkSize - 1) / opts.ChunkSize * opts.ChunkSize
+		}
+	}
+
+	write := func(line []byte) error {
+		if _, err := out.Write(line); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		checkpoint.Bytes += int64(len(line))
+		return nil
+	}
+	closeChunk := func() error {
+		checkpoint.Chunks++
+		trailer, err := chunk.trailer(checkpoint.Chunks)
+		if err != nil {
+			return err
+		}
+		return write(trailer)
+	}
+
+	save := func() error {
+		if err := out.Flush(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		checkpoint.SHA256 = hex.EncodeToString(h.Sum(nil))
+		if opts.Checkpoints == nil {
+			return nil
+		}
+		if err := opts.Checkpoints.Save(checkpoint); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+		return nil
+	}
+
+	redact := m.redactor()
+	for {
+		qb := m.Query().Sort("id", Asc).Limit(pageSize)
+		qb.Match(opts.Filters...)
+		if opts.Partition != nil {
+			qb.Match(opts.Partition.Filters()...)
+		}
+		if checkpoint.LastID != "" {
+			qb.Filter("id", Gt, checkpoint.LastID)
+		}
+
+		var page scannedPage
+		qb.scanned = &page
+		docs, err := qb.Exec()
+		if err != nil {
+			return checkpoint, fmt.Errorf("export page after %q failed: %w", checkpoint.LastID, err)
+		}
+
+		for _, doc := range docs {
+			if fields := m.codecs.fieldCodecs(); fields != nil && opts.EncodedFields {
+				if doc, err = fields.encode(doc, false); err != nil {
+					return checkpoint, err
+				}
+			}
+			if opts.Redacted {
+				doc = redact.doc(doc)
+			}
+			line, err := json.Marshal(doc)
+			if err != nil {
+				return checkpoint, fmt.Errorf("failed to encode document: %w", err)
+			}
+			line = append(line, '\n')
+			if err := write(line); err != nil {
+				return checkpoint, err
+			}
+
+			checkpoint.LastID = fmt.Sprintf("%v", doc["id"])
+			checkpoint.Documents++
+
+			if chunk != nil && chunk.add(line) {
+				if err := closeChunk(); err != nil {
+					return checkpoint, err
+				}
+			}
+			if checkpointEvery > 0 && checkpoint.Documents%checkpointEvery == 0 {
+				if err := save(); err != nil {
+					return checkpoint, err
+				}
+			}
+		}
+
+		// Hand the page to the writer before fetching the next one
+		if err := out.Flush(); err != nil {
+			return checkpoint, fmt.Errorf("failed to write export: %w", err)
+		}
+
+		// Filters evaluated client-side, such as decimal comparisons, may
+		// leave less than a page of a full server page, so the end is
+		// found on the page as the server returned it
+		if page.local {
+			if len(docs) < pageSize {
+				break
+			}
+			continue
+		}
+		if page.documents < pageSize {
+			break
+		}
+		if page.lastID > checkpoint.LastID {
+			checkpoint.LastID = page.lastID
+		}
+	}
+
+	if chunk != nil && chunk.count > 0 {
+		if err := closeChunk(); err != nil {
+			return checkpoint, err
+		}
+	}
+	checkpoint.Complete = true
+	if err := save(); err != nil {
+		return checkpoint, err
+	}
+
+	return checkpoint, nil
+}
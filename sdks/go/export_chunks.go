@@ -0,0 +1,149 @@
+package torm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+)
+
+// chunkTrailer closes a chunk of a snapshot: the number of documents since
+// the previous trailer and the hash of their lines, newlines included
+type chunkTrailer struct {
+	Chunk  int    `json:"_chunk"`
+	Count  int    `json:"count"`
+	SHA256 string `json:"sha256"`
+}
+
+// ChunkError is returned by ImportSnapshot for the first chunk of a
+// snapshot whose documents do not match its trailer
+type ChunkError struct {
+	Chunk   int    // Chunk number, from 1
+	Line    int    // Line of the trailer, or the last line when it is missing
+	Problem string // e.g. "hash mismatch" or "has no trailer"
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("torm: snapshot chunk %d (line %d) is corrupt: %s", e.Chunk, e.Line, e.Problem)
+}
+
+// maxHeldRecords is how many records an import holds back until their
+// chunk is verified. Larger chunks are still verified, but their first
+// documents may be written before a mismatch is found.
+const maxHeldRecords = 1000
+
+// chunkWriter hashes the documents of the current chunk of an export
+type chunkWriter struct {
+	size  int
+	count int
+	hash  hash.Hash
+}
+
+func newChunkWriter(size int) *chunkWriter {
+	return &chunkWriter{size: size, hash: sha256.New()}
+}
+
+// add hashes a document line and reports whether the chunk is full
+func (c *chunkWriter) add(line []byte) bool {
+	c.hash.Write(line)
+	c.count++
+	return c.count == c.size
+}
+
+// trailer closes the chunk, returning its trailer line
+func (c *chunkWriter) trailer(chunk int) ([]byte, error) {
+	line, err := json.Marshal(chunkTrailer{Chunk: chunk, Count: c.count, SHA256: hex.EncodeToString(c.hash.Sum(nil))})
+	if err != nil {
+		return nil, err
+	}
+	c.count = 0
+	c.hash.Reset()
+	return append(line, '\n'), nil
+}
+
+// chunkVerifier checks the trailers of a snapshot as it is read, holding
+// back the records of each chunk until its trailer matches
+type chunkVerifier struct {
+	hash   hash.Hash
+	count  int
+	chunks int // Trailers verified
+	held   []importRecord
+	ready  []importRecord
+}
+
+func newChunkVerifier() *chunkVerifier {
+	return &chunkVerifier{hash: sha256.New()}
+}
+
+// parseTrailer reports whether a decoded line is a chunk trailer
+func parseTrailer(doc map[string]interface{}) (chunkTrailer, bool) {
+	if len(doc) != 3 {
+		return chunkTrailer{}, false
+	}
+	chunk, ok1 := doc["_chunk"].(float64)
+	count, ok2 := doc["count"].(float64)
+	sum, ok3 := doc["sha256"].(string)
+	if !ok1 || !ok2 || !ok3 {
+		return chunkTrailer{}, false
+	}
+	return chunkTrailer{Chunk: int(chunk), Count: int(count), SHA256: sum}, true
+}
+
+// record hashes a document line and holds its record back
+func (v *chunkVerifier) record(raw []byte, record importRecord) {
+	v.hash.Write(raw)
+	v.hash.Write([]byte{'\n'})
+	v.count++
+	v.held = append(v.held, record)
+	if len(v.held) > maxHeldRecords {
+		v.ready = append(v.ready, v.held[0])
+		v.held = v.held[1:]
+	}
+}
+
+// verify checks a trailer against the lines since the previous one and
+// releases their records
+func (v *chunkVerifier) verify(trailer chunkTrailer, line int) error {
+	chunk := v.chunks + 1
+	sum := hex.EncodeToString(v.hash.Sum(nil))
+	switch {
+	case trailer.Chunk != chunk:
+		return &ChunkError{Chunk: chunk, Line: line, Problem: fmt.Sprintf("trailer is numbered %d", trailer.Chunk)}
+	case trailer.Count != v.count:
+		return &ChunkError{Chunk: chunk, Line: line, Problem: fmt.Sprintf("has %d documents, trailer expects %d", v.count, trailer.Count)}
+	case sum != trailer.SHA256:
+		return &ChunkError{Chunk: chunk, Line: line, Problem: "hash mismatch"}
+	}
+	v.chunks++
+	v.count = 0
+	v.hash.Reset()
+	v.release()
+	return nil
+}
+
+// release lets the held records through
+func (v *chunkVerifier) release() {
+	v.ready = append(v.ready, v.held...)
+	v.held = nil
+}
+
+// finish releases the records after the last trailer. In a chunked
+// snapshot they must have a trailer of their own.
+func (v *chunkVerifier) finish(line int) error {
+	if v.chunks > 0 && v.count > 0 {
+		return &ChunkError{Chunk: v.chunks + 1, Line: line, Problem: "has no trailer"}
+	}
+	v.release()
+	return nil
+}
+
+// next pops the next released record
+func (v *chunkVerifier) next() (importRecord, bool) {
+	if len(v.ready) == 0 {
+		return importRecord{}, false
+	}
+	record := v.ready[0]
+	v.ready = v.ready[1:]
+	return record, true
+}
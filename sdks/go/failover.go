@@ -0,0 +1,165 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// failover remembers which of the client's endpoints answered last, so
+// requests go there first instead of failing on the first endpoint each
+// time
+type failover struct {
+	interval time.Duration
+
+	mu     sync.Mutex
+	active int       // Index of the endpoint requests start at
+	since  time.Time // When the preferred endpoint was last given up or probed
+	last   int       // Index of the endpoint that served the last request, -1 before any
+}
+
+func newFailover(interval time.Duration) *failover {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &failover{interval: interval, last: -1}
+}
+
+// order returns the indexes of n endpoints in the order a request tries
+// them: the active endpoint, then the ones after it. Once FailbackInterval
+// is over, one request starts at the preferred endpoint again to probe it.
+func (f *failover) order(n int, now time.Time) []int {
+	f.mu.Lock()
+	start := f.active
+	if start != 0 && now.Sub(f.since) >= f.interval {
+		start, f.since = 0, now
+	}
+	f.mu.Unlock()
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = (start + i) % n
+	}
+	return order
+}
+
+// served records the endpoint that answered a request, returning the
+// endpoint that was active before when it changed
+func (f *failover) served(index int, now time.Time) (previous int, changed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.last = index
+	if index == f.active {
+		return index, false
+	}
+	previous, f.active = f.active, index
+	if previous == 0 {
+		f.since = now
+	}
+	return previous, true
+}
+
+// LastEndpoint returns the base URL that answered the last request,
+// empty before the first. With several BaseURLs it shows which server is
+// in use after a failover.
+func (c *Client) LastEndpoint() string {
+	c.failover.mu.Lock()
+	defer c.failover.mu.Unlock()
+	if c.failover.last < 0 {
+		return ""
+	}
+	return c.endpoints[c.failover.last]
+}
+
+// doWithFailover sends req once per endpoint at most, moving on to the
+// next after a transport error or 5xx. Requests that may not be repeated
+// move on only when the connection could not be made, as the server
+// never saw them.
+func (c *Client) doWithFailover(req *http.Request, path string) (*http.Response, error) {
+	order := c.failover.order(len(c.endpoints), c.now())
+	for i := 0; ; i++ {
+		release := func() {}
+		if i > 0 {
+			var err error
+			if release, err = c.readmit(req, false); err != nil {
+				return nil, err
+			}
+		}
+		index, attempt := order[i], req
+		if endpoint := c.endpoints[index]; endpoint != c.BaseURL || i > 0 {
+			var err error
+			if attempt, err = c.attemptRequest(req, endpoint, path); err != nil {
+				release()
+				return nil, err
+			}
+		}
+
+		resp, err := c.do(attempt)
+		release()
+		if i < len(order)-1 && failsOver(req, resp, err) {
+			if resp != nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		if !failoverStatus(resp.StatusCode) {
+			c.servedBy(index)
+		}
+		return resp, nil
+	}
+}
+
+// readmit asks the breaker again before a retry or failover attempt of
+// req, as failed attempts may have opened the circuit since intercept let
+// req through. Background requests are shed instead of their last retry.
+// It returns the function to call once the attempt is done.
+func (c *Client) readmit(req *http.Request, last bool) (func(), error) {
+	priority, _ := callPriority(req.Context())
+	if last {
+		if err := c.breaker.shedLast(req, priority); err != nil {
+			return nil, err
+		}
+	}
+	return c.breaker.admit(req, priority, c.now())
+}
+
+// failsOver reports whether req, answered with resp or failed with err,
+// may be sent to the next endpoint
+func failsOver(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		var opErr *net.OpError
+		return idempotent(req) || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+	return idempotent(req) && failoverStatus(resp.StatusCode)
+}
+
+// failoverStatus reports statuses that move a request to the next
+// endpoint. 429 does not: another endpoint of the same backend is no less
+// busy.
+func failoverStatus(status int) bool {
+	return status >= 500 && status != http.StatusNotImplemented
+}
+
+// servedBy records the endpoint that answered a request, logging a change
+// of endpoint
+func (c *Client) servedBy(index int) {
+	previous, changed := c.failover.served(index, c.now())
+	if changed && c.logger != nil {
+		c.logger.LogAttrs(context.Background(), slog.LevelWarn, "torm: failover",
+			slog.String("from", redactEndpoint(c.endpoints[previous])),
+			slog.String("to", redactEndpoint(c.endpoints[index])))
+	}
+}
@@ -0,0 +1,218 @@
+package torm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FidelityMode is how WithModelFidelityCheck reports a ToMap that does not
+// match the model's JSON form
+type FidelityMode string
+
+const (
+	FidelityWarn FidelityMode = "warn" // Log through ClientOptions.Logger and write anyway
+	FidelityFail FidelityMode = "fail" // Fail every write with a FidelityError
+)
+
+// FidelityIssue is one difference between a model's JSON form and ToMap
+type FidelityIssue struct {
+	Field string
+	// Problem is "missing from ToMap", "extra in ToMap" or "value differs"
+	Problem string
+	JSON    interface{} // Value in the JSON form, nil when missing
+	ToMap   interface{} // Value from ToMap, nil when missing
+}
+
+// FidelityError reports that a model type's ToMap drifted from its json tags
+type FidelityError struct {
+	Type   string
+	Issues []FidelityIssue
+}
+
+func (e *FidelityError) Error() string {
+	problems := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		problems[i] = fmt.Sprintf("'%s' %s", issue.Field, issue.Problem)
+	}
+	return fmt.Sprintf("torm: %s.ToMap does not match its JSON form: %s", e.Type, strings.Join(problems, ", "))
+}
+
+// WithModelFidelityCheck compares ToMap with the json tags of the model on
+// the collection's writes, catching fields a hand-written ToMap stops
+// persisting. Zero values are not compared, since omitempty and
+// conditional ToMap code both drop them, so each write checks the fields
+// not yet seen with a value. Checking stops at the first drift, or once
+// every field has been compared. Meant for development: without the option
+// nothing is checked.
+func WithModelFidelityCheck(mode FidelityMode) CollectionOption {
+	return func(o *collectionOptions) {
+		o.fidelity = &fidelityCheck{mode: mode}
+	}
+}
+
+type fidelityCheck struct {
+	mode FidelityMode
+
+	mu       sync.Mutex
+	fields   []string        // JSON fields of the model, set on the first write
+	compared map[string]bool // Fields already compared with a value
+	done     bool            // Drift was found or every field was compared
+	err      *FidelityError  // Nil when ToMap matched
+}
+
+// checkFidelity runs the fidelity check on writes until it is done,
+// returning its error on every write in FidelityFail mode
+func (c *Collection[T]) checkFidelity(model T, data map[string]interface{}) error {
+	check := c.options.fidelity
+	if check == nil {
+		return nil
+	}
+	check.mu.Lock()
+	defer check.mu.Unlock()
+	if !check.done {
+		if check.compared == nil {
+			check.fields = modelFields(reflect.TypeOf(model))
+			check.compared = make(map[string]bool, len(check.fields))
+		}
+		check.err = compareFidelity(model, data, check.compared)
+		check.done = check.err != nil || check.covered()
+		if check.err != nil {
+			check.err.redact(c.redactor())
+		}
+		if check.err != nil && check.mode != FidelityFail && c.client.logger != nil {
+			c.client.logger.LogAttrs(context.Background(), slog.LevelWarn, check.err.Error(),
+				slog.String("collection", c.collection))
+		}
+	}
+	if check.err != nil && check.mode == FidelityFail {
+		return check.err
+	}
+	return nil
+}
+
+// covered reports whether every field of the model has been compared
+func (f *fidelityCheck) covered() bool {
+	for _, field := range f.fields {
+		if !f.compared[field] {
+			return false
+		}
+	}
+	return true
+}
+
+// modelFields lists the names encoding/json gives the fields of a struct
+// type, nil for other types
+func modelFields(typ reflect.Type) []string {
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		name, tagged := jsonFieldName(sf)
+		switch {
+		case name == "-":
+		case sf.Anonymous && !tagged:
+			fields = append(fields, modelFields(sf.Type)...)
+		case sf.IsExported():
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// compareFidelity compares the fields of the model's JSON form that have a
+// value and are not in compared yet, adding them to it
+func compareFidelity(model interface{}, data map[string]interface{}, compared map[string]bool) *FidelityError {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	name := fmt.Sprint(typ)
+
+	fromJSON, err := normalizeDoc(model)
+	if err != nil {
+		return &FidelityError{Type: name, Issues: []FidelityIssue{{Problem: "cannot be marshaled: " + err.Error()}}}
+	}
+	fromMap, err := normalizeDoc(data)
+	if err != nil {
+		return &FidelityError{Type: name, Issues: []FidelityIssue{{Problem: "ToMap cannot be marshaled: " + err.Error()}}}
+	}
+
+	var issues []FidelityIssue
+	for field, value := range fromJSON {
+		mapped, ok := fromMap[field]
+		if compared[field] || isZeroJSON(value) {
+			continue
+		}
+		compared[field] = true
+		switch {
+		case !ok:
+			issues = append(issues, FidelityIssue{Field: field, Problem: "missing from ToMap", JSON: value})
+		case !reflect.DeepEqual(value, mapped):
+			issues = append(issues, FidelityIssue{Field: field, Problem: "value differs", JSON: value, ToMap: mapped})
+		}
+	}
+	for field, mapped := range fromMap {
+		if _, ok := fromJSON[field]; !ok && !isZeroJSON(mapped) {
+			issues = append(issues, FidelityIssue{Field: field, Problem: "extra in ToMap", ToMap: mapped})
+		}
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Field < issues[j].Field })
+	return &FidelityError{Type: name, Issues: issues}
+}
+
+// normalizeDoc round-trips a value through JSON, so both forms compare
+// with the same types
+func normalizeDoc(value interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	err = json.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+// isZeroJSON reports whether a decoded JSON value is one omitempty drops
+func isZeroJSON(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// redact masks the values of sensitive fields in the issues
+func (e *FidelityError) redact(r redactor) {
+	for i, issue := range e.Issues {
+		if r.fields[issue.Field] {
+			e.Issues[i].JSON, e.Issues[i].ToMap = MaskValue(issue.JSON), MaskValue(issue.ToMap)
+		}
+	}
+}
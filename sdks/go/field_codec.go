@@ -0,0 +1,301 @@
+package torm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// FieldCodec converts the value of one field between the form the
+// application sees and a compact form stored on the server
+type FieldCodec interface {
+	EncodeValue(value interface{}) (interface{}, error)
+	// DecodeValue reverses EncodeValue. Values not in the stored form, such
+	// as those written before the codec was added, are returned unchanged.
+	DecodeValue(stored interface{}) (interface{}, error)
+}
+
+// GzipBase64 stores values as gzip-compressed JSON in a base64 string
+// prefixed with "gzip:"
+func GzipBase64() FieldCodec {
+	return CompressBase64("gzip", gzipCompress, gzipDecompress)
+}
+
+// CompressBase64 stores values as compressed JSON in a base64 string
+// prefixed with name and a colon. It plugs in other algorithms, e.g. zstd:
+//
+//	torm.CompressBase64("zstd", func(b []byte) ([]byte, error) { return enc.EncodeAll(b, nil), nil }, dec.DecodeAll...)
+func CompressBase64(name string, compress, decompress func([]byte) ([]byte, error)) FieldCodec {
+	return &compressCodec{prefix: name + ":", compress: compress, decompress: decompress}
+}
+
+type compressCodec struct {
+	prefix     string
+	compress   func([]byte) ([]byte, error)
+	decompress func([]byte) ([]byte, error)
+}
+
+func (c *compressCodec) EncodeValue(value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := c.compress(raw)
+	if err != nil {
+		return nil, err
+	}
+	return c.prefix + base64.StdEncoding.EncodeToString(packed), nil
+}
+
+func (c *compressCodec) DecodeValue(stored interface{}) (interface{}, error) {
+	text, ok := stored.(string)
+	if !ok || !strings.HasPrefix(text, c.prefix) {
+		return stored, nil
+	}
+	packed, err := base64.StdEncoding.DecodeString(text[len(c.prefix):])
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.decompress(packed)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func gzipCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gzipDecompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// FieldCodecStats counts the values a field codec handled
+type FieldCodecStats struct {
+	Encoded  int64 // Values written
+	Decoded  int64 // Stored values read back
+	BytesIn  int64 // JSON size of the written values
+	BytesOut int64 // JSON size of their stored form
+}
+
+// WithFieldCodec stores one field of every document the collection writes
+// through codec, and decodes it on every read. The server only sees the
+// stored form, so queries filtering on the field are rejected.
+func WithFieldCodec(field string, codec FieldCodec) CollectionOption {
+	return func(o *collectionOptions) {
+		o.codecs = withFieldCodec(o.codecs, field, codec)
+	}
+}
+
+// WithFieldCodec stores one field through codec, as the collection option does
+func (m *Model) WithFieldCodec(field string, codec FieldCodec) *Model {
+	m.codecs = withFieldCodec(m.codecs, field, codec)
+	return m
+}
+
+// FieldCodecStats returns the counters of the model's field codecs by field
+func (m *Model) FieldCodecStats() map[string]FieldCodecStats {
+	return m.codecs.fieldCodecs().stats()
+}
+
+// FieldCodecStats returns the counters of the collection's field codecs by field
+func (c *Collection[T]) FieldCodecStats() map[string]FieldCodecStats {
+	return c.options.codecs.fieldCodecs().stats()
+}
+
+// withFieldCodec adds a field codec to a chain. Field codecs share one
+// entry at the start of the chain, so they see the application form.
+func withFieldCodec(chain codecChain, field string, codec FieldCodec) codecChain {
+	fields := chain.fieldCodecs()
+	if fields == nil {
+		fields = &fieldCodecs{codecs: make(map[string]*fieldCodecEntry)}
+		chain = append(codecChain{fields}, chain...)
+	}
+	fields.mu.Lock()
+	fields.codecs[field] = &fieldCodecEntry{codec: codec}
+	fields.mu.Unlock()
+	return chain
+}
+
+// fieldCodecs is the chain entry applying field codecs
+type fieldCodecs struct {
+	mu     sync.RWMutex
+	codecs map[string]*fieldCodecEntry
+}
+
+type fieldCodecEntry struct {
+	codec    FieldCodec
+	encoded  atomic.Int64
+	decoded  atomic.Int64
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+func (f *fieldCodecs) entry(field string) *fieldCodecEntry {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.codecs[field]
+}
+
+func (f *fieldCodecs) fields() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	fields := make([]string, 0, len(f.codecs))
+	for field := range f.codecs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// encodeDoc encodes the codec fields of a copy of doc
+func (f *fieldCodecs) encodeDoc(doc map[string]interface{}) (map[string]interface{}, error) {
+	return f.encode(doc, true)
+}
+
+// encode encodes the codec fields of a copy of doc, counting them in the
+// stats when the document is being written
+func (f *fieldCodecs) encode(doc map[string]interface{}, count bool) (map[string]interface{}, error) {
+	encoded := doc
+	copied := false
+	for _, field := range f.fields() {
+		value, ok := doc[field]
+		if !ok || value == nil {
+			continue
+		}
+		entry := f.entry(field)
+		stored, err := entry.codec.EncodeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field '%s': %w", field, err)
+		}
+		if !copied {
+			encoded, copied = cloneDoc(doc), true
+		}
+		encoded[field] = stored
+		if !count {
+			continue
+		}
+
+		in, _ := json.Marshal(value)
+		out, _ := json.Marshal(stored)
+		entry.encoded.Add(1)
+		entry.bytesIn.Add(int64(len(in)))
+		entry.bytesOut.Add(int64(len(out)))
+	}
+	return encoded, nil
+}
+
+// EncodeDoc implements DocumentCodec. Encoding failures panic, which the
+// SDK reports as a PanicError; the SDK itself calls encodeDoc.
+func (f *fieldCodecs) EncodeDoc(doc map[string]interface{}) map[string]interface{} {
+	encoded, err := f.encodeDoc(doc)
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
+
+// DecodeDoc implements DocumentCodec
+func (f *fieldCodecs) DecodeDoc(doc map[string]interface{}) (map[string]interface{}, error) {
+	return f.decode(doc, true)
+}
+
+// decode decodes the codec fields of a copy of doc, counting them in the
+// stats when the document was read from the server
+func (f *fieldCodecs) decode(doc map[string]interface{}, count bool) (map[string]interface{}, error) {
+	decoded := doc
+	copied := false
+	for _, field := range f.fields() {
+		stored, ok := doc[field]
+		if !ok || stored == nil {
+			continue
+		}
+		entry := f.entry(field)
+		value, err := entry.codec.DecodeValue(stored)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", field, err)
+		}
+		if !copied {
+			decoded, copied = cloneDoc(doc), true
+		}
+		decoded[field] = value
+		if count {
+			entry.decoded.Add(1)
+		}
+	}
+	return decoded, nil
+}
+
+func (f *fieldCodecs) stats() map[string]FieldCodecStats {
+	stats := make(map[string]FieldCodecStats)
+	if f == nil {
+		return stats
+	}
+	for _, field := range f.fields() {
+		entry := f.entry(field)
+		stats[field] = FieldCodecStats{
+			Encoded:  entry.encoded.Load(),
+			Decoded:  entry.decoded.Load(),
+			BytesIn:  entry.bytesIn.Load(),
+			BytesOut: entry.bytesOut.Load(),
+		}
+	}
+	return stats
+}
+
+// fieldCodecs returns the chain's field codecs, nil when it has none
+func (chain codecChain) fieldCodecs() *fieldCodecs {
+	for _, codec := range chain {
+		if fields, ok := codec.(*fieldCodecs); ok {
+			return fields
+		}
+	}
+	return nil
+}
+
+// encodes reports whether the server sees a field only in encoded form
+func (chain codecChain) encodes(field string) bool {
+	for _, codec := range chain {
+		fields, ok := codec.(*fieldCodecs)
+		if !ok || fields.entry(field) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// opaque reports whether the chain reshapes whole documents, rather than
+// only the values of some fields
+func (chain codecChain) opaque() bool {
+	for _, codec := range chain {
+		if _, ok := codec.(*fieldCodecs); !ok {
+			return true
+		}
+	}
+	return false
+}
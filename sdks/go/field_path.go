@@ -0,0 +1,174 @@
+package torm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CapabilityFieldProjection is the server's support for reading only some
+// fields of a document with GET /api/{collection}/{id}?fields=...
+const CapabilityFieldProjection Capability = "field_projection"
+
+// GetField reads the value at a dot path such as "settings.theme" from the
+// document id. The boolean is false when the path is absent, including
+// when an intermediate key is missing or is not an object. Servers that
+// project on GET send only that field; otherwise the whole document is
+// read and the path extracted here. Collections with codecs, a guard, read
+// repair or a read cache always read the whole document, as those need it.
+func (c *Collection[T]) GetField(id, path string) (_ interface{}, _ bool, err error) {
+	timer := c.timeOp(OpFindByID).target(id).as("GetField")
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
+
+	if _, err := splitFieldPath(path); err != nil {
+		return nil, false, err
+	}
+	return c.getField(id, path)
+}
+
+func (c *Collection[T]) getField(id, path string) (interface{}, bool, error) {
+	if c.options.guard != nil || len(c.options.codecs) > 0 || c.options.readRepair != nil || c.options.readCache != nil {
+		// The path is looked up in the stored document, which may hold
+		// fields T does not model
+		doc, _, err := c.findDocument(id)
+		if err != nil {
+			return nil, false, err
+		}
+		value, found := lookupFieldPath(jsonForm(doc), path)
+		return value, found, nil
+	}
+
+	supported, known := c.client.capabilities.lookup(CapabilityFieldProjection)
+	project := supported || !known
+	docPath := c.client.apiPath(c.collection, id)
+	if project {
+		docPath += "?" + url.Values{"fields": {path}}.Encode()
+	}
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.call("GET", docPath, nil)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if project && resp.StatusCode() == http.StatusBadRequest {
+		// Strict servers reject the parameter rather than ignore it
+		c.client.capabilities.record(CapabilityFieldProjection, false)
+		return c.getField(id, path)
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, false, notFound(c.collection, id)
+	}
+	if !resp.IsSuccess() {
+		return nil, false, resp.apiError("get field")
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(resp.Body(), &doc); err != nil {
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if project {
+		c.client.capabilities.record(CapabilityFieldProjection, isProjection(doc, path))
+	}
+	value, found := lookupFieldPath(doc, path)
+	return value, found, nil
+}
+
+// SetField sets the value at a dot path such as "settings.theme" on the
+// document id, creating the intermediate objects that are missing and
+// keeping the other keys of those that exist. It is written with Patch,
+// so a concurrent change to the same top-level field fails with a
+// *PatchConflictError. An intermediate key holding something other than
+// an object is an error.
+func (c *Collection[T]) SetField(id, path string, value interface{}) (err error) {
+	timer := c.timeOp(OpUpdate).target(id).as("SetField")
+	defer timer.end(&err)
+
+	segments, err := splitFieldPath(path)
+	if err != nil {
+		return err
+	}
+	current, err := c.readVersion(id)
+	if err != nil {
+		return err
+	}
+	base := c.factory()
+	if err := hydrate(current.doc, &base); err != nil {
+		return err
+	}
+
+	top := segments[0]
+	if len(segments) == 1 {
+		_, err = c.Patch(context.Background(), id, base, map[string]interface{}{top: value})
+		return err
+	}
+	nested, ok := deepCopyValue(jsonForm(current.doc)[top]).(map[string]interface{})
+	if !ok {
+		if existing, present := current.doc[top]; present && existing != nil {
+			return fmt.Errorf("set field %s of %s: %s is not an object", path, id, top)
+		}
+		nested = make(map[string]interface{})
+	}
+	parent := nested
+	for i, segment := range segments[1 : len(segments)-1] {
+		next, present := parent[segment]
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			if present && next != nil {
+				return fmt.Errorf("set field %s of %s: %s is not an object", path, id, strings.Join(segments[:i+2], "."))
+			}
+			child = make(map[string]interface{})
+			parent[segment] = child
+		}
+		parent = child
+	}
+	parent[segments[len(segments)-1]] = value
+
+	_, err = c.Patch(context.Background(), id, base, map[string]interface{}{top: nested})
+	return err
+}
+
+// splitFieldPath splits a dot path into its keys, rejecting empty keys and
+// paths into the id
+func splitFieldPath(path string) ([]string, error) {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid field path %q", path)
+		}
+	}
+	if segments[0] == "id" {
+		return nil, fmt.Errorf("invalid field path %q: the id is not a field", path)
+	}
+	return segments, nil
+}
+
+// lookupFieldPath returns the value at a dot path of doc
+func lookupFieldPath(doc map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = doc
+	for _, segment := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[segment]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// isProjection reports whether doc holds no top-level field besides the id
+// and the one path starts with, as a projected read returns
+func isProjection(doc map[string]interface{}, path string) bool {
+	top, _, _ := strings.Cut(path, ".")
+	for field := range doc {
+		if field != "id" && field != top {
+			return false
+		}
+	}
+	return true
+}
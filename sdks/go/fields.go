@@ -0,0 +1,165 @@
+package torm
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldRef is a typed reference to a document field. It resolves to the
+// field's wire name and only accepts values of the field's Go type, so a
+// misspelt field or a mistyped value fails to compile instead of silently
+// matching nothing:
+//
+//	var userEmail = torm.Field(func(u *User) *string { return &u.Email })
+//	client.Model("users", nil).Query().Match(userEmail.Eq("a@example.com"))
+type FieldRef[F any] struct {
+	name string
+}
+
+// OrderedFieldRef is a FieldRef whose values can be compared with Gt, Gte,
+// Lt and Lte
+type OrderedFieldRef[F cmp.Ordered] struct {
+	FieldRef[F]
+}
+
+// StringFieldRef is an OrderedFieldRef for text that also supports Contains
+type StringFieldRef struct {
+	OrderedFieldRef[string]
+}
+
+// Field returns a reference to the field of T the selector points at. The
+// wire name follows the field's json tag, as documents are encoded with
+// encoding/json. Fields of embedded structs are resolved like json promotes
+// them. Field panics if the selector does not return a field of its
+// argument, so references are best declared once as package variables.
+func Field[T any, F any](selector func(*T) *F) FieldRef[F] {
+	return FieldRef[F]{name: selectField(selector)}
+}
+
+// OrderedField is Field for numbers, strings and other ordered types
+func OrderedField[T any, F cmp.Ordered](selector func(*T) *F) OrderedFieldRef[F] {
+	return OrderedFieldRef[F]{FieldRef[F]{name: selectField(selector)}}
+}
+
+// StringField is Field for text fields
+func StringField[T any](selector func(*T) *string) StringFieldRef {
+	return StringFieldRef{OrderedFieldRef[string]{FieldRef[string]{name: selectField(selector)}}}
+}
+
+// NamedField returns a reference to a field by its wire name, for fields
+// that are not backed by a struct
+func NamedField[F any](name string) FieldRef[F] {
+	return FieldRef[F]{name: name}
+}
+
+// Name returns the field's wire name
+func (f FieldRef[F]) Name() string { return f.name }
+
+// Eq matches documents whose field equals value
+func (f FieldRef[F]) Eq(value F) QueryFilter { return f.filter(Eq, value) }
+
+// Ne matches documents whose field differs from value
+func (f FieldRef[F]) Ne(value F) QueryFilter { return f.filter(Ne, value) }
+
+// In matches documents whose field equals one of values
+func (f FieldRef[F]) In(values ...F) QueryFilter { return f.filter(In, anySlice(values)) }
+
+// NotIn matches documents whose field equals none of values
+func (f FieldRef[F]) NotIn(values ...F) QueryFilter { return f.filter(NotIn, anySlice(values)) }
+
+// Gt matches documents whose field is greater than value
+func (f OrderedFieldRef[F]) Gt(value F) QueryFilter { return f.filter(Gt, value) }
+
+// Gte matches documents whose field is greater than or equal to value
+func (f OrderedFieldRef[F]) Gte(value F) QueryFilter { return f.filter(Gte, value) }
+
+// Lt matches documents whose field is less than value
+func (f OrderedFieldRef[F]) Lt(value F) QueryFilter { return f.filter(Lt, value) }
+
+// Lte matches documents whose field is less than or equal to value
+func (f OrderedFieldRef[F]) Lte(value F) QueryFilter { return f.filter(Lte, value) }
+
+// Contains matches documents whose field contains substr
+func (f StringFieldRef) Contains(substr string) QueryFilter { return f.filter(Contains, substr) }
+
+func (f FieldRef[F]) filter(operator QueryOperator, value interface{}) QueryFilter {
+	return QueryFilter{Field: f.name, Operator: operator, Value: value}
+}
+
+// Match adds filters built from field references
+func (qb *QueryBuilder) Match(filters ...QueryFilter) *QueryBuilder {
+	for _, filter := range filters {
+		qb.Filter(filter.Field, filter.Operator, filter.Value)
+	}
+	return qb
+}
+
+// SortBy sorts by a referenced field
+func (qb *QueryBuilder) SortBy(field interface{ Name() string }, order SortOrder) *QueryBuilder {
+	return qb.Sort(field.Name(), order)
+}
+
+func anySlice[F any](values []F) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = value
+	}
+	return converted
+}
+
+// selectField finds the wire name of the field the selector returns by
+// calling it on a zero T and comparing addresses
+func selectField[T any, F any](selector func(*T) *F) string {
+	var model T
+	root := reflect.ValueOf(&model).Elem()
+	if root.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("torm: field selector on %s, which is not a struct", root.Type()))
+	}
+
+	target := reflect.ValueOf(selector(&model))
+	name, ok := findField(root, target.Pointer(), target.Type().Elem())
+	if !ok {
+		panic(fmt.Sprintf("torm: field selector on %s does not return one of its top-level fields", root.Type()))
+	}
+	return name
+}
+
+func findField(v reflect.Value, addr uintptr, typ reflect.Type) (string, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+		name, tagged := jsonFieldName(sf)
+		if name == "-" {
+			continue
+		}
+
+		field := v.Field(i)
+		if sf.Anonymous && !tagged && field.Kind() == reflect.Struct {
+			if name, ok := findField(field, addr, typ); ok {
+				return name, true
+			}
+			continue
+		}
+		if field.Addr().Pointer() == addr && sf.Type == typ {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// jsonFieldName returns the name encoding/json uses for a struct field and
+// whether it came from a tag
+func jsonFieldName(sf reflect.StructField) (string, bool) {
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return "-", true
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return sf.Name, false
+}
@@ -0,0 +1,139 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+)
+
+// MatchDocument reports whether doc matches every filter, as the SDK's
+// client-side matcher decides when it filters or checks query results:
+//
+//   - Eq and Ne compare by string form, so 42 equals "42". A boolean
+//     filter value matches true/false, "true"/"false" and 1/0.
+//   - Gt, Gte, Lt and Lte compare numerically when both values are
+//     numbers and by string form otherwise, so "10" < "9". Against a
+//     boolean, false orders before true.
+//   - A missing field or null takes the string form "<nil>", so it
+//     equals only a nil filter value and orders among strings.
+//   - Contains matches a substring of the string form.
+//   - In and NotIn take a []interface{} and compare items as Eq does;
+//     In with any other value matches nothing, NotIn everything.
+//
+// Schema-driven comparisons, such as decimals and CoerceNumeric, are not
+// applied. Use it to unit-test filters, or with WithFilterParityChecks to
+// find where a server disagrees.
+func MatchDocument(doc map[string]interface{}, filters []QueryFilter) bool {
+	qb := &QueryBuilder{filters: filters}
+	return qb.matchesFilters(doc)
+}
+
+// DivergenceKind tells how a server's filtering disagreed with
+// MatchDocument
+type DivergenceKind string
+
+const (
+	// DivergenceServerIncluded is a document the server returned although
+	// MatchDocument rejects it
+	DivergenceServerIncluded DivergenceKind = "server_included"
+	// DivergenceCount is a count of matches, reported by the server, that
+	// differs from the documents it returned: it may have left out
+	// documents MatchDocument accepts
+	DivergenceCount DivergenceKind = "count_mismatch"
+)
+
+// FilterDivergence is a disagreement between the server's filtering and
+// MatchDocument, found by WithFilterParityChecks
+type FilterDivergence struct {
+	Collection string
+	Query      string // The query as QueryBuilder.String shows it
+	Kind       DivergenceKind
+	ID         string       // The document, for DivergenceServerIncluded
+	Filter     *QueryFilter // The filter the document fails, ditto
+	Returned   int          // Documents the server returned, for DivergenceCount
+	Count      int          // Matches the server reported, ditto
+}
+
+// filterParity samples queries whose results are checked with the
+// client-side matcher
+type filterParity struct {
+	rate         float64
+	onDivergence func(FilterDivergence)
+}
+
+// WithFilterParityChecks checks a fraction of the collection's queries
+// (rate, from 0 to 1) against MatchDocument, to find filters the server
+// evaluates differently, e.g. comparing numbers as strings or treating
+// nulls apart. Divergences are logged as warnings through
+// ClientOptions.Logger and passed to onDivergence, which may be nil.
+// Checked queries are matched twice, so this is meant for staging.
+func WithFilterParityChecks(rate float64, onDivergence func(FilterDivergence)) CollectionOption {
+	return func(o *collectionOptions) {
+		o.parity = &filterParity{rate: rate, onDivergence: onDivergence}
+	}
+}
+
+// WithFilterParityChecks checks a fraction of the model's queries, as the
+// collection option does
+func (m *Model) WithFilterParityChecks(rate float64, onDivergence func(FilterDivergence)) *Model {
+	m.parity = &filterParity{rate: rate, onDivergence: onDivergence}
+	return m
+}
+
+// sample reports whether to check the next query
+func (p *filterParity) sample() bool {
+	return p != nil && p.rate > 0 && (p.rate >= 1 || rand.Float64() < p.rate)
+}
+
+// checkParity reports the documents the server returned for qb that the
+// client-side matcher rejects, and a count of matches from the response
+// (nil when it sent none) that differs from the documents returned.
+// Filters the server never saw, such as decimal comparisons, are skipped.
+func (qb *QueryBuilder) checkParity(docs []map[string]interface{}, count interface{}) {
+	for _, doc := range docs {
+		for _, filter := range qb.filters {
+			if !qb.sentToServer(filter) || qb.matchesFilter(doc[filter.Field], filter.Operator, filter.Value) {
+				continue
+			}
+			filter := filter
+			qb.diverged(FilterDivergence{Kind: DivergenceServerIncluded, ID: fmt.Sprintf("%v", doc["id"]), Filter: &filter})
+			break
+		}
+	}
+	if n, ok := count.(float64); ok && int(n) != len(docs) {
+		qb.diverged(FilterDivergence{Kind: DivergenceCount, Returned: len(docs), Count: int(n)})
+	}
+}
+
+// sentToServer reports whether the server evaluates filter
+func (qb *QueryBuilder) sentToServer(filter QueryFilter) bool {
+	return !qb.isDecimalComparison(filter) && !qb.isCoercedComparison(filter) && !qb.encoded(filter.Field)
+}
+
+// diverged logs and reports a divergence
+func (qb *QueryBuilder) diverged(divergence FilterDivergence) {
+	divergence.Collection = qb.collection
+	divergence.Query = qb.String()
+	if logger := qb.client.logger; logger != nil {
+		attrs := []slog.Attr{
+			slog.String("collection", divergence.Collection),
+			slog.String("kind", string(divergence.Kind)),
+			slog.String("query", divergence.Query),
+		}
+		if divergence.Filter != nil {
+			// The filter's value may be sensitive; the query shows it
+			// redacted
+			attrs = append(attrs,
+				slog.String("id", divergence.ID),
+				slog.String("field", divergence.Filter.Field),
+				slog.String("operator", string(divergence.Filter.Operator)))
+		} else {
+			attrs = append(attrs, slog.Int("returned", divergence.Returned), slog.Int("count", divergence.Count))
+		}
+		logger.LogAttrs(context.Background(), slog.LevelWarn, "torm: filter parity", attrs...)
+	}
+	if qb.parity.onDivergence != nil {
+		qb.parity.onDivergence(divergence)
+	}
+}
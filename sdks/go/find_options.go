@@ -0,0 +1,76 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+)
+
+// FindOption narrows or orders the documents Collection.Find returns
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	set     bool // Whether any option was given
+	filters map[string]interface{}
+	sort    *QuerySort
+	limit   *int
+	skip    *int
+}
+
+// WithFilters keeps the documents whose fields equal every filter value.
+// Given more than once, the filters are merged.
+func WithFilters(filters map[string]interface{}) FindOption {
+	return func(o *findOptions) {
+		if o.filters == nil {
+			o.filters = make(map[string]interface{}, len(filters))
+		}
+		for field, value := range filters {
+			o.filters[field] = value
+		}
+	}
+}
+
+// WithLimit returns at most n documents
+func WithLimit(n int) FindOption {
+	return func(o *findOptions) {
+		o.limit = &n
+	}
+}
+
+// WithSkip skips the first n documents
+func WithSkip(n int) FindOption {
+	return func(o *findOptions) {
+		o.skip = &n
+	}
+}
+
+// WithSort orders the documents by field
+func WithSort(field string, order SortOrder) FindOption {
+	return func(o *findOptions) {
+		o.sort = &QuerySort{Field: field, Order: order}
+	}
+}
+
+// findPaged runs a Find with sorting or paging as a query
+func (c *Collection[T]) findPaged(o findOptions) (found []T, err error) {
+	timer := c.timeOp(OpFind).counting(func() int { return len(found) })
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
+
+	qb := c.filterQuery(o.filters)
+	if o.sort != nil {
+		qb.Sort(o.sort.Field, o.sort.Order)
+	}
+	if o.limit != nil {
+		qb.Limit(*o.limit)
+	}
+	if o.skip != nil {
+		qb.Skip(*o.skip)
+	}
+	timer.query(qb)
+
+	docs, err := qb.exec()
+	if err != nil {
+		return nil, fmt.Errorf("%w (query: %s)", err, qb)
+	}
+	return c.toModels(OpQuery, docs)
+}
@@ -0,0 +1,52 @@
+// Command torm-gen writes a Go model struct inferred from an existing collection.
+//
+// Typical use from a go:generate directive:
+//
+//	//go:generate go run github.com/toonstore/torm-go/gen/cmd/torm-gen -collection users -type User -pkg models -o user_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/toonstore/torm-go"
+	"github.com/toonstore/torm-go/gen"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:3001", "TORM server URL")
+	collection := flag.String("collection", "", "collection to sample (required)")
+	typeName := flag.String("type", "", "name of the generated struct (required)")
+	pkg := flag.String("pkg", "models", "package name of the generated file")
+	sample := flag.Int("sample", 100, "number of documents to sample")
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+
+	if *collection == "" || *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: *baseURL})
+
+	schema, err := gen.InferSchema(client, *collection, *sample)
+	if err != nil {
+		log.Fatalf("torm-gen: %v", err)
+	}
+
+	src, err := gen.GenerateFile(*pkg, schema, *typeName)
+	if err != nil {
+		log.Fatalf("torm-gen: %v", err)
+	}
+
+	if *out == "" {
+		fmt.Print(string(src))
+		return
+	}
+
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("torm-gen: %v", err)
+	}
+}
@@ -0,0 +1,422 @@
+// Package gen infers Go model structs from documents already stored in ToonStore
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/toonstore/torm-go"
+)
+
+// Kind is the inferred type of a field
+type Kind string
+
+const (
+	KindString Kind = "string"
+	KindInt    Kind = "int"
+	KindFloat  Kind = "float"
+	KindBool   Kind = "bool"
+	KindTime   Kind = "time"
+	KindObject Kind = "object"
+	KindArray  Kind = "array"
+	KindAny    Kind = "any"
+)
+
+// Field describes an inferred document field
+type Field struct {
+	Name      string   // Key as stored in the document
+	Kind      Kind     // Reconciled type across all samples
+	Nullable  bool     // Null or absent in at least one sample
+	Conflicts []Kind   // Observed kinds when they could not be reconciled
+	Fields    []*Field // Members, for objects
+	Elem      *Field   // Element type, for arrays
+}
+
+// Schema is the inferred shape of a collection
+type Schema struct {
+	Collection string
+	Samples    int
+	Fields     []*Field
+}
+
+// InferSchema samples up to sampleSize documents from a collection and infers their shape
+func InferSchema(client *torm.Client, collection string, sampleSize int) (*Schema, error) {
+	if sampleSize <= 0 {
+		sampleSize = 100
+	}
+
+	docs, err := client.Model(collection, nil).Query().Limit(sampleSize).Exec()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample collection %s: %w", collection, err)
+	}
+
+	return InferFromDocuments(collection, docs), nil
+}
+
+// InferFromDocuments infers a schema from already fetched documents
+func InferFromDocuments(collection string, docs []map[string]interface{}) *Schema {
+	root := newObserved()
+	for _, doc := range docs {
+		root.addObject(doc)
+	}
+
+	return &Schema{
+		Collection: collection,
+		Samples:    len(docs),
+		Fields:     root.memberFields(),
+	}
+}
+
+// observed accumulates the values seen for one field
+type observed struct {
+	seen    int
+	nulls   int
+	objects int
+	kinds   map[Kind]bool
+	members map[string]*observed
+	elem    *observed
+}
+
+func newObserved() *observed {
+	return &observed{kinds: make(map[Kind]bool)}
+}
+
+func (o *observed) add(value interface{}) {
+	o.seen++
+
+	switch v := value.(type) {
+	case nil:
+		o.nulls++
+	case bool:
+		o.kinds[KindBool] = true
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			o.kinds[KindInt] = true
+		} else {
+			o.kinds[KindFloat] = true
+		}
+	case string:
+		if looksLikeTime(v) {
+			o.kinds[KindTime] = true
+		} else {
+			o.kinds[KindString] = true
+		}
+	case map[string]interface{}:
+		o.kinds[KindObject] = true
+		o.addObject(v)
+	case []interface{}:
+		o.kinds[KindArray] = true
+		if o.elem == nil {
+			o.elem = newObserved()
+		}
+		for _, item := range v {
+			o.elem.add(item)
+		}
+	default:
+		o.kinds[KindAny] = true
+	}
+}
+
+func (o *observed) addObject(doc map[string]interface{}) {
+	o.objects++
+	if o.members == nil {
+		o.members = make(map[string]*observed)
+	}
+	for key, value := range doc {
+		member, ok := o.members[key]
+		if !ok {
+			member = newObserved()
+			o.members[key] = member
+		}
+		member.add(value)
+	}
+}
+
+// memberFields resolves the members of an object, sorted with id first
+func (o *observed) memberFields() []*Field {
+	names := make([]string, 0, len(o.members))
+	for name := range o.members {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == "id" || names[j] == "id" {
+			return names[i] == "id"
+		}
+		return names[i] < names[j]
+	})
+
+	fields := make([]*Field, 0, len(names))
+	for _, name := range names {
+		member := o.members[name]
+		field := member.resolve(name)
+		if member.seen < o.objects {
+			field.Nullable = true
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// resolve reconciles the observed kinds into a single field description
+func (o *observed) resolve(name string) *Field {
+	field := &Field{Name: name, Nullable: o.nulls > 0}
+
+	kinds := make([]Kind, 0, len(o.kinds))
+	for kind := range o.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	switch {
+	case len(kinds) == 0:
+		field.Kind = KindAny
+		field.Nullable = true
+	case len(kinds) == 1:
+		field.Kind = kinds[0]
+	case len(kinds) == 2 && o.kinds[KindInt] && o.kinds[KindFloat]:
+		field.Kind = KindFloat
+	case len(kinds) == 2 && o.kinds[KindString] && o.kinds[KindTime]:
+		field.Kind = KindString
+	default:
+		field.Kind = KindAny
+		field.Conflicts = kinds
+	}
+
+	switch field.Kind {
+	case KindObject:
+		field.Fields = o.memberFields()
+	case KindArray:
+		if o.elem != nil && o.elem.seen > 0 {
+			field.Elem = o.elem.resolve(name)
+		} else {
+			field.Elem = &Field{Name: name, Kind: KindAny}
+		}
+	}
+
+	return field
+}
+
+// looksLikeTime reports whether a string holds an RFC 3339 timestamp or date
+func looksLikeTime(s string) bool {
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return true
+	}
+	if len(s) == len("2006-01-02") {
+		if _, err := time.Parse("2006-01-02", s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateStruct renders Go declarations for a schema: the model struct, any
+// nested object types, and the GetID/SetID/ToMap methods of the Document interface
+func GenerateStruct(schema *Schema, structName string) ([]byte, error) {
+	g := &generator{}
+	g.model(schema, structName)
+	return format.Source(g.buf.Bytes())
+}
+
+// GenerateFile renders a complete Go source file for a schema
+func GenerateFile(pkg string, schema *Schema, structName string) ([]byte, error) {
+	g := &generator{}
+	g.model(schema, structName)
+
+	var file bytes.Buffer
+	fmt.Fprintf(&file, "// Code generated by torm-gen from collection %q. DO NOT EDIT.\n\n", schema.Collection)
+	fmt.Fprintf(&file, "package %s\n\n", pkg)
+	if g.usesTime {
+		file.WriteString("import \"time\"\n\n")
+	}
+	file.Write(g.buf.Bytes())
+
+	return format.Source(file.Bytes())
+}
+
+type generator struct {
+	buf      bytes.Buffer
+	pending  []pendingType
+	usesTime bool
+}
+
+type pendingType struct {
+	name   string
+	fields []*Field
+}
+
+func (g *generator) model(schema *Schema, structName string) {
+	// GetID returns a string, so the id is one whatever the samples held
+	fields := append([]*Field{{Name: "id", Kind: KindString}}, schema.Fields...)
+	if len(schema.Fields) > 0 && schema.Fields[0].Name == "id" {
+		fields = append(fields[:1], schema.Fields[1:]...)
+	}
+	names := fieldNames(fields)
+
+	fmt.Fprintf(&g.buf, "// %s is a model for the %s collection\n", structName, schema.Collection)
+	g.structType(structName, fields, names)
+
+	fmt.Fprintf(&g.buf, "func (m *%s) GetID() string {\n\treturn m.ID\n}\n\n", structName)
+	fmt.Fprintf(&g.buf, "func (m *%s) SetID(id string) {\n\tm.ID = id\n}\n\n", structName)
+	g.toMap(structName, fields, names)
+
+	for i := 0; i < len(g.pending); i++ {
+		nested := g.pending[i]
+		fmt.Fprintf(&g.buf, "\n// %s is a nested object of %s\n", nested.name, structName)
+		g.structType(nested.name, nested.fields, fieldNames(nested.fields))
+	}
+}
+
+// toMap renders ToMap. Like the omitempty of their tags, fields that may
+// be null are only set when they are.
+func (g *generator) toMap(structName string, fields []*Field, names []string) {
+	var optional []int
+	fmt.Fprintf(&g.buf, "func (m *%s) ToMap() map[string]interface{} {\n", structName)
+	fmt.Fprint(&g.buf, "\tdata := map[string]interface{}{\n")
+	for i, field := range fields {
+		if omitEmpty(field) {
+			optional = append(optional, i)
+			continue
+		}
+		fmt.Fprintf(&g.buf, "\t\t%q: m.%s,\n", field.Name, names[i])
+	}
+	g.buf.WriteString("\t}\n")
+	for _, i := range optional {
+		fmt.Fprintf(&g.buf, "\tif m.%s != nil {\n\t\tdata[%q] = m.%s\n\t}\n", names[i], fields[i].Name, names[i])
+	}
+	g.buf.WriteString("\treturn data\n}\n")
+}
+
+// omitEmpty reports whether a struct field is tagged omitempty. Such
+// fields are pointers or interfaces, nil when null.
+func omitEmpty(field *Field) bool {
+	return field.Nullable && field.Name != "id"
+}
+
+func (g *generator) structType(name string, fields []*Field, names []string) {
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	for i, field := range fields {
+		tag := field.Name
+		if omitEmpty(field) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s `json:\"%s\"`", names[i], g.goType(name, names[i], field, true), tag)
+		if len(field.Conflicts) > 0 {
+			fmt.Fprintf(&g.buf, " // conflicting types: %s", joinKinds(field.Conflicts))
+		}
+		g.buf.WriteString("\n")
+	}
+	g.buf.WriteString("}\n\n")
+}
+
+// goType maps a field, declared as fieldName, to a Go type, queueing
+// nested struct declarations
+func (g *generator) goType(parent, fieldName string, field *Field, top bool) string {
+	var typ string
+
+	switch field.Kind {
+	case KindString:
+		typ = "string"
+	case KindInt:
+		typ = "int"
+	case KindFloat:
+		typ = "float64"
+	case KindBool:
+		typ = "bool"
+	case KindTime:
+		g.usesTime = true
+		typ = "time.Time"
+	case KindObject:
+		typ = parent + fieldName
+		g.pending = append(g.pending, pendingType{name: typ, fields: field.Fields})
+	case KindArray:
+		return "[]" + g.goType(parent, fieldName, field.Elem, false)
+	default:
+		return "interface{}"
+	}
+
+	if top && omitEmpty(field) {
+		return "*" + typ
+	}
+	return typ
+}
+
+// fieldNames returns the Go names of a struct's fields. Keys that map to
+// the same name, like "user_id" and "userId", get a numeric suffix in
+// the order of fields.
+func fieldNames(fields []*Field) []string {
+	names := make([]string, len(fields))
+	taken := make(map[string]bool, len(fields))
+	for i, field := range fields {
+		name := goName(field.Name)
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s%d", goName(field.Name), n)
+		}
+		names[i], taken[name] = name, true
+	}
+	return names
+}
+
+var initialisms = map[string]string{
+	"id": "ID", "url": "URL", "uri": "URI", "api": "API", "http": "HTTP",
+	"json": "JSON", "uuid": "UUID", "ip": "IP", "sku": "SKU",
+}
+
+// goName converts a document key like "user_id" or "createdAt" to an exported Go identifier
+func goName(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var name strings.Builder
+	for _, w := range words {
+		lower := strings.ToLower(w)
+		if initialism, ok := initialisms[lower]; ok {
+			name.WriteString(initialism)
+			continue
+		}
+		r := []rune(w)
+		name.WriteRune(unicode.ToUpper(r[0]))
+		name.WriteString(string(r[1:]))
+	}
+
+	if name.Len() == 0 {
+		return "Field"
+	}
+	if out := name.String(); unicode.IsDigit([]rune(out)[0]) {
+		return "F" + out
+	}
+	return name.String()
+}
+
+func joinKinds(kinds []Kind) string {
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = string(kind)
+	}
+	return strings.Join(names, ", ")
+}
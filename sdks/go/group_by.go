@@ -0,0 +1,375 @@
+package torm
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// GroupOverflow is what GroupBy does once a field has more distinct values
+// than GroupOptions.MaxGroups
+type GroupOverflow string
+
+const (
+	// GroupTopK keeps MaxGroups counters with the space-saving algorithm:
+	// the most frequent groups are found, with approximate counts
+	GroupTopK GroupOverflow = "top_k"
+	// GroupSpill writes groups to files on disk and merges them at the
+	// end, for exact counts
+	GroupSpill GroupOverflow = "spill"
+)
+
+// spillPartitions is how many files spilled groups are spread over. Merging
+// reads one at a time, holding about 1/16th of the distinct groups.
+const spillPartitions = 16
+
+// GroupOptions configures GroupBy
+type GroupOptions struct {
+	Filters   []QueryFilter // Only count matching documents
+	MaxGroups int           // Distinct groups held in memory (default 10000)
+	Overflow  GroupOverflow // What happens past MaxGroups (default GroupTopK)
+	Limit     int           // Groups returned, largest first (0 returns all)
+	SpillDir  string        // Where GroupSpill writes its files (default os.TempDir())
+	PageSize  int           // Documents fetched per query page (default 500)
+}
+
+// Group is the number of documents sharing a value of the grouped field
+type Group struct {
+	Key   interface{} // Nil for documents without the field
+	Count int64
+	// MaxError bounds how far Count may overstate the true count, which
+	// lies in [Count-MaxError, Count]. Zero when the result is exact.
+	MaxError int64
+}
+
+// GroupResult is the outcome of GroupBy
+type GroupResult struct {
+	Field     string
+	Groups    []Group // Largest first, ties by key
+	Documents int64   // Documents counted
+	// Exact is false when GroupTopK had to evict groups. Every group with
+	// more than Documents/MaxGroups documents is still reported.
+	Exact  bool
+	Spills int // Times GroupSpill wrote groups to disk
+}
+
+// GroupBy counts the documents of the collection by the value of field,
+// reading the collection a page at a time. At most opts.MaxGroups groups
+// are held in memory; see GroupOverflow for what happens beyond that.
+func (m *Model) GroupBy(ctx context.Context, field string, opts GroupOptions) (*GroupResult, error) {
+	m = m.prioritizedBy(ctx)
+	if opts.MaxGroups <= 0 {
+		opts.MaxGroups = 10000
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = 500
+	}
+
+	var counter groupCounter
+	switch opts.Overflow {
+	case "", GroupTopK:
+		counter = newSpaceSaving(opts.MaxGroups)
+	case GroupSpill:
+		spill, err := newSpillCounter(opts.MaxGroups, opts.SpillDir)
+		if err != nil {
+			return nil, err
+		}
+		defer spill.close()
+		counter = spill
+	default:
+		return nil, fmt.Errorf("unknown group overflow %q", opts.Overflow)
+	}
+
+	result := &GroupResult{Field: field}
+	lastID := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		qb := m.Query().Sort("id", Asc).Limit(pageSize).Select(field)
+		qb.Match(opts.Filters...)
+		if lastID != "" {
+			qb.Filter("id", Gt, lastID)
+		}
+		docs, err := qb.Exec()
+		if err != nil {
+			return nil, fmt.Errorf("group by page after %q failed: %w", lastID, err)
+		}
+
+		for _, doc := range docs {
+			key, err := groupKey(doc[field])
+			if err != nil {
+				return nil, fmt.Errorf("cannot group document %v: %w", doc["id"], err)
+			}
+			if err := counter.add(key, doc[field]); err != nil {
+				return nil, err
+			}
+			result.Documents++
+			lastID = fmt.Sprintf("%v", doc["id"])
+		}
+		if len(docs) < pageSize {
+			break
+		}
+	}
+
+	groups, exact, err := counter.groups()
+	if err != nil {
+		return nil, err
+	}
+	sortGroups(groups)
+	if opts.Limit > 0 && len(groups) > opts.Limit {
+		groups = groups[:opts.Limit]
+	}
+	result.Groups = groups
+	result.Exact = exact
+	if spill, ok := counter.(*spillCounter); ok {
+		result.Spills = spill.spills
+	}
+	return result, nil
+}
+
+// GroupBy counts the collection's documents by the value of field; see
+// Model.GroupBy
+func (c *Collection[T]) GroupBy(ctx context.Context, field string, opts GroupOptions) (*GroupResult, error) {
+	c = c.prioritizedBy(ctx)
+	return c.model().GroupBy(ctx, field, opts)
+}
+
+// groupKey is the canonical form of a group value, so equal JSON values
+// share a group
+func groupKey(value interface{}) (string, error) {
+	raw, err := json.Marshal(value)
+	return string(raw), err
+}
+
+func sortGroups(groups []Group) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return fmt.Sprint(groups[i].Key) < fmt.Sprint(groups[j].Key)
+	})
+}
+
+// groupCounter counts documents per group within a memory bound
+type groupCounter interface {
+	add(key string, value interface{}) error
+	groups() ([]Group, bool, error) // Groups and whether they are exact
+}
+
+// spaceSaving is the space-saving top-K algorithm: a full table replaces
+// its smallest counter, which the newcomer inherits as possible error
+type spaceSaving struct {
+	capacity int
+	index    map[string]*ssCounter
+	heap     ssHeap // Min-heap by count
+	evicted  bool
+}
+
+type ssCounter struct {
+	key   string
+	value interface{}
+	count int64
+	err   int64
+	pos   int
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{capacity: capacity, index: make(map[string]*ssCounter)}
+}
+
+func (s *spaceSaving) add(key string, value interface{}) error {
+	if counter, ok := s.index[key]; ok {
+		counter.count++
+		heap.Fix(&s.heap, counter.pos)
+		return nil
+	}
+	if len(s.heap) < s.capacity {
+		counter := &ssCounter{key: key, value: value, count: 1}
+		s.index[key] = counter
+		heap.Push(&s.heap, counter)
+		return nil
+	}
+
+	s.evicted = true
+	smallest := s.heap[0]
+	delete(s.index, smallest.key)
+	smallest.key, smallest.value = key, value
+	smallest.err = smallest.count
+	smallest.count++
+	s.index[key] = smallest
+	heap.Fix(&s.heap, 0)
+	return nil
+}
+
+func (s *spaceSaving) groups() ([]Group, bool, error) {
+	groups := make([]Group, 0, len(s.heap))
+	for _, counter := range s.heap {
+		groups = append(groups, Group{Key: counter.value, Count: counter.count, MaxError: counter.err})
+	}
+	return groups, !s.evicted, nil
+}
+
+type ssHeap []*ssCounter
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos, h[j].pos = i, j
+}
+
+func (h *ssHeap) Push(x interface{}) {
+	counter := x.(*ssCounter)
+	counter.pos = len(*h)
+	*h = append(*h, counter)
+}
+
+func (h *ssHeap) Pop() interface{} {
+	old := *h
+	counter := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return counter
+}
+
+// spillCounter counts in memory and, once it holds more than limit
+// groups, appends their partial counts to partition files on disk
+type spillCounter struct {
+	limit  int
+	dir    string
+	counts map[string]*Group
+	files  []*os.File
+	spills int
+}
+
+// spilledGroup is a line of a spill file
+type spilledGroup struct {
+	Key   json.RawMessage `json:"k"`
+	Count int64           `json:"c"`
+}
+
+func newSpillCounter(limit int, parent string) (*spillCounter, error) {
+	dir, err := os.MkdirTemp(parent, "torm-groupby-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	return &spillCounter{limit: limit, dir: dir, counts: make(map[string]*Group)}, nil
+}
+
+func (s *spillCounter) add(key string, value interface{}) error {
+	if group, ok := s.counts[key]; ok {
+		group.Count++
+		return nil
+	}
+	if len(s.counts) >= s.limit {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	s.counts[key] = &Group{Key: value, Count: 1}
+	return nil
+}
+
+// spill appends the groups in memory to their partition files
+func (s *spillCounter) spill() error {
+	if s.files == nil {
+		s.files = make([]*os.File, spillPartitions)
+		for i := range s.files {
+			file, err := os.Create(filepath.Join(s.dir, fmt.Sprintf("part-%02d.jsonl", i)))
+			if err != nil {
+				return fmt.Errorf("failed to create spill file: %w", err)
+			}
+			s.files[i] = file
+		}
+	}
+
+	writers := make([]*bufio.Writer, len(s.files))
+	for i, file := range s.files {
+		writers[i] = bufio.NewWriter(file)
+	}
+	for key, group := range s.counts {
+		line, err := json.Marshal(spilledGroup{Key: json.RawMessage(key), Count: group.Count})
+		if err != nil {
+			return err
+		}
+		if _, err := writers[spillPartition(key)].Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write spill file: %w", err)
+		}
+	}
+	for _, w := range writers {
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write spill file: %w", err)
+		}
+	}
+	s.counts = make(map[string]*Group)
+	s.spills++
+	return nil
+}
+
+func spillPartition(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % spillPartitions)
+}
+
+// groups merges the spill files one partition at a time
+func (s *spillCounter) groups() ([]Group, bool, error) {
+	if s.files == nil {
+		groups := make([]Group, 0, len(s.counts))
+		for _, group := range s.counts {
+			groups = append(groups, *group)
+		}
+		return groups, true, nil
+	}
+	if err := s.spill(); err != nil {
+		return nil, false, err
+	}
+
+	var groups []Group
+	for _, file := range s.files {
+		if _, err := file.Seek(0, 0); err != nil {
+			return nil, false, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		merged := make(map[string]*Group)
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var line spilledGroup
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				return nil, false, fmt.Errorf("failed to decode spill file: %w", err)
+			}
+			key := string(line.Key)
+			if group, ok := merged[key]; ok {
+				group.Count += line.Count
+				continue
+			}
+			var value interface{}
+			if err := json.Unmarshal(line.Key, &value); err != nil {
+				return nil, false, fmt.Errorf("failed to decode spilled key: %w", err)
+			}
+			merged[key] = &Group{Key: value, Count: line.Count}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, false, fmt.Errorf("failed to read spill file: %w", err)
+		}
+		for _, group := range merged {
+			groups = append(groups, *group)
+		}
+	}
+	return groups, true, nil
+}
+
+// close removes the spill files
+func (s *spillCounter) close() {
+	for _, file := range s.files {
+		file.Close()
+	}
+	os.RemoveAll(s.dir)
+}
@@ -0,0 +1,72 @@
+package torm
+
+import (
+	"fmt"
+)
+
+// Operation identifies the collection operation a document passes through
+type Operation string
+
+const (
+	OpCreate   Operation = "create"
+	OpFind     Operation = "find"
+	OpFindByID Operation = "find_by_id"
+	OpQuery    Operation = "query"
+	OpSave     Operation = "save"
+	OpUpdate   Operation = "update"
+	OpDelete   Operation = "delete"
+	OpCount    Operation = "count"
+)
+
+// Guard inspects a document on its way in or out of a collection.
+// Returning a non-nil error removes the document from read results
+// or aborts the write. A guard that panics fails the operation with a
+// *PanicError, or withholds the document where rejected ones are dropped.
+type Guard func(op Operation, doc map[string]interface{}) error
+
+// GuardError is returned when a guard rejects a write or a single-document read
+type GuardError struct {
+	Op  Operation
+	ID  string
+	Err error
+}
+
+func (e *GuardError) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("guard rejected %s of document %s: %v", e.Op, e.ID, e.Err)
+	}
+	return fmt.Sprintf("guard rejected %s: %v", e.Op, e.Err)
+}
+
+func (e *GuardError) Unwrap() error {
+	return e.Err
+}
+
+// WithGuard installs a guard that runs on every document read and write payload
+func WithGuard(guard Guard) CollectionOption {
+	return func(o *collectionOptions) {
+		o.guard = guard
+	}
+}
+
+// checkGuard runs the configured guard, counting rejections
+func (o *collectionOptions) checkGuard(op Operation, doc map[string]interface{}) error {
+	if o.guard == nil {
+		return nil
+	}
+	var rejected error
+	if err := protect("guard", o.collection, docID(doc), func() { rejected = o.guard(op, doc) }); err != nil {
+		return err
+	}
+	if rejected != nil {
+		o.guardRejections.Add(1)
+		id, _ := doc["id"].(string)
+		return &GuardError{Op: op, ID: id, Err: rejected}
+	}
+	return nil
+}
+
+// GuardRejections returns how many documents the guard has rejected so far
+func (c *Collection[T]) GuardRejections() int64 {
+	return c.options.guardRejections.Load()
+}
@@ -0,0 +1,107 @@
+package torm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidHint is returned by queries with a hint that has no key or
+// whose value cannot be sent as JSON
+var ErrInvalidHint = errors.New("torm: invalid query hint")
+
+// Hint keys the typed conveniences set
+const (
+	HintIndex          = "index"
+	HintTimeout        = "timeout_ms"
+	HintReadPreference = "read_preference"
+)
+
+// ReadPreference tells the server which replicas may answer a query
+type ReadPreference string
+
+const (
+	ReadPrimary            ReadPreference = "primary"
+	ReadPrimaryPreferred   ReadPreference = "primary_preferred"
+	ReadSecondary          ReadPreference = "secondary"
+	ReadSecondaryPreferred ReadPreference = "secondary_preferred"
+	ReadNearest            ReadPreference = "nearest"
+)
+
+// Hint passes an execution hint to the server under the "options" key of
+// the query payload. Keys the SDK does not know are sent as they are; the
+// value only has to be JSON-serializable, which Exec checks. Setting a key
+// again replaces its value.
+func (qb *QueryBuilder) Hint(key string, value interface{}) *QueryBuilder {
+	if qb.hints == nil {
+		qb.hints = make(map[string]interface{})
+	}
+	qb.hints[key] = value
+	return qb
+}
+
+// UseIndex asks the server to run the query on the named index
+func (qb *QueryBuilder) UseIndex(name string) *QueryBuilder {
+	return qb.Hint(HintIndex, name)
+}
+
+// ServerTimeout asks the server to give up on the query after d. It is
+// sent in milliseconds and does not change the client's own timeout.
+func (qb *QueryBuilder) ServerTimeout(d time.Duration) *QueryBuilder {
+	return qb.Hint(HintTimeout, d.Milliseconds())
+}
+
+// ReadPreference asks the server to read from the given replicas
+func (qb *QueryBuilder) ReadPreference(pref ReadPreference) *QueryBuilder {
+	return qb.Hint(HintReadPreference, string(pref))
+}
+
+// Hints returns a copy of the query's hints
+func (qb *QueryBuilder) Hints() map[string]interface{} {
+	if len(qb.hints) == 0 {
+		return nil
+	}
+	hints := make(map[string]interface{}, len(qb.hints))
+	for key, value := range qb.hints {
+		hints[key] = value
+	}
+	return hints
+}
+
+// validateHints checks that every hint can be sent
+func (qb *QueryBuilder) validateHints() error {
+	for _, key := range qb.hintKeys() {
+		if key == "" {
+			return fmt.Errorf("%w: hint without a key", ErrInvalidHint)
+		}
+		if _, err := json.Marshal(qb.hints[key]); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidHint, key, err)
+		}
+	}
+	return nil
+}
+
+func (qb *QueryBuilder) hintKeys() []string {
+	keys := make([]string, 0, len(qb.hints))
+	for key := range qb.hints {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hintString writes the hints for String, e.g. " HINT index=by_age,timeout_ms=500"
+func (qb *QueryBuilder) hintString() string {
+	if len(qb.hints) == 0 {
+		return ""
+	}
+	keys := qb.hintKeys()
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + formatQueryValue(qb.hints[key])
+	}
+	return " HINT " + strings.Join(parts, ",")
+}
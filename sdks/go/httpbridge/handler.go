@@ -0,0 +1,239 @@
+// Package httpbridge serves a torm collection over a plain REST API, for
+// admin tools and other thin passthroughs
+package httpbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/toonstore/torm-go"
+)
+
+// HandlerOptions configures NewHandler
+type HandlerOptions struct {
+	// Methods lists the enabled HTTP methods; others get 405. Empty enables
+	// GET, POST, PUT, PATCH and DELETE.
+	Methods []string
+
+	// Authorize runs before every request. A non-nil error rejects the
+	// request with 403 and the error message.
+	Authorize func(r *http.Request) error
+
+	// RedactFields are removed from every document in responses
+	RedactFields []string
+	// Redact, when set, may further edit each document before it is written
+	Redact func(r *http.Request, doc map[string]interface{})
+}
+
+var defaultMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+type handler struct {
+	model   *torm.Model
+	opts    HandlerOptions
+	methods map[string]bool
+	allow   string
+}
+
+// NewHandler returns an http.Handler serving the collection:
+//
+//	GET    /       query documents, see ParseQuery
+//	GET    /{id}   fetch a document
+//	POST   /       create a document from the JSON body
+//	PUT    /{id}   replace a document
+//	PATCH  /{id}   merge the JSON body into a document; null removes a field
+//	DELETE /{id}   delete a document
+//
+// Mount it with http.StripPrefix when serving below the root. Errors are
+// written as {"error": message}: validation failures and bad queries as 400,
+// unknown documents as 404, conflicts as 409 and server failures as 502.
+func NewHandler(collection *torm.Model, opts HandlerOptions) http.Handler {
+	methods := opts.Methods
+	if len(methods) == 0 {
+		methods = defaultMethods
+	}
+
+	h := &handler{model: collection, opts: opts, methods: make(map[string]bool)}
+	for _, method := range methods {
+		h.methods[strings.ToUpper(method)] = true
+	}
+	h.allow = strings.Join(methods, ", ")
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.methods[r.Method] {
+		w.Header().Set("Allow", h.allow)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if h.opts.Authorize != nil {
+		if err := h.opts.Authorize(r); err != nil {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+	}
+
+	id := strings.Trim(r.URL.Path, "/")
+	if strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		h.query(w, r)
+	case r.Method == http.MethodGet:
+		h.get(w, r, id)
+	case r.Method == http.MethodPost && id == "":
+		h.create(w, r)
+	case (r.Method == http.MethodPut || r.Method == http.MethodPatch) && id != "":
+		h.update(w, r, id, r.Method == http.MethodPatch)
+	case r.Method == http.MethodDelete && id != "":
+		h.delete(w, r, id)
+	default:
+		w.Header().Set("Allow", h.allow)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *handler) query(w http.ResponseWriter, r *http.Request) {
+	qb := h.model.Query()
+	if err := ParseQuery(qb, r.URL.Query()); err != nil {
+		writeFailure(w, err)
+		return
+	}
+
+	docs, err := qb.Exec()
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	for _, doc := range docs {
+		h.redact(r, doc)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"documents": docs, "count": len(docs)})
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request, id string) {
+	doc, ok := h.find(w, id)
+	if !ok {
+		return
+	}
+	h.redact(r, doc)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request) {
+	body, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := h.model.Create(body)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	h.redact(r, doc)
+	writeJSON(w, http.StatusCreated, doc)
+}
+
+func (h *handler) update(w http.ResponseWriter, r *http.Request, id string, merge bool) {
+	body, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+	stored, ok := h.find(w, id)
+	if !ok {
+		return
+	}
+
+	if merge {
+		for field, value := range body {
+			if value == nil {
+				delete(stored, field)
+			} else {
+				stored[field] = value
+			}
+		}
+		body = stored
+	}
+	body["id"] = id
+
+	doc, err := h.model.Update(id, body)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	h.redact(r, doc)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := h.find(w, id); !ok {
+		return
+	}
+	if _, err := h.model.Delete(id); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// find loads a document, writing 404 or the failure when it cannot
+func (h *handler) find(w http.ResponseWriter, id string) (map[string]interface{}, bool) {
+	doc, err := h.model.FindByID(id)
+	if errors.Is(err, torm.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "document not found")
+		return nil, false
+	}
+	if err != nil {
+		writeFailure(w, err)
+		return nil, false
+	}
+	return doc, true
+}
+
+func (h *handler) redact(r *http.Request, doc map[string]interface{}) {
+	for _, field := range h.opts.RedactFields {
+		delete(doc, field)
+	}
+	if h.opts.Redact != nil {
+		h.opts.Redact(r, doc)
+	}
+}
+
+// readDocument decodes a JSON object request body
+func readDocument(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	var doc map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil || doc == nil {
+		writeError(w, http.StatusBadRequest, "body must be a JSON object")
+		return nil, false
+	}
+	return doc, true
+}
+
+// writeFailure maps an SDK error to a status code
+func writeFailure(w http.ResponseWriter, err error) {
+	var conflict *torm.ConflictError
+	switch {
+	case errors.Is(err, torm.ErrValidation), errors.Is(err, torm.ErrInvalidFilter):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &conflict):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusBadGateway, err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,123 @@
+package httpbridge
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/toonstore/torm-go"
+)
+
+// Reserved query parameters; every other parameter is a filter
+const (
+	paramSort   = "sort"   // Field to sort by, "-field" for descending
+	paramLimit  = "limit"  // Maximum number of documents
+	paramSkip   = "skip"   // Documents to skip
+	paramFields = "fields" // Comma-separated projection
+)
+
+var operators = map[string]torm.QueryOperator{
+	"eq":       torm.Eq,
+	"ne":       torm.Ne,
+	"gt":       torm.Gt,
+	"gte":      torm.Gte,
+	"lt":       torm.Lt,
+	"lte":      torm.Lte,
+	"contains": torm.Contains,
+	"in":       torm.In,
+	"not_in":   torm.NotIn,
+}
+
+// ParseQuery applies URL query parameters to a query builder.
+//
+// A parameter "field=value" filters by equality and "field[op]=value" uses
+// one of eq, ne, gt, gte, lt, lte, contains, in and not_in; in and not_in
+// take comma-separated lists. Values that read as numbers or booleans are
+// typed as such unless wrapped in double quotes. The reserved parameters
+// sort, limit, skip and fields control ordering, paging and projection.
+// Malformed parameters return an error wrapping torm.ErrInvalidFilter.
+func ParseQuery(qb *torm.QueryBuilder, values url.Values) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, raw := range values[key] {
+			if err := applyParam(qb, key, raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyParam(qb *torm.QueryBuilder, key, raw string) error {
+	switch key {
+	case paramSort:
+		if field, ok := strings.CutPrefix(raw, "-"); ok {
+			qb.Sort(field, torm.Desc)
+		} else {
+			qb.Sort(raw, torm.Asc)
+		}
+		return nil
+	case paramLimit, paramSkip:
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%w: %s must be a non-negative integer", torm.ErrInvalidFilter, key)
+		}
+		if key == paramLimit {
+			qb.Limit(n)
+		} else {
+			qb.Skip(n)
+		}
+		return nil
+	case paramFields:
+		qb.Select(strings.Split(raw, ",")...)
+		return nil
+	}
+
+	field, operator := key, torm.Eq
+	if open := strings.IndexByte(key, '['); open >= 0 {
+		name, ok := strings.CutSuffix(key[open+1:], "]")
+		op, known := operators[name]
+		if !ok || !known || open == 0 {
+			return fmt.Errorf("%w: unsupported parameter %q", torm.ErrInvalidFilter, key)
+		}
+		field, operator = key[:open], op
+	}
+
+	switch operator {
+	case torm.In, torm.NotIn:
+		items := make([]interface{}, 0)
+		for _, item := range strings.Split(raw, ",") {
+			items = append(items, parseValue(field, item))
+		}
+		qb.Filter(field, operator, items)
+	case torm.Contains:
+		qb.Filter(field, operator, raw)
+	default:
+		qb.Filter(field, operator, parseValue(field, raw))
+	}
+	return nil
+}
+
+// parseValue types a parameter value. Ids are always strings.
+func parseValue(field, raw string) interface{} {
+	if field == "id" {
+		return raw
+	}
+	if len(raw) >= 2 && strings.HasPrefix(raw, `"`) && strings.HasSuffix(raw, `"`) {
+		return raw[1 : len(raw)-1]
+	}
+	if raw == "true" || raw == "false" {
+		return raw == "true"
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f
+	}
+	return raw
+}
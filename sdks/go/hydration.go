@@ -0,0 +1,409 @@
+package torm
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TypeField is the discriminator stored with values of registered types,
+// naming the type to decode them into. Object values carry it inline;
+// other values are stored as {"_type": name, "value": value}.
+const TypeField = "_type"
+
+// ErrUnknownType is returned when a document cannot be decoded because the
+// concrete type of an interface field cannot be determined
+var ErrUnknownType = errors.New("torm: cannot determine concrete type")
+
+// typeRegistry maps discriminators to the types registered for them
+var typeRegistry = struct {
+	sync.RWMutex
+	byName map[string]func() interface{}
+	byType map[reflect.Type]string
+}{byName: map[string]func() interface{}{}, byType: map[reflect.Type]string{}}
+
+// RegisterType registers a concrete type for interface-typed model fields.
+// Values of the type are written with name as their TypeField, and fields
+// of an interface type the type implements are decoded with factory.
+// Register both a pointer and its element type by registering either:
+//
+//	torm.RegisterType("circle", func() interface{} { return &Circle{} })
+//
+// RegisterType panics when name is empty or already registered for
+// another type, as it is meant to be called from init.
+func RegisterType(name string, factory func() interface{}) {
+	if name == "" || factory == nil {
+		panic("torm: RegisterType needs a name and a factory")
+	}
+	typ := reflect.TypeOf(factory())
+	if typ == nil {
+		panic(fmt.Sprintf("torm: factory of type %q returns nil", name))
+	}
+
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+	if existing, ok := typeRegistry.byName[name]; ok && reflect.TypeOf(existing()) != typ {
+		panic(fmt.Sprintf("torm: type name %q registered for both %s and %s", name, reflect.TypeOf(existing()), typ))
+	}
+	typeRegistry.byName[name] = factory
+	typeRegistry.byType[typ] = name
+	if typ.Kind() == reflect.Pointer {
+		typeRegistry.byType[typ.Elem()] = name
+	} else {
+		typeRegistry.byType[reflect.PointerTo(typ)] = name
+	}
+}
+
+func registeredName(typ reflect.Type) (string, bool) {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+	name, ok := typeRegistry.byType[typ]
+	return name, ok
+}
+
+func registeredFactory(name string) (func() interface{}, bool) {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+	factory, ok := typeRegistry.byName[name]
+	return factory, ok
+}
+
+func typesRegistered() bool {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+	return len(typeRegistry.byName) > 0
+}
+
+// tagTypes adds the discriminator to values of registered types anywhere
+// in a document's maps and slices, copying only what changes
+func tagTypes(doc map[string]interface{}) (map[string]interface{}, error) {
+	if !typesRegistered() {
+		return doc, nil
+	}
+	tagged, _, err := tagValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	return tagged.(map[string]interface{}), nil
+}
+
+func tagValue(value interface{}) (interface{}, bool, error) {
+	switch v := value.(type) {
+	case nil, string, bool, float64, int, int64, json.Number:
+		return value, false, nil
+	case map[string]interface{}:
+		var out map[string]interface{}
+		for key, item := range v {
+			tagged, changed, err := tagValue(item)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed {
+				if out == nil {
+					out = cloneDoc(v)
+				}
+				out[key] = tagged
+			}
+		}
+		if out == nil {
+			return v, false, nil
+		}
+		return out, true, nil
+	case []interface{}:
+		var out []interface{}
+		for i, item := range v {
+			tagged, changed, err := tagValue(item)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed {
+				if out == nil {
+					out = append([]interface{}(nil), v...)
+				}
+				out[i] = tagged
+			}
+		}
+		if out == nil {
+			return v, false, nil
+		}
+		return out, true, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	name, ok := registeredName(rv.Type())
+	if !ok {
+		// Slices of an interface type, such as []Shape, may hold registered values
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Interface && !rv.IsNil() {
+			items := make([]interface{}, rv.Len())
+			for i := range items {
+				items[i] = rv.Index(i).Interface()
+			}
+			tagged, _, err := tagValue(items)
+			return tagged, true, err
+		}
+		return value, false, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode %s value: %w", name, err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, false, err
+	}
+	if object, ok := decoded.(map[string]interface{}); ok {
+		object[TypeField] = name
+		return object, true, nil
+	}
+	return map[string]interface{}{TypeField: name, "value": decoded}, true, nil
+}
+
+// hydrate decodes a document into target, a pointer to a model
+func hydrate(doc map[string]interface{}, target interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return hydrateJSON(data, target)
+}
+
+// hydrateJSON decodes a document as read into target, a pointer to a
+// model. Models without interface fields are decoded by encoding/json
+// alone; interface fields are set from the type their value's discriminator
+// names. Embedded structs follow encoding/json: untagged ones are
+// flattened into the document, ones with a json name nest under it.
+func hydrateJSON(data []byte, target interface{}) error {
+	fields := polyFieldsOf(reflect.TypeOf(target))
+	if len(fields) == 0 || (!typesRegistered() && allEmptyInterfaces(fields)) {
+		return json.Unmarshal(data, target)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return json.Unmarshal(data, target)
+	}
+	raws := make([]interface{}, len(fields))
+	for i, field := range fields {
+		raws[i] = takeField(doc, field.keys)
+	}
+	rest, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rest, target); err != nil {
+		return err
+	}
+
+	root := reflect.ValueOf(target)
+	for root.Kind() == reflect.Pointer {
+		if root.IsNil() {
+			root.Set(reflect.New(root.Type().Elem()))
+		}
+		root = root.Elem()
+	}
+	for i, field := range fields {
+		if raws[i] == nil {
+			continue
+		}
+		if err := field.set(root, raws[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// polyField is an interface-typed field of a model, or a slice of one
+type polyField struct {
+	index []int        // Field index path from the model struct
+	keys  []string     // Document keys leading to the value
+	typ   reflect.Type // The interface type, of the elements for slices
+	slice bool
+}
+
+func (f polyField) path() string {
+	return strings.Join(f.keys, ".")
+}
+
+func (f polyField) set(root reflect.Value, raw interface{}) error {
+	field := root
+	for i, x := range f.index {
+		if i > 0 {
+			for field.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+		}
+		field = field.Field(x)
+	}
+
+	if !f.slice {
+		value, err := resolveType(raw, f.typ, f.path())
+		if err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+
+	items, ok := raw.([]interface{})
+	if !ok {
+		return fmt.Errorf("%w of field %s (%s): expected an array, got %T", ErrUnknownType, f.path(), field.Type(), raw)
+	}
+	slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		value, err := resolveType(item, f.typ, fmt.Sprintf("%s[%d]", f.path(), i))
+		if err != nil {
+			return err
+		}
+		slice.Index(i).Set(value)
+	}
+	field.Set(slice)
+	return nil
+}
+
+// resolveType decodes a stored value into the type its discriminator names
+func resolveType(raw interface{}, typ reflect.Type, path string) (reflect.Value, error) {
+	if raw == nil {
+		return reflect.Zero(typ), nil
+	}
+	object, _ := raw.(map[string]interface{})
+	name, tagged := object[TypeField].(string)
+	if !tagged {
+		if typ.NumMethod() == 0 {
+			return reflect.ValueOf(raw), nil
+		}
+		return reflect.Value{}, fmt.Errorf("%w of field %s (%s): the value has no %q discriminator", ErrUnknownType, path, typ, TypeField)
+	}
+	factory, ok := registeredFactory(name)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%w of field %s (%s): type %q is not registered", ErrUnknownType, path, typ, name)
+	}
+
+	payload := interface{}(object)
+	if wrapped, ok := object["value"]; ok && len(object) == 2 {
+		payload = wrapped
+	} else {
+		object = cloneDoc(object)
+		delete(object, TypeField)
+		payload = object
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	instance := reflect.ValueOf(factory())
+	target := instance
+	if instance.Kind() != reflect.Pointer {
+		target = reflect.New(instance.Type())
+		target.Elem().Set(instance)
+	}
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("field %s: cannot decode %s: %w", path, name, err)
+	}
+
+	switch {
+	case instance.Kind() == reflect.Pointer && target.Type().AssignableTo(typ):
+		return target, nil
+	case target.Elem().Type().AssignableTo(typ):
+		return target.Elem(), nil
+	case target.Type().AssignableTo(typ):
+		return target, nil
+	}
+	return reflect.Value{}, fmt.Errorf("%w of field %s (%s): registered type %q (%s) does not implement it", ErrUnknownType, path, typ, name, instance.Type())
+}
+
+// takeField removes and returns the value at keys, nil when absent
+func takeField(doc map[string]interface{}, keys []string) interface{} {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := doc[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		doc = next
+	}
+	last := keys[len(keys)-1]
+	value := doc[last]
+	delete(doc, last)
+	return value
+}
+
+func allEmptyInterfaces(fields []polyField) bool {
+	for _, field := range fields {
+		if field.typ.NumMethod() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var polyFieldCache sync.Map // reflect.Type -> []polyField
+
+// polyFieldsOf lists the interface fields of a model type, found through
+// pointers, embedded structs and nested structs
+func polyFieldsOf(typ reflect.Type) []polyField {
+	if cached, ok := polyFieldCache.Load(typ); ok {
+		return cached.([]polyField)
+	}
+	var fields []polyField
+	root := typ
+	for root != nil && root.Kind() == reflect.Pointer {
+		root = root.Elem()
+	}
+	if root != nil && root.Kind() == reflect.Struct {
+		collectPolyFields(root, nil, nil, map[reflect.Type]bool{}, &fields)
+	}
+	polyFieldCache.Store(typ, fields)
+	return fields
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func collectPolyFields(typ reflect.Type, index []int, keys []string, seen map[reflect.Type]bool, out *[]polyField) {
+	if seen[typ] {
+		return
+	}
+	seen[typ] = true
+	defer delete(seen, typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, tagged := jsonFieldName(sf)
+		if name == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		ft := sf.Type
+
+		inner := ft
+		if inner.Kind() == reflect.Pointer {
+			inner = inner.Elem()
+		}
+		decodesItself := reflect.PointerTo(inner).Implements(unmarshalerType) || reflect.PointerTo(inner).Implements(textUnmarshalerType)
+		if sf.Anonymous && !tagged && inner.Kind() == reflect.Struct && !decodesItself {
+			collectPolyFields(inner, fieldIndex, keys, seen, out)
+			continue
+		}
+
+		fieldKeys := append(append([]string(nil), keys...), name)
+		switch {
+		case ft.Kind() == reflect.Interface:
+			*out = append(*out, polyField{index: fieldIndex, keys: fieldKeys, typ: ft})
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Interface:
+			*out = append(*out, polyField{index: fieldIndex, keys: fieldKeys, typ: ft.Elem(), slice: true})
+		case inner.Kind() == reflect.Struct && !decodesItself:
+			collectPolyFields(inner, fieldIndex, fieldKeys, seen, out)
+		}
+	}
+}
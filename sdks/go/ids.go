@@ -0,0 +1,70 @@
+package torm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrMissingID is returned when a create requiring ids got none back; see IDOptions
+var ErrMissingID = errors.New("torm: created document has no id")
+
+// IDOptions configures how the id of a created document reaches the models
+type IDOptions struct {
+	// Required fails creates whose response has no id, neither at the top
+	// level nor in its data, with ErrMissingID. The document is stored.
+	Required bool
+	// KeepInput leaves the model passed to Create untouched; only the
+	// returned model gets the id. Save still sets the id it saves.
+	KeepInput bool
+}
+
+// WithIDs sets how the collection propagates the ids of created documents
+func WithIDs(opts IDOptions) CollectionOption {
+	return func(o *collectionOptions) {
+		o.ids = opts
+	}
+}
+
+// createdID is the id of a created document: the response's top-level id,
+// else the id in its data
+func createdID(body []byte) string {
+	var response struct {
+		ID   interface{}            `json:"id"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	if id := idString(response.ID); id != "" {
+		return id
+	}
+	return idString(response.Data["id"])
+}
+
+// idString accepts string ids and the numbers some servers return
+func idString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// propagateID sets the id of a created document on the returned model and,
+// unless the collection keeps its input, on the model passed in
+func (c *Collection[T]) propagateID(id string, input, result T) error {
+	if id == "" {
+		if c.options.ids.Required {
+			return fmt.Errorf("%w: create in %s", ErrMissingID, c.collection)
+		}
+		return nil
+	}
+	result.SetID(id)
+	if !c.options.ids.KeepInput {
+		input.SetID(id)
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package torm
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ImportFormat is the encoding of an import source
+type ImportFormat string
+
+const (
+	ImportJSONLines ImportFormat = "jsonl" // One JSON object per line (default)
+	ImportCSV       ImportFormat = "csv"   // Comma separated values with a header row
+)
+
+// MappingSpec reshapes foreign records into documents before they are
+// validated and written. Stages run in field order: Rename, Split, Join,
+// Constants, Casts, Drop and finally Transform.
+type MappingSpec struct {
+	Rename    map[string]string      // Source field to target field
+	Split     []SplitRule            // Fields split into several
+	Join      []JoinRule             // Fields joined into one
+	Constants map[string]interface{} // Values set on every record, replacing source values
+	// Casts converts fields to "string", "int", "float" or "bool". Text
+	// from CSV sources is parsed; missing and empty values are left alone.
+	Casts map[string]string
+	Drop  []string // Fields removed from every record
+	// Transform is a last custom stage. It may change the record in place
+	// and return it, or return a new one.
+	Transform func(record map[string]interface{}) (map[string]interface{}, error)
+}
+
+// SplitRule splits a text field on Separator. With Into the parts fill
+// those fields in order; without it the field becomes an array of parts.
+type SplitRule struct {
+	Field     string
+	Separator string
+	Into      []string
+}
+
+// JoinRule joins fields into Into with Separator, skipping missing ones
+type JoinRule struct {
+	Fields    []string
+	Separator string
+	Into      string
+	Keep      bool // Keep the source fields
+}
+
+// ImportSample is one record processed by a dry run
+type ImportSample struct {
+	Line   int                    // Source line of the record
+	Source map[string]interface{} // Record as read
+	Record map[string]interface{} // Record after mapping, nil when mapping failed
+	// Err is the mapping or validation failure, nil when the record would
+	// be imported
+	Err error
+}
+
+// importRecord is one record read from a source
+type importRecord struct {
+	line int
+	data map[string]interface{}
+}
+
+// recordReader reads records until io.EOF
+type recordReader interface {
+	next() (importRecord, error)
+	lines() int  // Lines read so far
+	chunks() int // Chunk trailers verified so far
+}
+
+func newRecordReader(r io.Reader, format ImportFormat) (recordReader, error) {
+	switch format {
+	case "", ImportJSONLines:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		return &jsonLinesReader{scanner: scanner, verifier: newChunkVerifier()}, nil
+	case ImportCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		return &csvReader{reader: reader}, nil
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+type jsonLinesReader struct {
+	scanner  *bufio.Scanner
+	line     int // Lines read
+	last     int // Line of the last record returned
+	verifier *chunkVerifier
+	failed   error // Returned once the records before it are
+	done     bool
+}
+
+func (j *jsonLinesReader) next() (importRecord, error) {
+	for {
+		if record, ok := j.verifier.next(); ok {
+			j.last = record.line
+			return record, nil
+		}
+		if j.failed != nil {
+			if j.verifier.chunks == 0 {
+				j.last = j.line
+			}
+			return importRecord{line: j.line}, j.failed
+		}
+		if j.done {
+			j.last = j.line
+			return importRecord{}, io.EOF
+		}
+
+		if !j.scanner.Scan() {
+			if err := j.scanner.Err(); err != nil {
+				return importRecord{}, fmt.Errorf("failed to read snapshot: %w", err)
+			}
+			j.done = true
+			if err := j.verifier.finish(j.line); err != nil {
+				return importRecord{line: j.line}, err
+			}
+			continue
+		}
+		j.line++
+		if len(j.scanner.Bytes()) == 0 {
+			continue
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(j.scanner.Bytes(), &doc); err != nil {
+			j.failed = fmt.Errorf("failed to decode line %d: %w", j.line, err)
+			// Without trailers there is nothing to verify the held records against
+			if j.verifier.chunks == 0 {
+				j.verifier.release()
+			}
+			continue
+		}
+		if trailer, ok := parseTrailer(doc); ok {
+			if err := j.verifier.verify(trailer, j.line); err != nil {
+				return importRecord{line: j.line}, err
+			}
+			continue
+		}
+		j.verifier.record(j.scanner.Bytes(), importRecord{line: j.line, data: doc})
+	}
+}
+
+func (j *jsonLinesReader) lines() int {
+	return j.last
+}
+
+func (j *jsonLinesReader) chunks() int {
+	return j.verifier.chunks
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+	line   int
+}
+
+func (c *csvReader) next() (importRecord, error) {
+	for {
+		row, err := c.reader.Read()
+		if err == io.EOF {
+			return importRecord{}, io.EOF
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				c.line = parseErr.StartLine
+			}
+			return importRecord{line: c.line}, fmt.Errorf("failed to decode line %d: %w", c.line, err)
+		}
+		c.line, _ = c.reader.FieldPos(0)
+
+		if c.header == nil {
+			c.header = row
+			continue
+		}
+		if len(row) != len(c.header) {
+			return importRecord{line: c.line}, fmt.Errorf("failed to decode line %d: %d fields, the header has %d", c.line, len(row), len(c.header))
+		}
+
+		doc := make(map[string]interface{}, len(row))
+		for i, value := range row {
+			doc[c.header[i]] = value
+		}
+		return importRecord{line: c.line, data: doc}, nil
+	}
+}
+
+func (c *csvReader) lines() int {
+	return c.line
+}
+
+func (c *csvReader) chunks() int {
+	return 0
+}
+
+// apply runs the mapping stages on a copy of a record
+func (spec *MappingSpec) apply(source map[string]interface{}) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(source))
+	for field, value := range source {
+		record[field] = value
+	}
+	if spec == nil {
+		return record, nil
+	}
+
+	for from, to := range spec.Rename {
+		if value, ok := record[from]; ok {
+			delete(record, from)
+			record[to] = value
+		}
+	}
+
+	for _, rule := range spec.Split {
+		value, ok := record[rule.Field]
+		if !ok || value == nil {
+			continue
+		}
+		text, isText := value.(string)
+		if !isText {
+			return nil, fmt.Errorf("%w: field '%s' cannot be split: not a string", ErrValidation, rule.Field)
+		}
+		parts := strings.Split(text, rule.Separator)
+		if len(rule.Into) == 0 {
+			items := make([]interface{}, len(parts))
+			for i, part := range parts {
+				items[i] = strings.TrimSpace(part)
+			}
+			record[rule.Field] = items
+			continue
+		}
+		if len(parts) > len(rule.Into) {
+			return nil, fmt.Errorf("%w: field '%s' splits into %d parts, expected at most %d", ErrValidation, rule.Field, len(parts), len(rule.Into))
+		}
+		delete(record, rule.Field)
+		for i, part := range parts {
+			record[rule.Into[i]] = strings.TrimSpace(part)
+		}
+	}
+
+	for _, rule := range spec.Join {
+		parts := make([]string, 0, len(rule.Fields))
+		for _, field := range rule.Fields {
+			if value, ok := record[field]; ok && value != nil && value != "" {
+				parts = append(parts, fmt.Sprint(value))
+			}
+			if !rule.Keep && field != rule.Into {
+				delete(record, field)
+			}
+		}
+		record[rule.Into] = strings.Join(parts, rule.Separator)
+	}
+
+	for field, value := range spec.Constants {
+		record[field] = value
+	}
+
+	for field, typ := range spec.Casts {
+		value, ok := record[field]
+		if !ok || value == nil || value == "" {
+			continue
+		}
+		cast, err := castValue(value, typ)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field '%s' %v", ErrValidation, field, err)
+		}
+		record[field] = cast
+	}
+
+	for _, field := range spec.Drop {
+		delete(record, field)
+	}
+
+	if spec.Transform != nil {
+		var transformed map[string]interface{}
+		var err error
+		if panicked := protect("import transform", "", docID(record), func() { transformed, err = spec.Transform(record) }); panicked != nil {
+			return nil, panicked
+		}
+		if err != nil {
+			return nil, err
+		}
+		if transformed != nil {
+			record = transformed
+		}
+	}
+	return record, nil
+}
+
+// castValue converts a value to a schema type
+func castValue(value interface{}, typ string) (interface{}, error) {
+	text, isText := value.(string)
+	text = strings.TrimSpace(text)
+
+	switch typ {
+	case "string", "str":
+		if isText {
+			return value, nil
+		}
+		return fmt.Sprint(value), nil
+	case "int":
+		if isText {
+			n, err := strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to int", text)
+			}
+			return n, nil
+		}
+		if f, ok := toFloat64(value); ok && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+	case "float":
+		if isText {
+			f, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to float", text)
+			}
+			return f, nil
+		}
+		if f, ok := toFloat64(value); ok {
+			return f, nil
+		}
+	case "bool":
+		if isText {
+			b, err := strconv.ParseBool(strings.ToLower(text))
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to bool", text)
+			}
+			return b, nil
+		}
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	default:
+		return nil, fmt.Errorf("has unknown cast type %q", typ)
+	}
+	return nil, fmt.Errorf("cannot cast %v to %s", value, typ)
+}
+
+// dryRunImport maps and validates the first records of a source without
+// writing anything
+func (m *Model) dryRunImport(records recordReader, opts ImportOptions, result *ImportResult) (*ImportResult, error) {
+	size := opts.SampleSize
+	if size <= 0 {
+		size = 10
+	}
+
+	for len(result.Samples) < size {
+		record, err := records.next()
+		result.Lines = records.lines()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		if record.line <= opts.Skip {
+			continue
+		}
+
+		sample := ImportSample{Line: record.line, Source: record.data}
+		mapped, err := m.importForm(record.data, opts.Mapping)
+		if err != nil {
+			sample.Err = fmt.Errorf("failed to map line %d: %w", record.line, err)
+			result.Samples = append(result.Samples, sample)
+			continue
+		}
+		if opts.Partition != nil && !opts.Partition.Contains(mapped) {
+			result.Skipped++
+			continue
+		}
+		sample.Record = mapped
+
+		// Validate a copy, since validation may coerce values
+		checked := make(map[string]interface{}, len(mapped))
+		for field, value := range mapped {
+			checked[field] = value
+		}
+		if err := m.validateWith(checked, false, ValidationFull); err != nil {
+			sample.Err = fmt.Errorf("line %d: %w", record.line, err)
+		}
+		result.Samples = append(result.Samples, sample)
+	}
+	return result, nil
+}
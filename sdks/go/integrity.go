@@ -0,0 +1,238 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IntegrityCheck is an invariant of stored data, verified by
+// RunIntegrityChecks. Exactly one of Filter, Reference and Func is set.
+type IntegrityCheck struct {
+	Name       string
+	Collection string
+
+	// Filter matches the documents that break the invariant, such as
+	// stock < 0; the check passes when it matches none
+	Filter []QueryFilter
+	// Reference requires a field to hold the id of an existing document
+	Reference *ReferenceCheck
+	// Func inspects every document of the collection and calls violation
+	// with the id of each one breaking the invariant
+	Func func(ctx context.Context, it *QueryIterator, violation func(id string)) error
+
+	MaxIDs int // Offending ids kept in the report (default 100)
+}
+
+// ReferenceCheck requires every value of Field to be the id of a document
+// in Collection. Documents without the field are not checked.
+type ReferenceCheck struct {
+	Field      string
+	Collection string
+	BatchSize  int // Ids looked up per request (default 100)
+}
+
+// IntegrityResult is the outcome of one check
+type IntegrityResult struct {
+	Check      string
+	Collection string
+	Checked    int      // Documents read, not counted for Func checks
+	Violations int      // Documents breaking the invariant
+	IDs        []string // Offending ids, capped at the check's MaxIDs
+	Err        error    // Set when the check could not run to the end
+	Duration   time.Duration
+}
+
+// OK reports whether the check ran and found no violations
+func (r IntegrityResult) OK() bool {
+	return r.Err == nil && r.Violations == 0
+}
+
+// IntegrityReport is the outcome of RunIntegrityChecks, one result per
+// registered check in registration order
+type IntegrityReport struct {
+	Results  []IntegrityResult
+	Duration time.Duration
+}
+
+// Failed returns the results of checks that found violations or errors
+func (r *IntegrityReport) Failed() []IntegrityResult {
+	var failed []IntegrityResult
+	for _, result := range r.Results {
+		if !result.OK() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// RegisterIntegrityCheck adds a check run by RunIntegrityChecks. Names
+// must be unique on the client.
+func (c *Client) RegisterIntegrityCheck(check IntegrityCheck) error {
+	kinds := 0
+	if len(check.Filter) > 0 {
+		kinds++
+	}
+	if check.Reference != nil {
+		kinds++
+		if check.Reference.Field == "" || check.Reference.Collection == "" {
+			return fmt.Errorf("integrity check %q: reference needs a field and a collection", check.Name)
+		}
+	}
+	if check.Func != nil {
+		kinds++
+	}
+	switch {
+	case check.Name == "" || check.Collection == "":
+		return fmt.Errorf("integrity check needs a name and a collection")
+	case kinds != 1:
+		return fmt.Errorf("integrity check %q: set exactly one of Filter, Reference and Func", check.Name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, registered := range c.integrityChecks {
+		if registered.Name == check.Name {
+			return fmt.Errorf("integrity check %q: %w", check.Name, ErrDuplicateKey)
+		}
+	}
+	c.integrityChecks = append(c.integrityChecks, check)
+	return nil
+}
+
+// OnIntegrityViolation registers a callback for alerting, called with the
+// result of every check that found violations or failed to run
+func (c *Client) OnIntegrityViolation(fn func(IntegrityResult)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.integrityHooks = append(c.integrityHooks, fn)
+}
+
+// RunIntegrityChecks runs every registered check in turn. A check that
+// cannot run does not stop the others; its error is in its result and the
+// errors are joined. Violations alone are not an error.
+func (c *Client) RunIntegrityChecks(ctx context.Context) (*IntegrityReport, error) {
+	c.mu.Lock()
+	checks := append([]IntegrityCheck(nil), c.integrityChecks...)
+	hooks := append(([]func(IntegrityResult))(nil), c.integrityHooks...)
+	c.mu.Unlock()
+
+	started := c.now()
+	report := &IntegrityReport{Results: make([]IntegrityResult, 0, len(checks))}
+	var errs []error
+	for _, check := range checks {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		result := c.runIntegrityCheck(ctx, check)
+		report.Results = append(report.Results, result)
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("integrity check %q: %w", check.Name, result.Err))
+		}
+		if !result.OK() {
+			for _, hook := range hooks {
+				_ = protect("integrity hook", check.Collection, "", func() { hook(result) })
+			}
+		}
+	}
+	report.Duration = c.now().Sub(started)
+	return report, errors.Join(errs...)
+}
+
+func (c *Client) runIntegrityCheck(ctx context.Context, check IntegrityCheck) IntegrityResult {
+	started := c.now()
+	result := IntegrityResult{Check: check.Name, Collection: check.Collection}
+	maxIDs := check.MaxIDs
+	if maxIDs <= 0 {
+		maxIDs = 100
+	}
+	violation := func(id string) {
+		result.Violations++
+		if len(result.IDs) < maxIDs {
+			result.IDs = append(result.IDs, id)
+		}
+	}
+
+	qb := c.Model(check.Collection, nil).Query()
+	switch {
+	case len(check.Filter) > 0:
+		qb.Match(check.Filter...).Select("id")
+	case check.Reference != nil:
+		qb.Select("id", check.Reference.Field)
+	}
+	it := qb.Iter(ctx)
+	defer it.Close()
+
+	switch {
+	case check.Func != nil:
+		err := protect("integrity check", check.Collection, "", func() {
+			result.Err = check.Func(ctx, it, violation)
+		})
+		if err != nil {
+			result.Err = err
+		}
+	case check.Reference != nil:
+		result.Err = c.checkReferences(it, *check.Reference, &result.Checked, violation)
+	default:
+		for it.Next() {
+			result.Checked++
+			violation(fmt.Sprint(it.Doc()["id"]))
+		}
+		result.Err = it.Err()
+	}
+	result.Duration = c.now().Sub(started)
+	return result
+}
+
+// checkReferences reads the referencing documents and looks their
+// referenced ids up in batches
+func (c *Client) checkReferences(it *QueryIterator, ref ReferenceCheck, checked *int, violation func(id string)) error {
+	batchSize := ref.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	target := c.Model(ref.Collection, nil)
+
+	type pending struct{ id, ref string }
+	var batch []pending
+	flush := func() error {
+		ids := make([]string, len(batch))
+		for i, p := range batch {
+			ids[i] = p.ref
+		}
+		found, err := target.FindByIDs(ids)
+		if err != nil {
+			return err
+		}
+		for _, p := range batch {
+			if _, ok := found[p.ref]; !ok {
+				violation(p.id)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for it.Next() {
+		doc := it.Doc()
+		*checked++
+		value, ok := doc[ref.Field]
+		if !ok || value == nil || value == "" {
+			continue
+		}
+		batch = append(batch, pending{id: fmt.Sprint(doc["id"]), ref: fmt.Sprint(value)})
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return flush()
+	}
+	return nil
+}
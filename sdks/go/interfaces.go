@@ -0,0 +1,29 @@
+package torm
+
+// CollectionReader is the read side of a collection, for code that only
+// looks documents up and for injecting fakes in tests
+type CollectionReader[T any] interface {
+	FindByID(id string) (T, error)
+	FindFiltered(filters map[string]interface{}) ([]T, error)
+	Count() (int, error)
+}
+
+// CollectionWriter is the write side of a collection
+type CollectionWriter[T any] interface {
+	Create(data T) (T, error)
+	Save(model T) error
+	Delete(id string) error
+}
+
+// KeyStore is the key-value API of a client
+type KeyStore interface {
+	GetKey(key string) (string, bool, error)
+	SetKey(key, value string) error
+	DeleteKey(key string) error
+}
+
+var (
+	_ CollectionReader[Document] = (*Collection[Document])(nil)
+	_ CollectionWriter[Document] = (*Collection[Document])(nil)
+	_ KeyStore                   = (*Client)(nil)
+)
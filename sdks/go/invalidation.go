@@ -0,0 +1,351 @@
+package torm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InvalidationEvent tells other processes that a document changed
+type InvalidationEvent struct {
+	Seq        int64     `json:"seq,omitempty"` // Assigned by the bus
+	Collection string    `json:"collection,omitempty"`
+	ID         string    `json:"id,omitempty"`
+	Op         Operation `json:"op,omitempty"`
+	Origin     string    `json:"origin,omitempty"` // Client that made the write
+
+	// Resync reports that events were missed, so every cached entry may be
+	// stale. Collection and ID are empty.
+	Resync bool `json:"resync,omitempty"`
+}
+
+// Publisher sends invalidation events to other processes
+type Publisher interface {
+	Publish(ctx context.Context, events []InvalidationEvent) error
+}
+
+// Subscriber delivers invalidation events published by other processes.
+// Subscribe calls handle for each event, in order, until ctx is done.
+type Subscriber interface {
+	Subscribe(ctx context.Context, handle func(InvalidationEvent)) error
+}
+
+// InvalidationStats counts the events a client tried to publish
+type InvalidationStats struct {
+	Published int64 // Events handed to the publisher
+	Dropped   int64 // Events discarded because the publisher fell behind
+	Failed    int64 // Events the publisher returned an error for
+}
+
+// invalidationQueueSize bounds the events waiting to be published
+const invalidationQueueSize = 256
+
+// invalidationHub publishes the client's writes and routes events from
+// other processes to the caches that hold the changed documents
+type invalidationHub struct {
+	origin string
+
+	mu        sync.Mutex
+	publisher Publisher
+	queue     chan InvalidationEvent
+	caches    map[string][]*indexRegistry
+	listeners []func(InvalidationEvent)
+
+	published atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+func newInvalidationHub() *invalidationHub {
+	origin := make([]byte, 8)
+	rand.Read(origin)
+	return &invalidationHub{origin: hex.EncodeToString(origin)}
+}
+
+// PublishInvalidations publishes an event for every write made through the
+// client. Events are sent in the background: a slow or failing publisher
+// never delays writes, and events it cannot keep up with are dropped.
+func (c *Client) PublishInvalidations(publisher Publisher) {
+	h := c.invalidation
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.publisher = publisher
+	if h.queue != nil {
+		return
+	}
+	h.queue = make(chan InvalidationEvent, invalidationQueueSize)
+	go c.runPublisher()
+}
+
+// FollowInvalidations evicts cached documents that other processes changed,
+// as reported by sub, until ctx is done. Events for the client's own
+// writes are ignored.
+func (c *Client) FollowInvalidations(ctx context.Context, sub Subscriber) error {
+	return sub.Subscribe(ctx, func(event InvalidationEvent) {
+		if event.Origin == c.invalidation.origin {
+			return
+		}
+		c.invalidation.route(ctx, event)
+	})
+}
+
+// OnInvalidate registers a callback for events received by
+// FollowInvalidations, so application caches can evict entries too
+func (c *Client) OnInvalidate(fn func(InvalidationEvent)) {
+	c.invalidation.mu.Lock()
+	defer c.invalidation.mu.Unlock()
+	c.invalidation.listeners = append(c.invalidation.listeners, fn)
+}
+
+// InvalidationStats returns counters of the client's published events
+func (c *Client) InvalidationStats() InvalidationStats {
+	h := c.invalidation
+	return InvalidationStats{
+		Published: h.published.Load(),
+		Dropped:   h.dropped.Load(),
+		Failed:    h.failed.Load(),
+	}
+}
+
+// invalidate queues an event for a write. It never blocks.
+func (c *Client) invalidate(collection, id string, op Operation) {
+	h := c.invalidation
+	h.mu.Lock()
+	queue := h.queue
+	h.mu.Unlock()
+	if queue == nil || id == "" {
+		return
+	}
+
+	select {
+	case queue <- InvalidationEvent{Collection: collection, ID: id, Op: op, Origin: h.origin}:
+	default:
+		h.dropped.Add(1)
+	}
+}
+
+// written updates the collection's indexes with a document it wrote,
+// publishes the write, mirrors it and audits it. It only fails for strict
+// mirrors and audit trails.
+func (c *Collection[T]) written(op Operation, doc map[string]interface{}) error {
+	c.options.indexes.put(doc)
+	id, _ := doc["id"].(string)
+	c.client.invalidate(c.collection, id, op)
+	if err := c.mirrorWrite(op, id, doc); err != nil {
+		return err
+	}
+	return c.auditWrite(op, id, doc)
+}
+
+// deleted drops a deleted document from the collection's indexes,
+// publishes the delete, mirrors it and audits it. It only fails for strict
+// mirrors and audit trails.
+func (c *Collection[T]) deleted(id string) error {
+	c.options.indexes.remove(id)
+	c.client.invalidate(c.collection, id, OpDelete)
+	if err := c.mirrorWrite(OpDelete, id, nil); err != nil {
+		return err
+	}
+	return c.auditWrite(OpDelete, id, nil)
+}
+
+// runPublisher sends queued events, batching those that arrive while a
+// publish is in flight
+func (c *Client) runPublisher() {
+	h := c.invalidation
+	for event := range h.queue {
+		batch := []InvalidationEvent{event}
+	drain:
+		for len(batch) < invalidationQueueSize {
+			select {
+			case next := <-h.queue:
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		h.mu.Lock()
+		publisher := h.publisher
+		h.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+		err := publisher.Publish(ctx, batch)
+		cancel()
+		if err != nil {
+			h.failed.Add(int64(len(batch)))
+			continue
+		}
+		h.published.Add(int64(len(batch)))
+	}
+}
+
+// track routes events for a collection to its indexes
+func (h *invalidationHub) track(collection string, registry *indexRegistry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, tracked := range h.caches[collection] {
+		if tracked == registry {
+			return
+		}
+	}
+	if h.caches == nil {
+		h.caches = make(map[string][]*indexRegistry)
+	}
+	h.caches[collection] = append(h.caches[collection], registry)
+}
+
+// route evicts the changed document from the caches holding it. After
+// missed events every index is refreshed instead.
+func (h *invalidationHub) route(ctx context.Context, event InvalidationEvent) {
+	h.mu.Lock()
+	var registries []*indexRegistry
+	if event.Resync {
+		for _, tracked := range h.caches {
+			registries = append(registries, tracked...)
+		}
+	} else {
+		registries = append(registries, h.caches[event.Collection]...)
+	}
+	listeners := append([]func(InvalidationEvent){}, h.listeners...)
+	h.mu.Unlock()
+
+	for _, registry := range registries {
+		if event.Resync {
+			registry.refresh(ctx)
+		} else {
+			registry.remove(event.ID)
+		}
+	}
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
+// KeyBusOptions configures a KeyBus
+type KeyBusOptions struct {
+	Capacity     int           // Events kept for subscribers that poll late (default 1000)
+	PollInterval time.Duration // How often subscribers read the bus (default 1s)
+}
+
+// KeyBus is a Publisher and Subscriber that keeps the latest events in a
+// key of the keys API, so processes sharing a ToonStore need nothing else.
+// Subscribers poll the key; one that falls more than Capacity events
+// behind receives a Resync event.
+type KeyBus struct {
+	client *Client
+	key    string
+	opts   KeyBusOptions
+}
+
+// keyBusLog is the value stored in a KeyBus key
+type keyBusLog struct {
+	Seq    int64               `json:"seq"`
+	Events []InvalidationEvent `json:"events"`
+}
+
+// NewKeyBus returns a bus on the key "torm:events:{channel}"
+func NewKeyBus(client *Client, channel string, opts KeyBusOptions) *KeyBus {
+	if opts.Capacity <= 0 {
+		opts.Capacity = 1000
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	return &KeyBus{client: client, key: "torm:events:" + channel, opts: opts}
+}
+
+// Publish appends events to the bus, numbering them and dropping the
+// oldest beyond Capacity
+func (b *KeyBus) Publish(ctx context.Context, events []InvalidationEvent) error {
+	for attempt := 0; attempt < maxSwapAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		log, raw, found, err := b.read()
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			log.Seq++
+			event.Seq = log.Seq
+			log.Events = append(log.Events, event)
+		}
+		if extra := len(log.Events) - b.opts.Capacity; extra > 0 {
+			log.Events = log.Events[extra:]
+		}
+
+		encoded, err := json.Marshal(log)
+		if err != nil {
+			return fmt.Errorf("failed to encode events: %w", err)
+		}
+		var old *string
+		if found {
+			old = &raw
+		}
+		swapped, err := b.client.putKeyIf(b.key, old, string(encoded))
+		if err != nil {
+			return err
+		}
+		if swapped {
+			return nil
+		}
+	}
+	return fmt.Errorf("publish to %s: too many concurrent writers", b.key)
+}
+
+// Subscribe delivers events published after it starts, polling the bus
+// until ctx is done. Failed polls are retried on the next interval.
+func (b *KeyBus) Subscribe(ctx context.Context, handle func(InvalidationEvent)) error {
+	log, _, _, err := b.read()
+	if err != nil {
+		return err
+	}
+	last := log.Seq
+
+	ticker := time.NewTicker(b.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		log, _, _, err := b.read()
+		if err != nil || log.Seq == last {
+			continue
+		}
+
+		// A gap, or a bus that was reset, means events were missed
+		if log.Seq < last || len(log.Events) == 0 || log.Events[0].Seq > last+1 {
+			handle(InvalidationEvent{Resync: true})
+		}
+		for _, event := range log.Events {
+			if event.Seq > last || log.Seq < last {
+				handle(event)
+			}
+		}
+		last = log.Seq
+	}
+}
+
+// read returns the bus contents along with the raw value for a swap
+func (b *KeyBus) read() (keyBusLog, string, bool, error) {
+	var log keyBusLog
+	raw, found, err := b.client.GetKey(b.key)
+	if err != nil || !found {
+		return log, raw, found, err
+	}
+	if err := json.Unmarshal([]byte(raw), &log); err != nil {
+		return log, raw, found, fmt.Errorf("corrupt event bus %s: %w", b.key, err)
+	}
+	return log, raw, found, nil
+}
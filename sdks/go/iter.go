@@ -0,0 +1,90 @@
+//go:build go1.23
+
+package torm
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns the query's results as an iterator, reading them a page at a
+// time, for use with range:
+//
+//	for doc, err := range users.Query().Where("active", true).All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// A failed page or a done context yields the error once and ends the
+// iteration. Breaking out of the loop stops before the next page is read.
+// Queries without a sort are read in id order so pages do not overlap.
+func (qb *QueryBuilder) All(ctx context.Context) iter.Seq2[map[string]interface{}, error] {
+	return func(yield func(map[string]interface{}, error) bool) {
+		skip, remaining := 0, -1
+		if qb.skipVal != nil {
+			skip = *qb.skipVal
+		}
+		if qb.limitVal != nil {
+			remaining = *qb.limitVal
+		}
+
+		for remaining != 0 {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			size := iterPageSize
+			if remaining > 0 && remaining < size {
+				size = remaining
+			}
+			docs, err := qb.pageAt(skip, size).Exec()
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, doc := range docs {
+				if !yield(doc, nil) {
+					return
+				}
+			}
+			if len(docs) < size {
+				return
+			}
+			skip += size
+			if remaining > 0 {
+				remaining -= size
+			}
+		}
+	}
+}
+
+// All returns every document of the collection as an iterator of models,
+// in id order; see QueryBuilder.All. Documents the guard rejects are
+// skipped.
+func (c *Collection[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	c = c.prioritizedBy(ctx)
+	return func(yield func(T, error) bool) {
+		for doc, err := range c.model().Query().All(ctx) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			models, err := c.toModels(OpQuery, []map[string]interface{}{doc})
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, model := range models {
+				if !yield(model, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package torm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyIndexPrefix names the client-managed index keys. The key itself lists
+// the indexed namespaces; "torm:keyindex:{namespace}" lists a namespace's keys.
+const keyIndexPrefix = "torm:keyindex"
+
+// maxSwapAttempts bounds compare-and-set retries under concurrent writers
+const maxSwapAttempts = 20
+
+// ListKeysOptions configures ListKeys
+type ListKeysOptions struct {
+	PageSize   int // Keys per page from the listing endpoint (default 1000)
+	MaxRetries int // Retries of a page answered with 429 or 503 (default 5)
+}
+
+// ListKeys returns the keys starting with prefix, in order. It pages through
+// the server's listing endpoint, backing off when the server answers 429 or
+// 503. Servers without the endpoint are served from the client-managed
+// index enabled by ClientOptions.KeyIndex; without it ErrNotSupported is
+// returned.
+func (c *Client) ListKeys(prefix string, opts *ListKeysOptions) ([]string, error) {
+	keys, err := c.listKeys(prefix, opts)
+	return keys, c.wrapOp(err, OpError{Op: "ListKeys", ID: prefix})
+}
+
+func (c *Client) listKeys(prefix string, opts *ListKeysOptions) ([]string, error) {
+	if opts == nil {
+		opts = &ListKeysOptions{}
+	}
+
+	if supported, known := c.capabilities.lookup(CapabilityKeyListing); !known || supported {
+		keys, supported, err := c.listKeysNative(prefix, opts)
+		if err != nil || supported {
+			return keys, err
+		}
+	}
+
+	if !c.keyIndex {
+		return nil, fmt.Errorf("list keys: %w", ErrNotSupported)
+	}
+	return c.listKeysIndexed(prefix)
+}
+
+// DeleteKeysByPrefix deletes every key starting with prefix and returns how
+// many were deleted. Index keys are never deleted.
+func (c *Client) DeleteKeysByPrefix(prefix string) (int, error) {
+	n, err := c.deleteKeysByPrefix(prefix)
+	return n, c.wrapOp(err, OpError{Op: "DeleteKeysByPrefix", ID: prefix})
+}
+
+func (c *Client) deleteKeysByPrefix(prefix string) (int, error) {
+	keys, err := c.listKeys(prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if isIndexKey(key) {
+			continue
+		}
+		if err := c.deleteKey(key); err != nil {
+			c.unindexKeys(deleted)
+			return len(deleted), err
+		}
+		deleted = append(deleted, key)
+	}
+
+	return len(deleted), c.unindexKeys(deleted)
+}
+
+// CompareAndSetKey stores value only if the key currently holds old, or does
+// not exist when old is nil. The boolean is false when the key held
+// something else. Servers that honor If-Match and If-None-Match make the
+// swap atomic; others get a compare-then-write.
+func (c *Client) CompareAndSetKey(key string, old *string, value string) (bool, error) {
+	swapped, err := c.compareAndSetKey(key, old, value)
+	return swapped, c.wrapOp(err, OpError{Op: "CompareAndSetKey", ID: key})
+}
+
+func (c *Client) compareAndSetKey(key string, old *string, value string) (bool, error) {
+	current, found, err := c.getKey(key)
+	if err != nil {
+		return false, err
+	}
+	if found != (old != nil) || (found && current != *old) {
+		return false, nil
+	}
+	return c.putKeyIf(key, old, value)
+}
+
+// putKeyIf writes a key with preconditions on its current value
+func (c *Client) putKeyIf(key string, old *string, value string) (bool, error) {
+	body, err := json.Marshal(map[string]interface{}{"value": value})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequest("PUT", c.BaseURL+c.apiPath("keys", key), strings.NewReader(string(body)))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if old == nil {
+		req.Header.Set("If-None-Match", "*")
+	} else {
+		req.Header.Set("If-Match", keyETag(*old))
+	}
+	if err := c.authorize(req); err != nil {
+		return false, err
+	}
+
+	resp, err := c.intercept(req, c.client.Do)
+	if err != nil {
+		c.observe(0, err)
+		return false, fmt.Errorf("set key failed: %w", err)
+	}
+	defer resp.Body.Close()
+	c.observe(resp.StatusCode, nil)
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return false, nil
+	}
+	if !isSuccess(resp.StatusCode) {
+		return false, responseError("set key", resp)
+	}
+	return true, nil
+}
+
+// keyETag is the entity tag of a key value used in conditional writes
+func keyETag(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// listKeysNative pages through the listing endpoint. The boolean is false
+// when the server does not have it.
+func (c *Client) listKeysNative(prefix string, opts *ListKeysOptions) ([]string, bool, error) {
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = 1000
+	}
+	maxRetries := opts.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+
+	keys := make([]string, 0)
+	cursor := ""
+	for retries := 0; ; {
+		params := url.Values{"prefix": {prefix}, "limit": {strconv.Itoa(pageSize)}}
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+
+		resp, err := c.request("GET", c.apiPath("keys")+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("list keys failed: %w", err)
+		}
+
+		switch resp.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			resp.Body.Close()
+			c.capabilities.record(CapabilityKeyListing, false)
+			return nil, false, nil
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			if retries >= maxRetries {
+				defer resp.Body.Close()
+				return nil, true, responseError("list keys", resp)
+			}
+			resp.Body.Close()
+			time.Sleep(retryDelay(resp, retries))
+			retries++
+			continue
+		}
+
+		if !isSuccess(resp.StatusCode) {
+			defer resp.Body.Close()
+			return nil, true, responseError("list keys", resp)
+		}
+		var page struct {
+			Keys       []string `json:"keys"`
+			NextCursor string   `json:"next_cursor"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to decode response: %w", err)
+		}
+		c.capabilities.record(CapabilityKeyListing, true)
+
+		keys = append(keys, page.Keys...)
+		if page.NextCursor == "" || len(page.Keys) == 0 {
+			break
+		}
+		cursor = page.NextCursor
+		retries = 0
+	}
+
+	sort.Strings(keys)
+	return keys, true, nil
+}
+
+// retryDelay honors Retry-After and otherwise backs off exponentially from 100ms
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	delay := 100 * time.Millisecond << attempt
+	if delay > 5*time.Second {
+		delay = 5 * time.Second
+	}
+	return delay
+}
+
+// listKeysIndexed reads the keys from the client-managed index
+func (c *Client) listKeysIndexed(prefix string) ([]string, error) {
+	namespaces := []string{keyNamespace(prefix)}
+	if !strings.Contains(prefix, ":") {
+		// The prefix may match un-namespaced keys and any namespace it starts
+		all, _, err := c.readIndex(keyIndexPrefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range all {
+			if ns != "" && strings.HasPrefix(ns, prefix) {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+
+	keys := make([]string, 0)
+	for _, ns := range namespaces {
+		indexed, _, err := c.readIndex(indexKeyFor(ns))
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range indexed {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// indexKeys adds written keys to the index when the client maintains one
+func (c *Client) indexKeys(keys ...string) error {
+	return c.updateIndexes(keys, true)
+}
+
+// unindexKeys removes deleted keys from the index when the client maintains one
+func (c *Client) unindexKeys(keys []string) error {
+	return c.updateIndexes(keys, false)
+}
+
+func (c *Client) updateIndexes(keys []string, add bool) error {
+	if !c.keyIndex || len(keys) == 0 {
+		return nil
+	}
+	if supported, known := c.capabilities.lookup(CapabilityKeyListing); !known {
+		if _, supported, err := c.listKeysNative("", &ListKeysOptions{PageSize: 1}); err != nil || supported {
+			return err
+		}
+	} else if supported {
+		return nil
+	}
+
+	byNamespace := make(map[string][]string)
+	for _, key := range keys {
+		if !isIndexKey(key) {
+			byNamespace[keyNamespace(key)] = append(byNamespace[keyNamespace(key)], key)
+		}
+	}
+
+	c.keyIndexMu.Lock()
+	defer c.keyIndexMu.Unlock()
+
+	for ns, nsKeys := range byNamespace {
+		if add {
+			// Register the namespace before its index so listings find it
+			if _, found, err := c.readIndex(indexKeyFor(ns)); err != nil {
+				return err
+			} else if !found {
+				if err := c.updateIndex(keyIndexPrefix, []string{ns}, true); err != nil {
+					return err
+				}
+			}
+		}
+		if err := c.updateIndex(indexKeyFor(ns), nsKeys, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateIndex adds or removes entries of one index key with compare-and-set,
+// retrying when another writer got there first
+func (c *Client) updateIndex(indexKey string, entries []string, add bool) error {
+	for attempt := 0; attempt < maxSwapAttempts; attempt++ {
+		raw, found, err := c.GetKey(indexKey)
+		if err != nil {
+			return err
+		}
+		current, err := decodeIndex(indexKey, raw, found)
+		if err != nil {
+			return err
+		}
+
+		set := make(map[string]bool, len(current)+len(entries))
+		for _, entry := range current {
+			set[entry] = true
+		}
+		changed := false
+		for _, entry := range entries {
+			if set[entry] != add {
+				set[entry] = add
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+
+		next := make([]string, 0, len(set))
+		for entry, present := range set {
+			if present {
+				next = append(next, entry)
+			}
+		}
+		sort.Strings(next)
+		encoded, _ := json.Marshal(next)
+
+		var old *string
+		if found {
+			old = &raw
+		}
+		swapped, err := c.putKeyIf(indexKey, old, string(encoded))
+		if err != nil {
+			return err
+		}
+		if swapped {
+			return nil
+		}
+	}
+	return fmt.Errorf("update key index %s: too many concurrent writers", indexKey)
+}
+
+func (c *Client) readIndex(indexKey string) ([]string, bool, error) {
+	raw, found, err := c.GetKey(indexKey)
+	if err != nil {
+		return nil, false, err
+	}
+	entries, err := decodeIndex(indexKey, raw, found)
+	return entries, found, err
+}
+
+func decodeIndex(indexKey, raw string, found bool) ([]string, error) {
+	if !found || raw == "" {
+		return nil, nil
+	}
+	var entries []string
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, fmt.Errorf("corrupt key index %s: %w", indexKey, err)
+	}
+	return entries, nil
+}
+
+// keyNamespace is the part of a key before its first colon, empty for
+// un-namespaced keys
+func keyNamespace(key string) string {
+	if i := strings.IndexByte(key, ':'); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+func indexKeyFor(namespace string) string {
+	return keyIndexPrefix + ":" + namespace
+}
+
+func isIndexKey(key string) bool {
+	return key == keyIndexPrefix || strings.HasPrefix(key, keyIndexPrefix+":")
+}
@@ -0,0 +1,80 @@
+package torm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GetKey reads a value from the keys API. The boolean is false when the key does not exist.
+func (c *Client) GetKey(key string) (string, bool, error) {
+	value, found, err := c.getKey(key)
+	return value, found, c.wrapOp(err, OpError{Op: "GetKey", ID: key})
+}
+
+func (c *Client) getKey(key string) (string, bool, error) {
+	resp, err := c.request("GET", c.apiPath("keys", key), nil)
+	if err != nil {
+		return "", false, fmt.Errorf("get key failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false, responseError("get key", resp)
+	}
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result.Value, true, nil
+}
+
+// SetKey stores a value in the keys API
+func (c *Client) SetKey(key, value string) error {
+	return c.wrapOp(c.setKey(key, value), OpError{Op: "SetKey", ID: key})
+}
+
+func (c *Client) setKey(key, value string) error {
+	resp, err := c.request("PUT", c.apiPath("keys", key), map[string]interface{}{"value": value})
+	if err != nil {
+		return fmt.Errorf("set key failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if !isSuccess(resp.StatusCode) {
+		return responseError("set key", resp)
+	}
+
+	return c.indexKeys(key)
+}
+
+// DeleteKey removes a value from the keys API. Deleting a missing key is not an error.
+func (c *Client) DeleteKey(key string) error {
+	err := c.deleteKey(key)
+	if err == nil {
+		err = c.unindexKeys([]string{key})
+	}
+	return c.wrapOp(err, OpError{Op: "DeleteKey", ID: key})
+}
+
+func (c *Client) deleteKey(key string) error {
+	resp, err := c.request("DELETE", c.apiPath("keys", key), nil)
+	if err != nil {
+		return fmt.Errorf("delete key failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if !isSuccess(resp.StatusCode) && resp.StatusCode != http.StatusNotFound {
+		return responseError("delete key", resp)
+	}
+
+	return nil
+}
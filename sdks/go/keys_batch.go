@@ -0,0 +1,253 @@
+package torm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// CapabilityKeysBatch is the server's atomic multi-key endpoint
+const CapabilityKeysBatch Capability = "keys_batch"
+
+// ErrDuplicateKey is returned when a keys batch names a key in more than one step
+var ErrDuplicateKey = errors.New("torm: key appears twice in batch")
+
+// KeyOp is the kind of a keys batch step
+type KeyOp string
+
+const (
+	KeySet           KeyOp = "set"
+	KeyDelete        KeyOp = "delete"
+	KeyCompareAndSet KeyOp = "compare_and_set"
+)
+
+// KeyStep is one step of a keys batch
+type KeyStep struct {
+	Op    KeyOp   `json:"op"`
+	Key   string  `json:"key"`
+	Value string  `json:"value,omitempty"`
+	Old   *string `json:"old"` // CompareAndSet only: expected value, nil for an absent key
+}
+
+// KeyStepStatus is the outcome of a keys batch step
+type KeyStepStatus string
+
+const (
+	KeyStepApplied    KeyStepStatus = "applied"     // In effect
+	KeyStepNotApplied KeyStepStatus = "not_applied" // Never written
+	KeyStepFailed     KeyStepStatus = "failed"      // Attempted and not in effect
+	KeyStepRolledBack KeyStepStatus = "rolled_back" // Written, then restored after a later step failed
+)
+
+// KeyStepResult reports one step of an executed batch
+type KeyStepResult struct {
+	KeyStep
+	Status KeyStepStatus
+	Err    error // Why the step failed, or why its rollback did
+}
+
+// KeysBatchResult reports an executed keys batch
+type KeysBatchResult struct {
+	Native bool // Executed atomically by the server
+	Steps  []KeyStepResult
+}
+
+// Applied returns the indexes of the steps in effect
+func (r *KeysBatchResult) Applied() []int {
+	var applied []int
+	for i, step := range r.Steps {
+		if step.Status == KeyStepApplied {
+			applied = append(applied, i)
+		}
+	}
+	return applied
+}
+
+// KeysBatch groups key writes that succeed or fail together. Build it with
+// Set, Delete and CompareAndSet, then Exec it; a batch can be executed
+// any number of times.
+type KeysBatch struct {
+	client *Client
+	steps  []KeyStep
+}
+
+// KeysBatch starts a batch of key writes
+func (c *Client) KeysBatch() *KeysBatch {
+	return &KeysBatch{client: c}
+}
+
+// Set stores value under key
+func (b *KeysBatch) Set(key, value string) *KeysBatch {
+	b.steps = append(b.steps, KeyStep{Op: KeySet, Key: key, Value: value})
+	return b
+}
+
+// Delete removes key
+func (b *KeysBatch) Delete(key string) *KeysBatch {
+	b.steps = append(b.steps, KeyStep{Op: KeyDelete, Key: key})
+	return b
+}
+
+// CompareAndSet stores value if key holds old, or is absent when old is
+// nil; otherwise the whole batch fails with ErrVersionConflict
+func (b *KeysBatch) CompareAndSet(key string, old *string, value string) *KeysBatch {
+	b.steps = append(b.steps, KeyStep{Op: KeyCompareAndSet, Key: key, Value: value, Old: old})
+	return b
+}
+
+// Exec runs the batch. Servers with a batch endpoint apply it atomically.
+// Otherwise the steps run one by one after the current values are read;
+// when a step fails, the steps before it are restored to those values on
+// a best-effort basis. The result says which steps are in effect either way.
+func (b *KeysBatch) Exec() (*KeysBatchResult, error) {
+	seen := make(map[string]int, len(b.steps))
+	for i, step := range b.steps {
+		if first, ok := seen[step.Key]; ok {
+			return nil, fmt.Errorf("%w: %q in steps %d and %d", ErrDuplicateKey, step.Key, first, i)
+		}
+		seen[step.Key] = i
+	}
+
+	result := &KeysBatchResult{Steps: make([]KeyStepResult, len(b.steps))}
+	for i, step := range b.steps {
+		result.Steps[i] = KeyStepResult{KeyStep: step, Status: KeyStepNotApplied}
+	}
+	if len(b.steps) == 0 {
+		return result, nil
+	}
+
+	if supported, known := b.client.capabilities.lookup(CapabilityKeysBatch); !known || supported {
+		handled, err := b.execNative(result)
+		if handled {
+			return result, err
+		}
+	}
+	return result, b.execSequential(result)
+}
+
+// execNative sends the batch to the server. The boolean is false when the
+// server has no batch endpoint.
+func (b *KeysBatch) execNative(result *KeysBatchResult) (bool, error) {
+	resp, err := b.client.request("POST", b.client.apiPath("keys", "_batch"), map[string]interface{}{"steps": b.steps})
+	if err != nil {
+		return true, fmt.Errorf("keys batch failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		b.client.capabilities.record(CapabilityKeysBatch, false)
+		return false, nil
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		b.client.capabilities.record(CapabilityKeysBatch, true)
+		var body struct {
+			Step *int `json:"step"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.Step != nil && *body.Step >= 0 && *body.Step < len(result.Steps) {
+			result.Steps[*body.Step].Status = KeyStepFailed
+			result.Steps[*body.Step].Err = ErrVersionConflict
+		}
+		result.Native = true
+		return true, fmt.Errorf("keys batch: %w", ErrVersionConflict)
+	}
+	if !isSuccess(resp.StatusCode) {
+		return true, responseError("keys batch", resp)
+	}
+	b.client.capabilities.record(CapabilityKeysBatch, true)
+
+	result.Native = true
+	var set, deleted []string
+	for i := range result.Steps {
+		result.Steps[i].Status = KeyStepApplied
+		if result.Steps[i].Op == KeyDelete {
+			deleted = append(deleted, result.Steps[i].Key)
+		} else {
+			set = append(set, result.Steps[i].Key)
+		}
+	}
+	if err := b.client.indexKeys(set...); err != nil {
+		return true, err
+	}
+	return true, b.client.unindexKeys(deleted)
+}
+
+// priorValue is a key's value before the batch
+type priorValue struct {
+	value string
+	found bool
+}
+
+func (b *KeysBatch) execSequential(result *KeysBatchResult) error {
+	c := b.client
+	prior := make([]priorValue, len(b.steps))
+	for i, step := range b.steps {
+		value, found, err := c.GetKey(step.Key)
+		if err != nil {
+			return fmt.Errorf("keys batch: reading %q: %w", step.Key, err)
+		}
+		prior[i] = priorValue{value: value, found: found}
+		if step.Op == KeyCompareAndSet && !holds(prior[i], step.Old) {
+			result.Steps[i].Status = KeyStepFailed
+			result.Steps[i].Err = ErrVersionConflict
+			return fmt.Errorf("keys batch: step %d: %w", i, ErrVersionConflict)
+		}
+	}
+
+	for i, step := range b.steps {
+		err := b.apply(step)
+		if err == nil {
+			result.Steps[i].Status = KeyStepApplied
+			continue
+		}
+		result.Steps[i].Status = KeyStepFailed
+		result.Steps[i].Err = err
+
+		// Restore what the earlier steps overwrote, newest first
+		for j := i - 1; j >= 0; j-- {
+			if rollbackErr := c.restoreKey(b.steps[j].Key, prior[j]); rollbackErr != nil {
+				result.Steps[j].Err = fmt.Errorf("rollback failed: %w", rollbackErr)
+				continue
+			}
+			result.Steps[j].Status = KeyStepRolledBack
+		}
+		return fmt.Errorf("keys batch: step %d (%s %q): %w", i, step.Op, step.Key, err)
+	}
+	return nil
+}
+
+func (b *KeysBatch) apply(step KeyStep) error {
+	switch step.Op {
+	case KeySet:
+		return b.client.SetKey(step.Key, step.Value)
+	case KeyDelete:
+		return b.client.DeleteKey(step.Key)
+	case KeyCompareAndSet:
+		swapped, err := b.client.putKeyIf(step.Key, step.Old, step.Value)
+		if err != nil {
+			return err
+		}
+		if !swapped {
+			return ErrVersionConflict
+		}
+		return b.client.indexKeys(step.Key)
+	}
+	return fmt.Errorf("unknown key operation %q", step.Op)
+}
+
+// holds reports whether a key's value is the expected one, nil meaning absent
+func holds(prior priorValue, old *string) bool {
+	if old == nil {
+		return !prior.found
+	}
+	return prior.found && prior.value == *old
+}
+
+// restoreKey puts back a key's value from before the batch
+func (c *Client) restoreKey(key string, prior priorValue) error {
+	if prior.found {
+		return c.SetKey(key, prior.value)
+	}
+	return c.DeleteKey(key)
+}
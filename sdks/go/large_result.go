@@ -0,0 +1,436 @@
+package torm
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrSpillTooLarge is returned by ExecLarge when the spilled documents
+// would exceed LargeOptions.MaxBytes
+var ErrSpillTooLarge = errors.New("torm: spilled result exceeds size cap")
+
+// LargeOptions configures ExecLarge
+type LargeOptions struct {
+	Dir        string // Where the spill files are written (default os.TempDir())
+	MaxBytes   int64  // Cap on the spilled documents (0 for none)
+	BufferSize int    // Documents held in memory at once, per page and per sort run (default 1000)
+}
+
+// LargeResult is a query result spilled to disk by ExecLarge. Documents are
+// stored as JSON lines next to an index of their offsets, so neither the
+// documents nor the index are held in memory. Close removes the files.
+type LargeResult struct {
+	mu       sync.RWMutex
+	dir      string
+	data     *os.File
+	index    *os.File
+	size     int64 // Bytes in data
+	n        int
+	buffer   int
+	maxBytes int64
+	qb       *QueryBuilder // Compares values for SortBy
+	sorts    int           // Names the files of each SortBy
+	closed   bool
+}
+
+// ExecLarge executes the query a page of opts.BufferSize documents at a
+// time and spills the results to a temporary directory, for results too
+// large to hold in memory. The pages are read by offset like ExecPage, so
+// writes made meanwhile can shift documents between pages. The query's
+// Skip and Limit apply to the whole result. The documents read back are
+// those Exec returns as JSON decodes them.
+func (qb *QueryBuilder) ExecLarge(opts LargeOptions) (*LargeResult, error) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1000
+	}
+	r, err := newLargeResult(qb, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, remaining := 0, -1
+	if qb.skipVal != nil {
+		offset = *qb.skipVal
+	}
+	if qb.limitVal != nil {
+		remaining = *qb.limitVal
+	}
+	w := r.writer(r.data, r.index)
+	for remaining != 0 {
+		size := opts.BufferSize
+		if remaining > 0 && remaining < size {
+			size = remaining
+		}
+		page := qb.Clone().Skip(offset).Limit(size)
+		docs, err := page.Exec()
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		for _, doc := range docs {
+			line, err := json.Marshal(doc)
+			if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("failed to encode document %v: %w", doc["id"], err)
+			}
+			if err := w.add(line); err != nil {
+				r.Close()
+				return nil, err
+			}
+		}
+		offset += len(docs)
+		if remaining > 0 {
+			remaining -= len(docs)
+		}
+		if len(docs) < size {
+			break
+		}
+	}
+	if err := w.flush(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	r.size, r.n = w.size, w.n
+	return r, nil
+}
+
+func newLargeResult(qb *QueryBuilder, opts LargeOptions) (*LargeResult, error) {
+	dir, err := os.MkdirTemp(opts.Dir, "torm-large-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	r := &LargeResult{dir: dir, buffer: opts.BufferSize, maxBytes: opts.MaxBytes, qb: qb.Clone()}
+	if r.data, r.index, err = r.create("rows"); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return r, nil
+}
+
+// create opens a data file and its offset index in the spill directory
+func (r *LargeResult) create(name string) (*os.File, *os.File, error) {
+	data, err := os.Create(filepath.Join(r.dir, name+".jsonl"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	index, err := os.Create(filepath.Join(r.dir, name+".idx"))
+	if err != nil {
+		data.Close()
+		return nil, nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	return data, index, nil
+}
+
+// Len returns the number of documents
+func (r *LargeResult) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.n
+}
+
+// Dir returns the directory holding the spill files, removed by Close
+func (r *LargeResult) Dir() string {
+	return r.dir
+}
+
+// At returns document i, reading it from disk
+func (r *LargeResult) At(i int) (map[string]interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return nil, fmt.Errorf("large result: %w", os.ErrClosed)
+	}
+	if i < 0 || i >= r.n {
+		return nil, fmt.Errorf("large result: index %d out of range [0, %d)", i, r.n)
+	}
+
+	var offsets [16]byte
+	count := 16
+	if i == r.n-1 {
+		count = 8
+	}
+	if _, err := r.index.ReadAt(offsets[:count], int64(i)*8); err != nil {
+		return nil, fmt.Errorf("failed to read spill index: %w", err)
+	}
+	start, end := int64(binary.BigEndian.Uint64(offsets[:8])), r.size
+	if count == 16 {
+		end = int64(binary.BigEndian.Uint64(offsets[8:]))
+	}
+
+	line := make([]byte, end-start)
+	if _, err := r.data.ReadAt(line, start); err != nil {
+		return nil, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode spilled document %d: %w", i, err)
+	}
+	return doc, nil
+}
+
+// Iterate calls fn with each document in order, stopping at the first error
+func (r *LargeResult) Iterate(fn func(i int, doc map[string]interface{}) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return fmt.Errorf("large result: %w", os.ErrClosed)
+	}
+
+	lines := bufio.NewReader(io.NewSectionReader(r.data, 0, r.size))
+	for i := 0; i < r.n; i++ {
+		line, err := lines.ReadBytes('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read spill file: %w", err)
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(line, &doc); err != nil {
+			return fmt.Errorf("failed to decode spilled document %d: %w", i, err)
+		}
+		if err := fn(i, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SortBy reorders the documents by field with an external merge sort:
+// runs of BufferSize documents are sorted in memory and written to disk,
+// then merged. Values compare as they do when the query sorts on the
+// client, and documents with equal values keep their order.
+func (r *LargeResult) SortBy(field string, order SortOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("large result: %w", os.ErrClosed)
+	}
+	key := r.qb.resolveSort(QuerySort{Field: field, Order: order})
+
+	runs, err := r.writeRuns(key)
+	defer func() {
+		for _, run := range runs {
+			run.Close()
+			os.Remove(run.Name())
+		}
+	}()
+	if err != nil {
+		return err
+	}
+
+	r.sorts++
+	data, index, err := r.create(fmt.Sprintf("sorted-%d", r.sorts))
+	if err != nil {
+		return err
+	}
+	w := r.writer(data, index)
+	if err := r.merge(runs, key, w); err != nil {
+		data.Close()
+		index.Close()
+		os.Remove(data.Name())
+		os.Remove(index.Name())
+		return err
+	}
+
+	r.data.Close()
+	r.index.Close()
+	os.Remove(r.data.Name())
+	os.Remove(r.index.Name())
+	r.data, r.index, r.size = data, index, w.size
+	return nil
+}
+
+// writeRuns splits the documents into sorted run files
+func (r *LargeResult) writeRuns(key QuerySort) ([]*os.File, error) {
+	var runs []*os.File
+	lines := bufio.NewReader(io.NewSectionReader(r.data, 0, r.size))
+	for read := 0; read < r.n; {
+		batch := make([]spilledDoc, 0, r.buffer)
+		for ; read < r.n && len(batch) < r.buffer; read++ {
+			doc, err := readSpilled(lines)
+			if err != nil {
+				return runs, err
+			}
+			batch = append(batch, doc)
+		}
+		sort.SliceStable(batch, func(i, j int) bool {
+			return r.qb.compareBy(key, batch[i].doc, batch[j].doc) < 0
+		})
+
+		run, err := os.Create(filepath.Join(r.dir, fmt.Sprintf("run-%04d.jsonl", len(runs))))
+		if err != nil {
+			return runs, fmt.Errorf("failed to create sort run: %w", err)
+		}
+		runs = append(runs, run)
+		w := bufio.NewWriter(run)
+		for _, doc := range batch {
+			if _, err := w.Write(doc.line); err != nil {
+				return runs, fmt.Errorf("failed to write sort run: %w", err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return runs, fmt.Errorf("failed to write sort run: %w", err)
+		}
+	}
+	return runs, nil
+}
+
+// merge writes the runs to w in order, holding one document per run
+func (r *LargeResult) merge(runs []*os.File, key QuerySort, w *spillWriter) error {
+	h := &mergeHeap{less: func(a, b spilledDoc) bool { return r.qb.compareBy(key, a.doc, b.doc) < 0 }}
+	for i, run := range runs {
+		if _, err := run.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read sort run: %w", err)
+		}
+		reader := bufio.NewReader(run)
+		doc, err := readSpilled(reader)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.items = append(h.items, mergeItem{spilledDoc: doc, run: i, reader: reader})
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		head := &h.items[0]
+		if err := w.add(head.line); err != nil {
+			return err
+		}
+		doc, err := readSpilled(head.reader)
+		if err == io.EOF {
+			heap.Pop(h)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		head.spilledDoc = doc
+		heap.Fix(h, 0)
+	}
+	return w.flush()
+}
+
+// Close removes the spill files. It is safe to call more than once.
+func (r *LargeResult) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.data.Close()
+	r.index.Close()
+	return os.RemoveAll(r.dir)
+}
+
+// spilledDoc is a spilled line and the document it decodes to
+type spilledDoc struct {
+	line []byte
+	doc  map[string]interface{}
+}
+
+// readSpilled reads the next line of a spill file, io.EOF at the end
+func readSpilled(lines *bufio.Reader) (spilledDoc, error) {
+	line, err := lines.ReadBytes('\n')
+	if err == io.EOF && len(line) == 0 {
+		return spilledDoc{}, io.EOF
+	}
+	if err != nil {
+		return spilledDoc{}, fmt.Errorf("failed to read spill file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(line, &doc); err != nil {
+		return spilledDoc{}, fmt.Errorf("failed to decode spilled document: %w", err)
+	}
+	return spilledDoc{line: line, doc: doc}, nil
+}
+
+// spillWriter appends lines to a data file and their offsets to its index,
+// enforcing the size cap
+type spillWriter struct {
+	data, index *bufio.Writer
+	size        int64
+	n           int
+	maxBytes    int64
+}
+
+func (r *LargeResult) writer(data, index *os.File) *spillWriter {
+	return &spillWriter{data: bufio.NewWriter(data), index: bufio.NewWriter(index), maxBytes: r.maxBytes}
+}
+
+// add writes a document's line, ending it with a newline if it has none
+func (w *spillWriter) add(line []byte) error {
+	if n := len(line); n == 0 || line[n-1] != '\n' {
+		line = append(line, '\n')
+	}
+	if w.maxBytes > 0 && w.size+int64(len(line)) > w.maxBytes {
+		return fmt.Errorf("%w: more than %d bytes after %d documents", ErrSpillTooLarge, w.maxBytes, w.n)
+	}
+
+	var offset [8]byte
+	binary.BigEndian.PutUint64(offset[:], uint64(w.size))
+	if _, err := w.index.Write(offset[:]); err != nil {
+		return fmt.Errorf("failed to write spill index: %w", err)
+	}
+	if _, err := w.data.Write(line); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	w.size += int64(len(line))
+	w.n++
+	return nil
+}
+
+func (w *spillWriter) flush() error {
+	if err := w.data.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	if err := w.index.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill index: %w", err)
+	}
+	return nil
+}
+
+// mergeItem is the current document of a sort run
+type mergeItem struct {
+	spilledDoc
+	run    int
+	reader *bufio.Reader
+}
+
+// mergeHeap orders run heads, earlier runs first among equals so the
+// merge is stable
+type mergeHeap struct {
+	items []mergeItem
+	less  func(a, b spilledDoc) bool
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.spilledDoc, b.spilledDoc) {
+		return true
+	}
+	if h.less(b.spilledDoc, a.spilledDoc) {
+		return false
+	}
+	return a.run < b.run
+}
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,248 @@
+package torm
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestClass groups requests whose latency is tracked together
+type RequestClass string
+
+const (
+	RequestRead  RequestClass = "read"  // Document, key and listing reads
+	RequestQuery RequestClass = "query" // Queries, which scan the collection
+	RequestWrite RequestClass = "write" // Creates, updates and deletes
+)
+
+// AdaptiveTimeout derives each request's timeout from the latency observed
+// for its RequestClass: Multiplier times the smoothed 95th percentile,
+// clamped to [Min, Max]. Latency is tracked as an exponentially weighted
+// mean and variance, measured with the client's clock. Until a class has
+// MinSamples samples its requests use ClientOptions.Timeout.
+type AdaptiveTimeout struct {
+	Min        time.Duration // Default 100ms
+	Max        time.Duration // Default 30s
+	Multiplier float64       // Default 3
+	MinSamples int           // Samples before the timeout adapts (default 20)
+	Smoothing  float64       // Weight of each new sample, in (0, 1] (default 0.1)
+}
+
+// WithAdaptiveTimeout returns an AdaptiveTimeout for
+// ClientOptions.AdaptiveTimeout with default smoothing
+func WithAdaptiveTimeout(min, max time.Duration, multiplier float64) *AdaptiveTimeout {
+	return &AdaptiveTimeout{Min: min, Max: max, Multiplier: multiplier}
+}
+
+// TimeoutStats is the latency tracked for a RequestClass and the timeout
+// derived from it
+type TimeoutStats struct {
+	Samples  int
+	Mean     time.Duration
+	StdDev   time.Duration
+	P95      time.Duration // Mean + 1.645 standard deviations
+	Timeout  time.Duration // Applied to the next request
+	Adaptive bool          // False while the static timeout is used
+}
+
+// ClientStats is a snapshot of the client's internal state for observability
+type ClientStats struct {
+	Timeouts map[RequestClass]TimeoutStats // Empty without AdaptiveTimeout
+	Retries  RetryStats                    // Zero unless RetryOptions.MaxAttempts is above 1
+	Shed     map[Priority]int64            // Requests shed with ErrLoadShed, by priority
+}
+
+// Stats returns a snapshot of the client's internal state; see also
+// DebugSnapshot
+func (c *Client) Stats() ClientStats {
+	return ClientStats{Timeouts: c.latency.stats(), Retries: c.retryStats(), Shed: c.breaker.shedStats()}
+}
+
+// latencyTracker keeps the smoothed latency of each request class
+type latencyTracker struct {
+	cfg     *AdaptiveTimeout // Nil when timeouts are static
+	static  time.Duration
+	bound   bool // Static timeouts are applied here, the HTTP client has none
+	mu      sync.Mutex
+	classes map[RequestClass]*ewma
+}
+
+// ewma is an exponentially weighted mean and variance, in seconds
+type ewma struct {
+	samples  int
+	mean     float64
+	variance float64
+}
+
+func newLatencyTracker(cfg *AdaptiveTimeout, static time.Duration) *latencyTracker {
+	t := &latencyTracker{static: static, classes: map[RequestClass]*ewma{}}
+	if cfg == nil {
+		return t
+	}
+	resolved := *cfg
+	if resolved.Min <= 0 {
+		resolved.Min = 100 * time.Millisecond
+	}
+	if resolved.Max <= 0 {
+		resolved.Max = 30 * time.Second
+	}
+	if resolved.Max < resolved.Min {
+		resolved.Max = resolved.Min
+	}
+	if resolved.Multiplier <= 0 {
+		resolved.Multiplier = 3
+	}
+	if resolved.MinSamples <= 0 {
+		resolved.MinSamples = 20
+	}
+	if resolved.Smoothing <= 0 || resolved.Smoothing > 1 {
+		resolved.Smoothing = 0.1
+	}
+	t.cfg = &resolved
+	return t
+}
+
+func (t *latencyTracker) observe(class RequestClass, latency time.Duration) {
+	if t.cfg == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := t.classes[class]
+	if e == nil {
+		e = &ewma{}
+		t.classes[class] = e
+	}
+
+	x := latency.Seconds()
+	e.samples++
+	if e.samples == 1 {
+		e.mean = x
+		return
+	}
+	diff := x - e.mean
+	increment := t.cfg.Smoothing * diff
+	e.mean += increment
+	e.variance = (1 - t.cfg.Smoothing) * (e.variance + diff*increment)
+}
+
+// timeout returns the timeout of the next request of a class, 0 when
+// timeouts are static and left to the HTTP client
+func (t *latencyTracker) timeout(class RequestClass) time.Duration {
+	if t.cfg == nil {
+		if t.bound {
+			return t.static
+		}
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.statsLocked(t.classes[class]).Timeout
+}
+
+func (t *latencyTracker) statsLocked(e *ewma) TimeoutStats {
+	if e == nil {
+		return TimeoutStats{Timeout: t.static}
+	}
+	stddev := math.Sqrt(e.variance)
+	p95 := e.mean + 1.645*stddev
+	stats := TimeoutStats{
+		Samples: e.samples,
+		Mean:    seconds(e.mean),
+		StdDev:  seconds(stddev),
+		P95:     seconds(p95),
+		Timeout: t.static,
+	}
+	if e.samples >= t.cfg.MinSamples {
+		timeout := seconds(t.cfg.Multiplier * p95)
+		if timeout < t.cfg.Min {
+			timeout = t.cfg.Min
+		}
+		if timeout > t.cfg.Max {
+			timeout = t.cfg.Max
+		}
+		stats.Timeout = timeout
+		stats.Adaptive = true
+	}
+	return stats
+}
+
+func (t *latencyTracker) stats() map[RequestClass]TimeoutStats {
+	stats := map[RequestClass]TimeoutStats{}
+	if t.cfg == nil {
+		return stats
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for class, e := range t.classes {
+		stats[class] = t.statsLocked(e)
+	}
+	return stats
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Round(s * float64(time.Second)))
+}
+
+// requestClass tells which latency a request is tracked with
+func requestClass(req *http.Request) RequestClass {
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/query"):
+		return RequestQuery
+	case req.Method == http.MethodGet || req.Method == http.MethodHead:
+		return RequestRead
+	}
+	return RequestWrite
+}
+
+// do sends one attempt of a request. With AdaptiveTimeout it bounds the
+// attempt by its class's timeout, until the response body is closed, and
+// records the latency of responses and timeouts.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	class := requestClass(req)
+	parent := req.Context()
+	timeout := c.latency.timeout(class)
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(req.Context(), timeout)
+		req = req.WithContext(ctx)
+	}
+
+	start := c.now()
+	resp, err := c.client.Do(req)
+	if err == nil || isTimeout(err) {
+		c.latency.observe(class, c.now().Sub(start))
+	}
+	if err != nil {
+		// A request its caller gave up on says nothing about the server
+		if parent.Err() == nil {
+			c.observe(0, err)
+		}
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+	c.observe(resp.StatusCode, nil)
+	if cancel != nil {
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's timeout once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,77 @@
+// Package torm keeps the API of the resty-based SDK, before the client was
+// unified, as thin adapters over github.com/toonstore/torm-go. Code
+// written against the old API builds unchanged once its import path
+// names this package; its package name is torm for that reason.
+//
+// Each adapter is listed by torm.Deprecations. legacy_test.go is the
+// pre-unification test suite, run against these adapters with only its
+// import path changed.
+package torm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	unified "github.com/toonstore/torm-go"
+)
+
+// Client is the unified client
+type Client = unified.Client
+
+// Model is the old name of the constraint of Collection
+type Model = unified.Document
+
+// NewClient creates a client for baseURL with the 30s timeout of the old
+// constructor
+//
+// Deprecated: Use torm.NewClient(&torm.ClientOptions{BaseURL: baseURL}).
+func NewClient(baseURL string) *Client {
+	return unified.NewClientFromURL(baseURL)
+}
+
+// Collection is a unified collection with the old map-based Query
+type Collection[T Model] struct {
+	*unified.Collection[T]
+}
+
+// NewCollection creates a new collection handler
+func NewCollection[T Model](client *Client, collection string, factory func() T) *Collection[T] {
+	return &Collection[T]{Collection: unified.NewCollection(client, collection, factory)}
+}
+
+// Query runs a query given as a request body, e.g.
+// {"filters": [{"field": "age", "operator": "gt", "value": 30}]}, with
+// optional "sort" ({"field", "order"}), "limit" and "skip".
+//
+// Deprecated: Use Query().Filter(field, operator, value).Exec() on the
+// unified collection.
+func (c *Collection[T]) Query(query map[string]interface{}) ([]T, error) {
+	encoded, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+	var parsed struct {
+		Filters []unified.QueryFilter `json:"filters"`
+		Sort    *unified.QuerySort    `json:"sort"`
+		Limit   *int                  `json:"limit"`
+		Skip    *int                  `json:"skip"`
+	}
+	if err := json.Unmarshal(encoded, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	q := c.Collection.Query()
+	for _, filter := range parsed.Filters {
+		q.Filter(filter.Field, filter.Operator, filter.Value)
+	}
+	if parsed.Sort != nil {
+		q.Sort(parsed.Sort.Field, parsed.Sort.Order)
+	}
+	if parsed.Limit != nil {
+		q.Limit(*parsed.Limit)
+	}
+	if parsed.Skip != nil {
+		q.Skip(*parsed.Skip)
+	}
+	return q.Exec()
+}
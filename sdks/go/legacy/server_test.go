@@ -0,0 +1,122 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	unified "github.com/toonstore/torm-go"
+)
+
+// The suite in legacy_test.go is the pre-unification one, kept unmodified
+// and run against a server at TORM_URL. Without one it runs against this
+// in-memory server, which is enough of the TORM API for the suite.
+func init() {
+	if os.Getenv("TORM_URL") != "" {
+		return
+	}
+	server := httptest.NewServer(&memoryServer{collections: make(map[string]map[string]map[string]interface{})})
+	os.Setenv("TORM_URL", server.URL)
+}
+
+type memoryServer struct {
+	mu          sync.Mutex
+	collections map[string]map[string]map[string]interface{}
+}
+
+func (s *memoryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "api" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "healthy"})
+		return
+	}
+	docs := s.collections[parts[1]]
+	if docs == nil {
+		docs = make(map[string]map[string]interface{})
+		s.collections[parts[1]] = docs
+	}
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		all := make([]map[string]interface{}, 0, len(docs))
+		for _, doc := range docs {
+			all = append(all, doc)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"collection": parts[1], "count": len(all), "documents": all})
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		id, _ := body.Data["id"].(string)
+		docs[id] = body.Data
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"success": true, "id": id, "data": body.Data})
+	case len(parts) == 3 && parts[2] == "count":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"collection": parts[1], "count": len(docs)})
+	case len(parts) == 3 && parts[2] == "query":
+		var query struct {
+			Filters []unified.QueryFilter `json:"filters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		matched := make([]map[string]interface{}, 0)
+		for _, doc := range docs {
+			if unified.MatchDocument(doc, query.Filters) {
+				matched = append(matched, doc)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"collection": parts[1], "count": len(matched), "documents": matched})
+	case len(parts) == 3:
+		s.serveDocument(w, r, docs, parts[2])
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "not found"})
+	}
+}
+
+func (s *memoryServer) serveDocument(w http.ResponseWriter, r *http.Request, docs map[string]map[string]interface{}, id string) {
+	doc, ok := docs[id]
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Document not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, doc)
+	case http.MethodPut:
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Document not found"})
+			return
+		}
+		docs[id] = body.Data
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "id": id, "data": body.Data})
+	case http.MethodDelete:
+		delete(docs, id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "deleted": ok})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"error": "method not allowed"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
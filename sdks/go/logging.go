@@ -0,0 +1,178 @@
+package torm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLoggedBody caps the bytes of a body logged with DebugBodies; the
+// rest is still sent or read, only left out of the log
+const maxLoggedBody = 16 << 10
+
+// retryCountKey carries the retries of a request from doWithRetry to the
+// request log
+type retryCountKey struct{}
+
+// logged wraps send so every request is logged at debug level through
+// ClientOptions.Logger once sent, with its final status and retries. It
+// runs inside the middleware, so it logs the request as sent. Headers are
+// never logged, so neither are credentials.
+func (c *Client) logged(send RoundTripFunc) RoundTripFunc {
+	if c.logger == nil {
+		return send
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if !c.logger.Enabled(ctx, slog.LevelDebug) {
+			return send(req)
+		}
+		retries := new(int)
+		req = req.WithContext(context.WithValue(ctx, retryCountKey{}, retries))
+
+		var reqBody string
+		if c.debugBodies {
+			reqBody = c.requestBody(req)
+		}
+		started := time.Now()
+		resp, err := send(req)
+		duration := time.Since(started)
+
+		// Exhausted retries fail with the last status
+		status := 0
+		var apiErr *APIError
+		if resp != nil {
+			status = resp.StatusCode
+		} else if errors.As(err, &apiErr) {
+			status = apiErr.StatusCode
+		}
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", duration),
+			slog.Int("retries", *retries),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		if reqBody != "" {
+			attrs = append(attrs, slog.String("request_body", reqBody))
+		}
+		if resp != nil && c.debugBodies {
+			if body := c.responseBody(resp); body != "" {
+				attrs = append(attrs, slog.String("response_body", body))
+			}
+		}
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "torm: request", attrs...)
+		return resp, err
+	}
+}
+
+// countRetries records the retries made for req, for the request log
+func countRetries(req *http.Request, retries int) {
+	if counter, ok := req.Context().Value(retryCountKey{}).(*int); ok {
+		*counter = retries
+	}
+}
+
+// requestBody reads a redacted copy of the request body, leaving the body
+// itself unread
+func (c *Client) requestBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(body, maxLoggedBody+1))
+	return c.redactBody(raw)
+}
+
+// responseBody reads the start of the response body for the log and puts
+// it back, so the caller still reads the whole body. Event streams are
+// not read, as their first bytes may be long in coming.
+func (c *Client) responseBody(resp *http.Response) string {
+	if resp.Body == nil || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return ""
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBody+1))
+	resp.Body = &replayedBody{Reader: io.MultiReader(bytes.NewReader(raw), resp.Body), Closer: resp.Body}
+	return c.redactBody(raw)
+}
+
+// replayedBody is a response body whose start was read for the log
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// redactBody renders a logged body with the values of RedactFields
+// masked. Bodies that are not JSON, or are cut short, are logged by size
+// only, as they cannot be redacted.
+func (c *Client) redactBody(raw []byte) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var body interface{}
+	if len(raw) > maxLoggedBody || json.Unmarshal(raw, &body) != nil {
+		return "[" + byteCount(len(raw)) + "]"
+	}
+	masked, err := json.Marshal(redactValue(body, c.logRedact))
+	if err != nil {
+		return "[" + byteCount(len(raw)) + "]"
+	}
+	return string(masked)
+}
+
+// redactValue masks the values under redacted keys at any depth, and the
+// values of filters on redacted fields, e.g. {"field": "email", "value": ...}
+func redactValue(value interface{}, redacted map[string]bool) interface{} {
+	if len(redacted) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		field, _ := v["field"].(string)
+		for key, inner := range v {
+			if redacted[strings.ToLower(key)] || (key == "value" && redacted[strings.ToLower(field)]) {
+				v[key] = MaskValue(inner)
+			} else {
+				v[key] = redactValue(inner, redacted)
+			}
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner, redacted)
+		}
+	}
+	return value
+}
+
+func byteCount(n int) string {
+	if n > maxLoggedBody {
+		return "more than " + byteCount(maxLoggedBody)
+	}
+	return strconv.Itoa(n) + " bytes"
+}
+
+// logRedactFields indexes ClientOptions.RedactFields by lower-case name
+func logRedactFields(fields []string) map[string]bool {
+	if len(fields) == 0 {
+		return nil
+	}
+	redacted := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		redacted[strings.ToLower(field)] = true
+	}
+	return redacted
+}
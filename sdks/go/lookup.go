@@ -0,0 +1,126 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrLookupTooLarge is returned when a lookup exceeds its configured size
+var ErrLookupTooLarge = errors.New("torm: lookup exceeds size limit")
+
+// LookupOption configures Lookup and LookupPair
+type LookupOption func(*lookupOptions)
+
+type lookupOptions struct {
+	pageSize   int
+	maxEntries int
+	each       func(key string, value interface{}) error
+}
+
+// WithLookupPageSize sets how many documents are fetched per page (default 1000)
+func WithLookupPageSize(n int) LookupOption {
+	return func(o *lookupOptions) {
+		o.pageSize = n
+	}
+}
+
+// WithLookupLimit fails the lookup with ErrLookupTooLarge once more than n entries are collected
+func WithLookupLimit(n int) LookupOption {
+	return func(o *lookupOptions) {
+		o.maxEntries = n
+	}
+}
+
+// WithLookupCallback streams entries to fn instead of building a map.
+// Lookup then returns a nil map; an error from fn stops the lookup.
+func WithLookupCallback(fn func(key string, value interface{}) error) LookupOption {
+	return func(o *lookupOptions) {
+		o.each = fn
+	}
+}
+
+// Lookup builds an id → field map over the documents matching filters,
+// fetching only the needed fields
+func (c *Collection[T]) Lookup(field string, filters map[string]interface{}, opts ...LookupOption) (_ map[string]interface{}, err error) {
+	timer := c.timeOp(OpQuery).as("Lookup")
+	defer timer.end(&err)
+	return c.traced(context.Background(), timer).lookupPair("id", field, filters, opts)
+}
+
+// LookupPair builds a keyField → valueField map over the documents matching
+// filters. Documents without the key field are skipped.
+func (c *Collection[T]) LookupPair(keyField, valueField string, filters map[string]interface{}, opts ...LookupOption) (_ map[string]interface{}, err error) {
+	timer := c.timeOp(OpQuery).as("LookupPair")
+	defer timer.end(&err)
+	return c.traced(context.Background(), timer).lookupPair(keyField, valueField, filters, opts)
+}
+
+func (c *Collection[T]) lookupPair(keyField, valueField string, filters map[string]interface{}, opts []LookupOption) (map[string]interface{}, error) {
+	options := &lookupOptions{pageSize: 1000}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var result map[string]interface{}
+	if options.each == nil {
+		result = make(map[string]interface{})
+	}
+
+	entries := 0
+	lastID := ""
+
+	for {
+		qb := c.model().Query().
+			Sort("id", Asc).
+			Limit(options.pageSize)
+		for field, value := range filters {
+			qb.Where(field, value)
+		}
+		if lastID != "" {
+			qb.Filter("id", Gt, lastID)
+		}
+		// A guard may depend on any field, so it gets full documents
+		if c.options.guard == nil {
+			qb.Select(keyField, valueField)
+		}
+
+		docs, err := qb.Exec()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range docs {
+			lastID = fmt.Sprintf("%v", doc["id"])
+
+			if c.options.checkGuard(OpQuery, doc) != nil {
+				continue
+			}
+
+			keyValue, ok := doc[keyField]
+			if !ok || keyValue == nil {
+				continue
+			}
+			key := fmt.Sprintf("%v", keyValue)
+
+			entries++
+			if options.maxEntries > 0 && entries > options.maxEntries {
+				return nil, fmt.Errorf("%w: more than %d entries", ErrLookupTooLarge, options.maxEntries)
+			}
+
+			if options.each != nil {
+				if err := options.each(key, doc[valueField]); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			result[key] = doc[valueField]
+		}
+
+		if len(docs) < options.pageSize {
+			break
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,315 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// MaintenanceTask names an operation of Maintenance
+type MaintenanceTask string
+
+const (
+	TaskPruneAudit        MaintenanceTask = "prune_audit"
+	TaskCompactMigrations MaintenanceTask = "compact_migrations"
+	TaskPurgeDeadLetters  MaintenanceTask = "purge_dead_letters"
+)
+
+// MaintenanceOptions configures a maintenance operation
+type MaintenanceOptions struct {
+	BatchSize int                       // Items removed between progress reports (default 100)
+	DryRun    bool                      // Count what would be removed without removing it
+	Progress  func(MaintenanceProgress) // Called after every batch
+}
+
+// MaintenanceProgress reports how far a maintenance operation has come
+type MaintenanceProgress struct {
+	Task    MaintenanceTask
+	Matched int
+	Removed int
+}
+
+// MaintenanceReport summarizes a maintenance operation
+type MaintenanceReport struct {
+	Task      MaintenanceTask
+	DryRun    bool
+	Matched   int      // Items past the threshold
+	Removed   int      // Items removed; zero in dry runs
+	FailedIDs []string // Items that could not be removed
+}
+
+// Maintenance prunes the bookkeeping the client's features leave behind:
+// audit entries, migration records and dead letters. It is meant for
+// scheduled jobs; see RunMaintenance.
+type Maintenance struct {
+	client *Client
+}
+
+// Maintenance returns the client's maintenance operations. They act on the
+// audit trails and dead-letter stores of the collections created on this
+// client with WithAudit and WithDeadLetter.
+func (c *Client) Maintenance() *Maintenance {
+	return &Maintenance{client: c}
+}
+
+// PruneAuditOlderThan deletes the audit entries recorded more than d ago
+// by the client's clock. AsOf cannot reach back past the pruned entries.
+func (m *Maintenance) PruneAuditOlderThan(ctx context.Context, d time.Duration, opts *MaintenanceOptions) (*MaintenanceReport, error) {
+	run := newMaintenanceRun(TaskPruneAudit, opts)
+	cutoff := m.client.now().Add(-d)
+
+	m.client.mu.Lock()
+	collections := append([]string(nil), m.client.auditCollections...)
+	m.client.mu.Unlock()
+
+	for _, collection := range collections {
+		model := m.client.Model(collection, nil)
+		// Matching finishes before deleting so pagination never skips entries
+		var ids []string
+		it := model.Query().Select("id", "at").Iter(ctx)
+		for it.Next() {
+			if at, ok := documentTime(it.Doc(), "at"); ok && at.Before(cutoff) {
+				ids = append(ids, fmt.Sprint(it.Doc()["id"]))
+			}
+		}
+		if err := it.Err(); err != nil {
+			return run.report, fmt.Errorf("prune audit of %s failed: %w", collection, err)
+		}
+
+		err := run.remove(ctx, ids, func(id string) error {
+			_, err := model.Delete(id)
+			return err
+		})
+		if err != nil {
+			return run.report, err
+		}
+	}
+	return run.report, nil
+}
+
+// CompactMigrationsHistory folds the records of all but the keepLast most
+// recently applied migrations into a single summary record, keeping their
+// id, name, status and applied_at. The migrations stay applied; only the
+// per-migration keys are removed. Failed and interrupted records are kept.
+func (m *Maintenance) CompactMigrationsHistory(ctx context.Context, keepLast int, opts *MaintenanceOptions) (*MaintenanceReport, error) {
+	run := newMaintenanceRun(TaskCompactMigrations, opts)
+	manager := NewMigrationManager(m.client)
+	records, err := manager.getAppliedMigrations()
+	if err != nil {
+		return run.report, fmt.Errorf("compact migrations failed: %w", err)
+	}
+	ids, found, err := m.client.readIndex(migrationIndexKey)
+	if err != nil || !found {
+		return run.report, err
+	}
+
+	// Only records with their own key can be compacted
+	var applied []string
+	for _, id := range ids {
+		if record, ok := records[id]; ok && recordString(record, "status") == string(MigrationApplied) {
+			applied = append(applied, id)
+		}
+	}
+	sort.Slice(applied, func(i, j int) bool {
+		a, b := recordString(records[applied[i]], "applied_at"), recordString(records[applied[j]], "applied_at")
+		if a != b {
+			return a < b
+		}
+		return applied[i] < applied[j]
+	})
+	if keepLast < 0 {
+		keepLast = 0
+	}
+	if len(applied) <= keepLast {
+		return run.report, nil
+	}
+	applied = applied[:len(applied)-keepLast]
+
+	err = run.batches(ctx, applied, func(batch []string) error {
+		compacted := make(map[string]map[string]interface{}, len(batch))
+		for _, id := range batch {
+			compacted[id] = records[id]
+		}
+		if err := manager.compactRecords(compacted); err != nil {
+			return fmt.Errorf("compact migrations failed: %w", err)
+		}
+		run.report.Removed += len(batch)
+		return nil
+	})
+	return run.report, err
+}
+
+// PurgeDeadLetters removes the dead letters that filter accepts, such as
+// those that failed more than a week ago, without replaying them. A nil
+// filter removes every dead letter.
+func (m *Maintenance) PurgeDeadLetters(ctx context.Context, filter func(DeadLetter) bool, opts *MaintenanceOptions) (*MaintenanceReport, error) {
+	run := newMaintenanceRun(TaskPurgeDeadLetters, opts)
+
+	m.client.mu.Lock()
+	stores := append([]DeadLetterStore(nil), m.client.deadLetterStores...)
+	m.client.mu.Unlock()
+
+	for _, store := range stores {
+		entries, err := store.List()
+		if err != nil {
+			return run.report, fmt.Errorf("failed to list dead letters: %w", err)
+		}
+		var ids []string
+		for _, entry := range entries {
+			accepted := true
+			if filter != nil {
+				if err := protect("dead letter filter", entry.Collection, entry.DocID, func() { accepted = filter(entry) }); err != nil {
+					return run.report, err
+				}
+			}
+			if accepted {
+				ids = append(ids, entry.ID)
+			}
+		}
+		if err := run.remove(ctx, ids, store.Remove); err != nil {
+			return run.report, err
+		}
+	}
+	return run.report, nil
+}
+
+// MaintenanceSpec selects the operations of a RunMaintenance job. Zero
+// fields skip their operation.
+type MaintenanceSpec struct {
+	AuditOlderThan time.Duration // Prune audit entries older than this
+	// CompactMigrations compacts migration records, keeping the last
+	// KeepMigrations in full
+	CompactMigrations bool
+	KeepMigrations    int
+	// PurgeDeadLetters removes the dead letters it accepts
+	PurgeDeadLetters func(DeadLetter) bool
+
+	BatchSize int
+	DryRun    bool
+	Progress  func(MaintenanceProgress)
+}
+
+// RunMaintenance runs the operations of spec in turn, for cron jobs. A
+// failing operation does not stop the others; their errors are joined.
+func (m *Maintenance) RunMaintenance(ctx context.Context, spec MaintenanceSpec) ([]*MaintenanceReport, error) {
+	opts := &MaintenanceOptions{BatchSize: spec.BatchSize, DryRun: spec.DryRun, Progress: spec.Progress}
+	var tasks []func() (*MaintenanceReport, error)
+	if spec.AuditOlderThan > 0 {
+		tasks = append(tasks, func() (*MaintenanceReport, error) { return m.PruneAuditOlderThan(ctx, spec.AuditOlderThan, opts) })
+	}
+	if spec.CompactMigrations {
+		tasks = append(tasks, func() (*MaintenanceReport, error) { return m.CompactMigrationsHistory(ctx, spec.KeepMigrations, opts) })
+	}
+	if spec.PurgeDeadLetters != nil {
+		tasks = append(tasks, func() (*MaintenanceReport, error) { return m.PurgeDeadLetters(ctx, spec.PurgeDeadLetters, opts) })
+	}
+
+	reports := make([]*MaintenanceReport, 0, len(tasks))
+	var errs []error
+	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return reports, err
+		}
+		report, err := task()
+		reports = append(reports, report)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+// maintenanceRun tallies one operation across its batches
+type maintenanceRun struct {
+	opts   MaintenanceOptions
+	report *MaintenanceReport
+}
+
+func newMaintenanceRun(task MaintenanceTask, opts *MaintenanceOptions) *maintenanceRun {
+	run := &maintenanceRun{report: &MaintenanceReport{Task: task}}
+	if opts != nil {
+		run.opts = *opts
+	}
+	if run.opts.BatchSize <= 0 {
+		run.opts.BatchSize = 100
+	}
+	run.report.DryRun = run.opts.DryRun
+	return run
+}
+
+// batches counts ids as matched and, unless dry running, hands them to fn
+// a batch at a time, reporting progress after each
+func (r *maintenanceRun) batches(ctx context.Context, ids []string, fn func(batch []string) error) error {
+	r.report.Matched += len(ids)
+	if r.opts.DryRun {
+		r.progress()
+		return nil
+	}
+	for start := 0; start < len(ids); start += r.opts.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := start + r.opts.BatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := fn(ids[start:end]); err != nil {
+			return err
+		}
+		r.progress()
+	}
+	return nil
+}
+
+// remove removes ids one at a time, reporting those that fail without
+// stopping
+func (r *maintenanceRun) remove(ctx context.Context, ids []string, remove func(id string) error) error {
+	return r.batches(ctx, ids, func(batch []string) error {
+		for _, id := range batch {
+			if err := remove(id); err != nil {
+				r.report.FailedIDs = append(r.report.FailedIDs, id)
+				continue
+			}
+			r.report.Removed++
+		}
+		return nil
+	})
+}
+
+func (r *maintenanceRun) progress() {
+	if r.opts.Progress != nil {
+		r.opts.Progress(MaintenanceProgress{Task: r.report.Task, Matched: r.report.Matched, Removed: r.report.Removed})
+	}
+}
+
+// registerAudit records an audit collection for PruneAuditOlderThan
+func (c *Client) registerAudit(collection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, registered := range c.auditCollections {
+		if registered == collection {
+			return
+		}
+	}
+	c.auditCollections = append(c.auditCollections, collection)
+}
+
+// registerDeadLetters records a dead-letter store for PurgeDeadLetters.
+// A store shared by several collections is recorded once.
+func (c *Client) registerDeadLetters(store DeadLetterStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if reflect.TypeOf(store).Comparable() {
+		for _, registered := range c.deadLetterStores {
+			if registered == store {
+				return
+			}
+		}
+	}
+	c.deadLetterStores = append(c.deadLetterStores, store)
+}
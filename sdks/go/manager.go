@@ -0,0 +1,142 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrEnvironmentExists is returned when an environment is registered twice
+	ErrEnvironmentExists = errors.New("torm: environment already registered")
+	// ErrUnknownEnvironment is returned for environments that were never registered
+	ErrUnknownEnvironment = errors.New("torm: unknown environment")
+)
+
+// ClientManager holds one client per named environment, such as "dev",
+// "staging" and "prod", so tooling that talks to several servers resolves
+// them by name instead of passing clients around
+type ClientManager struct {
+	mu          sync.RWMutex
+	clients     map[string]*Client
+	names       []string // In registration order
+	defaultName string
+}
+
+// NewClientManager creates an empty manager
+func NewClientManager() *ClientManager {
+	return &ClientManager{clients: make(map[string]*Client)}
+}
+
+// Register creates the client of an environment. The first environment
+// registered becomes the default. Set ClientOptions.WriteConfirmation to
+// write-protect it.
+func (m *ClientManager) Register(name string, opts *ClientOptions) (*Client, error) {
+	if name == "" {
+		return nil, fmt.Errorf("environment needs a name")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.clients[name]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentExists, name)
+	}
+	client := NewClient(opts)
+	m.clients[name] = client
+	m.names = append(m.names, name)
+	if m.defaultName == "" {
+		m.defaultName = name
+	}
+	return client, nil
+}
+
+// Get returns the client of an environment, or of the default one for ""
+func (m *ClientManager) Get(name string) (*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if name == "" {
+		name = m.defaultName
+	}
+	client, ok := m.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEnvironment, name)
+	}
+	return client, nil
+}
+
+// Default makes a registered environment the one Get("") returns
+func (m *ClientManager) Default(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[name]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownEnvironment, name)
+	}
+	m.defaultName = name
+	return nil
+}
+
+// Environments lists the registered environments in registration order
+func (m *ClientManager) Environments() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.names...)
+}
+
+// Model creates a model for a collection of an environment
+func (m *ClientManager) Model(env, name string, schema map[string]ValidationRule) (*Model, error) {
+	client, err := m.Get(env)
+	if err != nil {
+		return nil, err
+	}
+	return client.Model(name, schema), nil
+}
+
+// ManagedCollection creates a collection handler on the client of an
+// environment, or of the default one for "". Go methods cannot take type
+// parameters, hence a function rather than a ClientManager method.
+func ManagedCollection[T Document](m *ClientManager, env, collection string, factory func() T, opts ...CollectionOption) (*Collection[T], error) {
+	client, err := m.Get(env)
+	if err != nil {
+		return nil, err
+	}
+	return NewCollection(client, collection, factory, opts...), nil
+}
+
+// EnvironmentHealth is the health of one environment, see HealthAll
+type EnvironmentHealth struct {
+	Name           string
+	Default        bool
+	WriteProtected bool
+	State          ClientState            // The client's view before the check
+	Health         map[string]interface{} // The server's health response
+	Err            error                  // Why the check failed, if it did
+}
+
+// HealthAll checks every environment's health concurrently and reports
+// them in registration order
+func (m *ClientManager) HealthAll() []EnvironmentHealth {
+	m.mu.RLock()
+	report := make([]EnvironmentHealth, len(m.names))
+	clients := make([]*Client, len(m.names))
+	for i, name := range m.names {
+		clients[i] = m.clients[name]
+		report[i] = EnvironmentHealth{
+			Name:           name,
+			Default:        name == m.defaultName,
+			WriteProtected: clients[i].WriteProtected(),
+		}
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(entry *EnvironmentHealth, client *Client) {
+			defer wg.Done()
+			entry.State = client.State()
+			entry.Health, entry.Err = client.Health()
+		}(&report[i], client)
+	}
+	wg.Wait()
+	return report
+}
@@ -0,0 +1,395 @@
+package torm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrIndexTooLarge is returned when loading an index would exceed the
+// collection's index limit
+var ErrIndexTooLarge = errors.New("torm: index exceeds its entry limit")
+
+// defaultIndexLimit bounds an index when WithIndexLimit is not set
+const defaultIndexLimit = 1_000_000
+
+// indexEntryOverhead approximates the bytes an entry costs beyond its strings
+const indexEntryOverhead = 64
+
+// WithIndexLimit bounds every in-memory index of the collection to
+// maxEntries (document, field) pairs (default 1,000,000)
+func WithIndexLimit(maxEntries int) CollectionOption {
+	return func(o *collectionOptions) {
+		o.indexLimit = maxEntries
+	}
+}
+
+// IndexStats reports an index's size and freshness
+type IndexStats struct {
+	Documents   int
+	Entries     int   // Indexed (document, field) pairs
+	ApproxBytes int64 // Estimated memory held by the entries
+	LoadedAt    time.Time
+	Refreshes   int64
+	Stale       bool  // A local write was not indexed because of the limit
+	LastError   error // Error of the last automatic refresh, if any
+}
+
+// MemoryIndex answers equality lookups on a few fields from memory. It is
+// loaded from a snapshot of the collection, kept current with the
+// collection's own writes, and reloaded by Refresh or AutoRefresh.
+type MemoryIndex[T Document] struct {
+	collection *Collection[T]
+	fields     []string
+	limit      int
+
+	mu        sync.RWMutex
+	byField   map[string]map[string][]string // field -> value key -> ids
+	docs      map[string]map[string]string   // id -> field -> value key
+	entries   int
+	bytes     int64
+	loadedAt  time.Time
+	refreshes int64
+	stale     bool
+	lastErr   error
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// localIndex is kept current with a collection's writes
+type localIndex interface {
+	put(doc map[string]interface{})
+	remove(id string)
+	Refresh(ctx context.Context) error
+	indexedFields() []string // Fields lookups can use, none for caches
+}
+
+// indexRegistry holds the indexes loaded from a collection
+type indexRegistry struct {
+	mu      sync.Mutex
+	indexes []localIndex
+}
+
+func (r *indexRegistry) add(index localIndex) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.indexes = append(r.indexes, index)
+}
+
+func (r *indexRegistry) drop(index localIndex) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, registered := range r.indexes {
+		if registered == index {
+			r.indexes = append(r.indexes[:i], r.indexes[i+1:]...)
+			return
+		}
+	}
+}
+
+// put reindexes a document the collection wrote
+func (r *indexRegistry) put(doc map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, index := range r.indexes {
+		index.put(doc)
+	}
+}
+
+// remove drops a document the collection deleted
+func (r *indexRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, index := range r.indexes {
+		index.remove(id)
+	}
+}
+
+// fields lists the fields of every index
+func (r *indexRegistry) fields() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var fields []string
+	for _, index := range r.indexes {
+		fields = append(fields, index.indexedFields()...)
+	}
+	return fields
+}
+
+// refresh reloads every index, for when writes may have been missed
+func (r *indexRegistry) refresh(ctx context.Context) {
+	r.mu.Lock()
+	indexes := append([]localIndex{}, r.indexes...)
+	r.mu.Unlock()
+
+	for _, index := range indexes {
+		index.Refresh(ctx)
+	}
+}
+
+// LoadIndex snapshots the collection, projected to id and fields, into an
+// in-memory index. Later writes through the collection update the index,
+// and documents changed by other processes are evicted while the client
+// follows invalidations (see Client.FollowInvalidations).
+func (c *Collection[T]) LoadIndex(ctx context.Context, fields ...string) (*MemoryIndex[T], error) {
+	c = c.prioritizedBy(ctx)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("load index: no fields given")
+	}
+
+	limit := c.options.indexLimit
+	if limit <= 0 {
+		limit = defaultIndexLimit
+	}
+	index := &MemoryIndex[T]{
+		collection: c,
+		fields:     fields,
+		limit:      limit,
+		stop:       make(chan struct{}),
+	}
+	if err := index.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	c.options.indexes.add(index)
+	c.client.invalidation.track(c.collection, &c.options.indexes)
+	return index, nil
+}
+
+// Refresh reloads the index from a new snapshot of the collection. The old
+// contents keep answering lookups until the new snapshot is in place.
+func (idx *MemoryIndex[T]) Refresh(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	docs, err := idx.collection.model().Query().Select(append([]string{"id"}, idx.fields...)...).Exec()
+	if err != nil {
+		return fmt.Errorf("load index: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	byField := make(map[string]map[string][]string, len(idx.fields))
+	for _, field := range idx.fields {
+		byField[field] = make(map[string][]string)
+	}
+	entries := make(map[string]map[string]string, len(docs))
+	var bytes int64
+	count := 0
+	for _, doc := range docs {
+		id := fmt.Sprintf("%v", doc["id"])
+		keys := idx.keysOf(doc)
+		count += len(keys)
+		if count > idx.limit {
+			return fmt.Errorf("%w: more than %d entries", ErrIndexTooLarge, idx.limit)
+		}
+
+		entries[id] = keys
+		for field, key := range keys {
+			byField[field][key] = append(byField[field][key], id)
+			bytes += entrySize(id, key)
+		}
+	}
+	for _, values := range byField {
+		for _, ids := range values {
+			sort.Strings(ids)
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.byField = byField
+	idx.docs = entries
+	idx.entries = count
+	idx.bytes = bytes
+	idx.loadedAt = idx.collection.client.now()
+	idx.refreshes++
+	idx.stale = false
+	return nil
+}
+
+// AutoRefresh reloads the index every interval until Close is called.
+// Failed refreshes keep the previous contents and are reported by Stats.
+func (idx *MemoryIndex[T]) AutoRefresh(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-idx.stop:
+				return
+			case <-ticker.C:
+				err := idx.Refresh(context.Background())
+				idx.mu.Lock()
+				idx.lastErr = err
+				idx.mu.Unlock()
+			}
+		}
+	}()
+}
+
+func (idx *MemoryIndex[T]) indexedFields() []string {
+	return idx.fields
+}
+
+// Close stops automatic refreshes and detaches the index from the
+// collection's writes
+func (idx *MemoryIndex[T]) Close() {
+	idx.stopOnce.Do(func() {
+		close(idx.stop)
+		idx.collection.options.indexes.drop(idx)
+	})
+}
+
+// GetByField returns the ids of documents whose field equals value, or nil
+// when the field is not indexed
+func (idx *MemoryIndex[T]) GetByField(field string, value interface{}) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	values, ok := idx.byField[field]
+	if !ok {
+		return nil
+	}
+	ids := values[indexKey(value)]
+	return append(make([]string, 0, len(ids)), ids...)
+}
+
+// Get returns the documents whose field equals value, fetched from the
+// server in one query. Documents deleted since the index was loaded are
+// skipped.
+func (idx *MemoryIndex[T]) Get(field string, value interface{}) ([]T, error) {
+	ids := idx.GetByField(field, value)
+	if len(ids) == 0 {
+		return []T{}, nil
+	}
+
+	matches := make([]interface{}, len(ids))
+	for i, id := range ids {
+		matches[i] = id
+	}
+	docs, err := idx.collection.model().Query().Filter("id", In, matches).Exec()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return fmt.Sprintf("%v", docs[i]["id"]) < fmt.Sprintf("%v", docs[j]["id"])
+	})
+	return idx.collection.toModels(OpQuery, docs)
+}
+
+// Stats reports the index's size and freshness
+func (idx *MemoryIndex[T]) Stats() IndexStats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return IndexStats{
+		Documents:   len(idx.docs),
+		Entries:     idx.entries,
+		ApproxBytes: idx.bytes,
+		LoadedAt:    idx.loadedAt,
+		Refreshes:   idx.refreshes,
+		Stale:       idx.stale,
+		LastError:   idx.lastErr,
+	}
+}
+
+func (idx *MemoryIndex[T]) put(doc map[string]interface{}) {
+	id, _ := doc["id"].(string)
+	if id == "" {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(id)
+	keys := idx.keysOf(doc)
+	if idx.entries+len(keys) > idx.limit {
+		idx.stale = true
+		return
+	}
+
+	idx.docs[id] = keys
+	idx.entries += len(keys)
+	for field, key := range keys {
+		ids := append(idx.byField[field][key], id)
+		sort.Strings(ids)
+		idx.byField[field][key] = ids
+		idx.bytes += entrySize(id, key)
+	}
+}
+
+func (idx *MemoryIndex[T]) remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(id)
+}
+
+func (idx *MemoryIndex[T]) removeLocked(id string) {
+	keys, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	delete(idx.docs, id)
+	idx.entries -= len(keys)
+
+	for field, key := range keys {
+		ids := idx.byField[field][key]
+		for i, indexed := range ids {
+			if indexed == id {
+				ids = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(idx.byField[field], key)
+		} else {
+			idx.byField[field][key] = ids
+		}
+		idx.bytes -= entrySize(id, key)
+	}
+}
+
+// keysOf returns the value keys of the indexed fields a document has
+func (idx *MemoryIndex[T]) keysOf(doc map[string]interface{}) map[string]string {
+	keys := make(map[string]string, len(idx.fields))
+	for _, field := range idx.fields {
+		if value, ok := doc[field]; ok {
+			keys[field] = indexKey(value)
+		}
+	}
+	return keys
+}
+
+// indexKey maps a value to a lookup key, so that 3 and 3.0 match but "3"
+// does not
+func indexKey(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "s:" + v
+	case bool:
+		return "b:" + strconv.FormatBool(v)
+	}
+	if f, ok := toFloat64(value); ok {
+		return "n:" + strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	encoded, _ := json.Marshal(value)
+	return "j:" + string(encoded)
+}
+
+func entrySize(id, key string) int64 {
+	return int64(len(id) + len(key) + indexEntryOverhead)
+}
@@ -0,0 +1,173 @@
+package torm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// MicroCacheOptions sets how long the result of a parameterless read is
+// shared with later callers. Concurrent callers always share one request
+// while a window is set; zero disables sharing for that read.
+type MicroCacheOptions struct {
+	Count           time.Duration // Count without filters, per collection
+	Health          time.Duration
+	Info            time.Duration
+	ListCollections time.Duration
+}
+
+// WithMicroCache shares the collection's Count results among callers within
+// d of each other, overriding ClientOptions.MicroCache.Count
+func WithMicroCache(d time.Duration) CollectionOption {
+	return func(o *collectionOptions) {
+		o.countWindow = &d
+	}
+}
+
+// WithMicroCache shares the model's Count results among callers within d
+// of each other, overriding ClientOptions.MicroCache.Count
+func (m *Model) WithMicroCache(d time.Duration) *Model {
+	m.countWindow = &d
+	return m
+}
+
+// sharedRead is one fetch whose result is handed to every caller that
+// arrives while it runs or within the window after it completes
+type sharedRead struct {
+	done     chan struct{}
+	value    interface{}
+	err      error
+	finished time.Time
+}
+
+// microCache holds the latest shared read of each operation
+type microCache struct {
+	mu    sync.Mutex
+	reads map[string]*sharedRead
+}
+
+// shared returns the result of fetch, reusing a fetch of the same key that
+// is in flight or finished less than window ago. Failures are shared with
+// concurrent callers but never reused afterwards.
+func (c *Client) shared(key string, window time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	if window <= 0 {
+		return fetch()
+	}
+
+	mc := &c.microCache
+	mc.mu.Lock()
+	if read, ok := mc.reads[key]; ok {
+		select {
+		case <-read.done:
+			if read.err == nil && c.now().Sub(read.finished) < window {
+				mc.mu.Unlock()
+				return read.value, nil
+			}
+		default:
+			mc.mu.Unlock()
+			<-read.done
+			return read.value, read.err
+		}
+	}
+
+	read := &sharedRead{done: make(chan struct{})}
+	if mc.reads == nil {
+		mc.reads = make(map[string]*sharedRead)
+	}
+	mc.reads[key] = read
+	mc.mu.Unlock()
+
+	read.value, read.err = fetch()
+	read.finished = c.now()
+	close(read.done)
+	return read.value, read.err
+}
+
+// sharedDoc is shared for documents, giving each caller its own copy
+func (c *Client) sharedDoc(key string, window time.Duration, fetch func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	value, err := c.shared(key, window, func() (interface{}, error) {
+		return fetch()
+	})
+	doc, _ := value.(map[string]interface{})
+	if err != nil || doc == nil {
+		return doc, err
+	}
+	return deepCopyDoc(doc), nil
+}
+
+// sharedCount is shared for counts
+func (c *Client) sharedCount(collection string, window time.Duration, fetch func() (int, error)) (int, error) {
+	value, err := c.shared("count:"+collection, window, func() (interface{}, error) {
+		return fetch()
+	})
+	count, _ := value.(int)
+	return count, err
+}
+
+// countSharing returns the sharing window of the collection's Count
+func (c *Collection[T]) countSharing() time.Duration {
+	if c.options.countWindow != nil {
+		return *c.options.countWindow
+	}
+	return c.client.microCacheOptions.Count
+}
+
+// countSharing returns the sharing window of the model's Count
+func (m *Model) countSharing() time.Duration {
+	if m.countWindow != nil {
+		return *m.countWindow
+	}
+	return m.client.microCacheOptions.Count
+}
+
+// ListCollections returns the names of the server's collections. Servers
+// without the endpoint yield ErrNotSupported.
+func (c *Client) ListCollections() ([]string, error) {
+	value, err := c.shared("collections", c.microCacheOptions.ListCollections, func() (interface{}, error) {
+		return c.listCollections()
+	})
+	names, _ := value.([]string)
+	return append([]string(nil), names...), err
+}
+
+func (c *Client) listCollections() ([]string, error) {
+	resp, err := c.request("GET", c.apiPath("_collections"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("list collections failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, fmt.Errorf("list collections: %w", ErrNotSupported)
+	default:
+		return nil, responseError("list collections", resp)
+	}
+
+	// Collections are listed by name or as objects with a name
+	var result struct {
+		Collections []json.RawMessage `json:"collections"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode collections: %w", err)
+	}
+
+	names := make([]string, 0, len(result.Collections))
+	for _, raw := range result.Collections {
+		var name string
+		if json.Unmarshal(raw, &name) != nil {
+			var entry struct {
+				Name string `json:"name"`
+			}
+			json.Unmarshal(raw, &entry)
+			name = entry.Name
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
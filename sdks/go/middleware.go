@@ -0,0 +1,85 @@
+package torm
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// RoundTripFunc sends a request and returns its response
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of every request the client makes, e.g. for
+// logging, metrics or extra headers. It calls next to send the request, or
+// returns a response of its own to short-circuit it, such as one served
+// from a cache. It may change the request's headers and body (see
+// SetRequestBody) and the response, including replacing its Body. The
+// request URL should be left alone, as retries rebuild it for each
+// endpoint.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Use adds middleware around every request the client sends, after the
+// request is authorized. Middleware runs in the order it was added: the
+// first added sees the request first and the response last. Retries happen
+// inside the chain, so middleware sees each call once, with its final
+// response. A panic in middleware fails the request with a *PanicError.
+func (c *Client) Use(middleware ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// SetRequestBody replaces the body of a request in middleware, keeping it
+// replayable for retries
+func SetRequestBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
+// intercept sends req through the client's middleware, ending with send.
+// An open circuit fails req before any of it runs.
+func (c *Client) intercept(req *http.Request, send RoundTripFunc) (*http.Response, error) {
+	priority := requestPriority(req)
+	release, err := c.breaker.admit(req, priority, c.now())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if priority != PriorityNormal {
+		// Kept for readmit, which asks the breaker again before a retry
+		req = req.WithContext(WithCallPriority(req.Context(), priority))
+	}
+
+	send = c.logged(send)
+	c.mu.Lock()
+	middleware := c.middleware
+	c.mu.Unlock()
+	if len(middleware) == 0 {
+		return send(req)
+	}
+
+	next := send
+	for i := len(middleware) - 1; i >= 0; i-- {
+		next = middleware[i](next)
+	}
+	var resp *http.Response
+	if panicked := protect("middleware", "", "", func() { resp, err = next(req) }); panicked != nil {
+		return nil, panicked
+	}
+	if resp != nil {
+		// Responses made up by middleware may lack what callers rely on
+		if resp.Body == nil {
+			resp.Body = http.NoBody
+		}
+		if resp.Request == nil {
+			resp.Request = req
+		}
+		if resp.Header == nil {
+			resp.Header = make(http.Header)
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,123 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// migrationHooks are the callbacks registered on a MigrationManager
+type migrationHooks struct {
+	start    []func(id, name string)
+	progress []func(id string, processed, total int)
+	complete []func(id string, duration time.Duration)
+	failure  []func(id string, err error)
+}
+
+// migrationRun is the migration a manager is currently applying
+type migrationRun struct {
+	id        string
+	ctx       context.Context
+	processed atomic.Int64
+}
+
+// OnMigrationStart registers a callback called before a pending migration
+// runs. Migrations that are already applied are skipped without callbacks.
+func (m *MigrationManager) OnMigrationStart(fn func(id, name string)) {
+	m.hooks.start = append(m.hooks.start, fn)
+}
+
+// OnMigrationProgress registers a callback called after each document a
+// migration handles through ForEach or Backfill. Processed and total cover
+// the current ForEach or Backfill pass.
+func (m *MigrationManager) OnMigrationProgress(fn func(id string, processed, total int)) {
+	m.hooks.progress = append(m.hooks.progress, fn)
+}
+
+// OnMigrationComplete registers a callback called after a migration is
+// applied and recorded
+func (m *MigrationManager) OnMigrationComplete(fn func(id string, duration time.Duration)) {
+	m.hooks.complete = append(m.hooks.complete, fn)
+}
+
+// OnMigrationError registers a callback called when a migration fails, is
+// cancelled or cannot be recorded
+func (m *MigrationManager) OnMigrationError(fn func(id string, err error)) {
+	m.hooks.failure = append(m.hooks.failure, fn)
+}
+
+// ForEach calls fn with every document of the model in id order, reporting
+// progress to OnMigrationProgress. Inside a MigrateContext run it stops
+// between documents once the run's context is done, returning the
+// context's error so the migration is recorded as failed with its partial
+// progress.
+func (m *MigrationManager) ForEach(model *Model, fn func(doc map[string]interface{}) error) error {
+	run := m.current()
+	total, err := model.Count()
+	if err != nil {
+		return fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	processed := 0
+	return model.ForEach(nil, func(doc map[string]interface{}) error {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+		processed++
+		run.processed.Add(1)
+		if run.id != "" {
+			for _, hook := range m.hooks.progress {
+				hook(run.id, processed, total)
+			}
+		}
+		return nil
+	})
+}
+
+// Backfill is ForEach for migrations that rewrite documents: the fields fn
+// returns are written to the document with Update, and a nil map leaves it
+// unchanged
+func (m *MigrationManager) Backfill(model *Model, fn func(doc map[string]interface{}) (map[string]interface{}, error)) error {
+	return m.ForEach(model, func(doc map[string]interface{}) error {
+		updates, err := fn(doc)
+		if err != nil || updates == nil {
+			return err
+		}
+		id := fmt.Sprintf("%v", doc["id"])
+		if _, err := model.Update(id, updates); err != nil {
+			return fmt.Errorf("backfill of %s failed: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// current returns the migration being applied, or a detached run when the
+// helpers are used outside MigrateContext
+func (m *MigrationManager) current() *migrationRun {
+	if m.run != nil {
+		return m.run
+	}
+	return &migrationRun{ctx: context.Background()}
+}
+
+func (m *MigrationManager) started(migration Migration) {
+	for _, hook := range m.hooks.start {
+		hook(migration.ID, migration.Name)
+	}
+}
+
+func (m *MigrationManager) completed(id string, duration time.Duration) {
+	for _, hook := range m.hooks.complete {
+		hook(id, duration)
+	}
+}
+
+func (m *MigrationManager) failed(id string, err error) {
+	for _, hook := range m.hooks.failure {
+		hook(id, err)
+	}
+}
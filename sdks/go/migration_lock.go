@@ -0,0 +1,95 @@
+package torm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrMigrationLocked is returned when another process held the migration
+// lock for longer than the caller was willing to wait
+var ErrMigrationLocked = errors.New("torm: migration lock held by another process")
+
+const migrationLockKey = "torm:migrations-lock"
+
+// lockPollInterval is how often a waiting process checks the lock again
+const lockPollInterval = 250 * time.Millisecond
+
+// migrationLock is the value of the lock key. An empty value or an expired
+// lease means the lock is free.
+type migrationLock struct {
+	Owner string    `json:"owner"`
+	Until time.Time `json:"until"`
+}
+
+// lockOwner identifies this process in the lock, unique per call so two
+// clients in one process do not share a lease
+func lockOwner() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// acquireMigrationLock takes the migration lock for owner, waiting up to
+// wait for another holder to release it or for its lease to run out. The
+// lock is taken with a compare-and-set of the lock key, so it is atomic on
+// servers that honor If-Match and If-None-Match on keys. The returned
+// function releases the lock if owner still holds it.
+func (c *Client) acquireMigrationLock(ctx context.Context, owner string, ttl, wait time.Duration) (func() error, error) {
+	deadline := c.now().Add(wait)
+	for {
+		raw, found, err := c.GetKey(migrationLockKey)
+		if err != nil {
+			return nil, fmt.Errorf("read migration lock: %w", err)
+		}
+
+		var held migrationLock
+		if found && raw != "" {
+			if err := json.Unmarshal([]byte(raw), &held); err != nil {
+				return nil, fmt.Errorf("corrupt migration lock: %w", err)
+			}
+		}
+
+		now := c.now()
+		if held.Owner == "" || !now.Before(held.Until) {
+			encoded, err := json.Marshal(migrationLock{Owner: owner, Until: now.Add(ttl).UTC()})
+			if err != nil {
+				return nil, err
+			}
+			var old *string
+			if found {
+				old = &raw
+			}
+			value := string(encoded)
+			taken, err := c.CompareAndSetKey(migrationLockKey, old, value)
+			if err != nil {
+				return nil, fmt.Errorf("take migration lock: %w", err)
+			}
+			if taken {
+				return func() error {
+					_, err := c.CompareAndSetKey(migrationLockKey, &value, "")
+					return err
+				}, nil
+			}
+			continue // Someone else took it between the read and the write
+		}
+
+		remaining := deadline.Sub(now)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w: %s until %s", ErrMigrationLocked, held.Owner, held.Until.Format(time.RFC3339))
+		}
+		timer := time.NewTimer(min(remaining, lockPollInterval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package torm
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// MigrationOutcome is what happened to a migration during a run
+type MigrationOutcome string
+
+const (
+	OutcomeApplied    MigrationOutcome = "applied"
+	OutcomeRolledBack MigrationOutcome = "rolled_back"
+	OutcomeSkipped    MigrationOutcome = "skipped" // Already applied, or no registered migration to roll back
+	OutcomeFailed     MigrationOutcome = "failed"
+	OutcomeNotRun     MigrationOutcome = "not_run" // Not reached because the run stopped earlier
+)
+
+// MigrationResult is the outcome of a single migration within a run
+type MigrationResult struct {
+	ID       string
+	Name     string
+	Outcome  MigrationOutcome
+	Duration time.Duration
+	Err      error
+	// Processed counts the documents handled through ForEach and Backfill,
+	// including those of a migration that failed part way
+	Processed int
+	// Recorded reports whether the record store reflects the outcome. An
+	// applied migration with Recorded false will run again next time.
+	Recorded bool
+}
+
+// MigrateResult is the structured result of MigrateDetailed or RollbackDetailed
+type MigrateResult struct {
+	Migrations []MigrationResult
+	StoppedAt  string // Id of the migration where the run stopped, empty when it completed
+	Recorded   bool   // Whether every record write of the run succeeded
+}
+
+// Names returns the names of the migrations with the given outcome, in run order
+func (r *MigrateResult) Names(outcome MigrationOutcome) []string {
+	names := make([]string, 0)
+	for _, migration := range r.Migrations {
+		if migration.Outcome == outcome {
+			names = append(names, migration.Name)
+		}
+	}
+	return names
+}
+
+// stop marks the run as stopped at a migration and the remaining ones as not run
+func (r *MigrateResult) stop(id string, remaining []MigrationResult) {
+	r.StoppedAt = id
+	for _, migration := range remaining {
+		migration.Outcome = OutcomeNotRun
+		r.Migrations = append(r.Migrations, migration)
+	}
+}
+
+// MigrateDetailed runs all pending migrations, stopping at the first failure.
+// A failed migration is recorded as failed on a best-effort basis; a failed
+// record write after a successful Up also stops the run.
+func (m *MigrationManager) MigrateDetailed() (*MigrateResult, error) {
+	return m.MigrateContext(context.Background())
+}
+
+// MigrateContext is MigrateDetailed with cancellation. The context is
+// checked before each migration, and ForEach and Backfill check it between
+// documents; a migration stopped part way is recorded as failed along with
+// the documents it processed, and runs again next time.
+func (m *MigrationManager) MigrateContext(ctx context.Context) (*MigrateResult, error) {
+	result, err := m.migrate(ctx)
+	return result, m.client.wrapOp(err, OpError{Op: "Migrate", ID: stoppedAt(result)})
+}
+
+func (m *MigrationManager) migrate(ctx context.Context) (*MigrateResult, error) {
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrateResult{Recorded: true}
+
+	for i, migration := range m.migrations {
+		outcome := MigrationResult{ID: migration.ID, Name: migration.Name}
+
+		if record, exists := applied[migration.ID]; exists && !isFailedRecord(record) {
+			outcome.Outcome = OutcomeSkipped
+			outcome.Recorded = true
+			result.Migrations = append(result.Migrations, outcome)
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			result.stop(migration.ID, pendingResults(m.migrations[i:]))
+			return result, err
+		}
+
+		m.started(migration)
+		m.run = &migrationRun{id: migration.ID, ctx: ctx}
+		start := time.Now()
+		var err error
+		if panicked := protect("migration up", "", migration.ID, func() { err = migration.Up(m.client) }); panicked != nil {
+			err = panicked
+		}
+		outcome.Processed = int(m.run.processed.Load())
+		m.run = nil
+
+		if err != nil {
+			outcome.Outcome = OutcomeFailed
+			outcome.Duration = time.Since(start)
+			outcome.Err = err
+			// Best effort: the migration error matters more than a failed record write
+			outcome.Recorded = m.saveMigration(map[string]interface{}{
+				"id":        migration.ID,
+				"name":      migration.Name,
+				"status":    string(MigrationFailed),
+				"error":     err.Error(),
+				"failed_at": time.Now().Format(time.RFC3339),
+				"processed": outcome.Processed,
+			}) == nil
+			result.Recorded = result.Recorded && outcome.Recorded
+			result.Migrations = append(result.Migrations, outcome)
+			result.stop(migration.ID, pendingResults(m.migrations[i+1:]))
+			m.failed(migration.ID, err)
+			return result, err
+		}
+
+		outcome.Outcome = OutcomeApplied
+		outcome.Duration = time.Since(start)
+
+		if err := m.saveMigration(map[string]interface{}{
+			"id":          migration.ID,
+			"name":        migration.Name,
+			"status":      string(MigrationApplied),
+			"applied_at":  time.Now().Format(time.RFC3339),
+			"duration_ms": outcome.Duration.Milliseconds(),
+		}); err != nil {
+			outcome.Err = err
+			result.Recorded = false
+			result.Migrations = append(result.Migrations, outcome)
+			result.stop(migration.ID, pendingResults(m.migrations[i+1:]))
+			m.failed(migration.ID, err)
+			return result, err
+		}
+
+		outcome.Recorded = true
+		result.Migrations = append(result.Migrations, outcome)
+		m.completed(migration.ID, outcome.Duration)
+	}
+
+	return result, nil
+}
+
+// RollbackDetailed rolls back the last N applied migrations, newest first,
+// stopping at the first failure
+func (m *MigrationManager) RollbackDetailed(steps int) (*MigrateResult, error) {
+	return m.RollbackContext(context.Background(), steps)
+}
+
+// RollbackContext is RollbackDetailed with cancellation. Each migration is
+// recorded as rolling back before its Down runs, and its record is removed
+// only once Down succeeds, so a Down that fails or is cancelled part way
+// leaves the migration in MigrationRollingBack. Such migrations count as
+// applied: the next rollback runs their Down again, and ResumeInterrupted
+// lists them. The context is checked before each migration, and ForEach
+// and Backfill check it between documents.
+func (m *MigrationManager) RollbackContext(ctx context.Context, steps int) (*MigrateResult, error) {
+	result, err := m.rollbackSteps(ctx, steps)
+	return result, m.client.wrapOp(err, OpError{Op: "Rollback", ID: stoppedAt(result)})
+}
+
+func (m *MigrationManager) rollbackSteps(ctx context.Context, steps int) (*MigrateResult, error) {
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	type appliedMigration struct {
+		ID        string
+		Name      string
+		AppliedAt string
+		Record    map[string]interface{}
+	}
+
+	sorted := make([]appliedMigration, 0, len(applied))
+	for id, data := range applied {
+		if isFailedRecord(data) {
+			continue
+		}
+		sorted = append(sorted, appliedMigration{
+			ID:        id,
+			Name:      recordString(data, "name"),
+			AppliedAt: recordString(data, "applied_at"),
+			Record:    data,
+		})
+	}
+
+	// Newest first; ids break ties between migrations applied in the same second
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].AppliedAt != sorted[j].AppliedAt {
+			return sorted[i].AppliedAt > sorted[j].AppliedAt
+		}
+		return sorted[i].ID > sorted[j].ID
+	})
+	if steps < len(sorted) {
+		sorted = sorted[:steps]
+	}
+
+	registered := make(map[string]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		registered[migration.ID] = migration
+	}
+
+	result := &MigrateResult{Recorded: true}
+
+	for i, record := range sorted {
+		migration, ok := registered[record.ID]
+		if !ok {
+			result.Migrations = append(result.Migrations, MigrationResult{
+				ID:       record.ID,
+				Name:     record.Name,
+				Outcome:  OutcomeSkipped,
+				Recorded: true,
+			})
+			continue
+		}
+
+		remaining := make([]MigrationResult, 0, len(sorted)-i-1)
+		for _, rest := range sorted[i+1:] {
+			remaining = append(remaining, MigrationResult{ID: rest.ID, Name: rest.Name})
+		}
+
+		if err := ctx.Err(); err != nil {
+			result.stop(record.ID, append([]MigrationResult{{ID: record.ID, Name: record.Name}}, remaining...))
+			return result, err
+		}
+
+		outcome, err := m.rollback(ctx, migration, record.Record)
+		outcome.Name = record.Name
+		result.Recorded = result.Recorded && outcome.Recorded
+		result.Migrations = append(result.Migrations, outcome)
+		if err != nil {
+			result.stop(record.ID, remaining)
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// rollback runs the Down of an applied migration, recording it as rolling
+// back first and removing its record once Down succeeds
+func (m *MigrationManager) rollback(ctx context.Context, migration Migration, record map[string]interface{}) (MigrationResult, error) {
+	outcome := MigrationResult{ID: migration.ID, Name: migration.Name, Outcome: OutcomeFailed}
+
+	if !isRollingBackRecord(record) {
+		marked := make(map[string]interface{}, len(record)+2)
+		for field, value := range record {
+			marked[field] = value
+		}
+		marked["id"] = migration.ID
+		marked["status"] = string(MigrationRollingBack)
+		marked["rolling_back_at"] = time.Now().Format(time.RFC3339)
+		if err := m.saveMigration(marked); err != nil {
+			outcome.Err = err
+			return outcome, err
+		}
+	}
+
+	m.run = &migrationRun{id: migration.ID, ctx: ctx}
+	start := time.Now()
+	var err error
+	if panicked := protect("migration down", "", migration.ID, func() { err = migration.Down(m.client) }); panicked != nil {
+		err = panicked
+	}
+	outcome.Processed = int(m.run.processed.Load())
+	outcome.Duration = time.Since(start)
+	m.run = nil
+
+	if err != nil {
+		outcome.Err = err
+		outcome.Recorded = true // Left rolling back
+		return outcome, err
+	}
+
+	outcome.Outcome = OutcomeRolledBack
+	if err := m.removeMigration(migration.ID); err != nil {
+		outcome.Err = err
+		return outcome, err
+	}
+	outcome.Recorded = true
+	return outcome, nil
+}
+
+// stoppedAt is the migration a run stopped at, if it got that far
+func stoppedAt(result *MigrateResult) string {
+	if result == nil {
+		return ""
+	}
+	return result.StoppedAt
+}
+
+func pendingResults(migrations []Migration) []MigrationResult {
+	results := make([]MigrationResult, len(migrations))
+	for i, migration := range migrations {
+		results[i] = MigrationResult{ID: migration.ID, Name: migration.Name}
+	}
+	return results
+}
@@ -0,0 +1,105 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// InterruptedMigration is a migration left in an intermediate state, such
+// as a rollback whose Down failed, was cancelled or never finished because
+// the process exited. Its data may be partly reverted; resolve it with
+// RetryDown or MarkApplied.
+type InterruptedMigration struct {
+	ID         string
+	Name       string
+	State      MigrationState
+	Since      time.Time // When the state was entered; zero when unknown
+	Registered bool      // Whether the manager has the migration, which RetryDown needs
+
+	manager *MigrationManager
+}
+
+// ResumeInterrupted returns the recorded migrations stuck in an
+// intermediate state, ordered by id
+func (m *MigrationManager) ResumeInterrupted() ([]InterruptedMigration, error) {
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	interrupted := make([]InterruptedMigration, 0)
+	for id, record := range applied {
+		if !isRollingBackRecord(record) {
+			continue
+		}
+		stuck := InterruptedMigration{
+			ID:      id,
+			Name:    recordString(record, "name"),
+			State:   MigrationRollingBack,
+			manager: m,
+		}
+		if since, err := time.Parse(time.RFC3339, recordString(record, "rolling_back_at")); err == nil {
+			stuck.Since = since
+		}
+		_, stuck.Registered = m.registered(id)
+		interrupted = append(interrupted, stuck)
+	}
+
+	sort.Slice(interrupted, func(i, j int) bool {
+		return interrupted[i].ID < interrupted[j].ID
+	})
+	return interrupted, nil
+}
+
+// RetryDown runs the migration's Down again, removing its record once Down
+// succeeds. Down must cope with data it already partly reverted.
+func (i InterruptedMigration) RetryDown(ctx context.Context) (MigrationResult, error) {
+	record, err := i.record()
+	if err != nil {
+		return MigrationResult{ID: i.ID, Name: i.Name, Outcome: OutcomeFailed, Err: err}, err
+	}
+	migration, ok := i.manager.registered(i.ID)
+	if !ok {
+		err := fmt.Errorf("migration %s is not registered", i.ID)
+		return MigrationResult{ID: i.ID, Name: i.Name, Outcome: OutcomeFailed, Err: err}, err
+	}
+	return i.manager.rollback(ctx, migration, record)
+}
+
+// MarkApplied returns the migration to the applied state, for when its data
+// is known to be as Up left it
+func (i InterruptedMigration) MarkApplied() error {
+	record, err := i.record()
+	if err != nil {
+		return err
+	}
+	record["status"] = string(MigrationApplied)
+	delete(record, "rolling_back_at")
+	return i.manager.saveMigration(record)
+}
+
+// record reads the migration's record, checking it is still interrupted
+func (i InterruptedMigration) record() (map[string]interface{}, error) {
+	applied, err := i.manager.getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	record, ok := applied[i.ID]
+	if !ok || !isRollingBackRecord(record) {
+		return nil, fmt.Errorf("migration %s is no longer interrupted", i.ID)
+	}
+	record["id"] = i.ID
+	return record, nil
+}
+
+// registered returns the manager's migration with the given id
+func (m *MigrationManager) registered(id string) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.ID == id {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
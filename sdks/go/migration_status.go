@@ -0,0 +1,183 @@
+package torm
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// MigrationState is the lifecycle state of a migration
+type MigrationState string
+
+const (
+	MigrationPending MigrationState = "pending"
+	MigrationApplied MigrationState = "applied"
+	MigrationFailed  MigrationState = "failed"
+	// MigrationRollingBack marks a migration whose Down started but has not
+	// completed; see ResumeInterrupted
+	MigrationRollingBack MigrationState = "rolling_back"
+	// MigrationDrifted marks a record that no longer matches a registered
+	// migration: its id is unknown to the manager or its name changed
+	MigrationDrifted MigrationState = "drifted"
+)
+
+// MigrationStatus is the typed status of a single migration
+type MigrationStatus struct {
+	ID        string
+	Name      string
+	State     MigrationState
+	AppliedAt time.Time // Zero when unknown or not applied
+	Duration  time.Duration
+	Error     string
+	Processed int // Documents a failed migration processed before it stopped
+}
+
+// StatusDetailed returns the status of every registered migration followed by
+// any recorded migrations the manager does not know about
+func (m *MigrationManager) StatusDetailed() ([]MigrationStatus, error) {
+	applied, err := m.getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	known := make(map[string]bool, len(m.migrations))
+
+	for _, migration := range m.migrations {
+		known[migration.ID] = true
+
+		record, exists := applied[migration.ID]
+		if !exists {
+			statuses = append(statuses, MigrationStatus{
+				ID:    migration.ID,
+				Name:  migration.Name,
+				State: MigrationPending,
+			})
+			continue
+		}
+
+		status := statusFromRecord(migration.ID, record)
+		if status.Name == "" {
+			status.Name = migration.Name
+		}
+		if status.State == MigrationApplied && status.Name != migration.Name {
+			status.State = MigrationDrifted
+		}
+		statuses = append(statuses, status)
+	}
+
+	orphans := make([]string, 0)
+	for id := range applied {
+		if !known[id] {
+			orphans = append(orphans, id)
+		}
+	}
+	sort.Strings(orphans)
+
+	for _, id := range orphans {
+		status := statusFromRecord(id, applied[id])
+		status.State = MigrationDrifted
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// StatusJSON renders StatusDetailed as a single JSON document for tooling
+func (m *MigrationManager) StatusJSON() ([]byte, error) {
+	statuses, err := m.StatusDetailed()
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		ID         string         `json:"id"`
+		Name       string         `json:"name"`
+		State      MigrationState `json:"state"`
+		AppliedAt  string         `json:"applied_at,omitempty"`
+		DurationMs int64          `json:"duration_ms,omitempty"`
+		Error      string         `json:"error,omitempty"`
+		Processed  int            `json:"processed,omitempty"`
+	}
+
+	doc := struct {
+		Migrations []entry                `json:"migrations"`
+		Counts     map[MigrationState]int `json:"counts"`
+	}{
+		Migrations: make([]entry, 0, len(statuses)),
+		Counts: map[MigrationState]int{
+			MigrationPending:     0,
+			MigrationApplied:     0,
+			MigrationFailed:      0,
+			MigrationRollingBack: 0,
+			MigrationDrifted:     0,
+		},
+	}
+
+	for _, status := range statuses {
+		e := entry{
+			ID:         status.ID,
+			Name:       status.Name,
+			State:      status.State,
+			DurationMs: status.Duration.Milliseconds(),
+			Error:      status.Error,
+			Processed:  status.Processed,
+		}
+		if !status.AppliedAt.IsZero() {
+			e.AppliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		doc.Migrations = append(doc.Migrations, e)
+		doc.Counts[status.State]++
+	}
+
+	return json.Marshal(doc)
+}
+
+// statusFromRecord converts a stored migration record, tolerating missing or
+// malformed values
+func statusFromRecord(id string, record map[string]interface{}) MigrationStatus {
+	status := MigrationStatus{
+		ID:    id,
+		Name:  recordString(record, "name"),
+		State: MigrationApplied,
+		Error: recordString(record, "error"),
+	}
+
+	switch recordString(record, "status") {
+	case string(MigrationFailed):
+		status.State = MigrationFailed
+	case string(MigrationRollingBack):
+		status.State = MigrationRollingBack
+	}
+
+	if appliedAt, err := time.Parse(time.RFC3339, recordString(record, "applied_at")); err == nil {
+		status.AppliedAt = appliedAt
+	}
+
+	if ms, ok := record["duration_ms"].(float64); ok {
+		status.Duration = time.Duration(ms) * time.Millisecond
+	}
+
+	if processed, ok := record["processed"].(float64); ok {
+		status.Processed = int(processed)
+	}
+
+	return status
+}
+
+// recordString reads a string value from a migration record
+func recordString(record map[string]interface{}, key string) string {
+	value, _ := record[key].(string)
+	return value
+}
+
+// isFailedRecord reports whether a record describes a failed migration
+func isFailedRecord(record map[string]interface{}) bool {
+	return recordString(record, "status") == string(MigrationFailed)
+}
+
+// isRollingBackRecord reports whether a record describes a migration whose
+// rollback has not completed
+func isRollingBackRecord(record map[string]interface{}) bool {
+	return recordString(record, "status") == string(MigrationRollingBack)
+}
@@ -0,0 +1,226 @@
+package torm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Applied migrations are recorded one key per migration, so appliers never
+// rewrite each other's records, plus an index key listing the ids. Stores
+// written by older versions keep every record in the single migrationsKey
+// value; they are split on first read, keeping the original in a backup key.
+// CompactMigrationsHistory folds the records of long-applied migrations into
+// summaries in the baseline key.
+const (
+	migrationsKey        = "torm:migrations"          // Legacy record of every migration
+	migrationKeyPrefix   = "torm:migrations:"         // Followed by the migration id
+	migrationIndexKey    = "torm:migrations-index"    // Ids with a record key
+	migrationBackupKey   = "torm:migrations-backup"   // The legacy record as it was before the split
+	migrationBaselineKey = "torm:migrations-baseline" // Summaries of compacted records
+)
+
+// WithLegacyRecord keeps a summary of every record in the single key read
+// by older versions, for deploys where they still check migration status.
+// Records are still read from the per-migration keys only, so older
+// versions must not apply migrations once the store has been split.
+func (m *MigrationManager) WithLegacyRecord() *MigrationManager {
+	m.legacy = true
+	return m
+}
+
+func (m *MigrationManager) getAppliedMigrations() (map[string]map[string]interface{}, error) {
+	ids, found, err := m.client.readIndex(migrationIndexKey)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return m.splitLegacyRecord()
+	}
+
+	migrations := make(map[string]map[string]interface{}, len(ids))
+	for _, id := range ids {
+		raw, found, err := m.client.GetKey(migrationKeyPrefix + id)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue // Removed, and not yet unindexed
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			return nil, fmt.Errorf("corrupt migration record %s: %w", id, err)
+		}
+		migrations[id] = record
+	}
+
+	baseline, err := m.readSummaries(migrationBaselineKey)
+	if err != nil {
+		return nil, err
+	}
+	for id, summary := range baseline {
+		if _, ok := migrations[id]; !ok {
+			migrations[id] = summary
+		}
+	}
+	return migrations, nil
+}
+
+// splitLegacyRecord moves the records of a store written by an older
+// version to per-migration keys. Clients splitting at the same time write
+// the same keys, so the split needs no coordination.
+func (m *MigrationManager) splitLegacyRecord() (map[string]map[string]interface{}, error) {
+	migrations := make(map[string]map[string]interface{})
+	raw, found, err := m.client.GetKey(migrationsKey)
+	if err != nil || !found {
+		return migrations, err
+	}
+	if err := json.Unmarshal([]byte(raw), &migrations); err != nil {
+		return nil, fmt.Errorf("corrupt migration record %s: %w", migrationsKey, err)
+	}
+	if len(migrations) == 0 {
+		return migrations, nil
+	}
+
+	// Only the first split is backed up
+	if _, err := m.client.putKeyIf(migrationBackupKey, nil, raw); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(migrations))
+	for id, record := range migrations {
+		if err := m.writeRecord(id, record); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := m.client.updateIndex(migrationIndexKey, ids, true); err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+func (m *MigrationManager) saveMigration(migration map[string]interface{}) error {
+	id := migration["id"].(string)
+	if err := m.writeRecord(id, migration); err != nil {
+		return fmt.Errorf("failed to save migration: %w", err)
+	}
+	if err := m.client.updateIndex(migrationIndexKey, []string{id}, true); err != nil {
+		return fmt.Errorf("failed to save migration: %w", err)
+	}
+	if m.legacy {
+		return m.updateSummaries(migrationsKey, map[string]map[string]interface{}{id: migration})
+	}
+	return nil
+}
+
+func (m *MigrationManager) removeMigration(migrationID string) error {
+	if err := m.client.DeleteKey(migrationKeyPrefix + migrationID); err != nil {
+		return fmt.Errorf("failed to remove migration: %w", err)
+	}
+	if err := m.client.updateIndex(migrationIndexKey, []string{migrationID}, false); err != nil {
+		return fmt.Errorf("failed to remove migration: %w", err)
+	}
+	removed := map[string]map[string]interface{}{migrationID: nil}
+	if err := m.updateSummaries(migrationBaselineKey, removed); err != nil {
+		return fmt.Errorf("failed to remove migration: %w", err)
+	}
+	if m.legacy {
+		return m.updateSummaries(migrationsKey, removed)
+	}
+	return nil
+}
+
+func (m *MigrationManager) writeRecord(id string, record map[string]interface{}) error {
+	jsonData, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return m.client.SetKey(migrationKeyPrefix+id, string(jsonData))
+}
+
+// compactRecords folds the records of ids into summaries in the baseline
+// key, then removes their record keys. The summaries are written first, so
+// the migrations never look unapplied.
+func (m *MigrationManager) compactRecords(records map[string]map[string]interface{}) error {
+	if err := m.updateSummaries(migrationBaselineKey, records); err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(records))
+	for id := range records {
+		if err := m.client.DeleteKey(migrationKeyPrefix + id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	return m.client.updateIndex(migrationIndexKey, ids, false)
+}
+
+// readSummaries reads a key holding migration summaries by id, such as the
+// legacy record
+func (m *MigrationManager) readSummaries(key string) (map[string]map[string]interface{}, error) {
+	summaries := make(map[string]map[string]interface{})
+	raw, found, err := m.client.GetKey(key)
+	if err != nil || !found || raw == "" {
+		return summaries, err
+	}
+	if err := json.Unmarshal([]byte(raw), &summaries); err != nil {
+		return nil, fmt.Errorf("corrupt migration record %s: %w", key, err)
+	}
+	return summaries, nil
+}
+
+// updateSummaries sets or, for nil records, removes migrations in a key of
+// summaries, keeping the fields older versions read from the legacy record
+func (m *MigrationManager) updateSummaries(key string, records map[string]map[string]interface{}) error {
+	for attempt := 0; attempt < maxSwapAttempts; attempt++ {
+		raw, found, err := m.client.GetKey(key)
+		if err != nil {
+			return err
+		}
+		migrations := make(map[string]map[string]interface{})
+		if found && raw != "" {
+			if err := json.Unmarshal([]byte(raw), &migrations); err != nil {
+				return fmt.Errorf("corrupt migration record %s: %w", key, err)
+			}
+		}
+
+		changed := false
+		for id, record := range records {
+			if record == nil {
+				if _, ok := migrations[id]; ok {
+					delete(migrations, id)
+					changed = true
+				}
+				continue
+			}
+			summary := make(map[string]interface{})
+			for _, field := range []string{"id", "name", "status", "applied_at", "failed_at", "error"} {
+				if value, ok := record[field]; ok {
+					summary[field] = value
+				}
+			}
+			migrations[id] = summary
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+
+		jsonData, err := json.Marshal(migrations)
+		if err != nil {
+			return err
+		}
+		var old *string
+		if found {
+			old = &raw
+		}
+		swapped, err := m.client.putKeyIf(key, old, string(jsonData))
+		if err != nil {
+			return fmt.Errorf("failed to update migration record %s: %w", key, err)
+		}
+		if swapped {
+			return nil
+		}
+	}
+	return fmt.Errorf("update %s: too many concurrent writers", key)
+}
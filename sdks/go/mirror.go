@@ -0,0 +1,231 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrMirrorQueueFull is reported to MirrorOptions.OnError for writes
+// dropped because the mirror fell behind
+var ErrMirrorQueueFull = errors.New("torm: mirror queue full")
+
+// MirrorTarget receives a collection's mirrored writes: a *Client, which
+// mirrors to the collection of the same name, or a *Collection
+type MirrorTarget interface {
+	mirrorTarget(collection string) (*Client, string)
+}
+
+func (c *Client) mirrorTarget(collection string) (*Client, string) {
+	return c, collection
+}
+
+func (c *Collection[T]) mirrorTarget(string) (*Client, string) {
+	return c.client, c.collection
+}
+
+// MirrorOverflow decides what happens to writes when the mirror queue is full
+type MirrorOverflow int
+
+const (
+	MirrorDrop  MirrorOverflow = iota // Drop the write and report it to OnError (default)
+	MirrorBlock                       // Make the primary call wait for room
+)
+
+// MirrorOptions configures WithMirror
+type MirrorOptions struct {
+	QueueSize int            // Writes waiting to be replayed (default 1000)
+	Overflow  MirrorOverflow // What happens when the queue is full
+	// Strict replays each write before the primary call returns, failing
+	// the call when the mirror write fails. The primary write is not undone.
+	Strict  bool
+	OnError func(write MirrorWrite, err error) // Called for every failed or dropped replay
+}
+
+// MirrorWrite is a mutation replayed to a mirror
+type MirrorWrite struct {
+	Op  Operation
+	ID  string
+	Doc map[string]interface{} // Stored form of the document; nil for deletes
+	At  time.Time              // When the primary write completed
+}
+
+// MirrorStats reports the state of a collection's mirror
+type MirrorStats struct {
+	Pending  int           // Writes queued or being replayed
+	Replayed int64         // Writes the mirror accepted
+	Failed   int64         // Writes the mirror rejected or that failed to send
+	Dropped  int64         // Writes dropped because the queue was full
+	Lag      time.Duration // How long the last replayed write waited
+}
+
+// mirror replays a collection's writes to another collection, one at a
+// time in write order, so writes to a document reach the mirror in order
+type mirror struct {
+	client     *Client
+	collection string
+	opts       MirrorOptions
+
+	start sync.Once
+	queue chan MirrorWrite
+
+	pending  atomic.Int64
+	replayed atomic.Int64
+	failed   atomic.Int64
+	dropped  atomic.Int64
+	lag      atomic.Int64
+}
+
+// WithMirror replays every successful write of the collection to target,
+// e.g. for dual writes while moving to another cluster. Writes are
+// replayed in the background in the order they were made; reads never
+// touch the mirror. Documents are mirrored in the form stored on the
+// primary, after codecs.
+func WithMirror(target MirrorTarget, opts MirrorOptions) CollectionOption {
+	return func(o *collectionOptions) {
+		if opts.QueueSize <= 0 {
+			opts.QueueSize = 1000
+		}
+		client, collection := target.mirrorTarget(o.collection)
+		o.mirror = &mirror{client: client, collection: collection, opts: opts}
+	}
+}
+
+// MirrorStats returns the state of the collection's mirror
+func (c *Collection[T]) MirrorStats() MirrorStats {
+	m := c.options.mirror
+	if m == nil {
+		return MirrorStats{}
+	}
+	return MirrorStats{
+		Pending:  int(m.pending.Load()),
+		Replayed: m.replayed.Load(),
+		Failed:   m.failed.Load(),
+		Dropped:  m.dropped.Load(),
+		Lag:      time.Duration(m.lag.Load()),
+	}
+}
+
+// FlushMirror waits until every queued write has been replayed, e.g.
+// before switching reads to the mirror
+func (c *Collection[T]) FlushMirror(ctx context.Context) error {
+	c = c.prioritizedBy(ctx)
+	m := c.options.mirror
+	if m == nil {
+		return nil
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for m.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// mirrorWrite hands a completed write to the collection's mirror. It only
+// fails in strict mode.
+func (c *Collection[T]) mirrorWrite(op Operation, id string, doc map[string]interface{}) error {
+	m := c.options.mirror
+	if m == nil || id == "" {
+		return nil
+	}
+
+	write := MirrorWrite{Op: op, ID: id, At: c.client.now()}
+	if doc != nil {
+		stored, err := c.options.codecs.encode(deepCopyDoc(doc))
+		if err != nil {
+			return err
+		}
+		write.Doc = stored
+	}
+
+	if m.opts.Strict {
+		m.pending.Add(1)
+		defer m.pending.Add(-1)
+		if err := m.replay(c.client.now, write); err != nil {
+			return fmt.Errorf("mirror %s of %s failed: %w", op, id, err)
+		}
+		return nil
+	}
+
+	m.start.Do(func() {
+		m.queue = make(chan MirrorWrite, m.opts.QueueSize)
+		go m.run(c.client.now)
+	})
+
+	m.pending.Add(1)
+	if m.opts.Overflow == MirrorBlock {
+		m.queue <- write
+		return nil
+	}
+	select {
+	case m.queue <- write:
+	default:
+		m.pending.Add(-1)
+		m.dropped.Add(1)
+		m.report(write, ErrMirrorQueueFull)
+	}
+	return nil
+}
+
+func (m *mirror) run(now func() time.Time) {
+	for write := range m.queue {
+		m.replay(now, write)
+		m.pending.Add(-1)
+	}
+}
+
+// replay applies one write to the mirror. Documents are replaced when they
+// exist and created otherwise; deleting a missing document succeeds.
+func (m *mirror) replay(now func() time.Time, write MirrorWrite) error {
+	m.lag.Store(int64(now().Sub(write.At)))
+
+	err := func() error {
+		path := m.client.apiPath(m.collection, write.ID)
+		if write.Op == OpDelete {
+			resp, err := m.client.call("DELETE", path, nil)
+			if err != nil {
+				return err
+			}
+			if !resp.IsSuccess() && resp.StatusCode() != http.StatusNotFound {
+				return resp.apiError("mirror delete")
+			}
+			return nil
+		}
+
+		body := map[string]interface{}{"data": write.Doc}
+		resp, err := m.client.call("PUT", path, body)
+		if err == nil && resp.StatusCode() == http.StatusNotFound {
+			resp, err = m.client.call("POST", m.client.apiPath(m.collection), body)
+		}
+		if err != nil {
+			return err
+		}
+		if !resp.IsSuccess() {
+			return resp.apiError("mirror write")
+		}
+		return nil
+	}()
+
+	if err != nil {
+		m.failed.Add(1)
+		m.report(write, err)
+		return err
+	}
+	m.replayed.Add(1)
+	return nil
+}
+
+func (m *mirror) report(write MirrorWrite, err error) {
+	if m.opts.OnError != nil {
+		m.opts.OnError(write, err)
+	}
+}
@@ -1,9 +1,11 @@
 package torm
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // Model represents a database model
@@ -12,156 +14,347 @@ type Model struct {
 	name       string
 	collection string
 	schema     map[string]ValidationRule
+	compiled   *compiledSchema // See CompileSchema
 	validate   bool
+	codecs     codecChain
+	slugs      []slugRule
+	readRepair *readRepairer
+	autoCoerce bool // See WithAutoCoerce
+
+	countWindow  *time.Duration // See WithMicroCache
+	defaultOrder *QuerySort     // See WithDefaultOrder
+	sortDefaults *SortDefaults  // See WithSortDefaults
+	deadLetter   DeadLetterSink // See WithDeadLetter
+
+	slowThreshold *time.Duration // See WithSlowThreshold
+	redact        []string       // See WithRedaction
+	confirm       string         // See Confirm
+	sanitizer     *sanitizer     // See WithSanitizer
+	trace         *opTimer       // See traced
+	parity        *filterParity  // See WithFilterParityChecks
+	priority      Priority       // See WithPriority and WithCallPriority
 }
 
 // Create creates a new document
 func (m *Model) Create(data map[string]interface{}) (map[string]interface{}, error) {
-	if m.validate && m.schema != nil {
-		if err := m.validateData(data, false); err != nil {
+	result, err := m.CreateDetailed(data)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// CreateDetailed creates a new document and reports the response status,
+// including whether the server only accepted the write for later
+func (m *Model) CreateDetailed(data map[string]interface{}) (*WriteResult, error) {
+	return m.create(data, CreateOptions{})
+}
+
+func (m *Model) create(data map[string]interface{}, opts CreateOptions) (result *WriteResult, err error) {
+	timer := m.client.timeOp(OpCreate, m.collection, m.slowThreshold)
+	defer timer.end(&err)
+	m = m.traced(context.Background(), timer)
+	data, stripped, err := m.sanitizer.strip(m.collection, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySlugs(m.slugs, m.Query, data, "", nil); err != nil {
+		return nil, err
+	}
+
+	if m.validate {
+		if err := m.validateWith(data, false, opts.Validation); err != nil {
 			return nil, err
 		}
 	}
 
-	reqBody := map[string]interface{}{"data": data}
-	resp, err := m.client.request("POST", "/api/"+m.collection, reqBody)
+	stored, err := m.codecs.encode(data)
+	if err != nil {
+		return nil, err
+	}
+	reqBody := map[string]interface{}{"data": stored}
+	resp, err := m.request("POST", m.client.apiPath(m.collection), reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("create failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("create failed with status %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusConflict {
+		return nil, parseConflict(m.collection, responseError("create", resp)).redact(m.redactor(), data)
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if !isSuccess(resp.StatusCode) {
+		return nil, responseError("create", resp).redact(m.redactor(), data)
 	}
 
-	if resultData, ok := result["data"].(map[string]interface{}); ok {
-		return resultData, nil
+	result, err = m.decodeWrite("create", resp)
+	if err == nil {
+		restore(result.Data, stripped)
+		id, _ := result.Data["id"].(string)
+		if id == "" {
+			id, _ = data["id"].(string)
+		}
+		m.client.invalidate(m.collection, id, OpCreate)
+	}
+	return result, err
+}
+
+// Find finds all documents, following the pages of paginated listings
+// as configured by ClientOptions.Pagination
+func (m *Model) Find() (found []map[string]interface{}, err error) {
+	timer := m.client.timeOp(OpFind, m.collection, m.slowThreshold).counting(func() int { return len(found) })
+	defer timer.end(&err)
+	m = m.traced(context.Background(), timer)
+	listPath := m.client.apiPath(m.collection)
+	first, err := m.findPage(listPath)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	documents, err := m.client.collectPages(listPath, first, m.client.pagination, m.findPage)
+	if decodeErr := m.codecs.decodeAll(documents); decodeErr != nil {
+		return nil, decodeErr
+	}
+	for i, doc := range documents {
+		repaired, repairErr := m.repairDoc(doc)
+		if repairErr != nil {
+			return nil, repairErr
+		}
+		documents[i] = repaired
+	}
+	m.Query().sortDocuments(documents)
+	return documents, err
 }
 
-// Find finds all documents
-func (m *Model) Find() ([]map[string]interface{}, error) {
-	resp, err := m.client.request("GET", "/api/"+m.collection, nil)
+// findPage reads one page of the collection listing
+func (m *Model) findPage(path string) (listPage, error) {
+	var page listPage
+	resp, err := m.request("GET", path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("find failed: %w", err)
+		return page, fmt.Errorf("find failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := m.client.decodeResponse("list", resp, listShape, &result); err != nil {
+		return page, err
 	}
 
-	if docs, ok := result["documents"].([]interface{}); ok {
-		documents := make([]map[string]interface{}, len(docs))
-		for i, doc := range docs {
-			if docMap, ok := doc.(map[string]interface{}); ok {
-				documents[i] = docMap
-			}
+	docs, _ := result["documents"].([]interface{})
+	page.Documents = make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		if docMap, ok := doc.(map[string]interface{}); ok {
+			page.Documents = append(page.Documents, docMap)
 		}
-		return documents, nil
 	}
-
-	return []map[string]interface{}{}, nil
+	page.NextPage = result["next_page"]
+	return page, nil
 }
 
 // FindByID finds a document by ID
-func (m *Model) FindByID(id string) (map[string]interface{}, error) {
-	resp, err := m.client.request("GET", "/api/"+m.collection+"/"+id, nil)
+func (m *Model) FindByID(id string) (found map[string]interface{}, err error) {
+	timer := m.client.timeOp(OpFindByID, m.collection, m.slowThreshold).target(id)
+	defer timer.end(&err)
+	m = m.traced(context.Background(), timer)
+	resp, err := m.request("GET", m.client.apiPath(m.collection, id), nil)
 	if err != nil {
 		return nil, fmt.Errorf("find by ID failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, nil
+		return nil, notFound(m.collection, id)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("find by ID failed with status %d", resp.StatusCode)
+	if !isSuccess(resp.StatusCode) {
+		return nil, responseError("find by ID", resp)
 	}
 
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := m.client.decodeResponse("find", resp, documentShape, &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil
 	}
 
-	return result, nil
+	doc, err := m.codecs.decode(result)
+	if err != nil {
+		return nil, err
+	}
+	return m.repairDoc(doc)
+}
+
+// FindByIDs finds the documents with the given ids, querying up to 100
+// ids per request. Missing ids are absent from the result.
+func (m *Model) FindByIDs(ids []string) (map[string]map[string]interface{}, error) {
+	const batchSize = 100
+	found := make(map[string]map[string]interface{}, len(ids))
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := make([]interface{}, 0, end-start)
+		for _, id := range ids[start:end] {
+			batch = append(batch, id)
+		}
+		docs, err := m.Query().Filter("id", In, batch).Limit(len(batch)).Exec()
+		if err != nil {
+			return found, err
+		}
+		for _, doc := range docs {
+			if id, ok := doc["id"].(string); ok {
+				found[id] = doc
+			}
+		}
+	}
+	return found, nil
 }
 
 // Update updates a document by ID
 func (m *Model) Update(id string, data map[string]interface{}) (map[string]interface{}, error) {
-	if m.validate && m.schema != nil {
+	result, err := m.UpdateDetailed(id, data)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// UpdateDetailed updates a document by ID and reports the response status
+func (m *Model) UpdateDetailed(id string, data map[string]interface{}) (result *WriteResult, err error) {
+	timer := m.client.timeOp(OpUpdate, m.collection, m.slowThreshold).target(id)
+	defer timer.end(&err)
+	m = m.traced(context.Background(), timer)
+	data, stripped, err := m.sanitizer.strip(m.collection, data)
+	if err != nil {
+		return nil, err
+	}
+	if needsPrevious(m.slugs, data) {
+		previous, err := m.FindByID(id)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if previous == nil {
+			previous = map[string]interface{}{}
+		}
+		if err := applySlugs(m.slugs, m.Query, data, id, previous); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.validate {
 		if err := m.validateData(data, true); err != nil {
 			return nil, err
 		}
 	}
 
-	reqBody := map[string]interface{}{"data": data}
-	resp, err := m.client.request("PUT", "/api/"+m.collection+"/"+id, reqBody)
+	stored, err := m.codecs.encode(data)
+	if err != nil {
+		return nil, err
+	}
+	reqBody := map[string]interface{}{"data": stored}
+	resp, err := m.request("PUT", m.client.apiPath(m.collection, id), reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("update failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("update failed with status %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusConflict {
+		return nil, parseConflict(m.collection, responseError("update", resp)).redact(m.redactor(), data)
+	}
+
+	if !isSuccess(resp.StatusCode) {
+		return nil, responseError("update", resp).redact(m.redactor(), data)
 	}
 
+	result, err = m.decodeWrite("update", resp)
+	if err == nil {
+		restore(result.Data, stripped)
+		m.client.invalidate(m.collection, id, OpUpdate)
+	}
+	return result, err
+}
+
+// decodeWrite reads the document of a successful create or update
+func (m *Model) decodeWrite(operation string, resp *http.Response) (*WriteResult, error) {
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := m.client.decodeResponse(operation, resp, writeShape, &result); err != nil {
+		return nil, err
 	}
 
+	data := result
 	if resultData, ok := result["data"].(map[string]interface{}); ok {
-		return resultData, nil
+		decoded, err := m.codecs.decode(resultData)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+		// Some servers only return the id of a created document at the top level
+		if id := idString(result["id"]); id != "" && idString(data["id"]) == "" {
+			data["id"] = id
+		}
 	}
 
-	return result, nil
+	return m.writeResult(resp, data), nil
 }
 
-// Delete deletes a document by ID
+// Delete deletes a document by ID. Accepted (202) and empty (204) responses
+// count as success.
 func (m *Model) Delete(id string) (bool, error) {
-	resp, err := m.client.request("DELETE", "/api/"+m.collection+"/"+id, nil)
+	result, err := m.DeleteDetailed(id)
 	if err != nil {
-		return false, fmt.Errorf("delete failed: %w", err)
+		return false, err
+	}
+	if len(result.Data) == 0 {
+		return true, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("delete failed with status %d", resp.StatusCode)
+	success, _ := result.Data["success"].(bool)
+	return success, nil
+}
+
+// DeleteDetailed deletes a document by ID and reports the response status.
+// Data holds the response body.
+func (m *Model) DeleteDetailed(id string) (deleted *WriteResult, err error) {
+	timer := m.client.timeOp(OpDelete, m.collection, m.slowThreshold).target(id)
+	defer timer.end(&err)
+	m = m.traced(context.Background(), timer)
+	resp, err := m.request("DELETE", m.client.apiPath(m.collection, id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("delete failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return false, fmt.Errorf("failed to decode response: %w", err)
+	if !isSuccess(resp.StatusCode) {
+		return nil, responseError("delete", resp)
 	}
 
-	if success, ok := result["success"].(bool); ok {
-		return success, nil
+	var result map[string]interface{}
+	if err := m.client.decodeResponse("delete", resp, deleteShape, &result); err != nil {
+		return nil, err
 	}
+	m.client.invalidate(m.collection, id, OpDelete)
 
-	return false, nil
+	return m.writeResult(resp, result), nil
 }
 
-// Count counts all documents
+// Count counts all documents, soft-deleted ones included. Results are
+// shared as configured by WithMicroCache; see CountWith.
 func (m *Model) Count() (int, error) {
-	resp, err := m.client.request("GET", "/api/"+m.collection+"/count", nil)
+	return m.CountWith(context.Background(), CountOptions{IncludeDeleted: true})
+}
+
+func (m *Model) count() (int, error) {
+	resp, err := m.request("GET", m.client.apiPath(m.collection, "count"), nil)
 	if err != nil {
 		return 0, fmt.Errorf("count failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+	if err := m.client.decodeResponse("count", resp, countShape, &result); err != nil {
+		return 0, err
 	}
 
 	if count, ok := result["count"].(float64); ok {
@@ -173,9 +366,25 @@ func (m *Model) Count() (int, error) {
 
 // Query creates a new query builder
 func (m *Model) Query() *QueryBuilder {
-	return &QueryBuilder{
+	qb := &QueryBuilder{
 		client:     m.client,
 		collection: m.collection,
 		filters:    []QueryFilter{},
+		decimals:   decimalFields(m.schema),
+		codecs:     m.codecs,
+		repair:     m.repairDoc,
+
+		defaultOrder:  m.defaultOrder,
+		sortDefaults:  m.sortDefaults,
+		textFields:    textFields(m.schema),
+		slowThreshold: m.slowThreshold,
+		redacted:      m.redactor().list(),
+		trace:         m.trace,
+		parity:        m.parity,
+		priority:      m.priority,
+	}
+	if m.autoCoerce {
+		qb.coercions = numericFields(m.schema)
 	}
+	return qb
 }
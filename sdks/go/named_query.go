@@ -0,0 +1,80 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrQueryExists is returned when a named query is defined twice
+	ErrQueryExists = errors.New("torm: named query already defined")
+	// ErrUnknownQuery is returned for names that were never defined
+	ErrUnknownQuery = errors.New("torm: unknown named query")
+)
+
+// QueryPreset applies a reusable set of filters, sorting or paging to a builder
+type QueryPreset func(qb *QueryBuilder) *QueryBuilder
+
+// namedQueries is the per-collection registry of presets
+type namedQueries struct {
+	mu      sync.RWMutex
+	presets map[string]QueryPreset
+}
+
+// DefineQuery registers a preset under a name, e.g. "activeAdults"
+func (c *Collection[T]) DefineQuery(name string, preset QueryPreset) error {
+	if name == "" || preset == nil {
+		return fmt.Errorf("named query needs a name and a preset")
+	}
+
+	q := &c.options.queries
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, exists := q.presets[name]; exists {
+		return fmt.Errorf("%w: %s on %s", ErrQueryExists, name, c.collection)
+	}
+	if q.presets == nil {
+		q.presets = make(map[string]QueryPreset)
+	}
+	q.presets[name] = preset
+	return nil
+}
+
+// NamedQuery returns a fresh builder with the named preset applied. More
+// filters can be added before Exec.
+func (c *Collection[T]) NamedQuery(name string) (*QueryBuilder, error) {
+	q := &c.options.queries
+	q.mu.RLock()
+	preset, ok := q.presets[name]
+	q.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on %s", ErrUnknownQuery, name, c.collection)
+	}
+
+	qb := c.model().Query()
+	var applied *QueryBuilder
+	if err := protect("query preset "+name, c.collection, "", func() { applied = preset(qb) }); err != nil {
+		return nil, err
+	}
+	if applied != nil {
+		qb = applied
+	}
+	return qb, nil
+}
+
+// NamedQueries lists the defined query names in order
+func (c *Collection[T]) NamedQueries() []string {
+	q := &c.options.queries
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	names := make([]string, 0, len(q.presets))
+	for name := range q.presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,80 @@
+package torm
+
+import (
+	"errors"
+	"strings"
+)
+
+// OpError names the operation an error came from and what it acted on,
+// e.g. "torm: users.FindByID id=user:42: document not found". Errors of
+// collection, model and query operations, keys and migrations carry one
+// unless ClientOptions.LegacyErrorStrings is set; find it with errors.As.
+type OpError struct {
+	Op         string // The method, such as "FindByID" or "SetKey"
+	Collection string // Empty for keys and migrations
+	ID         string // The document id, key or migration, when there is one
+	// Query is the query as QueryBuilder.String shows it, for queries;
+	// their messages already end with it
+	Query string
+	Err   error
+}
+
+func (e *OpError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "torm: ")
+	if e.Op == "" {
+		// Only the target is known, as for a document not found
+		return "torm: " + msg + ": " + e.Collection + "/" + e.ID
+	}
+
+	var b strings.Builder
+	b.WriteString("torm: ")
+	if e.Collection != "" {
+		b.WriteString(e.Collection)
+		b.WriteString(".")
+	}
+	b.WriteString(e.Op)
+	if e.ID != "" {
+		b.WriteString(" id=")
+		b.WriteString(e.ID)
+	}
+	b.WriteString(": ")
+	b.WriteString(msg)
+	return b.String()
+}
+
+// Unwrap returns the underlying error
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// wrapOp wraps err in target, unless err is nil, the client keeps legacy
+// error strings or err already names the same operation. An error that
+// names only its target, as notFound's do, takes on the operation instead
+// of being wrapped again, and so does one returned as is by an operation
+// the method ran on the same collection, such as the Query behind Lookup.
+func (c *Client) wrapOp(err error, target OpError) error {
+	if err == nil || c.legacyErrorStrings {
+		return err
+	}
+	var inner *OpError
+	if errors.As(err, &inner) && inner.Collection == target.Collection {
+		switch {
+		case inner.Op == "":
+			inner.Op, inner.Query = target.Op, target.Query
+			return err
+		case inner.Op == target.Op:
+			return err
+		case inner == err:
+			if target.ID == "" {
+				target.ID = inner.ID
+			}
+			if target.Query == "" {
+				target.Query = inner.Query
+			}
+			target.Err = inner.Err
+			return &target
+		}
+	}
+	target.Err = err
+	return &target
+}
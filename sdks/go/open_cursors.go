@@ -0,0 +1,351 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCursorIdle is returned by an iterator or channel the watchdog closed
+// because it went unused for ClientOptions.CursorIdleTimeout
+var ErrCursorIdle = errors.New("torm: cursor closed after idle timeout")
+
+const (
+	defaultCursorIdleTimeout = 5 * time.Minute
+	defaultCursorCheck       = time.Second
+)
+
+// CursorKind tells iterators from channel producers
+type CursorKind string
+
+const (
+	CursorIterator CursorKind = "iterator" // See QueryBuilder.Iter
+	CursorChannel  CursorKind = "channel"  // See QueryBuilder.ExecChan
+)
+
+// CursorInfo describes an open iterator or channel producer
+type CursorInfo struct {
+	ID         int64
+	Kind       CursorKind
+	Collection string
+	Query      string // As QueryBuilder.String shows it
+	Created    time.Time
+	LastActive time.Time
+	Stack      string // Where it was created, with ClientOptions.Debug
+}
+
+// OpenCursors returns the iterators and channel producers still open, in
+// the order they were created, e.g. for a debug endpoint
+func (c *Client) OpenCursors() []CursorInfo {
+	c.cursors.mu.Lock()
+	defer c.cursors.mu.Unlock()
+
+	open := make([]CursorInfo, 0, len(c.cursors.open))
+	for _, cursor := range c.cursors.open {
+		open = append(open, cursor.info)
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].ID < open[j].ID })
+	return open
+}
+
+// cursorRegistry tracks the open cursors of a client. A watchdog goroutine
+// runs while any is open and closes those idle for longer than the timeout.
+type cursorRegistry struct {
+	mu       sync.Mutex
+	nextID   int64
+	open     map[int64]*trackedCursor
+	watching bool
+}
+
+type trackedCursor struct {
+	info CursorInfo   // Guarded by the registry's mutex
+	stop func() error // Closes the cursor with ErrCursorIdle
+}
+
+// openCursor registers a cursor; stop is called if it goes idle
+func (c *Client) openCursor(kind CursorKind, qb *QueryBuilder, stop func() error) *trackedCursor {
+	now := c.now()
+	cursor := &trackedCursor{
+		info: CursorInfo{Kind: kind, Collection: qb.collection, Query: qb.String(), Created: now, LastActive: now},
+		stop: stop,
+	}
+	if c.debug {
+		cursor.info.Stack = string(debug.Stack())
+	}
+
+	r := &c.cursors
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	cursor.info.ID = r.nextID
+	if r.open == nil {
+		r.open = make(map[int64]*trackedCursor)
+	}
+	r.open[cursor.info.ID] = cursor
+	if !r.watching {
+		r.watching = true
+		go c.watchCursors()
+	}
+	return cursor
+}
+
+// touch records that the cursor was used
+func (c *Client) touchCursor(cursor *trackedCursor) {
+	c.cursors.mu.Lock()
+	defer c.cursors.mu.Unlock()
+	cursor.info.LastActive = c.now()
+}
+
+// closeCursor unregisters the cursor
+func (c *Client) closeCursor(cursor *trackedCursor) {
+	c.cursors.mu.Lock()
+	defer c.cursors.mu.Unlock()
+	delete(c.cursors.open, cursor.info.ID)
+}
+
+// watchCursors closes idle cursors until none is left open
+func (c *Client) watchCursors() {
+	ticker := time.NewTicker(c.cursorCheck)
+	defer ticker.Stop()
+	for range ticker.C {
+		r := &c.cursors
+		r.mu.Lock()
+		if len(r.open) == 0 {
+			r.watching = false
+			r.mu.Unlock()
+			return
+		}
+		now := c.now()
+		var idle []*trackedCursor
+		for _, cursor := range r.open {
+			if now.Sub(cursor.info.LastActive) >= c.cursorIdle {
+				idle = append(idle, cursor)
+			}
+		}
+		r.mu.Unlock()
+
+		for _, cursor := range idle {
+			c.reapCursor(cursor, now)
+		}
+	}
+}
+
+// reapCursor closes an idle cursor and reports it as leaked
+func (c *Client) reapCursor(cursor *trackedCursor, now time.Time) {
+	protect("idle cursor close", cursor.info.Collection, "", func() { cursor.stop() })
+	c.closeCursor(cursor)
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("kind", string(cursor.info.Kind)),
+		slog.String("collection", cursor.info.Collection),
+		slog.String("query", cursor.info.Query),
+		slog.Duration("idle", now.Sub(cursor.info.LastActive)),
+		slog.Time("created", cursor.info.Created),
+	}
+	if cursor.info.Stack != "" {
+		attrs = append(attrs, slog.String("stack", cursor.info.Stack))
+	}
+	c.logger.LogAttrs(context.Background(), slog.LevelWarn, "torm: cursor leaked, closed after idle timeout", attrs...)
+}
+
+// iterPageSize is the number of documents All and Iter read per request
+const iterPageSize = 500
+
+// QueryIterator reads the results of a query a page at a time. It is open
+// until Next returns false or Close is called; see QueryBuilder.Iter.
+type QueryIterator struct {
+	qb      *QueryBuilder
+	ctx     context.Context
+	tracked *trackedCursor
+
+	mu        sync.Mutex
+	page      []map[string]interface{}
+	doc       map[string]interface{}
+	skip      int
+	remaining int // -1 when unlimited
+	last      bool
+	err       error
+	closed    bool
+}
+
+// Iter returns an iterator over the query's results, read a page at a
+// time. Close it when stopping early: an iterator left unused for
+// ClientOptions.CursorIdleTimeout is closed with ErrCursorIdle and logged
+// as leaked.
+//
+//	it := users.Query().Iter(ctx)
+//	defer it.Close()
+//	for it.Next() {
+//		doc := it.Doc()
+//		...
+//	}
+//	return it.Err()
+func (qb *QueryBuilder) Iter(ctx context.Context) *QueryIterator {
+	it := &QueryIterator{qb: qb, ctx: ctx, remaining: -1}
+	if qb.skipVal != nil {
+		it.skip = *qb.skipVal
+	}
+	if qb.limitVal != nil {
+		it.remaining = *qb.limitVal
+	}
+	it.tracked = qb.client.openCursor(CursorIterator, qb, func() error {
+		it.mu.Lock()
+		defer it.mu.Unlock()
+		it.release(ErrCursorIdle)
+		return nil
+	})
+	return it
+}
+
+// Next advances to the next document, reading a page when needed. It
+// returns false at the end of the results or on an error; see Err.
+func (it *QueryIterator) Next() bool {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.closed {
+		return false
+	}
+	it.qb.client.touchCursor(it.tracked)
+
+	if len(it.page) == 0 && !it.last {
+		if err := it.fetch(); err != nil {
+			it.release(err)
+			return false
+		}
+	}
+	if len(it.page) == 0 {
+		it.release(nil)
+		return false
+	}
+	it.doc, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *QueryIterator) fetch() error {
+	if err := it.ctx.Err(); err != nil {
+		return err
+	}
+	size := iterPageSize
+	if it.remaining == 0 {
+		it.last = true
+		return nil
+	}
+	if it.remaining > 0 && it.remaining < size {
+		size = it.remaining
+	}
+	docs, err := it.qb.pageAt(it.skip, size).Exec()
+	if err != nil {
+		return err
+	}
+	it.page = docs
+	it.skip += size
+	if it.remaining > 0 {
+		it.remaining -= size
+	}
+	it.last = len(docs) < size
+	return nil
+}
+
+// Doc returns the current document
+func (it *QueryIterator) Doc() map[string]interface{} {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.doc
+}
+
+// Err returns the error that ended the iteration, if any
+func (it *QueryIterator) Err() error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.err
+}
+
+// Close releases the iterator. It may be called any number of times.
+func (it *QueryIterator) Close() error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	it.release(nil)
+	return nil
+}
+
+// release closes the iterator with err; later calls have no effect
+func (it *QueryIterator) release(err error) {
+	if it.closed {
+		return
+	}
+	it.closed = true
+	it.err = err
+	it.page, it.doc = nil, nil
+	it.qb.client.closeCursor(it.tracked)
+}
+
+// ExecChan streams the query's results over a channel, read a page at a
+// time by a goroutine. The documents channel is closed at the end; the
+// error channel then yields the error that ended the stream, if any, and
+// is closed. A consumer that stops reading should cancel ctx; a producer
+// blocked for ClientOptions.CursorIdleTimeout stops with ErrCursorIdle and
+// is logged as leaked.
+func (qb *QueryBuilder) ExecChan(ctx context.Context) (<-chan map[string]interface{}, <-chan error) {
+	docs := make(chan map[string]interface{})
+	errs := make(chan error, 1)
+	stopped := make(chan struct{})
+	var once sync.Once
+	tracked := qb.client.openCursor(CursorChannel, qb, func() error {
+		once.Do(func() { close(stopped) })
+		return nil
+	})
+
+	go func() {
+		defer close(errs)
+		defer close(docs)
+		defer qb.client.closeCursor(tracked)
+
+		it := &QueryIterator{qb: qb, ctx: ctx, remaining: -1}
+		if qb.skipVal != nil {
+			it.skip = *qb.skipVal
+		}
+		if qb.limitVal != nil {
+			it.remaining = *qb.limitVal
+		}
+		for {
+			if len(it.page) == 0 && !it.last {
+				if err := it.fetch(); err != nil {
+					errs <- err
+					return
+				}
+			}
+			if len(it.page) == 0 {
+				return
+			}
+			select {
+			case docs <- it.page[0]:
+				it.page = it.page[1:]
+				qb.client.touchCursor(tracked)
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			case <-stopped:
+				errs <- ErrCursorIdle
+				return
+			}
+		}
+	}()
+	return docs, errs
+}
+
+// pageAt returns a copy of the query reading size documents from offset
+func (qb *QueryBuilder) pageAt(offset, size int) *QueryBuilder {
+	page := *qb
+	page.filters = append([]QueryFilter(nil), qb.filters...)
+	if page.sortField == nil {
+		page.sortField = &QuerySort{Field: "id", Order: Asc}
+	}
+	page.skipVal, page.limitVal = &offset, &size
+	return &page
+}
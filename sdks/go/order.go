@@ -0,0 +1,94 @@
+package torm
+
+// idOrder is the default order when none is configured
+var idOrder = QuerySort{Field: "id", Order: Asc}
+
+// WithDefaultOrder sets the sort key appended to every query of the
+// collection, overriding ClientOptions.DefaultOrder
+func WithDefaultOrder(field string, order SortOrder) CollectionOption {
+	return func(o *collectionOptions) {
+		o.defaultOrder = &QuerySort{Field: field, Order: order}
+		o.serverOrder = false
+	}
+}
+
+// WithServerOrder leaves the collection's results in the order the server
+// returns them, with no default order key
+func WithServerOrder() CollectionOption {
+	return func(o *collectionOptions) {
+		o.defaultOrder = nil
+		o.serverOrder = true
+	}
+}
+
+// WithDefaultOrder sets the sort key appended to every query of the model,
+// overriding ClientOptions.DefaultOrder
+func (m *Model) WithDefaultOrder(field string, order SortOrder) *Model {
+	m.defaultOrder = &QuerySort{Field: field, Order: order}
+	return m
+}
+
+// WithServerOrder leaves the model's results in the order the server
+// returns them, with no default order key
+func (m *Model) WithServerOrder() *Model {
+	m.defaultOrder = nil
+	return m
+}
+
+// clientDefaultOrder resolves the client's default order, nil when results
+// keep server order
+func clientDefaultOrder(opts *ClientOptions) *QuerySort {
+	switch {
+	case opts.ServerOrder:
+		return nil
+	case opts.DefaultOrder != nil:
+		order := *opts.DefaultOrder
+		return &order
+	}
+	order := idOrder
+	return &order
+}
+
+// applyOrder sets a model's default order from the collection options
+func (o *collectionOptions) applyOrder(m *Model) {
+	switch {
+	case o.serverOrder:
+		m.defaultOrder = nil
+	case o.defaultOrder != nil:
+		m.defaultOrder = o.defaultOrder
+	}
+}
+
+// sortKeys lists the keys results are sorted by, with their options
+// resolved: the query's sort, then the default order unless it sorts by
+// the same field
+func (qb *QueryBuilder) sortKeys() []QuerySort {
+	keys := make([]QuerySort, 0, 2)
+	if qb.sortField != nil {
+		keys = append(keys, qb.resolveSort(*qb.sortField))
+	}
+	if qb.sortField == nil || qb.tieBreaker() != nil {
+		if qb.defaultOrder != nil {
+			keys = append(keys, qb.resolveSort(*qb.defaultOrder))
+		}
+	}
+	return keys
+}
+
+// tieBreaker returns the default order key appended after an explicit
+// sort, nil when there is none
+func (qb *QueryBuilder) tieBreaker() *QuerySort {
+	if qb.sortField == nil || qb.defaultOrder == nil || qb.defaultOrder.Field == qb.sortField.Field {
+		return nil
+	}
+	return qb.defaultOrder
+}
+
+// injectedOrder returns the default order key the query adds, nil when
+// its own sort already covers it
+func (qb *QueryBuilder) injectedOrder() *QuerySort {
+	if qb.sortField == nil {
+		return qb.defaultOrder
+	}
+	return qb.tieBreaker()
+}
@@ -0,0 +1,96 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrTruncatedResult is returned with the documents read so far when a
+// paginated listing has pages the client did not fetch
+var ErrTruncatedResult = errors.New("torm: result truncated")
+
+// PaginationOptions controls how Find follows servers that split listings
+// into pages ({documents, count, next_page})
+type PaginationOptions struct {
+	Disabled bool // Return the first page with ErrTruncatedResult instead of following next_page
+	MaxPages int  // Pages fetched before giving up with ErrTruncatedResult (default 100)
+}
+
+// WithPagination sets how the collection's Find follows paginated listings,
+// overriding ClientOptions.Pagination
+func WithPagination(opts PaginationOptions) CollectionOption {
+	return func(o *collectionOptions) {
+		o.pagination = &opts
+	}
+}
+
+// listPage is one page of a listing
+type listPage struct {
+	Documents []map[string]interface{} `json:"documents"`
+	NextPage  interface{}              `json:"next_page"`
+}
+
+// collectPages returns the documents of first and of every page after it.
+// fetch loads a page from a path relative to BaseURL.
+func (c *Client) collectPages(listPath string, first listPage, opts PaginationOptions, fetch func(path string) (listPage, error)) ([]map[string]interface{}, error) {
+	maxPages := opts.MaxPages
+	if maxPages <= 0 {
+		maxPages = 100
+	}
+
+	docs := first.Documents
+	next := first.NextPage
+	for pages := 1; ; pages++ {
+		path, more, err := c.nextPagePath(listPath, next)
+		if err != nil || !more {
+			return docs, err
+		}
+		if opts.Disabled {
+			return docs, fmt.Errorf("%w: more pages after the first", ErrTruncatedResult)
+		}
+		if pages >= maxPages {
+			return docs, fmt.Errorf("%w: stopped after %d pages", ErrTruncatedResult, pages)
+		}
+
+		page, err := fetch(path)
+		if err != nil {
+			return docs, fmt.Errorf("fetch page %d: %w", pages+1, err)
+		}
+		docs = append(docs, page.Documents...)
+		next = page.NextPage
+	}
+}
+
+// nextPagePath resolves a next_page value: a page number or token is sent as
+// ?page= on the listing path, and a path or URL on this server is followed
+// as given. The boolean is false on the last page.
+func (c *Client) nextPagePath(listPath string, next interface{}) (string, bool, error) {
+	switch v := next.(type) {
+	case float64:
+		return listPath + "?page=" + strconv.FormatFloat(v, 'f', -1, 64), true, nil
+	case string:
+		switch {
+		case v == "":
+			return "", false, nil
+		case strings.HasPrefix(v, c.BaseURL+"/"):
+			return strings.TrimPrefix(v, c.BaseURL), true, nil
+		case strings.HasPrefix(v, "/"):
+			return v, true, nil
+		case strings.Contains(v, "://"):
+			return "", false, fmt.Errorf("%w: next page %s is on another host", ErrTruncatedResult, v)
+		}
+		return listPath + "?page=" + url.QueryEscape(v), true, nil
+	}
+	return "", false, nil
+}
+
+// pagination returns the collection's pagination settings
+func (c *Collection[T]) pagination() PaginationOptions {
+	if c.options.pagination != nil {
+		return *c.options.pagination
+	}
+	return c.client.pagination
+}
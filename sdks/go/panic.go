@@ -0,0 +1,59 @@
+package torm
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned when a callback supplied to the SDK panics: a
+// guard, validator, codec, read repair, ForEach function, query preset or
+// migration. The operation fails with this error instead of crashing the
+// goroutine, and the client stays usable. Panics in the SDK itself are not
+// recovered.
+type PanicError struct {
+	Op         string      // The callback that panicked, e.g. "guard" or "migration up"
+	Collection string      // Empty when the callback is not tied to a collection
+	ID         string      // Document id, or migration id for migrations; may be empty
+	Value      interface{} // The value passed to panic
+	Stack      []byte      // Stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	target := e.Collection
+	if e.ID != "" {
+		if target != "" {
+			target += "/"
+		}
+		target += e.ID
+	}
+	if target != "" {
+		return fmt.Sprintf("panic in %s of %s: %v", e.Op, target, e.Value)
+	}
+	return fmt.Sprintf("panic in %s: %v", e.Op, e.Value)
+}
+
+// Unwrap returns the panic value when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// protect calls a callback supplied to the SDK, returning a panic in it as a
+// *PanicError. Only the callback itself may run inside fn.
+func protect(op, collection, id string, fn func()) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &PanicError{Op: op, Collection: collection, ID: id, Value: value, Stack: debug.Stack()}
+		}
+	}()
+	fn()
+	return nil
+}
+
+// docID returns a document's id for diagnostics
+func docID(doc map[string]interface{}) string {
+	if doc == nil || doc["id"] == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", doc["id"])
+}
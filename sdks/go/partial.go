@@ -0,0 +1,66 @@
+package torm
+
+import (
+	"fmt"
+)
+
+// PartialResult splits query results into documents that decode into T and
+// those that do not. Both keep their position in the results, so callers
+// can restore the original order.
+type PartialResult[T any] struct {
+	Typed      []T
+	TypedIndex []int // Position of each Typed document in the results
+
+	// Untyped holds the documents that failed to decode, as read. Errors
+	// has one entry per Untyped document, in the same order.
+	Untyped []map[string]interface{}
+	Errors  []HydrationError
+}
+
+// Len returns the number of documents the query returned
+func (r *PartialResult[T]) Len() int {
+	return len(r.Typed) + len(r.Untyped)
+}
+
+// HydrationError reports a document that does not decode into the model
+type HydrationError struct {
+	Index int    // Position of the document in the results
+	ID    string // Document id, when it has one
+	Err   error
+}
+
+func (e *HydrationError) Error() string {
+	if e.ID != "" {
+		return fmt.Sprintf("document %d (%s) does not fit the model: %v", e.Index, e.ID, e.Err)
+	}
+	return fmt.Sprintf("document %d does not fit the model: %v", e.Index, e.Err)
+}
+
+func (e *HydrationError) Unwrap() error {
+	return e.Err
+}
+
+// ExecPartial executes the query and decodes each document into T, keeping
+// the documents that do not fit instead of dropping them or failing the
+// query. Codecs and read repair are applied before documents are decoded.
+// Only a failed query returns an error.
+func ExecPartial[T any](qb *QueryBuilder) (*PartialResult[T], error) {
+	docs, err := qb.Exec()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PartialResult[T]{}
+	for i, doc := range docs {
+		var model T
+		if err := hydrate(doc, &model); err != nil {
+			id, _ := doc["id"].(string)
+			result.Untyped = append(result.Untyped, doc)
+			result.Errors = append(result.Errors, HydrationError{Index: i, ID: id, Err: err})
+			continue
+		}
+		result.Typed = append(result.Typed, model)
+		result.TypedIndex = append(result.TypedIndex, i)
+	}
+	return result, nil
+}
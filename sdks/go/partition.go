@@ -0,0 +1,262 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Partition is a range of a collection's keyspace. Ranges are half-open, so
+// a document on a boundary belongs to exactly one partition.
+type Partition struct {
+	Index int         `json:"index"`
+	Field string      `json:"field"`
+	From  interface{} `json:"from,omitempty"` // Inclusive lower bound; nil for the first partition
+	To    interface{} `json:"to,omitempty"`   // Exclusive upper bound; nil for the last partition
+}
+
+// Filters returns the query filters selecting the partition's documents
+func (p *Partition) Filters() []QueryFilter {
+	var filters []QueryFilter
+	if p.From != nil {
+		filters = append(filters, QueryFilter{Field: p.Field, Operator: Gte, Value: p.From})
+	}
+	if p.To != nil {
+		filters = append(filters, QueryFilter{Field: p.Field, Operator: Lt, Value: p.To})
+	}
+	return filters
+}
+
+// Contains reports whether a document falls in the partition
+func (p *Partition) Contains(doc map[string]interface{}) bool {
+	value, ok := doc[p.Field]
+	if !ok || value == nil {
+		return false
+	}
+	qb := &QueryBuilder{}
+	if p.From != nil && qb.compareValues(value, p.From) < 0 {
+		return false
+	}
+	return p.To == nil || qb.compareValues(value, p.To) < 0
+}
+
+func (p *Partition) String() string {
+	return fmt.Sprintf("partition %d of %s [%v, %v)", p.Index, p.Field, p.From, p.To)
+}
+
+// Partitions splits the collection into n ranges of document ids of roughly
+// equal size, for exporting or iterating over them in parallel. See
+// PartitionsBy.
+func (m *Model) Partitions(n int) ([]Partition, error) {
+	return m.PartitionsBy("id", n)
+}
+
+// PartitionsBy splits the collection into n ranges of a sortable field. The
+// boundaries are the field's values at evenly spaced positions in sort
+// order, read with one query each, along with the smallest value. Fewer partitions are returned when the
+// collection is small or many documents share a value. Every document must
+// have the field, or it lands in no partition.
+func (m *Model) PartitionsBy(field string, n int) ([]Partition, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("partition count must be positive, got %d", n)
+	}
+
+	total, err := m.Count()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count documents to partition: %w", err)
+	}
+
+	// A boundary at the smallest value would open an empty first partition
+	var bounds []interface{}
+	var last interface{}
+	qb := &QueryBuilder{}
+	for i := 0; i < n; i++ {
+		offset := i * total / n
+		if i > 0 && offset == 0 {
+			continue
+		}
+		docs, err := m.Query().Select(field).Sort(field, Asc).Skip(offset).Limit(1).Exec()
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample partition boundary %d: %w", i, err)
+		}
+		if len(docs) == 0 || docs[0][field] == nil {
+			continue
+		}
+		bound := docs[0][field]
+		if last != nil && qb.compareValues(bound, last) <= 0 {
+			continue
+		}
+		if last != nil {
+			bounds = append(bounds, bound)
+		}
+		last = bound
+	}
+
+	partitions := make([]Partition, 0, len(bounds)+1)
+	var from interface{}
+	for i, bound := range bounds {
+		partitions = append(partitions, Partition{Index: i, Field: field, From: from, To: bound})
+		from = bound
+	}
+	return append(partitions, Partition{Index: len(bounds), Field: field, From: from}), nil
+}
+
+// Partitions splits the collection into n ranges of document ids; see
+// Model.PartitionsBy
+func (c *Collection[T]) Partitions(n int) ([]Partition, error) {
+	return c.model().Partitions(n)
+}
+
+// ForEach calls fn with every document of the partition in id order, a
+// page at a time. A nil partition covers the whole collection. It stops at
+// the first error fn returns.
+func (m *Model) ForEach(partition *Partition, fn func(doc map[string]interface{}) error) error {
+	const pageSize = 500
+
+	lastID := ""
+	for {
+		qb := m.Query().Sort("id", Asc).Limit(pageSize)
+		if partition != nil {
+			qb.Match(partition.Filters()...)
+		}
+		if lastID != "" {
+			qb.Filter("id", Gt, lastID)
+		}
+
+		docs, err := qb.Exec()
+		if err != nil {
+			return fmt.Errorf("page after %q failed: %w", lastID, err)
+		}
+		for _, doc := range docs {
+			var err error
+			if panicked := protect("for each", m.collection, docID(doc), func() { err = fn(doc) }); panicked != nil {
+				return panicked
+			}
+			if err != nil {
+				return err
+			}
+			lastID = fmt.Sprintf("%v", doc["id"])
+		}
+		if len(docs) < pageSize {
+			return nil
+		}
+	}
+}
+
+// ImportOptions configures a snapshot import
+type ImportOptions struct {
+	Partition *Partition // Import only the documents in this partition
+	Skip      int        // Lines to skip, e.g. ImportResult.Lines of an interrupted run
+
+	Format  ImportFormat // Source encoding (default ImportJSONLines)
+	Mapping *MappingSpec // Reshapes each record before it is validated and written
+	// DryRun maps and validates the first SampleSize records without
+	// writing anything, reporting each in ImportResult.Samples
+	DryRun     bool
+	SampleSize int // Records processed by a dry run (default 10)
+}
+
+// ImportResult reports the progress of a snapshot import
+type ImportResult struct {
+	Lines    int            // Lines read, including skipped ones; resume with Skip set to this
+	Imported int            // Documents written
+	Skipped  int            // Documents outside the partition
+	Samples  []ImportSample // Records processed by a dry run
+	Chunks   int            // Chunk trailers verified; see ExportOptions.ChunkSize
+	// DeadLettered counts documents that failed to write and were captured
+	// by the model's dead-letter sink; see Model.WithDeadLetter
+	DeadLettered int
+}
+
+// ImportSnapshot writes the documents of a JSON lines snapshot, as produced
+// by ExportSnapshot, to the collection. Documents that already exist are
+// overwritten. Several importers can share one snapshot by each taking a
+// partition. Chunk trailers are verified as they are read; a chunk that
+// does not match fails the import with a ChunkError. Other sources are
+// read with opts.Format and reshaped with
+// opts.Mapping; the import stops at the first record that fails, with an
+// error naming its line, unless a dead-letter sink captures documents that
+// fail to write (see Model.WithDeadLetter).
+func (m *Model) ImportSnapshot(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	result := &ImportResult{}
+	records, err := newRecordReader(r, opts.Format)
+	if err != nil {
+		return result, err
+	}
+	if opts.DryRun {
+		return m.dryRunImport(records, opts, result)
+	}
+
+	for {
+		record, err := records.next()
+		result.Lines = records.lines()
+		result.Chunks = records.chunks()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		if record.line <= opts.Skip {
+			continue
+		}
+
+		doc, err := m.importForm(record.data, opts.Mapping)
+		if err != nil {
+			return result, fmt.Errorf("failed to map line %d: %w", record.line, err)
+		}
+		if opts.Partition != nil && !opts.Partition.Contains(doc) {
+			result.Skipped++
+			continue
+		}
+
+		if err := m.importDocument(doc); err != nil {
+			err = fmt.Errorf("failed to import line %d: %w", record.line, err)
+			entry := newDeadLetter(m.collection, OpCreate, doc, docID(doc), err, m.client.now()).redacted(m.redactor(), m.deadLetter)
+			if captured, err := captureDeadLetter(m.deadLetter, entry, err); !captured {
+				return result, err
+			}
+			result.DeadLettered++
+			continue
+		}
+		result.Imported++
+	}
+}
+
+// importForm maps a record and decodes values exported in the stored form
+// of a field codec, which the write encodes again
+func (m *Model) importForm(record map[string]interface{}, mapping *MappingSpec) (map[string]interface{}, error) {
+	doc, err := mapping.apply(record)
+	if err != nil {
+		return nil, err
+	}
+	if fields := m.codecs.fieldCodecs(); fields != nil {
+		return fields.decode(doc, false)
+	}
+	return doc, nil
+}
+
+// importDocument creates a document, replacing it when it already exists
+func (m *Model) importDocument(doc map[string]interface{}) error {
+	_, err := m.Create(doc)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		return err
+	}
+
+	id, _ := doc["id"].(string)
+	if id == "" {
+		return err
+	}
+	_, err = m.Update(id, doc)
+	return err
+}
+
+// samePartition reports whether two partitions cover the same range. Bounds
+// are compared by their text, since checkpoints decode numbers as float64.
+func samePartition(a, b *Partition) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Field == b.Field && fmt.Sprint(a.From) == fmt.Sprint(b.From) && fmt.Sprint(a.To) == fmt.Sprint(b.To)
+}
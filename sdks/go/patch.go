@@ -0,0 +1,189 @@
+package torm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PatchConflictError is returned by Patch when the document changed after
+// the base it patches was read
+type PatchConflictError struct {
+	Collection string
+	ID         string
+	Changed    []string // Fields changed since the base, sorted
+	// Collided lists the patched fields among Changed. It is empty when
+	// only other fields changed and auto-merging is off.
+	Collided []string
+	Current  map[string]interface{} // The document as last read
+}
+
+func (e *PatchConflictError) Error() string {
+	if len(e.Collided) == 0 {
+		return fmt.Sprintf("patch of %s in collection %s conflicted: changed since read: %s",
+			e.ID, e.Collection, strings.Join(e.Changed, ", "))
+	}
+	return fmt.Sprintf("patch of %s in collection %s conflicted on %s",
+		e.ID, e.Collection, strings.Join(e.Collided, ", "))
+}
+
+// Unwrap makes errors.Is(err, ErrVersionConflict) match
+func (e *PatchConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// WithAutoMergePatches lets Patch apply a patch over concurrent changes to
+// other fields instead of failing. A write that loses the race to yet
+// another writer is tried again, up to attempts times in all (default 5).
+func WithAutoMergePatches(attempts int) CollectionOption {
+	return func(o *collectionOptions) {
+		if attempts <= 0 {
+			attempts = 5
+		}
+		o.autoMerge = attempts
+	}
+}
+
+// Patch sets fields on the document id, which the caller read as base,
+// and returns the patched document. The write is versioned like
+// UpdateWithRetry's: if the stored document no longer matches base, Patch
+// fails with a *PatchConflictError listing the changed fields. With
+// WithAutoMergePatches, changes confined to fields the patch does not
+// touch are kept and the patch is applied on top of them; only changes to
+// patched fields still conflict.
+func (c *Collection[T]) Patch(ctx context.Context, id string, base T, fields map[string]interface{}) (patched T, err error) {
+	tracked := c.timeOp(OpUpdate).target(id).as("Patch")
+	defer tracked.end(&err)
+	c = c.traced(ctx, tracked)
+	var zero T
+	if _, ok := fields["id"]; ok {
+		return zero, fmt.Errorf("patch cannot change the id of %s", id)
+	}
+
+	fields, _, err = c.options.sanitizer.strip(c.collection, fields)
+	if err != nil {
+		return zero, err
+	}
+	baseDoc, _, err := c.options.sanitizer.strip(c.collection, base.ToMap())
+	if err != nil {
+		return zero, err
+	}
+	attempts := 1
+	if c.options.autoMerge > 0 {
+		attempts = c.options.autoMerge
+	}
+
+	var current *versionedDoc
+	for attempt := 1; attempt <= attempts; attempt++ {
+		tracked.op.Attempts = attempt
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+
+		current, err = c.readVersion(id)
+		if err != nil {
+			return zero, err
+		}
+		if err := c.options.checkGuard(OpFindByID, current.doc); err != nil {
+			return zero, err
+		}
+
+		// Compared in the model's form, the only one base has
+		seen := c.factory()
+		if err := hydrate(current.doc, &seen); err != nil {
+			return zero, err
+		}
+		seenDoc, _, err := c.options.sanitizer.strip(c.collection, seen.ToMap())
+		if err != nil {
+			return zero, err
+		}
+		if changed := changedFields(baseDoc, seenDoc); len(changed) > 0 {
+			collided := make([]string, 0)
+			for _, field := range changed {
+				if _, ok := fields[field]; ok {
+					collided = append(collided, field)
+				}
+			}
+			if c.options.autoMerge == 0 || len(collided) > 0 {
+				return zero, &PatchConflictError{
+					Collection: c.collection,
+					ID:         id,
+					Changed:    changed,
+					Collided:   collided,
+					Current:    current.doc,
+				}
+			}
+		}
+
+		doc := make(map[string]interface{}, len(current.doc)+len(fields))
+		for field, value := range current.doc {
+			doc[field] = value
+		}
+		for field, value := range fields {
+			doc[field] = value
+		}
+		doc["id"] = id
+		if err := c.applySlugs(id, doc); err != nil {
+			return zero, err
+		}
+		if err := c.options.checkGuard(OpUpdate, doc); err != nil {
+			return zero, err
+		}
+
+		written, err := c.putVersion(id, current, doc)
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		if !written {
+			return zero, err
+		}
+		result := c.factory()
+		if hydrateErr := hydrate(doc, &result); hydrateErr != nil {
+			return zero, hydrateErr
+		}
+		return result, err
+	}
+
+	return zero, &ConflictExhaustedError{
+		Collection: c.collection,
+		ID:         id,
+		Attempts:   attempts,
+		Version:    current.version(),
+		LastSeen:   current.doc,
+	}
+}
+
+// changedFields lists the fields whose values differ between two forms of
+// a document, compared as JSON so number types and codecs do not matter
+func changedFields(before, after map[string]interface{}) []string {
+	a, b := jsonForm(before), jsonForm(after)
+	changed := make([]string, 0)
+	for field, value := range a {
+		if other, ok := b[field]; field != "id" && (!ok || !reflect.DeepEqual(value, other)) {
+			changed = append(changed, field)
+		}
+	}
+	for field := range b {
+		if _, ok := a[field]; field != "id" && !ok {
+			changed = append(changed, field)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func jsonForm(doc map[string]interface{}) map[string]interface{} {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return doc
+	}
+	var form map[string]interface{}
+	if err := json.Unmarshal(raw, &form); err != nil {
+		return doc
+	}
+	return form
+}
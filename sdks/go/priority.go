@@ -0,0 +1,159 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrLoadShed is returned when the client drops a background request
+// unsent to leave a recovering server to more important traffic, see
+// Priority
+var ErrLoadShed = errors.New("torm: load shed")
+
+// Priority ranks requests for the circuit breaker while the server
+// recovers. The zero value is PriorityNormal.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	// PriorityHigh requests, e.g. user-facing reads, get the first claim
+	// on the probes a half-open circuit lets through
+	PriorityHigh
+	// PriorityBackground requests, e.g. bulk jobs, fail with ErrLoadShed
+	// while the circuit is half-open or one failure away from opening, and
+	// instead of their last retry attempt, as retries of a failing request
+	// use up what a recovering server has left
+	PriorityBackground
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityBackground:
+		return "background"
+	}
+	return "unknown"
+}
+
+// priorityHeader carries the priority of a request from the collection
+// to intercept, which removes it before the request is sent
+const priorityHeader = "X-Torm-Priority"
+
+type priorityKey struct{}
+
+// WithPriority sets the priority of the collection's requests (default
+// PriorityNormal). Operations given a context from WithCallPriority use
+// that one instead. It only matters with ClientOptions.Breaker.
+func WithPriority(p Priority) CollectionOption {
+	return func(o *collectionOptions) {
+		o.priority = p
+	}
+}
+
+// WithPriority sets the priority of the model's requests, as the
+// collection option does
+func (m *Model) WithPriority(p Priority) *Model {
+	m.priority = p
+	return m
+}
+
+// WithCallPriority returns a context whose operations send their requests
+// with priority p, overriding the collection's. Only operations that take
+// a context see it; AtPriority sets it for the others.
+func WithCallPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// AtPriority returns a copy of the collection whose requests have priority
+// p, for calls without a context:
+//
+//	users.AtPriority(torm.PriorityHigh).FindByID(id)
+func (c *Collection[T]) AtPriority(p Priority) *Collection[T] {
+	prioritized := *c
+	prioritized.priority = p
+	return &prioritized
+}
+
+// AtPriority returns a copy of the model whose requests have priority p,
+// leaving m as it is
+func (m *Model) AtPriority(p Priority) *Model {
+	prioritized := *m
+	prioritized.priority = p
+	return &prioritized
+}
+
+// callPriority returns the priority set on ctx by WithCallPriority
+func callPriority(ctx context.Context) (Priority, bool) {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	return p, ok
+}
+
+// prioritized adds the priority p to header, unless it is the default
+func prioritized(header http.Header, p Priority) http.Header {
+	if p == PriorityNormal {
+		return header
+	}
+	all := make(http.Header, len(header)+1)
+	for name, values := range header {
+		all[name] = values
+	}
+	all.Set(priorityHeader, p.String())
+	return all
+}
+
+// requestPriority takes the priority of req off its headers
+func requestPriority(req *http.Request) Priority {
+	value := req.Header.Get(priorityHeader)
+	if value == "" {
+		return PriorityNormal
+	}
+	req.Header.Del(priorityHeader)
+	for _, p := range []Priority{PriorityHigh, PriorityBackground} {
+		if value == p.String() {
+			return p
+		}
+	}
+	return PriorityNormal
+}
+
+// shedCounters counts the requests shed with ErrLoadShed by priority
+type shedCounters [PriorityBackground + 1]atomic.Int64
+
+func (s *shedCounters) stats() map[Priority]int64 {
+	stats := make(map[Priority]int64)
+	for p := range s {
+		if n := s[p].Load(); n > 0 {
+			stats[Priority(p)] = n
+		}
+	}
+	return stats
+}
+
+// prioritizedBy returns a copy of c whose requests have the priority set
+// on ctx by WithCallPriority, or c itself when ctx sets none
+func (c *Collection[T]) prioritizedBy(ctx context.Context) *Collection[T] {
+	priority, ok := callPriority(ctx)
+	if !ok || priority == c.priority {
+		return c
+	}
+	prioritized := *c
+	prioritized.priority = priority
+	return &prioritized
+}
+
+// prioritizedBy returns a copy of m whose requests have the priority set
+// on ctx by WithCallPriority, or m itself when ctx sets none
+func (m *Model) prioritizedBy(ctx context.Context) *Model {
+	priority, ok := callPriority(ctx)
+	if !ok || priority == m.priority {
+		return m
+	}
+	prioritized := *m
+	prioritized.priority = priority
+	return &prioritized
+}
@@ -0,0 +1,143 @@
+package torm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNotSupported is returned when the server lacks an endpoint the SDK needs
+var ErrNotSupported = errors.New("torm: operation not supported by server")
+
+// EnsureCollectionOptions configures explicit collection creation
+type EnsureCollectionOptions struct {
+	Settings map[string]interface{} // Server-specific collection settings
+}
+
+// EnsureCollection creates a collection on servers that require explicit
+// creation. It is a no-op when the collection already exists and returns
+// whether it was created. Servers without the endpoint yield ErrNotSupported.
+func (c *Client) EnsureCollection(name string, opts *EnsureCollectionOptions) (bool, error) {
+	body := map[string]interface{}{"name": name}
+	if opts != nil && opts.Settings != nil {
+		body["settings"] = opts.Settings
+	}
+
+	resp, err := c.request("POST", c.apiPath("_collections"), body)
+	if err != nil {
+		return false, fmt.Errorf("ensure collection failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusOK, http.StatusConflict:
+		return false, nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return false, fmt.Errorf("ensure collection %s: %w", name, ErrNotSupported)
+	default:
+		return false, responseError("ensure collection", resp)
+	}
+}
+
+// CollectionNotFoundMatcher reports whether a response means the collection itself is missing
+type CollectionNotFoundMatcher func(status int, body []byte) bool
+
+// DefaultCollectionNotFound matches 404 responses whose body mentions a missing collection
+func DefaultCollectionNotFound(status int, body []byte) bool {
+	return status == http.StatusNotFound && bytes.Contains(bytes.ToLower(body), []byte("collection not found"))
+}
+
+// WithAutoProvision creates the collection the first time an operation fails
+// because it is missing, then retries that operation once. A nil matcher uses
+// DefaultCollectionNotFound.
+func WithAutoProvision(matcher CollectionNotFoundMatcher) CollectionOption {
+	return func(o *collectionOptions) {
+		if matcher == nil {
+			matcher = DefaultCollectionNotFound
+		}
+		o.provisionMatcher = matcher
+	}
+}
+
+// send performs a request, provisioning the collection and retrying once when
+// auto-provisioning is enabled and the response says the collection is missing
+func (c *Collection[T]) send(request func() (*bufferedResponse, error)) (*bufferedResponse, error) {
+	resp, err := request()
+	if err != nil || c.options.provisionMatcher == nil || c.options.provisioned.Load() {
+		return resp, err
+	}
+
+	if !c.options.provisionMatcher(resp.StatusCode(), resp.Body()) {
+		return resp, nil
+	}
+
+	if _, err := c.client.EnsureCollection(c.collection, nil); err != nil {
+		if errors.Is(err, ErrNotSupported) {
+			return resp, nil
+		}
+		return nil, fmt.Errorf("auto-provision of collection %s failed: %w", c.collection, err)
+	}
+	c.options.provisioned.Store(true)
+
+	return request()
+}
+
+// IndexSpec describes an index for EnsureIndex
+type IndexSpec struct {
+	Collection string
+	Name       string
+	Fields     []string // Indexed fields, in order; prefix one with "-" to sort it descending
+	Unique     bool
+}
+
+// EnsureIndex creates an index on servers with the index endpoint. It is a
+// no-op when an index of that name already exists and returns whether it
+// was created. Servers without the endpoint yield ErrNotSupported.
+func (c *Client) EnsureIndex(spec IndexSpec) (bool, error) {
+	if spec.Collection == "" || spec.Name == "" || len(spec.Fields) == 0 {
+		return false, fmt.Errorf("index needs a collection, a name and fields")
+	}
+	body := map[string]interface{}{"name": spec.Name, "fields": spec.Fields}
+	if spec.Unique {
+		body["unique"] = true
+	}
+
+	resp, err := c.request("POST", c.apiPath(spec.Collection, "_indexes"), body)
+	if err != nil {
+		return false, fmt.Errorf("ensure index failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		c.capabilities.record(CapabilityIndexes, true)
+		return true, nil
+	case http.StatusOK, http.StatusConflict:
+		c.capabilities.record(CapabilityIndexes, true)
+		return false, nil
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		c.capabilities.record(CapabilityIndexes, false)
+		return false, fmt.Errorf("ensure index %s on %s: %w", spec.Name, spec.Collection, ErrNotSupported)
+	default:
+		return false, responseError("ensure index", resp)
+	}
+}
+
+// EnsureIndexes ensures each index in turn and returns the names of those
+// created, stopping at the first failure
+func (c *Client) EnsureIndexes(specs ...IndexSpec) ([]string, error) {
+	created := make([]string, 0)
+	for _, spec := range specs {
+		ok, err := c.EnsureIndex(spec)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created = append(created, spec.Name)
+		}
+	}
+	return created, nil
+}
@@ -1,10 +1,11 @@
 package torm
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
 	"sort"
+	"time"
 )
 
 // QueryOperator represents query comparison operators
@@ -22,6 +23,9 @@ const (
 	NotIn    QueryOperator = "not_in"
 )
 
+// ErrInvalidFilter is returned when a filter's operator cannot apply to its value
+var ErrInvalidFilter = errors.New("torm: invalid filter")
+
 // SortOrder represents sort order
 type SortOrder string
 
@@ -41,6 +45,13 @@ type QueryFilter struct {
 type QuerySort struct {
 	Field string    `json:"field"`
 	Order SortOrder `json:"order"`
+
+	// Missing and null values sort first or last whatever the order;
+	// NullsFirst wins if both are set. Unset, the collection's
+	// SortDefaults apply.
+	NullsFirst      bool `json:"nulls_first,omitempty"`
+	NullsLast       bool `json:"nulls_last,omitempty"`
+	CaseInsensitive bool `json:"case_insensitive,omitempty"` // Strings compare ignoring case
 }
 
 // QueryBuilder builds complex queries
@@ -49,8 +60,29 @@ type QueryBuilder struct {
 	collection string
 	filters    []QueryFilter
 	sortField  *QuerySort
-	limitVal   *int
-	skipVal    *int
+	// defaultOrder is the final sort key of every query; see DefaultOrder
+	defaultOrder *QuerySort
+	limitVal     *int
+	skipVal      *int
+	fields       []string
+	decimals     map[string]bool                                              // Schema fields compared as decimals
+	codecs       codecChain                                                   // Documents are stored encoded; see WithCodec
+	repair       func(map[string]interface{}) (map[string]interface{}, error) // Read repair of results
+
+	coercions      map[string]coercion // Fields compared after coercion; see CoerceNumeric
+	coercionPolicy CoercionPolicy
+	redacted       []string // Fields masked in String; see Redact
+
+	hints map[string]interface{} // Sent as the payload's "options"; see Hint
+
+	sortDefaults *SortDefaults   // See WithSortDefaults
+	textFields   map[string]bool // Schema fields declared as strings
+
+	slowThreshold *time.Duration // See WithSlowThreshold
+	trace         *opTimer       // See traced
+	parity        *filterParity  // See WithFilterParityChecks
+	priority      Priority       // See WithPriority
+	scanned       *scannedPage   // Filled in by exec when set, see exportSnapshot
 }
 
 // Filter adds a filter condition
@@ -89,36 +121,74 @@ func (qb *QueryBuilder) Skip(n int) *QueryBuilder {
 	return qb
 }
 
-// Exec executes the query
-func (qb *QueryBuilder) Exec() ([]map[string]interface{}, error) {
-	queryData := make(map[string]interface{})
+// Select restricts returned documents to the given fields. The id field is always kept.
+func (qb *QueryBuilder) Select(fields ...string) *QueryBuilder {
+	qb.fields = append(qb.fields, fields...)
+	return qb
+}
 
-	if len(qb.filters) > 0 {
-		queryData["filters"] = qb.filters
-	}
+// Clone returns an independent copy of the query, so it can be refined
+// without changing the original
+func (qb *QueryBuilder) Clone() *QueryBuilder {
+	clone := *qb
+	clone.filters = append([]QueryFilter(nil), qb.filters...)
+	clone.fields = append([]string(nil), qb.fields...)
+	clone.redacted = append([]string(nil), qb.redacted...)
 	if qb.sortField != nil {
-		queryData["sort"] = qb.sortField
+		sortField := *qb.sortField
+		clone.sortField = &sortField
 	}
 	if qb.limitVal != nil {
-		queryData["limit"] = *qb.limitVal
+		limit := *qb.limitVal
+		clone.limitVal = &limit
 	}
 	if qb.skipVal != nil {
-		queryData["skip"] = *qb.skipVal
+		skip := *qb.skipVal
+		clone.skipVal = &skip
+	}
+	clone.hints = qb.Hints()
+	return &clone
+}
+
+// Exec executes the query. Errors name the query as String shows it.
+func (qb *QueryBuilder) Exec() (found []map[string]interface{}, err error) {
+	timer := qb.client.timeOp(OpQuery, qb.collection, qb.slowThreshold).query(qb).counting(func() int { return len(found) })
+	defer timer.end(&err)
+	found, err = qb.traced(timer).exec()
+	if err != nil {
+		return nil, fmt.Errorf("%w (query: %s)", err, qb)
 	}
+	return found, nil
+}
+
+func (qb *QueryBuilder) exec() ([]map[string]interface{}, error) {
+	if err := qb.validateFilters(); err != nil {
+		return nil, err
+	}
+	if err := qb.validateCoercions(); err != nil {
+		return nil, err
+	}
+	if err := qb.validateHints(); err != nil {
+		return nil, err
+	}
+
+	pageLocally := qb.pagesLocally()
+	queryData := qb.payload(pageLocally)
 
-	resp, err := qb.client.request("POST", "/api/"+qb.collection+"/query", queryData)
+	resp, err := qb.client.requestJSON("POST", qb.client.apiPath(qb.collection, "query"), queryData, qb.trace.headers(prioritized(nil, qb.priority)))
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
 	defer resp.Body.Close()
+	qb.trace.sent(resp.StatusCode)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("query failed with status %d", resp.StatusCode)
+	if !isSuccess(resp.StatusCode) {
+		return nil, responseError("query", resp)
 	}
 
 	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := qb.client.decodeResponse("query", resp, listShape, &result); err != nil {
+		return nil, err
 	}
 
 	docs, ok := result["documents"].([]interface{})
@@ -126,36 +196,171 @@ func (qb *QueryBuilder) Exec() ([]map[string]interface{}, error) {
 		return []map[string]interface{}{}, nil
 	}
 
+	check := qb.parity.sample()
+	var returned []map[string]interface{}
 	documents := make([]map[string]interface{}, 0, len(docs))
 	for _, doc := range docs {
 		if docMap, ok := doc.(map[string]interface{}); ok {
+			docMap, err := qb.codecs.decode(docMap)
+			if err != nil {
+				return nil, err
+			}
+			if check {
+				returned = append(returned, docMap)
+			}
 			if qb.matchesFilters(docMap) {
+				if qb.repair != nil {
+					if docMap, err = qb.repair(docMap); err != nil {
+						return nil, err
+					}
+				}
 				documents = append(documents, docMap)
 			}
 		}
 	}
 
-	// Apply client-side sorting
-	if qb.sortField != nil {
-		qb.sortDocuments(documents)
+	if check {
+		qb.checkParity(returned, result["count"])
+	}
+	if qb.scanned != nil {
+		qb.scanned.documents, qb.scanned.local = len(docs), pageLocally
+		if len(docs) > 0 {
+			if last, ok := docs[len(docs)-1].(map[string]interface{}); ok {
+				qb.scanned.lastID = fmt.Sprintf("%v", last["id"])
+			}
+		}
+	}
+
+	// Apply client-side sorting, which also breaks ties the server left
+	qb.sortDocuments(documents)
+
+	if pageLocally {
+		documents = qb.page(documents)
+	}
+
+	// Apply client-side projection for servers that ignore it
+	if len(qb.fields) > 0 {
+		fields := qb.projection()
+		for i, doc := range documents {
+			documents[i] = projectDocument(doc, fields)
+		}
 	}
 
 	return documents, nil
 }
 
-// Count counts matching documents
-func (qb *QueryBuilder) Count() (int, error) {
-	docs, err := qb.Exec()
-	if err != nil {
-		return 0, err
+// payload builds the request body of the query
+func (qb *QueryBuilder) payload(pageLocally bool) map[string]interface{} {
+	queryData := make(map[string]interface{})
+
+	serverFilters := make([]QueryFilter, 0, len(qb.filters))
+	for _, filter := range qb.filters {
+		if !qb.isDecimalComparison(filter) && !qb.isCoercedComparison(filter) && !qb.encoded(filter.Field) {
+			serverFilters = append(serverFilters, serverFilter(filter))
+		}
 	}
-	return len(docs), nil
+	if len(serverFilters) > 0 {
+		queryData["filters"] = serverFilters
+	}
+	if qb.sortField != nil && !qb.encoded(qb.sortField.Field) && !qb.coercedSort() {
+		queryData["sort"] = qb.serverSort(*qb.sortField)
+		if tieBreak := qb.tieBreaker(); tieBreak != nil && !qb.encoded(tieBreak.Field) {
+			queryData["then_by"] = []QuerySort{qb.serverSort(*tieBreak)}
+		}
+	} else if qb.sortField == nil && qb.defaultOrder != nil && !qb.encoded(qb.defaultOrder.Field) {
+		queryData["sort"] = qb.serverSort(*qb.defaultOrder)
+	}
+	if len(qb.coercions) > 0 {
+		queryData["coerce"] = qb.coercions
+	}
+	if qb.limitVal != nil && !pageLocally {
+		queryData["limit"] = *qb.limitVal
+	}
+	if qb.skipVal != nil && !pageLocally {
+		queryData["skip"] = *qb.skipVal
+	}
+	if len(qb.fields) > 0 && !qb.codecs.opaque() {
+		// Filter and sort fields are fetched too so the client-side pass can evaluate them
+		fields := qb.projection()
+		for _, filter := range qb.filters {
+			fields = append(fields, filter.Field)
+		}
+		for _, key := range qb.sortKeys() {
+			fields = append(fields, key.Field)
+		}
+		queryData["fields"] = uniqueFields(fields)
+	}
+	if hints := qb.Hints(); hints != nil {
+		queryData["options"] = hints
+	}
+	return queryData
+}
+
+// encoded reports whether a field is only readable after decoding, so
+// the server cannot filter or sort on it
+func (qb *QueryBuilder) encoded(field string) bool {
+	return field != "id" && qb.codecs.encodes(field)
+}
+
+// pagesLocally reports whether skip and limit must be applied after the
+// client-side filter and sort
+func (qb *QueryBuilder) pagesLocally() bool {
+	if qb.sortField != nil && (qb.encoded(qb.sortField.Field) || qb.coercedSort()) {
+		return true
+	}
+	if qb.localSort() {
+		return true
+	}
+	for _, filter := range qb.filters {
+		if qb.encoded(filter.Field) || qb.isCoercedComparison(filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// page applies skip and limit to already filtered documents
+func (qb *QueryBuilder) page(docs []map[string]interface{}) []map[string]interface{} {
+	if qb.skipVal != nil {
+		if *qb.skipVal >= len(docs) {
+			return docs[:0]
+		}
+		docs = docs[*qb.skipVal:]
+	}
+	if qb.limitVal != nil && *qb.limitVal < len(docs) {
+		docs = docs[:*qb.limitVal]
+	}
+	return docs
+}
+
+// Count counts matching documents, reading only their ids a page at a
+// time; see Collection.CountWith
+func (qb *QueryBuilder) Count() (n int, err error) {
+	timer := qb.client.timeOp(OpQuery, qb.collection, qb.slowThreshold).query(qb).as("Count").counting(func() int { return n })
+	defer timer.end(&err)
+	return countQuery(context.Background(), qb.traced(timer), CountOptions{IncludeDeleted: true})
 }
 
 // matchesFilters checks if document matches all filters
 func (qb *QueryBuilder) matchesFilters(doc map[string]interface{}) bool {
 	for _, filter := range qb.filters {
 		docValue := doc[filter.Field]
+		if qb.isCoercedComparison(filter) {
+			if matched, ok := qb.matchesCoerced(docValue, filter); ok {
+				if !matched {
+					return false
+				}
+				continue
+			}
+		}
+		if qb.isDecimalComparison(filter) {
+			if matched, ok := matchesDecimal(docValue, filter.Operator, filter.Value); ok {
+				if !matched {
+					return false
+				}
+				continue
+			}
+		}
 		if !qb.matchesFilter(docValue, filter.Operator, filter.Value) {
 			return false
 		}
@@ -167,9 +372,9 @@ func (qb *QueryBuilder) matchesFilters(doc map[string]interface{}) bool {
 func (qb *QueryBuilder) matchesFilter(docValue interface{}, operator QueryOperator, filterValue interface{}) bool {
 	switch operator {
 	case Eq:
-		return fmt.Sprintf("%v", docValue) == fmt.Sprintf("%v", filterValue)
+		return valuesEqual(docValue, filterValue)
 	case Ne:
-		return fmt.Sprintf("%v", docValue) != fmt.Sprintf("%v", filterValue)
+		return !valuesEqual(docValue, filterValue)
 	case Gt:
 		return qb.compareValues(docValue, filterValue) > 0
 	case Gte:
@@ -185,7 +390,7 @@ func (qb *QueryBuilder) matchesFilter(docValue interface{}, operator QueryOperat
 	case In:
 		if arr, ok := filterValue.([]interface{}); ok {
 			for _, item := range arr {
-				if fmt.Sprintf("%v", docValue) == fmt.Sprintf("%v", item) {
+				if valuesEqual(docValue, item) {
 					return true
 				}
 			}
@@ -194,7 +399,7 @@ func (qb *QueryBuilder) matchesFilter(docValue interface{}, operator QueryOperat
 	case NotIn:
 		if arr, ok := filterValue.([]interface{}); ok {
 			for _, item := range arr {
-				if fmt.Sprintf("%v", docValue) == fmt.Sprintf("%v", item) {
+				if valuesEqual(docValue, item) {
 					return false
 				}
 			}
@@ -204,8 +409,18 @@ func (qb *QueryBuilder) matchesFilter(docValue interface{}, operator QueryOperat
 	return false
 }
 
-// compareValues compares two values
+// compareValues compares two values. Booleans order false before true.
 func (qb *QueryBuilder) compareValues(a, b interface{}) int {
+	if aBool, bBool, ok := boolPair(a, b); ok {
+		switch {
+		case aBool == bBool:
+			return 0
+		case bBool:
+			return -1
+		}
+		return 1
+	}
+
 	aFloat, aOk := toFloat64(a)
 	bFloat, bOk := toFloat64(b)
 
@@ -229,30 +444,160 @@ func (qb *QueryBuilder) compareValues(a, b interface{}) int {
 	return 0
 }
 
-// sortDocuments sorts documents by the sort field
+// sortDocuments sorts documents by the sort field, then the default order
 func (qb *QueryBuilder) sortDocuments(docs []map[string]interface{}) {
-	if qb.sortField == nil {
+	keys := qb.sortKeys()
+	if len(keys) == 0 {
 		return
 	}
 
-	field := qb.sortField.Field
-	ascending := qb.sortField.Order == Asc
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range keys {
+			if cmp := qb.compareBy(key, docs[i], docs[j]); cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+}
+
+// compareBy orders two documents by one resolved sort key (see
+// resolveSort), negative when a comes first
+func (qb *QueryBuilder) compareBy(key QuerySort, a, b map[string]interface{}) int {
+	field := key.Field
+	ascending := key.Order != Desc
+	valA, valB := a[field], b[field]
+
+	// Explicit placement wins over coercion, which otherwise keeps
+	// uncoercible values, nulls included, where its policy puts them
+	if key.NullsFirst || key.NullsLast {
+		if cmp, ok := compareNulls(key, valA, valB); ok {
+			return cmp
+		}
+	}
+	if c, coerced := qb.coercions[field]; coerced {
+		if coercedCmp, ok := qb.compareForSort(c, valA, valB, ascending); ok {
+			if ascending {
+				return coercedCmp
+			}
+			return -coercedCmp
+		}
+	}
 
-	sort.Slice(docs, func(i, j int) bool {
-		valI := docs[i][field]
-		valJ := docs[j][field]
+	if cmp, ok := compareNulls(key, valA, valB); ok {
+		return cmp
+	}
+	cmp := qb.compareValues(valA, valB)
+	if aStr, bStr, ok := stringPair(valA, valB); ok && key.CaseInsensitive {
+		cmp = compareFolded(aStr, bStr)
+	}
+	if qb.decimals[field] {
+		a, aOk := parseDecimal(valA)
+		b, bOk := parseDecimal(valB)
+		if aOk && bOk {
+			cmp = a.Cmp(b)
+		}
+	}
 
-		cmp := qb.compareValues(valI, valJ)
+	if ascending {
+		return cmp
+	}
+	return -cmp
+}
 
-		if ascending {
-			return cmp < 0
+// validateFilters rejects operators that have no meaning for their value
+func (qb *QueryBuilder) validateFilters() error {
+	if fields := qb.codecs.fieldCodecs(); fields != nil {
+		for _, filter := range qb.filters {
+			if fields.entry(filter.Field) != nil {
+				return fmt.Errorf("%w: field '%s' is stored through a field codec and cannot be filtered",
+					ErrInvalidFilter, filter.Field)
+			}
 		}
-		return cmp > 0
-	})
+	}
+	for _, filter := range qb.filters {
+		if _, isBool := filter.Value.(bool); !isBool {
+			continue
+		}
+		switch filter.Operator {
+		case Eq, Ne:
+		default:
+			return fmt.Errorf("%w: operator %s cannot be used with boolean value on field '%s'",
+				ErrInvalidFilter, filter.Operator, filter.Field)
+		}
+	}
+	return nil
+}
+
+// serverFilter rewrites boolean filters so servers comparing values as
+// strings match every form the client treats as equal
+func serverFilter(filter QueryFilter) QueryFilter {
+	switch filter.Operator {
+	case Eq, Ne:
+		b, isBool := filter.Value.(bool)
+		if !isBool {
+			return filter
+		}
+		operator := In
+		if filter.Operator == Ne {
+			operator = NotIn
+		}
+		return QueryFilter{Field: filter.Field, Operator: operator, Value: booleanForms(b)}
+	case In, NotIn:
+		items, ok := filter.Value.([]interface{})
+		if !ok {
+			return filter
+		}
+		expanded := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			if b, isBool := item.(bool); isBool {
+				expanded = append(expanded, booleanForms(b)...)
+			} else {
+				expanded = append(expanded, item)
+			}
+		}
+		return QueryFilter{Field: filter.Field, Operator: filter.Operator, Value: expanded}
+	}
+	return filter
+}
+
+// booleanForms lists the stored values that match a boolean filter
+func booleanForms(b bool) []interface{} {
+	if b {
+		return []interface{}{true, "true", 1}
+	}
+	return []interface{}{false, "false", 0}
+}
+
+// projection returns the selected fields with id first
+func (qb *QueryBuilder) projection() []string {
+	return uniqueFields(append([]string{"id"}, qb.fields...))
 }
 
 // Helper functions
 
+func uniqueFields(fields []string) []string {
+	unique := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			unique = append(unique, field)
+		}
+	}
+	return unique
+}
+
+func projectDocument(doc map[string]interface{}, fields []string) map[string]interface{} {
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		if value, ok := doc[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > 0 && len(substr) > 0 && findSubstring(s, substr)))
@@ -267,6 +612,51 @@ func findSubstring(s, substr string) bool {
 	return false
 }
 
+// toBool coerces true/false, "true"/"false" and 1/0 to a boolean
+func toBool(val interface{}) (bool, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch v {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	default:
+		if f, ok := toFloat64(v); ok && (f == 0 || f == 1) {
+			return f == 1, true
+		}
+	}
+	return false, false
+}
+
+// boolPair coerces both values to booleans when at least one is a real
+// boolean, so sorting orders false before true
+func boolPair(a, b interface{}) (bool, bool, bool) {
+	_, aIsBool := a.(bool)
+	_, bIsBool := b.(bool)
+	if !aIsBool && !bIsBool {
+		return false, false, false
+	}
+
+	aBool, aOk := toBool(a)
+	bBool, bOk := toBool(b)
+	return aBool, bBool, aOk && bOk
+}
+
+// valuesEqual compares a document value with a filter value. A boolean
+// filter value matches true/false, "true"/"false" and 1/0; anything else
+// compares by string form, as servers do.
+func valuesEqual(docValue, filterValue interface{}) bool {
+	if want, isBool := filterValue.(bool); isBool {
+		got, ok := toBool(docValue)
+		return ok && got == want
+	}
+	return fmt.Sprintf("%v", docValue) == fmt.Sprintf("%v", filterValue)
+}
+
 func toFloat64(val interface{}) (float64, bool) {
 	switch v := val.(type) {
 	case float64:
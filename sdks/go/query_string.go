@@ -0,0 +1,150 @@
+package torm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedactedValue replaces masked filter values in query strings
+const RedactedValue = "***"
+
+// QueryRedactor returns the value a query string shows for a filter value
+type QueryRedactor func(field string, value interface{}) interface{}
+
+// RedactFields masks the values of filters on fields in query strings
+func RedactFields(fields ...string) QueryRedactor {
+	masked := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		masked[field] = true
+	}
+	return func(field string, value interface{}) interface{} {
+		if masked[field] {
+			return RedactedValue
+		}
+		return value
+	}
+}
+
+// Redact masks the values of filters on fields in the query's String,
+// on top of ClientOptions.RedactQuery
+func (qb *QueryBuilder) Redact(fields ...string) *QueryBuilder {
+	qb.redacted = append(qb.redacted, fields...)
+	return qb
+}
+
+// queryOperatorSymbols are how operators read in query strings
+var queryOperatorSymbols = map[QueryOperator]string{
+	Eq:       "=",
+	Ne:       "!=",
+	Gt:       ">",
+	Gte:      ">=",
+	Lt:       "<",
+	Lte:      "<=",
+	Contains: " CONTAINS ",
+	In:       " IN ",
+	NotIn:    " NOT IN ",
+}
+
+// String describes the query in one line for logs and errors, e.g.
+//
+//	users: age>30 AND status IN [a,b] SORT createdAt DESC LIMIT 20 SKIP 40 SELECT id,name HINT index=by_age
+//
+// Filters keep the order they were added in; the default order is not
+// shown. The format is stable, so it can be matched by alerts.
+func (qb *QueryBuilder) String() string {
+	var b strings.Builder
+	b.WriteString(qb.collection)
+	b.WriteString(":")
+
+	if len(qb.filters) == 0 {
+		b.WriteString(" *")
+	}
+	for i, filter := range qb.filters {
+		if i > 0 {
+			b.WriteString(" AND")
+		}
+		b.WriteString(" ")
+		b.WriteString(filter.Field)
+		symbol, ok := queryOperatorSymbols[filter.Operator]
+		if !ok {
+			symbol = " " + strings.ToUpper(string(filter.Operator)) + " "
+		}
+		b.WriteString(symbol)
+		b.WriteString(formatQueryValue(qb.redactValue(filter.Field, filter.Value)))
+	}
+
+	if qb.sortField != nil {
+		b.WriteString(" SORT ")
+		b.WriteString(qb.sortField.describe())
+	}
+	if qb.limitVal != nil {
+		fmt.Fprintf(&b, " LIMIT %d", *qb.limitVal)
+	}
+	if qb.skipVal != nil {
+		fmt.Fprintf(&b, " SKIP %d", *qb.skipVal)
+	}
+	if len(qb.fields) > 0 {
+		b.WriteString(" SELECT ")
+		b.WriteString(strings.Join(qb.fields, ","))
+	}
+	b.WriteString(qb.hintString())
+	return b.String()
+}
+
+func (qb *QueryBuilder) redactValue(field string, value interface{}) interface{} {
+	for _, redacted := range qb.redacted {
+		if redacted == field {
+			return RedactedValue
+		}
+	}
+	if qb.client != nil && qb.client.redactQuery != nil {
+		var shown interface{}
+		if err := protect("query redactor", qb.collection, "", func() { shown = qb.client.redactQuery(field, value) }); err != nil {
+			return RedactedValue
+		}
+		return shown
+	}
+	return value
+}
+
+// formatQueryValue writes a filter value. Strings are bare unless they
+// would be ambiguous; lists and objects are written recursively, objects
+// with sorted keys.
+func formatQueryValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		if v == "" || v == "null" || strings.ContainsAny(v, " \t\n,[]{}\"") {
+			return strconv.Quote(v)
+		}
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key + ":" + formatQueryValue(v[key])
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		parts := make([]string, rv.Len())
+		for i := range parts {
+			parts[i] = formatQueryValue(rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+	if s, ok := value.(fmt.Stringer); ok {
+		return formatQueryValue(s.String())
+	}
+	return fmt.Sprint(value)
+}
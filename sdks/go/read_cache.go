@@ -0,0 +1,247 @@
+package torm
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// ReadCacheOptions configures WithReadCache
+type ReadCacheOptions struct {
+	Size int           // Documents kept, least recently used evicted first (default 1000)
+	TTL  time.Duration // How long a cached document is served (default 1m)
+	// WarmShare is the share of Size a single Warm or WarmQuery may evict
+	// to make room, so warming cannot push out the documents in active use
+	// (default 0.5)
+	WarmShare float64
+}
+
+// ReadCacheStats reports a read cache's size and effectiveness
+type ReadCacheStats struct {
+	Entries     int
+	Hits        int64
+	Misses      int64
+	Evictions   int64
+	Revalidated int64 // Expired entries the server confirmed unchanged
+}
+
+// WithReadCache keeps the documents FindByID reads in memory and serves
+// them from there until the TTL passes. Documents the server sent an ETag
+// for are then revalidated with If-None-Match: a 304 serves the cached copy
+// for another TTL without transferring it again. Writes and deletes through the
+// collection evict the document, as do changes by other processes while
+// the client follows invalidations (see Client.FollowInvalidations). Hits
+// are still checked by the guard.
+func WithReadCache(opts ReadCacheOptions) CollectionOption {
+	if opts.Size <= 0 {
+		opts.Size = 1000
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.WarmShare <= 0 || opts.WarmShare > 1 {
+		opts.WarmShare = 0.5
+	}
+	return func(o *collectionOptions) {
+		o.readCache = &readCache{opts: opts, lru: list.New(), entries: make(map[string]*list.Element)}
+	}
+}
+
+// ReadCacheStats returns the counters of the collection's read cache; they
+// are zero without WithReadCache
+func (c *Collection[T]) ReadCacheStats() ReadCacheStats {
+	return c.options.readCache.stats()
+}
+
+func (rc *readCache) stats() ReadCacheStats {
+	if rc == nil {
+		return ReadCacheStats{}
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return ReadCacheStats{
+		Entries:     rc.lru.Len(),
+		Hits:        rc.hits,
+		Misses:      rc.misses,
+		Evictions:   rc.evictions,
+		Revalidated: rc.revalidated,
+	}
+}
+
+// readCache is an LRU of decoded documents. It is a localIndex so the
+// collection's writes and routed invalidations evict from it.
+type readCache struct {
+	opts  ReadCacheOptions
+	clock func() time.Time // Set by NewCollection
+
+	mu      sync.Mutex
+	lru     *list.List // Most recently used first
+	entries map[string]*list.Element
+	warms   int64 // Warm runs started, numbering them
+
+	hits        int64
+	misses      int64
+	evictions   int64
+	revalidated int64
+}
+
+type cacheEntry struct {
+	id     string
+	doc    map[string]interface{}
+	etag   string // Validator sent by the server, empty when it sent none
+	stored time.Time
+	warm   int64 // The warm run that stored the entry, zero for reads
+}
+
+// get returns a copy of a cached document that has not expired. Expired
+// entries with an ETag are kept for revalidation.
+func (rc *readCache) get(id string) (map[string]interface{}, bool) {
+	if rc == nil {
+		return nil, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	elem, ok := rc.entries[id]
+	if !ok {
+		rc.misses++
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if rc.clock().Sub(entry.stored) >= rc.opts.TTL {
+		if entry.etag == "" {
+			rc.removeElement(elem)
+		}
+		rc.misses++
+		return nil, false
+	}
+	rc.lru.MoveToFront(elem)
+	rc.hits++
+	return deepCopyDoc(entry.doc), true
+}
+
+// validator returns the ETag of a cached document, empty when there is none
+func (rc *readCache) validator(id string) string {
+	if rc == nil {
+		return ""
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if elem, ok := rc.entries[id]; ok {
+		return elem.Value.(*cacheEntry).etag
+	}
+	return ""
+}
+
+// revalidate restarts the TTL of a document the server reported unchanged
+// and returns a copy of it
+func (rc *readCache) revalidate(id string) (map[string]interface{}, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	elem, ok := rc.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	entry.stored = rc.clock()
+	rc.lru.MoveToFront(elem)
+	rc.revalidated++
+	return deepCopyDoc(entry.doc), true
+}
+
+// store caches a document that was just read, along with its ETag
+func (rc *readCache) store(id, etag string, doc map[string]interface{}) {
+	if rc == nil || id == "" {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.insert(&cacheEntry{id: id, doc: deepCopyDoc(doc), etag: etag, stored: rc.clock()})
+	for rc.lru.Len() > rc.opts.Size {
+		rc.removeElement(rc.lru.Back())
+		rc.evictions++
+	}
+}
+
+// warmRun tracks the evictions of one Warm or WarmQuery
+type warmRun struct {
+	id     int64
+	budget int // Entries of other runs and reads it may still evict
+}
+
+func (rc *readCache) startWarm() *warmRun {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.warms++
+	return &warmRun{id: rc.warms, budget: int(float64(rc.opts.Size) * rc.opts.WarmShare)}
+}
+
+// warm caches a document for a warm run, reporting false when that would
+// evict more entries than the run's budget allows. Entries the same run
+// stored are evicted freely.
+func (rc *readCache) warm(run *warmRun, id string, doc map[string]interface{}) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if _, cached := rc.entries[id]; !cached && rc.lru.Len() >= rc.opts.Size {
+		victim := rc.lru.Back()
+		if victim.Value.(*cacheEntry).warm != run.id {
+			if run.budget == 0 {
+				return false
+			}
+			run.budget--
+		}
+		rc.removeElement(victim)
+		rc.evictions++
+	}
+	rc.insert(&cacheEntry{id: id, doc: deepCopyDoc(doc), stored: rc.clock(), warm: run.id})
+	return true
+}
+
+func (rc *readCache) insert(entry *cacheEntry) {
+	if elem, ok := rc.entries[entry.id]; ok {
+		elem.Value = entry
+		rc.lru.MoveToFront(elem)
+		return
+	}
+	rc.entries[entry.id] = rc.lru.PushFront(entry)
+}
+
+func (rc *readCache) removeElement(elem *list.Element) {
+	rc.lru.Remove(elem)
+	delete(rc.entries, elem.Value.(*cacheEntry).id)
+}
+
+// put evicts a document the collection wrote; the written payload may be
+// partial, so the next read fetches the stored copy
+func (rc *readCache) put(doc map[string]interface{}) {
+	id, _ := doc["id"].(string)
+	rc.remove(id)
+}
+
+func (rc *readCache) remove(id string) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if elem, ok := rc.entries[id]; ok {
+		rc.removeElement(elem)
+	}
+}
+
+// Refresh empties the cache, for when invalidations were missed
+func (rc *readCache) Refresh(ctx context.Context) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.lru.Init()
+	rc.entries = make(map[string]*list.Element)
+	return nil
+}
+
+func (rc *readCache) indexedFields() []string {
+	return nil
+}
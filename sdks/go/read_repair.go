@@ -0,0 +1,280 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReadRepair upgrades documents written under an older schema as they are
+// read. Repairs happen in memory; with WriteBack the repaired document is
+// also stored, asynchronously and at most once at a time per id.
+type ReadRepair struct {
+	Defaults map[string]interface{}                // Values for fields a document lacks
+	Renames  map[string]string                     // Legacy field name to current name
+	Upgrade  func(doc map[string]interface{}) bool // Custom transform, reports whether it changed doc
+
+	WriteBack       bool          // Store repaired documents that pass validation
+	MaxWriteBacks   int           // Write-backs started per window (default 10)
+	WriteBackWindow time.Duration // Rate limit window, on the client's clock (default 1s)
+
+	OnRepair    func(event RepairEvent)    // Called for every repaired document
+	OnWriteBack func(id string, err error) // Called when a write-back finishes
+}
+
+// RepairEvent describes one read repair
+type RepairEvent struct {
+	Collection string
+	ID         string
+	Fields     []string // Fields added, renamed or changed, sorted
+	Invalid    error    // Validation error of the document as read, if any
+}
+
+// ReadRepairStats counts read repairs
+type ReadRepairStats struct {
+	Repaired        int64 // Documents repaired in memory
+	WrittenBack     int64 // Repaired documents stored
+	WriteBackFailed int64 // Write-backs the server rejected or that failed to send
+	Deduplicated    int64 // Write-backs skipped because one for the id was in flight
+	RateLimited     int64 // Write-backs skipped by the rate limit
+	Skipped         int64 // Write-backs skipped because the repair still fails validation
+}
+
+// readRepairer applies a ReadRepair and holds its shared state
+type readRepairer struct {
+	opts ReadRepair
+
+	mu          sync.Mutex
+	inFlight    map[string]bool
+	windowStart time.Time
+	windowCount int
+
+	repaired        atomic.Int64
+	writtenBack     atomic.Int64
+	writeBackFailed atomic.Int64
+	deduplicated    atomic.Int64
+	rateLimited     atomic.Int64
+	skipped         atomic.Int64
+}
+
+func newReadRepairer(opts ReadRepair) *readRepairer {
+	if opts.MaxWriteBacks <= 0 {
+		opts.MaxWriteBacks = 10
+	}
+	if opts.WriteBackWindow <= 0 {
+		opts.WriteBackWindow = time.Second
+	}
+	return &readRepairer{opts: opts, inFlight: make(map[string]bool)}
+}
+
+// WithReadRepair repairs documents the collection reads through FindByID,
+// Find and queries
+func WithReadRepair(opts ReadRepair) CollectionOption {
+	return func(o *collectionOptions) {
+		o.readRepair = newReadRepairer(opts)
+	}
+}
+
+// WithReadRepair repairs documents the model reads through FindByID, Find
+// and queries. Documents failing the model's schema are repaired too, and
+// only repairs that pass it are written back.
+func (m *Model) WithReadRepair(opts ReadRepair) *Model {
+	m.readRepair = newReadRepairer(opts)
+	return m
+}
+
+// ReadRepairStats returns the model's read repair counters
+func (m *Model) ReadRepairStats() ReadRepairStats {
+	return m.readRepair.stats()
+}
+
+// ReadRepairStats returns the collection's read repair counters
+func (c *Collection[T]) ReadRepairStats() ReadRepairStats {
+	return c.options.readRepair.stats()
+}
+
+func (r *readRepairer) stats() ReadRepairStats {
+	if r == nil {
+		return ReadRepairStats{}
+	}
+	return ReadRepairStats{
+		Repaired:        r.repaired.Load(),
+		WrittenBack:     r.writtenBack.Load(),
+		WriteBackFailed: r.writeBackFailed.Load(),
+		Deduplicated:    r.deduplicated.Load(),
+		RateLimited:     r.rateLimited.Load(),
+		Skipped:         r.skipped.Load(),
+	}
+}
+
+// repairDoc returns doc repaired, or doc itself when it needs no repair. It
+// fails only when a callback of the repair panics.
+func (m *Model) repairDoc(doc map[string]interface{}) (map[string]interface{}, error) {
+	r := m.readRepair
+	if r == nil || doc == nil {
+		return doc, nil
+	}
+
+	var invalid error
+	if m.schema != nil {
+		invalid = m.validateData(cloneDoc(doc), false)
+		var panicked *PanicError
+		if errors.As(invalid, &panicked) {
+			return nil, invalid
+		}
+	}
+
+	repaired, fields, err := r.apply(m.collection, doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return doc, nil
+	}
+
+	id, _ := repaired["id"].(string)
+	r.repaired.Add(1)
+	if r.opts.OnRepair != nil {
+		event := RepairEvent{Collection: m.collection, ID: id, Fields: fields, Invalid: invalid}
+		if err := protect("read repair hook", m.collection, id, func() { r.opts.OnRepair(event) }); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.opts.WriteBack && id != "" {
+		if m.schema != nil && m.validateData(cloneDoc(repaired), false) != nil {
+			r.skipped.Add(1)
+		} else if r.acquire(id, m.client.now()) {
+			go m.writeBack(id, cloneDoc(repaired))
+		}
+	}
+
+	return repaired, nil
+}
+
+// apply repairs a copy of doc and returns it with the fields it changed
+func (r *readRepairer) apply(collection string, doc map[string]interface{}) (map[string]interface{}, []string, error) {
+	repaired := cloneDoc(doc)
+	changed := make(map[string]bool)
+
+	for legacy, current := range r.opts.Renames {
+		value, ok := repaired[legacy]
+		if !ok {
+			continue
+		}
+		if _, exists := repaired[current]; !exists {
+			repaired[current] = value
+		}
+		delete(repaired, legacy)
+		changed[legacy] = true
+		changed[current] = true
+	}
+
+	for field, value := range r.opts.Defaults {
+		if _, exists := repaired[field]; !exists {
+			repaired[field] = value
+			changed[field] = true
+		}
+	}
+
+	if r.opts.Upgrade != nil {
+		before := cloneDoc(repaired)
+		upgraded := false
+		if err := protect("read repair", collection, docID(doc), func() { upgraded = r.opts.Upgrade(repaired) }); err != nil {
+			return nil, nil, err
+		}
+		if upgraded {
+			for field, value := range repaired {
+				if old, ok := before[field]; !ok || fmt.Sprint(old) != fmt.Sprint(value) {
+					changed[field] = true
+				}
+			}
+			for field := range before {
+				if _, ok := repaired[field]; !ok {
+					changed[field] = true
+				}
+			}
+		}
+	}
+
+	fields := make([]string, 0, len(changed))
+	for field := range changed {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return repaired, fields, nil
+}
+
+// acquire reserves a write-back for id unless one is in flight or the rate
+// limit is reached
+func (r *readRepairer) acquire(id string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.inFlight[id] {
+		r.deduplicated.Add(1)
+		return false
+	}
+	if now.Sub(r.windowStart) >= r.opts.WriteBackWindow || now.Before(r.windowStart) {
+		r.windowStart = now
+		r.windowCount = 0
+	}
+	if r.windowCount >= r.opts.MaxWriteBacks {
+		r.rateLimited.Add(1)
+		return false
+	}
+
+	r.windowCount++
+	r.inFlight[id] = true
+	return true
+}
+
+func (r *readRepairer) release(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.inFlight, id)
+}
+
+// writeBack stores a repaired document
+func (m *Model) writeBack(id string, doc map[string]interface{}) {
+	r := m.readRepair
+
+	err := func() error {
+		stored, err := m.codecs.encode(doc)
+		if err != nil {
+			return err
+		}
+		reqBody := map[string]interface{}{"data": stored}
+		resp, err := m.request("PUT", m.client.apiPath(m.collection, id), reqBody)
+		if err != nil {
+			return fmt.Errorf("read repair write-back failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if !isSuccess(resp.StatusCode) {
+			return responseError("read repair write-back", resp)
+		}
+		return nil
+	}()
+
+	if err != nil {
+		r.writeBackFailed.Add(1)
+	} else {
+		r.writtenBack.Add(1)
+	}
+	r.release(id)
+	if r.opts.OnWriteBack != nil {
+		r.opts.OnWriteBack(id, err)
+	}
+}
+
+func cloneDoc(doc map[string]interface{}) map[string]interface{} {
+	clone := make(map[string]interface{}, len(doc))
+	for field, value := range doc {
+		clone[field] = value
+	}
+	return clone
+}
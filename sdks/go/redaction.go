@@ -0,0 +1,170 @@
+package torm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithRedaction marks fields as sensitive, like ValidationRule.Sensitive:
+// their values are masked wherever the SDK shows document content to a
+// person, i.e. query strings and slow operation logs, conflict messages,
+// fidelity issues, dead-letter errors, redacting audit trails and
+// redacted exports
+func WithRedaction(fields ...string) CollectionOption {
+	return func(o *collectionOptions) {
+		o.redact = append(o.redact, fields...)
+	}
+}
+
+// WithRedaction marks fields as sensitive on top of the schema's
+// Sensitive rules; see the collection option
+func (m *Model) WithRedaction(fields ...string) *Model {
+	m.redact = append(m.redact, fields...)
+	return m
+}
+
+// MaskValue is the masked form of a sensitive value: RedactedValue with a
+// hint of the value's type, e.g. "***(string)". Nil stays nil.
+func MaskValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return RedactedValue + "(" + typeHint(value) + ")"
+}
+
+func typeHint(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	if _, ok := toFloat64(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// redactor masks the sensitive fields of documents. The zero value masks
+// nothing.
+type redactor struct {
+	fields map[string]bool
+}
+
+func newRedactor(schema map[string]ValidationRule, extra []string) redactor {
+	var r redactor
+	mark := func(field string) {
+		if r.fields == nil {
+			r.fields = make(map[string]bool)
+		}
+		r.fields[field] = true
+	}
+	for field, rules := range schema {
+		if rules.Sensitive {
+			mark(field)
+		}
+	}
+	for _, field := range extra {
+		mark(field)
+	}
+	return r
+}
+
+// redactor returns the model's sensitive fields
+func (m *Model) redactor() redactor {
+	return newRedactor(m.schema, m.redact)
+}
+
+func (c *Collection[T]) redactor() redactor {
+	return newRedactor(nil, c.options.redact)
+}
+
+// list returns the sensitive fields in name order
+func (r redactor) list() []string {
+	fields := make([]string, 0, len(r.fields))
+	for field := range r.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// doc returns a copy of doc with sensitive fields masked, or doc itself
+// when it has none
+func (r redactor) doc(doc map[string]interface{}) map[string]interface{} {
+	if doc == nil || !r.touches(doc) {
+		return doc
+	}
+	masked := make(map[string]interface{}, len(doc))
+	for field, value := range doc {
+		if r.fields[field] {
+			value = MaskValue(value)
+		}
+		masked[field] = value
+	}
+	return masked
+}
+
+func (r redactor) touches(doc map[string]interface{}) bool {
+	for field := range r.fields {
+		if _, ok := doc[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// text masks the sensitive values of docs wherever they appear in text,
+// such as a server message echoing a rejected value
+func (r redactor) text(text string, docs ...map[string]interface{}) string {
+	for _, doc := range docs {
+		for field := range r.fields {
+			value, ok := doc[field]
+			if !ok || value == nil {
+				continue
+			}
+			raw := fmt.Sprint(value)
+			if raw == "" {
+				continue
+			}
+			text = strings.ReplaceAll(text, raw, fmt.Sprint(MaskValue(value)))
+		}
+	}
+	return text
+}
+
+// redact masks the values of doc echoed by the server's message
+func (e *ConflictError) redact(r redactor, doc map[string]interface{}) *ConflictError {
+	e.Message = r.text(e.Message, doc)
+	if e.api != nil {
+		e.api.redact(r, doc)
+	}
+	return e
+}
+
+// redact masks the values of doc echoed anywhere in the server's response
+func (e *APIError) redact(r redactor, doc map[string]interface{}) *APIError {
+	if len(r.fields) == 0 {
+		return e
+	}
+	e.Message = r.text(e.Message, doc)
+	if e.RawBody != nil {
+		e.RawBody = []byte(r.text(string(e.RawBody), doc))
+	}
+	if e.Details != nil {
+		masked := make(map[string]interface{}, len(e.Details))
+		for key, value := range r.doc(e.Details) {
+			if text, ok := value.(string); ok {
+				value = r.text(text, doc)
+			}
+			masked[key] = value
+		}
+		e.Details = masked
+	}
+	return e
+}
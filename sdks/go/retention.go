@@ -0,0 +1,126 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy deletes documents once their timestamp field is older than MaxAge
+type RetentionPolicy struct {
+	Field     string                 // Timestamp field, RFC 3339 or Unix milliseconds
+	MaxAge    time.Duration          // Documents older than now - MaxAge are purged
+	Filter    map[string]interface{} // Optional equality filter limiting the policy
+	BatchSize int                    // Deletes per batch (default 100)
+	Progress  func(DeleteProgress)   // Called after every batch
+	// Concurrency deletes with an adaptive worker pool, see DeleteWhereOptions
+	Concurrency *AdaptiveConcurrency
+}
+
+// RetentionReport summarizes one retention run
+type RetentionReport struct {
+	Collection string
+	Cutoff     time.Time
+	DryRun     bool
+	DeleteReport
+}
+
+// RetentionOption configures a single retention run
+type RetentionOption func(*retentionRun)
+
+type retentionRun struct {
+	dryRun bool
+}
+
+// RetentionDryRun lists what a retention run would delete without deleting it
+func RetentionDryRun() RetentionOption {
+	return func(r *retentionRun) {
+		r.dryRun = true
+	}
+}
+
+// retentionTarget is a collection registered with its client for ApplyAllRetentions
+type retentionTarget interface {
+	ApplyRetention(ctx context.Context, opts ...RetentionOption) (*RetentionReport, error)
+}
+
+// WithRetention attaches a retention policy to the collection and registers
+// it with the client for ApplyAllRetentions
+func WithRetention(policy RetentionPolicy) CollectionOption {
+	return func(o *collectionOptions) {
+		o.retention = &policy
+	}
+}
+
+// ApplyRetention deletes the documents whose policy field is older than the
+// cutoff computed from the client's clock. Documents without a readable
+// timestamp are never deleted.
+func (c *Collection[T]) ApplyRetention(ctx context.Context, opts ...RetentionOption) (*RetentionReport, error) {
+	c = c.prioritizedBy(ctx)
+	policy := c.options.retention
+	if policy == nil {
+		return nil, fmt.Errorf("collection %s has no retention policy", c.collection)
+	}
+
+	run := &retentionRun{}
+	for _, opt := range opts {
+		opt(run)
+	}
+
+	report := &RetentionReport{
+		Collection: c.collection,
+		Cutoff:     c.client.now().Add(-policy.MaxAge),
+		DryRun:     run.dryRun,
+	}
+
+	deleted, err := c.DeleteWhere(ctx, policy.Filter, &DeleteWhereOptions{
+		BatchSize:   policy.BatchSize,
+		DryRun:      run.dryRun,
+		Progress:    policy.Progress,
+		Concurrency: policy.Concurrency,
+		Match: func(doc map[string]interface{}) bool {
+			at, ok := documentTime(doc, policy.Field)
+			return ok && at.Before(report.Cutoff)
+		},
+	})
+	if deleted != nil {
+		report.DeleteReport = *deleted
+	}
+	if err != nil {
+		return report, fmt.Errorf("retention for %s failed: %w", c.collection, err)
+	}
+
+	return report, nil
+}
+
+// ApplyAllRetentions applies the retention policy of every collection
+// created with WithRetention on this client. A failing collection does not
+// stop the others; their errors are joined.
+func (c *Client) ApplyAllRetentions(ctx context.Context, opts ...RetentionOption) ([]*RetentionReport, error) {
+	c.mu.Lock()
+	targets := append([]retentionTarget(nil), c.retentions...)
+	c.mu.Unlock()
+
+	reports := make([]*RetentionReport, 0, len(targets))
+	var errs []error
+	for _, target := range targets {
+		report, err := target.ApplyRetention(ctx, opts...)
+		if report != nil {
+			reports = append(reports, report)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return reports, errors.Join(errs...)
+}
+
+// registerRetention records a collection with a retention policy
+func (c *Client) registerRetention(target retentionTarget) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.retentions = append(c.retentions, target)
+}
@@ -0,0 +1,268 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RetryOptions configures how requests are retried. Only idempotent
+// methods (GET, HEAD, PUT, DELETE, OPTIONS) are retried, after network
+// errors, 429 and 5xx responses other than 501.
+type RetryOptions struct {
+	// MaxAttempts is the number of tries of a request, across all
+	// endpoints (default 1, no retries)
+	MaxAttempts int
+	// Backoff is the wait before retrying an endpoint already tried by the
+	// call, doubled on each retry up to MaxBackoff (default 100ms). Moving
+	// on to an endpoint not tried yet does not wait. Canceling the
+	// request's context ends the wait.
+	Backoff    time.Duration
+	MaxBackoff time.Duration // Default 5s
+	// MaxHistory caps the attempts kept on errors; the most recent are
+	// kept (default 10)
+	MaxHistory int
+	// OnRetry is called with each failed attempt that is about to be
+	// retried, e.g. to count retries in metrics
+	OnRetry func(AttemptInfo)
+}
+
+// RetryStats counts the requests sent with retries enabled
+type RetryStats struct {
+	MaxAttempts int   `json:"max_attempts"`
+	Requests    int64 `json:"requests"`  // Requests sent through the retry loop
+	Retries     int64 `json:"retries"`   // Attempts after the first
+	Exhausted   int64 `json:"exhausted"` // Requests that failed on every attempt
+}
+
+// retryCounters backs RetryStats
+type retryCounters struct {
+	requests  atomic.Int64
+	retries   atomic.Int64
+	exhausted atomic.Int64
+}
+
+func (c *Client) retryStats() RetryStats {
+	return RetryStats{
+		MaxAttempts: c.retry.MaxAttempts,
+		Requests:    c.retries.requests.Load(),
+		Retries:     c.retries.retries.Load(),
+		Exhausted:   c.retries.exhausted.Load(),
+	}
+}
+
+// AttemptInfo describes one try of a request
+type AttemptInfo struct {
+	Number         int // 1 for the first try
+	StartedAt      time.Time
+	Duration       time.Duration
+	Endpoint       string        // Base URL the attempt went to
+	StatusOrErr    string        // Response status, or the transport error
+	BackoffApplied time.Duration // Waited before this attempt
+}
+
+func (a AttemptInfo) String() string {
+	line := fmt.Sprintf("attempt %d at %s to %s: %s in %s", a.Number, a.StartedAt.Format("15:04:05.000"), a.Endpoint, a.StatusOrErr, a.Duration)
+	if a.BackoffApplied > 0 {
+		line += fmt.Sprintf(" after %s backoff", a.BackoffApplied)
+	}
+	return line
+}
+
+// RequestError is returned when all attempts of a request failed without a
+// response, such as on timeouts and refused connections
+type RequestError struct {
+	Method   string
+	Path     string
+	Err      error         // The error of the last attempt
+	Attempts []AttemptInfo // Oldest first, capped by RetryOptions.MaxHistory
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s %s failed%s: %v", e.Method, e.Path, summarizeAttempts(e.Attempts), e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// AttemptHistory returns the attempts recorded on err, nil when the
+// request was not retried
+func AttemptHistory(err error) []AttemptInfo {
+	var api *APIError
+	if errors.As(err, &api) {
+		return api.Attempts
+	}
+	var req *RequestError
+	if errors.As(err, &req) {
+		return req.Attempts
+	}
+	return nil
+}
+
+// FormatAttempts renders the attempts recorded on err one per line, empty
+// when the request was not retried
+func FormatAttempts(err error) string {
+	attempts := AttemptHistory(err)
+	lines := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		lines[i] = attempt.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// summarizeAttempts is the compact form of a history used in Error
+func summarizeAttempts(attempts []AttemptInfo) string {
+	if len(attempts) == 0 {
+		return ""
+	}
+	endpoints := map[string]bool{}
+	for _, attempt := range attempts {
+		endpoints[attempt.Endpoint] = true
+	}
+	summary := fmt.Sprintf(" after %d attempts", attempts[len(attempts)-1].Number)
+	if len(endpoints) > 1 {
+		summary += fmt.Sprintf(" on %d endpoints", len(endpoints))
+	}
+	return summary
+}
+
+// retrying reports whether a request may be tried more than once
+func (c *Client) retrying(req *http.Request) bool {
+	return c.retry.MaxAttempts > 1 && (req.Body == nil || req.GetBody != nil) && idempotent(req)
+}
+
+// idempotent reports whether req has a method that may be sent twice
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// doWithRetry sends req, retrying failed attempts on the next endpoint.
+// The history is only built once an attempt fails. Attempts start at the
+// active endpoint and count against MaxAttempts whichever endpoint they
+// go to, so failover does not multiply them.
+func (c *Client) doWithRetry(req *http.Request, path string) (*http.Response, error) {
+	opts := c.retry
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Second
+	}
+	maxHistory := opts.MaxHistory
+	if maxHistory <= 0 {
+		maxHistory = 10
+	}
+
+	c.retries.requests.Add(1)
+	order := c.failover.order(len(c.endpoints), c.now())
+	var history []AttemptInfo
+	var wait time.Duration
+	for number := 1; ; number++ {
+		release := func() {}
+		if number > 1 {
+			var err error
+			if release, err = c.readmit(req, number == opts.MaxAttempts); err != nil {
+				return nil, err
+			}
+			c.retries.retries.Add(1)
+		}
+		countRetries(req, number-1)
+		index := order[(number-1)%len(order)]
+		endpoint := c.endpoints[index]
+		attempt, err := c.attemptRequest(req, endpoint, path)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		started := time.Now()
+		resp, err := c.do(attempt)
+		release()
+		info := AttemptInfo{Number: number, StartedAt: started, Duration: time.Since(started), Endpoint: endpoint, BackoffApplied: wait}
+		if err != nil {
+			info.StatusOrErr = err.Error()
+		} else {
+			if !retryableStatus(resp.StatusCode) {
+				if !failoverStatus(resp.StatusCode) {
+					c.servedBy(index)
+				}
+				return resp, nil
+			}
+			info.StatusOrErr = resp.Status
+		}
+
+		if len(history) == maxHistory {
+			history = append(history[:0], history[1:]...)
+		}
+		history = append(history, info)
+
+		if number == opts.MaxAttempts {
+			c.retries.exhausted.Add(1)
+			if err != nil {
+				return nil, fmt.Errorf("request failed: %w", &RequestError{Method: req.Method, Path: path, Err: err, Attempts: history})
+			}
+			apiErr := responseError("", resp)
+			resp.Body.Close()
+			apiErr.Method, apiErr.Path, apiErr.Attempts = req.Method, path, history
+			return nil, apiErr
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if opts.OnRetry != nil {
+			_ = protect("retry callback", "", "", func() { opts.OnRetry(info) })
+		}
+
+		// Endpoints not tried yet are tried at once
+		wait = 0
+		if number >= len(c.endpoints) {
+			wait = backoff
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			select {
+			case <-time.After(wait):
+			case <-req.Context().Done():
+				return nil, fmt.Errorf("request failed: %w", &RequestError{Method: req.Method, Path: path, Err: req.Context().Err(), Attempts: history})
+			}
+		}
+	}
+}
+
+// attemptRequest copies req for one attempt against endpoint
+func (c *Client) attemptRequest(req *http.Request, endpoint, path string) (*http.Request, error) {
+	attempt, err := http.NewRequestWithContext(req.Context(), req.Method, endpoint+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	attempt.Header = req.Header.Clone()
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to replay request body: %w", err)
+		}
+		attempt.Body = body
+		attempt.GetBody = req.GetBody
+		attempt.ContentLength = req.ContentLength
+	}
+	return attempt, nil
+}
+
+// retryableStatus reports statuses worth another try. 501 means the server
+// lacks a feature, which capability detection relies on.
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || (status >= 500 && status != http.StatusNotImplemented)
+}
@@ -0,0 +1,134 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrSyntheticField is returned by strict sanitizers for writes that carry
+// fields the sanitizer would otherwise strip
+var ErrSyntheticField = errors.New("torm: document carries synthetic fields")
+
+// SanitizeOptions configures the write-sanitization pass. Documents read
+// back can carry fields that are not stored data: virtuals computed on
+// read, populated references embedded next to their key, cache metadata
+// and audit stamps. Saving such a document would persist them, so the pass
+// removes them from the top level of written payloads before validation,
+// slugs and guards see them. The id and the TypeField discriminator are
+// never removed.
+type SanitizeOptions struct {
+	Fields   []string // Exact names, e.g. virtuals such as "fullName"
+	Prefixes []string // Internal fields (default "_"; an empty slice keeps them)
+	Suffixes []string // Populated references, e.g. "userId_doc" (default "_doc"; an empty slice keeps them)
+	// Strict fails the write with ErrSyntheticField instead of stripping,
+	// so callers notice documents passed through by accident
+	Strict bool
+}
+
+// sanitizer strips synthetic fields from written payloads. A nil sanitizer
+// strips nothing.
+type sanitizer struct {
+	opts   SanitizeOptions
+	fields map[string]bool
+}
+
+func newSanitizer(opts SanitizeOptions) *sanitizer {
+	if opts.Prefixes == nil {
+		opts.Prefixes = []string{"_"}
+	}
+	if opts.Suffixes == nil {
+		opts.Suffixes = []string{"_doc"}
+	}
+	s := &sanitizer{opts: opts, fields: make(map[string]bool, len(opts.Fields))}
+	for _, field := range opts.Fields {
+		s.fields[field] = true
+	}
+	return s
+}
+
+// WithSanitizer strips synthetic fields from the payloads of Create, Save,
+// Upsert, Update and UpdateWithRetry, or rejects them in strict mode.
+// Create, Upsert and Update put stripped values back on the document they
+// return.
+func WithSanitizer(opts SanitizeOptions) CollectionOption {
+	return func(o *collectionOptions) {
+		o.sanitizer = newSanitizer(opts)
+	}
+}
+
+// WithSanitizer strips synthetic fields from the documents passed to Create
+// and Update, or rejects them in strict mode. The caller's map is left as
+// is, and the returned document gets the stripped values back.
+func (m *Model) WithSanitizer(opts SanitizeOptions) *Model {
+	m.sanitizer = newSanitizer(opts)
+	return m
+}
+
+// synthetic reports whether a top-level field is not stored data
+func (s *sanitizer) synthetic(field string) bool {
+	if field == "id" || field == TypeField {
+		return false
+	}
+	if s.fields[field] {
+		return true
+	}
+	for _, prefix := range s.opts.Prefixes {
+		if prefix != "" && strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	for _, suffix := range s.opts.Suffixes {
+		if suffix != "" && strings.HasSuffix(field, suffix) && len(field) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// strip returns doc without its synthetic fields, and those fields. doc
+// itself is not modified.
+func (s *sanitizer) strip(collection string, doc map[string]interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	if s == nil {
+		return doc, nil, nil
+	}
+
+	var found []string
+	for field := range doc {
+		if s.synthetic(field) {
+			found = append(found, field)
+		}
+	}
+	if len(found) == 0 {
+		return doc, nil, nil
+	}
+	sort.Strings(found)
+	if s.opts.Strict {
+		return nil, nil, fmt.Errorf("%w: %s in write to %s", ErrSyntheticField, strings.Join(found, ", "), collection)
+	}
+
+	clean := make(map[string]interface{}, len(doc)-len(found))
+	stripped := make(map[string]interface{}, len(found))
+	for field, value := range doc {
+		if s.synthetic(field) {
+			stripped[field] = value
+		} else {
+			clean[field] = value
+		}
+	}
+	return clean, stripped, nil
+}
+
+// restore puts stripped fields back on a written document, keeping any
+// value the server returned for them
+func restore(doc, stripped map[string]interface{}) {
+	if doc == nil {
+		return
+	}
+	for field, value := range stripped {
+		if _, ok := doc[field]; !ok {
+			doc[field] = value
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package torm
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SlowOperation describes an operation that took longer than its slow
+// threshold
+type SlowOperation struct {
+	Operation  Operation
+	Collection string
+	Duration   time.Duration
+	Threshold  time.Duration
+	Query      string        // The query as QueryBuilder.String shows it, empty for other operations
+	Filters    int           // Filters of a query or find, 0 for other operations
+	Limit      int           // Limit of a query, 0 when unlimited
+	Attempts   int           // Tries, more than 1 when the operation retried
+	Err        error         // Nil when the operation succeeded
+	History    []AttemptInfo // Request attempts recorded on Err, see AttemptHistory
+	At         time.Time
+}
+
+// WithSlowThreshold overrides ClientOptions.SlowThreshold for the
+// collection. Zero disables the slow-operation log for it.
+func WithSlowThreshold(d time.Duration) CollectionOption {
+	return func(o *collectionOptions) {
+		o.slowThreshold = &d
+	}
+}
+
+// WithSlowThreshold overrides ClientOptions.SlowThreshold for the model, as
+// the collection option does
+func (m *Model) WithSlowThreshold(d time.Duration) *Model {
+	m.slowThreshold = &d
+	return m
+}
+
+// SlowOperations returns the most recent slow operations, oldest first
+func (c *Client) SlowOperations() []SlowOperation {
+	return c.slowLog.list()
+}
+
+// slowLog keeps the last slow operations in a ring buffer
+type slowLog struct {
+	mu      sync.Mutex
+	entries []SlowOperation
+	next    int
+	full    bool
+}
+
+func newSlowLog(size int) *slowLog {
+	if size <= 0 {
+		size = 100
+	}
+	return &slowLog{entries: make([]SlowOperation, size)}
+}
+
+func (l *slowLog) add(op SlowOperation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = op
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+func (l *slowLog) list() []SlowOperation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]SlowOperation(nil), l.entries[:l.next]...)
+	}
+	ops := make([]SlowOperation, 0, len(l.entries))
+	ops = append(ops, l.entries[l.next:]...)
+	return append(ops, l.entries[:l.next]...)
+}
+
+// opTimer times one operation for the slow-operation log, names it in
+// the operation's error and traces it
+type opTimer struct {
+	client    *Client
+	threshold time.Duration
+	op        SlowOperation
+	start     time.Time
+	describe  func() string // Query string, nil for other operations
+	name      string        // Method named by OpError, see as
+	id        string        // Document named by OpError, see target
+
+	// Tracing, see traced
+	span    Span
+	spanCtx context.Context
+	header  http.Header  // Propagation headers of the span
+	status  atomic.Int32 // Last response status, see sent
+	count   func() int   // Documents returned, see counting
+}
+
+// opNames are the methods OpError names for each operation
+var opNames = map[Operation]string{
+	OpCreate:   "Create",
+	OpFind:     "Find",
+	OpFindByID: "FindByID",
+	OpQuery:    "Query",
+	OpSave:     "Save",
+	OpUpdate:   "Update",
+	OpDelete:   "Delete",
+	OpCount:    "Count",
+}
+
+// timeOp starts timing an operation. override is the collection's
+// threshold, nil to use the client's.
+func (c *Client) timeOp(op Operation, collection string, override *time.Duration) *opTimer {
+	threshold := c.slowThreshold
+	if override != nil {
+		threshold = *override
+	}
+	return &opTimer{
+		client:    c,
+		threshold: threshold,
+		op:        SlowOperation{Operation: op, Collection: collection, Attempts: 1},
+		start:     time.Now(),
+		name:      opNames[op],
+	}
+}
+
+// timeOp starts timing an operation on the collection
+func (c *Collection[T]) timeOp(op Operation) *opTimer {
+	return c.client.timeOp(op, c.collection, c.options.slowThreshold)
+}
+
+// query records the shape of a query, described with its String when
+// the operation turns out slow
+func (t *opTimer) query(qb *QueryBuilder) *opTimer {
+	t.op.Filters = len(qb.filters)
+	if qb.limitVal != nil {
+		t.op.Limit = *qb.limitVal
+	}
+	t.describe = qb.String
+	return t
+}
+
+// target records the document the operation acts on
+func (t *opTimer) target(id string) *opTimer {
+	t.id = id
+	return t
+}
+
+// as names the method when it differs from the operation's, e.g. "Patch"
+// for an update
+func (t *opTimer) as(name string) *opTimer {
+	t.name = name
+	return t
+}
+
+// end wraps the operation's error in an *OpError and reports the
+// operation when it was slow. It takes the error by pointer so it can be
+// deferred.
+func (t *opTimer) end(err *error) {
+	if *err != nil {
+		target := OpError{Op: t.name, Collection: t.op.Collection, ID: t.id}
+		if t.describe != nil {
+			target.Query = t.describe()
+		}
+		*err = t.client.wrapOp(*err, target)
+	}
+	t.endSpan(*err)
+	duration := time.Since(t.start)
+	if t.threshold <= 0 || duration < t.threshold {
+		return
+	}
+
+	c := t.client
+	op := t.op
+	op.Duration = duration
+	op.Threshold = t.threshold
+	op.At = c.now()
+	if err != nil && *err != nil {
+		op.Err = *err
+		op.History = AttemptHistory(op.Err)
+	}
+	if t.describe != nil {
+		op.Query = t.describe()
+	}
+	c.slowLog.add(op)
+
+	if c.logger != nil {
+		attrs := []slog.Attr{
+			slog.String("operation", string(op.Operation)),
+			slog.String("collection", op.Collection),
+			slog.Duration("duration", op.Duration),
+			slog.Duration("threshold", op.Threshold),
+			slog.Int("attempts", op.Attempts),
+			slog.Int("filters", op.Filters),
+			slog.Int("limit", op.Limit),
+		}
+		if op.Query != "" {
+			attrs = append(attrs, slog.String("query", op.Query))
+		}
+		if op.Err != nil {
+			attrs = append(attrs, slog.String("error", op.Err.Error()))
+		}
+		if len(op.History) > 0 {
+			attrs = append(attrs, slog.String("history", strings.ReplaceAll(FormatAttempts(op.Err), "\n", "; ")))
+		}
+		c.logger.LogAttrs(context.Background(), slog.LevelWarn, "torm: slow operation", attrs...)
+	}
+	if c.onSlowOperation != nil {
+		_ = protect("slow operation callback", op.Collection, "", func() { c.onSlowOperation(op) })
+	}
+}
@@ -0,0 +1,212 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SlugOptions configures WithSlug
+type SlugOptions struct {
+	MaxAttempts int  // Candidates tried before giving up: slug, slug-2, ... (default 10)
+	MaxLength   int  // Longest base slug, before any counter (default 80)
+	OnUpdate    bool // Regenerate the slug when an update changes the source field
+}
+
+// SlugExhaustedError is returned when every candidate slug is taken
+type SlugExhaustedError struct {
+	Field    string // Target field
+	Base     string // Slug generated from the source
+	Attempts int
+}
+
+func (e *SlugExhaustedError) Error() string {
+	return fmt.Sprintf("torm: no unique slug for field '%s' from '%s' after %d attempts", e.Field, e.Base, e.Attempts)
+}
+
+// slugRule generates target from source
+type slugRule struct {
+	source string
+	target string
+	opts   SlugOptions
+}
+
+// WithSlug fills targetField on create with a URL slug of sourceField:
+// lowercased, transliterated to ASCII where possible and hyphenated. A
+// taken slug gets -2, -3 and so on appended. A target the caller already
+// set is kept as it is.
+func WithSlug(sourceField, targetField string, opts SlugOptions) CollectionOption {
+	return func(o *collectionOptions) {
+		o.slugs = append(o.slugs, slugRule{source: sourceField, target: targetField, opts: opts})
+	}
+}
+
+// WithSlug adds a slug rule to a model, as the collection option does
+func (m *Model) WithSlug(sourceField, targetField string, opts SlugOptions) *Model {
+	m.slugs = append(m.slugs, slugRule{source: sourceField, target: targetField, opts: opts})
+	return m
+}
+
+// Exists reports whether any document matches the query. It limits the
+// query to one document.
+func (qb *QueryBuilder) Exists() (bool, error) {
+	docs, err := qb.Limit(1).Exec()
+	if err != nil {
+		return false, err
+	}
+	return len(docs) > 0, nil
+}
+
+// applySlugs sets the slug fields of a document being written. For updates
+// selfID is the document's id, excluded from collision checks, and previous
+// its stored form (nil for creates).
+func applySlugs(rules []slugRule, query func() *QueryBuilder, doc map[string]interface{}, selfID string, previous map[string]interface{}) error {
+	for _, rule := range rules {
+		source, ok := doc[rule.source].(string)
+		if !ok {
+			continue
+		}
+
+		if previous == nil {
+			if target, _ := doc[rule.target].(string); target != "" {
+				continue
+			}
+		} else if !rule.opts.OnUpdate || previous[rule.source] == source {
+			continue
+		}
+
+		maxLength := rule.opts.MaxLength
+		if maxLength <= 0 {
+			maxLength = 80
+		}
+		base := slugify(source, maxLength)
+		if base == "" {
+			return fmt.Errorf("%w: field '%s' has nothing to build a slug from", ErrValidation, rule.source)
+		}
+
+		slug, err := uniqueSlug(rule, query, base, selfID)
+		if err != nil {
+			return err
+		}
+		doc[rule.target] = slug
+	}
+	return nil
+}
+
+// applySlugs sets the slug fields of a document saved under id, comparing
+// against the stored copy when the document already exists
+func (c *Collection[T]) applySlugs(id string, doc map[string]interface{}) error {
+	if id == "" {
+		return applySlugs(c.options.slugs, c.model().Query, doc, "", nil)
+	}
+	if !needsPrevious(c.options.slugs, doc) {
+		return nil
+	}
+
+	previous, err := c.model().FindByID(id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if previous == nil {
+		previous = map[string]interface{}{}
+	}
+	return applySlugs(c.options.slugs, c.model().Query, doc, id, previous)
+}
+
+// needsPrevious reports whether an update must load the stored document to
+// tell whether a slug source changed
+func needsPrevious(rules []slugRule, doc map[string]interface{}) bool {
+	for _, rule := range rules {
+		if _, ok := doc[rule.source]; ok && rule.opts.OnUpdate {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueSlug(rule slugRule, query func() *QueryBuilder, base, selfID string) (string, error) {
+	attempts := rule.opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = 10
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		candidate := base
+		if attempt > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, attempt)
+		}
+
+		qb := query().Where(rule.target, candidate)
+		if selfID != "" {
+			qb.Filter("id", Ne, selfID)
+		}
+		taken, err := qb.Exists()
+		if err != nil {
+			return "", fmt.Errorf("slug check failed: %w", err)
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+
+	return "", &SlugExhaustedError{Field: rule.target, Base: base, Attempts: attempts}
+}
+
+// slugify lowercases, transliterates and hyphenates a string. Letters
+// without an ASCII form are kept as they are.
+func slugify(s string, maxLength int) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		part, mapped := transliterations[r]
+		switch {
+		case mapped:
+			if part == "" {
+				continue
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			part = string(r)
+		case unicode.Is(unicode.Mn, r):
+			continue
+		default:
+			hyphen = b.Len() > 0
+			continue
+		}
+
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(part)
+	}
+
+	slug := b.String()
+	if len(slug) > maxLength {
+		slug = slug[:maxLength]
+		// Never cut a multi-byte letter in half
+		for !utf8.ValidString(slug) {
+			slug = slug[:len(slug)-1]
+		}
+	}
+	return strings.Trim(slug, "-")
+}
+
+// transliterations maps common non-ASCII letters to ASCII
+var transliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ĉ': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "oe", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "ue", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ы': "y", 'э': "e", 'ю': "yu", 'я': "ya",
+	'ъ': "", 'ь': "",
+}
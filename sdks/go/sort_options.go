@@ -0,0 +1,178 @@
+package torm
+
+import "strings"
+
+// CapabilitySortOptions is the server honouring the null placement and
+// case options of sort keys
+const CapabilitySortOptions Capability = "sort_options"
+
+// SortDefaults are the options of a collection's sort keys that do not set
+// their own, see WithSortDefaults. Without them, missing and null values
+// sort before every other value in ascending order and after them in
+// descending order, and strings compare by case.
+type SortDefaults struct {
+	NullsFirst bool
+	NullsLast  bool
+	// CaseInsensitive applies to the fields the schema declares as "str",
+	// or to every field of a model without a schema
+	CaseInsensitive bool
+}
+
+// WithSortDefaults sets the null placement and case options used by sort
+// keys of the collection that leave them unset, including the default
+// order
+func WithSortDefaults(defaults SortDefaults) CollectionOption {
+	return func(o *collectionOptions) {
+		o.sortDefaults = &defaults
+	}
+}
+
+// WithSortDefaults sets the sort options of the model, as the collection
+// option does
+func (m *Model) WithSortDefaults(defaults SortDefaults) *Model {
+	m.sortDefaults = &defaults
+	return m
+}
+
+// SortWith sets the sort key with its options, e.g. to place documents
+// missing the field last:
+//
+//	qb.SortWith(QuerySort{Field: "lastLoginAt", Order: Desc, NullsLast: true})
+func (qb *QueryBuilder) SortWith(key QuerySort) *QueryBuilder {
+	qb.sortField = &key
+	return qb
+}
+
+// SortWith sets the sort key with its options, see QueryBuilder.SortWith
+func (q *TypedQuery[T]) SortWith(key QuerySort) *TypedQuery[T] {
+	q.qb.SortWith(key)
+	return q
+}
+
+// hasOptions reports whether the key sets a null placement or case option
+func (key QuerySort) hasOptions() bool {
+	return key.NullsFirst || key.NullsLast || key.CaseInsensitive
+}
+
+// describe renders the key for query strings, e.g. "name ASC NULLS LAST
+// NOCASE"
+func (key QuerySort) describe() string {
+	return key.Field + " " + strings.ToUpper(string(key.Order)) + key.options()
+}
+
+// options renders the key's options, empty when it sets none
+func (key QuerySort) options() string {
+	var b strings.Builder
+	switch {
+	case key.NullsFirst:
+		b.WriteString(" NULLS FIRST")
+	case key.NullsLast:
+		b.WriteString(" NULLS LAST")
+	}
+	if key.CaseInsensitive {
+		b.WriteString(" NOCASE")
+	}
+	return b.String()
+}
+
+// resolveSort fills the options a key leaves unset from the sort defaults
+func (qb *QueryBuilder) resolveSort(key QuerySort) QuerySort {
+	defaults := qb.sortDefaults
+	if defaults == nil {
+		return key
+	}
+	if !key.NullsFirst && !key.NullsLast {
+		key.NullsFirst, key.NullsLast = defaults.NullsFirst, defaults.NullsLast && !defaults.NullsFirst
+	}
+	if defaults.CaseInsensitive && (qb.textFields == nil || qb.textFields[key.Field]) {
+		key.CaseInsensitive = true
+	}
+	return key
+}
+
+// serverSort is the key as sent to the server, without the options a
+// server not known to support them would not expect
+func (qb *QueryBuilder) serverSort(key QuerySort) QuerySort {
+	key = qb.resolveSort(key)
+	if key.hasOptions() && !qb.sortOptionsSupported() {
+		key.NullsFirst, key.NullsLast, key.CaseInsensitive = false, false, false
+	}
+	return key
+}
+
+// localSort reports whether the sort keys carry options the server is not
+// known to honour, so its order cannot be relied on to page the results
+func (qb *QueryBuilder) localSort() bool {
+	for _, key := range qb.sortKeys() {
+		if key.hasOptions() {
+			return !qb.sortOptionsSupported()
+		}
+	}
+	return false
+}
+
+// sortOptionsSupported reports whether the server has been seen or has
+// advertised to support sort options. It never asks the server, so
+// building a query stays offline.
+func (qb *QueryBuilder) sortOptionsSupported() bool {
+	if supported, known := qb.client.capabilities.lookup(CapabilitySortOptions); known {
+		return supported
+	}
+	advertised, _ := qb.client.capabilities.advertisedSet()
+	return advertised[CapabilitySortOptions]
+}
+
+// compareNulls places missing and null values by the key's options, ok is
+// false when neither value is null
+func compareNulls(key QuerySort, a, b interface{}) (cmp int, ok bool) {
+	aNull, bNull := a == nil, b == nil
+	switch {
+	case !aNull && !bNull:
+		return 0, false
+	case aNull && bNull:
+		return 0, true
+	}
+	// Nulls sort low unless placed explicitly
+	first := key.NullsFirst || (!key.NullsLast && key.Order != Desc)
+	if aNull == first {
+		return -1, true
+	}
+	return 1, true
+}
+
+// compareFolded compares two strings ignoring case, then by case so equal
+// strings of different case keep a fixed order
+func compareFolded(a, b string) int {
+	if cmp := strings.Compare(strings.ToLower(a), strings.ToLower(b)); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a, b)
+}
+
+// textFields lists the schema fields declared as strings, nil without a
+// schema
+func textFields(schema map[string]ValidationRule) map[string]bool {
+	if schema == nil {
+		return nil
+	}
+	fields := make(map[string]bool)
+	for field, rules := range schema {
+		if rules.Type == "str" {
+			fields[field] = true
+		}
+	}
+	return fields
+}
+
+// stringPair returns both values when they are strings that compare as
+// text, not as numbers
+func stringPair(a, b interface{}) (string, string, bool) {
+	aStr, aOk := a.(string)
+	bStr, bOk := b.(string)
+	if !aOk || !bOk {
+		return "", "", false
+	}
+	_, aNumeric := toFloat64(aStr)
+	_, bNumeric := toFloat64(bStr)
+	return aStr, bStr, !aNumeric || !bNumeric
+}
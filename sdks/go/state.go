@@ -0,0 +1,144 @@
+package torm
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientState is the client's view of the backend's health
+type ClientState int
+
+const (
+	StateHealthy  ClientState = iota // Requests succeed
+	StateDegraded                    // Some consecutive requests failed
+	StateDown                        // Requests keep failing
+)
+
+func (s ClientState) String() string {
+	switch s {
+	case StateHealthy:
+		return "healthy"
+	case StateDegraded:
+		return "degraded"
+	case StateDown:
+		return "down"
+	}
+	return "unknown"
+}
+
+// HealthOptions tunes how the client derives its state from request outcomes
+type HealthOptions struct {
+	DegradedAfter int           // Consecutive failures before Degraded (default 1)
+	DownAfter     int           // Consecutive failures before Down (default 5)
+	Debounce      time.Duration // How long a new state must hold before it is reported (default 0)
+}
+
+// healthTracker derives the client state from consecutive failures. A new
+// state is committed once it has been observed for the debounce period, so
+// a flapping backend does not produce a burst of transitions.
+type healthTracker struct {
+	opts HealthOptions
+
+	mu           sync.Mutex
+	failures     int
+	state        ClientState
+	pending      ClientState
+	pendingSince time.Time
+	listeners    []func(old, new ClientState)
+}
+
+func newHealthTracker(opts HealthOptions) *healthTracker {
+	if opts.DegradedAfter <= 0 {
+		opts.DegradedAfter = 1
+	}
+	if opts.DownAfter < opts.DegradedAfter {
+		opts.DownAfter = opts.DegradedAfter + 4
+	}
+	return &healthTracker{opts: opts}
+}
+
+// OnStateChange registers a callback for state transitions. Callbacks run
+// synchronously, in registration order, on the goroutine whose request
+// completed the transition.
+func (c *Client) OnStateChange(fn func(old, new ClientState)) {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	c.health.listeners = append(c.health.listeners, fn)
+}
+
+// State returns the client's current view of the backend
+func (c *Client) State() ClientState {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	return c.health.state
+}
+
+// MonitorHealth probes the server's health endpoint every interval until ctx
+// is done, so the state follows the backend while the application is idle
+func (c *Client) MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.ProbeHealth(ctx)
+		}
+	}
+}
+
+// ProbeHealth checks the server's health endpoint once, failing over
+// between BaseURLs, and records the outcome like any other request
+func (c *Client) ProbeHealth(ctx context.Context) ClientState {
+	if resp, err := c.requestRoot(ctx, c.rootPath("/health")); err == nil {
+		resp.Body.Close()
+	}
+	return c.State()
+}
+
+// observe records the outcome of a request. Transport errors, 5xx and 429
+// count as failures; any other response shows the backend is up.
+func (c *Client) observe(status int, err error) {
+	failed := err != nil || status >= 500 || status == http.StatusTooManyRequests
+	c.health.record(failed, c.now())
+	c.breaker.record(failed, c.now())
+}
+
+func (h *healthTracker) record(failed bool, now time.Time) {
+	h.mu.Lock()
+	if failed {
+		h.failures++
+	} else {
+		h.failures = 0
+	}
+
+	observed := StateHealthy
+	switch {
+	case h.failures >= h.opts.DownAfter:
+		observed = StateDown
+	case h.failures >= h.opts.DegradedAfter:
+		observed = StateDegraded
+	}
+
+	if observed != h.pending {
+		h.pending = observed
+		h.pendingSince = now
+	}
+	if h.pending == h.state || now.Sub(h.pendingSince) < h.opts.Debounce {
+		h.mu.Unlock()
+		return
+	}
+
+	old, current := h.state, h.pending
+	h.state = current
+	listeners := append([]func(old, new ClientState){}, h.listeners...)
+	h.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(old, current)
+	}
+}
@@ -0,0 +1,144 @@
+package torm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoStatusLocation is returned by WriteResult.Wait when the server
+// accepted a write without saying where to poll for its completion
+var ErrNoStatusLocation = errors.New("torm: accepted write has no status location")
+
+// ErrForeignStatusLocation is returned by WriteResult.Wait when the status
+// location is not under one of the client's base URLs. Polling it would
+// send the client's credentials to another server.
+var ErrForeignStatusLocation = errors.New("torm: status location outside the client's base URLs")
+
+// WriteResult is the outcome of a write along with its HTTP status. Any
+// 2xx status is a success; 202 Accepted means the server queued the write.
+type WriteResult struct {
+	Status   int
+	Data     map[string]interface{} // Response document; empty for 202 and 204
+	Accepted bool                   // The write was queued (202), see Wait
+	Location string                 // Status URL of an accepted write, from the Location header
+
+	client *Client
+	codecs codecChain
+}
+
+// isSuccess reports whether a status is 2xx
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
+
+// hasDocument reports whether a successful response carries a document to
+// decode. 202 bodies describe the queued job, not the document.
+func hasDocument(status int, body []byte) bool {
+	return status != http.StatusAccepted && status != http.StatusNoContent && len(bytes.TrimSpace(body)) > 0
+}
+
+// writeResult builds the result of a successful write response
+func (m *Model) writeResult(resp *http.Response, data map[string]interface{}) *WriteResult {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return &WriteResult{
+		Status:   resp.StatusCode,
+		Data:     data,
+		Accepted: resp.StatusCode == http.StatusAccepted,
+		Location: resp.Header.Get("Location"),
+		client:   m.client,
+		codecs:   m.codecs,
+	}
+}
+
+// Wait polls the Location of an accepted write until the server stops
+// answering 202, and returns the document it then reports. Retry-After
+// overrides interval (default 1s). The Location must lie under one of the
+// client's base URLs; polls are sent like any other request, failing over
+// between them. Results that were not accepted are returned as they are.
+func (r *WriteResult) Wait(ctx context.Context, interval time.Duration) (map[string]interface{}, error) {
+	if !r.Accepted {
+		return r.Data, nil
+	}
+	if r.Location == "" {
+		return nil, ErrNoStatusLocation
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	path, err := r.client.statusPath(r.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		resp, err := r.client.requestWithContext(ctx, http.MethodGet, path, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("wait failed: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusAccepted:
+			delay := interval
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+				delay = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		case isSuccess(resp.StatusCode):
+			if !hasDocument(resp.StatusCode, body) {
+				return map[string]interface{}{}, nil
+			}
+			var result map[string]interface{}
+			if err := json.Unmarshal(body, &result); err != nil {
+				return nil, fmt.Errorf("failed to decode response: %w", err)
+			}
+			if data, ok := result["data"].(map[string]interface{}); ok {
+				return r.codecs.decode(data)
+			}
+			return r.codecs.decode(result)
+		default:
+			return nil, responseError("wait", resp)
+		}
+	}
+}
+
+// statusPath returns the path of a status location relative to the base
+// URL it lies under, so it can be sent to any of the client's endpoints
+func (c *Client) statusPath(location string) (string, error) {
+	target, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid status location %q: %w", location, err)
+	}
+	base, err := url.Parse(c.BaseURL + "/")
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+	resolved := base.ResolveReference(target).String()
+	for _, endpoint := range c.endpoints {
+		path, under := strings.CutPrefix(resolved, strings.TrimSuffix(endpoint, "/"))
+		if under && (path == "" || path[0] == '/' || path[0] == '?') {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrForeignStatusLocation, redactEndpoint(resolved))
+}
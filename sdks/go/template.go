@@ -0,0 +1,223 @@
+package torm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ErrUnknownTemplate is returned when creating from a template nobody defined
+var ErrUnknownTemplate = errors.New("torm: unknown template")
+
+// templateKeyPrefix names the keys holding templates:
+// "torm:template:{collection}:{name}" holds the versioned document and
+// the same key with ":version" appended holds only its version, so clients
+// can check for updates without fetching the document.
+const templateKeyPrefix = "torm:template"
+
+// Template is a stored base document
+type Template struct {
+	Name    string
+	Version int
+	Doc     map[string]interface{}
+}
+
+// templateCache holds the templates a collection has fetched
+type templateCache struct {
+	mu        sync.Mutex
+	templates map[string]*Template
+}
+
+func (tc *templateCache) get(name string) *Template {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.templates[name]
+}
+
+func (tc *templateCache) put(template *Template) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.templates == nil {
+		tc.templates = make(map[string]*Template)
+	}
+	tc.templates[template.Name] = template
+}
+
+// DefineTemplate stores base as the named template of the collection,
+// replacing any previous version. Other clients pick up the new version on
+// their next create from it.
+func (c *Collection[T]) DefineTemplate(name string, base map[string]interface{}) (*Template, error) {
+	key := c.templateKey(name)
+	for attempt := 0; attempt < maxSwapAttempts; attempt++ {
+		raw, found, err := c.client.GetKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		template := &Template{Name: name, Version: 1, Doc: deepCopyDoc(base)}
+		var old *string
+		if found {
+			current, err := decodeTemplate(name, raw)
+			if err != nil {
+				return nil, err
+			}
+			template.Version = current.Version + 1
+			old = &raw
+		}
+
+		encoded, err := json.Marshal(map[string]interface{}{"version": template.Version, "doc": template.Doc})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode template %s: %w", name, err)
+		}
+		swapped, err := c.client.putKeyIf(key, old, string(encoded))
+		if err != nil {
+			return nil, err
+		}
+		if !swapped {
+			continue
+		}
+
+		if err := c.client.SetKey(key+":version", strconv.Itoa(template.Version)); err != nil {
+			return nil, err
+		}
+		c.options.templates.put(template)
+		return template, nil
+	}
+	return nil, fmt.Errorf("define template %s: too many concurrent writers", name)
+}
+
+// CreateFromTemplate creates a document from the named template with
+// overrides deep-merged over it. Nested maps merge key by key, a nil
+// override removes the key, and any other value replaces the template's.
+// The result goes through the same slugs, guard and codecs as Create.
+func (c *Collection[T]) CreateFromTemplate(name string, overrides map[string]interface{}) (T, error) {
+	template, err := c.template(name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return c.createFromTemplate(template, overrides)
+}
+
+// CreateManyFromTemplate creates one document per overrides entry from the
+// named template, checking its version once. It stops at the first failure
+// and returns the documents created before it.
+func (c *Collection[T]) CreateManyFromTemplate(name string, overrides []map[string]interface{}) ([]T, error) {
+	template, err := c.template(name)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]T, 0, len(overrides))
+	for i, override := range overrides {
+		result, err := c.createFromTemplate(template, override)
+		if err != nil {
+			return created, fmt.Errorf("create from template %s, item %d: %w", name, i, err)
+		}
+		created = append(created, result)
+	}
+	return created, nil
+}
+
+func (c *Collection[T]) createFromTemplate(template *Template, overrides map[string]interface{}) (T, error) {
+	var result T
+
+	merged := mergeDocs(template.Doc, overrides)
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode document from template %s: %w", template.Name, err)
+	}
+	data := c.factory()
+	if err := hydrateJSON(raw, &data); err != nil {
+		return result, fmt.Errorf("document from template %s does not fit the model: %w", template.Name, err)
+	}
+	return c.Create(data)
+}
+
+// template returns the current version of a template, fetching it only when
+// the stored version differs from the cached one
+func (c *Collection[T]) template(name string) (*Template, error) {
+	key := c.templateKey(name)
+	version, found, err := c.client.GetKey(key + ":version")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	if cached := c.options.templates.get(name); cached != nil && strconv.Itoa(cached.Version) == version {
+		return cached, nil
+	}
+
+	raw, found, err := c.client.GetKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	template, err := decodeTemplate(name, raw)
+	if err != nil {
+		return nil, err
+	}
+	c.options.templates.put(template)
+	return template, nil
+}
+
+func (c *Collection[T]) templateKey(name string) string {
+	return templateKeyPrefix + ":" + c.collection + ":" + name
+}
+
+func decodeTemplate(name, raw string) (*Template, error) {
+	var stored struct {
+		Version int                    `json:"version"`
+		Doc     map[string]interface{} `json:"doc"`
+	}
+	if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+		return nil, fmt.Errorf("corrupt template %s: %w", name, err)
+	}
+	return &Template{Name: name, Version: stored.Version, Doc: stored.Doc}, nil
+}
+
+// mergeDocs returns overrides deep-merged over a copy of base
+func mergeDocs(base, overrides map[string]interface{}) map[string]interface{} {
+	merged := deepCopyDoc(base)
+	for field, value := range overrides {
+		if value == nil {
+			delete(merged, field)
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			current, _ := merged[field].(map[string]interface{})
+			merged[field] = mergeDocs(current, nested)
+			continue
+		}
+		merged[field] = deepCopyValue(value)
+	}
+	return merged
+}
+
+func deepCopyDoc(doc map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(doc))
+	for field, value := range doc {
+		copied[field] = deepCopyValue(value)
+	}
+	return copied
+}
+
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return deepCopyDoc(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyValue(item)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,158 @@
+package torm_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// delayedReads makes each document read take the next latency of the
+// sequence on the fake clock
+func delayedReads(ms *mockServer, clock *fakeClock, latencies ...time.Duration) {
+	var mu sync.Mutex
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodGet {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(latencies) > 0 {
+			clock.Advance(latencies[0])
+			latencies = latencies[1:]
+		}
+		return false
+	})
+}
+
+func assertTimeout(t *testing.T, client *torm.Client, samples int, want time.Duration, adaptive bool) {
+	t.Helper()
+	stats := client.Stats().Timeouts[torm.RequestRead]
+	diff := stats.Timeout - want
+	if diff < 0 {
+		diff = -diff
+	}
+	if stats.Samples != samples || diff > time.Millisecond || stats.Adaptive != adaptive {
+		t.Errorf("after %d samples: got %+v, want timeout %s (adaptive %v)", samples, stats, want, adaptive)
+	}
+}
+
+func TestAdaptiveTimeoutFollowsLatency(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ann"})
+	clock := newFakeClock()
+	delayedReads(ms, clock,
+		100*time.Millisecond, 300*time.Millisecond, 300*time.Millisecond,
+		2*time.Second, 2*time.Second, 2*time.Second, 2*time.Second)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Timeout: 5 * time.Second,
+		Clock:   clock.Now,
+		AdaptiveTimeout: &torm.AdaptiveTimeout{
+			Min:        50 * time.Millisecond,
+			Max:        time.Second,
+			Multiplier: 2,
+			MinSamples: 2,
+			Smoothing:  0.5,
+		},
+	})
+	users := client.Model("users", nil)
+	read := func() {
+		t.Helper()
+		if _, err := users.FindByID("user:1"); err != nil {
+			t.Fatalf("FindByID failed: %v", err)
+		}
+	}
+
+	if len(client.Stats().Timeouts) != 0 {
+		t.Errorf("expected no tracked classes yet, got %v", client.Stats().Timeouts)
+	}
+
+	// One sample is not enough, so the static timeout applies
+	read()
+	assertTimeout(t, client, 1, 5*time.Second, false)
+
+	// mean 0.2s, variance 0.01: 2 × (0.2 + 1.645 × 0.1)
+	read()
+	assertTimeout(t, client, 2, 729*time.Millisecond, true)
+
+	// mean 0.25s, variance 0.0075: 2 × (0.25 + 1.645 × 0.0866)
+	read()
+	assertTimeout(t, client, 3, 784922*time.Microsecond, true)
+
+	// Slow responses push the timeout up to Max
+	for i := 0; i < 4; i++ {
+		read()
+	}
+	assertTimeout(t, client, 7, time.Second, true)
+
+	if _, ok := client.Stats().Timeouts[torm.RequestQuery]; ok {
+		t.Error("queries were never sent, so they should not be tracked")
+	}
+}
+
+func TestAdaptiveTimeoutClampsToMin(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ann"})
+	clock := newFakeClock()
+	delayedReads(ms, clock, time.Millisecond, time.Millisecond, time.Millisecond)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:         ms.URL,
+		Clock:           clock.Now,
+		AdaptiveTimeout: torm.WithAdaptiveTimeout(50*time.Millisecond, time.Second, 3),
+	})
+	users := client.Model("users", nil)
+
+	for i := 0; i < 3; i++ {
+		if _, err := users.FindByID("user:1"); err != nil {
+			t.Fatalf("FindByID failed: %v", err)
+		}
+	}
+	stats := client.Stats().Timeouts[torm.RequestRead]
+	if stats.Adaptive || stats.Timeout != 5*time.Second {
+		t.Errorf("below the default 20 samples the static timeout should apply, got %+v", stats)
+	}
+	if stats.Mean != time.Millisecond {
+		t.Errorf("mean = %s, want 1ms", stats.Mean)
+	}
+
+	clocked := torm.NewClient(&torm.ClientOptions{
+		BaseURL:         ms.URL,
+		Clock:           clock.Now,
+		AdaptiveTimeout: &torm.AdaptiveTimeout{Min: 50 * time.Millisecond, Max: time.Second, Multiplier: 3, MinSamples: 1},
+	})
+	if _, err := clocked.Model("users", nil).FindByID("user:1"); err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	assertTimeout(t, clocked, 1, 50*time.Millisecond, true)
+}
+
+func TestAdaptiveTimeoutBoundsRequests(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ann"})
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:         ms.URL,
+		AdaptiveTimeout: &torm.AdaptiveTimeout{Min: 50 * time.Millisecond, Max: 100 * time.Millisecond, MinSamples: 1},
+	})
+	users := client.Model("users", nil)
+
+	doc, err := users.FindByID("user:1")
+	if err != nil || doc["name"] != "Ann" {
+		t.Fatalf("FindByID = %v, %v", doc, err)
+	}
+
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		time.Sleep(300 * time.Millisecond)
+		return false
+	})
+	start := time.Now()
+	_, err = users.FindByID("user:1")
+	if !torm.IsTimeout(err) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("request took %s, the adaptive timeout should have cut it short", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// failWith answers every request whose path contains fragment with status
+// and body
+func failWith(ms *mockServer, fragment string, status int, body string) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if !strings.Contains(r.URL.Path, fragment) {
+			return false
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+		return true
+	})
+}
+
+func TestAPIErrorFromCollection(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, torm.WithRedaction("email"))
+
+	failWith(ms, "/users", http.StatusUnprocessableEntity, `{"error": "email bob@example.com is invalid", "field": "email", "value": "bob@example.com"}`)
+	_, err := users.Create(&TestUser{ID: "user:1", Name: "Bob", Email: "bob@example.com"})
+	var apiErr *torm.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Operation != "create" || apiErr.Method != http.MethodPost {
+		t.Errorf("Expected a 422 from POST create, got %+v", apiErr)
+	}
+	if apiErr.Details["field"] != "email" {
+		t.Errorf("Expected the field in Details, got %v", apiErr.Details)
+	}
+	if strings.Contains(err.Error(), "bob@example.com") || strings.Contains(string(apiErr.RawBody), "bob@example.com") ||
+		apiErr.Details["value"] == "bob@example.com" {
+		t.Errorf("Expected the sensitive value to be masked everywhere, got %v, %s, %v", err, apiErr.RawBody, apiErr.Details)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryValidation {
+		t.Errorf("Expected a validation error, got %s", torm.ErrorCategory(err))
+	}
+
+	failWith(ms, "/users", http.StatusConflict, `{"error": "duplicate", "field": "email", "existing_id": "user:2"}`)
+	_, err = users.Create(&TestUser{ID: "user:1", Name: "Bob"})
+	var conflict *torm.ConflictError
+	if !errors.As(err, &conflict) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected a ConflictError carrying a 409 APIError, got %v", err)
+	}
+	if conflict.ExistingID != "user:2" || !errors.Is(err, torm.ErrConflict) {
+		t.Errorf("Expected the conflict to keep its details, got %+v", conflict)
+	}
+}
+
+func TestAPIErrorFromModelAndQuery(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", nil)
+
+	failWith(ms, "/users", http.StatusInternalServerError, "database unavailable\n")
+	_, err := users.Create(map[string]interface{}{"id": "user:1", "name": "Bob"})
+	var apiErr *torm.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected a 500 APIError, got %v", err)
+	}
+	if apiErr.Message != "database unavailable" || apiErr.Details != nil {
+		t.Errorf("Expected a plain text body as the message, got %q and %v", apiErr.Message, apiErr.Details)
+	}
+	if want := "torm: users.Create: create failed with status 500: database unavailable"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	failWith(ms, "/users", http.StatusBadRequest, `{"error": {"message": "unknown operator $near", "code": "bad_filter"}}`)
+	_, err = users.Query().Where("name", "Bob").Exec()
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Operation != "query" {
+		t.Fatalf("Expected a 400 APIError from the query, got %v", err)
+	}
+	if apiErr.Message != "unknown operator $near" || apiErr.Details["code"] != "bad_filter" {
+		t.Errorf("Expected a nested error to be flattened, got %q and %v", apiErr.Message, apiErr.Details)
+	}
+}
+
+func TestAPIErrorFromMigrationManager(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	manager := torm.NewMigrationManager(client)
+
+	failWith(ms, "/keys", http.StatusForbidden, `{"message": "token lacks keys:read"}`)
+	_, err := manager.StatusDetailed()
+	var apiErr *torm.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden || apiErr.Message != "token lacks keys:read" {
+		t.Fatalf("Expected a 403 APIError, got %v", err)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryForbidden {
+		t.Errorf("Expected a forbidden error, got %s", torm.ErrorCategory(err))
+	}
+}
@@ -0,0 +1,158 @@
+package torm_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// countingReader produces size bytes of synthetic data and counts what was read
+type countingReader struct {
+	size int64
+	read atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	remaining := r.size - r.read.Load()
+	if remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	r.read.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// attachmentStore serves the attachment endpoints on top of the mock
+type attachmentStore struct {
+	mu           sync.Mutex
+	sizes        map[string]int64
+	contentTypes map[string]string
+	readAtFirst  int64 // Client-side bytes read when the first chunk arrived
+}
+
+func serveAttachments(ms *mockServer, source *countingReader) *attachmentStore {
+	store := &attachmentStore{sizes: make(map[string]int64), contentTypes: make(map[string]string)}
+
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) < 5 || parts[3] != "attachments" {
+			return false
+		}
+		key := parts[1] + "/" + parts[2] + "/" + parts[4]
+
+		store.mu.Lock()
+		defer store.mu.Unlock()
+
+		switch {
+		case len(parts) == 6 && parts[5] == "url" && r.Method == http.MethodGet:
+			if _, ok := store.sizes[key]; !ok {
+				writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Document not found"})
+				return true
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"url":        "https://files.example.com/" + key + "?sig=abc",
+				"expires_at": "2030-01-01T00:00:00Z",
+			})
+		case len(parts) == 5 && r.Method == http.MethodPost:
+			reader, err := r.MultipartReader()
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+				return true
+			}
+			part, err := reader.NextPart()
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+				return true
+			}
+
+			chunk := make([]byte, 1024)
+			n, _ := io.ReadFull(part, chunk)
+			store.readAtFirst = source.read.Load()
+			rest, _ := io.Copy(io.Discard, part)
+
+			store.sizes[key] = int64(n) + rest
+			store.contentTypes[key] = part.Header.Get("Content-Type")
+			writeJSON(w, http.StatusCreated, map[string]interface{}{"success": true})
+		case len(parts) == 5 && r.Method == http.MethodDelete:
+			delete(store.sizes, key)
+			writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+		default:
+			return false
+		}
+		return true
+	})
+
+	return store
+}
+
+func TestAttachmentUploadStreams(t *testing.T) {
+	ms := newMockServer(t)
+	source := &countingReader{size: 64 << 20}
+	store := serveAttachments(ms, source)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Timeout: time.Minute})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if err := users.UploadAttachment("user:1", "avatar", source, "image/png"); err != nil {
+		t.Fatalf("UploadAttachment failed: %v", err)
+	}
+
+	if got := store.sizes["users/user:1/avatar"]; got != source.size {
+		t.Errorf("Expected server to receive %d bytes, got %d", source.size, got)
+	}
+	if got := store.contentTypes["users/user:1/avatar"]; got != "image/png" {
+		t.Errorf("Expected part content type image/png, got %q", got)
+	}
+	if store.readAtFirst >= source.size/2 {
+		t.Errorf("Expected upload to stream, but %d of %d bytes were read before the server saw any", store.readAtFirst, source.size)
+	}
+
+	url, expiresAt, err := users.GetAttachmentURL("user:1", "avatar")
+	if err != nil {
+		t.Fatalf("GetAttachmentURL failed: %v", err)
+	}
+	if !strings.Contains(url, "sig=") || expiresAt.Year() != 2030 {
+		t.Errorf("Unexpected signed url %q expiring %v", url, expiresAt)
+	}
+
+	if err := users.DeleteAttachment("user:1", "avatar"); err != nil {
+		t.Fatalf("DeleteAttachment failed: %v", err)
+	}
+	if _, _, err := users.GetAttachmentURL("user:1", "avatar"); err == nil || errors.Is(err, torm.ErrNotSupported) {
+		t.Errorf("Expected a not found error after delete, got %v", err)
+	}
+}
+
+func TestAttachmentsNotSupported(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if _, _, err := users.GetAttachmentURL("user:1", "avatar"); !errors.Is(err, torm.ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+
+	// The missing capability is remembered, so later calls skip the server
+	before := len(ms.requestLog())
+	if err := users.UploadAttachment("user:1", "avatar", strings.NewReader("data"), ""); !errors.Is(err, torm.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported on upload, got %v", err)
+	}
+	if err := users.DeleteAttachment("user:1", "avatar"); !errors.Is(err, torm.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported on delete, got %v", err)
+	}
+	if after := len(ms.requestLog()); after != before {
+		t.Errorf("Expected no further requests, got %d", after-before)
+	}
+}
@@ -0,0 +1,169 @@
+package torm_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// auditedHistory writes a known edit history an hour apart and returns the
+// collection and the time of the first write
+func auditedHistory(t *testing.T) (*mockServer, *torm.Collection[*TestProduct], time.Time) {
+	t.Helper()
+	ms := newMockServer(t)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} },
+		torm.WithAudit(torm.AuditOptions{Strict: true}))
+	start := clock.Now()
+
+	product, err := products.Create(&TestProduct{ID: "product:1", Name: "Anvil", Price: 10, SKU: "A-1"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	clock.Advance(time.Hour)
+	product.Name = "Heavy anvil"
+	if err := products.Save(product); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	clock.Advance(time.Hour)
+	product.Price = 12
+	product.Stock = 3
+	if err := products.Save(product); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	clock.Advance(time.Hour)
+	if err := products.Delete("product:1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	return ms, products, start
+}
+
+func TestAsOfReconstructsStates(t *testing.T) {
+	_, products, start := auditedHistory(t)
+
+	trail, err := products.AuditTrail("product:1")
+	if err != nil {
+		t.Fatalf("AuditTrail failed: %v", err)
+	}
+	ops := make([]torm.Operation, len(trail))
+	for i, entry := range trail {
+		ops[i] = entry.Op
+	}
+	if want := []torm.Operation{torm.OpCreate, torm.OpSave, torm.OpSave, torm.OpDelete}; !reflect.DeepEqual(ops, want) {
+		t.Fatalf("Expected operations %v, got %v", want, ops)
+	}
+
+	tests := []struct {
+		at    time.Duration
+		name  string
+		price float64
+		stock float64
+	}{
+		{0, "Anvil", 10, 0},
+		{30 * time.Minute, "Anvil", 10, 0},
+		{90 * time.Minute, "Heavy anvil", 10, 0},
+		{150 * time.Minute, "Heavy anvil", 12, 3},
+	}
+	for _, tt := range tests {
+		doc, err := products.AsOf("product:1", start.Add(tt.at))
+		if err != nil {
+			t.Fatalf("AsOf(%v) failed: %v", tt.at, err)
+		}
+		if doc["name"] != tt.name || doc["price"] != tt.price || doc["stock"] != tt.stock {
+			t.Errorf("AsOf(%v): unexpected state %v", tt.at, doc)
+		}
+	}
+
+	deleted, err := products.AsOf("product:1", start.Add(4*time.Hour))
+	if err != nil || deleted != nil {
+		t.Errorf("Expected no document after the delete, got %v, %v", deleted, err)
+	}
+}
+
+func TestAsOfWithoutHistory(t *testing.T) {
+	_, products, start := auditedHistory(t)
+
+	if _, err := products.AsOf("product:1", start.Add(-time.Minute)); !errors.Is(err, torm.ErrNoHistory) {
+		t.Errorf("Expected ErrNoHistory before the first write, got %v", err)
+	}
+	if _, err := products.AsOf("product:404", start.Add(time.Hour)); !errors.Is(err, torm.ErrNoHistory) {
+		t.Errorf("Expected ErrNoHistory for an unaudited document, got %v", err)
+	}
+	if !torm.IsNotFound(torm.ErrNoHistory) {
+		t.Error("Expected ErrNoHistory to classify as not found")
+	}
+}
+
+func TestAsOfReverseAppliesPrunedTrail(t *testing.T) {
+	ms, products, start := auditedHistory(t)
+
+	// Prune the create, as a retention job would
+	trail, err := products.AuditTrail("product:1")
+	if err != nil {
+		t.Fatalf("AuditTrail failed: %v", err)
+	}
+	ms.mu.Lock()
+	delete(ms.store("products_audit"), trail[0].ID)
+	ms.mu.Unlock()
+
+	doc, err := products.AsOf("product:1", start.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("AsOf failed: %v", err)
+	}
+	if doc["name"] != "Anvil" {
+		t.Errorf("Expected the state before the first remaining entry, got %v", doc)
+	}
+}
+
+func TestDiffBetween(t *testing.T) {
+	_, products, start := auditedHistory(t)
+
+	changes, err := products.DiffBetween("product:1", start.Add(30*time.Minute), start.Add(150*time.Minute))
+	if err != nil {
+		t.Fatalf("DiffBetween failed: %v", err)
+	}
+	want := []torm.FieldChange{
+		{Field: "name", Kind: torm.FieldModified, Before: "Anvil", After: "Heavy anvil"},
+		{Field: "price", Kind: torm.FieldModified, Before: float64(10), After: float64(12)},
+		{Field: "stock", Kind: torm.FieldModified, Before: float64(0), After: float64(3)},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected %+v, got %+v", want, changes)
+	}
+
+	unchanged, err := products.DiffBetween("product:1", start, start.Add(30*time.Minute))
+	if err != nil || len(unchanged) != 0 {
+		t.Errorf("Expected no changes within one state, got %+v, %v", unchanged, err)
+	}
+
+	removed, err := products.DiffBetween("product:1", start.Add(150*time.Minute), start.Add(4*time.Hour))
+	if err != nil {
+		t.Fatalf("DiffBetween failed: %v", err)
+	}
+	for _, change := range removed {
+		if change.Kind != torm.FieldRemoved {
+			t.Errorf("Expected every field removed by the delete, got %+v", change)
+		}
+	}
+	if len(removed) != 5 {
+		t.Errorf("Expected 5 removed fields, got %+v", removed)
+	}
+
+	if _, err := products.DiffBetween("product:1", start.Add(-time.Hour), start); !errors.Is(err, torm.ErrNoHistory) {
+		t.Errorf("Expected ErrNoHistory, got %v", err)
+	}
+}
+
+func TestAuditTrailRequiresAudit(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} })
+
+	if _, err := products.AsOf("product:1", time.Now()); !errors.Is(err, torm.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// touchEverything makes a request through each part of the client
+func touchEverything(t *testing.T, client *torm.Client) {
+	t.Helper()
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	if _, err := users.Create(&TestUser{ID: "user:1", Name: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Model("users", nil).Query().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := torm.NewMigrationManager(client).StatusDetailed(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Health(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthTokenOnEveryRequest(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, AuthToken: "s3cret"})
+	touchEverything(t, client)
+
+	log := ms.requestLog()
+	if len(log) < 4 {
+		t.Fatalf("Expected at least 4 requests, got %d", len(log))
+	}
+	for _, req := range log {
+		if got := req.Header.Get("Authorization"); got != "Bearer s3cret" {
+			t.Errorf("%s %s: expected the bearer token, got %q", req.Method, req.Path, got)
+		}
+		if got := req.Header.Get("X-API-Key"); got != "" {
+			t.Errorf("%s %s: expected no API key, got %q", req.Method, req.Path, got)
+		}
+	}
+}
+
+func TestAPIKeyHeader(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, APIKey: "key-1", APIKeyHeader: "X-Gateway-Key"})
+	touchEverything(t, client)
+
+	for _, req := range ms.requestLog() {
+		if got := req.Header.Get("X-Gateway-Key"); got != "key-1" {
+			t.Errorf("%s %s: expected the API key, got %q", req.Method, req.Path, got)
+		}
+		if got := req.Header.Get("Authorization"); got != "" {
+			t.Errorf("%s %s: expected no bearer token, got %q", req.Method, req.Path, got)
+		}
+	}
+}
+
+func TestTokenProvider(t *testing.T) {
+	ms := newMockServer(t)
+	calls := 0
+	var failure error
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:   ms.URL,
+		AuthToken: "ignored",
+		TokenProvider: func(ctx context.Context) (string, error) {
+			calls++
+			return fmt.Sprintf("token-%d", calls), failure
+		},
+	})
+	users := client.Model("users", nil)
+
+	for i := 1; i <= 2; i++ {
+		if _, err := users.Query().Exec(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log := ms.requestLog()
+	for i, req := range log {
+		if want := fmt.Sprintf("Bearer token-%d", i+1); req.Header.Get("Authorization") != want {
+			t.Errorf("Request %d: expected %q, got %q", i+1, want, req.Header.Get("Authorization"))
+		}
+	}
+
+	failure = errors.New("vault sealed")
+	_, err := users.Create(map[string]interface{}{"id": "user:1", "name": "Alice"})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the provider's error, got %v", err)
+	}
+	if sent := len(ms.requestLog()); sent != len(log) {
+		t.Errorf("Expected nothing sent after the provider failed, got %d more requests", sent-len(log))
+	}
+}
@@ -0,0 +1,230 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// serveProvisioning adds the collection and index endpoints to the mock,
+// remembering what was created
+func serveProvisioning(ms *mockServer) (collections, indexes map[string]map[string]interface{}) {
+	var mu sync.Mutex
+	collections = make(map[string]map[string]interface{})
+	indexes = make(map[string]map[string]interface{})
+
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method != http.MethodPost {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		var created map[string]map[string]interface{}
+		var name string
+		switch {
+		case r.URL.Path == "/api/_collections":
+			created, name = collections, body["name"].(string)
+		case strings.HasSuffix(r.URL.Path, "/_indexes"):
+			created, name = indexes, strings.TrimPrefix(strings.TrimSuffix(r.URL.Path, "/_indexes"), "/api/")+"."+body["name"].(string)
+		default:
+			return false
+		}
+		if _, ok := created[name]; ok {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "Exists"})
+			return true
+		}
+		created[name] = body
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"success": true})
+		return true
+	})
+	return collections, indexes
+}
+
+// bootstrapSpec describes a users service with one migration and a seeder,
+// counting how often each runs
+func bootstrapSpec(client *torm.Client, migrated, seeded *int) torm.BootstrapSpec {
+	users := client.Model("users", map[string]torm.ValidationRule{"email": {Type: "str", Required: true}})
+	migrations := torm.NewMigrationManager(client)
+	migrations.AddMigration(torm.Migration{ID: "001_settings", Name: "default settings", Up: func(c *torm.Client) error {
+		*migrated++
+		return c.SetKey("settings:theme", "light")
+	}})
+
+	return torm.BootstrapSpec{
+		Models:     []*torm.Model{users},
+		Indexes:    []torm.IndexSpec{{Collection: "users", Name: "by_email", Fields: []string{"email"}, Unique: true}},
+		Migrations: migrations,
+		Seeders: []torm.Seeder{{Name: "admin", Model: users, Seed: func(ctx context.Context, c *torm.Client) error {
+			*seeded++
+			_, err := c.Model("users", nil).Create(map[string]interface{}{"id": "user:admin", "email": "admin@example.com"})
+			return err
+		}}},
+	}
+}
+
+func TestBootstrapIsIdempotent(t *testing.T) {
+	ms := newMockServer(t)
+	collections, indexes := serveProvisioning(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	var migrated, seeded int
+	spec := bootstrapSpec(client, &migrated, &seeded)
+
+	first, err := client.Bootstrap(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("First bootstrap failed: %v", err)
+	}
+	want := []torm.BootstrapItem{
+		{Stage: torm.StageSchemas, Item: "users", Outcome: torm.BootstrapCreated},
+		{Stage: torm.StageIndexes, Item: "users.by_email", Outcome: torm.BootstrapCreated},
+		{Stage: torm.StageMigrations, Item: "001_settings", Outcome: torm.BootstrapApplied},
+		{Stage: torm.StageSeeds, Item: "admin", Outcome: torm.BootstrapApplied},
+	}
+	if !reflect.DeepEqual(first.Items, want) {
+		t.Errorf("Unexpected first report:\n got %+v\nwant %+v", first.Items, want)
+	}
+	if !first.Changed() {
+		t.Error("Expected the first run to report changes")
+	}
+	if settings, _ := collections["users"]["settings"].(map[string]interface{}); settings["schema"] == nil {
+		t.Errorf("Expected the schema to be sent with the collection, got %v", collections["users"])
+	}
+	if index := indexes["users.by_email"]; index["unique"] != true {
+		t.Errorf("Expected a unique index, got %v", index)
+	}
+	if _, ok := ms.doc("users", "user:admin"); !ok {
+		t.Error("Expected the seeder to write the admin user")
+	}
+
+	second, err := client.Bootstrap(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Second bootstrap failed: %v", err)
+	}
+	want = []torm.BootstrapItem{
+		{Stage: torm.StageSchemas, Item: "users", Outcome: torm.BootstrapSkipped, Reason: "exists"},
+		{Stage: torm.StageIndexes, Item: "users.by_email", Outcome: torm.BootstrapSkipped, Reason: "exists"},
+		{Stage: torm.StageMigrations, Item: "001_settings", Outcome: torm.BootstrapSkipped, Reason: "already applied"},
+		{Stage: torm.StageSeeds, Item: "admin", Outcome: torm.BootstrapSkipped, Reason: "collection not empty"},
+	}
+	if !reflect.DeepEqual(second.Items, want) {
+		t.Errorf("Unexpected second report:\n got %+v\nwant %+v", second.Items, want)
+	}
+	if second.Changed() {
+		t.Error("Expected the second run to change nothing")
+	}
+	if migrated != 1 || seeded != 1 {
+		t.Errorf("Expected the migration and seeder to run once, got %d and %d", migrated, seeded)
+	}
+	if first.Owner == second.Owner {
+		t.Error("Expected each run to hold the lock under its own owner")
+	}
+	if names := second.Names(torm.StageMigrations, torm.BootstrapSkipped); len(names) != 1 || names[0] != "001_settings" {
+		t.Errorf("Expected Names to list the skipped migration, got %v", names)
+	}
+}
+
+func TestBootstrapWithoutProvisioningEndpoints(t *testing.T) {
+	ms := newMockServer(t)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if strings.Contains(r.URL.Path, "/_") {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Not found"})
+			return true
+		}
+		return false
+	})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	var migrated, seeded int
+
+	report, err := client.Bootstrap(context.Background(), bootstrapSpec(client, &migrated, &seeded))
+	if err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	for _, item := range report.Items[:2] {
+		if item.Outcome != torm.BootstrapSkipped || item.Reason != "not supported by server" {
+			t.Errorf("Expected %s %s to be skipped as unsupported, got %+v", item.Stage, item.Item, item)
+		}
+	}
+	if migrated != 1 || seeded != 1 {
+		t.Errorf("Expected the later stages to run anyway, got %d and %d", migrated, seeded)
+	}
+}
+
+func TestBootstrapFailureNamesStageAndItem(t *testing.T) {
+	ms := newMockServer(t)
+	serveProvisioning(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	var migrated, seeded int
+	spec := bootstrapSpec(client, &migrated, &seeded)
+	spec.Migrations.AddMigration(torm.Migration{ID: "002_broken", Name: "broken", Up: func(*torm.Client) error {
+		return errors.New("boom")
+	}})
+
+	report, err := client.Bootstrap(context.Background(), spec)
+	var failed *torm.BootstrapError
+	if !errors.As(err, &failed) || failed.Stage != torm.StageMigrations || failed.Item != "002_broken" {
+		t.Fatalf("Expected a BootstrapError for migration 002_broken, got %v", err)
+	}
+	if want := "bootstrap migrations 002_broken: boom"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if seeded != 0 {
+		t.Error("Expected the seeders not to run after a failed migration")
+	}
+	if got := report.Names(torm.StageMigrations, torm.BootstrapApplied); len(got) != 1 || got[0] != "001_settings" {
+		t.Errorf("Expected the report to keep the applied migration, got %v", got)
+	}
+
+	// The lock is released after a failure
+	if _, err := client.Bootstrap(context.Background(), torm.BootstrapSpec{LockWait: time.Millisecond}); err != nil {
+		t.Errorf("Expected the lock to be free again, got %v", err)
+	}
+}
+
+func TestBootstrapWaitsForTheMigrationLock(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	until := time.Now().Add(time.Hour).UTC().Format(time.RFC3339Nano)
+	if err := client.SetKey("torm:migrations-lock", `{"owner":"replica-2","until":"`+until+`"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	ran := false
+	spec := torm.BootstrapSpec{
+		LockWait: 50 * time.Millisecond,
+		Seeders: []torm.Seeder{{Model: client.Model("users", nil), Seed: func(context.Context, *torm.Client) error {
+			ran = true
+			return nil
+		}}},
+	}
+	_, err := client.Bootstrap(context.Background(), spec)
+	if !errors.Is(err, torm.ErrMigrationLocked) {
+		t.Fatalf("Expected ErrMigrationLocked, got %v", err)
+	}
+	var failed *torm.BootstrapError
+	if !errors.As(err, &failed) || failed.Stage != torm.StageLock {
+		t.Errorf("Expected the lock stage to be named, got %v", err)
+	}
+	if ran {
+		t.Error("Expected nothing to run without the lock")
+	}
+
+	// An expired lease is taken over
+	expired := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339Nano)
+	if err := client.SetKey("torm:migrations-lock", `{"owner":"replica-2","until":"`+expired+`"}`); err != nil {
+		t.Fatal(err)
+	}
+	report, err := client.Bootstrap(context.Background(), spec)
+	if err != nil || !ran {
+		t.Fatalf("Expected an expired lock to be taken over, got %v", err)
+	}
+	if got := report.Names(torm.StageSeeds, torm.BootstrapApplied); len(got) != 1 || got[0] != "users" {
+		t.Errorf("Expected the seeder to be named after its collection, got %v", got)
+	}
+}
@@ -0,0 +1,277 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada"})
+	down := true
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if !down {
+			return false
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	})
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Clock:   clock.Now,
+		Breaker: &torm.BreakerOptions{FailureThreshold: 3, OpenDuration: time.Minute, HalfOpenProbes: 2},
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	for i := 0; i < 3; i++ {
+		if _, err := users.FindByID("user:1"); err == nil || torm.IsCircuitOpen(err) {
+			t.Fatalf("Expected request %d to reach the failing server, got %v", i+1, err)
+		}
+	}
+	if state := client.BreakerState(); state != torm.BreakerOpen {
+		t.Fatalf("Expected the circuit open after 3 failures, got %s", state)
+	}
+
+	sent := len(ms.requestLog())
+	_, err := users.FindByID("user:1")
+	if !errors.Is(err, torm.ErrCircuitOpen) || torm.ErrorCategory(err) != torm.CategoryCircuitOpen {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if len(ms.requestLog()) != sent {
+		t.Error("Expected an open circuit to send nothing")
+	}
+
+	// Once the open duration is over, probes go through and a failed one
+	// opens the circuit again
+	clock.Advance(time.Minute)
+	if state := client.BreakerState(); state != torm.BreakerHalfOpen {
+		t.Fatalf("Expected the circuit half-open, got %s", state)
+	}
+	if _, err := users.FindByID("user:1"); err == nil || torm.IsCircuitOpen(err) {
+		t.Fatalf("Expected the probe to reach the server, got %v", err)
+	}
+	if state := client.BreakerState(); state != torm.BreakerOpen {
+		t.Fatalf("Expected a failed probe to reopen the circuit, got %s", state)
+	}
+
+	// Every probe must succeed to close it
+	down = false
+	clock.Advance(time.Minute)
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if state := client.BreakerState(); state != torm.BreakerHalfOpen {
+		t.Fatalf("Expected the circuit to stay half-open after one of two probes, got %s", state)
+	}
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if state := client.BreakerState(); state != torm.BreakerClosed {
+		t.Fatalf("Expected successful probes to close the circuit, got %s", state)
+	}
+}
+
+func TestCircuitBreakerReset(t *testing.T) {
+	ms := newMockServer(t)
+	failWith(ms, "/users", http.StatusInternalServerError, "down")
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Breaker: &torm.BreakerOptions{FailureThreshold: 1}})
+	users := client.Model("users", nil)
+
+	users.FindByID("user:1")
+	if _, err := users.FindByID("user:1"); !torm.IsCircuitOpen(err) {
+		t.Fatalf("Expected the circuit open after one failure, got %v", err)
+	}
+	if got := client.DebugSnapshot().Health.Breaker; got != "open" {
+		t.Errorf("Expected the snapshot to show the open circuit, got %q", got)
+	}
+
+	client.ResetBreaker()
+	if state := client.BreakerState(); state != torm.BreakerClosed {
+		t.Fatalf("Expected Reset to close the circuit, got %s", state)
+	}
+	if _, err := users.FindByID("user:1"); torm.IsCircuitOpen(err) {
+		t.Errorf("Expected the request sent after Reset, got %v", err)
+	}
+
+	// Without a breaker, nothing fails fast
+	plain := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	for i := 0; i < 10; i++ {
+		if _, err := plain.Model("users", nil).FindByID("user:1"); torm.IsCircuitOpen(err) {
+			t.Fatal("Expected no breaker by default")
+		}
+	}
+	if state := plain.BreakerState(); state != torm.BreakerClosed {
+		t.Errorf("Expected BreakerClosed without a breaker, got %s", state)
+	}
+}
+
+func TestCircuitBreakerPriorities(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada"})
+	unavailable(ms)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Clock:   clock.Now,
+		Breaker: &torm.BreakerOptions{FailureThreshold: 3, OpenDuration: time.Minute, HalfOpenProbes: 2},
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	jobs := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, torm.WithPriority(torm.PriorityBackground))
+	high := torm.WithCallPriority(context.Background(), torm.PriorityHigh)
+
+	// One failure away from opening, background requests are shed
+	users.FindByID("user:1")
+	if _, err := jobs.FindByID("user:1"); err == nil || torm.ErrorCategory(err) == torm.CategoryLoadShed {
+		t.Fatalf("Expected a background request sent after one failure, got %v", err)
+	}
+	sent := len(ms.requestLog())
+	if _, err := jobs.FindByID("user:1"); !errors.Is(err, torm.ErrLoadShed) || torm.ErrorCategory(err) != torm.CategoryLoadShed {
+		t.Fatalf("Expected ErrLoadShed, got %v", err)
+	}
+	if len(ms.requestLog()) != sent {
+		t.Error("Expected a shed request to send nothing")
+	}
+	users.FindByID("user:1")
+
+	// While half-open, background requests are shed and, with a probe in
+	// flight, the last one is kept for high priority requests
+	held, release := make(chan struct{}), make(chan struct{})
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Path == "/api/users/user:2" {
+			close(held)
+			<-release
+		}
+		return false
+	})
+	clock.Advance(time.Minute)
+	if _, err := jobs.FindByID("user:1"); !errors.Is(err, torm.ErrLoadShed) {
+		t.Fatalf("Expected the background request shed while half-open, got %v", err)
+	}
+	probed := make(chan error)
+	go func() {
+		_, err := users.FindByID("user:2")
+		probed <- err
+	}()
+	<-held
+	if _, err := users.FindByID("user:1"); !torm.IsCircuitOpen(err) {
+		t.Fatalf("Expected the last probe kept for high priority, got %v", err)
+	}
+	if n, err := users.CountWith(high, torm.CountOptions{}); err != nil || n != 1 {
+		t.Fatalf("Expected the high priority count to probe, got %d, %v", n, err)
+	}
+	close(release)
+	if err := <-probed; !torm.IsNotFound(err) {
+		t.Fatalf("Expected the normal probe answered, got %v", err)
+	}
+	if state := client.BreakerState(); state != torm.BreakerClosed {
+		t.Fatalf("Expected the probes to close the circuit, got %s", state)
+	}
+
+	// Once recovered, background requests go through again
+	if _, err := jobs.FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if shed := client.Stats().Shed; shed[torm.PriorityBackground] != 2 || len(shed) != 1 {
+		t.Errorf("Expected two background requests counted as shed, got %v", shed)
+	}
+	for _, req := range ms.requestLog() {
+		if req.Header.Get("X-Torm-Priority") != "" {
+			t.Fatal("Expected the priority kept off the wire")
+		}
+	}
+}
+
+func TestCircuitBreakerRetries(t *testing.T) {
+	ms := newMockServer(t)
+	unavailable(ms)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Retry:   torm.RetryOptions{MaxAttempts: 5, Backoff: time.Millisecond},
+		Breaker: &torm.BreakerOptions{FailureThreshold: 2},
+	})
+
+	// The attempts of one call open the circuit, and no more are sent
+	_, err := client.Model("users", nil).FindByID("user:1")
+	if !torm.IsCircuitOpen(err) {
+		t.Fatalf("Expected the retries stopped by the open circuit, got %v", err)
+	}
+	if n := len(ms.requestLog()); n != 2 {
+		t.Errorf("Expected 2 attempts before the circuit opened, got %d", n)
+	}
+
+	// Failing over to another endpoint is an attempt too
+	standby := newMockServer(t)
+	failover := torm.NewClient(&torm.ClientOptions{
+		BaseURLs: []string{closedURL(), standby.URL},
+		Breaker:  &torm.BreakerOptions{FailureThreshold: 1},
+	})
+	if _, err := failover.Model("users", nil).FindByID("user:1"); !torm.IsCircuitOpen(err) {
+		t.Fatalf("Expected the failover stopped by the open circuit, got %v", err)
+	}
+	if len(standby.requestLog()) != 0 {
+		t.Error("Expected nothing sent to the standby once the circuit opened")
+	}
+}
+
+func TestCircuitBreakerCallPriority(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada"})
+	unavailable(ms)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Breaker: &torm.BreakerOptions{FailureThreshold: 2},
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	model := client.Model("users", nil)
+
+	// Calls without a context take their priority from a copy
+	users.FindByID("user:1")
+	if _, err := users.AtPriority(torm.PriorityBackground).FindByID("user:1"); !errors.Is(err, torm.ErrLoadShed) {
+		t.Fatalf("Expected the background call shed, got %v", err)
+	}
+	if _, err := model.AtPriority(torm.PriorityBackground).Find(); !errors.Is(err, torm.ErrLoadShed) {
+		t.Fatalf("Expected the background model call shed, got %v", err)
+	}
+	if _, err := users.FindByID("user:1"); errors.Is(err, torm.ErrLoadShed) {
+		t.Fatal("Expected AtPriority to leave the collection as it was")
+	}
+	if _, err := model.Find(); errors.Is(err, torm.ErrLoadShed) {
+		t.Fatal("Expected AtPriority to leave the model as it was")
+	}
+}
+
+func TestCircuitBreakerShedsLastRetry(t *testing.T) {
+	ms := newMockServer(t)
+	unavailable(ms)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Retry:   torm.RetryOptions{MaxAttempts: 3, Backoff: time.Millisecond},
+		Breaker: &torm.BreakerOptions{FailureThreshold: 10},
+	})
+	users := client.Model("users", nil)
+
+	_, err := users.AtPriority(torm.PriorityBackground).FindByID("user:1")
+	if !errors.Is(err, torm.ErrLoadShed) {
+		t.Fatalf("Expected the last retry shed, got %v", err)
+	}
+	if n := len(ms.requestLog()); n != 2 {
+		t.Errorf("Expected 2 attempts sent, got %d", n)
+	}
+	if shed := client.Stats().Shed; shed[torm.PriorityBackground] != 1 {
+		t.Errorf("Expected the shed retry counted, got %v", shed)
+	}
+
+	// Other requests use every attempt
+	if _, err := users.FindByID("user:1"); errors.Is(err, torm.ErrLoadShed) {
+		t.Fatalf("Expected a normal request retried to the end, got %v", err)
+	}
+	if n := len(ms.requestLog()); n != 2+3 {
+		t.Errorf("Expected 3 more attempts, got %d", n-2)
+	}
+}
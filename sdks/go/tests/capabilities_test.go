@@ -0,0 +1,94 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// advertise makes the server's info document list capabilities under key
+func advertise(ms *mockServer, key string, capabilities interface{}) *int {
+	reads := new(int)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Path != "/" {
+			return false
+		}
+		*reads++
+		writeJSON(w, http.StatusOK, map[string]interface{}{"name": "TORM Server", key: capabilities})
+		return true
+	})
+	return reads
+}
+
+func TestSupportsAdvertisedCapabilities(t *testing.T) {
+	ms := newMockServer(t)
+	reads := advertise(ms, "capabilities", []string{"count", "distinct", "indexes"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	for capability, want := range map[torm.Capability]bool{
+		torm.CapabilityServerSideCount: true,
+		torm.CapabilityDistinct:        true,
+		torm.CapabilityIndexes:         true,
+		torm.CapabilityWatchSSE:        false,
+		torm.CapabilitySchema:          false,
+	} {
+		if got := client.Supports(capability); got != want {
+			t.Errorf("Supports(%s) = %v, want %v", capability, got, want)
+		}
+	}
+	if *reads != 1 {
+		t.Errorf("Expected the info document to be read once, got %d reads", *reads)
+	}
+
+	err := client.RequireCapabilities(torm.CapabilityServerSideCount, torm.CapabilityWatchSSE, torm.CapabilityBulkDelete)
+	var missing *torm.MissingCapabilitiesError
+	if !errors.As(err, &missing) || !errors.Is(err, torm.ErrNotSupported) {
+		t.Fatalf("Expected a MissingCapabilitiesError, got %v", err)
+	}
+	if want := []torm.Capability{torm.CapabilityWatchSSE, torm.CapabilityBulkDelete}; !reflect.DeepEqual(missing.Missing, want) {
+		t.Errorf("Expected %v missing, got %v", want, missing.Missing)
+	}
+	if want := "torm: server lacks required capabilities: watch_sse, bulk_delete"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if err := client.RequireCapabilities(torm.CapabilityDistinct, torm.CapabilityIndexes); err != nil {
+		t.Errorf("Expected advertised capabilities to be satisfied, got %v", err)
+	}
+}
+
+func TestSupportsFeatureFlagsAndObservedSupport(t *testing.T) {
+	ms := newMockServer(t)
+	advertise(ms, "features", map[string]interface{}{"watch_sse": true, "schema": true, "indexes": true})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	if !client.Supports(torm.CapabilityWatchSSE) || !client.Supports(torm.CapabilitySchema) {
+		t.Error("Expected capabilities flagged on under features to be supported")
+	}
+
+	// The mock has no index endpoint, which outweighs what the server advertised
+	if !client.Supports(torm.CapabilityIndexes) {
+		t.Fatal("Expected indexes to be advertised")
+	}
+	if _, err := client.EnsureIndex(torm.IndexSpec{Collection: "users", Name: "by_email", Fields: []string{"email"}}); !errors.Is(err, torm.ErrNotSupported) {
+		t.Fatalf("Expected the index endpoint to be unsupported, got %v", err)
+	}
+	if client.Supports(torm.CapabilityIndexes) {
+		t.Error("Expected the observed lack of the index endpoint to win")
+	}
+}
+
+func TestSupportsWithoutAdvertisedCapabilities(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	if client.Supports(torm.CapabilityServerSideCount) {
+		t.Error("Expected capabilities the server does not advertise to be unsupported")
+	}
+	err := client.RequireCapabilities(torm.CapabilitySchema)
+	if torm.ErrorCategory(err) != torm.CategoryUnsupported {
+		t.Errorf("Expected an unsupported error, got %v", err)
+	}
+}
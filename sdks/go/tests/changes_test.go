@@ -0,0 +1,87 @@
+package torm_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func at(second int) string {
+	return time.Date(2024, 1, 1, 0, 0, second, 0, time.UTC).Format(time.RFC3339)
+}
+
+// drain collects the ids of an iterator's changes, running write after the first
+func drain(t *testing.T, it *torm.ChangeIterator, write func()) []string {
+	t.Helper()
+
+	ids := make([]string, 0)
+	for it.Next() {
+		change := it.Change()
+		id := change.ID
+		if change.Deleted {
+			id += "(deleted)"
+		}
+		ids = append(ids, id)
+		if write != nil && len(ids) == 1 {
+			write()
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iteration failed: %v", err)
+	}
+	return ids
+}
+
+func TestChangedSinceSyncRounds(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("items",
+		map[string]interface{}{"id": "a", "updatedAt": at(1)},
+		map[string]interface{}{"id": "b", "updatedAt": at(2)},
+		map[string]interface{}{"id": "c", "updatedAt": at(2)},
+		map[string]interface{}{"id": "d", "updatedAt": at(2)},
+		map[string]interface{}{"id": "e", "updatedAt": at(3)},
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := torm.NewCollection(client, "items", func() *TestUser { return &TestUser{} })
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Round one: b, c and d share a timestamp across the first page
+	// boundary, and writes land while the round is running
+	first := items.ChangedSince(since, &torm.ChangedSinceOptions{PageSize: 2})
+	got := drain(t, first, func() {
+		ms.seed("items",
+			map[string]interface{}{"id": "f", "updatedAt": at(4)},
+			map[string]interface{}{"id": "a", "updatedAt": at(5)},
+		)
+	})
+	if strings.Join(got, ",") != "a,b,c,d,e,f,a" {
+		t.Fatalf("Unexpected first round: %v", got)
+	}
+	mark := first.HighWaterMark()
+
+	// Round two resumes after the mark and sees only later writes,
+	// including a soft delete as a tombstone
+	ms.seed("items",
+		map[string]interface{}{"id": "g", "updatedAt": at(6)},
+		map[string]interface{}{"id": "c", "updatedAt": at(7), "deletedAt": at(7)},
+	)
+	second := items.ChangedSince(since, &torm.ChangedSinceOptions{PageSize: 2, After: mark, Tombstones: true})
+	if got := drain(t, second, nil); strings.Join(got, ",") != "g,c(deleted)" {
+		t.Errorf("Unexpected second round: %v", got)
+	}
+
+	// Without tombstones the deleted document is skipped
+	plain := items.ChangedSince(since, &torm.ChangedSinceOptions{After: mark})
+	if got := drain(t, plain, nil); strings.Join(got, ",") != "g" {
+		t.Errorf("Expected soft-deleted document to be skipped, got %v", got)
+	}
+
+	// Nothing new leaves the mark in place
+	mark = second.HighWaterMark()
+	third := items.ChangedSince(since, &torm.ChangedSinceOptions{After: mark})
+	if got := drain(t, third, nil); len(got) != 0 || third.HighWaterMark() != mark {
+		t.Errorf("Expected an empty round keeping the mark, got %v %q", got, third.HighWaterMark())
+	}
+}
@@ -0,0 +1,174 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+type Job struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Owner      string `json:"owner,omitempty"`
+	LeaseUntil string `json:"leaseUntil,omitempty"`
+}
+
+func (j *Job) GetID() string {
+	return j.ID
+}
+
+func (j *Job) SetID(id string) {
+	j.ID = id
+}
+
+func (j *Job) ToMap() map[string]interface{} {
+	return map[string]interface{}{"id": j.ID, "status": j.Status, "owner": j.Owner, "leaseUntil": j.LeaseUntil}
+}
+
+func seedPendingJobs(ms *mockServer, n int) {
+	for i := 1; i <= n; i++ {
+		ms.seed("queue", map[string]interface{}{"id": fmt.Sprintf("job:%03d", i), "status": "pending"})
+	}
+}
+
+func newJobs(client *torm.Client) *torm.Collection[*Job] {
+	return torm.NewCollection(client, "queue", func() *Job { return &Job{} })
+}
+
+var pendingJobs = []torm.QueryFilter{{Field: "status", Operator: torm.Eq, Value: "pending"}}
+
+func TestClaimConcurrentClaimersNeverShareAJob(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableDocumentETags()
+	seedPendingJobs(ms, 20)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	jobs := newJobs(client)
+
+	var mu sync.Mutex
+	claimedBy := map[string][]string{}
+	var wg sync.WaitGroup
+	for w := 0; w < 5; w++ {
+		owner := fmt.Sprintf("worker-%d", w)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				claimed, err := jobs.Claim(context.Background(), 3, pendingJobs, torm.ClaimOptions{Owner: owner, LeaseDuration: time.Hour})
+				if err != nil {
+					t.Errorf("Claim failed: %v", err)
+					return
+				}
+				if len(claimed) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, job := range claimed {
+					if job.Owner != owner {
+						t.Errorf("%s returned with owner %q, want %q", job.ID, job.Owner, owner)
+					}
+					claimedBy[job.ID] = append(claimedBy[job.ID], owner)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimedBy) != 20 {
+		t.Errorf("claimed %d jobs, want 20", len(claimedBy))
+	}
+	for id, owners := range claimedBy {
+		if len(owners) != 1 {
+			t.Errorf("%s claimed by %v", id, owners)
+		}
+		stored, _ := ms.doc("queue", id)
+		if stored["owner"] != owners[0] {
+			t.Errorf("%s stored owner %v, want %s", id, stored["owner"], owners[0])
+		}
+	}
+}
+
+func TestClaimExpiredLeaseIsClaimableAgain(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableDocumentETags()
+	seedPendingJobs(ms, 2)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	jobs := newJobs(client)
+	opts := torm.ClaimOptions{Owner: "a", LeaseDuration: time.Minute}
+
+	first, err := jobs.Claim(context.Background(), 1, pendingJobs, opts)
+	if err != nil || len(first) != 1 || first[0].ID != "job:001" {
+		t.Fatalf("first claim = %v, %v", first, err)
+	}
+
+	second, err := jobs.Claim(context.Background(), 2, pendingJobs, torm.ClaimOptions{Owner: "b", LeaseDuration: time.Minute})
+	if err != nil {
+		t.Fatalf("second claim failed: %v", err)
+	}
+	if len(second) != 1 || second[0].ID != "job:002" {
+		t.Fatalf("second claim = %v, want only job:002 while job:001 is leased", second)
+	}
+
+	clock.Advance(2 * time.Minute)
+	third, err := jobs.Claim(context.Background(), 2, pendingJobs, torm.ClaimOptions{Owner: "c", LeaseDuration: time.Minute})
+	if err != nil {
+		t.Fatalf("third claim failed: %v", err)
+	}
+	if len(third) != 2 {
+		t.Fatalf("third claim got %d jobs, want both expired leases", len(third))
+	}
+
+	if err := jobs.Renew("job:001", opts); !errors.Is(err, torm.ErrLeaseLost) {
+		t.Errorf("Renew by the previous owner = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestClaimRenewAndRelease(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableDocumentETags()
+	seedPendingJobs(ms, 1)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	jobs := newJobs(client)
+	opts := torm.ClaimOptions{Owner: "a", LeaseDuration: time.Minute}
+
+	if _, err := jobs.Claim(context.Background(), 1, pendingJobs, opts); err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+
+	clock.Advance(45 * time.Second)
+	if err := jobs.Renew("job:001", opts); err != nil {
+		t.Fatalf("Renew failed: %v", err)
+	}
+	clock.Advance(45 * time.Second)
+	claimed, err := jobs.Claim(context.Background(), 1, pendingJobs, torm.ClaimOptions{Owner: "b"})
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("claim of a renewed job = %v, %v; want none", claimed, err)
+	}
+
+	if err := jobs.Release("job:001", map[string]interface{}{"status": "done"}, torm.ClaimOptions{Owner: "b"}); !errors.Is(err, torm.ErrLeaseLost) {
+		t.Errorf("Release by another owner = %v, want ErrLeaseLost", err)
+	}
+	if err := jobs.Release("job:001", map[string]interface{}{"status": "done"}, opts); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	stored, _ := ms.doc("queue", "job:001")
+	if stored["status"] != "done" || stored["owner"] != nil || stored["leaseUntil"] != nil {
+		t.Errorf("released job = %v, want status done without owner or lease", stored)
+	}
+}
+
+func TestClaimRequiresOwner(t *testing.T) {
+	ms := newMockServer(t)
+	jobs := newJobs(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}))
+
+	if _, err := jobs.Claim(context.Background(), 1, nil, torm.ClaimOptions{}); err == nil {
+		t.Error("Claim without an owner succeeded")
+	}
+}
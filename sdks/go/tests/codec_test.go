@@ -0,0 +1,169 @@
+package torm_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// envelopeCodec stores documents as {"id", "v", "body"}
+type envelopeCodec struct {
+	version float64
+}
+
+func (e envelopeCodec) EncodeDoc(doc map[string]interface{}) map[string]interface{} {
+	body := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		if k != "id" {
+			body[k] = v
+		}
+	}
+	return map[string]interface{}{"id": doc["id"], "v": e.version, "body": body}
+}
+
+func (e envelopeCodec) DecodeDoc(doc map[string]interface{}) (map[string]interface{}, error) {
+	if doc["v"] != e.version {
+		return nil, errors.New("unexpected envelope version")
+	}
+	body, ok := doc["body"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("envelope has no body")
+	}
+	out := map[string]interface{}{"id": doc["id"]}
+	for k, v := range body {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// assertEnveloped checks the stored form of a document is an envelope
+func assertEnveloped(t *testing.T, ms *mockServer, id string) {
+	t.Helper()
+
+	stored, ok := ms.doc("users", id)
+	if !ok {
+		t.Fatalf("Expected %s to be stored", id)
+	}
+	if _, hasName := stored["name"]; hasName || stored["v"] != float64(1) {
+		t.Fatalf("Expected %s stored as an envelope, got %v", id, stored)
+	}
+}
+
+func TestCodecCollectionPaths(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithCodec(envelopeCodec{version: 1}))
+
+	created, err := users.Create(&TestUser{ID: "user:1", Name: "Alice", Age: 30})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.Name != "Alice" {
+		t.Errorf("Expected decoded create response, got %+v", created)
+	}
+	assertEnveloped(t, ms, "user:1")
+
+	if _, err := users.Create(&TestUser{ID: "user:2", Name: "Bob", Age: 25}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := users.Save(&TestUser{ID: "user:2", Name: "Bobby", Age: 26}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	assertEnveloped(t, ms, "user:2")
+
+	found, err := users.FindByID("user:2")
+	if err != nil || found.Name != "Bobby" || found.Age != 26 {
+		t.Fatalf("Expected decoded document, got %+v, %v", found, err)
+	}
+
+	all, err := users.Find()
+	if err != nil || len(all) != 2 || all[0].Name != "Alice" {
+		t.Fatalf("Expected decoded list, got %+v, %v", all, err)
+	}
+
+	// Filters apply to the application form, not the envelope
+	matched, err := users.FindFiltered(map[string]interface{}{"name": "Bobby"})
+	if err != nil || len(matched) != 1 || matched[0].ID != "user:2" {
+		t.Fatalf("Expected filter on decoded field, got %+v, %v", matched, err)
+	}
+
+	names, err := users.Lookup("name", nil)
+	if err != nil || names["user:1"] != "Alice" {
+		t.Fatalf("Expected lookup on decoded field, got %v, %v", names, err)
+	}
+
+	if _, err := users.Upsert(&TestUser{ID: "user:1", Name: "Alicia"}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	assertEnveloped(t, ms, "user:1")
+}
+
+func TestCodecModelPaths(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", nil).WithCodec(envelopeCodec{version: 1})
+
+	for i, name := range []string{"Carol", "Alice", "Bob"} {
+		id := []string{"user:1", "user:2", "user:3"}[i]
+		doc, err := users.Create(map[string]interface{}{"id": id, "name": name, "age": float64(20 + i)})
+		if err != nil || doc["name"] != name {
+			t.Fatalf("Create failed: %v, %v", doc, err)
+		}
+		assertEnveloped(t, ms, id)
+	}
+
+	if _, err := users.Update("user:3", map[string]interface{}{"id": "user:3", "name": "Bobby", "age": float64(40)}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	assertEnveloped(t, ms, "user:3")
+
+	// Sort, skip and limit on a decoded field are applied after decoding
+	docs, err := users.Query().Filter("age", torm.Gte, 21).Sort("name", torm.Asc).Skip(1).Limit(1).Select("name").Exec()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0]["id"] != "user:3" || docs[0]["age"] != nil {
+		t.Fatalf("Unexpected query result: %v", docs)
+	}
+
+	var out bytes.Buffer
+	if _, err := users.ExportSnapshot(&out, torm.ExportOptions{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `"name":"Bobby"`) || strings.Contains(out.String(), `"body"`) {
+		t.Errorf("Expected export in application form, got %s", out.String())
+	}
+
+	// A document the codec cannot read is an error, not a zero value
+	ms.seed("users", map[string]interface{}{"id": "user:9", "name": "Raw"})
+	if _, err := users.FindByID("user:9"); err == nil {
+		t.Errorf("Expected decode error for a document not in envelope form")
+	}
+}
+
+func TestCodecChainOrder(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithCodec(envelopeCodec{version: 1}, envelopeCodec{version: 2}))
+
+	if _, err := users.Create(&TestUser{ID: "user:1", Name: "Alice"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// The last codec is the outermost layer of the stored form
+	stored, _ := ms.doc("users", "user:1")
+	inner, _ := stored["body"].(map[string]interface{})
+	if stored["v"] != float64(2) || inner["v"] != float64(1) {
+		t.Fatalf("Expected nested envelopes v2(v1(doc)), got %v", stored)
+	}
+
+	found, err := users.FindByID("user:1")
+	if err != nil || found.Name != "Alice" {
+		t.Fatalf("Expected chain to decode in reverse, got %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,124 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedPeople(ms *mockServer) {
+	ms.seed("people",
+		map[string]interface{}{"id": "p1", "age": "30", "joined": "2024-03-01"},
+		map[string]interface{}{"id": "p2", "age": 25, "joined": "2024-01-15T10:00:00Z"},
+		map[string]interface{}{"id": "p3", "age": "41", "joined": "2023-12-31"},
+		map[string]interface{}{"id": "p4", "age": "n/a", "joined": "unknown"},
+		map[string]interface{}{"id": "p5", "age": 9, "joined": "2024-02-01T00:00:00Z"},
+	)
+}
+
+func ids(docs []map[string]interface{}) []string {
+	result := make([]string, len(docs))
+	for i, doc := range docs {
+		result[i], _ = doc["id"].(string)
+	}
+	return result
+}
+
+func TestCoerceNumeric(t *testing.T) {
+	ms := newMockServer(t)
+	seedPeople(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	people := client.Model("people", nil)
+
+	tests := []struct {
+		name string
+		qb   *torm.QueryBuilder
+		want []string
+	}{
+		{"gt", people.Query().CoerceNumeric("age").Filter("age", torm.Gt, 20).Sort("id", torm.Asc), []string{"p1", "p2", "p3"}},
+		{"string filter value", people.Query().CoerceNumeric("age").Filter("age", torm.Lte, "25").Sort("id", torm.Asc), []string{"p2", "p5"}},
+		{"eq across forms", people.Query().CoerceNumeric("age").Where("age", 30.0), []string{"p1"}},
+		{"in", people.Query().CoerceNumeric("age").Filter("age", torm.In, []interface{}{"9", 41}).Sort("id", torm.Asc), []string{"p3", "p5"}},
+		{"sort asc", people.Query().CoerceNumeric("age").Sort("age", torm.Asc), []string{"p5", "p2", "p1", "p3", "p4"}},
+		{"sort desc", people.Query().CoerceNumeric("age").Sort("age", torm.Desc), []string{"p3", "p1", "p2", "p5", "p4"}},
+		{"paged after coercion", people.Query().CoerceNumeric("age").Sort("age", torm.Asc).Skip(1).Limit(2), []string{"p2", "p1"}},
+		{"ne excludes uncoercible", people.Query().CoerceNumeric("age").Filter("age", torm.Ne, 30).Sort("id", torm.Asc), []string{"p2", "p3", "p5"}},
+		{"compare as string", people.Query().CoerceNumeric("age").OnCoercionFailure(torm.CoercionCompareAsString).Filter("age", torm.Ne, 30).Sort("id", torm.Asc), []string{"p2", "p3", "p4", "p5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := tt.qb.Exec()
+			if err != nil {
+				t.Fatalf("Exec failed: %v", err)
+			}
+			if got := ids(docs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Without hints the string-stored ages are missed
+	docs, _ := people.Query().Filter("age", torm.Gt, 20).Exec()
+	if len(docs) == 3 {
+		t.Errorf("Expected the uncoerced filter to miss string values, got %v", ids(docs))
+	}
+
+	// The hints are sent, and coerced filters stay on the client
+	people.Query().CoerceNumeric("age").Filter("age", torm.Gt, 20).Where("id", "p1").Exec()
+	var body map[string]interface{}
+	json.Unmarshal([]byte(lastQueryBody(t, ms)), &body)
+	if coerce, ok := body["coerce"].(map[string]interface{}); !ok || coerce["age"] == nil {
+		t.Errorf("Expected coercion hints in the payload, got %v", body)
+	}
+	if filters, _ := body["filters"].([]interface{}); len(filters) != 1 {
+		t.Errorf("Expected only the id filter on the server, got %v", body["filters"])
+	}
+
+	if _, err := people.Query().CoerceNumeric("age").Filter("age", torm.Gt, "many").Exec(); !errors.Is(err, torm.ErrInvalidFilter) {
+		t.Errorf("Expected an uncoercible filter value to be rejected, got %v", err)
+	}
+}
+
+func TestCoerceTime(t *testing.T) {
+	ms := newMockServer(t)
+	seedPeople(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	people := client.Model("people", nil)
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	docs, err := people.Query().
+		CoerceTime("joined", time.RFC3339, "2006-01-02").
+		Filter("joined", torm.Gte, since).
+		Sort("joined", torm.Asc).
+		Exec()
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got, want := ids(docs), []string{"p2", "p5", "p1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	docs, _ = people.Query().CoerceTime("joined", time.RFC3339, "2006-01-02").Sort("joined", torm.Desc).Exec()
+	if got, want := ids(docs), []string{"p1", "p5", "p2", "p3", "p4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected uncoercible values last, got %v", got)
+	}
+}
+
+func TestAutoCoerce(t *testing.T) {
+	ms := newMockServer(t)
+	seedPeople(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	people := client.Model("people", map[string]torm.ValidationRule{"age": {Type: "int"}}).WithAutoCoerce()
+
+	docs, err := people.Query().Filter("age", torm.Gte, 25).Sort("age", torm.Asc).Exec()
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got, want := ids(docs), []string{"p2", "p1", "p3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,98 @@
+package torm_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestDeprecationsReport(t *testing.T) {
+	report := torm.Deprecations()
+	if len(report) == 0 {
+		t.Fatal("Expected deprecations to be reported")
+	}
+	for _, d := range report {
+		if d.API == "" || d.Replacement == "" {
+			t.Errorf("Incomplete deprecation: %+v", d)
+		}
+	}
+
+	report[0].API = "changed"
+	if torm.Deprecations()[0].API == "changed" {
+		t.Error("Expected Deprecations to return a copy")
+	}
+}
+
+func TestCollectionTransportErrors(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		legacy bool
+		prefix string
+	}{
+		{false, "torm: users.FindByID id=user:1: request failed: Get \""},
+		{true, "Get \""},
+	}
+	for _, tt := range tests {
+		client := torm.NewClient(&torm.ClientOptions{BaseURL: closed.URL, LegacyErrorStrings: tt.legacy})
+		users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+		_, err := users.FindByID("user:1")
+		if err == nil || !strings.HasPrefix(err.Error(), tt.prefix) {
+			t.Errorf("LegacyErrorStrings=%v: expected an error starting with %q, got %v", tt.legacy, tt.prefix, err)
+		}
+		if torm.ErrorCategory(err) != torm.CategoryNetwork {
+			t.Errorf("LegacyErrorStrings=%v: expected a network error, got %s", tt.legacy, torm.ErrorCategory(err))
+		}
+	}
+}
+
+func TestCollectionUsesClientOptions(t *testing.T) {
+	ms := newMockServer(t)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Path == "/api/users/user:slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return false
+	})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Timeout: 50 * time.Millisecond})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if _, err := users.FindByID("user:slow"); !torm.IsTimeout(err) {
+		t.Errorf("Expected the client timeout to apply to collections, got %v", err)
+	}
+
+	// Collection requests feed the same health state as Model requests
+	if client.State() != torm.StateDegraded {
+		t.Errorf("Expected the failed request to degrade the client, got %s", client.State())
+	}
+}
+
+func TestNewClientFromURL(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+
+	client := torm.NewClientFromURL(ms.URL + "/")
+	if client.BaseURL != ms.URL {
+		t.Errorf("Expected base URL %s, got %s", ms.URL, client.BaseURL)
+	}
+	if client.Timeout != 30*time.Second {
+		t.Errorf("Expected the 30s timeout of the string constructor, got %v", client.Timeout)
+	}
+
+	// Collections and models share the one client
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	user, err := users.FindByID("user:1")
+	if err != nil || user.Name != "Alice" {
+		t.Fatalf("Expected the collection to read user:1, got %+v, %v", user, err)
+	}
+	doc, err := client.Model("users", nil).FindByID("user:1")
+	if err != nil || doc["name"] != "Alice" {
+		t.Errorf("Expected the model to read user:1, got %v, %v", doc, err)
+	}
+}
@@ -0,0 +1,236 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedJobs(ms *mockServer, n int) {
+	for i := 0; i < n; i++ {
+		ms.seed("jobs", map[string]interface{}{"id": fmt.Sprintf("job:%03d", i), "state": "done"})
+	}
+}
+
+// concurrencyLog records the sizes reported by the metrics hook
+type concurrencyLog struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+func (l *concurrencyLog) record(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sizes = append(l.sizes, n)
+}
+
+func (l *concurrencyLog) values() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]int(nil), l.sizes...)
+}
+
+func TestAdaptiveConcurrencyRampsUp(t *testing.T) {
+	ms := newMockServer(t)
+	seedJobs(ms, 60)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	jobs := torm.NewCollection(client, "jobs", func() *TestUser { return &TestUser{} })
+
+	log := &concurrencyLog{}
+	report, err := jobs.DeleteWhere(context.Background(), nil, &torm.DeleteWhereOptions{
+		Concurrency: &torm.AdaptiveConcurrency{Min: 1, Max: 6, OnChange: log.record},
+	})
+	if err != nil || report.Deleted != 60 {
+		t.Fatalf("Expected 60 deletes, got %+v, %v", report, err)
+	}
+
+	sizes := log.values()
+	want := []int{2, 3, 4, 5, 6}
+	if len(sizes) != len(want) {
+		t.Fatalf("Expected steady ramp to the ceiling, got %v", sizes)
+	}
+	for i := range want {
+		if sizes[i] != want[i] {
+			t.Fatalf("Expected steady ramp to the ceiling, got %v", sizes)
+		}
+	}
+}
+
+func TestAdaptiveConcurrencyBacksOff(t *testing.T) {
+	ms := newMockServer(t)
+	seedJobs(ms, 80)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	jobs := torm.NewCollection(client, "jobs", func() *TestUser { return &TestUser{} })
+
+	// Deletes 31 to 40 are rate limited
+	var deletes atomic.Int64
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodDelete {
+			return false
+		}
+		if n := deletes.Add(1); n > 30 && n <= 40 {
+			writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{"error": "Too many requests"})
+			return true
+		}
+		return false
+	})
+
+	log := &concurrencyLog{}
+	report, err := jobs.DeleteWhere(context.Background(), nil, &torm.DeleteWhereOptions{
+		Concurrency: &torm.AdaptiveConcurrency{Min: 2, Max: 6, OnChange: log.record},
+	})
+	if err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	if report.Deleted != 70 || len(report.FailedIDs) != 10 {
+		t.Fatalf("Expected 70 deleted and 10 rate limited, got %d and %v", report.Deleted, report.FailedIDs)
+	}
+
+	sizes := log.values()
+	backedOff, lowest := false, 6
+	for i, size := range sizes {
+		if size < 2 || size > 6 {
+			t.Fatalf("Concurrency left its bounds: %v", sizes)
+		}
+		if i > 0 && size < sizes[i-1] {
+			backedOff = true
+		}
+		if backedOff && size < lowest {
+			lowest = size
+		}
+	}
+	if !backedOff || lowest > 3 || sizes[len(sizes)-1] <= lowest {
+		t.Fatalf("Expected halving on 429 and recovery afterwards, got %v", sizes)
+	}
+
+	// Rate limiting surfaces as ErrOverloaded
+	ms.seed("jobs", map[string]interface{}{"id": "job:x"})
+	deletes.Store(30)
+	if _, err := client.Model("jobs", nil).Delete("job:x"); !errors.Is(err, torm.ErrOverloaded) {
+		t.Errorf("Expected ErrOverloaded, got %v", err)
+	}
+}
+
+func TestAdaptiveConcurrencySlowResponses(t *testing.T) {
+	ms := newMockServer(t)
+	seedJobs(ms, 20)
+
+	// Every clock reading advances two seconds, so each request looks slow
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(2 * time.Second)
+		return now
+	}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock})
+	jobs := torm.NewCollection(client, "jobs", func() *TestUser { return &TestUser{} })
+
+	log := &concurrencyLog{}
+	report, err := jobs.DeleteWhere(context.Background(), nil, &torm.DeleteWhereOptions{
+		Concurrency: &torm.AdaptiveConcurrency{Min: 1, Max: 8, LatencyTarget: time.Second, OnChange: log.record},
+	})
+	if err != nil || report.Deleted != 20 {
+		t.Fatalf("Expected 20 deletes, got %+v, %v", report, err)
+	}
+	if sizes := log.values(); len(sizes) != 0 {
+		t.Errorf("Expected slow responses to hold concurrency at the floor, got %v", sizes)
+	}
+}
+
+// delayWrites slows the responses to requests from the fromth to the toth
+// with method, counting from 1
+func delayWrites(ms *mockServer, method string, from, to int64, delay time.Duration) {
+	var n atomic.Int64
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method == method {
+			if i := n.Add(1); i >= from && i <= to {
+				time.Sleep(delay)
+			}
+		}
+		return false
+	})
+}
+
+// roseFellAndRecovered reports whether sizes grow, then shrink and grow again
+func roseFellAndRecovered(sizes []int) bool {
+	phase := 0 // Rising, falling, recovering
+	for i := 1; i < len(sizes); i++ {
+		switch up := sizes[i] > sizes[i-1]; {
+		case phase == 0 && !up && i > 1:
+			phase = 1
+		case phase == 1 && up:
+			phase = 2
+		}
+	}
+	return phase == 2 && sizes[0] > 1
+}
+
+func TestAdaptiveConcurrencyBulkWrites(t *testing.T) {
+	// Writes 21 to 35 are slower than the target
+	cfg := func(log *concurrencyLog) torm.AdaptiveConcurrency {
+		return torm.AdaptiveConcurrency{Min: 1, Max: 6, LatencyTarget: 20 * time.Millisecond, OnChange: log.record}
+	}
+
+	t.Run("CreateMany", func(t *testing.T) {
+		ms := newMockServer(t)
+		delayWrites(ms, http.MethodPost, 21, 35, 40*time.Millisecond)
+		client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+		log := &concurrencyLog{}
+		jobs := torm.NewCollection(client, "jobs", func() *TestUser { return &TestUser{} }, torm.WithBulkConcurrency(cfg(log)))
+
+		items := make([]*TestUser, 60)
+		for i := range items {
+			items[i] = &TestUser{ID: fmt.Sprintf("job:%03d", i), Name: "job"}
+		}
+		result, err := jobs.CreateMany(context.Background(), items)
+		if err != nil || len(result.Created) != 60 || len(result.Failed) != 0 {
+			t.Fatalf("Expected 60 creates, got %+v, %v", result, err)
+		}
+		for i, created := range result.Created {
+			if created.ID != items[i].ID {
+				t.Fatalf("Expected results in input order, got %s at %d", created.ID, i)
+			}
+		}
+		if sizes := log.values(); !roseFellAndRecovered(sizes) {
+			t.Errorf("Expected concurrency to rise, fall with latency and recover, got %v", sizes)
+		}
+	})
+
+	t.Run("UpdateWhere", func(t *testing.T) {
+		ms := newMockServer(t)
+		seedJobs(ms, 60)
+		delayWrites(ms, http.MethodPut, 21, 35, 40*time.Millisecond)
+		client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+		jobs := torm.NewCollection(client, "jobs", func() *TestUser { return &TestUser{} })
+
+		log := &concurrencyLog{}
+		concurrency := cfg(log)
+		var lastIDs []string
+		report, err := jobs.UpdateWhere(context.Background(), nil, func(u *TestUser) (*TestUser, error) {
+			u.Name = "updated"
+			return u, nil
+		}, &torm.UpdateWhereOptions{
+			BatchSize:   20,
+			Concurrency: &concurrency,
+			Progress:    func(p torm.UpdateProgress) { lastIDs = append(lastIDs, p.LastID) },
+		})
+		if err != nil || report.Updated != 60 {
+			t.Fatalf("Expected 60 updates, got %+v, %v", report, err)
+		}
+		if want := []string{"job:019", "job:039", "job:059"}; fmt.Sprint(lastIDs) != fmt.Sprint(want) {
+			t.Errorf("Expected progress after each batch in id order, got %v", lastIDs)
+		}
+		if sizes := log.values(); !roseFellAndRecovered(sizes) {
+			t.Errorf("Expected concurrency to rise, fall with latency and recover, got %v", sizes)
+		}
+	})
+}
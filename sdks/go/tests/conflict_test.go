@@ -0,0 +1,149 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// conflictOnExisting makes the mock answer 409 when a create targets an existing id
+func conflictOnExisting(ms *mockServer, structured bool) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method != http.MethodPost || strings.Count(strings.Trim(r.URL.Path, "/"), "/") != 1 {
+			return false
+		}
+		collection := strings.TrimPrefix(r.URL.Path, "/api/")
+		data, _ := body["data"].(map[string]interface{})
+		id, _ := data["id"].(string)
+		if _, exists := ms.doc(collection, id); !exists {
+			return false
+		}
+
+		if structured {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{
+				"error":       "duplicate key",
+				"field":       "id",
+				"existing_id": id,
+			})
+		} else {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte("document already exists\n"))
+		}
+		return true
+	})
+}
+
+func newConflictCollection(t *testing.T, structured bool, opts ...torm.CollectionOption) (*mockServer, *torm.Collection[*TestUser]) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice", "email": "alice@example.com", "age": float64(30)})
+	conflictOnExisting(ms, structured)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, opts...)
+	return ms, users
+}
+
+func TestCreateConflictStructured(t *testing.T) {
+	_, users := newConflictCollection(t, true)
+
+	_, err := users.Create(&TestUser{ID: "user:1", Name: "Impostor"})
+	if !errors.Is(err, torm.ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+
+	var conflict *torm.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected *ConflictError, got %T", err)
+	}
+	if conflict.Field != "id" || conflict.ExistingID != "user:1" || conflict.Message != "duplicate key" {
+		t.Errorf("Unexpected conflict detail: %+v", conflict)
+	}
+}
+
+func TestCreateConflictUnstructured(t *testing.T) {
+	_, users := newConflictCollection(t, false)
+
+	_, err := users.Create(&TestUser{ID: "user:1", Name: "Impostor"})
+	var conflict *torm.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected *ConflictError, got %v", err)
+	}
+	if conflict.Message != "document already exists" || conflict.Field != "" {
+		t.Errorf("Unexpected conflict detail: %+v", conflict)
+	}
+}
+
+func TestCreateConflictIgnore(t *testing.T) {
+	ms, users := newConflictCollection(t, true, torm.WithOnConflict(torm.OnConflictIgnore))
+
+	input := &TestUser{ID: "user:1", Name: "Impostor"}
+	result, err := users.Create(input)
+	if err != nil {
+		t.Fatalf("Expected conflict to be ignored, got %v", err)
+	}
+	if result != input {
+		t.Error("Expected the input model to be returned")
+	}
+	if stored, _ := ms.doc("users", "user:1"); stored["name"] != "Alice" {
+		t.Errorf("Ignored conflict modified the document: %v", stored)
+	}
+}
+
+func TestCreateConflictOverwrite(t *testing.T) {
+	for _, structured := range []bool{true, false} {
+		ms, users := newConflictCollection(t, structured, torm.WithOnConflict(torm.OnConflictOverwrite))
+
+		result, err := users.Create(&TestUser{ID: "user:1", Name: "Alicia", Age: 31})
+		if err != nil {
+			t.Fatalf("Expected overwrite to succeed, got %v", err)
+		}
+		if result.Name != "Alicia" {
+			t.Errorf("Expected overwritten name, got %s", result.Name)
+		}
+		if stored, _ := ms.doc("users", "user:1"); stored["name"] != "Alicia" {
+			t.Errorf("Expected stored document to be overwritten, got %v", stored)
+		}
+	}
+}
+
+func TestUpsertAndGetOrCreate(t *testing.T) {
+	ms, users := newConflictCollection(t, true)
+
+	upserted, err := users.Upsert(&TestUser{ID: "user:1", Name: "Alice Updated"})
+	if err != nil || upserted.Name != "Alice Updated" {
+		t.Fatalf("Upsert of existing document failed: %v %+v", err, upserted)
+	}
+
+	existing, created, err := users.GetOrCreate(&TestUser{ID: "user:1", Name: "Someone Else"})
+	if err != nil {
+		t.Fatalf("GetOrCreate failed: %v", err)
+	}
+	if created || existing.Name != "Alice Updated" {
+		t.Errorf("Expected stored document, got created=%v %+v", created, existing)
+	}
+
+	fresh, created, err := users.GetOrCreate(&TestUser{ID: "user:2", Name: "Bob"})
+	if err != nil || !created || fresh.Name != "Bob" {
+		t.Errorf("Expected new document, got created=%v %+v err=%v", created, fresh, err)
+	}
+	if _, exists := ms.doc("users", "user:2"); !exists {
+		t.Error("GetOrCreate did not store the new document")
+	}
+}
+
+func TestModelCreateConflict(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+	conflictOnExisting(ms, true)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	_, err := client.Model("users", nil).Create(map[string]interface{}{"id": "user:1", "name": "Alice"})
+
+	var conflict *torm.ConflictError
+	if !errors.As(err, &conflict) || conflict.ExistingID != "user:1" {
+		t.Errorf("Expected ConflictError from Model.Create, got %v", err)
+	}
+}
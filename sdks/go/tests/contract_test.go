@@ -0,0 +1,121 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// respondWith makes the mock answer every request with a fixed raw body
+func respondWith(ms *mockServer, body string) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+		return true
+	})
+}
+
+func TestStrictResponsesCollection(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithStrictResponses())
+
+	cases := []struct {
+		name string
+		body string
+		run  func() error
+		want string
+	}{
+		{"create renamed id", `{"success":true,"_id":"user:1","data":{}}`,
+			func() error { _, err := users.Create(&TestUser{Name: "A"}); return err },
+			"create response violates contract: key 'id' is missing"},
+		{"create data as string", `{"success":true,"id":"user:1","data":"{}"}`,
+			func() error { _, err := users.Create(&TestUser{Name: "A"}); return err },
+			"key 'data' should be object, got string"},
+		{"find array body", `[{"id":"user:1"}]`,
+			func() error { _, err := users.FindByID("user:1"); return err },
+			"find response violates contract: body is not a JSON object"},
+		{"list items key", `{"collection":"users","count":1,"items":[]}`,
+			func() error { _, err := users.Find(); return err },
+			"list response violates contract: key 'documents' is missing"},
+		{"query count as string", `{"count":"1","documents":[]}`,
+			func() error { _, err := users.FindFiltered(map[string]interface{}{"name": "A"}); return err },
+			"query response violates contract: key 'count' should be number, got string"},
+		{"count null", `{"collection":"users","count":null}`,
+			func() error { _, err := users.Count(); return err },
+			"count response violates contract: key 'count' should be number, got null"},
+		{"save without success", `{"id":"user:1","data":{}}`,
+			func() error { return users.Save(&TestUser{ID: "user:1"}) },
+			"update response violates contract: key 'success' is missing"},
+		{"delete success as string", `{"success":"true"}`,
+			func() error { return users.Delete("user:1") },
+			"delete response violates contract: key 'success' should be boolean, got string"},
+	}
+
+	for _, tc := range cases {
+		respondWith(ms, tc.body)
+		err := tc.run()
+
+		var contractErr *torm.ContractError
+		if !errors.As(err, &contractErr) {
+			t.Errorf("%s: expected ContractError, got %v", tc.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) || !strings.Contains(err.Error(), tc.body) {
+			t.Errorf("%s: expected %q with body snippet, got %q", tc.name, tc.want, err)
+		}
+	}
+
+	// Extra keys are fine
+	respondWith(ms, `{"success":true,"id":"user:1","data":{"id":"user:1","name":"A"},"version":2}`)
+	if _, err := users.Create(&TestUser{Name: "A"}); err != nil {
+		t.Errorf("Expected extra keys to be allowed, got %v", err)
+	}
+}
+
+func TestStrictResponsesModel(t *testing.T) {
+	ms := newMockServer(t)
+	strict := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, StrictResponses: true})
+	lenient := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	cases := []struct {
+		name string
+		body string
+		run  func(*torm.Model) error
+		want string
+	}{
+		{"create", `{"success":true,"id":1,"data":{}}`,
+			func(m *torm.Model) error { _, err := m.Create(map[string]interface{}{}); return err },
+			"key 'id' should be string, got number"},
+		{"update", `{"success":true,"id":"a"}`,
+			func(m *torm.Model) error { _, err := m.Update("a", map[string]interface{}{}); return err },
+			"update response violates contract: key 'data' is missing"},
+		{"list", `{"documents":{},"count":0}`,
+			func(m *torm.Model) error { _, err := m.Find(); return err },
+			"key 'documents' should be array, got object"},
+		{"query", `{"count":0}`,
+			func(m *torm.Model) error { _, err := m.Query().Exec(); return err },
+			"query response violates contract: key 'documents' is missing"},
+		{"count", `{"total":3}`,
+			func(m *torm.Model) error { _, err := m.Count(); return err },
+			"key 'count' is missing"},
+		{"delete", `{"deleted":true}`,
+			func(m *torm.Model) error { _, err := m.Delete("a"); return err },
+			"key 'success' is missing"},
+	}
+
+	for _, tc := range cases {
+		respondWith(ms, tc.body)
+
+		if err := tc.run(strict.Model("users", nil)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected %q, got %v", tc.name, tc.want, err)
+		}
+		if err := tc.run(lenient.Model("users", nil)); err != nil {
+			t.Errorf("%s: expected lenient client to decode zero values, got %v", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package torm_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// miscount makes the count endpoint of a collection report n documents
+// whatever it holds
+func miscount(ms *mockServer, collection string, n int) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/"+collection+"/count" {
+			return false
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"collection": collection, "count": n})
+		return true
+	})
+}
+
+func TestVerifyCountReportsDivergence(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 40)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	for _, server := range []int{43, 37, 0, 1000} {
+		miscount(ms, "items", server)
+		for _, sampled := range []bool{false, true} {
+			report, err := items.VerifyCount(context.Background(), torm.VerifyCountOptions{Sampled: sampled})
+			if err != nil {
+				t.Fatalf("VerifyCount failed: %v", err)
+			}
+			if report.Server != server || report.Listed != 40 || report.Delta != 40-server || !report.Diverged || report.Sampled != sampled {
+				t.Errorf("server %d, sampled %v: unexpected report %+v", server, sampled, report)
+			}
+			if !strings.Contains(report.String(), fmt.Sprintf("delta %+d", 40-server)) {
+				t.Errorf("report does not show the delta: %s", report)
+			}
+			if sampled && report.Requests > 16 {
+				t.Errorf("sampled verification of %d took %d requests", server, report.Requests)
+			}
+		}
+	}
+}
+
+func TestVerifyCountAgrees(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 12)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := torm.NewCollection(client, "items", func() *TestProduct { return &TestProduct{} })
+
+	report, err := items.VerifyCount(context.Background(), torm.VerifyCountOptions{Sampled: true})
+	if err != nil {
+		t.Fatalf("VerifyCount failed: %v", err)
+	}
+	if report.Diverged || report.Listed != 12 || report.Requests != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if n := ms.countRequests("POST", "/api/items/query"); n != 2 {
+		t.Errorf("expected 2 probes, got %d", n)
+	}
+}
+
+func TestCountWithOptions(t *testing.T) {
+	ms := newMockServer(t)
+	for i := 1; i <= 6; i++ {
+		doc := map[string]interface{}{"id": fmt.Sprintf("task:%d", i), "done": i%2 == 0}
+		if i > 4 {
+			doc["deletedAt"] = "2024-01-01T00:00:00Z"
+		}
+		ms.seed("tasks", doc)
+	}
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:    ms.URL,
+		MicroCache: torm.MicroCacheOptions{Count: time.Minute},
+	})
+	tasks := client.Model("tasks", nil)
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		opts torm.CountOptions
+		want int
+	}{
+		{"all", torm.CountOptions{IncludeDeleted: true}, 6},
+		{"live", torm.CountOptions{}, 4},
+		{"filtered", torm.CountOptions{Filters: []torm.QueryFilter{{Field: "done", Operator: torm.Eq, Value: true}}, IncludeDeleted: true}, 3},
+		{"filtered live", torm.CountOptions{Filters: []torm.QueryFilter{{Field: "done", Operator: torm.Eq, Value: true}}}, 2},
+		{"exact", torm.CountOptions{IncludeDeleted: true, Consistency: torm.CountExact}, 6},
+	}
+	for _, tc := range cases {
+		n, err := tasks.CountWith(ctx, tc.opts)
+		if err != nil || n != tc.want {
+			t.Errorf("%s: got %d, %v; want %d", tc.name, n, err, tc.want)
+		}
+	}
+	if n := ms.countRequests("GET", "/api/tasks/count"); n != 1 {
+		t.Errorf("only the unfiltered count should use the endpoint, got %d requests", n)
+	}
+
+	if n, _ := tasks.Count(); n != 6 {
+		t.Errorf("Count = %d, want 6", n)
+	}
+	if n := ms.countRequests("GET", "/api/tasks/count"); n != 1 {
+		t.Errorf("Count should share the earlier result, got %d requests", n)
+	}
+	if _, err := tasks.CountWith(ctx, torm.CountOptions{IncludeDeleted: true, Consistency: torm.CountFresh}); err != nil {
+		t.Fatalf("fresh count failed: %v", err)
+	}
+	if n := ms.countRequests("GET", "/api/tasks/count"); n != 2 {
+		t.Errorf("CountFresh should ask the server, got %d requests", n)
+	}
+}
+
+func TestCountsAgreeAcrossAPIs(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 7)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := torm.NewCollection(client, "items", func() *TestProduct { return &TestProduct{} })
+	model := client.Model("items", nil)
+
+	viaCollection, err1 := items.Count()
+	viaModel, err2 := model.Count()
+	viaQuery, err3 := model.Query().Count()
+	if err1 != nil || err2 != nil || err3 != nil {
+		t.Fatalf("counts failed: %v, %v, %v", err1, err2, err3)
+	}
+	if viaCollection != 7 || viaModel != 7 || viaQuery != 7 {
+		t.Errorf("counts disagree: collection %d, model %d, query %d", viaCollection, viaModel, viaQuery)
+	}
+
+	if n, err := model.Query().Skip(2).Limit(3).Count(); err != nil || n != 3 {
+		t.Errorf("paged query count = %d, %v; want 3", n, err)
+	}
+}
@@ -0,0 +1,170 @@
+package torm_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestExecPageWalksEveryPage(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 12)
+	items := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("items", nil)
+
+	var ids []string
+	cursor := ""
+	pages := 0
+	for {
+		page, err := items.Query().Sort("id", torm.Asc).ExecPage(torm.PageOptions{Size: 5, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ExecPage failed: %v", err)
+		}
+		pages++
+		for _, doc := range page.Documents {
+			ids = append(ids, doc["id"].(string))
+		}
+		if page.Next == "" {
+			break
+		}
+		if url.QueryEscape(page.Next) != page.Next {
+			t.Fatalf("cursor is not URL-safe: %s", page.Next)
+		}
+		cursor = page.Next
+	}
+	if pages != 3 || len(ids) != 12 || ids[0] != "item:001" || ids[11] != "item:012" {
+		t.Fatalf("expected 12 items over 3 pages, got %d over %d: %v", len(ids), pages, ids)
+	}
+}
+
+func TestExecPageRejectsCursorOfAnotherQuery(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 12)
+	items := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("items", nil)
+
+	first, err := items.Query().Filter("price", torm.Gt, 2).Sort("price", torm.Asc).ExecPage(torm.PageOptions{Size: 3})
+	if err != nil {
+		t.Fatalf("ExecPage failed: %v", err)
+	}
+	if first.Next == "" {
+		t.Fatal("expected a next cursor")
+	}
+
+	cases := []struct {
+		name  string
+		query *torm.QueryBuilder
+		want  string
+	}{
+		{"filter value", items.Query().Filter("price", torm.Gt, 5).Sort("price", torm.Asc), "filters changed"},
+		{"extra filter", items.Query().Filter("price", torm.Gt, 2).Where("name", "x").Sort("price", torm.Asc), "filters changed"},
+		{"sort order", items.Query().Filter("price", torm.Gt, 2).Sort("price", torm.Desc), `sort changed from "price asc, id asc" to "price desc, id asc"`},
+		{"projection", items.Query().Filter("price", torm.Gt, 2).Sort("price", torm.Asc).Select("name"), "selected fields changed"},
+		{"collection", items.Query().Filter("price", torm.Gt, 2).Sort("price", torm.Asc), ""},
+	}
+	cases[4].query = torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("products", nil).
+		Query().Filter("price", torm.Gt, 2).Sort("price", torm.Asc)
+	cases[4].want = "collection changed from items to products"
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.query.ExecPage(torm.PageOptions{Size: 3, Cursor: first.Next})
+			if !errors.Is(err, torm.ErrCursorMismatch) {
+				t.Fatalf("expected ErrCursorMismatch, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected %q in %v", tc.want, err)
+			}
+		})
+	}
+
+	// Filters added in another order are the same query
+	same := items.Query().Sort("price", torm.Asc).Filter("price", torm.Gt, 2)
+	if _, err := same.ExecPage(torm.PageOptions{Size: 3, Cursor: first.Next}); err != nil {
+		t.Errorf("expected the same query to accept the cursor, got %v", err)
+	}
+}
+
+func TestExecPageCursorExpires(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 6)
+	clock := newFakeClock()
+	items := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now}).Model("items", nil)
+
+	first, err := items.Query().ExecPage(torm.PageOptions{Size: 2, TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("ExecPage failed: %v", err)
+	}
+
+	clock.Advance(30 * time.Second)
+	second, err := items.Query().ExecPage(torm.PageOptions{Size: 2, Cursor: first.Next, TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("expected the cursor to be valid within its TTL, got %v", err)
+	}
+	if len(second.Documents) != 2 || second.Documents[0]["id"] != "item:003" {
+		t.Fatalf("expected the second page, got %v", second.Documents)
+	}
+
+	clock.Advance(2 * time.Minute)
+	_, err = items.Query().ExecPage(torm.PageOptions{Size: 2, Cursor: second.Next})
+	if !errors.Is(err, torm.ErrCursorExpired) {
+		t.Fatalf("expected ErrCursorExpired, got %v", err)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryUsage {
+		t.Errorf("expected a usage error, got %s", torm.ErrorCategory(err))
+	}
+}
+
+func TestExecPageRejectsTamperedCursors(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 6)
+	items := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("items", nil)
+
+	first, err := items.Query().ExecPage(torm.PageOptions{Size: 2})
+	if err != nil {
+		t.Fatalf("ExecPage failed: %v", err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(first.Next)
+	if err != nil {
+		t.Fatalf("expected a base64url cursor: %v", err)
+	}
+
+	payload := raw[:len(raw)-6]
+	edited := strings.Replace(string(payload), `"o":2`, `"o":4`, 1)
+	if edited == string(payload) {
+		t.Fatalf("unexpected cursor payload %s", payload)
+	}
+	future := strings.Replace(string(payload), `"v":1`, `"v":2`, 1)
+
+	cases := map[string]struct {
+		cursor string
+		want   string
+	}{
+		"garbage":        {"not a cursor!", "not a cursor"},
+		"truncated":      {first.Next[:len(first.Next)-4], "checksum mismatch"},
+		"edited offset":  {base64.RawURLEncoding.EncodeToString(append([]byte(edited), raw[len(raw)-6:]...)), "checksum mismatch"},
+		"future version": {reencode(future), "format version 2"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := items.Query().ExecPage(torm.PageOptions{Size: 2, Cursor: tc.cursor})
+			if !errors.Is(err, torm.ErrInvalidCursor) {
+				t.Fatalf("expected ErrInvalidCursor, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("expected %q in %v", tc.want, err)
+			}
+		})
+	}
+}
+
+// reencode builds a cursor with a valid checksum around payload, as a
+// newer SDK would
+func reencode(payload string) string {
+	sum := sha256.Sum256([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(append([]byte(payload), sum[:6]...))
+}
@@ -0,0 +1,197 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// failWrites makes the mock reject writes to collection whose document id
+// contains "bad" until the returned flag is cleared
+func failWrites(ms *mockServer, collection, method string) *atomic.Bool {
+	failing := &atomic.Bool{}
+	failing.Store(true)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if !failing.Load() || r.Method != method || !strings.HasPrefix(r.URL.Path, "/api/"+collection) {
+			return false
+		}
+		data, _ := body["data"].(map[string]interface{})
+		id, _ := data["id"].(string)
+		if id == "" {
+			id = r.URL.Path
+		}
+		if !strings.Contains(id, "bad") {
+			return false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "disk full"})
+		return true
+	})
+	return failing
+}
+
+func TestCreateManyDeadLetters(t *testing.T) {
+	ms := newMockServer(t)
+	failing := failWrites(ms, "products", http.MethodPost)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} },
+		torm.WithDeadLetter(torm.DeadLetterCollection(client, "dead_letters")))
+
+	items := []*TestProduct{
+		{ID: "product:1", Name: "Anvil"},
+		{ID: "product:bad-2", Name: "Bolt"},
+		{ID: "product:3", Name: "Chain"},
+		{ID: "product:bad-4", Name: "Drill"},
+		{ID: "product:5", Name: "Eye bolt"},
+	}
+	result, err := products.CreateMany(context.Background(), items)
+	if err != nil {
+		t.Fatalf("CreateMany failed: %v", err)
+	}
+	if len(result.Created) != 3 || len(result.Failed) != 2 || result.DeadLettered != 2 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+	if result.Failed[0].Index != 1 || result.Failed[0].ID != "product:bad-2" || result.Failed[1].Index != 3 {
+		t.Errorf("Unexpected failures: %+v", result.Failed)
+	}
+
+	entry, ok := ms.doc("dead_letters", "deadletter:products:create:product:bad-2")
+	if !ok {
+		t.Fatalf("Expected a dead letter for the failed item, have %v", ms.store("dead_letters"))
+	}
+	doc, _ := entry["doc"].(map[string]interface{})
+	if entry["collection"] != "products" || entry["operation"] != "create" || entry["doc_id"] != "product:bad-2" ||
+		doc["name"] != "Bolt" || entry["attempts"] != float64(1) ||
+		entry["failed_at"] != "2024-01-01T00:00:00Z" || !strings.Contains(entry["error"].(string), "item 1") {
+		t.Errorf("Unexpected dead letter: %v", entry)
+	}
+	if len(ms.store("dead_letters")) != 2 {
+		t.Errorf("Expected exactly the failed items, got %v", ms.store("dead_letters"))
+	}
+
+	// Still failing: attempts go up and the entries stay
+	clock.Advance(time.Minute)
+	report, err := products.ReplayDeadLetters(context.Background(), nil)
+	if err != nil || report.Replayed != 0 || report.Failed != 2 {
+		t.Fatalf("Unexpected replay: %+v, %v", report, err)
+	}
+	entry, _ = ms.doc("dead_letters", "deadletter:products:create:product:bad-2")
+	if entry["attempts"] != float64(2) || entry["failed_at"] != "2024-01-01T00:01:00Z" {
+		t.Errorf("Expected the attempt to be recorded, got %v", entry)
+	}
+
+	failing.Store(false)
+	report, err = products.ReplayDeadLetters(context.Background(), func(entry torm.DeadLetter) bool {
+		return entry.DocID == "product:bad-4"
+	})
+	if err != nil || report.Replayed != 1 || report.Failed != 0 {
+		t.Fatalf("Unexpected filtered replay: %+v, %v", report, err)
+	}
+	if _, ok := ms.doc("dead_letters", "deadletter:products:create:product:bad-4"); ok {
+		t.Error("Expected the replayed entry to be removed")
+	}
+
+	report, err = products.ReplayDeadLetters(context.Background(), nil)
+	if err != nil || report.Replayed != 1 {
+		t.Fatalf("Unexpected replay: %+v, %v", report, err)
+	}
+	if len(ms.store("dead_letters")) != 0 || len(ms.store("products")) != 5 {
+		t.Errorf("Expected every item stored and no dead letters left, got %d products, %v",
+			len(ms.store("products")), ms.store("dead_letters"))
+	}
+}
+
+func TestImportDeadLettersToFile(t *testing.T) {
+	ms := newMockServer(t)
+	failing := failWrites(ms, "people", http.MethodPost)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	people := client.Model("people", nil).WithDeadLetter(torm.DeadLetterFile(path))
+
+	snapshot := `{"id":"person:1","name":"Ann"}` + "\n" +
+		`{"id":"person:bad","name":"Bob"}` + "\n" +
+		`{"id":"person:3","name":"Cy"}` + "\n"
+	result, err := people.ImportSnapshot(strings.NewReader(snapshot), torm.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected the import to continue past the failure, got %v", err)
+	}
+	if result.Imported != 2 || result.DeadLettered != 1 || result.Lines != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	entries, err := torm.DeadLetterFile(path).List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one dead letter, got %v, %v", entries, err)
+	}
+	if e := entries[0]; e.DocID != "person:bad" || e.Operation != torm.OpCreate || e.Doc["name"] != "Bob" ||
+		!strings.Contains(e.Error, "line 2") || e.Attempts != 1 || e.FailedAt.IsZero() {
+		t.Errorf("Unexpected dead letter: %+v", e)
+	}
+
+	failing.Store(false)
+	report, err := people.ReplayDeadLetters(context.Background(), nil)
+	if err != nil || report.Replayed != 1 {
+		t.Fatalf("Unexpected replay: %+v, %v", report, err)
+	}
+	if doc, ok := ms.doc("people", "person:bad"); !ok || doc["name"] != "Bob" {
+		t.Errorf("Expected the replayed document to be stored, got %v", doc)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("Expected the dead-letter file to be empty, got %s", data)
+	}
+}
+
+func TestDeleteWhereDeadLetters(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("products",
+		map[string]interface{}{"id": "product:1", "sku": "old"},
+		map[string]interface{}{"id": "product:bad", "sku": "old"},
+	)
+	failing := failWrites(ms, "products", http.MethodDelete)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} },
+		torm.WithDeadLetter(torm.DeadLetterFile(path)))
+
+	report, err := products.DeleteWhere(context.Background(), map[string]interface{}{"sku": "old"}, nil)
+	if err != nil || report.Deleted != 1 || report.DeadLettered != 1 {
+		t.Fatalf("Unexpected report: %+v, %v", report, err)
+	}
+
+	failing.Store(false)
+	replay, err := products.ReplayDeadLetters(context.Background(), nil)
+	if err != nil || replay.Replayed != 1 {
+		t.Fatalf("Unexpected replay: %+v, %v", replay, err)
+	}
+	if len(ms.store("products")) != 0 {
+		t.Errorf("Expected the replay to delete the document, got %v", ms.store("products"))
+	}
+}
+
+func TestDeadLetterWriterCannotReplay(t *testing.T) {
+	ms := newMockServer(t)
+	failWrites(ms, "products", http.MethodPost)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	var out strings.Builder
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} },
+		torm.WithDeadLetter(torm.DeadLetterWriter(&out)))
+
+	result, err := products.CreateMany(context.Background(), []*TestProduct{{ID: "product:bad"}})
+	if err != nil || result.DeadLettered != 1 {
+		t.Fatalf("Unexpected result: %+v, %v", result, err)
+	}
+	if !strings.Contains(out.String(), `"doc_id":"product:bad"`) {
+		t.Errorf("Expected the dead letter to be written, got %s", out.String())
+	}
+	if _, err := products.ReplayDeadLetters(context.Background(), nil); !errors.Is(err, torm.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+}
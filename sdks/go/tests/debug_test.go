@@ -0,0 +1,119 @@
+package torm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestDebugSnapshot(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "name": "Ada", "email": "ada@example.com"},
+		map[string]interface{}{"id": "user:2", "name": "Bob", "email": "bob@example.com"},
+	)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:         strings.Replace(ms.URL, "http://", "http://admin:hunter2@", 1),
+		AuthToken:       "secret-token",
+		APIKey:          "secret-key",
+		Retry:           torm.RetryOptions{MaxAttempts: 3, Backoff: time.Millisecond},
+		AdaptiveTimeout: torm.WithAdaptiveTimeout(10*time.Millisecond, time.Second, 3),
+		RedactQuery:     torm.RedactFields("email"),
+		SlowThreshold:   time.Nanosecond,
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, torm.WithReadCache(torm.ReadCacheOptions{}))
+	plain := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	// One miss then two hits
+	for i := 0; i < 3; i++ {
+		if _, err := users.FindByID("user:1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first read of user:2 is retried once
+	failed := false
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Path != "/api/users/user:2" || failed {
+			return false
+		}
+		failed = true
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	})
+	if _, err := plain.FindByID("user:2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := plain.GetField("user:2", "name"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := client.Model("users", nil).Query().Where("email", "ada@example.com").Iter(ctx)
+	defer it.Close()
+	if !it.Next() {
+		t.Fatalf("Expected a match, got %v", it.Err())
+	}
+	plain.Watch(ctx, torm.WatchOptions{Interval: time.Hour})
+
+	snapshot := client.DebugSnapshot()
+	if cache := snapshot.ReadCaches["users"]; cache.Hits != 2 || cache.Misses != 1 || cache.Entries != 1 {
+		t.Errorf("Expected 2 hits and 1 miss on one entry, got %+v", cache)
+	}
+	if rate := snapshot.ReadCaches["users"].HitRate; rate < 0.66 || rate > 0.67 {
+		t.Errorf("Expected a hit rate of 2/3, got %f", rate)
+	}
+	if retries := snapshot.Retries; retries.MaxAttempts != 3 || retries.Retries != 1 || retries.Exhausted != 0 {
+		t.Errorf("Expected one retry, got %+v", retries)
+	}
+	if snapshot.Timeouts["read"].Samples == 0 {
+		t.Errorf("Expected read latency to be tracked, got %+v", snapshot.Timeouts)
+	}
+	if len(snapshot.Cursors) != 1 || snapshot.Cursors[0].Collection != "users" {
+		t.Errorf("Expected the open iterator, got %+v", snapshot.Cursors)
+	}
+	if snapshot.Watchers["users"] != 1 {
+		t.Errorf("Expected one active watcher, got %v", snapshot.Watchers)
+	}
+	if supported, known := snapshot.Capabilities.Observed[torm.CapabilityFieldProjection]; !known || supported {
+		t.Errorf("Expected projection to be known unsupported, got %v", snapshot.Capabilities.Observed)
+	}
+	if snapshot.Health.State != "healthy" || len(snapshot.SlowOperations) == 0 {
+		t.Errorf("Expected a healthy client with slow operations logged, got %+v", snapshot.Health)
+	}
+	if want := []string{"bearer", "api_key"}; strings.Join(snapshot.Auth, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected auth schemes %v, got %v", want, snapshot.Auth)
+	}
+
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Snapshot does not marshal: %v", err)
+	}
+	for _, secret := range []string{"secret-token", "secret-key", "hunter2", "ada@example.com"} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("Expected %q to be redacted from the snapshot", secret)
+		}
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(raw, &decoded)
+	for _, key := range []string{"endpoints", "health", "retries", "timeouts", "read_caches", "cursors", "watchers", "capabilities", "invalidation", "slow_operations"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("Expected %q in the snapshot JSON", key)
+		}
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for client.DebugSnapshot().Watchers["users"] != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := client.DebugSnapshot().Watchers["users"]; n != 0 {
+		t.Errorf("Expected the watcher to be gone once its context ended, got %d", n)
+	}
+}
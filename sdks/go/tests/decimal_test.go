@@ -0,0 +1,110 @@
+package torm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func priceSchema(coerce bool) map[string]torm.ValidationRule {
+	return map[string]torm.ValidationRule{
+		"price": {
+			Type:       "decimal",
+			Required:   true,
+			Scale:      torm.IntPtr(2),
+			MinDecimal: "0.01",
+			MaxDecimal: "9999.99",
+			Coerce:     coerce,
+		},
+	}
+}
+
+func TestDecimalValidation(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := client.Model("products", priceSchema(false))
+
+	cases := []struct {
+		value interface{}
+		err   string
+	}{
+		{"12.50", ""},
+		{"12.5", ""},
+		{"12.345", "at most 2 decimal places"},
+		{"0.00", "at least 0.01"},
+		{"10000", "at most 9999.99"},
+		{"12,50", "must be a decimal"},
+		{"1e3", "must be a decimal"},
+		{12.5, "must be a decimal string"},
+	}
+
+	for _, tc := range cases {
+		_, err := products.Create(map[string]interface{}{"price": tc.value})
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("Expected %v to be valid, got %v", tc.value, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("Expected %v to fail with %q, got %v", tc.value, tc.err, err)
+		}
+	}
+}
+
+func TestDecimalCoercion(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := client.Model("products", priceSchema(true))
+
+	for value, expected := range map[interface{}]string{
+		12.50:      "12.50",
+		"12.5":     "12.50",
+		"0012.500": "12.50",
+		7:          "7.00",
+	} {
+		created, err := products.Create(map[string]interface{}{"price": value})
+		if err != nil {
+			t.Fatalf("Create with %v failed: %v", value, err)
+		}
+		if created["price"] != expected {
+			t.Errorf("Expected %v to be stored as %q, got %v", value, expected, created["price"])
+		}
+	}
+
+	a, b := 0.1, 0.2
+	if _, err := products.Create(map[string]interface{}{"price": a + b}); err == nil {
+		t.Error("Expected inexact float to violate the scale")
+	}
+
+	if got, err := torm.NormalizeDecimal("-0.50", -1); err != nil || got != "-0.5" {
+		t.Errorf("Expected minimal form -0.5, got %q %v", got, err)
+	}
+}
+
+func TestDecimalQueryComparison(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("products",
+		map[string]interface{}{"id": "p1", "price": "9.99"},
+		map[string]interface{}{"id": "p2", "price": "10.00"},
+		map[string]interface{}{"id": "p3", "price": "100.50"},
+		map[string]interface{}{"id": "p4", "price": "2.00"},
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := client.Model("products", priceSchema(false))
+
+	// Lexically "9.99" > "10.00", numerically it is not
+	docs, err := products.Query().Filter("price", torm.Gt, "9.99").Sort("price", torm.Asc).Exec()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(docs) != 2 || docs[0]["id"] != "p2" || docs[1]["id"] != "p3" {
+		t.Errorf("Expected p2 and p3 in numeric order, got %v", docs)
+	}
+
+	docs, err = products.Query().Where("price", "10").Exec()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0]["id"] != "p2" {
+		t.Errorf("Expected equality to ignore trailing zeros, got %v", docs)
+	}
+}
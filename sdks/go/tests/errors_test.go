@@ -0,0 +1,276 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// exportedErrors lists a sample of every exported error the package defines
+var exportedErrors = map[string]struct {
+	err      error
+	category torm.Category
+}{
+	"ErrCheckpointMismatch":    {torm.ErrCheckpointMismatch, torm.CategoryUsage},
+	"ErrCircuitOpen":           {torm.ErrCircuitOpen, torm.CategoryCircuitOpen},
+	"ErrConflict":              {torm.ErrConflict, torm.CategoryConflict},
+	"ErrCursorExpired":         {torm.ErrCursorExpired, torm.CategoryUsage},
+	"ErrCursorIdle":            {torm.ErrCursorIdle, torm.CategoryTimeout},
+	"ErrCursorMismatch":        {torm.ErrCursorMismatch, torm.CategoryUsage},
+	"ErrDuplicateKey":          {torm.ErrDuplicateKey, torm.CategoryUsage},
+	"ErrEnvironmentExists":     {torm.ErrEnvironmentExists, torm.CategoryUsage},
+	"ErrIndexTooLarge":         {torm.ErrIndexTooLarge, torm.CategoryLimit},
+	"ErrInvalidCursor":         {torm.ErrInvalidCursor, torm.CategoryUsage},
+	"ErrInvalidFilter":         {torm.ErrInvalidFilter, torm.CategoryValidation},
+	"ErrForeignStatusLocation": {torm.ErrForeignStatusLocation, torm.CategoryUnsupported},
+	"ErrInvalidHint":           {torm.ErrInvalidHint, torm.CategoryValidation},
+	"ErrLeaseLost":             {torm.ErrLeaseLost, torm.CategoryConflict},
+	"ErrLoadShed":              {torm.ErrLoadShed, torm.CategoryLoadShed},
+	"ErrLookupTooLarge":        {torm.ErrLookupTooLarge, torm.CategoryLimit},
+	"ErrMigrationLocked":       {torm.ErrMigrationLocked, torm.CategoryConflict},
+	"ErrMissingID":             {torm.ErrMissingID, torm.CategoryContract},
+	"ErrMirrorQueueFull":       {torm.ErrMirrorQueueFull, torm.CategoryLimit},
+	"ErrNoHistory":             {torm.ErrNoHistory, torm.CategoryNotFound},
+	"ErrNoStatusLocation":      {torm.ErrNoStatusLocation, torm.CategoryUnsupported},
+	"ErrNotSupported":          {torm.ErrNotSupported, torm.CategoryUnsupported},
+	"ErrNotFound":              {torm.ErrNotFound, torm.CategoryNotFound},
+	"ErrOverloaded":            {torm.ErrOverloaded, torm.CategoryOverloaded},
+	"ErrQueryExists":           {torm.ErrQueryExists, torm.CategoryUsage},
+	"ErrSpillTooLarge":         {torm.ErrSpillTooLarge, torm.CategoryLimit},
+	"ErrSyntheticField":        {torm.ErrSyntheticField, torm.CategoryValidation},
+	"ErrTruncatedResult":       {torm.ErrTruncatedResult, torm.CategoryLimit},
+	"ErrUnknownEnvironment":    {torm.ErrUnknownEnvironment, torm.CategoryUsage},
+	"ErrUnknownQuery":          {torm.ErrUnknownQuery, torm.CategoryUsage},
+	"ErrUnknownTemplate":       {torm.ErrUnknownTemplate, torm.CategoryUsage},
+	"ErrUnknownType":           {torm.ErrUnknownType, torm.CategoryContract},
+	"ErrValidation":            {torm.ErrValidation, torm.CategoryValidation},
+	"ErrVersionConflict":       {torm.ErrVersionConflict, torm.CategoryConflict},
+	"ErrWriteProtected":        {torm.ErrWriteProtected, torm.CategoryForbidden},
+	"APIError":                 {&torm.APIError{StatusCode: 500}, torm.CategoryServer},
+	"BootstrapError":           {&torm.BootstrapError{Stage: torm.StageSeeds, Err: torm.ErrValidation}, torm.CategoryValidation},
+	"ChunkError":               {&torm.ChunkError{Chunk: 2}, torm.CategoryValidation},
+	"ConflictError":            {&torm.ConflictError{Collection: "users"}, torm.CategoryConflict},
+	"ConflictExhaustedError":   {&torm.ConflictExhaustedError{ID: "user:1"}, torm.CategoryConflict},
+	"ContractError":            {&torm.ContractError{Operation: "find"}, torm.CategoryContract},
+	"FidelityError":            {&torm.FidelityError{Type: "User"}, torm.CategoryContract},
+	"GuardError":               {&torm.GuardError{Op: torm.OpCreate, Err: errors.New("no")}, torm.CategoryForbidden},
+	"HydrationError":           {&torm.HydrationError{Index: 1, Err: errors.New("bad")}, torm.CategoryContract},
+	"MissingCapabilitiesError": {&torm.MissingCapabilitiesError{Missing: []torm.Capability{torm.CapabilityDistinct}}, torm.CategoryUnsupported},
+	"OpError":                  {&torm.OpError{Op: "FindByID", Collection: "users", Err: torm.ErrNotFound}, torm.CategoryNotFound},
+	"PanicError":               {&torm.PanicError{Op: "guard", Value: "boom"}, torm.CategoryPanic},
+	"PatchConflictError":       {&torm.PatchConflictError{ID: "user:1"}, torm.CategoryConflict},
+	"RequestError":             {&torm.RequestError{Err: context.DeadlineExceeded}, torm.CategoryTimeout},
+	"SlugExhaustedError":       {&torm.SlugExhaustedError{Field: "slug"}, torm.CategoryConflict},
+}
+
+// TestEveryErrorClassified parses the package and checks that each exported
+// error variable and error type is classified
+func TestEveryErrorClassified(t *testing.T) {
+	paths, err := filepath.Glob("../*.go")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("Failed to list package files: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	found := make(map[string]bool)
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", path, err)
+		}
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if vs, ok := spec.(*ast.ValueSpec); ok {
+						for _, name := range vs.Names {
+							if name.IsExported() && strings.HasPrefix(name.Name, "Err") {
+								found[name.Name] = true
+							}
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if d.Name.Name != "Error" || d.Recv == nil {
+					continue
+				}
+				recv := d.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok && ident.IsExported() {
+					found[ident.Name] = true
+				}
+			}
+		}
+	}
+
+	for name := range found {
+		sample, ok := exportedErrors[name]
+		if !ok {
+			t.Errorf("%s has no entry in the classification table", name)
+			continue
+		}
+		if got := torm.ErrorCategory(sample.err); got != sample.category {
+			t.Errorf("%s: expected %q, got %q", name, sample.category, got)
+		}
+		wrapped := fmt.Errorf("layer: %w", fmt.Errorf("inner: %w", sample.err))
+		if got := torm.ErrorCategory(wrapped); got != sample.category {
+			t.Errorf("%s wrapped: expected %q, got %q", name, sample.category, got)
+		}
+	}
+}
+
+func TestStatusErrorCategories(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ann"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", nil)
+
+	var status int
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method == http.MethodPut {
+			writeJSON(w, status, map[string]interface{}{"error": "nope"})
+			return true
+		}
+		return false
+	})
+
+	cases := []struct {
+		status    int
+		category  torm.Category
+		retryable bool
+	}{
+		{400, torm.CategoryValidation, false},
+		{401, torm.CategoryForbidden, false},
+		{404, torm.CategoryNotFound, false},
+		{409, torm.CategoryConflict, false},
+		{412, torm.CategoryConflict, false},
+		{418, torm.CategoryRejected, false},
+		{422, torm.CategoryValidation, false},
+		{429, torm.CategoryOverloaded, true},
+		{500, torm.CategoryServer, true},
+		{501, torm.CategoryUnsupported, false},
+		{503, torm.CategoryOverloaded, true},
+		{504, torm.CategoryTimeout, true},
+	}
+	for _, tc := range cases {
+		status = tc.status
+		_, err := users.Update("user:1", map[string]interface{}{"name": "Bea"})
+		if got := torm.ErrorCategory(err); got != tc.category {
+			t.Errorf("Status %d: expected %q, got %q (%v)", tc.status, tc.category, got, err)
+		}
+		if torm.IsRetryable(err) != tc.retryable {
+			t.Errorf("Status %d: expected retryable %v", tc.status, tc.retryable)
+		}
+	}
+
+	if _, err := users.Update("user:1", map[string]interface{}{"name": "Bea"}); !torm.IsTimeout(err) {
+		t.Errorf("Expected IsTimeout for 504, got %v", err)
+	}
+	status = 404
+	if _, err := users.Update("user:1", nil); !torm.IsNotFound(err) {
+		t.Errorf("Expected IsNotFound for 404, got %v", err)
+	}
+}
+
+func TestErrNotFoundEverywhere(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	model := client.Model("users", nil)
+
+	_, findErr := users.FindByID("user:9")
+	_, updateErr := users.Update("user:9", &TestUser{Name: "Nobody"})
+	deleteErr := users.Delete("user:9")
+	doc, modelErr := model.FindByID("user:9")
+
+	for name, err := range map[string]error{
+		"Collection.FindByID": findErr,
+		"Collection.Update":   updateErr,
+		"Collection.Delete":   deleteErr,
+		"Model.FindByID":      modelErr,
+	} {
+		if !errors.Is(err, torm.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+			continue
+		}
+		var opErr *torm.OpError
+		if !errors.As(err, &opErr) || opErr.Collection != "users" || opErr.ID != "user:9" {
+			t.Errorf("%s: expected the collection and id in %v", name, err)
+		}
+	}
+	if doc != nil {
+		t.Errorf("Expected no document from Model.FindByID, got %v", doc)
+	}
+}
+
+func TestErrorPredicates(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Timeout: 20 * time.Millisecond})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	model := client.Model("users", map[string]torm.ValidationRule{"name": {Required: true}})
+
+	_, err := users.FindByID("user:missing")
+	if !torm.IsNotFound(err) || torm.IsRetryable(err) {
+		t.Errorf("Expected a non-retryable not found, got %v", err)
+	}
+
+	_, err = model.Create(map[string]interface{}{})
+	if !torm.IsValidation(err) {
+		t.Errorf("Expected IsValidation, got %v", err)
+	}
+
+	joined := errors.Join(fmt.Errorf("create: %w", err), torm.ErrConflict)
+	if !torm.IsValidation(joined) {
+		t.Errorf("Expected the first cause of a joined error to win, got %q", torm.ErrorCategory(joined))
+	}
+
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		time.Sleep(200 * time.Millisecond)
+		return false
+	})
+	_, err = model.FindByID("user:1")
+	if !torm.IsTimeout(err) || !torm.IsRetryable(err) {
+		t.Errorf("Expected a retryable timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = users.DeleteWhere(ctx, nil, nil)
+	if got := torm.ErrorCategory(err); got != torm.CategoryCanceled {
+		t.Errorf("Expected canceled, got %q (%v)", got, err)
+	}
+	deadline, stop := context.WithTimeout(context.Background(), 0)
+	defer stop()
+	<-deadline.Done()
+	if !torm.IsTimeout(deadline.Err()) {
+		t.Errorf("Expected an expired deadline to be a timeout")
+	}
+
+	ms.Close()
+	_, err = model.FindByID("user:1")
+	if got := torm.ErrorCategory(err); got != torm.CategoryNetwork || !torm.IsRetryable(err) {
+		t.Errorf("Expected a retryable network error, got %q (%v)", got, err)
+	}
+
+	if torm.ErrorCategory(nil) != torm.CategoryNone || torm.ErrorCategory(errors.New("other")) != torm.CategoryUnknown {
+		t.Errorf("Unexpected categories for nil and foreign errors")
+	}
+	if !torm.IsCircuitOpen(fmt.Errorf("find: %w", torm.ErrCircuitOpen)) {
+		t.Errorf("Expected IsCircuitOpen")
+	}
+}
@@ -0,0 +1,90 @@
+package torm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// docReads returns the If-None-Match header of every read of a document
+func docReads(ms *mockServer, id string) []string {
+	var conditions []string
+	for _, req := range ms.requestLog() {
+		if req.Method == "GET" && req.Path == "/api/docs/"+id {
+			conditions = append(conditions, req.Header.Get("If-None-Match"))
+		}
+	}
+	return conditions
+}
+
+func TestReadCacheRevalidatesWithETags(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableDocumentETags()
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "First"})
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	docs := newTenantDocs(client, torm.WithReadCache(torm.ReadCacheOptions{TTL: time.Minute}))
+
+	if _, err := docs.FindByID("doc:1"); err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	clock.Advance(time.Minute)
+	doc, err := docs.FindByID("doc:1")
+	if err != nil || doc.Title != "First" {
+		t.Fatalf("Expected the revalidated copy, got %+v: %v", doc, err)
+	}
+	reads := docReads(ms, "doc:1")
+	if len(reads) != 2 || reads[0] != "" || reads[1] == "" {
+		t.Fatalf("Expected an unconditional read then a conditional one, got %q", reads)
+	}
+	if stats := docs.ReadCacheStats(); stats.Revalidated != 1 {
+		t.Errorf("Expected one revalidation, got %+v", stats)
+	}
+
+	// A 304 restarts the TTL
+	clock.Advance(30 * time.Second)
+	docs.FindByID("doc:1")
+	if n := len(docReads(ms, "doc:1")); n != 2 {
+		t.Errorf("Expected the revalidated copy to be fresh, got %d reads", n)
+	}
+
+	// A changed document is sent in full and replaces the cached copy
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "Second"})
+	clock.Advance(time.Minute)
+	if doc, _ := docs.FindByID("doc:1"); doc.Title != "Second" {
+		t.Errorf("Expected the changed document, got %+v", doc)
+	}
+	clock.Advance(time.Minute)
+	if doc, _ := docs.FindByID("doc:1"); doc.Title != "Second" {
+		t.Errorf("Expected the new copy to be revalidated, got %+v", doc)
+	}
+	if stats := docs.ReadCacheStats(); stats.Revalidated != 2 {
+		t.Errorf("Expected two revalidations, got %+v", stats)
+	}
+}
+
+func TestReadCacheWithoutETags(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "First"})
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	docs := newTenantDocs(client, torm.WithReadCache(torm.ReadCacheOptions{TTL: time.Minute}))
+
+	docs.FindByID("doc:1")
+	docs.FindByID("doc:1")
+	clock.Advance(time.Minute)
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "Second"})
+	doc, err := docs.FindByID("doc:1")
+	if err != nil || doc.Title != "Second" {
+		t.Fatalf("Expected the expired copy to be read again, got %+v: %v", doc, err)
+	}
+
+	reads := docReads(ms, "doc:1")
+	if len(reads) != 2 || reads[0] != "" || reads[1] != "" {
+		t.Errorf("Expected two unconditional reads, got %q", reads)
+	}
+	if stats := docs.ReadCacheStats(); stats.Hits != 1 || stats.Revalidated != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,346 @@
+package torm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedItems(ms *mockServer, n int) {
+	for i := 1; i <= n; i++ {
+		ms.seed("items", map[string]interface{}{
+			"id":    fmt.Sprintf("item:%03d", i),
+			"name":  fmt.Sprintf("Item %d", i),
+			"price": float64(i) * 1.5,
+		})
+	}
+}
+
+// failQueryPage makes the mock fail the nth query request with a 500
+func failQueryPage(ms *mockServer, n int) {
+	calls := 0
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.URL.Path != "/api/items/query" {
+			return false
+		}
+		calls++
+		if calls != n {
+			return false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "injected fault"})
+		return true
+	})
+}
+
+func TestExportSnapshotResume(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 23)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	var full bytes.Buffer
+	done, err := items.ExportSnapshot(&full, torm.ExportOptions{PageSize: 5})
+	if err != nil {
+		t.Fatalf("Uninterrupted export failed: %v", err)
+	}
+	if done.Documents != 23 || !done.Complete {
+		t.Fatalf("Unexpected final checkpoint: %+v", done)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "items.jsonl")
+	opts := torm.ExportOptions{
+		PageSize:        5,
+		CheckpointEvery: 3,
+		Checkpoints:     torm.FileCheckpoint(filepath.Join(dir, "items.checkpoint")),
+		Resume:          true,
+	}
+
+	failQueryPage(ms, 3)
+	if _, err := items.ExportSnapshotFile(path, opts); err == nil {
+		t.Fatal("Expected the injected fault to interrupt the export")
+	}
+
+	stored, err := opts.Checkpoints.Load()
+	if err != nil || stored == nil {
+		t.Fatalf("Expected a checkpoint after interruption: %v", err)
+	}
+	if stored.Complete || stored.Documents != 9 || stored.LastID != "item:009" {
+		t.Errorf("Unexpected checkpoint after interruption: %+v", stored)
+	}
+
+	ms.setIntercept(nil)
+	resumed, err := items.ExportSnapshotFile(path, opts)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if resumed.Documents != 23 || resumed.SHA256 != done.SHA256 {
+		t.Errorf("Resumed checkpoint differs from uninterrupted export: %+v vs %+v", resumed, done)
+	}
+
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, full.Bytes()) {
+		t.Errorf("Resumed export differs from uninterrupted export:\n%s\nvs\n%s", got, full.Bytes())
+	}
+}
+
+func TestExportSnapshotResumeDetectsCorruption(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 12)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "items.jsonl")
+	opts := torm.ExportOptions{
+		PageSize:        4,
+		CheckpointEvery: 2,
+		Checkpoints:     torm.KeyCheckpoint(client, "export:items"),
+		Resume:          true,
+	}
+
+	failQueryPage(ms, 2)
+	if _, err := items.ExportSnapshotFile(path, opts); err == nil {
+		t.Fatal("Expected the injected fault to interrupt the export")
+	}
+	ms.setIntercept(nil)
+
+	data, _ := os.ReadFile(path)
+	data[0] = 'X'
+	os.WriteFile(path, data, 0o644)
+
+	_, err := items.ExportSnapshotFile(path, opts)
+	if !errors.Is(err, torm.ErrCheckpointMismatch) {
+		t.Errorf("Expected ErrCheckpointMismatch for corrupted file, got %v", err)
+	}
+
+	os.WriteFile(path, data[:10], 0o644)
+	_, err = items.ExportSnapshotFile(path, opts)
+	if !errors.Is(err, torm.ErrCheckpointMismatch) {
+		t.Errorf("Expected ErrCheckpointMismatch for truncated file, got %v", err)
+	}
+}
+
+// slowWriter takes delay over every write, as a slow upload would
+type slowWriter struct {
+	delay time.Duration
+	buf   bytes.Buffer
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.buf.Write(p)
+}
+
+func TestExportChunkTrailers(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 10)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	var out bytes.Buffer
+	done, err := client.Model("items", nil).ExportSnapshot(&out, torm.ExportOptions{PageSize: 4, ChunkSize: 3})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if done.Documents != 10 || done.Chunks != 4 {
+		t.Errorf("Unexpected checkpoint: %+v", done)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 14 {
+		t.Fatalf("Expected 10 documents and 4 trailers, got %d lines", len(lines))
+	}
+	for i, n := range map[int]int{3: 3, 7: 3, 11: 3, 13: 1} {
+		var trailer map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &trailer); err != nil || trailer["count"] != float64(n) || trailer["sha256"] == "" {
+			t.Errorf("Line %d: expected a trailer for %d documents, got %s", i+1, n, lines[i])
+		}
+	}
+	if !strings.HasPrefix(lines[3], `{"_chunk":1,`) || !strings.HasPrefix(lines[13], `{"_chunk":4,`) {
+		t.Errorf("Expected trailers numbered from 1, got %s and %s", lines[3], lines[13])
+	}
+
+	target := newMockServer(t)
+	result, err := torm.NewClient(&torm.ClientOptions{BaseURL: target.URL}).Model("items", nil).
+		ImportSnapshot(bytes.NewReader(out.Bytes()), torm.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 10 || result.Chunks != 4 || result.Lines != 14 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+	if doc, _ := target.doc("items", "item:010"); doc == nil || doc["_chunk"] != nil {
+		t.Errorf("Expected the last document without trailer fields, got %v", doc)
+	}
+}
+
+func TestExportChunkedResume(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 23)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	var full bytes.Buffer
+	if _, err := items.ExportSnapshot(&full, torm.ExportOptions{PageSize: 5, ChunkSize: 4}); err != nil {
+		t.Fatalf("Uninterrupted export failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "items.jsonl")
+	opts := torm.ExportOptions{
+		PageSize:        5,
+		ChunkSize:       4,
+		CheckpointEvery: 3,
+		Checkpoints:     torm.FileCheckpoint(filepath.Join(dir, "items.checkpoint")),
+		Resume:          true,
+	}
+
+	failQueryPage(ms, 3)
+	if _, err := items.ExportSnapshotFile(path, opts); err == nil {
+		t.Fatal("Expected the injected fault to interrupt the export")
+	}
+	stored, _ := opts.Checkpoints.Load()
+	if stored == nil || stored.Documents != 8 || stored.Chunks != 2 {
+		t.Fatalf("Expected a checkpoint on the chunk boundary after 8 documents, got %+v", stored)
+	}
+
+	ms.setIntercept(nil)
+	if _, err := items.ExportSnapshotFile(path, opts); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, full.Bytes()) {
+		t.Errorf("Resumed export differs from uninterrupted export:\n%s\nvs\n%s", got, full.Bytes())
+	}
+}
+
+func TestExportThrottledBySlowWriter(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 12)
+
+	var mu sync.Mutex
+	var fetched []time.Time
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.URL.Path == "/api/items/query" {
+			mu.Lock()
+			fetched = append(fetched, time.Now())
+			mu.Unlock()
+		}
+		return false
+	})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	const delay = 30 * time.Millisecond
+	sink := &slowWriter{delay: delay}
+	if _, err := client.Model("items", nil).ExportSnapshot(sink, torm.ExportOptions{PageSize: 4, ChunkSize: 4}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fetched) != 4 {
+		t.Fatalf("Expected 4 page requests, got %d", len(fetched))
+	}
+	for i := 1; i < len(fetched); i++ {
+		if gap := fetched[i].Sub(fetched[i-1]); gap < delay {
+			t.Errorf("Page %d was fetched %v after the previous one, before the writer took it", i+1, gap)
+		}
+	}
+	if n := strings.Count(sink.buf.String(), "\n"); n != 15 {
+		t.Errorf("Expected 12 documents and 3 trailers, got %d lines", n)
+	}
+}
+
+func TestExportThroughPipeIntoImport(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 25)
+	target := newMockServer(t)
+	source := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("items", nil)
+	sink := torm.NewClient(&torm.ClientOptions{BaseURL: target.URL}).Model("items", nil)
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := source.ExportSnapshot(pw, torm.ExportOptions{PageSize: 7, ChunkSize: 5})
+		pw.CloseWithError(err)
+	}()
+
+	result, err := sink.ImportSnapshot(pr, torm.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 25 || result.Chunks != 5 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+}
+
+func TestImportDetectsCorruptChunk(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 10)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	var out bytes.Buffer
+	if _, err := client.Model("items", nil).ExportSnapshot(&out, torm.ExportOptions{ChunkSize: 3}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	snapshot := out.String()
+
+	// Chunk 2 holds items 4 to 6; its trailer is line 8
+	corrupted := strings.Replace(snapshot, `"Item 5"`, `"Item X"`, 1)
+	target := newMockServer(t)
+	items := torm.NewClient(&torm.ClientOptions{BaseURL: target.URL}).Model("items", nil)
+	result, err := items.ImportSnapshot(strings.NewReader(corrupted), torm.ImportOptions{})
+
+	var chunkErr *torm.ChunkError
+	if !errors.As(err, &chunkErr) {
+		t.Fatalf("Expected a ChunkError, got %v", err)
+	}
+	if chunkErr.Chunk != 2 || chunkErr.Line != 8 || chunkErr.Problem != "hash mismatch" {
+		t.Errorf("Unexpected chunk error: %+v", chunkErr)
+	}
+	if result.Imported != 3 || result.Chunks != 1 || result.Lines != 3 {
+		t.Errorf("Expected only the first chunk imported, got %+v", result)
+	}
+	if _, ok := target.doc("items", "item:004"); ok {
+		t.Error("Expected no document of the corrupt chunk to be written")
+	}
+
+	// A snapshot cut after a document loses the last trailer
+	cut := snapshot[:strings.LastIndex(strings.TrimSuffix(snapshot, "\n"), "\n")+1]
+	_, err = items.ImportSnapshot(strings.NewReader(cut), torm.ImportOptions{})
+	if !errors.As(err, &chunkErr) || chunkErr.Chunk != 4 || chunkErr.Problem != "has no trailer" {
+		t.Errorf("Expected the missing trailer of chunk 4, got %v", err)
+	}
+}
+
+func TestExportSnapshotClientSideFilters(t *testing.T) {
+	ms := newMockServer(t)
+	for i := 1; i <= 12; i++ {
+		ms.seed("items", map[string]interface{}{"id": fmt.Sprintf("item:%03d", i), "price": fmt.Sprintf("%d.00", i)})
+	}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", priceSchema(false))
+
+	// Decimal comparisons run client-side, so most server pages shrink
+	var out bytes.Buffer
+	done, err := items.ExportSnapshot(&out, torm.ExportOptions{
+		PageSize: 4,
+		Filters:  []torm.QueryFilter{{Field: "price", Operator: torm.Gt, Value: "9.50"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Documents != 3 || !done.Complete || done.LastID != "item:012" {
+		t.Fatalf("Expected items 10 to 12 exported, got %+v:\n%s", done, out.String())
+	}
+}
@@ -0,0 +1,109 @@
+package torm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+	"github.com/toonstore/torm-go/tormtest"
+)
+
+func accountSchema() map[string]torm.ValidationRule {
+	return map[string]torm.ValidationRule{
+		"email":    {Type: "str", Required: true, Email: true},
+		"handle":   {Type: "str", Required: true, MinLength: torm.IntPtr(3), MaxLength: torm.IntPtr(8)},
+		"sku":      {Type: "str", Pattern: `^[A-Z]{3}-\d{4}$`},
+		"plan":     {Type: "str", Required: true, Pattern: `^(free|pro|team)$`},
+		"age":      {Type: "int", Min: torm.Float64Ptr(18), Max: torm.Float64Ptr(99)},
+		"score":    {Type: "float", Min: torm.Float64Ptr(0), Max: torm.Float64Ptr(1)},
+		"balance":  {Type: "decimal", Scale: torm.IntPtr(2), MinDecimal: "0.01", MaxDecimal: "500"},
+		"site":     {Type: "str", URL: true},
+		"active":   {Type: "bool", Required: true},
+		"settings": {Type: "map"},
+		"tags":     {Type: "slice"},
+	}
+}
+
+func TestFactoryBuildsValidDocuments(t *testing.T) {
+	accounts := torm.NewClient(nil).Model("accounts", accountSchema())
+	factory := tormtest.NewFactory(accounts.Schema())
+
+	docs, err := factory.BuildN(100, nil)
+	if err != nil {
+		t.Fatalf("BuildN failed: %v", err)
+	}
+	emails := make(map[string]bool)
+	for _, doc := range docs {
+		if err := accounts.Validate(doc, torm.ValidationFull); err != nil {
+			t.Fatalf("Generated document %v is invalid: %v", doc, err)
+		}
+		emails[doc["email"].(string)] = true
+	}
+	if len(emails) != 100 {
+		t.Errorf("Expected 100 distinct emails, got %d", len(emails))
+	}
+}
+
+func TestFactoryOverridesAndSequences(t *testing.T) {
+	factory := tormtest.NewFactory(accountSchema()).
+		Sequence("email", tormtest.Emails("corp.test")).
+		Sequence("source", func(n int) interface{} { return n })
+
+	first, err := factory.Build(map[string]interface{}{"plan": "team", "extra": true})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	second, err := factory.Build(nil)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if first["plan"] != "team" || first["extra"] != true {
+		t.Errorf("Expected overrides to apply, got %v", first)
+	}
+	if first["email"] != "user1@corp.test" || second["email"] != "user2@corp.test" {
+		t.Errorf("Expected sequenced emails, got %v and %v", first["email"], second["email"])
+	}
+	if first["source"] != 1 || second["source"] != 2 {
+		t.Errorf("Expected sequences outside the schema to apply, got %v and %v", first["source"], second["source"])
+	}
+}
+
+func TestFactoryUnsatisfiableRules(t *testing.T) {
+	cases := map[string]torm.ValidationRule{
+		"age":    {Type: "int", Min: torm.Float64Ptr(5.5), Max: torm.Float64Ptr(5.9)},
+		"code":   {Type: "str", Pattern: `^\bx`},
+		"email":  {Type: "str", Email: true, MaxLength: torm.IntPtr(4)},
+		"custom": {Validate: func(interface{}) bool { return false }},
+	}
+	for field, rules := range cases {
+		factory := tormtest.NewFactory(map[string]torm.ValidationRule{field: rules})
+		_, err := factory.Build(nil)
+		if !errors.Is(err, tormtest.ErrUnsatisfiable) || !strings.Contains(err.Error(), "'"+field+"'") {
+			t.Errorf("Expected an unsatisfiable error naming %s, got %v", field, err)
+		}
+		if _, err := factory.Build(map[string]interface{}{field: "given"}); err != nil {
+			t.Errorf("Expected an override to stand in for %s, got %v", field, err)
+		}
+	}
+}
+
+func TestFactoryCreateN(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	accounts := client.Model("accounts", accountSchema())
+
+	created, err := tormtest.NewFactory(accounts.Schema()).CreateN(accounts, 5, map[string]interface{}{"active": true})
+	if err != nil {
+		t.Fatalf("CreateN failed: %v", err)
+	}
+	if len(created) != 5 || len(ms.store("accounts")) != 5 {
+		t.Errorf("Expected 5 stored documents, got %d created and %d stored", len(created), len(ms.store("accounts")))
+	}
+	for _, doc := range ms.store("accounts") {
+		if doc["active"] != true {
+			t.Errorf("Expected the override to be stored, got %v", doc)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package torm_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestFailover(t *testing.T) {
+	primary, standby := newMockServer(t), newMockServer(t)
+	unavailable(primary)
+	standby.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada"})
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURLs:         []string{primary.URL, standby.URL},
+		FailbackInterval: time.Minute,
+		Clock:            clock.Now,
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if client.LastEndpoint() != "" {
+		t.Errorf("Expected no endpoint before the first request, got %q", client.LastEndpoint())
+	}
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Fatalf("Expected the standby to answer, got %v", err)
+	}
+	if client.LastEndpoint() != standby.URL || client.DebugSnapshot().LastEndpoint != standby.URL {
+		t.Errorf("Expected the standby as last endpoint, got %q", client.LastEndpoint())
+	}
+
+	// Later requests start at the standby, and a 4xx stays there
+	sent := len(primary.requestLog())
+	if _, err := users.FindByID("user:404"); !torm.IsNotFound(err) {
+		t.Fatalf("Expected the standby's not found, got %v", err)
+	}
+	if len(primary.requestLog()) != sent {
+		t.Error("Expected the primary skipped after a failover")
+	}
+
+	// Once the interval is over, the primary is probed and taken back
+	primary.setIntercept(nil)
+	primary.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada"})
+	clock.Advance(time.Minute)
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if client.LastEndpoint() != primary.URL || len(primary.requestLog()) != sent+1 {
+		t.Errorf("Expected the recovered primary to answer, got %q", client.LastEndpoint())
+	}
+}
+
+func TestFailoverBoundsAttempts(t *testing.T) {
+	primary, standby := newMockServer(t), newMockServer(t)
+	unavailable(primary)
+	unavailable(standby)
+
+	// Without retries each endpoint is tried once
+	client := torm.NewClient(&torm.ClientOptions{BaseURLs: []string{primary.URL, standby.URL}})
+	if _, err := client.Model("users", nil).FindByID("user:1"); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if p, s := len(primary.requestLog()), len(standby.requestLog()); p != 1 || s != 1 {
+		t.Errorf("Expected one attempt per endpoint, got %d and %d", p, s)
+	}
+	if client.LastEndpoint() != "" {
+		t.Errorf("Expected no endpoint to have answered, got %q", client.LastEndpoint())
+	}
+
+	// With retries MaxAttempts covers every endpoint
+	retried := torm.NewClient(&torm.ClientOptions{
+		BaseURLs: []string{primary.URL, standby.URL},
+		Retry:    torm.RetryOptions{MaxAttempts: 3, Backoff: time.Millisecond},
+	})
+	if _, err := retried.Model("users", nil).FindByID("user:1"); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if total := len(primary.requestLog()) + len(standby.requestLog()); total != 2+3 {
+		t.Errorf("Expected 3 attempts in all, got %d", total-2)
+	}
+}
+
+func TestFailoverWrites(t *testing.T) {
+	primary, standby := newMockServer(t), newMockServer(t)
+	failWith(primary, "/users", http.StatusInternalServerError, "failed")
+	client := torm.NewClient(&torm.ClientOptions{BaseURLs: []string{primary.URL, standby.URL}})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	// The primary may have written the document before failing
+	if _, err := users.Create(&TestUser{ID: "user:1", Name: "Ada"}); err == nil {
+		t.Fatal("Expected the primary's error")
+	}
+	if len(standby.requestLog()) != 0 {
+		t.Error("Expected a failed create not to be sent again")
+	}
+
+	// A create the primary never received goes to the standby
+	unreachable := torm.NewClient(&torm.ClientOptions{BaseURLs: []string{closedURL(), standby.URL}})
+	created := torm.NewCollection(unreachable, "users", func() *TestUser { return &TestUser{} })
+	if _, err := created.Create(&TestUser{ID: "user:1", Name: "Ada"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(standby.requestLog()) != 1 || unreachable.LastEndpoint() != standby.URL {
+		t.Errorf("Expected the create sent to the standby, got %d requests", len(standby.requestLog()))
+	}
+}
+
+func TestFailoverHealth(t *testing.T) {
+	primary, standby := newMockServer(t), newMockServer(t)
+	unavailable(primary)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURLs: []string{primary.URL, standby.URL},
+		Breaker:  &torm.BreakerOptions{FailureThreshold: 3},
+	})
+
+	health, err := client.Health()
+	if err != nil || health["status"] != "ok" {
+		t.Fatalf("Expected the standby's health, got %v, %v", health, err)
+	}
+	if client.LastEndpoint() != standby.URL {
+		t.Errorf("Expected the standby as last endpoint, got %q", client.LastEndpoint())
+	}
+	if info, err := client.Info(); err != nil || info["name"] != "TORM Server" {
+		t.Fatalf("Expected the standby's info, got %v, %v", info, err)
+	}
+	if state := client.ProbeHealth(context.Background()); state != torm.StateHealthy {
+		t.Errorf("Expected a healthy probe from the standby, got %s", state)
+	}
+
+	// Health checks count for the breaker like other requests
+	unavailable(standby)
+	client.Health()
+	if _, err := client.Info(); !torm.IsCircuitOpen(err) {
+		t.Fatalf("Expected the failed health checks to open the circuit, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package torm_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// DriftedUser's ToMap stopped persisting email and misspells nickname
+type DriftedUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Nickname string `json:"nickname,omitempty"`
+	Age      int    `json:"age"`
+}
+
+func (u *DriftedUser) GetID() string   { return u.ID }
+func (u *DriftedUser) SetID(id string) { u.ID = id }
+func (u *DriftedUser) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        u.ID,
+		"name":      u.Name,
+		"nick_name": u.Nickname,
+		"age":       u.Age + 1,
+	}
+}
+
+func driftedUser() *DriftedUser {
+	return &DriftedUser{ID: "user:1", Name: "Ada", Email: "ada@example.com", Nickname: "ada", Age: 36}
+}
+
+func TestFidelityCheckFailsDriftedModel(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *DriftedUser { return &DriftedUser{} },
+		torm.WithModelFidelityCheck(torm.FidelityFail))
+
+	_, err := users.Create(driftedUser())
+	var fidelity *torm.FidelityError
+	if !errors.As(err, &fidelity) {
+		t.Fatalf("Expected a FidelityError, got %v", err)
+	}
+	if fidelity.Type != "torm_test.DriftedUser" {
+		t.Errorf("Unexpected type name %q", fidelity.Type)
+	}
+
+	want := map[string]string{
+		"age":       "value differs",
+		"email":     "missing from ToMap",
+		"nick_name": "extra in ToMap",
+		"nickname":  "missing from ToMap",
+	}
+	if len(fidelity.Issues) != len(want) {
+		t.Fatalf("Expected %d issues, got %+v", len(want), fidelity.Issues)
+	}
+	for _, issue := range fidelity.Issues {
+		if want[issue.Field] != issue.Problem {
+			t.Errorf("Field %s: expected %q, got %q", issue.Field, want[issue.Field], issue.Problem)
+		}
+	}
+	if torm.ErrorCategory(err) != torm.CategoryContract {
+		t.Errorf("Expected a contract error, got %s", torm.ErrorCategory(err))
+	}
+
+	// The result is kept, so later writes fail the same way without a new check
+	if err := users.Save(driftedUser()); !errors.As(err, &fidelity) {
+		t.Errorf("Expected Save to fail too, got %v", err)
+	}
+	if _, ok := ms.doc("users", "user:1"); ok {
+		t.Error("Expected nothing written")
+	}
+}
+
+func TestFidelityCheckWarnsOnce(t *testing.T) {
+	ms := newMockServer(t)
+	var logs bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+	users := torm.NewCollection(client, "users", func() *DriftedUser { return &DriftedUser{} },
+		torm.WithModelFidelityCheck(torm.FidelityWarn))
+
+	for i := 0; i < 3; i++ {
+		if _, err := users.Upsert(driftedUser()); err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+	if n := strings.Count(logs.String(), "does not match its JSON form"); n != 1 {
+		t.Errorf("Expected one warning, got %d: %s", n, logs.String())
+	}
+	if !strings.Contains(logs.String(), "'email' missing from ToMap") {
+		t.Errorf("Expected the missing field in the warning, got %s", logs.String())
+	}
+}
+
+func TestFidelityCheckPassesCleanModel(t *testing.T) {
+	ms := newMockServer(t)
+	var logs bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} },
+		torm.WithModelFidelityCheck(torm.FidelityFail))
+
+	if _, err := products.Create(&TestProduct{ID: "product:1", Name: "Anvil", Price: 10, Stock: 2, SKU: "A-1"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := products.Save(&TestProduct{ID: "product:1", Name: "Anvil"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected no warnings, got %s", logs.String())
+	}
+}
+
+func TestFidelityCheckAfterZeroValuedWrite(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *DriftedUser { return &DriftedUser{} },
+		torm.WithModelFidelityCheck(torm.FidelityFail))
+
+	// Nothing to compare yet: every drifted field is zero
+	if _, err := users.Create(&DriftedUser{ID: "user:1"}); err != nil {
+		t.Fatalf("Expected the zero-valued write to pass, got %v", err)
+	}
+
+	err := users.Save(driftedUser())
+	var fidelity *torm.FidelityError
+	if !errors.As(err, &fidelity) {
+		t.Fatalf("Expected the first write with values to fail, got %v", err)
+	}
+	var fields []string
+	for _, issue := range fidelity.Issues {
+		fields = append(fields, issue.Field)
+	}
+	if got := strings.Join(fields, ","); got != "age,email,nick_name,nickname" {
+		t.Errorf("Expected the drifted fields reported, got %s", got)
+	}
+}
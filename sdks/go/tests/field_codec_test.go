@@ -0,0 +1,130 @@
+package torm_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+type TestPage struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	RenderedHTML string `json:"renderedHtml"`
+}
+
+func (p *TestPage) GetID() string   { return p.ID }
+func (p *TestPage) SetID(id string) { p.ID = id }
+func (p *TestPage) ToMap() map[string]interface{} {
+	return map[string]interface{}{"id": p.ID, "title": p.Title, "renderedHtml": p.RenderedHTML}
+}
+
+var largeHTML = strings.Repeat("<section><h2>Heading</h2><p>Some repeated paragraph text.</p></section>\n", 2000)
+
+func TestFieldCodecRoundTrip(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("pages", map[string]interface{}{"id": "page:legacy", "title": "Old", "renderedHtml": "<p>plain</p>"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	pages := torm.NewCollection(client, "pages", func() *TestPage { return &TestPage{} },
+		torm.WithFieldCodec("renderedHtml", torm.GzipBase64()))
+
+	created, err := pages.Create(&TestPage{ID: "page:1", Title: "Home", RenderedHTML: largeHTML})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.RenderedHTML != largeHTML {
+		t.Errorf("Expected the created page to be decoded, got %d bytes", len(created.RenderedHTML))
+	}
+
+	stored, _ := ms.doc("pages", "page:1")
+	html, _ := stored["renderedHtml"].(string)
+	if !strings.HasPrefix(html, "gzip:") || len(html) > len(largeHTML)/10 {
+		t.Errorf("Expected a compressed stored form, got %d bytes: %.40s", len(html), html)
+	}
+	if stored["title"] != "Home" {
+		t.Errorf("Expected other fields stored as is, got %v", stored["title"])
+	}
+
+	page, err := pages.FindByID("page:1")
+	if err != nil || page.RenderedHTML != largeHTML {
+		t.Fatalf("Expected FindByID to decode, got %v", err)
+	}
+	all, err := pages.Find()
+	if err != nil || len(all) != 2 || all[0].RenderedHTML != largeHTML {
+		t.Fatalf("Expected Find to decode, got %v", err)
+	}
+	if all[1].RenderedHTML != "<p>plain</p>" {
+		t.Errorf("Expected values written before the codec to read as is, got %q", all[1].RenderedHTML)
+	}
+
+	stats := pages.FieldCodecStats()["renderedHtml"]
+	if stats.Encoded != 1 || stats.Decoded < 2 || stats.BytesOut*10 > stats.BytesIn {
+		t.Errorf("Unexpected codec stats: %+v", stats)
+	}
+}
+
+func TestFieldCodecRejectsFilters(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	pages := client.Model("pages", nil).WithFieldCodec("renderedHtml", torm.GzipBase64())
+	if _, err := pages.Create(map[string]interface{}{"id": "page:1", "title": "Home", "renderedHtml": largeHTML}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	before := len(ms.requestLog())
+	_, err := pages.Query().Filter("renderedHtml", torm.Contains, "Heading").Exec()
+	if !errors.Is(err, torm.ErrInvalidFilter) || !strings.Contains(err.Error(), "renderedHtml") {
+		t.Errorf("Expected the filter to be rejected, got %v", err)
+	}
+	if len(ms.requestLog()) != before {
+		t.Error("Expected a rejected query not to be sent")
+	}
+
+	docs, err := pages.Query().Where("title", "Home").Exec()
+	if err != nil || len(docs) != 1 || docs[0]["renderedHtml"] != largeHTML {
+		t.Fatalf("Expected other fields to stay queryable, got %v", err)
+	}
+	if body := lastQueryBody(t, ms); !strings.Contains(body, `"field":"title"`) {
+		t.Errorf("Expected the title filter to be sent to the server, got %s", body)
+	}
+}
+
+func TestFieldCodecExportImport(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	pages := client.Model("pages", nil).WithFieldCodec("renderedHtml", torm.GzipBase64())
+	if _, err := pages.Create(map[string]interface{}{"id": "page:1", "title": "Home", "renderedHtml": largeHTML}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var decoded, encoded bytes.Buffer
+	if _, err := pages.ExportSnapshot(&decoded, torm.ExportOptions{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(decoded.String(), "Some repeated paragraph text.") {
+		t.Error("Expected the default export to hold decoded values")
+	}
+	if _, err := pages.ExportSnapshot(&encoded, torm.ExportOptions{EncodedFields: true}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(encoded.String(), `"renderedHtml":"gzip:`) || encoded.Len()*10 > decoded.Len() {
+		t.Errorf("Expected the encoded export to keep the stored form, got %d bytes", encoded.Len())
+	}
+
+	for name, snapshot := range map[string]*bytes.Buffer{"decoded": &decoded, "encoded": &encoded} {
+		restored := client.Model("restored_"+name, nil).WithFieldCodec("renderedHtml", torm.GzipBase64())
+		if _, err := restored.ImportSnapshot(snapshot, torm.ImportOptions{}); err != nil {
+			t.Fatalf("Import of the %s snapshot failed: %v", name, err)
+		}
+		stored, _ := ms.doc("restored_"+name, "page:1")
+		if html, _ := stored["renderedHtml"].(string); !strings.HasPrefix(html, "gzip:") || len(html) > len(largeHTML)/10 {
+			t.Errorf("Expected the %s snapshot to be stored compressed once, got %.40s", name, html)
+		}
+		doc, err := restored.FindByID("page:1")
+		if err != nil || doc["renderedHtml"] != largeHTML {
+			t.Errorf("Expected the %s snapshot to round-trip, got %v", name, err)
+		}
+	}
+}
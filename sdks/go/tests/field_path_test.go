@@ -0,0 +1,179 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedSettingsUser seeds a user with nested settings
+func seedSettingsUser(ms *mockServer) {
+	ms.seed("users", map[string]interface{}{
+		"id":   "user:1",
+		"name": "Ada",
+		"settings": map[string]interface{}{
+			"theme":         "dark",
+			"notifications": map[string]interface{}{"email": true},
+		},
+	})
+}
+
+func TestGetFieldExtractsLocally(t *testing.T) {
+	ms := newMockServer(t)
+	seedSettingsUser(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	cases := []struct {
+		path  string
+		value interface{}
+		found bool
+	}{
+		{"settings.theme", "dark", true},
+		{"settings.notifications.email", true, true},
+		{"settings.notifications", map[string]interface{}{"email": true}, true},
+		{"settings.privacy.level", nil, false}, // Absent intermediate key
+		{"name.first", nil, false},             // Intermediate key is not an object
+		{"nickname", nil, false},
+	}
+	for _, tc := range cases {
+		value, found, err := users.GetField("user:1", tc.path)
+		if err != nil {
+			t.Fatalf("GetField(%s) failed: %v", tc.path, err)
+		}
+		if found != tc.found || !reflect.DeepEqual(value, tc.value) {
+			t.Errorf("GetField(%s): expected %v, %v, got %v, %v", tc.path, tc.value, tc.found, value, found)
+		}
+	}
+
+	// The mock ignores the projection, so later reads stop asking for it
+	if client.Supports(torm.CapabilityFieldProjection) {
+		t.Error("Expected a server returning whole documents not to support projection")
+	}
+	if _, _, err := users.GetField("user:404", "settings.theme"); !errors.Is(err, torm.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing document, got %v", err)
+	}
+	if _, _, err := users.GetField("user:1", "settings..theme"); err == nil {
+		t.Error("Expected an empty key to be rejected")
+	}
+}
+
+func TestGetFieldUsesServerProjection(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	var requested []string
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Path != "/api/users/user:1" {
+			return false
+		}
+		requested = append(requested, r.URL.Query().Get("fields"))
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": "user:1", "settings": map[string]interface{}{"theme": "light"}})
+		return true
+	})
+
+	value, found, err := users.GetField("user:1", "settings.theme")
+	if err != nil || !found || value != "light" {
+		t.Fatalf("Expected the projected value, got %v, %v, %v", value, found, err)
+	}
+	if _, found, _ := users.GetField("user:1", "settings.font"); found {
+		t.Error("Expected a path missing from the projection to be absent")
+	}
+	if want := []string{"settings.theme", "settings.font"}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("Expected projected reads of %v, got %v", want, requested)
+	}
+	if !client.Supports(torm.CapabilityFieldProjection) {
+		t.Error("Expected projection to be recorded as supported")
+	}
+}
+
+func TestGetFieldFallsBackWhenProjectionIsRejected(t *testing.T) {
+	ms := newMockServer(t)
+	seedSettingsUser(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	rejected := 0
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Query().Get("fields") == "" {
+			return false
+		}
+		rejected++
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "unknown parameter fields"})
+		return true
+	})
+
+	for i := 0; i < 2; i++ {
+		value, found, err := users.GetField("user:1", "settings.theme")
+		if err != nil || !found || value != "dark" {
+			t.Fatalf("Expected the value from the whole document, got %v, %v, %v", value, found, err)
+		}
+	}
+	if rejected != 1 {
+		t.Errorf("Expected the projection to be tried once, got %d", rejected)
+	}
+}
+
+func TestSetFieldBuildsNestedObjects(t *testing.T) {
+	ms := newMockServer(t)
+	seedSettingsUser(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if err := users.SetField("user:1", "settings.notifications.sms", true); err != nil {
+		t.Fatalf("SetField failed: %v", err)
+	}
+	if err := users.SetField("user:1", "profile.address.city", "Oslo"); err != nil {
+		t.Fatalf("SetField with absent intermediate keys failed: %v", err)
+	}
+	if err := users.SetField("user:1", "name", "Ada L."); err != nil {
+		t.Fatalf("SetField of a top-level field failed: %v", err)
+	}
+
+	doc, _ := ms.doc("users", "user:1")
+	want := map[string]interface{}{
+		"theme":         "dark",
+		"notifications": map[string]interface{}{"email": true, "sms": true},
+	}
+	if !reflect.DeepEqual(doc["settings"], want) {
+		t.Errorf("Expected the sibling keys to be kept, got %v", doc["settings"])
+	}
+	if city, _, _ := users.GetField("user:1", "profile.address.city"); city != "Oslo" || doc["name"] != "Ada L." {
+		t.Errorf("Expected the new nested and top-level values, got %v and %v", city, doc["name"])
+	}
+
+	if err := users.SetField("user:1", "name.first", "Ada"); err == nil {
+		t.Error("Expected setting a key inside a string to fail")
+	}
+	if err := users.SetField("user:404", "settings.theme", "light"); !errors.Is(err, torm.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing document, got %v", err)
+	}
+}
+
+func TestGetFieldWithGuardReadsStoredDocument(t *testing.T) {
+	ms := newMockServer(t)
+	seedSettingsUser(ms)
+	ms.seed("users", map[string]interface{}{"id": "user:2", "name": "Eve", "settings": map[string]interface{}{"theme": "light"}})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithGuard(func(op torm.Operation, doc map[string]interface{}) error {
+			if doc["name"] == "Eve" {
+				return errors.New("hidden")
+			}
+			return nil
+		}))
+
+	// TestUser has no settings, yet the stored document does
+	value, found, err := users.GetField("user:1", "settings.theme")
+	if err != nil || !found || value != "dark" {
+		t.Fatalf("Expected dark from the stored document, got %v, %v, %v", value, found, err)
+	}
+	var guardErr *torm.GuardError
+	if _, _, err := users.GetField("user:2", "settings.theme"); !errors.As(err, &guardErr) {
+		t.Errorf("Expected the guard to reject the document, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package torm_test
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// TestMember embeds TestUser and renames a field with its json tag
+type TestMember struct {
+	TestUser
+	Level   int    `json:"member_level"`
+	Note    string `json:"-"`
+	Company string
+}
+
+var userFields = struct {
+	Name  torm.StringFieldRef
+	Email torm.StringFieldRef
+	Age   torm.OrderedFieldRef[int]
+}{
+	Name:  torm.StringField(func(u *TestUser) *string { return &u.Name }),
+	Email: torm.StringField(func(u *TestUser) *string { return &u.Email }),
+	Age:   torm.OrderedField(func(u *TestUser) *int { return &u.Age }),
+}
+
+func TestFieldRefsResolveWireNames(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+	}{
+		{"email", userFields.Email.Name()},
+		{"age", userFields.Age.Name()},
+		{"email", torm.Field(func(m *TestMember) *string { return &m.Email }).Name()},
+		{"member_level", torm.OrderedField(func(m *TestMember) *int { return &m.Level }).Name()},
+		{"Company", torm.StringField(func(m *TestMember) *string { return &m.Company }).Name()},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.name {
+			t.Errorf("Expected wire name %q, got %q", tt.name, tt.got)
+		}
+	}
+
+	for _, selector := range []func(*TestMember) *string{
+		func(m *TestMember) *string { return &m.Note },
+		func(m *TestMember) *string { return new(string) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected a panic for a selector that does not return a wire field")
+				}
+			}()
+			torm.Field(selector)
+		}()
+	}
+}
+
+func TestQueryWithFieldRefs(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "name": "Alice", "email": "alice@example.com", "age": 30},
+		map[string]interface{}{"id": "user:2", "name": "Bob", "email": "bob@example.org", "age": 25},
+		map[string]interface{}{"id": "user:3", "name": "Carol", "email": "carol@example.com", "age": 41},
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	docs, err := client.Model("users", nil).Query().
+		Match(userFields.Email.Contains("example.com"), userFields.Age.Gte(30), userFields.Name.NotIn("Mallory")).
+		SortBy(userFields.Age, torm.Desc).
+		Exec()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(docs) != 2 || docs[0]["name"] != "Carol" || docs[1]["name"] != "Alice" {
+		t.Errorf("Unexpected results: %v", docs)
+	}
+
+	want := `{"filters":[{"field":"email","operator":"contains","value":"example.com"},{"field":"age","operator":"gte","value":30},{"field":"name","operator":"not_in","value":["Mallory"]}],"sort":{"field":"age","order":"desc"},"then_by":[{"field":"id","order":"asc"}]}`
+	if got := lastQueryBody(t, ms); got != want {
+		t.Errorf("Unexpected query payload:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestFieldRefsRejectMisuse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a package")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available")
+	}
+
+	out, err := exec.Command(goTool, "build", "-tags", "torm_negative", "./negative").CombinedOutput()
+	if err == nil {
+		t.Fatal("Expected the negative examples to fail to compile")
+	}
+
+	src, err := os.ReadFile("negative/fields.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range strings.Split(string(src), "\n") {
+		if !strings.Contains(line, "// want error") {
+			continue
+		}
+		if !regexp.MustCompile(`fields\.go:` + strconv.Itoa(i+1) + `:`).Match(out) {
+			t.Errorf("Expected a compile error on line %d: %s\noutput:\n%s", i+1, strings.TrimSpace(line), out)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package torm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// scriptQueries answers every query on users with docs, reporting count
+// matches
+func scriptQueries(ms *mockServer, count int, docs ...map[string]interface{}) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/users/query" {
+			return false
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"collection": "users", "documents": docs, "count": count})
+		return true
+	})
+}
+
+func TestFilterParityChecks(t *testing.T) {
+	ms := newMockServer(t)
+	// The server compares ages as strings and drops a match
+	scriptQueries(ms, 3,
+		map[string]interface{}{"id": "user:1", "age": "10"},
+		map[string]interface{}{"id": "user:2", "age": float64(12)},
+	)
+	var logs bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+	var divergences []torm.FilterDivergence
+	users := client.Model("users", nil).WithFilterParityChecks(1, func(d torm.FilterDivergence) { divergences = append(divergences, d) })
+
+	found, err := users.Query().Filter("age", torm.Gt, 9).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0]["id"] != "user:2" {
+		t.Errorf("Expected the matcher to keep only user:2, got %v", found)
+	}
+	if len(divergences) != 2 {
+		t.Fatalf("Expected two divergences, got %+v", divergences)
+	}
+	included, counted := divergences[0], divergences[1]
+	if included.Kind != torm.DivergenceServerIncluded || included.ID != "user:1" || included.Collection != "users" ||
+		included.Filter == nil || included.Filter.Field != "age" || included.Filter.Operator != torm.Gt {
+		t.Errorf("Expected user:1 reported as included by the server, got %+v", included)
+	}
+	if counted.Kind != torm.DivergenceCount || counted.Returned != 2 || counted.Count != 3 {
+		t.Errorf("Expected the count of 3 for 2 documents reported, got %+v", counted)
+	}
+	if want := users.Query().Filter("age", torm.Gt, 9).String(); included.Query != want {
+		t.Errorf("Expected the query described as %q, got %q", want, included.Query)
+	}
+
+	var entry map[string]interface{}
+	line := strings.SplitN(strings.TrimSpace(logs.String()), "\n", 2)[0]
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("Malformed log line %q: %v", line, err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "torm: filter parity" || entry["kind"] != "server_included" ||
+		entry["id"] != "user:1" || entry["field"] != "age" {
+		t.Errorf("Expected a structured parity warning, got %v", entry)
+	}
+}
+
+func TestFilterParityChecksOnFind(t *testing.T) {
+	ms := newMockServer(t)
+	// The server matches any truthy string
+	scriptQueries(ms, 1, map[string]interface{}{"id": "user:1", "name": "Ada", "active": "yes"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	var divergences []torm.FilterDivergence
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithFilterParityChecks(1, func(d torm.FilterDivergence) { divergences = append(divergences, d) }))
+
+	found, err := users.FindFiltered(map[string]interface{}{"active": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Errorf("Expected Find to keep the server's matches, got %v", found)
+	}
+	if len(divergences) != 1 || divergences[0].ID != "user:1" || divergences[0].Filter.Field != "active" {
+		t.Errorf("Expected user:1 reported, got %+v", divergences)
+	}
+
+	// Unsampled queries are not checked
+	divergences = nil
+	unchecked := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithFilterParityChecks(0, func(d torm.FilterDivergence) { divergences = append(divergences, d) }))
+	if _, err := unchecked.FindFiltered(map[string]interface{}{"active": true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(divergences) != 0 {
+		t.Errorf("Expected no checks at rate 0, got %+v", divergences)
+	}
+}
+
+func TestMatchDocument(t *testing.T) {
+	doc := map[string]interface{}{"name": "Ada", "age": float64(36), "code": "10", "active": "true", "tags": "admin,ops"}
+	tests := []struct {
+		filter torm.QueryFilter
+		want   bool
+	}{
+		{torm.QueryFilter{Field: "age", Operator: torm.Eq, Value: "36"}, true},
+		{torm.QueryFilter{Field: "age", Operator: torm.Gt, Value: 9}, true},
+		{torm.QueryFilter{Field: "code", Operator: torm.Gt, Value: "9"}, false}, // Strings compare as strings
+		{torm.QueryFilter{Field: "active", Operator: torm.Eq, Value: true}, true},
+		{torm.QueryFilter{Field: "missing", Operator: torm.Eq, Value: nil}, true},
+		{torm.QueryFilter{Field: "missing", Operator: torm.Ne, Value: "Ada"}, true},
+		{torm.QueryFilter{Field: "tags", Operator: torm.Contains, Value: "ops"}, true},
+		{torm.QueryFilter{Field: "name", Operator: torm.In, Value: []interface{}{"Ada", "Bob"}}, true},
+		{torm.QueryFilter{Field: "name", Operator: torm.In, Value: "Ada"}, false},
+		{torm.QueryFilter{Field: "name", Operator: torm.NotIn, Value: []interface{}{"Bob"}}, true},
+	}
+	for _, tt := range tests {
+		if got := torm.MatchDocument(doc, []torm.QueryFilter{tt.filter}); got != tt.want {
+			t.Errorf("%s %s %v: expected %v, got %v", tt.filter.Field, tt.filter.Operator, tt.filter.Value, tt.want, got)
+		}
+	}
+
+	all := []torm.QueryFilter{{Field: "name", Operator: torm.Eq, Value: "Ada"}, {Field: "age", Operator: torm.Lt, Value: 30}}
+	if torm.MatchDocument(doc, all) {
+		t.Error("Expected every filter to have to match")
+	}
+}
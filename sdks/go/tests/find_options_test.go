@@ -0,0 +1,87 @@
+package torm_test
+
+import (
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestFindWithoutOptionsLists(t *testing.T) {
+	ms := newMockServer(t)
+	seedQueryUsers(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	all, err := users.Find()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Errorf("Expected 5 users, got %d", len(all))
+	}
+	if ms.countRequests("GET", "/api/users") != 1 || ms.countRequests("POST", "/api/users/query") != 0 {
+		t.Errorf("Expected a plain listing, got %v", ms.requestLog())
+	}
+}
+
+func TestFindWithOptionsQueries(t *testing.T) {
+	ms := newMockServer(t)
+	seedQueryUsers(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	active, err := users.Find(torm.WithFilters(map[string]interface{}{"active": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 4 {
+		t.Errorf("Expected 4 active users, got %d", len(active))
+	}
+
+	page, err := users.Find(
+		torm.WithFilters(map[string]interface{}{"active": true}),
+		torm.WithSort("age", torm.Desc),
+		torm.WithSkip(1),
+		torm.WithLimit(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Age != 30 || page[1].Age != 30 {
+		t.Errorf("Expected the two 30-year-olds after Dave, got %+v", page)
+	}
+	log := ms.requestLog()
+	body := log[len(log)-1].Body
+	if body["limit"] != float64(2) || body["skip"] != float64(1) || body["sort"] == nil {
+		t.Errorf("Expected sort, skip and limit in the query, got %v", body)
+	}
+
+	// Paging alone queries too
+	first, err := users.Find(torm.WithLimit(1))
+	if err != nil || len(first) != 1 {
+		t.Errorf("Expected one user, got %+v, %v", first, err)
+	}
+	if n := ms.countRequests("POST", "/api/users/query"); n != 3 {
+		t.Errorf("Expected every Find with options to query, got %d queries", n)
+	}
+	if n := ms.countRequests("GET", "/api/users"); n != 0 {
+		t.Errorf("Expected no listings, got %d", n)
+	}
+}
+
+func TestFindFilteredKeepsOldBehaviour(t *testing.T) {
+	ms := newMockServer(t)
+	seedQueryUsers(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if all, err := users.FindFiltered(nil); err != nil || len(all) != 5 {
+		t.Errorf("Expected nil filters to list every user, got %d, %v", len(all), err)
+	}
+	if bob, err := users.FindFiltered(map[string]interface{}{"name": "Bob"}); err != nil || len(bob) != 1 {
+		t.Errorf("Expected to find Bob, got %+v, %v", bob, err)
+	}
+	if ms.countRequests("GET", "/api/users") != 1 || ms.countRequests("POST", "/api/users/query") != 1 {
+		t.Errorf("Expected one listing and one query, got %v", ms.requestLog())
+	}
+}
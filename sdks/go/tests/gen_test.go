@@ -0,0 +1,104 @@
+package torm_test
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+	"github.com/toonstore/torm-go/gen"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files")
+
+func TestGenerateStructFromCollection(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("customers",
+		map[string]interface{}{
+			"id":         "customer:1",
+			"name":       "Alice",
+			"age":        float64(30),
+			"balance":    float64(10),
+			"created_at": "2024-03-01T10:00:00Z",
+			"tags":       []interface{}{"vip", "beta"},
+			"address":    map[string]interface{}{"city": "Oslo", "zip": "0150"},
+			"ref":        "abc",
+		},
+		map[string]interface{}{
+			"id":         "customer:2",
+			"name":       "Bob",
+			"age":        float64(41),
+			"balance":    12.5,
+			"created_at": "2024-03-02T11:30:00Z",
+			"tags":       []interface{}{},
+			"address":    map[string]interface{}{"city": "Bergen"},
+			"ref":        float64(7),
+			"nickname":   nil,
+		},
+	)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	schema, err := gen.InferSchema(client, "customers", 10)
+	if err != nil {
+		t.Fatalf("InferSchema failed: %v", err)
+	}
+	if schema.Samples != 2 {
+		t.Errorf("Expected 2 samples, got %d", schema.Samples)
+	}
+
+	src, err := gen.GenerateFile("models", schema, "Customer")
+	if err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "customer_gen.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("Generated source does not match %s:\n%s", golden, src)
+	}
+}
+
+func TestGenerateStructCompiles(t *testing.T) {
+	// Numeric and mixed ids, and keys that map to the same Go name
+	schema := gen.InferFromDocuments("accounts", []map[string]interface{}{
+		{"id": float64(1), "user_id": "u1", "userId": float64(7), "_id": "x"},
+		{"id": "account:2", "user_id": "u2"},
+	})
+	src, err := gen.GenerateFile("models", schema, "Account")
+	if err != nil {
+		t.Fatalf("GenerateFile failed: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "account.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&types.Config{}).Check("models", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("Generated source does not compile: %v\n%s", err, src)
+	}
+	// Fields sort by key, so "userId" comes before "user_id"
+	compact := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{"ID string `json:\"id\"`", "ID2 *string `json:\"_id,omitempty\"`",
+		"UserID *int `json:\"userId,omitempty\"`", "UserID2 string `json:\"user_id\"`", `"user_id": m.UserID2`} {
+		if !strings.Contains(compact, want) {
+			t.Errorf("Expected %s in:\n%s", want, src)
+		}
+	}
+}
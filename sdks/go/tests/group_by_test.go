@@ -0,0 +1,164 @@
+package torm_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedUserEvents seeds events whose userId is skewed: a few heavy users and
+// many users with one event each, interleaved in id order. It returns the
+// true count per user.
+func seedUserEvents(ms *mockServer, heavy, light int) map[string]int64 {
+	var users []string
+	for i := 0; i < heavy; i++ {
+		for n := 0; n < 60+i*5; n++ {
+			users = append(users, fmt.Sprintf("heavy-%02d", i))
+		}
+	}
+	for i := 0; i < light; i++ {
+		users = append(users, fmt.Sprintf("light-%04d", i))
+	}
+	rand.New(rand.NewSource(7)).Shuffle(len(users), func(i, j int) { users[i], users[j] = users[j], users[i] })
+
+	truth := make(map[string]int64)
+	for i, user := range users {
+		ms.seed("user_events", map[string]interface{}{"id": fmt.Sprintf("event:%05d", i), "userId": user})
+		truth[user]++
+	}
+	return truth
+}
+
+func TestGroupByUnderTheBoundIsExact(t *testing.T) {
+	ms := newMockServer(t)
+	truth := seedUserEvents(ms, 5, 10)
+
+	result, err := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("user_events", nil).GroupBy(context.Background(), "userId", torm.GroupOptions{MaxGroups: 100})
+	if err != nil {
+		t.Fatalf("GroupBy failed: %v", err)
+	}
+	if !result.Exact || len(result.Groups) != len(truth) {
+		t.Fatalf("expected %d exact groups, got %d (exact %v)", len(truth), len(result.Groups), result.Exact)
+	}
+	for _, group := range result.Groups {
+		if group.Count != truth[group.Key.(string)] || group.MaxError != 0 {
+			t.Errorf("group %v: got %d±%d, want %d", group.Key, group.Count, group.MaxError, truth[group.Key.(string)])
+		}
+	}
+	if result.Groups[0].Key != "heavy-04" {
+		t.Errorf("expected the largest group first, got %v", result.Groups[0].Key)
+	}
+}
+
+func TestGroupByTopKBoundsApproximateCounts(t *testing.T) {
+	ms := newMockServer(t)
+	truth := seedUserEvents(ms, 20, 1500)
+	const maxGroups = 100
+
+	result, err := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("user_events", nil).GroupBy(context.Background(), "userId", torm.GroupOptions{
+		MaxGroups: maxGroups,
+		Overflow:  torm.GroupTopK,
+	})
+	if err != nil {
+		t.Fatalf("GroupBy failed: %v", err)
+	}
+	if result.Exact {
+		t.Fatal("expected an approximate result past the bound")
+	}
+	if len(result.Groups) != maxGroups {
+		t.Fatalf("expected %d groups, got %d", maxGroups, len(result.Groups))
+	}
+	var total int64
+	for _, n := range truth {
+		total += n
+	}
+	if result.Documents != total {
+		t.Fatalf("expected %d documents, got %d", total, result.Documents)
+	}
+
+	bound := total / maxGroups
+	found := make(map[string]bool)
+	for _, group := range result.Groups {
+		key := group.Key.(string)
+		found[key] = true
+		if group.MaxError > bound {
+			t.Errorf("group %s: error %d exceeds N/MaxGroups = %d", key, group.MaxError, bound)
+		}
+		if want := truth[key]; want > group.Count || want < group.Count-group.MaxError {
+			t.Errorf("group %s: true count %d outside [%d, %d]", key, want, group.Count-group.MaxError, group.Count)
+		}
+	}
+	// Every user with more than N/MaxGroups events is guaranteed to be kept
+	for user, n := range truth {
+		if n > bound && !found[user] {
+			t.Errorf("frequent user %s (%d events) missing from the result", user, n)
+		}
+	}
+}
+
+func TestGroupBySpillIsExact(t *testing.T) {
+	ms := newMockServer(t)
+	truth := seedUserEvents(ms, 20, 1500)
+	dir := t.TempDir()
+
+	result, err := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("user_events", nil).GroupBy(context.Background(), "userId", torm.GroupOptions{
+		MaxGroups: 100,
+		Overflow:  torm.GroupSpill,
+		SpillDir:  dir,
+	})
+	if err != nil {
+		t.Fatalf("GroupBy failed: %v", err)
+	}
+	if !result.Exact || result.Spills == 0 {
+		t.Fatalf("expected an exact result after spilling, got exact %v with %d spills", result.Exact, result.Spills)
+	}
+	if len(result.Groups) != len(truth) {
+		t.Fatalf("expected %d groups, got %d", len(truth), len(result.Groups))
+	}
+	for _, group := range result.Groups {
+		if want := truth[group.Key.(string)]; group.Count != want || group.MaxError != 0 {
+			t.Errorf("group %v: got %d, want %d", group.Key, group.Count, want)
+		}
+	}
+	for i := 1; i < len(result.Groups); i++ {
+		if result.Groups[i].Count > result.Groups[i-1].Count {
+			t.Fatalf("groups not sorted by count at %d", i)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected spill files to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestGroupBySpillWithLimit(t *testing.T) {
+	ms := newMockServer(t)
+	seedUserEvents(ms, 20, 300)
+
+	result, err := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("user_events", nil).GroupBy(context.Background(), "userId", torm.GroupOptions{
+		MaxGroups: 50,
+		Overflow:  torm.GroupSpill,
+		SpillDir:  t.TempDir(),
+		Limit:     3,
+	})
+	if err != nil {
+		t.Fatalf("GroupBy failed: %v", err)
+	}
+	want := []string{"heavy-19", "heavy-18", "heavy-17"}
+	if len(result.Groups) != len(want) {
+		t.Fatalf("expected %d groups, got %d", len(want), len(result.Groups))
+	}
+	for i, group := range result.Groups {
+		if group.Key != want[i] {
+			t.Errorf("group %d: got %v, want %s", i, group.Key, want[i])
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package torm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// TenantDoc is a test model scoped to a tenant
+type TenantDoc struct {
+	ID     string `json:"id"`
+	Tenant string `json:"tenant"`
+	Title  string `json:"title"`
+}
+
+func (d *TenantDoc) GetID() string {
+	return d.ID
+}
+
+func (d *TenantDoc) SetID(id string) {
+	d.ID = id
+}
+
+func (d *TenantDoc) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":     d.ID,
+		"tenant": d.Tenant,
+		"title":  d.Title,
+	}
+}
+
+var errCrossTenant = errors.New("cross-tenant access")
+
+func tenantGuard(tenant string) torm.Guard {
+	return func(op torm.Operation, doc map[string]interface{}) error {
+		if doc["tenant"] != tenant {
+			return errCrossTenant
+		}
+		return nil
+	}
+}
+
+func newTenantCollection(t *testing.T) (*mockServer, *torm.Collection[*TenantDoc]) {
+	ms := newMockServer(t)
+	ms.seed("docs",
+		map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "first"},
+		map[string]interface{}{"id": "doc:2", "tenant": "b", "title": "second"},
+		map[string]interface{}{"id": "doc:3", "tenant": "a", "title": "third"},
+	)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	docs := torm.NewCollection(client, "docs", func() *TenantDoc { return &TenantDoc{} },
+		torm.WithGuard(tenantGuard("a")))
+	return ms, docs
+}
+
+func TestGuardFiltersFind(t *testing.T) {
+	_, docs := newTenantCollection(t)
+
+	all, err := docs.Find()
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 tenant documents, got %d", len(all))
+	}
+	for _, doc := range all {
+		if doc.Tenant != "a" {
+			t.Errorf("Guard leaked document %s of tenant %s", doc.ID, doc.Tenant)
+		}
+	}
+
+	queried, err := docs.FindFiltered(map[string]interface{}{"title": "second"})
+	if err != nil {
+		t.Fatalf("Find with filters failed: %v", err)
+	}
+	if len(queried) != 0 {
+		t.Errorf("Expected query to return no documents, got %d", len(queried))
+	}
+
+	if got := docs.GuardRejections(); got != 2 {
+		t.Errorf("Expected 2 guard rejections, got %d", got)
+	}
+}
+
+func TestGuardRejectsFindByID(t *testing.T) {
+	_, docs := newTenantCollection(t)
+
+	if _, err := docs.FindByID("doc:1"); err != nil {
+		t.Fatalf("Expected own document, got %v", err)
+	}
+
+	_, err := docs.FindByID("doc:2")
+	var guardErr *torm.GuardError
+	if !errors.As(err, &guardErr) {
+		t.Fatalf("Expected GuardError, got %v", err)
+	}
+	if guardErr.Op != torm.OpFindByID || !errors.Is(err, errCrossTenant) {
+		t.Errorf("Unexpected guard error: %v", err)
+	}
+}
+
+func TestGuardRejectsWrites(t *testing.T) {
+	ms, docs := newTenantCollection(t)
+
+	_, err := docs.Create(&TenantDoc{ID: "doc:4", Tenant: "b", Title: "sneaky"})
+	if !errors.Is(err, errCrossTenant) {
+		t.Errorf("Expected Create to be rejected, got %v", err)
+	}
+	if _, exists := ms.doc("docs", "doc:4"); exists {
+		t.Error("Rejected Create reached the server")
+	}
+
+	err = docs.Save(&TenantDoc{ID: "doc:2", Tenant: "b", Title: "changed"})
+	if !errors.Is(err, errCrossTenant) {
+		t.Errorf("Expected Save to be rejected, got %v", err)
+	}
+	if stored, _ := ms.doc("docs", "doc:2"); stored["title"] != "second" {
+		t.Error("Rejected Save modified the document")
+	}
+
+	err = docs.Delete("doc:2")
+	if !errors.Is(err, errCrossTenant) {
+		t.Errorf("Expected Delete to be rejected, got %v", err)
+	}
+	if _, exists := ms.doc("docs", "doc:2"); !exists {
+		t.Error("Rejected Delete removed the document")
+	}
+
+	if err := docs.Delete("doc:3"); err != nil {
+		t.Errorf("Expected own document delete to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package torm_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestQueryHintsPayload(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "age": 30})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", nil)
+
+	_, err := users.Query().
+		Filter("age", torm.Gte, 18).
+		UseIndex("by_age").
+		ServerTimeout(1500*time.Millisecond).
+		ReadPreference(torm.ReadSecondaryPreferred).
+		Hint("explain_plan", map[string]interface{}{"verbose": true}).
+		Exec()
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	log := ms.requestLog()
+	body := log[len(log)-1].Body
+	want := map[string]interface{}{
+		"index":           "by_age",
+		"timeout_ms":      float64(1500),
+		"read_preference": "secondary_preferred",
+		"explain_plan":    map[string]interface{}{"verbose": true},
+	}
+	if !reflect.DeepEqual(body["options"], want) {
+		t.Errorf("Expected options %v, got %v", want, body["options"])
+	}
+	if _, ok := body["filters"]; !ok {
+		t.Error("Expected filters alongside the options")
+	}
+
+	// Queries without hints send no options
+	if _, err := users.Query().Where("age", 30).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	log = ms.requestLog()
+	if _, ok := log[len(log)-1].Body["options"]; ok {
+		t.Error("Expected no options key without hints")
+	}
+}
+
+func TestQueryHintsInExplainAndString(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: "http://unused"})
+	qb := client.Model("users", nil).Query().Where("age", 30).UseIndex("by_age").ServerTimeout(time.Second)
+
+	plan := qb.Explain()
+	if plan.Hints["index"] != "by_age" || plan.Hints["timeout_ms"] != int64(1000) {
+		t.Errorf("Expected hints in the plan, got %v", plan.Hints)
+	}
+	if options, _ := plan.Payload["options"].(map[string]interface{}); options["index"] != "by_age" {
+		t.Errorf("Expected hints in the planned payload, got %v", plan.Payload)
+	}
+
+	if want := "users: age=30 HINT index=by_age,timeout_ms=1000"; qb.String() != want {
+		t.Errorf("Expected %q, got %q", want, qb.String())
+	}
+}
+
+func TestQueryHintsSurviveCloneAndPresets(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	err := users.DefineQuery("reporting", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		return qb.ReadPreference(torm.ReadSecondary).UseIndex("by_country")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	qb, err := users.NamedQuery("reporting")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clone := qb.Clone().Where("country", "NL").UseIndex("by_country_age")
+	if got := qb.Hints()["index"]; got != "by_country" {
+		t.Errorf("Expected the clone to leave the original's hints alone, got %v", got)
+	}
+	if got := clone.Hints(); got["read_preference"] != "secondary" || got["index"] != "by_country_age" {
+		t.Errorf("Expected the clone to carry the preset's hints, got %v", got)
+	}
+	if qb.String() != "users: * HINT index=by_country,read_preference=secondary" {
+		t.Errorf("Expected the original query unchanged, got %s", qb)
+	}
+
+	if _, err := clone.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	log := ms.requestLog()
+	options, _ := log[len(log)-1].Body["options"].(map[string]interface{})
+	if options["read_preference"] != "secondary" || options["index"] != "by_country_age" {
+		t.Errorf("Expected the preset's hints to be sent, got %v", options)
+	}
+}
+
+func TestQueryHintsMustBeJSON(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", nil)
+
+	_, err := users.Query().Hint("callback", func() {}).Exec()
+	if !errors.Is(err, torm.ErrInvalidHint) {
+		t.Fatalf("Expected ErrInvalidHint, got %v", err)
+	}
+	if !torm.IsValidation(err) {
+		t.Errorf("Expected a validation error, got %s", torm.ErrorCategory(err))
+	}
+	if _, err := users.Query().Hint("", 1).Exec(); !errors.Is(err, torm.ErrInvalidHint) {
+		t.Errorf("Expected a hint without a key to be refused, got %v", err)
+	}
+	if n := len(ms.requestLog()); n != 0 {
+		t.Errorf("Expected invalid hints to send nothing, got %d requests", n)
+	}
+}
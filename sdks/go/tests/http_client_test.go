@@ -0,0 +1,95 @@
+package torm_test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// taggingTransport counts round trips and marks each request
+type taggingTransport struct {
+	trips atomic.Int32
+}
+
+func (t *taggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.trips.Add(1)
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Traced", "yes")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// slowDown delays every response by d
+func slowDown(ms *mockServer, d time.Duration) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		time.Sleep(d)
+		return false
+	})
+}
+
+func TestClientUsesTransport(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+	var traced atomic.Bool
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		traced.Store(r.Header.Get("X-Traced") == "yes")
+		return false
+	})
+	transport := &taggingTransport{}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Transport: transport})
+
+	if _, err := client.Model("users", nil).FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if transport.trips.Load() != 1 || !traced.Load() {
+		t.Errorf("Expected the request to go through the transport, got %d round trips", transport.trips.Load())
+	}
+}
+
+func TestClientUsesHTTPClientVerbatim(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+	transport := &taggingTransport{}
+	httpClient := &http.Client{Transport: transport}
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:    ms.URL,
+		HTTPClient: httpClient,
+		Transport:  http.DefaultTransport, // Ignored next to HTTPClient
+		Timeout:    50 * time.Millisecond,
+	})
+
+	if _, err := client.Model("users", nil).FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if transport.trips.Load() != 1 {
+		t.Errorf("Expected the request to go through the caller's client, got %d round trips", transport.trips.Load())
+	}
+
+	// Without a timeout of its own, the client gets Timeout per request,
+	// and the caller's client is left as it was
+	slowDown(ms, 200*time.Millisecond)
+	_, err := client.Model("users", nil).FindByID("user:1")
+	if torm.ErrorCategory(err) != torm.CategoryTimeout {
+		t.Errorf("Expected Timeout to apply, got %v", err)
+	}
+	if httpClient.Timeout != 0 {
+		t.Errorf("Expected the caller's client to be unchanged, got a timeout of %s", httpClient.Timeout)
+	}
+}
+
+func TestHTTPClientTimeoutWins(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+	slowDown(ms, 100*time.Millisecond)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:    ms.URL,
+		HTTPClient: &http.Client{Timeout: 5 * time.Second},
+		Timeout:    20 * time.Millisecond,
+	})
+
+	if _, err := client.Model("users", nil).FindByID("user:1"); err != nil {
+		t.Errorf("Expected the HTTP client's own timeout to be kept, got %v", err)
+	}
+}
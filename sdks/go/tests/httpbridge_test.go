@@ -0,0 +1,180 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+	"github.com/toonstore/torm-go/httpbridge"
+)
+
+// bridgeCall sends a request to the bridge and decodes the JSON response
+func bridgeCall(t *testing.T, srv *httptest.Server, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	req.Header.Set("X-Role", "admin")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp.StatusCode, decoded
+}
+
+func newBridge(t *testing.T, opts httpbridge.HandlerOptions) (*mockServer, *httptest.Server) {
+	t.Helper()
+
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	minAge := 0.0
+	users := client.Model("users", map[string]torm.ValidationRule{
+		"name": {Type: "str", Required: true},
+		"age":  {Type: "float", Min: &minAge},
+	})
+
+	srv := httptest.NewServer(http.StripPrefix("/users", httpbridge.NewHandler(users, opts)))
+	t.Cleanup(srv.Close)
+	return ms, srv
+}
+
+func TestHTTPBridgeRoutes(t *testing.T) {
+	ms, srv := newBridge(t, httpbridge.HandlerOptions{RedactFields: []string{"password"}})
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "name": "Alice", "age": 30, "password": "x"},
+		map[string]interface{}{"id": "user:2", "name": "Bob", "age": 25, "password": "y"},
+	)
+
+	status, doc := bridgeCall(t, srv, "GET", "/users/user:1", "")
+	if status != http.StatusOK || doc["name"] != "Alice" || doc["password"] != nil {
+		t.Fatalf("GET: unexpected %d %v", status, doc)
+	}
+
+	status, doc = bridgeCall(t, srv, "GET", "/users/?age[gte]=26&sort=-name", "")
+	docs, _ := doc["documents"].([]interface{})
+	if status != http.StatusOK || len(docs) != 1 || docs[0].(map[string]interface{})["id"] != "user:1" {
+		t.Fatalf("Query: unexpected %d %v", status, doc)
+	}
+
+	status, doc = bridgeCall(t, srv, "POST", "/users", `{"id":"user:3","name":"Carol","age":41}`)
+	if status != http.StatusCreated || doc["name"] != "Carol" {
+		t.Fatalf("POST: unexpected %d %v", status, doc)
+	}
+
+	status, doc = bridgeCall(t, srv, "PATCH", "/users/user:2", `{"age":26,"password":null}`)
+	if status != http.StatusOK || doc["name"] != "Bob" || doc["age"] != float64(26) {
+		t.Fatalf("PATCH: unexpected %d %v", status, doc)
+	}
+	if stored, _ := ms.doc("users", "user:2"); stored["password"] != nil {
+		t.Errorf("Expected PATCH null to remove the field, got %v", stored)
+	}
+
+	status, doc = bridgeCall(t, srv, "PUT", "/users/user:2", `{"name":"Robert"}`)
+	if stored, _ := ms.doc("users", "user:2"); status != http.StatusOK || stored["age"] != nil || stored["name"] != "Robert" {
+		t.Fatalf("PUT: unexpected %d %v, stored %v", status, doc, stored)
+	}
+
+	if status, _ := bridgeCall(t, srv, "DELETE", "/users/user:2", ""); status != http.StatusNoContent {
+		t.Fatalf("DELETE: unexpected %d", status)
+	}
+	if _, ok := ms.doc("users", "user:2"); ok {
+		t.Errorf("Expected user:2 to be deleted")
+	}
+}
+
+func TestHTTPBridgeErrors(t *testing.T) {
+	ms, srv := newBridge(t, httpbridge.HandlerOptions{})
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice", "active": true})
+
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/users") {
+			if data, _ := body["data"].(map[string]interface{}); data["id"] == "user:1" {
+				writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "exists", "existing_id": "user:1"})
+				return true
+			}
+		}
+		return false
+	})
+
+	cases := []struct {
+		method, path, body string
+		status             int
+		message            string
+	}{
+		{"GET", "/users/user:9", "", http.StatusNotFound, "document not found"},
+		{"PUT", "/users/user:9", `{"name":"X"}`, http.StatusNotFound, "document not found"},
+		{"PATCH", "/users/user:9", `{"name":"X"}`, http.StatusNotFound, "document not found"},
+		{"DELETE", "/users/user:9", "", http.StatusNotFound, "document not found"},
+		{"POST", "/users", `{"age":3}`, http.StatusBadRequest, "field 'name' is required"},
+		{"PATCH", "/users/user:1", `{"age":-1}`, http.StatusBadRequest, "field 'age' must be at least 0"},
+		{"POST", "/users", `[1,2]`, http.StatusBadRequest, "body must be a JSON object"},
+		{"POST", "/users", `{"id":"user:1","name":"Dup"}`, http.StatusConflict, "exists"},
+		{"GET", "/users/?active[gt]=true", "", http.StatusBadRequest, "cannot be used with boolean"},
+		{"GET", "/users/?age[between]=1", "", http.StatusBadRequest, "unsupported parameter"},
+		{"GET", "/users/?limit=-1", "", http.StatusBadRequest, "limit must be a non-negative integer"},
+		{"POST", "/users/user:1", `{}`, http.StatusMethodNotAllowed, "method not allowed"},
+	}
+	for _, tc := range cases {
+		status, body := bridgeCall(t, srv, tc.method, tc.path, tc.body)
+		message, _ := body["error"].(string)
+		if status != tc.status || !strings.Contains(message, tc.message) {
+			t.Errorf("%s %s: expected %d %q, got %d %v", tc.method, tc.path, tc.status, tc.message, status, body)
+		}
+	}
+
+	// Server failures become 502
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "boom"})
+		return true
+	})
+	if status, _ := bridgeCall(t, srv, "GET", "/users/user:1", ""); status != http.StatusBadGateway {
+		t.Errorf("Expected 502 for a server failure, got %d", status)
+	}
+}
+
+func TestHTTPBridgeOptions(t *testing.T) {
+	ms, srv := newBridge(t, httpbridge.HandlerOptions{
+		Methods: []string{"GET"},
+		Authorize: func(r *http.Request) error {
+			if r.Header.Get("X-Role") != "admin" {
+				return errors.New("admins only")
+			}
+			return nil
+		},
+		Redact: func(r *http.Request, doc map[string]interface{}) {
+			doc["email"] = "***"
+		},
+	})
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice", "email": "a@example.com"})
+
+	status, doc := bridgeCall(t, srv, "GET", "/users/user:1", "")
+	if status != http.StatusOK || doc["email"] != "***" {
+		t.Fatalf("Expected redacted email, got %d %v", status, doc)
+	}
+
+	if status, _ := bridgeCall(t, srv, "DELETE", "/users/user:1", ""); status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected disabled DELETE to return 405, got %d", status)
+	}
+	if _, ok := ms.doc("users", "user:1"); !ok {
+		t.Errorf("Expected disabled DELETE to leave the document")
+	}
+
+	resp, err := http.Get(srv.URL + "/users/user:1")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected unauthorized request to get 403, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,202 @@
+package torm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64 `json:"radius"`
+}
+
+func (c *Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type Square struct {
+	Side float64 `json:"side"`
+}
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+// Dot is stored as a plain number, so it needs the value wrapper
+type Dot int
+
+func (Dot) Area() float64 { return 0 }
+
+func init() {
+	torm.RegisterType("circle", func() interface{} { return &Circle{} })
+	torm.RegisterType("square", func() interface{} { return Square{} })
+	torm.RegisterType("dot", func() interface{} { return Dot(0) })
+}
+
+type Audit struct {
+	CreatedBy string `json:"createdBy"`
+	UpdatedBy string `json:"updatedBy"`
+}
+
+type DrawingMeta struct {
+	Source string `json:"source"`
+	Pinned Shape  `json:"pinned,omitempty"`
+}
+
+type Drawing struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Audit
+	DrawingMeta `json:"meta"`
+	Shape       Shape   `json:"shape"`
+	Layers      []Shape `json:"layers"`
+}
+
+func (d *Drawing) GetID() string {
+	return d.ID
+}
+
+func (d *Drawing) SetID(id string) {
+	d.ID = id
+}
+
+func (d *Drawing) ToMap() map[string]interface{} {
+	meta := map[string]interface{}{"source": d.Source}
+	if d.Pinned != nil {
+		meta["pinned"] = d.Pinned
+	}
+	return map[string]interface{}{
+		"id":        d.ID,
+		"title":     d.Title,
+		"createdBy": d.CreatedBy,
+		"updatedBy": d.UpdatedBy,
+		"meta":      meta,
+		"shape":     d.Shape,
+		"layers":    d.Layers,
+	}
+}
+
+func newDrawings(client *torm.Client) *torm.Collection[*Drawing] {
+	return torm.NewCollection(client, "drawings", func() *Drawing { return &Drawing{} })
+}
+
+func TestHydrationRoundTripsEmbeddedAndInterfaceFields(t *testing.T) {
+	ms := newMockServer(t)
+	drawings := newDrawings(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}))
+
+	drawing := &Drawing{
+		ID:          "drawing:1",
+		Title:       "Plan",
+		Audit:       Audit{CreatedBy: "ann", UpdatedBy: "bob"},
+		DrawingMeta: DrawingMeta{Source: "upload", Pinned: Square{Side: 1}},
+		Shape:       &Circle{Radius: 2},
+		Layers:      []Shape{Square{Side: 3}, Dot(7), &Circle{Radius: 1}},
+	}
+	created, err := drawings.Create(drawing)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	assertDrawing(t, "created", created)
+
+	stored, _ := ms.doc("drawings", "drawing:1")
+	if stored["createdBy"] != "ann" {
+		t.Errorf("embedded Audit should be flattened, got %v", stored)
+	}
+	if shape, _ := stored["shape"].(map[string]interface{}); shape["_type"] != "circle" || shape["radius"] != 2.0 {
+		t.Errorf("shape stored as %v, want an inline discriminator", stored["shape"])
+	}
+	layers, _ := stored["layers"].([]interface{})
+	if len(layers) != 3 {
+		t.Fatalf("layers stored as %v", stored["layers"])
+	}
+	if dot, _ := layers[1].(map[string]interface{}); dot["_type"] != "dot" || dot["value"] != 7.0 {
+		t.Errorf("dot stored as %v, want a wrapped value", layers[1])
+	}
+
+	found, err := drawings.FindByID("drawing:1")
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	assertDrawing(t, "found", found)
+
+	listed, err := drawings.FindFiltered(map[string]interface{}{"title": "Plan"})
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("Find = %v, %v", listed, err)
+	}
+	assertDrawing(t, "listed", listed[0])
+
+	partial, err := torm.ExecPartial[Drawing](torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("drawings", nil).Query())
+	if err != nil || len(partial.Typed) != 1 {
+		t.Fatalf("ExecPartial = %+v, %v", partial, err)
+	}
+	assertDrawing(t, "queried", &partial.Typed[0])
+}
+
+func assertDrawing(t *testing.T, name string, d *Drawing) {
+	t.Helper()
+	if d.CreatedBy != "ann" || d.UpdatedBy != "bob" || d.Source != "upload" {
+		t.Errorf("%s: embedded structs lost: %+v %+v", name, d.Audit, d.DrawingMeta)
+	}
+	if pinned, ok := d.Pinned.(Square); !ok || pinned.Side != 1 {
+		t.Errorf("%s: pinned = %#v, want Square{1}", name, d.Pinned)
+	}
+	if circle, ok := d.Shape.(*Circle); !ok || circle.Radius != 2 {
+		t.Errorf("%s: shape = %#v, want &Circle{2}", name, d.Shape)
+	}
+	if len(d.Layers) != 3 {
+		t.Fatalf("%s: layers = %#v", name, d.Layers)
+	}
+	if square, ok := d.Layers[0].(Square); !ok || square.Side != 3 {
+		t.Errorf("%s: layer 0 = %#v", name, d.Layers[0])
+	}
+	if dot, ok := d.Layers[1].(Dot); !ok || dot != 7 {
+		t.Errorf("%s: layer 1 = %#v", name, d.Layers[1])
+	}
+	if circle, ok := d.Layers[2].(*Circle); !ok || circle.Radius != 1 {
+		t.Errorf("%s: layer 2 = %#v", name, d.Layers[2])
+	}
+}
+
+func TestHydrationErrorsNameTheField(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("drawings",
+		map[string]interface{}{"id": "d1", "shape": map[string]interface{}{"_type": "hexagon", "side": 1}},
+		map[string]interface{}{"id": "d2", "shape": map[string]interface{}{"radius": 1}},
+		map[string]interface{}{"id": "d3", "layers": []interface{}{map[string]interface{}{"_type": "circle", "radius": 1}, map[string]interface{}{"side": 2}}},
+		map[string]interface{}{"id": "d4", "meta": map[string]interface{}{"pinned": map[string]interface{}{"_type": "nope"}}},
+		map[string]interface{}{"id": "d5", "title": "no shape"},
+	)
+	drawings := newDrawings(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}))
+
+	cases := map[string]string{
+		"d1": `field shape (torm_test.Shape): type "hexagon" is not registered`,
+		"d2": `field shape (torm_test.Shape): the value has no "_type" discriminator`,
+		"d3": `field layers[1]`,
+		"d4": `field meta.pinned`,
+	}
+	for id, want := range cases {
+		_, err := drawings.FindByID(id)
+		if !errors.Is(err, torm.ErrUnknownType) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want an ErrUnknownType naming %q", id, err, want)
+		}
+		if torm.ErrorCategory(err) != torm.CategoryContract {
+			t.Errorf("%s: category %q", id, torm.ErrorCategory(err))
+		}
+	}
+
+	empty, err := drawings.FindByID("d5")
+	if err != nil || empty.Shape != nil || empty.Layers != nil {
+		t.Errorf("missing interface fields should stay nil, got %+v, %v", empty, err)
+	}
+}
+
+func TestRegisterTypeRejectsConflicts(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering another type under a taken name should panic")
+		}
+	}()
+	torm.RegisterType("circle", func() interface{} { return &Square{} })
+}
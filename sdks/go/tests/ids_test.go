@@ -0,0 +1,131 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// answerCreates makes the mock answer creates in users with the sent data,
+// placing id at the top level, in the data, or nowhere
+func answerCreates(ms *mockServer, topLevel, inData bool) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/users" {
+			return false
+		}
+		data, _ := body["data"].(map[string]interface{})
+		delete(data, "id")
+		response := map[string]interface{}{"success": true, "data": data}
+		if inData {
+			data["id"] = "user:42"
+		}
+		if topLevel {
+			response["id"] = "user:42"
+		}
+		writeJSON(w, http.StatusCreated, response)
+		return true
+	})
+}
+
+func newUsers(ms *mockServer, opts ...torm.CollectionOption) *torm.Collection[*TestUser] {
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	return torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, opts...)
+}
+
+func TestCreatePropagatesIDFromEitherPlace(t *testing.T) {
+	cases := map[string]struct{ topLevel, inData bool }{
+		"top level only": {true, false},
+		"data only":      {false, true},
+		"both":           {true, true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ms := newMockServer(t)
+			answerCreates(ms, tc.topLevel, tc.inData)
+
+			input := &TestUser{Name: "Alice"}
+			created, err := newUsers(ms).Create(input)
+			if err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			if created.GetID() != "user:42" || created.Name != "Alice" {
+				t.Errorf("expected the returned model to be user:42, got %+v", created)
+			}
+			if input.GetID() != "user:42" {
+				t.Errorf("expected the input model to get the id, got %q", input.GetID())
+			}
+		})
+	}
+}
+
+func TestCreateKeepInputLeavesModelUntouched(t *testing.T) {
+	ms := newMockServer(t)
+	answerCreates(ms, true, false)
+
+	input := &TestUser{Name: "Alice"}
+	created, err := newUsers(ms, torm.WithIDs(torm.IDOptions{KeepInput: true})).Create(input)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.GetID() != "user:42" {
+		t.Errorf("expected the returned model to get the id, got %q", created.GetID())
+	}
+	if input.GetID() != "" {
+		t.Errorf("expected the input model to keep its empty id, got %q", input.GetID())
+	}
+}
+
+func TestCreateWithoutIDInResponse(t *testing.T) {
+	ms := newMockServer(t)
+	answerCreates(ms, false, false)
+
+	created, err := newUsers(ms).Create(&TestUser{Name: "Alice"})
+	if err != nil {
+		t.Fatalf("expected ids to be optional by default, got %v", err)
+	}
+	if created.GetID() != "" {
+		t.Errorf("expected no id, got %q", created.GetID())
+	}
+
+	_, err = newUsers(ms, torm.WithIDs(torm.IDOptions{Required: true})).Create(&TestUser{Name: "Alice"})
+	if !errors.Is(err, torm.ErrMissingID) {
+		t.Fatalf("expected ErrMissingID, got %v", err)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryContract {
+		t.Errorf("expected a contract error, got %s", torm.ErrorCategory(err))
+	}
+
+	err = newUsers(ms, torm.WithIDs(torm.IDOptions{Required: true})).Save(&TestUser{Name: "Alice"})
+	if !errors.Is(err, torm.ErrMissingID) {
+		t.Fatalf("expected ErrMissingID from Save, got %v", err)
+	}
+}
+
+func TestSaveTakesIDFromData(t *testing.T) {
+	ms := newMockServer(t)
+	answerCreates(ms, false, true)
+
+	user := &TestUser{Name: "Alice"}
+	if err := newUsers(ms).Save(user); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if user.GetID() != "user:42" {
+		t.Errorf("expected Save to set the id from the data, got %q", user.GetID())
+	}
+}
+
+func TestModelCreateTakesTopLevelID(t *testing.T) {
+	ms := newMockServer(t)
+	answerCreates(ms, true, false)
+
+	created, err := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("users", nil).
+		Create(map[string]interface{}{"name": "Alice"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created["id"] != "user:42" {
+		t.Errorf("expected the top-level id in the data, got %v", created)
+	}
+}
@@ -0,0 +1,160 @@
+package torm_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func peopleModel(client *torm.Client) *torm.Model {
+	minAge := 0.0
+	return client.Model("people", map[string]torm.ValidationRule{
+		"name":   {Type: "string", Required: true},
+		"age":    {Type: "int", Min: &minAge},
+		"email":  {Type: "string", Email: true},
+		"active": {Type: "bool"},
+	})
+}
+
+var peopleMapping = &torm.MappingSpec{
+	Rename:    map[string]string{"full_name": "name", "years": "age", "contact": "email", "active_flag": "active"},
+	Split:     []torm.SplitRule{{Field: "tags", Separator: ";"}},
+	Constants: map[string]interface{}{"source": "csv"},
+	Casts:     map[string]string{"age": "int", "active": "bool"},
+	Transform: func(record map[string]interface{}) (map[string]interface{}, error) {
+		first := strings.Fields(record["name"].(string))[0]
+		record["id"] = "person:" + strings.ToLower(first)
+		return record, nil
+	},
+}
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestImportDryRun(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	people := peopleModel(client)
+
+	result, err := people.ImportSnapshot(openFixture(t, "import_people.csv"), torm.ImportOptions{
+		Format:  torm.ImportCSV,
+		Mapping: peopleMapping,
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(ms.requestLog()) != 0 {
+		t.Errorf("Expected a dry run to send nothing, got %v", ms.requestLog())
+	}
+	if len(result.Samples) != 4 || result.Imported != 0 {
+		t.Fatalf("Expected 4 samples and no imports, got %+v", result)
+	}
+
+	ann := result.Samples[0]
+	want := map[string]interface{}{
+		"id": "person:ann", "name": "Ann Smith", "age": int64(34), "email": "ann@example.com",
+		"active": true, "tags": []interface{}{"admin", "ops"}, "source": "csv",
+	}
+	if ann.Line != 2 || ann.Err != nil || !reflect.DeepEqual(ann.Record, want) {
+		t.Errorf("Unexpected first sample: %+v", ann)
+	}
+	if ann.Source["full_name"] != "Ann Smith" {
+		t.Errorf("Expected the source record to be kept, got %v", ann.Source)
+	}
+
+	bob := result.Samples[1]
+	if bob.Line != 3 || bob.Record != nil || !errors.Is(bob.Err, torm.ErrValidation) ||
+		!strings.Contains(bob.Err.Error(), "line 3") || !strings.Contains(bob.Err.Error(), "age") {
+		t.Errorf("Expected a cast failure on line 3, got %+v", bob)
+	}
+
+	cy := result.Samples[2]
+	if cy.Line != 4 || cy.Record == nil || cy.Record["active"] != true ||
+		!strings.Contains(cy.Err.Error(), "line 4") || !strings.Contains(cy.Err.Error(), "email") {
+		t.Errorf("Expected a validation failure on line 4, got %+v", cy)
+	}
+
+	if dee := result.Samples[3]; dee.Err != nil || !reflect.DeepEqual(dee.Record["tags"], []interface{}{"sales", "ops"}) {
+		t.Errorf("Unexpected last sample: %+v", dee)
+	}
+
+	result, err = people.ImportSnapshot(openFixture(t, "import_people.csv"), torm.ImportOptions{
+		Format: torm.ImportCSV, Mapping: peopleMapping, DryRun: true, SampleSize: 2,
+	})
+	if err != nil || len(result.Samples) != 2 || result.Lines != 3 {
+		t.Errorf("Expected the dry run to stop after 2 records, got %+v, %v", result, err)
+	}
+}
+
+func TestImportWithMapping(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	people := peopleModel(client)
+	opts := torm.ImportOptions{Format: torm.ImportCSV, Mapping: peopleMapping}
+
+	result, err := people.ImportSnapshot(openFixture(t, "import_people.csv"), opts)
+	if err == nil || !strings.Contains(err.Error(), "failed to map line 3") || !errors.Is(err, torm.ErrValidation) {
+		t.Fatalf("Expected the cast failure on line 3, got %v", err)
+	}
+	if result.Imported != 1 || result.Lines != 3 {
+		t.Errorf("Expected one import before the failure, got %+v", result)
+	}
+	if doc, _ := ms.doc("people", "person:ann"); doc == nil || doc["age"] != float64(34) || doc["source"] != "csv" {
+		t.Errorf("Unexpected stored document: %v", doc)
+	}
+
+	opts.Skip = result.Lines
+	result, err = people.ImportSnapshot(openFixture(t, "import_people.csv"), opts)
+	if err == nil || !strings.Contains(err.Error(), "failed to import line 4") || !errors.Is(err, torm.ErrValidation) {
+		t.Fatalf("Expected the validation failure on line 4, got %v", err)
+	}
+
+	opts.Skip = result.Lines
+	result, err = people.ImportSnapshot(openFixture(t, "import_people.csv"), opts)
+	if err != nil || result.Imported != 1 || result.Lines != 5 {
+		t.Fatalf("Expected the last record to import, got %+v, %v", result, err)
+	}
+	if doc, _ := ms.doc("people", "person:dee"); doc == nil || doc["active"] != false {
+		t.Errorf("Unexpected stored document: %v", doc)
+	}
+}
+
+func TestMappingSplitAndJoin(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	contacts := client.Model("contacts", nil)
+
+	source := `{"id":"contact:1","name":"Ann Smith","street":"1 Main St","city":"Springfield","internal":"x"}` + "\n" +
+		`{"id":"contact:2","name":"Bob","city":"Shelbyville"}` + "\n"
+	result, err := contacts.ImportSnapshot(strings.NewReader(source), torm.ImportOptions{
+		Mapping: &torm.MappingSpec{
+			Split: []torm.SplitRule{{Field: "name", Separator: " ", Into: []string{"first", "last"}}},
+			Join:  []torm.JoinRule{{Fields: []string{"street", "city"}, Separator: ", ", Into: "address"}},
+			Drop:  []string{"internal"},
+		},
+	})
+	if err != nil || result.Imported != 2 {
+		t.Fatalf("Import failed: %+v, %v", result, err)
+	}
+
+	want := map[string]interface{}{"id": "contact:1", "first": "Ann", "last": "Smith", "address": "1 Main St, Springfield"}
+	if doc, _ := ms.doc("contacts", "contact:1"); !reflect.DeepEqual(doc, want) {
+		t.Errorf("Unexpected first contact: %v", doc)
+	}
+	want = map[string]interface{}{"id": "contact:2", "first": "Bob", "address": "Shelbyville"}
+	if doc, _ := ms.doc("contacts", "contact:2"); !reflect.DeepEqual(doc, want) {
+		t.Errorf("Unexpected second contact: %v", doc)
+	}
+}
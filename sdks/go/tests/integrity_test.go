@@ -0,0 +1,151 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedShop seeds users, orders referencing them and products, with two
+// orphaned orders, one product below zero stock and a repeated sku
+func seedShop(ms *mockServer) {
+	for _, id := range []string{"user:1", "user:2"} {
+		ms.seed("users", map[string]interface{}{"id": id})
+	}
+	for i, userID := range []string{"user:1", "user:9", "user:2", "", "user:8", "user:1"} {
+		order := map[string]interface{}{"id": fmt.Sprintf("order:%d", i+1)}
+		if userID != "" {
+			order["userId"] = userID
+		}
+		ms.seed("orders", order)
+	}
+	for i, stock := range []float64{5, -2, 0, 3} {
+		ms.seed("products", map[string]interface{}{"id": fmt.Sprintf("product:%d", i+1), "stock": stock, "sku": fmt.Sprintf("SKU-%d", i%3)})
+	}
+}
+
+func TestRunIntegrityChecks(t *testing.T) {
+	ms := newMockServer(t)
+	seedShop(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	checks := []torm.IntegrityCheck{
+		{
+			Name:       "orders-have-users",
+			Collection: "orders",
+			Reference:  &torm.ReferenceCheck{Field: "userId", Collection: "users", BatchSize: 2},
+		},
+		{
+			Name:       "no-negative-stock",
+			Collection: "products",
+			Filter:     []torm.QueryFilter{{Field: "stock", Operator: torm.Lt, Value: 0}},
+		},
+		{
+			Name:       "unique-skus",
+			Collection: "products",
+			MaxIDs:     1,
+			Func: func(ctx context.Context, it *torm.QueryIterator, violation func(id string)) error {
+				seen := map[interface{}]bool{}
+				for it.Next() {
+					doc := it.Doc()
+					if seen[doc["sku"]] {
+						violation(doc["id"].(string))
+					}
+					seen[doc["sku"]] = true
+				}
+				return it.Err()
+			},
+		},
+		{
+			Name:       "users-exist",
+			Collection: "users",
+			Filter:     []torm.QueryFilter{{Field: "id", Operator: torm.Eq, Value: "user:404"}},
+		},
+	}
+	for _, check := range checks {
+		if err := client.RegisterIntegrityCheck(check); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var alerted []string
+	client.OnIntegrityViolation(func(result torm.IntegrityResult) {
+		alerted = append(alerted, result.Check)
+	})
+
+	report, err := client.RunIntegrityChecks(context.Background())
+	if err != nil {
+		t.Fatalf("RunIntegrityChecks failed: %v", err)
+	}
+	if len(report.Results) != 4 {
+		t.Fatalf("Expected a result per check, got %d", len(report.Results))
+	}
+
+	refs := report.Results[0]
+	if refs.Violations != 2 || !reflect.DeepEqual(refs.IDs, []string{"order:2", "order:5"}) || refs.Checked != 6 {
+		t.Errorf("Expected orders 2 and 5 to be orphaned among 6, got %+v", refs)
+	}
+	// Three batches of references, then the users-exist check
+	if lookups := ms.countRequests("POST", "/api/users/query"); lookups != 4 {
+		t.Errorf("Expected 5 references looked up in 3 batches, got %d queries", lookups)
+	}
+	if stock := report.Results[1]; stock.Violations != 1 || !reflect.DeepEqual(stock.IDs, []string{"product:2"}) {
+		t.Errorf("Expected product:2 to have negative stock, got %+v", stock)
+	}
+	if skus := report.Results[2]; skus.Violations != 1 || len(skus.IDs) != 1 || skus.IDs[0] != "product:4" {
+		t.Errorf("Expected product:4 to repeat a sku, got %+v", skus)
+	}
+	if !report.Results[3].OK() {
+		t.Errorf("Expected the passing check to be OK, got %+v", report.Results[3])
+	}
+	if want := []string{"orders-have-users", "no-negative-stock", "unique-skus"}; !reflect.DeepEqual(alerted, want) {
+		t.Errorf("Expected alerts for %v, got %v", want, alerted)
+	}
+	if failed := report.Failed(); len(failed) != 3 {
+		t.Errorf("Expected 3 failed checks, got %d", len(failed))
+	}
+}
+
+func TestIntegrityCheckCapsIDsAndReportsErrors(t *testing.T) {
+	ms := newMockServer(t)
+	seedShop(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	err := client.RegisterIntegrityCheck(torm.IntegrityCheck{
+		Name:       "no-stock",
+		Collection: "products",
+		Filter:     []torm.QueryFilter{{Field: "stock", Operator: torm.Lte, Value: 0}},
+		MaxIDs:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.RegisterIntegrityCheck(torm.IntegrityCheck{
+		Name:       "broken",
+		Collection: "orders",
+		Func: func(ctx context.Context, it *torm.QueryIterator, violation func(id string)) error {
+			return errors.New("lost the thread")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := client.RunIntegrityChecks(context.Background())
+	if err == nil || report.Results[1].Err == nil {
+		t.Fatalf("Expected the failing check's error, got %v", err)
+	}
+	if capped := report.Results[0]; capped.Violations != 2 || len(capped.IDs) != 1 {
+		t.Errorf("Expected 2 violations with 1 id kept, got %+v", capped)
+	}
+
+	if err := client.RegisterIntegrityCheck(torm.IntegrityCheck{Name: "no-stock", Collection: "products", Filter: []torm.QueryFilter{{Field: "stock", Operator: torm.Lt, Value: 0}}}); !errors.Is(err, torm.ErrDuplicateKey) {
+		t.Errorf("Expected a duplicate name to be rejected, got %v", err)
+	}
+	if err := client.RegisterIntegrityCheck(torm.IntegrityCheck{Name: "both", Collection: "products", Filter: []torm.QueryFilter{{Field: "stock", Operator: torm.Lt, Value: 0}}, Reference: &torm.ReferenceCheck{Field: "a", Collection: "b"}}); err == nil {
+		t.Error("Expected a check of two kinds to be rejected")
+	}
+}
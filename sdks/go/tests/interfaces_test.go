@@ -0,0 +1,67 @@
+package torm_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+	"github.com/toonstore/torm-go/tormtest"
+)
+
+// describeUser stands in for handler code that only needs to read users
+func describeUser(users torm.CollectionReader[*TestUser], id string) (string, error) {
+	user, err := users.FindByID(id)
+	if err != nil {
+		return "", err
+	}
+	adults, err := users.FindFiltered(map[string]interface{}{"age": user.Age})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%d with age %d)", user.Name, len(adults), user.Age), nil
+}
+
+func TestHandlerWithStubReader(t *testing.T) {
+	stub := tormtest.NewStubReader(
+		&TestUser{ID: "user:1", Name: "Alice", Age: 30},
+		&TestUser{ID: "user:2", Name: "Bob", Age: 30},
+		&TestUser{ID: "user:3", Name: "Carol", Age: 41},
+	)
+
+	got, err := describeUser(stub, "user:1")
+	if err != nil || got != "Alice (2 with age 30)" {
+		t.Errorf("Unexpected result %q %v", got, err)
+	}
+
+	if _, err := describeUser(stub, "user:404"); !errors.Is(err, tormtest.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	stub.Err = errors.New("unavailable")
+	if _, err := describeUser(stub, "user:1"); err != stub.Err {
+		t.Errorf("Expected injected error, got %v", err)
+	}
+}
+
+func TestCollectionSatisfiesInterfaces(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice", "age": 30.0})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	var writer torm.CollectionWriter[*TestUser] = users
+	var keys torm.KeyStore = client
+
+	if _, err := writer.Create(&TestUser{ID: "user:2", Name: "Bob", Age: 30}); err != nil {
+		t.Fatalf("Create through the writer failed: %v", err)
+	}
+	if err := keys.SetKey("greeting", "hello"); err != nil {
+		t.Fatalf("SetKey through the key store failed: %v", err)
+	}
+
+	got, err := describeUser(users, "user:1")
+	if err != nil || got != "Alice (2 with age 30)" {
+		t.Errorf("Unexpected result %q %v", got, err)
+	}
+}
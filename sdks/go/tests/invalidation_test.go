@@ -0,0 +1,184 @@
+package torm_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// eventLog records invalidation events
+type eventLog struct {
+	mu     sync.Mutex
+	events []torm.InvalidationEvent
+}
+
+func (l *eventLog) record(event torm.InvalidationEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) snapshot() []torm.InvalidationEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]torm.InvalidationEvent(nil), l.events...)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestInvalidationAcrossClients(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+
+	writer := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	writer.PublishInvalidations(torm.NewKeyBus(writer, "shop", torm.KeyBusOptions{}))
+
+	reader := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	index, err := torm.NewCollection(reader, "products", func() *TestProduct { return &TestProduct{} }).
+		LoadIndex(context.Background(), "sku")
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	received := &eventLog{}
+	reader.OnInvalidate(received.record)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reader.FollowInvalidations(ctx, torm.NewKeyBus(reader, "shop", torm.KeyBusOptions{PollInterval: 5 * time.Millisecond}))
+	waitFor(t, "the subscriber to start", func() bool {
+		return ms.countRequests("GET", "/api/keys/torm:events:shop") > 0
+	})
+
+	products := torm.NewCollection(writer, "products", func() *TestProduct { return &TestProduct{} })
+	if err := products.Save(&TestProduct{ID: "product:1", SKU: "A-2", Name: "Anvil", Price: 11}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	waitFor(t, "the updated product to be evicted", func() bool {
+		return strings.Join(index.GetByField("sku", "A-1"), ",") == "product:3"
+	})
+
+	if _, err := writer.Model("products", nil).Delete("product:3"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	waitFor(t, "the deleted product to be evicted", func() bool {
+		return len(index.GetByField("sku", "A-1")) == 0
+	})
+
+	// Writes to other collections reach application listeners only
+	if _, err := writer.Model("orders", nil).Create(map[string]interface{}{"id": "order:1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the order event", func() bool { return len(received.snapshot()) == 3 })
+
+	events := received.snapshot()
+	want := []string{"products/product:1/save", "products/product:3/delete", "orders/order:1/create"}
+	for i, event := range events {
+		if got := event.Collection + "/" + event.ID + "/" + string(event.Op); got != want[i] || event.Seq != int64(i+1) {
+			t.Errorf("Event %d: expected %s with seq %d, got %+v", i, want[i], i+1, event)
+		}
+	}
+	if stats := writer.InvalidationStats(); stats.Published != 3 || stats.Dropped != 0 || stats.Failed != 0 {
+		t.Errorf("Unexpected publisher stats: %+v", stats)
+	}
+}
+
+func TestInvalidationIgnoresOwnWrites(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	bus := torm.NewKeyBus(client, "app", torm.KeyBusOptions{PollInterval: 5 * time.Millisecond})
+	client.PublishInvalidations(bus)
+
+	received := &eventLog{}
+	client.OnInvalidate(received.record)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.FollowInvalidations(ctx, bus)
+	waitFor(t, "the subscriber to start", func() bool {
+		return ms.countRequests("GET", "/api/keys/torm:events:app") > 0
+	})
+
+	client.Model("users", nil).Create(map[string]interface{}{"id": "user:1"})
+	waitFor(t, "the event to be published", func() bool { return client.InvalidationStats().Published == 1 })
+	reads := ms.countRequests("GET", "/api/keys/torm:events:app")
+	waitFor(t, "another poll", func() bool { return ms.countRequests("GET", "/api/keys/torm:events:app") > reads+1 })
+
+	if events := received.snapshot(); len(events) != 0 {
+		t.Errorf("Expected the client's own writes to be ignored, got %v", events)
+	}
+}
+
+func TestKeyBusResyncAfterMissedEvents(t *testing.T) {
+	ms := newMockServer(t)
+	bus := torm.NewKeyBus(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}), "app",
+		torm.KeyBusOptions{Capacity: 2, PollInterval: 5 * time.Millisecond})
+
+	received := &eventLog{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bus.Subscribe(ctx, received.record)
+	waitFor(t, "the subscriber to start", func() bool {
+		return ms.countRequests("GET", "/api/keys/torm:events:app") > 0
+	})
+
+	var events []torm.InvalidationEvent
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		events = append(events, torm.InvalidationEvent{Collection: "items", ID: id, Op: torm.OpUpdate})
+	}
+	if err := bus.Publish(context.Background(), events); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	waitFor(t, "delivery", func() bool { return len(received.snapshot()) == 3 })
+	got := received.snapshot()
+	if !got[0].Resync || got[1].ID != "d" || got[2].ID != "e" || got[2].Seq != 5 {
+		t.Errorf("Expected a resync then the retained events, got %+v", got)
+	}
+}
+
+// blockingPublisher never returns until released
+type blockingPublisher struct{ release chan struct{} }
+
+func (p *blockingPublisher) Publish(ctx context.Context, events []torm.InvalidationEvent) error {
+	<-p.release
+	return nil
+}
+
+func TestSlowPublisherNeverBlocksWrites(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	publisher := &blockingPublisher{release: make(chan struct{})}
+	defer close(publisher.release)
+	client.PublishInvalidations(publisher)
+
+	users := client.Model("users", nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 300; i++ {
+			users.Create(map[string]interface{}{"id": "user:" + string(rune('a'+i%26)) + string(rune('a'+i/26))})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Writes blocked on the publisher")
+	}
+	if stats := client.InvalidationStats(); stats.Dropped == 0 {
+		t.Errorf("Expected events beyond the queue to be dropped, got %+v", stats)
+	}
+}
@@ -0,0 +1,133 @@
+//go:build go1.23
+
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestQueryAllPages(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 1200)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	seen := make(map[string]bool)
+	for doc, err := range client.Model("items", nil).Query().All(context.Background()) {
+		if err != nil {
+			t.Fatalf("Iteration failed: %v", err)
+		}
+		seen[doc["id"].(string)] = true
+	}
+	if len(seen) != 1200 {
+		t.Errorf("Expected 1200 distinct documents, got %d", len(seen))
+	}
+	if n := ms.countRequests("POST", "/api/items/query"); n != 3 {
+		t.Errorf("Expected 3 page requests, got %d", n)
+	}
+}
+
+func TestQueryAllHonorsLimitAndSkip(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 1200)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	var got []string
+	qb := client.Model("items", nil).Query().Sort("id", torm.Asc).Skip(10).Limit(520)
+	for doc, err := range qb.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("Iteration failed: %v", err)
+		}
+		got = append(got, doc["id"].(string))
+	}
+	if len(got) != 520 || got[0] != "item:011" {
+		t.Errorf("Expected 520 documents from item:011, got %d from %v", len(got), got[0])
+	}
+}
+
+func TestQueryAllBreakStopsPaging(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 1200)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+	if _, err := items.Count(); err != nil { // Opens the connection the pages reuse
+		t.Fatalf("Count failed: %v", err)
+	}
+	before := runtime.NumGoroutine()
+
+	read := 0
+	for _, err := range items.Query().All(context.Background()) {
+		if err != nil {
+			t.Fatalf("Iteration failed: %v", err)
+		}
+		read++
+		if read == 10 {
+			break
+		}
+	}
+	if n := ms.countRequests("POST", "/api/items/query"); n != 1 {
+		t.Errorf("Expected breaking early to read one page, got %d requests", n)
+	}
+	waitFor(t, "goroutines to settle", func() bool {
+		return runtime.NumGoroutine() <= before
+	})
+}
+
+func TestQueryAllYieldsErrors(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 1200)
+	failQueryPage(ms, 2)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	read, failures := 0, 0
+	for _, err := range client.Model("items", nil).Query().All(context.Background()) {
+		if err != nil {
+			failures++
+			continue
+		}
+		read++
+	}
+	if read != 500 || failures != 1 {
+		t.Errorf("Expected one page then one error, got %d documents and %d errors", read, failures)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range client.Model("items", nil).Query().All(ctx) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	}
+}
+
+func TestCollectionAll(t *testing.T) {
+	ms := newMockServer(t)
+	for i := 1; i <= 600; i++ {
+		tenant := "a"
+		if i%3 == 0 {
+			tenant = "b"
+		}
+		ms.seed("docs", map[string]interface{}{"id": fmt.Sprintf("doc:%03d", i), "tenant": tenant})
+	}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	docs := newTenantDocs(client, torm.WithGuard(tenantGuard("a")))
+
+	count := 0
+	for doc, err := range docs.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("Iteration failed: %v", err)
+		}
+		if doc.Tenant != "a" {
+			t.Fatalf("Expected the guard to hide other tenants, got %+v", doc)
+		}
+		count++
+	}
+	if count != 400 {
+		t.Errorf("Expected 400 documents, got %d", count)
+	}
+}
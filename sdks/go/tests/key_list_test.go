@@ -0,0 +1,148 @@
+package torm_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func setKeys(t *testing.T, client *torm.Client, keys ...string) {
+	t.Helper()
+
+	for _, key := range keys {
+		if err := client.SetKey(key, "v"); err != nil {
+			t.Fatalf("SetKey %s failed: %v", key, err)
+		}
+	}
+}
+
+func TestListKeysNative(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableKeyListing()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	setKeys(t, client, "queue:5", "queue:1", "queue:3", "queue:2", "queue:4", "session:a", "other")
+
+	// The first listing request is rate limited
+	var throttled atomic.Bool
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Path == "/api/keys" && throttled.CompareAndSwap(false, true) {
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{"error": "slow down"})
+			return true
+		}
+		return false
+	})
+
+	keys, err := client.ListKeys("queue:", &torm.ListKeysOptions{PageSize: 2})
+	if err != nil {
+		t.Fatalf("ListKeys failed: %v", err)
+	}
+	if strings.Join(keys, ",") != "queue:1,queue:2,queue:3,queue:4,queue:5" {
+		t.Fatalf("Unexpected keys: %v", keys)
+	}
+	if n := ms.countRequests("GET", "/api/keys"); n != 4 {
+		t.Errorf("Expected one retry and three pages, got %d requests", n)
+	}
+
+	deleted, err := client.DeleteKeysByPrefix("queue:")
+	if err != nil || deleted != 5 {
+		t.Fatalf("Expected 5 deletes, got %d, %v", deleted, err)
+	}
+	if keys, _ := client.ListKeys("", nil); strings.Join(keys, ",") != "other,session:a" {
+		t.Errorf("Unexpected remaining keys: %v", keys)
+	}
+}
+
+func TestListKeysIndexed(t *testing.T) {
+	ms := newMockServer(t)
+
+	plain := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	if _, err := plain.ListKeys("queue:", nil); !errors.Is(err, torm.ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported without an index, got %v", err)
+	}
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, KeyIndex: true})
+	setKeys(t, client, "queue:2", "queue:1", "queues:x", "qux", "session:a", "other")
+
+	keys, err := client.ListKeys("queue:", nil)
+	if err != nil || strings.Join(keys, ",") != "queue:1,queue:2" {
+		t.Fatalf("Unexpected namespaced keys: %v, %v", keys, err)
+	}
+	keys, err = client.ListKeys("q", nil)
+	if err != nil || strings.Join(keys, ",") != "queue:1,queue:2,queues:x,qux" {
+		t.Fatalf("Unexpected keys across namespaces: %v, %v", keys, err)
+	}
+
+	if err := client.DeleteKey("queue:1"); err != nil {
+		t.Fatalf("DeleteKey failed: %v", err)
+	}
+	deleted, err := client.DeleteKeysByPrefix("q")
+	if err != nil || deleted != 3 {
+		t.Fatalf("Expected 3 deletes, got %d, %v", deleted, err)
+	}
+	if keys, _ := client.ListKeys("", nil); strings.Join(keys, ",") != "other,session:a" {
+		t.Errorf("Unexpected remaining keys: %v", keys)
+	}
+	if _, found, _ := client.GetKey("queue:2"); found {
+		t.Errorf("Expected queue:2 to be deleted")
+	}
+}
+
+func TestKeyIndexConcurrentWriters(t *testing.T) {
+	ms := newMockServer(t)
+	clients := []*torm.Client{
+		torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, KeyIndex: true}),
+		torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, KeyIndex: true}),
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- clients[i%2].SetKey(fmt.Sprintf("jobs:%02d", i), "queued")
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("SetKey failed: %v", err)
+		}
+	}
+
+	keys, err := clients[0].ListKeys("jobs:", nil)
+	if err != nil || len(keys) != 40 {
+		t.Fatalf("Expected all 40 keys indexed, got %d: %v", len(keys), err)
+	}
+}
+
+func TestCompareAndSetKey(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	if ok, err := client.CompareAndSetKey("lock", nil, "a"); err != nil || !ok {
+		t.Fatalf("Expected create-if-absent to succeed, got %v, %v", ok, err)
+	}
+	if ok, _ := client.CompareAndSetKey("lock", nil, "b"); ok {
+		t.Errorf("Expected create-if-absent on an existing key to fail")
+	}
+	stale := "x"
+	if ok, _ := client.CompareAndSetKey("lock", &stale, "b"); ok {
+		t.Errorf("Expected swap from a stale value to fail")
+	}
+	current := "a"
+	if ok, err := client.CompareAndSetKey("lock", &current, "b"); err != nil || !ok {
+		t.Fatalf("Expected swap to succeed, got %v, %v", ok, err)
+	}
+	if value, _, _ := client.GetKey("lock"); value != "b" {
+		t.Errorf("Expected b, got %q", value)
+	}
+}
@@ -0,0 +1,160 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// keyValue reads a key through the client, "" with false when absent
+func keyValue(t *testing.T, client *torm.Client, key string) (string, bool) {
+	t.Helper()
+	value, found, err := client.GetKey(key)
+	if err != nil {
+		t.Fatalf("GetKey(%s) failed: %v", key, err)
+	}
+	return value, found
+}
+
+func stepStatuses(result *torm.KeysBatchResult) []torm.KeyStepStatus {
+	statuses := make([]torm.KeyStepStatus, len(result.Steps))
+	for i, step := range result.Steps {
+		statuses[i] = step.Status
+	}
+	return statuses
+}
+
+func TestKeysBatchUsesNativeEndpoint(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableKeysBatch()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	if err := client.SetKey("queue:head", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	old := "1"
+	result, err := client.KeysBatch().
+		Set("queue:item:2", "payload").
+		CompareAndSet("queue:head", &old, "2").
+		Delete("queue:item:1").
+		Exec()
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if !result.Native || !reflect.DeepEqual(result.Applied(), []int{0, 1, 2}) {
+		t.Fatalf("expected every step applied natively, got %+v", result)
+	}
+	if head, _ := keyValue(t, client, "queue:head"); head != "2" {
+		t.Errorf("expected head 2, got %q", head)
+	}
+	if n := ms.countRequests(http.MethodPut, "/api/keys/queue:item:2"); n != 0 {
+		t.Errorf("expected no per-key writes, got %d", n)
+	}
+
+	// A failed compare-and-set leaves every key untouched
+	result, err = client.KeysBatch().
+		Set("queue:item:3", "payload").
+		CompareAndSet("queue:head", &old, "3").
+		Exec()
+	if !errors.Is(err, torm.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	want := []torm.KeyStepStatus{torm.KeyStepNotApplied, torm.KeyStepFailed}
+	if !reflect.DeepEqual(stepStatuses(result), want) {
+		t.Errorf("expected %v, got %v", want, stepStatuses(result))
+	}
+	if _, found := keyValue(t, client, "queue:item:3"); found {
+		t.Error("expected the aborted batch to write nothing")
+	}
+}
+
+func TestKeysBatchSequentialRollsBackOnFailure(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	client.SetKey("lock:a", "old")
+	client.SetKey("lock:b", "keep")
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method == http.MethodPut && r.URL.Path == "/api/keys/lock:c" {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "boom"})
+			return true
+		}
+		return false
+	})
+
+	batch := client.KeysBatch().
+		Set("lock:a", "new").
+		Delete("lock:b").
+		Set("lock:c", "owner").
+		Set("lock:d", "never")
+	result, err := batch.Exec()
+	if err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if result.Native {
+		t.Error("expected the sequential path")
+	}
+	want := []torm.KeyStepStatus{torm.KeyStepRolledBack, torm.KeyStepRolledBack, torm.KeyStepFailed, torm.KeyStepNotApplied}
+	if !reflect.DeepEqual(stepStatuses(result), want) {
+		t.Fatalf("expected %v, got %v", want, stepStatuses(result))
+	}
+	if len(result.Applied()) != 0 {
+		t.Errorf("expected no step in effect, got %v", result.Applied())
+	}
+	if a, _ := keyValue(t, client, "lock:a"); a != "old" {
+		t.Errorf("expected lock:a restored to old, got %q", a)
+	}
+	if b, found := keyValue(t, client, "lock:b"); !found || b != "keep" {
+		t.Errorf("expected lock:b restored, got %q (%v)", b, found)
+	}
+	if _, found := keyValue(t, client, "lock:d"); found {
+		t.Error("expected steps after the failure not to run")
+	}
+
+	// The missing endpoint is remembered, and the batch can run again
+	ms.setIntercept(nil)
+	if _, err := batch.Exec(); err != nil {
+		t.Fatalf("second Exec failed: %v", err)
+	}
+	if n := ms.countRequests(http.MethodPost, "/api/keys/_batch"); n != 1 {
+		t.Errorf("expected the batch endpoint to be probed once, got %d", n)
+	}
+	if c, _ := keyValue(t, client, "lock:c"); c != "owner" {
+		t.Errorf("expected lock:c written on the second run, got %q", c)
+	}
+}
+
+func TestKeysBatchSequentialCompareAndSetFailsBeforeWriting(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	client.SetKey("lock:owner", "someone-else")
+
+	result, err := client.KeysBatch().
+		Set("lock:since", "now").
+		CompareAndSet("lock:owner", nil, "me").
+		Exec()
+	if !errors.Is(err, torm.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if result.Steps[1].Status != torm.KeyStepFailed || result.Steps[0].Status != torm.KeyStepNotApplied {
+		t.Errorf("unexpected statuses %v", stepStatuses(result))
+	}
+	if _, found := keyValue(t, client, "lock:since"); found {
+		t.Error("expected nothing written")
+	}
+}
+
+func TestKeysBatchRejectsDuplicateKeys(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	_, err := client.KeysBatch().Set("k", "1").Delete("other").Delete("k").Exec()
+	if !errors.Is(err, torm.ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+	if len(ms.requestLog()) != 0 {
+		t.Errorf("expected no requests, got %d", len(ms.requestLog()))
+	}
+}
@@ -0,0 +1,167 @@
+package torm_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedRankedItems seeds n items whose rank repeats, so sorting has ties
+func seedRankedItems(ms *mockServer, n int) {
+	for i := 1; i <= n; i++ {
+		ms.seed("items", map[string]interface{}{
+			"id":   fmt.Sprintf("item:%03d", i),
+			"rank": float64(i * 37 % 23),
+		})
+	}
+}
+
+func TestExecLargeSpillsPastTheBuffer(t *testing.T) {
+	ms := newMockServer(t)
+	seedRankedItems(ms, 250)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	dir := t.TempDir()
+
+	result, err := client.Model("items", nil).Query().ExecLarge(torm.LargeOptions{Dir: dir, BufferSize: 16})
+	if err != nil {
+		t.Fatalf("ExecLarge failed: %v", err)
+	}
+	defer result.Close()
+
+	if result.Len() != 250 {
+		t.Fatalf("Expected 250 documents, got %d", result.Len())
+	}
+	pages := 0
+	for _, req := range ms.requestLog() {
+		if limit, _ := req.Body["limit"].(float64); limit > 16 {
+			t.Errorf("Expected pages of at most 16 documents, got a limit of %v", limit)
+		}
+		pages++
+	}
+	if pages != 16 {
+		t.Errorf("Expected 16 pages, got %d", pages)
+	}
+
+	for _, i := range []int{0, 137, 249, 3} {
+		doc, err := result.At(i)
+		if err != nil {
+			t.Fatalf("At(%d) failed: %v", i, err)
+		}
+		if want := fmt.Sprintf("item:%03d", i+1); doc["id"] != want {
+			t.Errorf("At(%d): expected %s, got %v", i, want, doc["id"])
+		}
+	}
+	if _, err := result.At(250); err == nil {
+		t.Error("Expected an index past the end to fail")
+	}
+
+	seen := 0
+	err = result.Iterate(func(i int, doc map[string]interface{}) error {
+		if want := fmt.Sprintf("item:%03d", i+1); doc["id"] != want {
+			return fmt.Errorf("document %d: expected %s, got %v", i, want, doc["id"])
+		}
+		seen++
+		return nil
+	})
+	if err != nil || seen != 250 {
+		t.Errorf("Expected to iterate 250 documents, got %d, %v", seen, err)
+	}
+}
+
+func TestExecLargeSortBy(t *testing.T) {
+	ms := newMockServer(t)
+	seedRankedItems(ms, 250)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	dir := t.TempDir()
+
+	result, err := client.Model("items", nil).Query().ExecLarge(torm.LargeOptions{Dir: dir, BufferSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+
+	if err := result.SortBy("rank", torm.Desc); err != nil {
+		t.Fatalf("SortBy failed: %v", err)
+	}
+	if result.Len() != 250 {
+		t.Fatalf("Expected the sort to keep 250 documents, got %d", result.Len())
+	}
+	var prev map[string]interface{}
+	err = result.Iterate(func(i int, doc map[string]interface{}) error {
+		if prev != nil {
+			pr, r := prev["rank"].(float64), doc["rank"].(float64)
+			if pr < r {
+				return fmt.Errorf("document %d: rank %v after %v", i, r, pr)
+			}
+			if pr == r && prev["id"].(string) > doc["id"].(string) {
+				return fmt.Errorf("document %d: equal ranks out of their original order", i)
+			}
+		}
+		prev = doc
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if first, _ := result.At(0); first["rank"] != float64(22) {
+		t.Errorf("Expected the highest rank first, got %v", first)
+	}
+
+	// Sorting again replaces the sorted files
+	if err := result.SortBy("id", torm.Asc); err != nil {
+		t.Fatal(err)
+	}
+	if last, _ := result.At(249); last["id"] != "item:250" {
+		t.Errorf("Expected item:250 last after sorting by id, got %v", last)
+	}
+	entries, _ := os.ReadDir(result.Dir())
+	if len(entries) != 2 {
+		t.Errorf("Expected only the current data and index files to remain, got %d files", len(entries))
+	}
+}
+
+func TestExecLargeCleansUp(t *testing.T) {
+	ms := newMockServer(t)
+	seedRankedItems(ms, 40)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	dir := t.TempDir()
+	items := client.Model("items", nil)
+
+	result, err := items.Query().Skip(5).Limit(30).ExecLarge(torm.LargeOptions{Dir: dir, BufferSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Len() != 30 {
+		t.Errorf("Expected Skip and Limit to apply to the whole result, got %d documents", result.Len())
+	}
+	if first, _ := result.At(0); first["id"] != "item:006" {
+		t.Errorf("Expected the result to start after the skipped documents, got %v", first)
+	}
+	if err := result.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(result.Dir()); !os.IsNotExist(err) {
+		t.Errorf("Expected Close to remove %s", result.Dir())
+	}
+	if _, err := result.At(0); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected reads after Close to fail, got %v", err)
+	}
+	if err := result.Close(); err != nil {
+		t.Errorf("Expected a second Close to be a no-op, got %v", err)
+	}
+
+	// Past the size cap nothing is left behind
+	_, err = items.Query().ExecLarge(torm.LargeOptions{Dir: dir, BufferSize: 8, MaxBytes: 200})
+	if !errors.Is(err, torm.ErrSpillTooLarge) {
+		t.Fatalf("Expected ErrSpillTooLarge, got %v", err)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryLimit {
+		t.Errorf("Expected a limit error, got %s", torm.ErrorCategory(err))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the spill directory to be removed, found %d entries", len(entries))
+	}
+}
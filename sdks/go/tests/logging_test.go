@@ -0,0 +1,144 @@
+package torm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// requestLogs decodes the request entries of a JSON log
+func requestLogs(t *testing.T, logs *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Malformed log line %q: %v", line, err)
+		}
+		if entry["msg"] == "torm: request" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func TestRequestLogging(t *testing.T) {
+	ms := newMockServer(t)
+	var logs bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:   ms.URL,
+		AuthToken: "secret-token",
+		Retry:     torm.RetryOptions{MaxAttempts: 3, Backoff: time.Millisecond},
+		Logger:    slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	users := client.Model("users", nil)
+
+	if _, err := users.Create(map[string]interface{}{"id": "user:1", "name": "Ada", "password": "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	failed := false
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodGet || failed {
+			return false
+		}
+		failed = true
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	})
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	users.FindByID("user:404")
+
+	entries := requestLogs(t, &logs)
+	if len(entries) != 3 {
+		t.Fatalf("Expected one entry per request, got %d:\n%s", len(entries), logs.String())
+	}
+	want := []struct {
+		method, path string
+		status       float64
+		retries      float64
+	}{
+		{"POST", "/api/users", 201, 0},
+		{"GET", "/api/users/user:1", 200, 1},
+		{"GET", "/api/users/user:404", 404, 0},
+	}
+	for i, w := range want {
+		entry := entries[i]
+		if entry["level"] != "DEBUG" || entry["method"] != w.method || entry["path"] != w.path ||
+			entry["status"] != w.status || entry["retries"] != w.retries || entry["duration"] == nil {
+			t.Errorf("Expected %s %s %v after %v retries, got %v", w.method, w.path, w.status, w.retries, entry)
+		}
+		if _, ok := entry["request_body"]; ok {
+			t.Errorf("Expected no bodies without DebugBodies, got %v", entry)
+		}
+	}
+	for _, secret := range []string{"secret-token", "hunter2"} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("Expected %q to stay out of the log", secret)
+		}
+	}
+
+	// Nothing is logged above debug level
+	logs.Reset()
+	quiet := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Logger: slog.New(slog.NewJSONHandler(&logs, nil))})
+	quiet.Model("users", nil).FindByID("user:1")
+	if logs.Len() != 0 {
+		t.Errorf("Expected no request log at info level, got %s", logs.String())
+	}
+}
+
+func TestRequestLoggingRedactsBodies(t *testing.T) {
+	ms := newMockServer(t)
+	var logs bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:      ms.URL,
+		AuthToken:    "secret-token",
+		APIKey:       "secret-key",
+		Logger:       slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		DebugBodies:  true,
+		RedactFields: []string{"password", "Email"},
+	})
+	users := client.Model("users", nil)
+
+	created, err := users.Create(map[string]interface{}{
+		"id":       "user:1",
+		"name":     "Ada",
+		"email":    "ada@example.com",
+		"password": "hunter2",
+		"profile":  map[string]interface{}{"password": "old-secret"},
+	})
+	if err != nil || created["email"] != "ada@example.com" {
+		t.Fatalf("Expected the caller to read the whole response, got %v, %v", created, err)
+	}
+	found, err := users.Query().Where("email", "ada@example.com").Exec()
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Expected the match, got %v, %v", found, err)
+	}
+
+	entries := requestLogs(t, &logs)
+	if len(entries) != 2 {
+		t.Fatalf("Expected two entries, got %d", len(entries))
+	}
+	request, _ := entries[0]["request_body"].(string)
+	if !strings.Contains(request, `"name":"Ada"`) || !strings.Contains(request, `"password":"***(string)"`) {
+		t.Errorf("Expected the body with the password masked, got %s", request)
+	}
+	if response, _ := entries[0]["response_body"].(string); !strings.Contains(response, `"email":"***(string)"`) {
+		t.Errorf("Expected the response with the email masked, got %s", response)
+	}
+	if query, _ := entries[1]["request_body"].(string); !strings.Contains(query, `"value":"***(string)"`) {
+		t.Errorf("Expected the filter value masked, got %s", query)
+	}
+	for _, secret := range []string{"secret-token", "secret-key", "hunter2", "old-secret", "ada@example.com"} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("Expected %q to stay out of the log", secret)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package torm_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func newLookupCollection(t *testing.T) (*mockServer, *torm.Collection[*TestProduct]) {
+	ms := newMockServer(t)
+	for i := 1; i <= 5; i++ {
+		ms.seed("products", map[string]interface{}{
+			"id":          fmt.Sprintf("product:%d", i),
+			"name":        fmt.Sprintf("Product %d", i),
+			"sku":         fmt.Sprintf("SKU-%d", i),
+			"price":       float64(i * 10),
+			"description": "a long description that should never be transferred",
+			"category":    []string{"even", "odd"}[i%2],
+		})
+	}
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	return ms, torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} })
+}
+
+func TestLookupProjectsFields(t *testing.T) {
+	ms, products := newLookupCollection(t)
+
+	names, err := products.Lookup("name", nil, torm.WithLookupPageSize(2))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"product:1": "Product 1",
+		"product:2": "Product 2",
+		"product:3": "Product 3",
+		"product:4": "Product 4",
+		"product:5": "Product 5",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Unexpected lookup map: %v", names)
+	}
+
+	queries := 0
+	for _, req := range ms.requestLog() {
+		if req.Path != "/api/products/query" {
+			continue
+		}
+		queries++
+		if !reflect.DeepEqual(req.Body["fields"], []interface{}{"id", "name"}) {
+			t.Errorf("Expected projection of id and name, got %v", req.Body["fields"])
+		}
+	}
+	if queries != 3 {
+		t.Errorf("Expected 3 pages of 2, got %d queries", queries)
+	}
+}
+
+func TestLookupPairWithFilters(t *testing.T) {
+	_, products := newLookupCollection(t)
+
+	prices, err := products.LookupPair("sku", "price", map[string]interface{}{"category": "odd"})
+	if err != nil {
+		t.Fatalf("LookupPair failed: %v", err)
+	}
+
+	expected := map[string]interface{}{"SKU-1": float64(10), "SKU-3": float64(30), "SKU-5": float64(50)}
+	if !reflect.DeepEqual(prices, expected) {
+		t.Errorf("Unexpected lookup map: %v", prices)
+	}
+}
+
+func TestLookupLimitAndCallback(t *testing.T) {
+	_, products := newLookupCollection(t)
+
+	if _, err := products.Lookup("name", nil, torm.WithLookupLimit(3)); !errors.Is(err, torm.ErrLookupTooLarge) {
+		t.Errorf("Expected ErrLookupTooLarge, got %v", err)
+	}
+
+	streamed := make(map[string]interface{})
+	result, err := products.Lookup("sku", nil, torm.WithLookupLimit(10), torm.WithLookupCallback(func(key string, value interface{}) error {
+		streamed[key] = value
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Streaming lookup failed: %v", err)
+	}
+	if result != nil {
+		t.Error("Expected no map when streaming")
+	}
+	if len(streamed) != 5 || streamed["product:2"] != "SKU-2" {
+		t.Errorf("Unexpected streamed entries: %v", streamed)
+	}
+}
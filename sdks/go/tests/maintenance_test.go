@@ -0,0 +1,165 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestMaintenancePrunesAuditAndDeadLetters(t *testing.T) {
+	ms := newMockServer(t)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	dead := torm.DeadLetterFile(filepath.Join(t.TempDir(), "dead.jsonl"))
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} },
+		torm.WithAudit(torm.AuditOptions{Strict: true}), torm.WithDeadLetter(dead))
+	ctx := context.Background()
+
+	// Two entries 40 days old, one 20 days old and one from the last hour
+	for _, id := range []string{"product:1", "product:2"} {
+		if _, err := products.Create(&TestProduct{ID: id, Name: "Anvil"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(20 * 24 * time.Hour)
+	if err := products.Save(&TestProduct{ID: "product:1", Name: "Heavy anvil"}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(20*24*time.Hour - time.Hour)
+	if _, err := products.Create(&TestProduct{ID: "product:3", Name: "Hammer"}); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+
+	// Dead letters failed 1 to 5 days ago
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		dead.Capture(torm.DeadLetter{ID: id, Collection: "products", Operation: torm.OpDelete, DocID: id, FailedAt: clock.Now().Add(-time.Duration(i+1) * 24 * time.Hour)})
+	}
+	olderThan := func(d time.Duration) func(torm.DeadLetter) bool {
+		return func(entry torm.DeadLetter) bool { return entry.FailedAt.Before(clock.Now().Add(-d)) }
+	}
+
+	maintenance := client.Maintenance()
+	reports, err := maintenance.RunMaintenance(ctx, torm.MaintenanceSpec{
+		AuditOlderThan:    30 * 24 * time.Hour,
+		CompactMigrations: true,
+		PurgeDeadLetters:  olderThan(2*24*time.Hour + time.Minute),
+		DryRun:            true,
+	})
+	if err != nil {
+		t.Fatalf("RunMaintenance failed: %v", err)
+	}
+	if len(reports) != 3 || reports[0].Matched != 2 || reports[1].Matched != 0 || reports[2].Matched != 3 {
+		t.Fatalf("Expected 2 audit entries and 3 dead letters matched, got %+v %+v %+v", reports[0], reports[1], reports[2])
+	}
+	for _, report := range reports {
+		if !report.DryRun || report.Removed != 0 {
+			t.Errorf("Expected the dry run to remove nothing, got %+v", report)
+		}
+	}
+	if entries := len(ms.store("products_audit")); entries != 4 {
+		t.Errorf("Expected the dry run to keep all 4 audit entries, got %d", entries)
+	}
+
+	var progress []torm.MaintenanceProgress
+	opts := &torm.MaintenanceOptions{BatchSize: 2, Progress: func(p torm.MaintenanceProgress) { progress = append(progress, p) }}
+	report, err := maintenance.PruneAuditOlderThan(ctx, 10*24*time.Hour, opts)
+	if err != nil {
+		t.Fatalf("PruneAuditOlderThan failed: %v", err)
+	}
+	if report.Matched != 3 || report.Removed != 3 || len(progress) != 2 || progress[1].Removed != 3 {
+		t.Errorf("Expected 3 entries pruned in 2 batches, got %+v and %+v", report, progress)
+	}
+	trail, err := products.AuditTrail("product:3")
+	if err != nil || len(trail) != 1 {
+		t.Errorf("Expected the recent entry to survive, got %v, %v", trail, err)
+	}
+	if trail, _ := products.AuditTrail("product:1"); len(trail) != 0 {
+		t.Errorf("Expected the old entries to be pruned, got %d", len(trail))
+	}
+
+	progress = nil
+	report, err = maintenance.PurgeDeadLetters(ctx, olderThan(2*24*time.Hour+time.Minute), opts)
+	if err != nil {
+		t.Fatalf("PurgeDeadLetters failed: %v", err)
+	}
+	if report.Removed != 3 || len(progress) != 2 {
+		t.Errorf("Expected 3 dead letters purged in 2 batches, got %+v and %+v", report, progress)
+	}
+	if left, _ := dead.List(); len(left) != 2 || left[0].ID != "a" || left[1].ID != "b" {
+		t.Errorf("Expected the 2 recent dead letters to remain, got %+v", left)
+	}
+}
+
+func TestCompactMigrationsHistory(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	ctx := context.Background()
+
+	runs := map[string]int{}
+	manager := torm.NewMigrationManager(client)
+	for _, id := range []string{"001", "002", "003", "004"} {
+		id := id
+		manager.AddMigration(torm.Migration{ID: id, Name: "m" + id, Up: func(*torm.Client) error {
+			runs[id]++
+			return nil
+		}, Down: noopMigration})
+	}
+	manager.AddMigration(torm.Migration{ID: "005", Name: "broken", Up: func(*torm.Client) error {
+		return errors.New("not yet")
+	}, Down: noopMigration})
+	if _, err := manager.Migrate(); err == nil {
+		t.Fatal("Expected the last migration to fail")
+	}
+
+	maintenance := client.Maintenance()
+	preview, err := maintenance.CompactMigrationsHistory(ctx, 1, &torm.MaintenanceOptions{DryRun: true})
+	if err != nil || preview.Matched != 3 || preview.Removed != 0 {
+		t.Fatalf("Expected 3 records to compact in the dry run, got %+v, %v", preview, err)
+	}
+	if _, found, _ := client.GetKey("torm:migrations:001"); !found {
+		t.Fatal("Expected the dry run to keep the records")
+	}
+
+	var batches int
+	report, err := maintenance.CompactMigrationsHistory(ctx, 1, &torm.MaintenanceOptions{BatchSize: 2, Progress: func(torm.MaintenanceProgress) { batches++ }})
+	if err != nil {
+		t.Fatalf("CompactMigrationsHistory failed: %v", err)
+	}
+	if report.Removed != 3 || batches != 2 {
+		t.Errorf("Expected 3 records compacted in 2 batches, got %+v after %d", report, batches)
+	}
+	for id, kept := range map[string]bool{"001": false, "002": false, "003": false, "004": true, "005": true} {
+		if _, found, _ := client.GetKey("torm:migrations:" + id); found != kept {
+			t.Errorf("Expected the record key of %s kept=%v", id, kept)
+		}
+	}
+
+	// Compacted migrations stay applied
+	statuses, err := manager.StatusDetailed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses[:4] {
+		if status.State != torm.MigrationApplied {
+			t.Errorf("Expected %s to stay applied, got %s", status.ID, status.State)
+		}
+	}
+	if _, err := manager.Migrate(); err == nil || runs["001"] != 1 {
+		t.Errorf("Expected compacted migrations not to run again, got %d runs of 001", runs["001"])
+	}
+
+	// Rolling back reaches into the compacted records
+	if _, err := manager.Rollback(2); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	status, _ := manager.Status()
+	if status["003"] != "Pending" || !strings.HasPrefix(status["002"], "Applied") {
+		t.Errorf("Expected 003 to be rolled back out of the baseline, got %v", status)
+	}
+}
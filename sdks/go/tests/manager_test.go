@@ -0,0 +1,171 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+const prodToken = "yes-write-to-prod"
+
+// newEnvironments registers a dev and a write-protected prod environment,
+// each backed by its own mock server
+func newEnvironments(t *testing.T) (*torm.ClientManager, *mockServer, *mockServer) {
+	t.Helper()
+	dev, prod := newMockServer(t), newMockServer(t)
+
+	manager := torm.NewClientManager()
+	if _, err := manager.Register("dev", &torm.ClientOptions{BaseURL: dev.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Register("prod", &torm.ClientOptions{BaseURL: prod.URL, WriteConfirmation: prodToken}); err != nil {
+		t.Fatal(err)
+	}
+	return manager, dev, prod
+}
+
+func TestClientManagerRoutesByEnvironment(t *testing.T) {
+	manager, dev, prod := newEnvironments(t)
+	prod.seed("users", map[string]interface{}{"id": "user:1", "name": "Prod Alice"})
+
+	devUsers, err := torm.ManagedCollection(manager, "dev", "users", func() *TestUser { return &TestUser{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := devUsers.Create(&TestUser{ID: "user:2", Name: "Dev Bob"}); err != nil {
+		t.Fatalf("Create on dev failed: %v", err)
+	}
+	if _, ok := dev.doc("users", "user:2"); !ok {
+		t.Error("Expected the dev write to reach the dev server")
+	}
+	if _, ok := prod.doc("users", "user:2"); ok {
+		t.Error("Expected the dev write to stay off the prod server")
+	}
+
+	prodUsers, err := torm.ManagedCollection(manager, "prod", "users", func() *TestUser { return &TestUser{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := prodUsers.FindByID("user:1")
+	if err != nil || user.Name != "Prod Alice" {
+		t.Errorf("Expected to read user:1 from prod, got %+v, %v", user, err)
+	}
+
+	// The first environment registered is the default until changed
+	if client, _ := manager.Get(""); client.BaseURL != dev.URL {
+		t.Errorf("Expected dev to be the default, got %s", client.BaseURL)
+	}
+	if err := manager.Default("prod"); err != nil {
+		t.Fatal(err)
+	}
+	model, err := manager.Model("", "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := model.FindByID("user:1"); err != nil || doc["name"] != "Prod Alice" {
+		t.Errorf("Expected the default model to read from prod, got %v, %v", doc, err)
+	}
+}
+
+func TestClientManagerUnknownEnvironment(t *testing.T) {
+	manager, _, _ := newEnvironments(t)
+
+	if _, err := manager.Get("qa"); !errors.Is(err, torm.ErrUnknownEnvironment) {
+		t.Errorf("Expected ErrUnknownEnvironment, got %v", err)
+	}
+	if err := manager.Default("qa"); !errors.Is(err, torm.ErrUnknownEnvironment) {
+		t.Errorf("Expected ErrUnknownEnvironment from Default, got %v", err)
+	}
+	if _, err := torm.ManagedCollection(manager, "qa", "users", func() *TestUser { return &TestUser{} }); !errors.Is(err, torm.ErrUnknownEnvironment) {
+		t.Errorf("Expected ErrUnknownEnvironment from ManagedCollection, got %v", err)
+	}
+	if _, err := manager.Register("prod", nil); !errors.Is(err, torm.ErrEnvironmentExists) {
+		t.Errorf("Expected ErrEnvironmentExists, got %v", err)
+	}
+	if got := manager.Environments(); len(got) != 2 || got[0] != "dev" || got[1] != "prod" {
+		t.Errorf("Expected [dev prod], got %v", got)
+	}
+}
+
+func TestClientManagerProdWriteGuard(t *testing.T) {
+	manager, _, prod := newEnvironments(t)
+	prod.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+
+	users, err := torm.ManagedCollection(manager, "prod", "users", func() *TestUser { return &TestUser{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = users.Create(&TestUser{ID: "user:2", Name: "Bob"})
+	if !errors.Is(err, torm.ErrWriteProtected) {
+		t.Fatalf("Expected an unconfirmed create to fail with ErrWriteProtected, got %v", err)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryForbidden {
+		t.Errorf("Expected a forbidden error, got %s", torm.ErrorCategory(err))
+	}
+	if err := users.Confirm("wrong").Delete("user:1"); !errors.Is(err, torm.ErrWriteProtected) {
+		t.Errorf("Expected a wrong token to be refused, got %v", err)
+	}
+	if prod.countRequests("POST", "/api/users") != 0 || prod.countRequests("DELETE", "/api/users/user:1") != 0 {
+		t.Error("Expected refused writes to never reach the server")
+	}
+
+	// Reads need no confirmation
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Errorf("Expected reads to be allowed, got %v", err)
+	}
+
+	if _, err := users.Confirm(prodToken).Create(&TestUser{ID: "user:2", Name: "Bob"}); err != nil {
+		t.Fatalf("Expected a confirmed create to succeed, got %v", err)
+	}
+	if _, ok := prod.doc("users", "user:2"); !ok {
+		t.Error("Expected the confirmed create to reach prod")
+	}
+	for _, req := range prod.requestLog() {
+		if req.Header.Get("X-Torm-Confirm") != "" {
+			t.Errorf("Expected the confirmation token to stay on the client, %s %s sent it", req.Method, req.Path)
+		}
+	}
+
+	// Confirmation covers only the confirmed copy
+	if err := users.Delete("user:2"); !errors.Is(err, torm.ErrWriteProtected) {
+		t.Errorf("Expected the original collection to stay protected, got %v", err)
+	}
+
+	model, err := manager.Model("prod", "users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.Delete("user:2"); !errors.Is(err, torm.ErrWriteProtected) {
+		t.Errorf("Expected an unconfirmed model delete to be refused, got %v", err)
+	}
+	if _, err := model.Confirm(prodToken).Delete("user:2"); err != nil {
+		t.Errorf("Expected a confirmed model delete to succeed, got %v", err)
+	}
+}
+
+func TestClientManagerHealthAll(t *testing.T) {
+	manager, _, _ := newEnvironments(t)
+	down := httptest.NewServer(nil)
+	down.Close()
+	if _, err := manager.Register("staging", &torm.ClientOptions{BaseURL: down.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	report := manager.HealthAll()
+	if len(report) != 3 {
+		t.Fatalf("Expected 3 environments, got %d", len(report))
+	}
+	dev, prod, staging := report[0], report[1], report[2]
+	if dev.Name != "dev" || !dev.Default || dev.WriteProtected || dev.Err != nil || dev.Health["status"] != "ok" {
+		t.Errorf("Unexpected dev health: %+v", dev)
+	}
+	if prod.Name != "prod" || prod.Default || !prod.WriteProtected || prod.Err != nil {
+		t.Errorf("Unexpected prod health: %+v", prod)
+	}
+	if staging.Name != "staging" || staging.Err == nil {
+		t.Errorf("Expected staging to report its failed check, got %+v", staging)
+	}
+}
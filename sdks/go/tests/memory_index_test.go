@@ -0,0 +1,162 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedProducts(ms *mockServer) {
+	ms.seed("products",
+		map[string]interface{}{"id": "product:1", "sku": "A-1", "name": "Anvil", "price": 10},
+		map[string]interface{}{"id": "product:2", "sku": "B-2", "name": "Bolt", "price": 2.5},
+		map[string]interface{}{"id": "product:3", "sku": "A-1", "name": "Anvil (old)", "price": 10},
+	)
+}
+
+func TestMemoryIndexLookups(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} })
+
+	index, err := products.LoadIndex(context.Background(), "sku", "price")
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	if fields := lastQueryBody(t, ms); !strings.Contains(fields, `"fields":["id","sku","price"]`) {
+		t.Errorf("Expected the snapshot to be projected, got %s", fields)
+	}
+
+	requests := len(ms.requestLog())
+	if ids := index.GetByField("sku", "A-1"); strings.Join(ids, ",") != "product:1,product:3" {
+		t.Errorf("Unexpected ids for A-1: %v", ids)
+	}
+	if ids := index.GetByField("price", 10.0); len(ids) != 2 {
+		t.Errorf("Expected numbers to match across types, got %v", ids)
+	}
+	if ids := index.GetByField("price", "10"); len(ids) != 0 {
+		t.Errorf("Expected a string not to match a number, got %v", ids)
+	}
+	if ids := index.GetByField("name", "Bolt"); ids != nil {
+		t.Errorf("Expected nil for an unindexed field, got %v", ids)
+	}
+	if n := len(ms.requestLog()); n != requests {
+		t.Errorf("Expected lookups to stay in memory, got %d requests", n-requests)
+	}
+
+	stats := index.Stats()
+	if stats.Documents != 3 || stats.Entries != 6 || stats.ApproxBytes <= 0 || stats.Refreshes != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	found, err := index.Get("sku", "B-2")
+	if err != nil || len(found) != 1 || found[0].Name != "Bolt" {
+		t.Fatalf("Unexpected hydrated products: %v, %v", found, err)
+	}
+}
+
+func TestMemoryIndexLocalWrites(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} })
+
+	index, err := products.LoadIndex(context.Background(), "sku")
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	if _, err := products.Create(&TestProduct{ID: "product:4", SKU: "C-3", Name: "Clamp"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if ids := index.GetByField("sku", "C-3"); strings.Join(ids, ",") != "product:4" {
+		t.Errorf("Expected the created product indexed, got %v", ids)
+	}
+
+	if err := products.Save(&TestProduct{ID: "product:3", SKU: "D-4", Name: "Anvil (old)"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if ids := index.GetByField("sku", "A-1"); strings.Join(ids, ",") != "product:1" {
+		t.Errorf("Expected product:3 moved off A-1, got %v", ids)
+	}
+	if ids := index.GetByField("sku", "D-4"); strings.Join(ids, ",") != "product:3" {
+		t.Errorf("Expected product:3 under D-4, got %v", ids)
+	}
+
+	if err := products.Delete("product:1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if ids := index.GetByField("sku", "A-1"); len(ids) != 0 {
+		t.Errorf("Expected A-1 gone after delete, got %v", ids)
+	}
+	if stats := index.Stats(); stats.Documents != 3 || stats.Entries != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemoryIndexRefresh(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} })
+
+	index, err := products.LoadIndex(context.Background(), "sku")
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	// Written by someone else, so only a refresh sees it
+	ms.seed("products", map[string]interface{}{"id": "product:9", "sku": "Z-9", "name": "Zip"})
+	if ids := index.GetByField("sku", "Z-9"); len(ids) != 0 {
+		t.Fatalf("Expected Z-9 unknown before refresh, got %v", ids)
+	}
+	if err := index.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if ids := index.GetByField("sku", "Z-9"); strings.Join(ids, ",") != "product:9" {
+		t.Errorf("Expected refresh to pick up product:9, got %v", ids)
+	}
+
+	ms.seed("products", map[string]interface{}{"id": "product:10", "sku": "Y-8"})
+	index.AutoRefresh(10 * time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(index.GetByField("sku", "Y-8")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected auto-refresh to pick up product:10")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMemoryIndexLimit(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} },
+		torm.WithIndexLimit(4))
+
+	if _, err := products.LoadIndex(context.Background(), "sku", "price"); !errors.Is(err, torm.ErrIndexTooLarge) {
+		t.Fatalf("Expected ErrIndexTooLarge, got %v", err)
+	}
+
+	index, err := products.LoadIndex(context.Background(), "sku")
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	defer index.Close()
+
+	products.Create(&TestProduct{ID: "product:4", SKU: "C-3"})
+	products.Create(&TestProduct{ID: "product:5", SKU: "E-5"})
+	if stats := index.Stats(); stats.Entries != 4 || !stats.Stale {
+		t.Errorf("Expected the index capped at 4 entries and stale, got %+v", stats)
+	}
+}
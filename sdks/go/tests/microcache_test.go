@@ -0,0 +1,178 @@
+package torm_test
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// fakeClock is a settable time source
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestMicroCacheCoalescesConcurrentCounts(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1"}, map[string]interface{}{"id": "user:2"})
+
+	var hits atomic.Int32
+	release := make(chan struct{})
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.URL.Path == "/api/users/count" {
+			hits.Add(1)
+			<-release
+		}
+		return false
+	})
+
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:    ms.URL,
+		MicroCache: torm.MicroCacheOptions{Count: 100 * time.Millisecond},
+		Clock:      newFakeClock().Now,
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	var wg sync.WaitGroup
+	counts := make([]int, 20)
+	for i := range counts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			counts[i], _ = users.Count()
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, count := range counts {
+		if count != 2 {
+			t.Errorf("Caller %d got count %d", i, count)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("Expected one request for 20 concurrent callers, got %d", n)
+	}
+
+	// Models of the same collection share the result too
+	if count, err := client.Model("users", nil).Count(); err != nil || count != 2 || hits.Load() != 1 {
+		t.Errorf("Expected Model.Count to share the result, got %d (%v) after %d requests", count, err, hits.Load())
+	}
+}
+
+func TestMicroCacheWindow(t *testing.T) {
+	ms := newMockServer(t)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:    ms.URL,
+		MicroCache: torm.MicroCacheOptions{Count: 100 * time.Millisecond, Health: time.Second},
+		Clock:      clock.Now,
+	})
+	users := client.Model("users", nil)
+
+	users.Count()
+	clock.Advance(99 * time.Millisecond)
+	users.Count()
+	if n := ms.countRequests("GET", "/api/users/count"); n != 1 {
+		t.Errorf("Expected the second call inside the window to be shared, got %d requests", n)
+	}
+
+	clock.Advance(time.Millisecond)
+	users.Count()
+	users.Count()
+	if n := ms.countRequests("GET", "/api/users/count"); n != 2 {
+		t.Errorf("Expected one refresh at the window boundary, got %d requests", n)
+	}
+
+	// Windows are per operation: the health window outlives the count window
+	client.Health()
+	clock.Advance(500 * time.Millisecond)
+	health, err := client.Health()
+	if err != nil || ms.countRequests("GET", "/health") != 1 {
+		t.Errorf("Expected one health request, got %d: %v", ms.countRequests("GET", "/health"), err)
+	}
+	health["status"] = "mutated"
+	if again, _ := client.Health(); again["status"] == "mutated" {
+		t.Error("Expected each caller to get its own copy of the shared result")
+	}
+	users.Count()
+	if n := ms.countRequests("GET", "/api/users/count"); n != 3 {
+		t.Errorf("Expected the count window to have expired, got %d requests", n)
+	}
+
+	// Zero disables sharing
+	uncached := users.WithMicroCache(0)
+	uncached.Count()
+	uncached.Count()
+	if n := ms.countRequests("GET", "/api/users/count"); n != 5 {
+		t.Errorf("Expected every uncached count to reach the server, got %d requests", n)
+	}
+	client.Info()
+	client.Info()
+	if n := ms.countRequests("GET", "/"); n != 2 {
+		t.Errorf("Expected Info without a window to be uncached, got %d requests", n)
+	}
+}
+
+func TestMicroCacheDoesNotKeepFailures(t *testing.T) {
+	ms := newMockServer(t)
+	failing := true
+	var mu sync.Mutex
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path != "/api/_collections" {
+			return false
+		}
+		if failing {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "down"})
+			return true
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"collections": []interface{}{"users", map[string]interface{}{"name": "orders"}},
+		})
+		return true
+	})
+
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:    ms.URL,
+		MicroCache: torm.MicroCacheOptions{ListCollections: time.Minute},
+		Clock:      newFakeClock().Now,
+	})
+	if _, err := client.ListCollections(); err == nil {
+		t.Fatal("Expected the failure to be reported")
+	}
+
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	names, err := client.ListCollections()
+	if err != nil || len(names) != 2 || names[0] != "users" || names[1] != "orders" {
+		t.Fatalf("Expected the collections after the failure, got %v: %v", names, err)
+	}
+	client.ListCollections()
+	if n := ms.countRequests("GET", "/api/_collections"); n != 2 {
+		t.Errorf("Expected the failure refetched and the success shared, got %d requests", n)
+	}
+}
@@ -0,0 +1,174 @@
+package torm_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestMiddlewareInjectsHeaders(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Retry: torm.RetryOptions{MaxAttempts: 3, Backoff: time.Millisecond}})
+	client.Use(func(next torm.RoundTripFunc) torm.RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Tenant", "acme")
+			return next(req)
+		}
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if _, err := users.Create(&TestUser{ID: "user:1", Name: "Ada"}); err != nil {
+		t.Fatal(err)
+	}
+	// The header survives the retry of a failed read
+	failed := false
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodGet || failed {
+			return false
+		}
+		failed = true
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	})
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	client.SetKey("greeting", "hello")
+
+	log := ms.requestLog()
+	if len(log) != 4 {
+		t.Fatalf("Expected a create, two read attempts and a key write, got %d requests", len(log))
+	}
+	for _, req := range log {
+		if req.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("Expected the header on %s %s", req.Method, req.Path)
+		}
+	}
+}
+
+func TestMiddlewareRecordsLatencyInOrder(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	var mu sync.Mutex
+	var order []string
+	latency := map[string][]time.Duration{}
+	client.Use(
+		func(next torm.RoundTripFunc) torm.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, "outer")
+				started := time.Now()
+				resp, err := next(req)
+				mu.Lock()
+				latency[req.URL.Path] = append(latency[req.URL.Path], time.Since(started))
+				mu.Unlock()
+				order = append(order, "outer done")
+				return resp, err
+			}
+		},
+		func(next torm.RoundTripFunc) torm.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, "inner")
+				resp, err := next(req)
+				order = append(order, "inner done")
+				return resp, err
+			}
+		},
+	)
+
+	users := client.Model("users", nil)
+	for i := 0; i < 2; i++ {
+		if _, err := users.FindByID("user:1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := users.Query().Where("name", "Ada").Exec(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(latency["/api/users/user:1"]) != 2 || len(latency["/api/users/query"]) != 1 {
+		t.Errorf("Expected latency recorded per path, got %v", latency)
+	}
+	if want := []string{"outer", "inner", "inner done", "outer done"}; !reflect.DeepEqual(order[:4], want) {
+		t.Errorf("Expected middleware to run in registration order, got %v", order[:4])
+	}
+}
+
+func TestMiddlewareShortCircuitsAndRewrites(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	client.Use(func(next torm.RoundTripFunc) torm.RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			switch {
+			case req.URL.Path == "/api/users/user:9":
+				// Served from a cache without reaching the server
+				body := `{"id": "user:9", "name": "Cached"}`
+				return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+			case req.Method == http.MethodPost && req.URL.Path == "/api/users":
+				var body struct {
+					Data map[string]interface{} `json:"data"`
+				}
+				json.NewDecoder(req.Body).Decode(&body)
+				body.Data["source"] = "sdk"
+				raw, _ := json.Marshal(body)
+				torm.SetRequestBody(req, raw)
+			}
+			resp, err := next(req)
+			if err == nil && req.Method == http.MethodGet {
+				raw, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				resp.Body = io.NopCloser(bytes.NewReader(bytes.ReplaceAll(raw, []byte("Ada"), []byte("Ada L."))))
+			}
+			return resp, err
+		}
+	})
+	users := client.Model("users", nil)
+
+	cached, err := users.FindByID("user:9")
+	if err != nil || cached["name"] != "Cached" {
+		t.Fatalf("Expected the short-circuited response, got %v, %v", cached, err)
+	}
+	if n := ms.countRequests("GET", "/api/users/user:9"); n != 0 {
+		t.Errorf("Expected no request to reach the server, got %d", n)
+	}
+
+	if _, err := users.Create(map[string]interface{}{"id": "user:2", "name": "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := ms.doc("users", "user:2"); stored["source"] != "sdk" {
+		t.Errorf("Expected the rewritten body to be stored, got %v", stored)
+	}
+
+	doc, err := users.FindByID("user:1")
+	if err != nil || doc["name"] != "Ada L." {
+		t.Errorf("Expected the rewritten response, got %v, %v", doc, err)
+	}
+}
+
+func TestMiddlewarePanicFailsRequest(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	client.Use(func(next torm.RoundTripFunc) torm.RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			panic("broken middleware")
+		}
+	})
+
+	_, err := client.Model("users", nil).FindByID("user:1")
+	var panicErr *torm.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a PanicError, got %v", err)
+	}
+}
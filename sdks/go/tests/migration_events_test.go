@@ -0,0 +1,154 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// hookLog records migration hook calls in order
+type hookLog []string
+
+func (l *hookLog) attach(manager *torm.MigrationManager) {
+	manager.OnMigrationStart(func(id, name string) {
+		*l = append(*l, "start "+id)
+	})
+	manager.OnMigrationProgress(func(id string, processed, total int) {
+		*l = append(*l, fmt.Sprintf("progress %s %d/%d", id, processed, total))
+	})
+	manager.OnMigrationComplete(func(id string, duration time.Duration) {
+		*l = append(*l, "complete "+id)
+	})
+	manager.OnMigrationError(func(id string, err error) {
+		*l = append(*l, "error "+id)
+	})
+}
+
+// slowPlan registers three migrations; the second walks every item, calling
+// step before each one
+func slowPlan(client *torm.Client, step func(doc map[string]interface{})) *torm.MigrationManager {
+	manager := torm.NewMigrationManager(client)
+	items := client.Model("items", nil)
+
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "backfill", Up: func(*torm.Client) error {
+		return manager.Backfill(items, func(doc map[string]interface{}) (map[string]interface{}, error) {
+			step(doc)
+			return map[string]interface{}{"migrated": true}, nil
+		})
+	}, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "003", Name: "third", Up: noopMigration, Down: noopMigration})
+	return manager
+}
+
+func TestMigrationHooks(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("items", map[string]interface{}{"id": "a"}, map[string]interface{}{"id": "b"}, map[string]interface{}{"id": "c"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	manager := slowPlan(client, func(map[string]interface{}) { time.Sleep(time.Millisecond) })
+	var log hookLog
+	log.attach(manager)
+
+	result, err := manager.MigrateContext(context.Background())
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	want := hookLog{
+		"start 001", "complete 001",
+		"start 002", "progress 002 1/3", "progress 002 2/3", "progress 002 3/3", "complete 002",
+		"start 003", "complete 003",
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("Unexpected hook calls:\n got %v\nwant %v", log, want)
+	}
+	if result.Migrations[1].Processed != 3 {
+		t.Errorf("Expected 3 processed documents, got %d", result.Migrations[1].Processed)
+	}
+	if doc, _ := ms.doc("items", "c"); doc["migrated"] != true {
+		t.Errorf("Expected the backfill to write, got %v", doc)
+	}
+
+	// Applied migrations are skipped silently
+	log = nil
+	if _, err := manager.Migrate(); err != nil || len(log) != 0 {
+		t.Errorf("Expected a silent no-op run, got %v, %v", log, err)
+	}
+}
+
+func TestMigrateContextCancelledMidMigration(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("items", map[string]interface{}{"id": "a"}, map[string]interface{}{"id": "b"}, map[string]interface{}{"id": "c"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	// The deploy is cancelled while the second document is migrated
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := slowPlan(client, func(doc map[string]interface{}) {
+		if doc["id"] == "b" {
+			cancel()
+		}
+	})
+	var log hookLog
+	log.attach(manager)
+
+	result, err := manager.MigrateContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+
+	want := hookLog{"start 001", "complete 001", "start 002", "progress 002 1/3", "progress 002 2/3", "error 002"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("Unexpected hook calls:\n got %v\nwant %v", log, want)
+	}
+	if !sameOutcomes(outcomes(result), torm.OutcomeApplied, torm.OutcomeFailed, torm.OutcomeNotRun) {
+		t.Errorf("Unexpected outcomes: %v", outcomes(result))
+	}
+	if result.StoppedAt != "002" || result.Migrations[1].Processed != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if doc, _ := ms.doc("items", "c"); doc["migrated"] != nil {
+		t.Errorf("Expected the run to stop before the third document, got %v", doc)
+	}
+
+	// The partial progress is recorded and the migration runs again
+	statuses, err := manager.StatusDetailed()
+	if err != nil {
+		t.Fatalf("StatusDetailed failed: %v", err)
+	}
+	if statuses[1].State != torm.MigrationFailed || statuses[1].Processed != 2 {
+		t.Errorf("Expected a failed record with 2 processed, got %+v", statuses[1])
+	}
+	if statuses[2].State != torm.MigrationPending {
+		t.Errorf("Expected the third migration to be pending, got %+v", statuses[2])
+	}
+}
+
+func TestMigrateContextCancelledBetweenMigrations(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := slowPlan(client, func(map[string]interface{}) {})
+	var log hookLog
+	log.attach(manager)
+	manager.OnMigrationComplete(func(id string, duration time.Duration) { cancel() })
+
+	result, err := manager.MigrateContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+	if want := (hookLog{"start 001", "complete 001"}); !reflect.DeepEqual(log, want) {
+		t.Errorf("Unexpected hook calls:\n got %v\nwant %v", log, want)
+	}
+	if !sameOutcomes(outcomes(result), torm.OutcomeApplied, torm.OutcomeNotRun, torm.OutcomeNotRun) || result.StoppedAt != "002" {
+		t.Errorf("Unexpected result: %v, stopped at %q", outcomes(result), result.StoppedAt)
+	}
+}
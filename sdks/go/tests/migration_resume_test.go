@@ -0,0 +1,145 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// cancellingDown returns a Down that reverts the items' flag through
+// Backfill, cancelling the rollback after the given number of documents
+func cancellingDown(manager *torm.MigrationManager, items *torm.Model, cancel context.CancelFunc, after int) func(*torm.Client) error {
+	return func(*torm.Client) error {
+		reverted := 0
+		return manager.Backfill(items, func(doc map[string]interface{}) (map[string]interface{}, error) {
+			reverted++
+			if reverted == after {
+				cancel()
+			}
+			return map[string]interface{}{"id": doc["id"], "flag": false}, nil
+		})
+	}
+}
+
+func TestRollbackCancelledMidDown(t *testing.T) {
+	ms := newMockServer(t)
+	for i := 1; i <= 10; i++ {
+		ms.seed("items", map[string]interface{}{"id": fmt.Sprintf("item:%02d", i), "flag": true})
+	}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "flag items", Up: noopMigration, Down: cancellingDown(manager, items, cancel, 4)})
+	if _, err := manager.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	result, err := manager.RollbackContext(ctx, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+	if !sameOutcomes(outcomes(result), torm.OutcomeFailed, torm.OutcomeNotRun) || result.Migrations[0].Processed != 4 {
+		t.Errorf("Expected 002 to stop after 4 documents, got %+v", result)
+	}
+
+	statuses, err := manager.StatusDetailed()
+	if err != nil {
+		t.Fatalf("StatusDetailed failed: %v", err)
+	}
+	if statuses[0].State != torm.MigrationApplied || statuses[1].State != torm.MigrationRollingBack {
+		t.Errorf("Expected 001 applied and 002 rolling back, got %+v", statuses)
+	}
+	status, _ := manager.Status()
+	if status["002"][:len("Rolling back")] != "Rolling back" {
+		t.Errorf("Expected a rolling back status, got %q", status["002"])
+	}
+
+	// Migrate leaves an interrupted rollback alone
+	migrated, err := manager.MigrateDetailed()
+	if err != nil || !sameOutcomes(outcomes(migrated), torm.OutcomeSkipped, torm.OutcomeSkipped) {
+		t.Errorf("Expected Migrate to skip both, got %v: %v", outcomes(migrated), err)
+	}
+}
+
+func TestResumeInterruptedRetryDown(t *testing.T) {
+	ms := newMockServer(t)
+	for i := 1; i <= 10; i++ {
+		ms.seed("items", map[string]interface{}{"id": fmt.Sprintf("item:%02d", i), "flag": true})
+	}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "flag items", Up: noopMigration, Down: cancellingDown(manager, items, cancel, 3)})
+	if _, err := manager.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if _, err := manager.RollbackContext(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancellation, got %v", err)
+	}
+
+	interrupted, err := manager.ResumeInterrupted()
+	if err != nil || len(interrupted) != 1 {
+		t.Fatalf("Expected one interrupted migration, got %+v: %v", interrupted, err)
+	}
+	stuck := interrupted[0]
+	if stuck.ID != "001" || stuck.State != torm.MigrationRollingBack || !stuck.Registered || stuck.Since.IsZero() {
+		t.Errorf("Unexpected interrupted migration: %+v", stuck)
+	}
+
+	outcome, err := stuck.RetryDown(context.Background())
+	if err != nil || outcome.Outcome != torm.OutcomeRolledBack || outcome.Processed != 10 {
+		t.Fatalf("Expected the retry to revert every item, got %+v: %v", outcome, err)
+	}
+	for id, doc := range ms.store("items") {
+		if doc["flag"] != false {
+			t.Errorf("Expected %s reverted, got %v", id, doc)
+		}
+	}
+	statuses, _ := manager.StatusDetailed()
+	if statuses[0].State != torm.MigrationPending {
+		t.Errorf("Expected the rolled back migration pending, got %+v", statuses)
+	}
+	if _, err := stuck.RetryDown(context.Background()); err == nil {
+		t.Error("Expected a second retry to fail")
+	}
+}
+
+func TestResumeInterruptedMarkApplied(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	boom := errors.New("boom")
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: func(*torm.Client) error { return boom }})
+	if _, err := manager.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if _, err := manager.Rollback(1); !errors.Is(err, boom) {
+		t.Fatalf("Expected Down failure, got %v", err)
+	}
+
+	interrupted, err := manager.ResumeInterrupted()
+	if err != nil || len(interrupted) != 1 {
+		t.Fatalf("Expected one interrupted migration, got %+v: %v", interrupted, err)
+	}
+	if err := interrupted[0].MarkApplied(); err != nil {
+		t.Fatalf("MarkApplied failed: %v", err)
+	}
+
+	statuses, _ := manager.StatusDetailed()
+	if statuses[0].State != torm.MigrationApplied || statuses[0].AppliedAt.IsZero() {
+		t.Errorf("Expected the migration applied again, got %+v", statuses)
+	}
+	if remaining, _ := manager.ResumeInterrupted(); len(remaining) != 0 {
+		t.Errorf("Expected nothing interrupted, got %+v", remaining)
+	}
+}
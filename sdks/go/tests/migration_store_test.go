@@ -0,0 +1,155 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestMigrationRecordSplitsLegacyFormat(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	legacy := `{
+		"001": {"id": "001", "name": "first", "status": "applied", "applied_at": "2024-01-01T00:00:00Z"},
+		"002": {"id": "002", "name": "second", "status": "applied", "applied_at": "2024-01-02T00:00:00Z"}
+	}`
+	client.SetKey("torm:migrations", legacy)
+
+	ran := false
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "second", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "003", Name: "third", Up: func(*torm.Client) error {
+		ran = true
+		return nil
+	}, Down: noopMigration})
+
+	names, err := manager.Migrate()
+	if err != nil || !ran || len(names) != 1 || names[0] != "third" {
+		t.Fatalf("Expected only the new migration to run, got %v, %v", names, err)
+	}
+
+	for _, id := range []string{"001", "002", "003"} {
+		if _, found, _ := client.GetKey("torm:migrations:" + id); !found {
+			t.Errorf("Expected a record key for %s", id)
+		}
+	}
+	index, _, _ := client.GetKey("torm:migrations-index")
+	if index != `["001","002","003"]` {
+		t.Errorf("Unexpected index %s", index)
+	}
+	if backup, _, _ := client.GetKey("torm:migrations-backup"); backup != legacy {
+		t.Errorf("Expected the legacy record to be backed up, got %s", backup)
+	}
+	if current, _, _ := client.GetKey("torm:migrations"); current != legacy {
+		t.Errorf("Expected the legacy record to be left alone without the flag, got %s", current)
+	}
+
+	// Later runs read the split records only
+	client.SetKey("torm:migrations", `{}`)
+	statuses, err := manager.StatusDetailed()
+	if err != nil {
+		t.Fatalf("StatusDetailed failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.State != torm.MigrationApplied {
+			t.Errorf("Expected %s to be applied, got %s", status.ID, status.State)
+		}
+	}
+
+	if _, err := manager.Rollback(1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if _, found, _ := client.GetKey("torm:migrations:003"); found {
+		t.Error("Expected the rolled back record key to be deleted")
+	}
+	if index, _, _ := client.GetKey("torm:migrations-index"); index != `["001","002"]` {
+		t.Errorf("Unexpected index after rollback %s", index)
+	}
+}
+
+func TestMigrationRecordConcurrentAppliers(t *testing.T) {
+	ms := newMockServer(t)
+
+	// Each deploy applies its own migration against the same store
+	const appliers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, appliers)
+	for i := 0; i < appliers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			manager := torm.NewMigrationManager(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}))
+			manager.AddMigration(torm.Migration{ID: fmt.Sprintf("%03d", i), Name: "step", Up: noopMigration, Down: noopMigration})
+			if _, err := manager.Migrate(); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	manager := torm.NewMigrationManager(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}))
+	for i := 0; i < appliers; i++ {
+		manager.AddMigration(torm.Migration{ID: fmt.Sprintf("%03d", i), Name: "step", Up: noopMigration, Down: noopMigration})
+	}
+	statuses, err := manager.StatusDetailed()
+	if err != nil {
+		t.Fatalf("StatusDetailed failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.State != torm.MigrationApplied {
+			t.Errorf("Lost the record of %s: %s", status.ID, status.State)
+		}
+	}
+}
+
+func TestMigrationRecordLegacyCompatibility(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	manager := torm.NewMigrationManager(client).WithLegacyRecord()
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "second", Up: noopMigration, Down: noopMigration})
+	if _, err := manager.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Older versions read every record from the single key
+	raw, found, _ := client.GetKey("torm:migrations")
+	var summary map[string]map[string]interface{}
+	if !found || json.Unmarshal([]byte(raw), &summary) != nil {
+		t.Fatalf("Expected a legacy record, got %q", raw)
+	}
+	if len(summary) != 2 || summary["002"]["status"] != "applied" || summary["002"]["applied_at"] == nil {
+		t.Errorf("Unexpected legacy record %v", summary)
+	}
+	if _, ok := summary["002"]["duration_ms"]; ok {
+		t.Errorf("Expected the legacy record to be summarized, got %v", summary["002"])
+	}
+
+	if _, err := manager.Rollback(1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	raw, _, _ = client.GetKey("torm:migrations")
+	summary = nil
+	json.Unmarshal([]byte(raw), &summary)
+	if _, ok := summary["002"]; ok || len(summary) != 1 {
+		t.Errorf("Expected the rollback in the legacy record, got %v", summary)
+	}
+
+	// Without the flag the single key is not written
+	other := newMockServer(t)
+	plain := torm.NewMigrationManager(torm.NewClient(&torm.ClientOptions{BaseURL: other.URL}))
+	plain.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	plain.Migrate()
+	if _, found, _ := torm.NewClient(&torm.ClientOptions{BaseURL: other.URL}).GetKey("torm:migrations"); found {
+		t.Error("Expected no legacy record without the flag")
+	}
+}
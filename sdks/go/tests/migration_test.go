@@ -0,0 +1,266 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func noopMigration(*torm.Client) error { return nil }
+
+func TestMigrationStatusDetailed(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	// Records left behind by earlier deploys: one with a broken timestamp,
+	// one renamed since, and one whose migration no longer exists
+	client.SetKey("torm:migrations", `{
+		"000_legacy": {"id": "000_legacy", "name": "legacy", "applied_at": "not-a-time"},
+		"001_renamed": {"id": "001_renamed", "name": "old name", "applied_at": "2024-01-02T03:04:05Z"},
+		"099_removed": {"id": "099_removed", "name": "removed", "applied_at": "2024-01-01T00:00:00Z"}
+	}`)
+
+	boom := errors.New("boom")
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "000_legacy", Name: "legacy", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "001_renamed", Name: "new name", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002_users", Name: "create users", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "003_broken", Name: "broken", Up: func(*torm.Client) error { return boom }, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "004_later", Name: "later", Up: noopMigration, Down: noopMigration})
+
+	if _, err := manager.Migrate(); !errors.Is(err, boom) {
+		t.Fatalf("Expected migration failure, got %v", err)
+	}
+
+	statuses, err := manager.StatusDetailed()
+	if err != nil {
+		t.Fatalf("StatusDetailed failed: %v", err)
+	}
+
+	byID := make(map[string]torm.MigrationStatus)
+	for _, status := range statuses {
+		byID[status.ID] = status
+	}
+
+	expected := map[string]torm.MigrationState{
+		"000_legacy":  torm.MigrationApplied,
+		"001_renamed": torm.MigrationDrifted,
+		"002_users":   torm.MigrationApplied,
+		"003_broken":  torm.MigrationFailed,
+		"004_later":   torm.MigrationPending,
+		"099_removed": torm.MigrationDrifted,
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("Expected %d statuses, got %d", len(expected), len(statuses))
+	}
+	for id, state := range expected {
+		if byID[id].State != state {
+			t.Errorf("Expected %s to be %s, got %s", id, state, byID[id].State)
+		}
+	}
+
+	if !byID["000_legacy"].AppliedAt.IsZero() {
+		t.Error("Expected invalid timestamp to yield a zero AppliedAt")
+	}
+	if byID["002_users"].AppliedAt.IsZero() {
+		t.Error("Expected applied migration to carry its timestamp")
+	}
+	if byID["003_broken"].Error != "boom" {
+		t.Errorf("Expected failure message, got %q", byID["003_broken"].Error)
+	}
+
+	legacy, err := manager.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !strings.HasPrefix(legacy["002_users"], "Applied (") || legacy["004_later"] != "Pending" {
+		t.Errorf("Unexpected legacy status: %v", legacy)
+	}
+}
+
+func TestMigrationStatusJSONAndRetry(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	fail := true
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "flaky", Up: func(*torm.Client) error {
+		if fail {
+			return errors.New("not yet")
+		}
+		return nil
+	}, Down: noopMigration})
+
+	manager.Migrate()
+
+	raw, err := manager.StatusJSON()
+	if err != nil {
+		t.Fatalf("StatusJSON failed: %v", err)
+	}
+
+	var doc struct {
+		Migrations []struct {
+			ID        string `json:"id"`
+			State     string `json:"state"`
+			AppliedAt string `json:"applied_at"`
+			Error     string `json:"error"`
+		} `json:"migrations"`
+		Counts map[string]int `json:"counts"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("StatusJSON produced invalid JSON: %v", err)
+	}
+	if len(doc.Migrations) != 2 || doc.Migrations[0].State != "applied" || doc.Migrations[0].AppliedAt == "" {
+		t.Errorf("Unexpected migrations in JSON: %s", raw)
+	}
+	if doc.Migrations[1].State != "failed" || doc.Migrations[1].Error != "not yet" {
+		t.Errorf("Expected failed migration in JSON: %s", raw)
+	}
+	if doc.Counts["applied"] != 1 || doc.Counts["failed"] != 1 || doc.Counts["pending"] != 0 {
+		t.Errorf("Unexpected counts: %v", doc.Counts)
+	}
+
+	// A failed migration is retried on the next run
+	fail = false
+	applied, err := manager.Migrate()
+	if err != nil || len(applied) != 1 || applied[0] != "flaky" {
+		t.Errorf("Expected failed migration to be retried, got %v %v", applied, err)
+	}
+}
+
+func outcomes(result *torm.MigrateResult) []torm.MigrationOutcome {
+	list := make([]torm.MigrationOutcome, len(result.Migrations))
+	for i, migration := range result.Migrations {
+		list[i] = migration.Outcome
+	}
+	return list
+}
+
+func sameOutcomes(got []torm.MigrationOutcome, want ...torm.MigrationOutcome) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMigrateDetailed(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	boom := errors.New("boom")
+	failing := true
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "second", Up: func(*torm.Client) error {
+		if failing {
+			return boom
+		}
+		return nil
+	}, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "003", Name: "third", Up: noopMigration, Down: noopMigration})
+
+	// A mid-run failure stops the run and is recorded
+	result, err := manager.MigrateDetailed()
+	if !errors.Is(err, boom) {
+		t.Fatalf("Expected migration failure, got %v", err)
+	}
+	if !sameOutcomes(outcomes(result), torm.OutcomeApplied, torm.OutcomeFailed, torm.OutcomeNotRun) {
+		t.Errorf("Unexpected outcomes: %v", outcomes(result))
+	}
+	if result.StoppedAt != "002" || !result.Recorded || !errors.Is(result.Migrations[1].Err, boom) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	// The next run skips what is applied and completes
+	failing = false
+	result, err = manager.MigrateDetailed()
+	if err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if !sameOutcomes(outcomes(result), torm.OutcomeSkipped, torm.OutcomeApplied, torm.OutcomeApplied) {
+		t.Errorf("Unexpected outcomes: %v", outcomes(result))
+	}
+	if result.StoppedAt != "" || !result.Recorded {
+		t.Errorf("Expected a complete, recorded run: %+v", result)
+	}
+}
+
+func TestMigrateDetailedRecordWriteFailure(t *testing.T) {
+	ms := newMockServer(t)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/keys/torm:migrations") {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "disk full"})
+			return true
+		}
+		return false
+	})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	ran := 0
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: func(*torm.Client) error {
+		ran++
+		return nil
+	}, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "second", Up: noopMigration, Down: noopMigration})
+
+	result, err := manager.MigrateDetailed()
+	if err == nil {
+		t.Fatal("Expected the record write failure to be reported")
+	}
+	if ran != 1 || !sameOutcomes(outcomes(result), torm.OutcomeApplied, torm.OutcomeNotRun) {
+		t.Errorf("Unexpected outcomes: %v", outcomes(result))
+	}
+	if result.Recorded || result.Migrations[0].Recorded || result.StoppedAt != "001" {
+		t.Errorf("Expected an unrecorded stop at 001: %+v", result)
+	}
+
+	names, err := manager.Migrate()
+	if err == nil || len(names) != 1 || names[0] != "first" {
+		t.Errorf("Expected Migrate to delegate, got %v %v", names, err)
+	}
+}
+
+func TestRollbackDetailed(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	boom := errors.New("boom")
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "second", Up: noopMigration, Down: func(*torm.Client) error { return boom }})
+	manager.AddMigration(torm.Migration{ID: "003", Name: "third", Up: noopMigration, Down: noopMigration})
+
+	if _, err := manager.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	result, err := manager.RollbackDetailed(3)
+	if !errors.Is(err, boom) {
+		t.Fatalf("Expected Down failure, got %v", err)
+	}
+	if !sameOutcomes(outcomes(result), torm.OutcomeRolledBack, torm.OutcomeFailed, torm.OutcomeNotRun) {
+		t.Errorf("Unexpected outcomes: %v", outcomes(result))
+	}
+	if result.Migrations[0].ID != "003" || result.StoppedAt != "002" || !result.Recorded {
+		t.Errorf("Expected newest-first rollback stopping at 002: %+v", result)
+	}
+
+	statuses, err := manager.StatusDetailed()
+	if err != nil {
+		t.Fatalf("StatusDetailed failed: %v", err)
+	}
+	if statuses[1].State != torm.MigrationRollingBack || statuses[2].State != torm.MigrationPending {
+		t.Errorf("Expected 002 rolling back and 003 pending, got %+v", statuses)
+	}
+}
@@ -0,0 +1,169 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func newTenantDocs(client *torm.Client, opts ...torm.CollectionOption) *torm.Collection[*TenantDoc] {
+	return torm.NewCollection(client, "docs", func() *TenantDoc { return &TenantDoc{} }, opts...)
+}
+
+func TestMirrorReplaysInOrder(t *testing.T) {
+	primary, secondary := newMockServer(t), newMockServer(t)
+	secondary.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		time.Sleep(time.Millisecond) // A slow mirror must not reorder writes
+		return false
+	})
+	target := torm.NewClient(&torm.ClientOptions{BaseURL: secondary.URL})
+	docs := newTenantDocs(torm.NewClient(&torm.ClientOptions{BaseURL: primary.URL}), torm.WithMirror(target, torm.MirrorOptions{}))
+
+	for _, id := range []string{"doc:1", "doc:2", "doc:3"} {
+		if _, err := docs.Create(&TenantDoc{ID: id, Tenant: "a", Title: "v1"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	for _, title := range []string{"v2", "v3"} {
+		if err := docs.Save(&TenantDoc{ID: "doc:1", Tenant: "a", Title: title}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	if err := docs.Delete("doc:2"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := docs.Upsert(&TenantDoc{ID: "doc:2", Tenant: "a", Title: "back"}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := docs.FlushMirror(ctx); err != nil {
+		t.Fatalf("FlushMirror failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(primary.store("docs"), secondary.store("docs")) {
+		t.Errorf("Mirror diverged:\nprimary %v\nmirror  %v", primary.store("docs"), secondary.store("docs"))
+	}
+
+	// Each document's writes arrive in the order they were made
+	var doc1 []string
+	for _, req := range secondary.requestLog() {
+		if strings.HasSuffix(req.Path, "doc:1") || (req.Method == http.MethodPost && req.Body["data"].(map[string]interface{})["id"] == "doc:1") {
+			doc1 = append(doc1, req.Method+" "+req.Body["data"].(map[string]interface{})["title"].(string))
+		}
+	}
+	if want := []string{"PUT v1", "POST v1", "PUT v2", "PUT v3"}; !reflect.DeepEqual(doc1, want) {
+		t.Errorf("Expected %v, got %v", want, doc1)
+	}
+
+	stats := docs.MirrorStats()
+	if stats.Replayed != 7 || stats.Pending != 0 || stats.Failed != 0 || stats.Dropped != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Reads never touch the mirror
+	before := len(secondary.requestLog())
+	docs.FindByID("doc:1")
+	docs.Find()
+	if len(secondary.requestLog()) != before {
+		t.Error("Expected reads to stay on the primary")
+	}
+}
+
+func TestMirrorQueueOverflow(t *testing.T) {
+	primary, secondary := newMockServer(t), newMockServer(t)
+	arrived, release := make(chan struct{}, 10), make(chan struct{})
+	secondary.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		arrived <- struct{}{}
+		<-release
+		return false
+	})
+
+	var mu sync.Mutex
+	var dropped []string
+	target := torm.NewClient(&torm.ClientOptions{BaseURL: secondary.URL})
+	docs := newTenantDocs(torm.NewClient(&torm.ClientOptions{BaseURL: primary.URL}), torm.WithMirror(target, torm.MirrorOptions{
+		QueueSize: 2,
+		OnError: func(write torm.MirrorWrite, err error) {
+			if errors.Is(err, torm.ErrMirrorQueueFull) {
+				mu.Lock()
+				dropped = append(dropped, write.ID)
+				mu.Unlock()
+			}
+		},
+	}))
+
+	// The first write is in flight, two more fill the queue, and the rest
+	// are dropped without slowing the primary
+	docs.Create(&TenantDoc{ID: "doc:1", Tenant: "a"})
+	<-arrived
+	for _, id := range []string{"doc:2", "doc:3", "doc:4", "doc:5"} {
+		if _, err := docs.Create(&TenantDoc{ID: id, Tenant: "a"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if stats := docs.MirrorStats(); stats.Dropped != 2 || stats.Pending != 3 {
+		t.Errorf("Unexpected stats while blocked: %+v", stats)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := docs.FlushMirror(ctx); err != nil {
+		t.Fatalf("FlushMirror failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(dropped, []string{"doc:4", "doc:5"}) {
+		t.Errorf("Unexpected dropped writes %v", dropped)
+	}
+	if stats := docs.MirrorStats(); stats.Replayed != 3 || stats.Dropped != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestMirrorStrict(t *testing.T) {
+	primary, secondary := newMockServer(t), newMockServer(t)
+	failing := true
+	secondary.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if failing {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "down"})
+			return true
+		}
+		return false
+	})
+
+	mirrorDocs := newTenantDocs(torm.NewClient(&torm.ClientOptions{BaseURL: secondary.URL}))
+	docs := newTenantDocs(torm.NewClient(&torm.ClientOptions{BaseURL: primary.URL}), torm.WithMirror(mirrorDocs, torm.MirrorOptions{Strict: true}))
+
+	_, err := docs.Create(&TenantDoc{ID: "doc:1", Tenant: "a"})
+	if err == nil || torm.ErrorCategory(err) != torm.CategoryServer {
+		t.Fatalf("Expected the mirror failure to fail the call, got %v", err)
+	}
+	if _, stored := primary.doc("docs", "doc:1"); !stored {
+		t.Error("Expected the primary write to stand")
+	}
+
+	failing = false
+	if err := docs.Delete("doc:1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := docs.Create(&TenantDoc{ID: "doc:2", Tenant: "a"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, stored := secondary.doc("docs", "doc:2"); !stored {
+		t.Error("Expected the write to be mirrored before Create returned")
+	}
+	if stats := docs.MirrorStats(); stats.Failed != 1 || stats.Replayed != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
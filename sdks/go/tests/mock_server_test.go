@@ -0,0 +1,532 @@
+package torm_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mockRequest records a request received by the mock server
+type mockRequest struct {
+	Method  string
+	Path    string
+	RawPath string // Path as sent, before unescaping
+	Query   string
+	Header  http.Header
+	Body    map[string]interface{}
+}
+
+// mockServer is an in-memory stand-in for the ToonStore HTTP API
+type mockServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	collections map[string]map[string]map[string]interface{}
+	keys        map[string]string
+	requests    []mockRequest
+	nextID      int
+
+	intercept func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool
+
+	prefix  string // Stripped before routing, see mount
+	version string
+
+	keyListing bool // Serve GET /api/keys?prefix=, see enableKeyListing
+	keysBatch  bool // Serve POST /api/keys/_batch, see enableKeysBatch
+	docETags   bool // Tag documents and honor If-None-Match, see enableDocumentETags
+	shuffle    bool // Return documents in random order, see enableShuffle
+}
+
+func newMockServer(t *testing.T) *mockServer {
+	t.Helper()
+
+	ms := startMockServer()
+	t.Cleanup(ms.Close)
+	return ms
+}
+
+// startMockServer starts a mock server the caller closes, for use outside
+// a test such as in TestMain
+func startMockServer() *mockServer {
+	ms := &mockServer{
+		collections: make(map[string]map[string]map[string]interface{}),
+		keys:        make(map[string]string),
+	}
+	ms.Server = httptest.NewServer(http.HandlerFunc(ms.serve))
+	return ms
+}
+
+// setIntercept installs a handler that may fully handle a request by returning true
+func (ms *mockServer) setIntercept(fn func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.intercept = fn
+}
+
+// mount serves the API under a path prefix and /api/{version}, as behind a gateway
+func (ms *mockServer) mount(prefix, version string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.prefix = prefix
+	ms.version = version
+}
+
+// enableShuffle returns listings and query results in a different order on
+// every request, as servers without a stable order do. Requested sorts are
+// still applied, leaving ties in random order.
+func (ms *mockServer) enableShuffle() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.shuffle = true
+}
+
+// enableKeyListing serves the paginated key listing endpoint
+func (ms *mockServer) enableKeyListing() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.keyListing = true
+}
+
+// enableKeysBatch serves the atomic multi-key endpoint
+func (ms *mockServer) enableKeysBatch() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.keysBatch = true
+}
+
+// enableDocumentETags sends an ETag with every document read, answers 304
+// when If-None-Match carries the current one and fails updates whose
+// If-Match does not
+func (ms *mockServer) enableDocumentETags() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.docETags = true
+}
+
+// seed stores documents directly, bypassing the HTTP layer
+func (ms *mockServer) seed(collection string, docs ...map[string]interface{}) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, doc := range docs {
+		ms.store(collection)[fmt.Sprintf("%v", doc["id"])] = doc
+	}
+}
+
+// doc returns the stored form of a document
+func (ms *mockServer) doc(collection, id string) (map[string]interface{}, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	doc, ok := ms.collections[collection][id]
+	return doc, ok
+}
+
+// requestLog returns a copy of the requests received so far
+func (ms *mockServer) requestLog() []mockRequest {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return append([]mockRequest(nil), ms.requests...)
+}
+
+// countRequests counts received requests with the given method and path
+func (ms *mockServer) countRequests(method, path string) int {
+	n := 0
+	for _, req := range ms.requestLog() {
+		if req.Method == method && req.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+func (ms *mockServer) store(collection string) map[string]map[string]interface{} {
+	docs, ok := ms.collections[collection]
+	if !ok {
+		docs = make(map[string]map[string]interface{})
+		ms.collections[collection] = docs
+	}
+	return docs
+}
+
+func (ms *mockServer) serve(w http.ResponseWriter, r *http.Request) {
+	// Multipart bodies are left unread for intercepts that consume streams
+	var body map[string]interface{}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if raw, _ := io.ReadAll(r.Body); len(raw) > 0 {
+			json.Unmarshal(raw, &body)
+		}
+	}
+
+	ms.mu.Lock()
+	ms.requests = append(ms.requests, mockRequest{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		RawPath: r.URL.EscapedPath(),
+		Query:   r.URL.RawQuery,
+		Header:  r.Header.Clone(),
+		Body:    body,
+	})
+	intercept := ms.intercept
+	ms.mu.Unlock()
+
+	if intercept != nil && intercept(w, r, body) {
+		return
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, ms.prefix)
+	if ms.version != "" {
+		path = strings.Replace(path, "/api/"+ms.version, "/api", 1)
+	}
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+
+	switch {
+	case path == "":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"name": "TORM Server", "status": "running"})
+	case path == "health":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok", "database": "connected"})
+	case path == "api/keys" && ms.keyListing && r.Method == http.MethodGet:
+		ms.listKeys(w, r)
+	case path == "api/keys/_batch" && ms.keysBatch && r.Method == http.MethodPost:
+		ms.batchKeys(w, body)
+	case len(parts) >= 2 && parts[0] == "api" && parts[1] == "keys":
+		ms.serveKey(w, r, strings.TrimPrefix(path, "api/keys/"), body)
+	case len(parts) == 2 && parts[0] == "api":
+		ms.serveCollection(w, r, parts[1], body)
+	case len(parts) == 3 && parts[0] == "api" && !strings.Contains(parts[2], "/"):
+		ms.serveDocument(w, r, parts[1], parts[2], body)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Not found"})
+	}
+}
+
+func (ms *mockServer) serveKey(w http.ResponseWriter, r *http.Request, key string, body map[string]interface{}) {
+	switch r.Method {
+	case http.MethodGet:
+		value, ok := ms.keys[key]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Key not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"key": key, "value": value})
+	case http.MethodPut:
+		current, exists := ms.keys[key]
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || match != mockETag(current)) {
+			writeJSON(w, http.StatusPreconditionFailed, map[string]interface{}{"error": "Precondition failed"})
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			writeJSON(w, http.StatusPreconditionFailed, map[string]interface{}{"error": "Precondition failed"})
+			return
+		}
+		ms.keys[key] = fmt.Sprintf("%v", body["value"])
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "key": key})
+	case http.MethodDelete:
+		delete(ms.keys, key)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"error": "Method not allowed"})
+	}
+}
+
+// batchKeys applies every step or, when a compare-and-set step fails, none
+func (ms *mockServer) batchKeys(w http.ResponseWriter, body map[string]interface{}) {
+	steps, _ := body["steps"].([]interface{})
+	for i, raw := range steps {
+		step, _ := raw.(map[string]interface{})
+		if step["op"] != "compare_and_set" {
+			continue
+		}
+		current, exists := ms.keys[fmt.Sprintf("%v", step["key"])]
+		old, hasOld := step["old"].(string)
+		if exists != hasOld || (exists && current != old) {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "Precondition failed", "step": i})
+			return
+		}
+	}
+	for _, raw := range steps {
+		step, _ := raw.(map[string]interface{})
+		key := fmt.Sprintf("%v", step["key"])
+		if step["op"] == "delete" {
+			delete(ms.keys, key)
+		} else {
+			ms.keys[key] = fmt.Sprintf("%v", step["value"])
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// listKeys pages through keys in order, the cursor being the last key returned
+func (ms *mockServer) listKeys(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, cursor := query.Get("prefix"), query.Get("cursor")
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 1000
+	}
+
+	keys := make([]string, 0)
+	for key := range ms.keys {
+		if strings.HasPrefix(key, prefix) && key > cursor {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	next := ""
+	if len(keys) > limit {
+		keys = keys[:limit]
+		next = keys[limit-1]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys, "next_cursor": next})
+}
+
+// mockETag matches the entity tag the client sends for conditional key writes
+func mockETag(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (ms *mockServer) serveCollection(w http.ResponseWriter, r *http.Request, collection string, body map[string]interface{}) {
+	switch r.Method {
+	case http.MethodGet:
+		docs := ms.sorted(collection)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"collection": collection,
+			"count":      len(docs),
+			"documents":  docs,
+		})
+	case http.MethodPost:
+		data, _ := body["data"].(map[string]interface{})
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		id, _ := data["id"].(string)
+		if id == "" {
+			ms.nextID++
+			id = fmt.Sprintf("%s:%d", collection, ms.nextID)
+		}
+		ms.store(collection)[id] = data
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"success": true, "id": id, "data": data})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"error": "Method not allowed"})
+	}
+}
+
+func (ms *mockServer) serveDocument(w http.ResponseWriter, r *http.Request, collection, id string, body map[string]interface{}) {
+	switch {
+	case id == "count" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"collection": collection, "count": len(ms.collections[collection])})
+		return
+	case id == "query" && r.Method == http.MethodPost:
+		ms.serveQuery(w, collection, body)
+		return
+	}
+
+	doc, exists := ms.collections[collection][id]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Document not found"})
+			return
+		}
+		if ms.docETags {
+			encoded, _ := json.Marshal(doc)
+			etag := mockETag(string(encoded))
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+		}
+		writeJSON(w, http.StatusOK, doc)
+	case http.MethodPut:
+		if !exists {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "Document not found"})
+			return
+		}
+		if match := r.Header.Get("If-Match"); ms.docETags && match != "" {
+			encoded, _ := json.Marshal(doc)
+			if match != mockETag(string(encoded)) {
+				writeJSON(w, http.StatusPreconditionFailed, map[string]interface{}{"success": false, "error": "Document changed"})
+				return
+			}
+		}
+		data, _ := body["data"].(map[string]interface{})
+		ms.store(collection)[id] = data
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "id": id, "data": data})
+	case http.MethodDelete:
+		if !exists {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "Document not found"})
+			return
+		}
+		delete(ms.collections[collection], id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "deleted": true})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]interface{}{"error": "Method not allowed"})
+	}
+}
+
+func (ms *mockServer) serveQuery(w http.ResponseWriter, collection string, body map[string]interface{}) {
+	docs := make([]map[string]interface{}, 0)
+	for _, doc := range ms.sorted(collection) {
+		if mockMatches(doc, body["filters"]) {
+			docs = append(docs, doc)
+		}
+	}
+
+	if s, ok := body["sort"].(map[string]interface{}); ok {
+		field, _ := s["field"].(string)
+		desc := s["order"] == "desc"
+		sort.SliceStable(docs, func(i, j int) bool {
+			less := mockCompare(docs[i][field], docs[j][field]) < 0
+			if desc {
+				return mockCompare(docs[i][field], docs[j][field]) > 0
+			}
+			return less
+		})
+	}
+
+	if skip, ok := body["skip"].(float64); ok {
+		if int(skip) >= len(docs) {
+			docs = docs[:0]
+		} else {
+			docs = docs[int(skip):]
+		}
+	}
+	if limit, ok := body["limit"].(float64); ok && int(limit) < len(docs) {
+		docs = docs[:int(limit)]
+	}
+
+	if fields, ok := body["fields"].([]interface{}); ok {
+		for i, doc := range docs {
+			projected := make(map[string]interface{})
+			for _, field := range fields {
+				if value, ok := doc[field.(string)]; ok {
+					projected[field.(string)] = value
+				}
+			}
+			docs[i] = projected
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"collection": collection,
+		"count":      len(docs),
+		"documents":  docs,
+	})
+}
+
+// sorted returns the documents of a collection ordered by id, unless
+// shuffling is enabled
+func (ms *mockServer) sorted(collection string) []map[string]interface{} {
+	ids := make([]string, 0, len(ms.collections[collection]))
+	for id := range ms.collections[collection] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	docs := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		docs = append(docs, ms.collections[collection][id])
+	}
+	if ms.shuffle {
+		rand.Shuffle(len(docs), func(i, j int) { docs[i], docs[j] = docs[j], docs[i] })
+	}
+	return docs
+}
+
+// mockMatches applies query filters in either list or {field: value} form
+func mockMatches(doc map[string]interface{}, filters interface{}) bool {
+	switch f := filters.(type) {
+	case []interface{}:
+		for _, raw := range f {
+			filter, _ := raw.(map[string]interface{})
+			field, _ := filter["field"].(string)
+			op, _ := filter["operator"].(string)
+			if !mockMatch(doc[field], op, filter["value"]) {
+				return false
+			}
+		}
+	case map[string]interface{}:
+		for field, value := range f {
+			if !mockMatch(doc[field], "eq", value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func mockMatch(docValue interface{}, op string, value interface{}) bool {
+	switch op {
+	case "eq":
+		return fmt.Sprintf("%v", docValue) == fmt.Sprintf("%v", value)
+	case "ne":
+		return fmt.Sprintf("%v", docValue) != fmt.Sprintf("%v", value)
+	case "gt":
+		return mockCompare(docValue, value) > 0
+	case "gte":
+		return mockCompare(docValue, value) >= 0
+	case "lt":
+		return mockCompare(docValue, value) < 0
+	case "lte":
+		return mockCompare(docValue, value) <= 0
+	case "contains":
+		return strings.Contains(fmt.Sprintf("%v", docValue), fmt.Sprintf("%v", value))
+	case "in", "not_in":
+		found := false
+		if items, ok := value.([]interface{}); ok {
+			for _, item := range items {
+				if fmt.Sprintf("%v", docValue) == fmt.Sprintf("%v", item) {
+					found = true
+				}
+			}
+		}
+		return found == (op == "in")
+	}
+	return false
+}
+
+func mockCompare(a, b interface{}) int {
+	af, aok := a.(float64)
+	bf, bok := b.(float64)
+	if aok && bok {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
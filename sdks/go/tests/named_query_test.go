@@ -0,0 +1,89 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// lastQueryBody returns the JSON payload of the most recent query request
+func lastQueryBody(t *testing.T, ms *mockServer) string {
+	t.Helper()
+
+	log := ms.requestLog()
+	for i := len(log) - 1; i >= 0; i-- {
+		if strings.HasSuffix(log[i].Path, "/query") {
+			body, _ := json.Marshal(log[i].Body)
+			return string(body)
+		}
+	}
+	t.Fatalf("No query request recorded")
+	return ""
+}
+
+func TestNamedQueries(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "active": true, "age": 30, "country": "NL"},
+		map[string]interface{}{"id": "user:2", "active": true, "age": 16, "country": "NL"},
+		map[string]interface{}{"id": "user:3", "active": false, "age": 40, "country": "NL"},
+		map[string]interface{}{"id": "user:4", "active": true, "age": 50, "country": "BE"},
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	err := users.DefineQuery("activeAdults", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		return qb.Where("active", true).Filter("age", torm.Gte, 18)
+	})
+	if err != nil {
+		t.Fatalf("DefineQuery failed: %v", err)
+	}
+	err = users.DefineQuery("oldestFirst", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		return qb.Sort("age", torm.Desc).Limit(2)
+	})
+	if err != nil {
+		t.Fatalf("DefineQuery failed: %v", err)
+	}
+
+	qb, err := users.NamedQuery("activeAdults")
+	if err != nil {
+		t.Fatalf("NamedQuery failed: %v", err)
+	}
+	docs, err := qb.Where("country", "NL").Exec()
+	if err != nil || len(docs) != 1 || docs[0]["id"] != "user:1" {
+		t.Fatalf("Unexpected composed result: %v, %v", docs, err)
+	}
+	want := `{"filters":[{"field":"active","operator":"in","value":[true,"true",1]},` +
+		`{"field":"age","operator":"gte","value":18},{"field":"country","operator":"eq","value":"NL"}],"sort":{"field":"id","order":"asc"}}`
+	if got := lastQueryBody(t, ms); got != want {
+		t.Errorf("Unexpected composed payload:\n got %s\nwant %s", got, want)
+	}
+
+	// Each call starts from a fresh builder
+	qb, _ = users.NamedQuery("activeAdults")
+	if docs, _ := qb.Exec(); len(docs) != 2 {
+		t.Errorf("Expected the extra filter not to leak into the preset, got %v", docs)
+	}
+
+	qb, _ = users.NamedQuery("oldestFirst")
+	if _, err := qb.Filter("country", torm.Ne, "BE").Exec(); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	want = `{"filters":[{"field":"country","operator":"ne","value":"BE"}],"limit":2,"sort":{"field":"age","order":"desc"},"then_by":[{"field":"id","order":"asc"}]}`
+	if got := lastQueryBody(t, ms); got != want {
+		t.Errorf("Unexpected composed payload:\n got %s\nwant %s", got, want)
+	}
+
+	if err := users.DefineQuery("activeAdults", func(qb *torm.QueryBuilder) *torm.QueryBuilder { return qb }); !errors.Is(err, torm.ErrQueryExists) {
+		t.Errorf("Expected ErrQueryExists, got %v", err)
+	}
+	if _, err := users.NamedQuery("missing"); !errors.Is(err, torm.ErrUnknownQuery) {
+		t.Errorf("Expected ErrUnknownQuery, got %v", err)
+	}
+	if names := users.NamedQueries(); strings.Join(names, ",") != "activeAdults,oldestFirst" {
+		t.Errorf("Unexpected query names: %v", names)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build torm_negative
+
+// Package negative holds field reference misuses that must not compile.
+// TestFieldRefsRejectMisuse builds it with the torm_negative tag and checks
+// that each line marked "want error" is reported.
+package negative
+
+import "github.com/toonstore/torm-go"
+
+type account struct {
+	Email   string `json:"email"`
+	Active  bool   `json:"active"`
+	Balance int    `json:"balance"`
+}
+
+var (
+	email   = torm.StringField(func(a *account) *string { return &a.Email })
+	active  = torm.Field(func(a *account) *bool { return &a.Active })
+	balance = torm.OrderedField(func(a *account) *int { return &a.Balance })
+)
+
+var (
+	_ = email.Eq(42)                                                   // want error: wrong value type
+	_ = balance.In("ten")                                              // want error: wrong value type
+	_ = active.Gt(true)                                                // want error: bool is not ordered
+	_ = balance.Contains("1")                                          // want error: Contains is for text
+	_ = torm.OrderedField(func(a *account) *bool { return &a.Active }) // want error: bool is not ordered
+	_ = torm.StringField(func(a *account) *int { return &a.Balance })  // want error: not a text field
+)
@@ -0,0 +1,153 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestOpErrorNamesTheOperation(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	_, err := users.FindByID("user:42")
+	var opErr *torm.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "FindByID" || opErr.Collection != "users" || opErr.ID != "user:42" {
+		t.Fatalf("Expected an OpError naming users.FindByID, got %#v", err)
+	}
+	if !errors.Is(err, torm.ErrNotFound) || torm.ErrorCategory(err) != torm.CategoryNotFound {
+		t.Errorf("Expected the OpError to unwrap to ErrNotFound, got %v", err)
+	}
+	if want := "torm: users.FindByID id=user:42: document not found"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	failWith(ms, "/users", http.StatusInternalServerError, "database unavailable")
+	_, err = client.Model("users", nil).Create(map[string]interface{}{"id": "user:1"})
+	if !errors.As(err, &opErr) || opErr.Op != "Create" || opErr.Collection != "users" || opErr.ID != "" {
+		t.Errorf("Expected an OpError naming users.Create, got %#v", err)
+	}
+	var apiErr *torm.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected the APIError beneath the OpError, got %v", err)
+	}
+	if want := "torm: users.Create: create failed with status 500: database unavailable"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestOpErrorFromQuery(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	failWith(ms, "/users", http.StatusBadRequest, "bad filter")
+	query := client.Model("users", nil).Query().Where("name", "Bob")
+	_, err := query.Exec()
+	var opErr *torm.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "Query" || opErr.Collection != "users" {
+		t.Fatalf("Expected an OpError naming users.Query, got %#v", err)
+	}
+	if opErr.Query != query.String() {
+		t.Errorf("Expected the query %q, got %q", query.String(), opErr.Query)
+	}
+	if !strings.HasPrefix(err.Error(), "torm: users.Query: ") {
+		t.Errorf("Expected the operation first in %q", err.Error())
+	}
+
+	_, err = query.Count()
+	if !errors.As(err, &opErr) || opErr.Op != "Count" {
+		t.Errorf("Expected an OpError naming users.Count, got %v", err)
+	}
+}
+
+func TestOpErrorFromKeysAndMigrations(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	boom := errors.New("boom")
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "first", Up: noopMigration, Down: noopMigration})
+	manager.AddMigration(torm.Migration{ID: "002", Name: "second", Up: func(*torm.Client) error { return boom }, Down: noopMigration})
+	_, err := manager.MigrateDetailed()
+	var opErr *torm.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "Migrate" || opErr.Collection != "" || opErr.ID != "002" || !errors.Is(err, boom) {
+		t.Errorf("Expected an OpError naming the failed migration, got %#v", err)
+	}
+	if want := "torm: Migrate id=002: boom"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	failWith(ms, "/keys", http.StatusServiceUnavailable, "read only")
+	err = client.SetKey("feature:dark-mode", "on")
+	if !errors.As(err, &opErr) || opErr.Op != "SetKey" || opErr.ID != "feature:dark-mode" {
+		t.Errorf("Expected an OpError naming the key, got %#v", err)
+	}
+	if want := "torm: SetKey id=feature:dark-mode: set key failed with status 503: read only"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestOpErrorNamesCollectionMethods(t *testing.T) {
+	ms := newMockServer(t)
+	failWith(ms, "/users", http.StatusInternalServerError, "boom")
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	ctx := context.Background()
+
+	tests := []struct {
+		op   string
+		id   string
+		call func() error
+	}{
+		{"GetAttachmentURL", "user:1", func() error { _, _, err := users.GetAttachmentURL("user:1", "avatar"); return err }},
+		{"UploadAttachment", "user:1", func() error { return users.UploadAttachment("user:1", "avatar", strings.NewReader("png"), "image/png") }},
+		{"DeleteAttachment", "user:1", func() error { return users.DeleteAttachment("user:1", "avatar") }},
+		{"GetField", "user:1", func() error { _, _, err := users.GetField("user:1", "settings.theme"); return err }},
+		{"SetField", "user:1", func() error { return users.SetField("user:1", "settings.theme", "dark") }},
+		{"Lookup", "", func() error { _, err := users.Lookup("name", nil); return err }},
+		{"DeleteWhere", "", func() error { _, err := users.DeleteWhere(ctx, nil, nil); return err }},
+		{"UpdateWhere", "", func() error {
+			_, err := users.UpdateWhere(ctx, nil, func(u *TestUser) (*TestUser, error) { return u, nil }, nil)
+			return err
+		}},
+		{"Claim", "", func() error { _, err := users.Claim(ctx, 1, nil, torm.ClaimOptions{Owner: "worker:1"}); return err }},
+	}
+	for _, tt := range tests {
+		err := tt.call()
+		var opErr *torm.OpError
+		if !errors.As(err, &opErr) || opErr.Op != tt.op || opErr.Collection != "users" || opErr.ID != tt.id {
+			t.Errorf("Expected an OpError naming users.%s, got %#v", tt.op, err)
+			continue
+		}
+		prefix := "torm: users." + tt.op
+		if tt.id != "" {
+			prefix += " id=" + tt.id
+		}
+		if !strings.HasPrefix(err.Error(), prefix+": ") || strings.Contains(err.Error(), "users.Query") {
+			t.Errorf("Expected only %s named in %q", tt.op, err.Error())
+		}
+		if torm.ErrorCategory(err) != torm.CategoryServer {
+			t.Errorf("Expected the server error beneath %s, got %v", tt.op, err)
+		}
+	}
+}
+
+func TestOpErrorLegacyErrorStrings(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, LegacyErrorStrings: true})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	_, err := users.FindByID("user:42")
+	var opErr *torm.OpError
+	if errors.As(err, &opErr) && opErr.Op != "" {
+		t.Errorf("Expected no operation with LegacyErrorStrings, got %#v", opErr)
+	}
+	if want := "torm: document not found: users/user:42"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
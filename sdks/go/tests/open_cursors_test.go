@@ -0,0 +1,183 @@
+package torm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// lockedBuffer is a log destination safe to read while the watchdog writes
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// watchedClient has a one-minute idle timeout checked every few milliseconds
+func watchedClient(ms *mockServer, clock *fakeClock, logs *lockedBuffer) *torm.Client {
+	return torm.NewClient(&torm.ClientOptions{
+		BaseURL:             ms.URL,
+		Clock:               clock.Now,
+		Logger:              slog.New(slog.NewJSONHandler(logs, nil)),
+		Debug:               true,
+		CursorIdleTimeout:   time.Minute,
+		CursorCheckInterval: 5 * time.Millisecond,
+	})
+}
+
+// waitForNoCursors waits for the watchdog to close every open cursor
+func waitForNoCursors(t *testing.T, client *torm.Client) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(client.OpenCursors()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the watchdog to close the cursors, still open: %+v", client.OpenCursors())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func abandonIterator(client *torm.Client) *torm.QueryIterator {
+	it := client.Model("items", nil).Query().Where("name", "Item 1").Iter(context.Background())
+	it.Next()
+	return it
+}
+
+func TestAbandonedIteratorIsClosedAfterIdleTimeout(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 3)
+	clock := newFakeClock()
+	logs := &lockedBuffer{}
+	client := watchedClient(ms, clock, logs)
+
+	it := abandonIterator(client)
+	open := client.OpenCursors()
+	if len(open) != 1 || open[0].Kind != torm.CursorIterator || open[0].Collection != "items" {
+		t.Fatalf("expected one open iterator, got %+v", open)
+	}
+	if !strings.Contains(open[0].Stack, "abandonIterator") {
+		t.Errorf("expected the creation stack with Debug, got %q", open[0].Stack)
+	}
+
+	clock.Advance(30 * time.Second)
+	time.Sleep(30 * time.Millisecond)
+	if len(client.OpenCursors()) != 1 {
+		t.Fatal("expected the iterator to stay open within the timeout")
+	}
+
+	clock.Advance(time.Minute)
+	waitForNoCursors(t, client)
+
+	if it.Next() {
+		t.Error("expected a closed iterator to end")
+	}
+	if !errors.Is(it.Err(), torm.ErrCursorIdle) {
+		t.Errorf("expected ErrCursorIdle, got %v", it.Err())
+	}
+	output := logs.String()
+	if !strings.Contains(output, "cursor leaked") || !strings.Contains(output, "abandonIterator") ||
+		!strings.Contains(output, `"query":"items: name=\"Item 1\""`) {
+		t.Errorf("expected a leak warning naming the query and stack, got %s", output)
+	}
+
+	if err := it.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := it.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
+
+func TestIteratorClosesItselfAtTheEnd(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 7)
+	client := watchedClient(ms, newFakeClock(), &lockedBuffer{})
+
+	it := client.Model("items", nil).Query().Limit(5).Iter(context.Background())
+	n := 0
+	for it.Next() {
+		n++
+		if n == 1 && it.Doc()["id"] != "item:001" {
+			t.Errorf("expected id order, got %v", it.Doc())
+		}
+	}
+	if it.Err() != nil || n != 5 {
+		t.Fatalf("expected 5 documents, got %d (%v)", n, it.Err())
+	}
+	if open := client.OpenCursors(); len(open) != 0 {
+		t.Errorf("expected no open cursors, got %+v", open)
+	}
+	it.Close()
+
+	closed := client.Model("items", nil).Query().Iter(context.Background())
+	closed.Close()
+	closed.Close()
+	if closed.Next() || len(client.OpenCursors()) != 0 {
+		t.Error("expected Close to end and unregister the iterator")
+	}
+}
+
+func TestStalledChannelConsumerIsStopped(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 10)
+	clock := newFakeClock()
+	logs := &lockedBuffer{}
+	client := watchedClient(ms, clock, logs)
+
+	docs, errs := client.Model("items", nil).Query().ExecChan(context.Background())
+	if doc := <-docs; doc["id"] != "item:001" {
+		t.Fatalf("expected the first item, got %v", doc)
+	}
+	if open := client.OpenCursors(); len(open) != 1 || open[0].Kind != torm.CursorChannel {
+		t.Fatalf("expected one open channel, got %+v", open)
+	}
+
+	// The consumer stops reading
+	clock.Advance(2 * time.Minute)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, torm.ErrCursorIdle) {
+			t.Fatalf("expected ErrCursorIdle, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the producer to stop")
+	}
+	waitForNoCursors(t, client)
+	if !strings.Contains(logs.String(), `"kind":"channel"`) {
+		t.Errorf("expected a leak warning for the channel, got %s", logs.String())
+	}
+}
+
+func TestExecChanDeliversEveryDocument(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 4)
+	client := watchedClient(ms, newFakeClock(), &lockedBuffer{})
+
+	docs, errs := client.Model("items", nil).Query().ExecChan(context.Background())
+	n := 0
+	for range docs {
+		n++
+	}
+	if err := <-errs; err != nil || n != 4 {
+		t.Fatalf("expected 4 documents, got %d (%v)", n, err)
+	}
+	waitForNoCursors(t, client)
+}
@@ -0,0 +1,176 @@
+package torm_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedGroups stores n documents spread over three groups, so sorting by
+// group leaves ties
+func seedGroups(ms *mockServer, n int) {
+	for i := 0; i < n; i++ {
+		ms.seed("items", map[string]interface{}{
+			"id":    fmt.Sprintf("item:%02d", i),
+			"group": []string{"a", "b", "c"}[i%3],
+			"name":  fmt.Sprintf("Item %d", n-i),
+		})
+	}
+}
+
+func docIDs(docs []map[string]interface{}) string {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = fmt.Sprintf("%v", doc["id"])
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestDefaultOrderIsStableAcrossRuns(t *testing.T) {
+	ms := newMockServer(t)
+	seedGroups(ms, 20)
+	ms.enableShuffle()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	runs := map[string]func() ([]map[string]interface{}, error){
+		"unsorted query": func() ([]map[string]interface{}, error) { return items.Query().Exec() },
+		"sorted query": func() ([]map[string]interface{}, error) {
+			return items.Query().Sort("group", torm.Desc).Exec()
+		},
+		"find": items.Find,
+	}
+	for name, run := range runs {
+		first, err := run()
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		for i := 0; i < 5; i++ {
+			again, err := run()
+			if err != nil {
+				t.Fatalf("%s failed: %v", name, err)
+			}
+			if docIDs(again) != docIDs(first) {
+				t.Fatalf("Expected %s to return the same order, got\n%s\n%s", name, docIDs(first), docIDs(again))
+			}
+		}
+		if name == "sorted query" {
+			if first[0]["group"] != "c" || first[0]["id"] != "item:02" || first[1]["id"] != "item:05" {
+				t.Errorf("Expected ties broken by id, got %s", docIDs(first))
+			}
+			continue
+		}
+		if first[0]["id"] != "item:00" || first[19]["id"] != "item:19" {
+			t.Errorf("Expected %s in id order, got %s", name, docIDs(first))
+		}
+	}
+}
+
+func TestDefaultOrderOnCollections(t *testing.T) {
+	ms := newMockServer(t)
+	seedGroups(ms, 12)
+	ms.enableShuffle()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := torm.NewCollection(client, "items", func() *TestProduct { return &TestProduct{} })
+
+	first, err := items.FindFiltered(map[string]interface{}{"group": "a"})
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if !strings.Contains(lastQueryBody(t, ms), `"sort":{"field":"id","order":"asc"}`) {
+		t.Errorf("Expected the default order in the payload, got %s", lastQueryBody(t, ms))
+	}
+	for i := 0; i < 5; i++ {
+		again, err := items.Find()
+		if err != nil {
+			t.Fatalf("Find failed: %v", err)
+		}
+		if again[0].ID != "item:00" || again[11].ID != "item:11" {
+			t.Fatalf("Expected listings in id order, got %v", again)
+		}
+	}
+	if len(first) != 4 || first[0].ID != "item:00" || first[3].ID != "item:09" {
+		t.Errorf("Unexpected filtered order: %v", first)
+	}
+
+	byName := torm.NewCollection(client, "items", func() *TestProduct { return &TestProduct{} },
+		torm.WithDefaultOrder("name", torm.Desc))
+	docs, err := byName.Find()
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if docs[0].Name != "Item 9" || docs[len(docs)-1].Name != "Item 1" {
+		t.Errorf("Expected the collection's own order, got %v", docs)
+	}
+}
+
+func TestServerOrderDisablesDefault(t *testing.T) {
+	ms := newMockServer(t)
+	seedGroups(ms, 20)
+	ms.enableShuffle()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, ServerOrder: true})
+	items := client.Model("items", nil)
+
+	first, err := items.Query().Exec()
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got := lastQueryBody(t, ms); got != `{}` {
+		t.Errorf("Expected no injected sort, got %s", got)
+	}
+
+	changed := false
+	for i := 0; i < 10 && !changed; i++ {
+		again, err := items.Query().Exec()
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		changed = docIDs(again) != docIDs(first)
+	}
+	if !changed {
+		t.Error("Expected raw server order to vary between runs")
+	}
+
+	if plan := items.Query().Sort("group", torm.Asc).Explain(); plan.DefaultOrder != nil || len(plan.Sort) != 1 {
+		t.Errorf("Expected no tie-breaker, got %+v", plan)
+	}
+}
+
+func TestExplainShowsDefaultOrder(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:      "http://localhost:1",
+		DefaultOrder: &torm.QuerySort{Field: "created_at", Order: torm.Asc},
+	})
+	items := client.Model("items", nil)
+	createdAt := torm.QuerySort{Field: "created_at", Order: torm.Asc}
+
+	plan := items.Query().Explain()
+	if plan.DefaultOrder == nil || *plan.DefaultOrder != createdAt {
+		t.Errorf("Expected the injected key, got %+v", plan.DefaultOrder)
+	}
+	if !reflect.DeepEqual(plan.Sort, []torm.QuerySort{createdAt}) || plan.Payload["sort"] == nil {
+		t.Errorf("Expected an unsorted query to sort by the default order, got %+v", plan)
+	}
+
+	plan = items.Query().Sort("group", torm.Desc).Explain()
+	want := []torm.QuerySort{{Field: "group", Order: torm.Desc}, createdAt}
+	if !reflect.DeepEqual(plan.Sort, want) || plan.DefaultOrder == nil {
+		t.Errorf("Expected the default order as tie-breaker, got %+v", plan)
+	}
+	if thenBy, _ := plan.Payload["then_by"].([]torm.QuerySort); !reflect.DeepEqual(thenBy, []torm.QuerySort{createdAt}) {
+		t.Errorf("Expected the tie-breaker in the payload, got %v", plan.Payload)
+	}
+
+	// Sorting by the default field needs no tie-breaker
+	plan = items.Query().Sort("created_at", torm.Desc).Explain()
+	if plan.DefaultOrder != nil || len(plan.Sort) != 1 || plan.Payload["then_by"] != nil {
+		t.Errorf("Expected no tie-breaker, got %+v", plan)
+	}
+
+	if plan := items.WithServerOrder().Query().Explain(); plan.DefaultOrder != nil || len(plan.Sort) != 0 {
+		t.Errorf("Expected WithServerOrder to drop the default order, got %+v", plan)
+	}
+}
@@ -0,0 +1,21 @@
+// Package attribute stands in for go.opentelemetry.io/otel/attribute, which
+// cannot be vendored into this module. It has only what the README's
+// OpenTelemetry adapter uses, so ExampleTracer compiles that adapter as
+// written.
+package attribute
+
+// KeyValue is a span attribute
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// String makes a string attribute
+func String(key, value string) KeyValue {
+	return KeyValue{Key: key, Value: value}
+}
+
+// Int makes an int attribute
+func Int(key string, value int) KeyValue {
+	return KeyValue{Key: key, Value: value}
+}
@@ -0,0 +1,13 @@
+// Package codes stands in for go.opentelemetry.io/otel/codes, see package
+// attribute
+package codes
+
+// Code is the status of a span
+type Code int
+
+// Span statuses
+const (
+	Unset Code = iota
+	Error
+	Ok
+)
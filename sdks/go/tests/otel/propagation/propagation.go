@@ -0,0 +1,25 @@
+// Package propagation stands in for go.opentelemetry.io/otel/propagation,
+// see package attribute
+package propagation
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/toonstore/torm-go/tests/otel/trace"
+)
+
+// HeaderCarrier carries a span context in HTTP headers
+type HeaderCarrier http.Header
+
+// TraceContext propagates span contexts as W3C traceparent headers
+type TraceContext struct{}
+
+// Inject writes the span context of ctx into carrier
+func (TraceContext) Inject(ctx context.Context, carrier HeaderCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	http.Header(carrier).Set("traceparent", "00-"+sc.TraceID+"-"+sc.SpanID+"-01")
+}
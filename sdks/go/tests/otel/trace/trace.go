@@ -0,0 +1,129 @@
+// Package trace stands in for go.opentelemetry.io/otel/trace, see package
+// attribute. Its provider prints each span as it ends.
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/toonstore/torm-go/tests/otel/attribute"
+	"github.com/toonstore/torm-go/tests/otel/codes"
+)
+
+// SpanKind is the role of a span in a trace
+type SpanKind int
+
+// Span kinds
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindClient
+)
+
+// SpanStartOption configures a span when it starts
+type SpanStartOption func(*span)
+
+// WithSpanKind sets the kind of a span
+func WithSpanKind(kind SpanKind) SpanStartOption {
+	return func(s *span) { s.kind = kind }
+}
+
+// SpanContext identifies a span within its trace
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// IsValid reports whether sc identifies a span
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// Tracer starts spans
+type Tracer interface {
+	Start(ctx context.Context, name string, opts ...SpanStartOption) (context.Context, Span)
+}
+
+// Span is a started span
+type Span interface {
+	SetAttributes(kv ...attribute.KeyValue)
+	RecordError(err error)
+	SetStatus(code codes.Code, description string)
+	End()
+	SpanContext() SpanContext
+}
+
+type spanKey struct{}
+
+// SpanContextFromContext returns the span context of the span in ctx
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s, ok := ctx.Value(spanKey{}).(Span); ok {
+		return s.SpanContext()
+	}
+	return SpanContext{}
+}
+
+// Provider hands out tracers that print their spans to w
+type Provider struct {
+	w     io.Writer
+	spans int
+}
+
+// NewProvider creates a provider printing to w
+func NewProvider(w io.Writer) *Provider {
+	return &Provider{w: w}
+}
+
+// Tracer returns a tracer for the named instrumentation
+func (p *Provider) Tracer(name string) Tracer {
+	return tracer{p}
+}
+
+type tracer struct{ provider *Provider }
+
+func (t tracer) Start(ctx context.Context, name string, opts ...SpanStartOption) (context.Context, Span) {
+	t.provider.spans++
+	s := &span{provider: t.provider, name: name, context: SpanContext{
+		TraceID: fmt.Sprintf("%032x", 1),
+		SpanID:  fmt.Sprintf("%016x", t.provider.spans),
+	}}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		s.context.TraceID = parent.TraceID
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return context.WithValue(ctx, spanKey{}, Span(s)), s
+}
+
+type span struct {
+	provider *Provider
+	name     string
+	kind     SpanKind
+	context  SpanContext
+	attrs    []attribute.KeyValue
+	status   codes.Code
+	errors   []error
+}
+
+func (s *span) SetAttributes(kv ...attribute.KeyValue) { s.attrs = append(s.attrs, kv...) }
+func (s *span) RecordError(err error)                  { s.errors = append(s.errors, err) }
+func (s *span) SetStatus(code codes.Code, _ string)    { s.status = code }
+func (s *span) SpanContext() SpanContext               { return s.context }
+
+func (s *span) End() {
+	fields := make([]string, 0, len(s.attrs))
+	for _, kv := range s.attrs {
+		fields = append(fields, fmt.Sprintf("%s=%v", kv.Key, kv.Value))
+	}
+	kind := "internal"
+	if s.kind == SpanKindClient {
+		kind = "client"
+	}
+	fmt.Fprintf(s.provider.w, "%s (%s) %s", s.name, kind, strings.Join(fields, " "))
+	if s.status == codes.Error {
+		fmt.Fprintf(s.provider.w, " error=%q", s.errors[len(s.errors)-1])
+	}
+	fmt.Fprintln(s.provider.w)
+}
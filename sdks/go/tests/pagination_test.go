@@ -0,0 +1,98 @@
+package torm_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// servePages answers GET /api/users in three pages, linking them with a page
+// number, then a path
+func servePages(ms *mockServer, lastLink interface{}) {
+	page := func(from int, next interface{}) map[string]interface{} {
+		docs := []interface{}{
+			map[string]interface{}{"id": fmt.Sprintf("user:%d", from), "name": "User", "age": from},
+			map[string]interface{}{"id": fmt.Sprintf("user:%d", from+1), "name": "User", "age": from + 1},
+		}
+		return map[string]interface{}{"documents": docs, "count": 6, "next_page": next}
+	}
+
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/users" {
+			return false
+		}
+		switch r.URL.Query().Get("page") {
+		case "":
+			writeJSON(w, http.StatusOK, page(1, 2))
+		case "2":
+			writeJSON(w, http.StatusOK, page(3, "/api/users?page=c3"))
+		case "c3":
+			writeJSON(w, http.StatusOK, page(5, lastLink))
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "bad page"})
+		}
+		return true
+	})
+}
+
+func TestFindFollowsPages(t *testing.T) {
+	ms := newMockServer(t)
+	servePages(ms, nil)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	found, err := users.Find()
+	if err != nil || len(found) != 6 {
+		t.Fatalf("Expected all 6 users, got %d: %v", len(found), err)
+	}
+	for i, user := range found {
+		if user.Age != i+1 {
+			t.Errorf("Expected pages in order, got %+v at %d", user, i)
+		}
+	}
+	if n := ms.countRequests("GET", "/api/users"); n != 3 {
+		t.Errorf("Expected 3 page requests, got %d", n)
+	}
+
+	docs, err := client.Model("users", nil).Find()
+	if err != nil || len(docs) != 6 || docs[5]["id"] != "user:6" {
+		t.Fatalf("Expected Model.Find to read all pages, got %d: %v", len(docs), err)
+	}
+}
+
+func TestFindPageSafeguards(t *testing.T) {
+	ms := newMockServer(t)
+	servePages(ms, nil)
+
+	// Stop after two pages
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Pagination: torm.PaginationOptions{MaxPages: 2}})
+	docs, err := client.Model("users", nil).Find()
+	if !errors.Is(err, torm.ErrTruncatedResult) || len(docs) != 4 {
+		t.Errorf("Expected 4 documents and ErrTruncatedResult, got %d: %v", len(docs), err)
+	}
+
+	// Following disabled for one collection
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithPagination(torm.PaginationOptions{Disabled: true}))
+	found, err := users.Find()
+	if !errors.Is(err, torm.ErrTruncatedResult) || len(found) != 2 {
+		t.Errorf("Expected the first page and ErrTruncatedResult, got %d: %v", len(found), err)
+	}
+	if n := ms.countRequests("GET", "/api/users"); n != 3 {
+		t.Errorf("Expected 2 + 1 page requests, got %d", n)
+	}
+}
+
+func TestFindRefusesForeignPageLinks(t *testing.T) {
+	ms := newMockServer(t)
+	servePages(ms, "https://elsewhere.example/api/users?page=4")
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	docs, err := client.Model("users", nil).Find()
+	if !errors.Is(err, torm.ErrTruncatedResult) || len(docs) != 6 {
+		t.Errorf("Expected 6 documents and ErrTruncatedResult, got %d: %v", len(docs), err)
+	}
+}
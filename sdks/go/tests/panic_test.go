@@ -0,0 +1,126 @@
+package torm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestPanickingGuard(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "first"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	explode := true
+	docs := torm.NewCollection(client, "docs", func() *TenantDoc { return &TenantDoc{} },
+		torm.WithGuard(func(op torm.Operation, doc map[string]interface{}) error {
+			if explode && doc["title"] == "boom" {
+				panic("guard exploded")
+			}
+			return nil
+		}))
+
+	_, err := docs.Create(&TenantDoc{ID: "doc:2", Tenant: "a", Title: "boom"})
+	var panicked *torm.PanicError
+	if !errors.As(err, &panicked) {
+		t.Fatalf("Expected a PanicError, got %v", err)
+	}
+	if panicked.Op != "guard" || panicked.Collection != "docs" || panicked.ID != "doc:2" || panicked.Value != "guard exploded" {
+		t.Errorf("Unexpected diagnostics: %+v", panicked)
+	}
+	if !strings.Contains(string(panicked.Stack), "TestPanickingGuard") {
+		t.Errorf("Expected the stack to include the guard, got:\n%s", panicked.Stack)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryPanic || torm.IsRetryable(err) {
+		t.Errorf("Unexpected classification %q", torm.ErrorCategory(err))
+	}
+	if _, stored := ms.doc("docs", "doc:2"); stored {
+		t.Error("Expected the write to be aborted")
+	}
+
+	// The client keeps working
+	explode = false
+	if _, err := docs.Create(&TenantDoc{ID: "doc:2", Tenant: "a", Title: "boom"}); err != nil {
+		t.Fatalf("Create after the panic failed: %v", err)
+	}
+	found, err := docs.Find()
+	if err != nil || len(found) != 2 {
+		t.Errorf("Expected both documents, got %d, %v", len(found), err)
+	}
+
+	// Reads that drop rejected documents fail instead of hiding the panic
+	explode = true
+	if _, err := docs.Find(); !errors.As(err, &panicked) {
+		t.Errorf("Expected Find to report the panic, got %v", err)
+	}
+}
+
+func TestPanickingCallbacks(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("items", map[string]interface{}{"id": "i1", "name": "one"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	boom := errors.New("boom")
+	validated := client.Model("items", map[string]torm.ValidationRule{
+		"name": {Validate: func(interface{}) bool { panic(boom) }},
+	})
+	items := client.Model("items", nil)
+	repaired := client.Model("items", nil).WithReadRepair(torm.ReadRepair{
+		Upgrade: func(map[string]interface{}) bool { panic("bad upgrade") },
+	})
+	manager := torm.NewMigrationManager(client)
+	manager.AddMigration(torm.Migration{ID: "001", Name: "explodes", Up: func(*torm.Client) error { panic("bad migration") }, Down: noopMigration})
+
+	tests := []struct {
+		name       string
+		run        func() error
+		op, id     string
+		collection string
+	}{
+		{"validator", func() error {
+			_, err := validated.Create(map[string]interface{}{"id": "i2", "name": "two"})
+			return err
+		}, "validator of name", "i2", "items"},
+		{"compiled validator", func() error {
+			_, err := validated.CompileSchema().Create(map[string]interface{}{"id": "i3", "name": "three"})
+			return err
+		}, "validator of name", "i3", "items"},
+		{"read repair", func() error {
+			_, err := repaired.FindByID("i1")
+			return err
+		}, "read repair", "i1", "items"},
+		{"for each", func() error {
+			return items.ForEach(nil, func(map[string]interface{}) error { panic("bad visit") })
+		}, "for each", "i1", "items"},
+		{"migration", func() error {
+			_, err := manager.Migrate()
+			return err
+		}, "migration up", "001", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			var panicked *torm.PanicError
+			if !errors.As(err, &panicked) {
+				t.Fatalf("Expected a PanicError, got %v", err)
+			}
+			if panicked.Op != tt.op || panicked.ID != tt.id || panicked.Collection != tt.collection || len(panicked.Stack) == 0 {
+				t.Errorf("Unexpected diagnostics: %+v", panicked)
+			}
+		})
+	}
+
+	// Error panic values stay reachable
+	_, err := validated.Create(map[string]interface{}{"id": "i4", "name": "four"})
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected the panic value to unwrap, got %v", err)
+	}
+
+	// The failed migration is recorded like any other failure
+	statuses, _ := manager.StatusDetailed()
+	if len(statuses) != 1 || statuses[0].State != torm.MigrationFailed {
+		t.Errorf("Expected a failed migration, got %+v", statuses)
+	}
+}
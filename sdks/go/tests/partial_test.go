@@ -0,0 +1,83 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+type legacyAccount struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Balance int    `json:"balance"`
+}
+
+func TestExecPartial(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("accounts",
+		map[string]interface{}{"id": "a1", "name": "Ada", "balance": 10},
+		map[string]interface{}{"id": "a2", "name": "Bob", "balance": "ten"}, // Legacy row with text balance
+		map[string]interface{}{"id": "a3", "name": "Cy", "balance": 30},
+		map[string]interface{}{"id": "a4", "name": 4, "balance": 40}, // Legacy row with numeric name
+		map[string]interface{}{"id": "a5", "name": "Eve"},
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	// Read repair runs before the split, so it can rescue documents
+	accounts := client.Model("accounts", nil).WithReadRepair(torm.ReadRepair{
+		Upgrade: func(doc map[string]interface{}) bool {
+			if doc["balance"] == "ten" {
+				doc["balance"] = 10
+				return true
+			}
+			return false
+		},
+	})
+	result, err := torm.ExecPartial[legacyAccount](accounts.Query().Sort("id", torm.Asc))
+	if err != nil {
+		t.Fatalf("ExecPartial failed: %v", err)
+	}
+
+	if result.Len() != 5 || len(result.Typed) != 4 || len(result.Untyped) != 1 || len(result.Errors) != 1 {
+		t.Fatalf("Unexpected split: %+v", result)
+	}
+	wantIndex := []int{0, 1, 2, 4}
+	for i, account := range result.Typed {
+		if result.TypedIndex[i] != wantIndex[i] {
+			t.Errorf("Typed %d: expected index %d, got %d", i, wantIndex[i], result.TypedIndex[i])
+		}
+		if i == 1 && (account.ID != "a2" || account.Balance != 10) {
+			t.Errorf("Expected the repaired account, got %+v", account)
+		}
+	}
+
+	failure := result.Errors[0]
+	if failure.Index != 3 || failure.ID != "a4" || result.Untyped[0]["id"] != "a4" {
+		t.Errorf("Unexpected failure: %+v, %v", failure, result.Untyped[0])
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(&failure, &typeErr) || typeErr.Field != "name" {
+		t.Errorf("Expected the decode error to be kept, got %v", failure.Err)
+	}
+	if torm.ErrorCategory(&failure) != torm.CategoryContract {
+		t.Errorf("Unexpected category %q", torm.ErrorCategory(&failure))
+	}
+
+	// Without repair, the legacy balance lands with the stragglers in order
+	result, err = torm.ExecPartial[legacyAccount](client.Model("accounts", nil).Query().Sort("id", torm.Asc))
+	if err != nil {
+		t.Fatalf("ExecPartial failed: %v", err)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Index != 1 || result.Errors[1].Index != 3 {
+		t.Errorf("Unexpected failures: %+v", result.Errors)
+	}
+}
+
+func TestExecPartialQueryFailure(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: "http://127.0.0.1:1"})
+	if _, err := torm.ExecPartial[legacyAccount](client.Model("accounts", nil).Query()); err == nil {
+		t.Error("Expected a failed query to return an error")
+	}
+}
@@ -0,0 +1,165 @@
+package torm_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestPartitionedExport(t *testing.T) {
+	ms := newMockServer(t)
+	seedItems(ms, 37)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	items := client.Model("items", nil)
+
+	partitions, err := items.Partitions(4)
+	if err != nil || len(partitions) != 4 {
+		t.Fatalf("Expected 4 partitions, got %v: %v", partitions, err)
+	}
+	if partitions[0].From != nil || partitions[3].To != nil {
+		t.Errorf("Expected the outer partitions to be unbounded: %v", partitions)
+	}
+
+	var full bytes.Buffer
+	if _, err := items.ExportSnapshot(&full, torm.ExportOptions{PageSize: 4}); err != nil {
+		t.Fatalf("Full export failed: %v", err)
+	}
+
+	outputs := make([]bytes.Buffer, len(partitions))
+	var wg sync.WaitGroup
+	errs := make(chan error, len(partitions))
+	for i := range partitions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := items.ExportSnapshot(&outputs[i], torm.ExportOptions{PageSize: 4, Partition: &partitions[i]})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Partition export failed: %v", err)
+		}
+	}
+
+	seen := make(map[string]int)
+	var union []string
+	for i, out := range outputs {
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) < 9 || len(lines) > 10 {
+			t.Errorf("Expected partition %d to hold about a quarter of the documents, got %d", i, len(lines))
+		}
+		for _, line := range lines {
+			seen[line]++
+			union = append(union, line)
+		}
+	}
+	for line, n := range seen {
+		if n > 1 {
+			t.Errorf("Document exported %d times: %s", n, line)
+		}
+	}
+	if got := strings.Join(union, "\n") + "\n"; got != full.String() {
+		t.Errorf("Partitions differ from the full export:\n%s\nvs\n%s", got, full.String())
+	}
+
+	// The boundary document belongs to the partition it opens
+	boundary := partitions[1].From
+	var owners []int
+	for _, p := range partitions {
+		err := items.ForEach(&p, func(doc map[string]interface{}) error {
+			if doc["id"] == boundary {
+				owners = append(owners, p.Index)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ForEach failed: %v", err)
+		}
+	}
+	if len(owners) != 1 || owners[0] != 1 {
+		t.Errorf("Expected boundary %v only in partition 1, got %v", boundary, owners)
+	}
+}
+
+func TestPartitionsByField(t *testing.T) {
+	ms := newMockServer(t)
+	for i, price := range []float64{1, 1, 1, 1, 1, 1, 2, 3} {
+		ms.seed("items", map[string]interface{}{"id": "item:" + string(rune('a'+i)), "price": price})
+	}
+	items := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}).Model("items", nil)
+
+	// Shared values collapse boundaries instead of producing empty ranges
+	partitions, err := items.PartitionsBy("price", 4)
+	if err != nil {
+		t.Fatalf("PartitionsBy failed: %v", err)
+	}
+	if len(partitions) != 2 || partitions[1].From != float64(2) {
+		t.Fatalf("Expected a split at price 2, got %v", partitions)
+	}
+
+	total := 0
+	for _, p := range partitions {
+		docs, err := items.Query().Match(p.Filters()...).Exec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(docs)
+	}
+	if total != 8 {
+		t.Errorf("Expected every document in one partition, got %d in total", total)
+	}
+
+	if _, err := items.Partitions(0); err == nil {
+		t.Error("Expected an error for zero partitions")
+	}
+}
+
+func TestPartitionedImport(t *testing.T) {
+	source := newMockServer(t)
+	seedItems(source, 12)
+	items := torm.NewClient(&torm.ClientOptions{BaseURL: source.URL}).Model("items", nil)
+
+	var snapshot bytes.Buffer
+	if _, err := items.ExportSnapshot(&snapshot, torm.ExportOptions{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	partitions, err := items.Partitions(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := newMockServer(t)
+	target.seed("items", map[string]interface{}{"id": "item:005", "name": "stale"})
+	conflictOnExisting(target, true)
+	restored := torm.NewClient(&torm.ClientOptions{BaseURL: target.URL}).Model("items", nil)
+
+	imported := 0
+	for i := range partitions {
+		result, err := restored.ImportSnapshot(bytes.NewReader(snapshot.Bytes()), torm.ImportOptions{Partition: &partitions[i]})
+		if err != nil {
+			t.Fatalf("Import of partition %d failed: %v", i, err)
+		}
+		if result.Lines != 12 || result.Imported+result.Skipped != 12 {
+			t.Errorf("Unexpected import result: %+v", result)
+		}
+		imported += result.Imported
+	}
+	if imported != 12 {
+		t.Errorf("Expected each document imported once, got %d imports", imported)
+	}
+	if doc, _ := target.doc("items", "item:005"); doc["name"] != "Item 5" {
+		t.Errorf("Expected the existing document overwritten, got %v", doc)
+	}
+
+	// Resuming skips the lines already read
+	result, err := restored.ImportSnapshot(bytes.NewReader(snapshot.Bytes()), torm.ImportOptions{Skip: 10})
+	if err != nil || result.Imported != 2 {
+		t.Errorf("Expected only the last 2 lines imported, got %+v: %v", result, err)
+	}
+}
@@ -0,0 +1,143 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// readThenChange reads user:1 as a patch base, then lets another writer
+// change fields of the stored document
+func readThenChange(t *testing.T, ms *mockServer, users *torm.Collection[*TestUser], changes map[string]interface{}) *TestUser {
+	t.Helper()
+	base, err := users.FindByID("user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := ms.doc("users", "user:1")
+	for field, value := range changes {
+		stored[field] = value
+	}
+	ms.seed("users", stored)
+	return base
+}
+
+func seedPatchUser(ms *mockServer) {
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice", "email": "alice@example.com", "age": float64(30)})
+}
+
+func TestPatchWithoutAutoMergeConflicts(t *testing.T) {
+	ms := newMockServer(t)
+	seedPatchUser(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	base, err := users.FindByID("user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := users.Patch(context.Background(), "user:1", base, map[string]interface{}{"email": "alice@new.example"})
+	if err != nil {
+		t.Fatalf("Expected an uncontested patch to succeed, got %v", err)
+	}
+	if patched.Email != "alice@new.example" || patched.Name != "Alice" {
+		t.Errorf("Expected the patched document, got %+v", patched)
+	}
+
+	base = readThenChange(t, ms, users, map[string]interface{}{"name": "Alicia"})
+	_, err = users.Patch(context.Background(), "user:1", base, map[string]interface{}{"age": 31})
+	var conflict *torm.PatchConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, torm.ErrVersionConflict) {
+		t.Fatalf("Expected a PatchConflictError, got %v", err)
+	}
+	if !reflect.DeepEqual(conflict.Changed, []string{"name"}) || len(conflict.Collided) != 0 {
+		t.Errorf("Expected name changed and nothing collided, got %+v", conflict)
+	}
+	if stored, _ := ms.doc("users", "user:1"); stored["age"] != float64(30) {
+		t.Errorf("Expected the conflicting patch not to be written, got %v", stored)
+	}
+}
+
+func TestPatchAutoMergesDisjointFields(t *testing.T) {
+	ms := newMockServer(t)
+	seedPatchUser(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, torm.WithAutoMergePatches(3))
+
+	base := readThenChange(t, ms, users, map[string]interface{}{"name": "Alicia"})
+	patched, err := users.Patch(context.Background(), "user:1", base, map[string]interface{}{"email": "alice@new.example"})
+	if err != nil {
+		t.Fatalf("Expected a disjoint patch to merge, got %v", err)
+	}
+	if patched.Name != "Alicia" || patched.Email != "alice@new.example" {
+		t.Errorf("Expected both changes in the result, got %+v", patched)
+	}
+	stored, _ := ms.doc("users", "user:1")
+	if stored["name"] != "Alicia" || stored["email"] != "alice@new.example" || stored["age"] != float64(30) {
+		t.Errorf("Expected both changes to be stored, got %v", stored)
+	}
+}
+
+func TestPatchAutoMergeSurfacesOverlap(t *testing.T) {
+	ms := newMockServer(t)
+	seedPatchUser(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, torm.WithAutoMergePatches(3))
+
+	base := readThenChange(t, ms, users, map[string]interface{}{"email": "alice@other.example", "age": float64(31)})
+	_, err := users.Patch(context.Background(), "user:1", base, map[string]interface{}{"email": "alice@new.example", "name": "Al"})
+	var conflict *torm.PatchConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a PatchConflictError, got %v", err)
+	}
+	if !reflect.DeepEqual(conflict.Collided, []string{"email"}) || !reflect.DeepEqual(conflict.Changed, []string{"age", "email"}) {
+		t.Errorf("Expected email to collide among age and email, got %+v", conflict)
+	}
+	if want := "torm: users.Patch id=user:1: patch of user:1 in collection users conflicted on email"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if torm.ErrorCategory(err) != torm.CategoryConflict {
+		t.Errorf("Expected a conflict error, got %s", torm.ErrorCategory(err))
+	}
+	if stored, _ := ms.doc("users", "user:1"); stored["email"] != "alice@other.example" || stored["name"] != "Alice" {
+		t.Errorf("Expected the other writer's email to stand, got %v", stored)
+	}
+}
+
+func TestPatchAutoMergeRetriesLostRace(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableDocumentETags()
+	seedPatchUser(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, torm.WithAutoMergePatches(3))
+
+	base := readThenChange(t, ms, users, map[string]interface{}{"name": "Alicia"})
+	puts := 0
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method == http.MethodPut {
+			puts++
+			if puts == 1 {
+				// Another writer changes age between the read and the write
+				stored, _ := ms.doc("users", "user:1")
+				stored["age"] = float64(31)
+				ms.seed("users", stored)
+			}
+		}
+		return false
+	})
+
+	if _, err := users.Patch(context.Background(), "user:1", base, map[string]interface{}{"email": "alice@new.example"}); err != nil {
+		t.Fatalf("Expected the patch to be merged again after the lost race, got %v", err)
+	}
+	if puts != 2 {
+		t.Errorf("Expected a second write after the 412, got %d writes", puts)
+	}
+	stored, _ := ms.doc("users", "user:1")
+	if stored["name"] != "Alicia" || stored["age"] != float64(31) || stored["email"] != "alice@new.example" {
+		t.Errorf("Expected all three changes to be stored, got %v", stored)
+	}
+}
@@ -0,0 +1,94 @@
+package torm_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestPathPrefixAndAPIVersion(t *testing.T) {
+	ms := newMockServer(t)
+	ms.mount("/toonstore", "v2")
+
+	// Stray slashes are normalized
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:    ms.URL + "/",
+		PathPrefix: "toonstore/",
+		APIVersion: "/v2/",
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	model := client.Model("users", nil)
+
+	steps := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"health", func() error { _, err := client.Health(); return err }, "GET /toonstore/health"},
+		{"info", func() error { _, err := client.Info(); return err }, "GET /toonstore/"},
+		{"create", func() error { _, err := users.Create(&TestUser{ID: "user:1", Name: "Alice"}); return err }, "POST /toonstore/api/v2/users"},
+		{"find by id", func() error { _, err := users.FindByID("user:1"); return err }, "GET /toonstore/api/v2/users/user:1"},
+		{"find", func() error { _, err := users.Find(); return err }, "GET /toonstore/api/v2/users"},
+		{"query", func() error { _, err := users.FindFiltered(map[string]interface{}{"name": "Alice"}); return err }, "POST /toonstore/api/v2/users/query"},
+		{"count", func() error { _, err := users.Count(); return err }, "GET /toonstore/api/v2/users/count"},
+		{"save", func() error { return users.Save(&TestUser{ID: "user:1", Name: "Alicia"}) }, "PUT /toonstore/api/v2/users/user:1"},
+		{"model query", func() error { _, err := model.Query().Where("name", "Alicia").Exec(); return err }, "POST /toonstore/api/v2/users/query"},
+		{"model count", func() error { _, err := model.Count(); return err }, "GET /toonstore/api/v2/users/count"},
+		{"delete", func() error { return users.Delete("user:1") }, "DELETE /toonstore/api/v2/users/user:1"},
+		{"set key", func() error { return client.SetKey("a/b c", "1") }, "PUT /toonstore/api/v2/keys/a%2Fb%20c"},
+		{"get key", func() error { _, _, err := client.GetKey("a/b c"); return err }, "GET /toonstore/api/v2/keys/a%2Fb%20c"},
+		{"delete key", func() error { return client.DeleteKey("a/b c") }, "DELETE /toonstore/api/v2/keys/a%2Fb%20c"},
+	}
+
+	for _, step := range steps {
+		before := len(ms.requestLog())
+		if err := step.run(); err != nil {
+			t.Errorf("%s failed: %v", step.name, err)
+			continue
+		}
+
+		log := ms.requestLog()[before:]
+		if len(log) == 0 {
+			t.Errorf("%s sent no request", step.name)
+			continue
+		}
+		last := log[len(log)-1]
+		if got := last.Method + " " + last.RawPath; got != step.want {
+			t.Errorf("%s: expected %s, got %s", step.name, step.want, got)
+		}
+	}
+
+	if value, ok, err := client.GetKey("a/b c"); err != nil || ok {
+		t.Errorf("Expected escaped key to round-trip to a delete, got %q %v %v", value, ok, err)
+	}
+}
+
+func TestHealthAtRoot(t *testing.T) {
+	ms := newMockServer(t)
+	ms.mount("/toonstore", "")
+
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:      ms.URL,
+		PathPrefix:   "/toonstore",
+		HealthAtRoot: true,
+	})
+
+	if _, err := client.Health(); err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if _, err := client.Info(); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if err := client.SetKey("k", "v"); err != nil {
+		t.Fatalf("SetKey failed: %v", err)
+	}
+
+	paths := make([]string, 0)
+	for _, req := range ms.requestLog() {
+		paths = append(paths, req.Path)
+	}
+	if got := strings.Join(paths, " "); got != "/health / /toonstore/api/keys/k" {
+		t.Errorf("Unexpected paths: %s", got)
+	}
+}
@@ -0,0 +1,139 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// requireExplicitCollections makes the mock behave like a server that only
+// serves collections created through POST /api/_collections
+func requireExplicitCollections(ms *mockServer, endpoint bool) {
+	var mu sync.Mutex
+	created := make(map[string]bool)
+
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/api/_collections" {
+			if !endpoint {
+				writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Not found"})
+				return true
+			}
+			name, _ := body["name"].(string)
+			if created[name] {
+				writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "Collection exists"})
+				return true
+			}
+			created[name] = true
+			writeJSON(w, http.StatusCreated, map[string]interface{}{"success": true, "name": name})
+			return true
+		}
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) >= 2 && parts[0] == "api" && parts[1] != "keys" && !created[parts[1]] {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "Collection not found"})
+			return true
+		}
+		return false
+	})
+}
+
+func TestEnsureCollection(t *testing.T) {
+	ms := newMockServer(t)
+	requireExplicitCollections(ms, true)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	created, err := client.EnsureCollection("orders", nil)
+	if err != nil || !created {
+		t.Fatalf("Expected collection to be created, got %v %v", created, err)
+	}
+
+	created, err = client.EnsureCollection("orders", nil)
+	if err != nil || created {
+		t.Errorf("Expected second ensure to be a no-op, got %v %v", created, err)
+	}
+}
+
+func TestAutoProvisionRetriesOnce(t *testing.T) {
+	ms := newMockServer(t)
+	requireExplicitCollections(ms, true)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithAutoProvision(nil))
+
+	created, err := users.Create(&TestUser{ID: "user:1", Name: "Alice"})
+	if err != nil {
+		t.Fatalf("Expected Create to provision and succeed, got %v", err)
+	}
+	if created.Name != "Alice" {
+		t.Errorf("Unexpected created document: %+v", created)
+	}
+
+	if got := ms.countRequests("POST", "/api/users"); got != 2 {
+		t.Errorf("Expected original request plus one retry, got %d", got)
+	}
+	if got := ms.countRequests("POST", "/api/_collections"); got != 1 {
+		t.Errorf("Expected a single provisioning call, got %d", got)
+	}
+
+	// A plain missing document must not trigger provisioning
+	if _, err := users.FindByID("user:404"); err == nil {
+		t.Error("Expected missing document to fail")
+	}
+	if got := ms.countRequests("POST", "/api/_collections"); got != 1 {
+		t.Errorf("Expected no further provisioning, got %d calls", got)
+	}
+}
+
+func TestAutoProvisionWithoutEndpoint(t *testing.T) {
+	ms := newMockServer(t)
+	requireExplicitCollections(ms, false)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	if _, err := client.EnsureCollection("orders", nil); !errors.Is(err, torm.ErrNotSupported) {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithAutoProvision(nil))
+	if _, err := users.Create(&TestUser{ID: "user:1", Name: "Alice"}); err == nil {
+		t.Error("Expected Create to report the original failure")
+	}
+	if got := ms.countRequests("POST", "/api/users"); got != 1 {
+		t.Errorf("Expected no retry without provisioning, got %d requests", got)
+	}
+}
+
+func TestAutoProvisionCustomMatcher(t *testing.T) {
+	ms := newMockServer(t)
+	created := false
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.URL.Path == "/api/_collections" {
+			created = true
+			writeJSON(w, http.StatusCreated, map[string]interface{}{"success": true})
+			return true
+		}
+		if !created {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"code": "NO_SUCH_COLLECTION"})
+			return true
+		}
+		return false
+	})
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithAutoProvision(func(status int, body []byte) bool {
+			return status == http.StatusUnprocessableEntity && strings.Contains(string(body), "NO_SUCH_COLLECTION")
+		}))
+
+	count, err := users.Count()
+	if err != nil || count != 0 {
+		t.Errorf("Expected Count to succeed after provisioning, got %d %v", count, err)
+	}
+}
@@ -0,0 +1,102 @@
+package torm_test
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedFlags(ms *mockServer) {
+	ms.seed("flags",
+		map[string]interface{}{"id": "bool-true", "active": true},
+		map[string]interface{}{"id": "bool-false", "active": false},
+		map[string]interface{}{"id": "str-true", "active": "true"},
+		map[string]interface{}{"id": "str-false", "active": "false"},
+		map[string]interface{}{"id": "num-1", "active": 1.0},
+		map[string]interface{}{"id": "num-0", "active": 0.0},
+		map[string]interface{}{"id": "str-yes", "active": "yes"},
+		map[string]interface{}{"id": "missing"},
+	)
+}
+
+func TestBooleanFilterSemantics(t *testing.T) {
+	ms := newMockServer(t)
+	seedFlags(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	flags := client.Model("flags", nil)
+
+	cases := []struct {
+		operator torm.QueryOperator
+		value    interface{}
+		expected string
+	}{
+		{torm.Eq, true, "bool-true,num-1,str-true"},
+		{torm.Eq, false, "bool-false,num-0,str-false"},
+		{torm.Ne, true, "bool-false,missing,num-0,str-false,str-yes"},
+		{torm.Ne, false, "bool-true,missing,num-1,str-true,str-yes"},
+		// Only a boolean filter value coerces; others compare by string form
+		{torm.Eq, "true", "bool-true,str-true"},
+		{torm.Eq, "false", "bool-false,str-false"},
+		{torm.Eq, 1, "num-1"},
+		{torm.Eq, 0, "num-0"},
+		{torm.Eq, "yes", "str-yes"},
+		{torm.In, []interface{}{true}, "bool-true,num-1,str-true"},
+		{torm.In, []interface{}{false, "yes"}, "bool-false,num-0,str-false,str-yes"},
+		{torm.NotIn, []interface{}{true, false}, "missing,str-yes"},
+	}
+
+	for _, tc := range cases {
+		docs, err := flags.Query().Filter("active", tc.operator, tc.value).Exec()
+		if err != nil {
+			t.Errorf("%s %v: query failed: %v", tc.operator, tc.value, err)
+			continue
+		}
+
+		ids := make([]string, 0, len(docs))
+		for _, doc := range docs {
+			ids = append(ids, doc["id"].(string))
+		}
+		sort.Strings(ids)
+
+		if got := strings.Join(ids, ","); got != tc.expected {
+			t.Errorf("%s %v (%T): expected %s, got %s", tc.operator, tc.value, tc.value, tc.expected, got)
+		}
+	}
+}
+
+func TestBooleanOrderingAndValidation(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("flags",
+		map[string]interface{}{"id": "a", "active": true},
+		map[string]interface{}{"id": "b", "active": false},
+		map[string]interface{}{"id": "c", "active": true},
+		map[string]interface{}{"id": "d", "active": false},
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	flags := client.Model("flags", nil)
+
+	docs, err := flags.Query().Sort("active", torm.Asc).Exec()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for i, doc := range docs {
+		if expected := i >= 2; doc["active"] != expected {
+			t.Errorf("Expected false before true, got %v", docs)
+			break
+		}
+	}
+
+	for _, operator := range []torm.QueryOperator{torm.Gt, torm.Gte, torm.Lt, torm.Lte, torm.Contains} {
+		before := ms.countRequests("POST", "/api/flags/query")
+		_, err := flags.Query().Filter("active", operator, true).Exec()
+		if !errors.Is(err, torm.ErrInvalidFilter) {
+			t.Errorf("Expected %s on a boolean to be rejected, got %v", operator, err)
+		}
+		if ms.countRequests("POST", "/api/flags/query") != before {
+			t.Errorf("Expected invalid %s filter not to reach the server", operator)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package torm_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestQueryStringOperators(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: "http://localhost:0"})
+	users := client.Model("users", nil)
+
+	tests := []struct {
+		operator torm.QueryOperator
+		value    interface{}
+		want     string
+	}{
+		{torm.Eq, "active", "users: status=active"},
+		{torm.Ne, "active", "users: status!=active"},
+		{torm.Gt, 30, "users: status>30"},
+		{torm.Gte, 30.5, "users: status>=30.5"},
+		{torm.Lt, int64(7), "users: status<7"},
+		{torm.Lte, true, "users: status<=true"},
+		{torm.Contains, "act", "users: status CONTAINS act"},
+		{torm.In, []string{"a", "b"}, "users: status IN [a,b]"},
+		{torm.NotIn, []interface{}{"a", 2, nil}, "users: status NOT IN [a,2,null]"},
+	}
+	for _, tt := range tests {
+		if got := users.Query().Filter("status", tt.operator, tt.value).String(); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.operator, tt.want, got)
+		}
+	}
+}
+
+func TestQueryStringClauses(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: "http://localhost:0"})
+	users := client.Model("users", nil)
+
+	got := users.Query().
+		Filter("age", torm.Gt, 30).
+		Filter("status", torm.In, []string{"a", "b"}).
+		Sort("createdAt", torm.Desc).
+		Limit(20).
+		Skip(40).
+		Select("id", "name").
+		String()
+	want := "users: age>30 AND status IN [a,b] SORT createdAt DESC LIMIT 20 SKIP 40 SELECT id,name"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if got := users.Query().String(); got != "users: *" {
+		t.Errorf("Expected an unfiltered query to read \"users: *\", got %q", got)
+	}
+	if got := users.Query().Sort("name", torm.Asc).String(); got != "users: * SORT name ASC" {
+		t.Errorf("Unexpected sorted query: %q", got)
+	}
+
+	values := users.Query().
+		Where("name", "Ada Lovelace").
+		Where("nickname", "").
+		Where("deleted", nil).
+		Where("meta", map[string]interface{}{"b": 1, "a": "x"}).
+		String()
+	want = `users: name="Ada Lovelace" AND nickname="" AND deleted=null AND meta={a:x,b:1}`
+	if values != want {
+		t.Errorf("Expected %q, got %q", want, values)
+	}
+}
+
+func TestQueryStringRedaction(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:     "http://localhost:0",
+		RedactQuery: torm.RedactFields("email"),
+	})
+	users := client.Model("users", nil)
+
+	got := users.Query().
+		Where("email", "ada@example.com").
+		Where("ssn", "123-45-6789").
+		Where("name", "Ada").
+		Redact("ssn").
+		String()
+	want := "users: email=*** AND ssn=*** AND name=Ada"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	custom := torm.NewClient(&torm.ClientOptions{
+		BaseURL: "http://localhost:0",
+		RedactQuery: func(field string, value interface{}) interface{} {
+			if s, ok := value.(string); ok && strings.Contains(s, "@") {
+				return "<email>"
+			}
+			return value
+		},
+	})
+	if got := custom.Model("users", nil).Query().Filter("contact", torm.In, []string{"a@b.c"}).Where("contact", "x@y.z").String(); got != "users: contact IN [a@b.c] AND contact=<email>" {
+		t.Errorf("Unexpected custom redaction: %q", got)
+	}
+}
+
+func TestQueryStringInErrors(t *testing.T) {
+	ms := newMockServer(t)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		time.Sleep(15 * time.Millisecond)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "boom"})
+		return true
+	})
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:       ms.URL,
+		RedactQuery:   torm.RedactFields("email"),
+		SlowThreshold: 10 * time.Millisecond,
+	})
+	users := client.Model("users", nil)
+
+	_, err := users.Query().Where("email", "ada@example.com").Filter("age", torm.Gt, 30).Limit(5).Exec()
+	want := "(query: users: email=*** AND age>30 LIMIT 5)"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Expected the error to name the query, got %v", err)
+	}
+	if strings.Contains(err.Error(), "ada@example.com") {
+		t.Errorf("Expected the email to be masked, got %v", err)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryServer {
+		t.Errorf("Expected the status to stay classified, got %s", torm.ErrorCategory(err))
+	}
+
+	if _, err := users.Query().Where("role", "admin").Count(); err == nil || !strings.HasSuffix(err.Error(), "(query: users: role=admin)") {
+		t.Errorf("Expected Count errors to name the query once, got %v", err)
+	}
+
+	ops := client.SlowOperations()
+	if len(ops) == 0 || ops[0].Query != "users: email=*** AND age>30 LIMIT 5" {
+		t.Errorf("Expected the slow log to carry the redacted query, got %+v", ops)
+	}
+}
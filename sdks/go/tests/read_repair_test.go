@@ -0,0 +1,183 @@
+package torm_test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// legacyUserRepair renames full_name and fills in a status
+func legacyUserRepair(writeBacks chan string) torm.ReadRepair {
+	return torm.ReadRepair{
+		Renames:   map[string]string{"full_name": "name"},
+		Defaults:  map[string]interface{}{"status": "active"},
+		WriteBack: true,
+		OnWriteBack: func(id string, err error) {
+			if err == nil {
+				writeBacks <- id
+			}
+		},
+	}
+}
+
+func waitWriteBack(t *testing.T, writeBacks chan string) string {
+	t.Helper()
+
+	select {
+	case id := <-writeBacks:
+		return id
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for a write-back")
+		return ""
+	}
+}
+
+func TestReadRepairLegacyDocument(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "full_name": "Ann"},
+		map[string]interface{}{"id": "user:2", "age": 40},
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	writeBacks := make(chan string, 4)
+	var events []torm.RepairEvent
+	repair := legacyUserRepair(writeBacks)
+	repair.OnRepair = func(event torm.RepairEvent) { events = append(events, event) }
+	users := client.Model("users", map[string]torm.ValidationRule{
+		"name":   {Type: "str", Required: true},
+		"status": {Type: "str", Required: true},
+	}).WithReadRepair(repair)
+
+	doc, err := users.FindByID("user:1")
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if doc["name"] != "Ann" || doc["status"] != "active" || doc["full_name"] != nil {
+		t.Fatalf("Unexpected repaired shape: %v", doc)
+	}
+	if len(events) != 1 || strings.Join(events[0].Fields, ",") != "full_name,name,status" || events[0].Invalid == nil {
+		t.Fatalf("Unexpected repair event: %+v", events)
+	}
+
+	if id := waitWriteBack(t, writeBacks); id != "user:1" {
+		t.Fatalf("Expected user:1 written back, got %s", id)
+	}
+	stored, _ := ms.doc("users", "user:1")
+	if stored["name"] != "Ann" || stored["status"] != "active" || stored["full_name"] != nil {
+		t.Errorf("Unexpected stored document: %v", stored)
+	}
+
+	// A repair that still fails validation is returned but not stored
+	doc, _ = users.FindByID("user:2")
+	if doc["status"] != "active" {
+		t.Errorf("Expected default status, got %v", doc)
+	}
+	stats := users.ReadRepairStats()
+	if stats.Repaired != 2 || stats.WrittenBack != 1 || stats.Skipped != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Repaired documents are left alone on later reads
+	if docs, err := users.Query().Where("name", "Ann").Exec(); err != nil || len(docs) != 1 {
+		t.Fatalf("Query failed: %v, %v", docs, err)
+	}
+	if stats := users.ReadRepairStats(); stats.Repaired != 2 {
+		t.Errorf("Expected no further repairs, got %+v", stats)
+	}
+}
+
+func TestReadRepairRateLimit(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "full_name": "Ann"},
+		map[string]interface{}{"id": "user:2", "full_name": "Bob"},
+		map[string]interface{}{"id": "user:3", "full_name": "Cy"},
+	)
+
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock})
+
+	writeBacks := make(chan string, 4)
+	repair := legacyUserRepair(writeBacks)
+	repair.MaxWriteBacks = 2
+	repair.WriteBackWindow = time.Minute
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, torm.WithReadRepair(repair))
+
+	found, err := users.Find()
+	if err != nil || len(found) != 3 || found[0].Name != "Ann" {
+		t.Fatalf("Unexpected repaired users: %v, %v", found, err)
+	}
+	waitWriteBack(t, writeBacks)
+	waitWriteBack(t, writeBacks)
+	if stats := users.ReadRepairStats(); stats.WrittenBack != 2 || stats.RateLimited != 1 {
+		t.Fatalf("Expected two write-backs and one rate limited, got %+v", stats)
+	}
+	if stored, _ := ms.doc("users", "user:3"); stored["full_name"] != "Cy" {
+		t.Fatalf("Expected user:3 to stay legacy, got %v", stored)
+	}
+
+	// Within the window the limit still holds
+	users.FindByID("user:3")
+	if stats := users.ReadRepairStats(); stats.RateLimited != 2 {
+		t.Errorf("Expected a second rate limited write-back, got %+v", stats)
+	}
+
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+
+	if user, err := users.FindByID("user:3"); err != nil || user.Name != "Cy" {
+		t.Fatalf("FindByID failed: %v, %v", user, err)
+	}
+	if id := waitWriteBack(t, writeBacks); id != "user:3" {
+		t.Fatalf("Expected user:3 written back, got %s", id)
+	}
+	if stats := users.ReadRepairStats(); stats.Repaired != 5 || stats.WrittenBack != 3 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestReadRepairSingleFlight(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "full_name": "Ann"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	// Hold the first write-back until both reads are done
+	release := make(chan struct{})
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method == http.MethodPut {
+			<-release
+		}
+		return false
+	})
+
+	writeBacks := make(chan string, 4)
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithReadRepair(legacyUserRepair(writeBacks)))
+
+	for i := 0; i < 2; i++ {
+		if user, err := users.FindByID("user:1"); err != nil || user.Name != "Ann" {
+			t.Fatalf("FindByID failed: %v, %v", user, err)
+		}
+	}
+	close(release)
+	waitWriteBack(t, writeBacks)
+
+	if n := ms.countRequests("PUT", "/api/users/user:1"); n != 1 {
+		t.Errorf("Expected one write-back request, got %d", n)
+	}
+	if stats := users.ReadRepairStats(); stats.Repaired != 2 || stats.Deduplicated != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,213 @@
+package torm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+const secretEmail = "alice.secret@example.com"
+
+// echoConflicts makes the mock reject creates in collection with a 409
+// whose message echoes the submitted email, as unique indexes often do
+func echoConflicts(ms *mockServer, collection string) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/"+collection {
+			return false
+		}
+		data, _ := body["data"].(map[string]interface{})
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": fmt.Sprintf("duplicate value %v for email", data["email"]),
+			"field": "email",
+		})
+		return true
+	})
+}
+
+// assertNoSecret fails when output contains the raw sensitive value
+func assertNoSecret(t *testing.T, where, output string) {
+	t.Helper()
+	if strings.Contains(output, secretEmail) {
+		t.Errorf("%s leaks the sensitive value: %s", where, output)
+	}
+}
+
+func TestSensitiveSchemaFieldIsMaskedInModelOutputs(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("accounts", map[string]interface{}{"id": "account:1", "name": "Alice", "email": secretEmail})
+
+	var logs bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:       ms.URL,
+		Logger:        slog.New(slog.NewJSONHandler(&logs, nil)),
+		SlowThreshold: time.Nanosecond,
+	})
+	accounts := client.Model("accounts", map[string]torm.ValidationRule{
+		"email": {Type: "str", Email: true, Sensitive: true},
+	})
+
+	// Slow operation logs and their query strings
+	if _, err := accounts.Query().Where("email", secretEmail).Exec(); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for _, op := range client.SlowOperations() {
+		assertNoSecret(t, "slow operation", op.Query)
+	}
+	if !strings.Contains(logs.String(), "email=***") {
+		t.Errorf("expected the masked filter in the slow log, got %s", logs.String())
+	}
+	assertNoSecret(t, "log", logs.String())
+
+	// Validation errors
+	_, err := accounts.Create(map[string]interface{}{"name": "Bob", "email": "not-an-email " + secretEmail})
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+	assertNoSecret(t, "validation error", err.Error())
+
+	// Redacted exports
+	var out bytes.Buffer
+	if _, err := accounts.ExportSnapshot(&out, torm.ExportOptions{Redacted: true}); err != nil {
+		t.Fatalf("ExportSnapshot failed: %v", err)
+	}
+	assertNoSecret(t, "redacted export", out.String())
+	if !strings.Contains(out.String(), `"email":"***(string)"`) {
+		t.Errorf("expected the masked email with its type hint, got %s", out.String())
+	}
+	if !strings.Contains(out.String(), `"name":"Alice"`) {
+		t.Errorf("expected other fields to be exported as is, got %s", out.String())
+	}
+
+	// Conflict messages echoing the value
+	echoConflicts(ms, "accounts")
+	_, err = accounts.Create(map[string]interface{}{"name": "Alice", "email": secretEmail})
+	if err == nil {
+		t.Fatal("expected a conflict")
+	}
+	assertNoSecret(t, "conflict error", err.Error())
+	if !strings.Contains(err.Error(), "***(string)") {
+		t.Errorf("expected the masked value in the conflict, got %v", err)
+	}
+	assertNoSecret(t, "log", logs.String())
+}
+
+func TestExportWithoutRedactedKeepsSensitiveValues(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("accounts", map[string]interface{}{"id": "account:1", "email": secretEmail})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	var out bytes.Buffer
+	if _, err := client.Model("accounts", nil).WithRedaction("email").ExportSnapshot(&out, torm.ExportOptions{}); err != nil {
+		t.Fatalf("ExportSnapshot failed: %v", err)
+	}
+	if !strings.Contains(out.String(), secretEmail) {
+		t.Errorf("expected a restorable export to keep the value, got %s", out.String())
+	}
+}
+
+func TestRedactedCollectionMasksAuditAndDeadLetters(t *testing.T) {
+	ms := newMockServer(t)
+	var logs, deadLetters bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:       ms.URL,
+		Logger:        slog.New(slog.NewJSONHandler(&logs, nil)),
+		SlowThreshold: time.Nanosecond,
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithRedaction("email"),
+		torm.WithAudit(torm.AuditOptions{Strict: true, Redact: true}),
+		torm.WithDeadLetter(torm.DeadLetterWriter(&deadLetters)))
+
+	user, err := users.Create(&TestUser{ID: "user:1", Name: "Alice", Email: secretEmail, Age: 30})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	user.Age = 31
+	if err := users.Save(user); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := users.FindFiltered(map[string]interface{}{"email": secretEmail}); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+
+	trail, err := users.AuditTrail("user:1")
+	if err != nil {
+		t.Fatalf("AuditTrail failed: %v", err)
+	}
+	if len(trail) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(trail))
+	}
+	raw, _ := json.Marshal(trail)
+	assertNoSecret(t, "audit trail", string(raw))
+	if trail[1].Before["email"] != "***(string)" || trail[1].After["age"] != float64(31) {
+		t.Errorf("expected masked email and plain age, got %v -> %v", trail[1].Before, trail[1].After)
+	}
+
+	echoConflicts(ms, "users")
+	result, err := users.CreateMany(context.Background(), []*TestUser{{ID: "user:2", Name: "Alice", Email: secretEmail}})
+	if err != nil {
+		t.Fatalf("CreateMany failed: %v", err)
+	}
+	if result.DeadLettered != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", result.DeadLettered)
+	}
+	assertNoSecret(t, "bulk failure", result.Failed[0].Err.Error())
+	assertNoSecret(t, "dead letter", deadLetters.String())
+	assertNoSecret(t, "log", logs.String())
+
+	// The stored document keeps the real value
+	stored, err := users.FindByID("user:1")
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if stored.Email != secretEmail {
+		t.Errorf("expected the stored email to be untouched, got %q", stored.Email)
+	}
+}
+
+func TestReplayableDeadLettersKeepTheDocument(t *testing.T) {
+	ms := newMockServer(t)
+	echoConflicts(ms, "users")
+	store := torm.DeadLetterFile(t.TempDir() + "/dead.jsonl")
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} },
+		torm.WithRedaction("email"), torm.WithDeadLetter(store))
+
+	if _, err := users.CreateMany(context.Background(), []*TestUser{{ID: "user:2", Email: secretEmail}}); err != nil {
+		t.Fatalf("CreateMany failed: %v", err)
+	}
+	entries, err := store.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d (%v)", len(entries), err)
+	}
+	assertNoSecret(t, "dead letter error", entries[0].Error)
+	if entries[0].Doc["email"] != secretEmail {
+		t.Errorf("expected the document to be kept for replay, got %v", entries[0].Doc)
+	}
+}
+
+func TestMaskValueKeepsTypeHints(t *testing.T) {
+	cases := map[string]interface{}{
+		"***(string)": "secret",
+		"***(number)": 42.5,
+		"***(bool)":   true,
+		"***(object)": map[string]interface{}{"a": 1},
+		"***(array)":  []interface{}{1},
+	}
+	for want, value := range cases {
+		if got := torm.MaskValue(value); got != want {
+			t.Errorf("MaskValue(%v) = %v, want %s", value, got, want)
+		}
+	}
+	if torm.MaskValue(nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+}
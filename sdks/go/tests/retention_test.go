@@ -0,0 +1,112 @@
+package torm_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedEvents(ms *mockServer, now time.Time) {
+	ms.seed("events",
+		map[string]interface{}{"id": "e1", "kind": "login", "createdAt": now.Add(-100 * 24 * time.Hour).Format(time.RFC3339)},
+		map[string]interface{}{"id": "e2", "kind": "login", "createdAt": now.Add(-91 * 24 * time.Hour).Format(time.RFC3339)},
+		map[string]interface{}{"id": "e3", "kind": "login", "createdAt": now.Add(-89 * 24 * time.Hour).Format(time.RFC3339)},
+		map[string]interface{}{"id": "e4", "kind": "audit", "createdAt": now.Add(-200 * 24 * time.Hour).Format(time.RFC3339)},
+		map[string]interface{}{"id": "e5", "kind": "login", "createdAt": float64(now.Add(-95 * 24 * time.Hour).UnixMilli())},
+		map[string]interface{}{"id": "e6", "kind": "login"},
+	)
+}
+
+func TestApplyRetention(t *testing.T) {
+	ms := newMockServer(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	seedEvents(ms, now)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: func() time.Time { return now }})
+
+	var progress []torm.DeleteProgress
+	events := torm.NewCollection(client, "events", func() *TestUser { return &TestUser{} },
+		torm.WithRetention(torm.RetentionPolicy{
+			Field:     "createdAt",
+			MaxAge:    90 * 24 * time.Hour,
+			Filter:    map[string]interface{}{"kind": "login"},
+			BatchSize: 2,
+			Progress:  func(p torm.DeleteProgress) { progress = append(progress, p) },
+		}))
+
+	// The dry run lists old login events and leaves everything in place
+	preview, err := events.ApplyRetention(context.Background(), torm.RetentionDryRun())
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	sort.Strings(preview.MatchedIDs)
+	if !preview.DryRun || preview.Matched != 3 || len(preview.MatchedIDs) != 3 ||
+		preview.MatchedIDs[0] != "e1" || preview.MatchedIDs[1] != "e2" || preview.MatchedIDs[2] != "e5" {
+		t.Errorf("Unexpected dry run report: %+v", preview)
+	}
+	if preview.Deleted != 0 || ms.countRequests("DELETE", "/api/events/e1") != 0 {
+		t.Error("Expected dry run not to delete")
+	}
+
+	report, err := events.ApplyRetention(context.Background())
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if report.Matched != 3 || report.Deleted != 3 || len(report.FailedIDs) != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if !report.Cutoff.Equal(now.Add(-90 * 24 * time.Hour)) {
+		t.Errorf("Expected cutoff from the fake clock, got %v", report.Cutoff)
+	}
+	if len(progress) != 2 || progress[1].Deleted != 3 {
+		t.Errorf("Expected progress after each batch of 2, got %+v", progress)
+	}
+
+	for _, id := range []string{"e1", "e2", "e5"} {
+		if _, ok := ms.doc("events", id); ok {
+			t.Errorf("Expected %s to be purged", id)
+		}
+	}
+	for _, id := range []string{"e3", "e4", "e6"} {
+		if _, ok := ms.doc("events", id); !ok {
+			t.Errorf("Expected %s to be kept", id)
+		}
+	}
+}
+
+func TestApplyAllRetentions(t *testing.T) {
+	ms := newMockServer(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	seedEvents(ms, now)
+	ms.seed("sessions",
+		map[string]interface{}{"id": "s1", "lastSeen": now.Add(-2 * time.Hour).Format(time.RFC3339)},
+		map[string]interface{}{"id": "s2", "lastSeen": now.Add(-10 * time.Minute).Format(time.RFC3339)},
+	)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: func() time.Time { return now }})
+	torm.NewCollection(client, "events", func() *TestUser { return &TestUser{} },
+		torm.WithRetention(torm.RetentionPolicy{Field: "createdAt", MaxAge: 90 * 24 * time.Hour}))
+	torm.NewCollection(client, "sessions", func() *TestUser { return &TestUser{} },
+		torm.WithRetention(torm.RetentionPolicy{Field: "lastSeen", MaxAge: time.Hour}))
+	torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	reports, err := client.ApplyAllRetentions(context.Background())
+	if err != nil {
+		t.Fatalf("ApplyAllRetentions failed: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected a report per policy, got %d", len(reports))
+	}
+	if reports[0].Collection != "events" || reports[0].Deleted != 4 {
+		t.Errorf("Unexpected events report: %+v", reports[0])
+	}
+	if reports[1].Collection != "sessions" || reports[1].Deleted != 1 {
+		t.Errorf("Unexpected sessions report: %+v", reports[1])
+	}
+	if _, ok := ms.doc("sessions", "s2"); !ok {
+		t.Error("Expected recent session to be kept")
+	}
+}
@@ -0,0 +1,209 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// unavailable answers every request with 503
+func unavailable(ms *mockServer) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "unavailable"})
+		return true
+	})
+}
+
+// closedURL is the address of a server that no longer accepts connections
+func closedURL() string {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	return dead.URL
+}
+
+func TestRetryHistoryAcrossEndpoints(t *testing.T) {
+	primary := newMockServer(t)
+	unavailable(primary)
+	standby := closedURL()
+
+	var mu sync.Mutex
+	var retried []torm.AttemptInfo
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURLs: []string{primary.URL, standby},
+		Retry: torm.RetryOptions{
+			MaxAttempts: 3,
+			Backoff:     time.Millisecond,
+			OnRetry: func(a torm.AttemptInfo) {
+				mu.Lock()
+				defer mu.Unlock()
+				retried = append(retried, a)
+			},
+		},
+	})
+
+	_, err := client.Model("users", nil).FindByID("user:1")
+	var apiErr *torm.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusServiceUnavailable || torm.ErrorCategory(err) != torm.CategoryOverloaded {
+		t.Errorf("status %d, category %q", apiErr.StatusCode, torm.ErrorCategory(err))
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts on 2 endpoints") {
+		t.Errorf("error does not summarize the attempts: %v", err)
+	}
+
+	history := torm.AttemptHistory(err)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 attempts, got %d:\n%s", len(history), torm.FormatAttempts(err))
+	}
+	want := []struct {
+		endpoint string
+		outcome  string
+		backoff  bool
+	}{
+		{primary.URL, "503", false},
+		{standby, "connection refused", false},
+		{primary.URL, "503", true},
+	}
+	for i, attempt := range history {
+		if attempt.Number != i+1 || attempt.Endpoint != want[i].endpoint {
+			t.Errorf("attempt %d: number %d endpoint %s, want %s", i, attempt.Number, attempt.Endpoint, want[i].endpoint)
+		}
+		if !strings.Contains(attempt.StatusOrErr, want[i].outcome) {
+			t.Errorf("attempt %d: outcome %q, want %q", i+1, attempt.StatusOrErr, want[i].outcome)
+		}
+		if (attempt.BackoffApplied > 0) != want[i].backoff {
+			t.Errorf("attempt %d: backoff %s", i+1, attempt.BackoffApplied)
+		}
+		if i > 0 && attempt.StartedAt.Before(history[i-1].StartedAt) {
+			t.Errorf("attempt %d started before attempt %d", i+1, i)
+		}
+	}
+	if lines := strings.Split(torm.FormatAttempts(err), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[1], "attempt 2 at ") {
+		t.Errorf("unexpected FormatAttempts output:\n%s", torm.FormatAttempts(err))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(retried) != 2 || retried[0].Number != 1 || retried[1].Number != 2 {
+		t.Errorf("OnRetry saw %v, want attempts 1 and 2", retried)
+	}
+}
+
+func TestRetryTransportFailureKeepsHistory(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: closedURL(),
+		Retry:   torm.RetryOptions{MaxAttempts: 4, Backoff: time.Millisecond, MaxHistory: 2},
+	})
+
+	_, err := client.Model("users", nil).FindByID("user:1")
+	var reqErr *torm.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("expected a RequestError, got %v", err)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryNetwork {
+		t.Errorf("expected a network error, got %q", torm.ErrorCategory(err))
+	}
+	history := torm.AttemptHistory(err)
+	if len(history) != 2 || history[0].Number != 3 || history[1].Number != 4 {
+		t.Errorf("expected the last 2 of 4 attempts, got %v", history)
+	}
+	if history[1].BackoffApplied != 2*history[0].BackoffApplied {
+		t.Errorf("backoff did not double: %s then %s", history[0].BackoffApplied, history[1].BackoffApplied)
+	}
+	if !strings.Contains(err.Error(), "after 4 attempts") {
+		t.Errorf("error does not count dropped attempts: %v", err)
+	}
+}
+
+func TestRetryBackoffStopsOnCancel(t *testing.T) {
+	ms := newMockServer(t)
+	unavailable(ms)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Retry: torm.RetryOptions{
+			MaxAttempts: 3,
+			Backoff:     10 * time.Second,
+			OnRetry:     func(torm.AttemptInfo) { cancel() },
+		},
+	})
+	client.Use(func(next torm.RoundTripFunc) torm.RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return next(req.WithContext(ctx))
+		}
+	})
+
+	start := time.Now()
+	_, err := client.Model("users", nil).FindByID("user:1")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the backoff to end on cancel, took %s", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) || torm.ErrorCategory(err) != torm.CategoryCanceled {
+		t.Errorf("expected a canceled error, got %v (%s)", err, torm.ErrorCategory(err))
+	}
+	if history := torm.AttemptHistory(err); len(history) != 1 {
+		t.Errorf("expected the failed attempt in the history, got %v", history)
+	}
+	if n := ms.countRequests("GET", "/api/users/user:1"); n != 1 {
+		t.Errorf("expected no attempt after the cancel, got %d requests", n)
+	}
+}
+
+func TestRetryRecoversWithoutError(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ann"})
+	failures := 1
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if failures == 0 {
+			return false
+		}
+		failures--
+		writeJSON(w, http.StatusBadGateway, map[string]interface{}{"error": "bad gateway"})
+		return true
+	})
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Retry:   torm.RetryOptions{MaxAttempts: 3, Backoff: time.Millisecond},
+	})
+
+	doc, err := client.Model("users", nil).FindByID("user:1")
+	if err != nil || doc["name"] != "Ann" {
+		t.Fatalf("FindByID = %v, %v", doc, err)
+	}
+	if n := ms.countRequests("GET", "/api/users/user:1"); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestRetrySkipsCreatesAndIsOffByDefault(t *testing.T) {
+	ms := newMockServer(t)
+	unavailable(ms)
+
+	retrying := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Retry: torm.RetryOptions{MaxAttempts: 3, Backoff: time.Millisecond}})
+	_, err := retrying.Model("users", nil).Create(map[string]interface{}{"name": "Ann"})
+	if err == nil || torm.AttemptHistory(err) != nil {
+		t.Errorf("create should fail once without history, got %v", err)
+	}
+	if n := ms.countRequests("POST", "/api/users"); n != 1 {
+		t.Errorf("create was sent %d times", n)
+	}
+
+	plain := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	_, err = plain.Model("users", nil).FindByID("user:1")
+	if err == nil || torm.AttemptHistory(err) != nil || torm.FormatAttempts(err) != "" {
+		t.Errorf("expected a single attempt without history, got %v", err)
+	}
+	if n := ms.countRequests("GET", "/api/users/user:1"); n != 1 {
+		t.Errorf("find was sent %d times", n)
+	}
+}
@@ -0,0 +1,175 @@
+package torm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// Post is read back with a populated author, a computed headline and cache
+// metadata, none of which are stored
+type Post struct {
+	ID        string                 `json:"id"`
+	Title     string                 `json:"title"`
+	AuthorID  string                 `json:"authorId"`
+	Author    map[string]interface{} `json:"authorId_doc,omitempty"`
+	Headline  string                 `json:"headline,omitempty"`
+	CachedAt  string                 `json:"_cachedAt,omitempty"`
+	AuditedBy string                 `json:"_auditedBy,omitempty"`
+}
+
+func (p *Post) GetID() string   { return p.ID }
+func (p *Post) SetID(id string) { p.ID = id }
+func (p *Post) ToMap() map[string]interface{} {
+	m := map[string]interface{}{"id": p.ID, "title": p.Title, "authorId": p.AuthorID}
+	if p.Author != nil {
+		m["authorId_doc"] = p.Author
+	}
+	if p.Headline != "" {
+		m["headline"] = p.Headline
+	}
+	if p.CachedAt != "" {
+		m["_cachedAt"] = p.CachedAt
+	}
+	if p.AuditedBy != "" {
+		m["_auditedBy"] = p.AuditedBy
+	}
+	return m
+}
+
+var postSanitizer = torm.SanitizeOptions{Fields: []string{"headline"}}
+
+// assertNoSyntheticWrites fails if any write to ms carried a synthetic field
+func assertNoSyntheticWrites(t *testing.T, ms *mockServer) {
+	t.Helper()
+	writes := 0
+	for _, req := range ms.requestLog() {
+		if req.Method != "POST" && req.Method != "PUT" {
+			continue
+		}
+		writes++
+		data, _ := req.Body["data"].(map[string]interface{})
+		for _, field := range []string{"authorId_doc", "headline", "_cachedAt", "_auditedBy"} {
+			if _, ok := data[field]; ok {
+				t.Errorf("%s %s sent synthetic field %s", req.Method, req.Path, field)
+			}
+		}
+	}
+	if writes == 0 {
+		t.Error("Expected writes to reach the server")
+	}
+}
+
+func TestSanitizerStripsReadBackFields(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	posts := torm.NewCollection(client, "posts", func() *Post { return &Post{} }, torm.WithSanitizer(postSanitizer))
+
+	ms.seed("posts", map[string]interface{}{"id": "post:1", "title": "Draft", "authorId": "user:1"})
+	post, err := posts.FindByID("post:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// What a populate, a virtual and the read cache would add
+	post.Author = map[string]interface{}{"id": "user:1", "name": "Alice"}
+	post.Headline = "DRAFT by Alice"
+	post.CachedAt = "2026-01-01T00:00:00Z"
+	post.AuditedBy = "alice"
+
+	post.Title = "Published"
+	if err := posts.Save(post); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	stored, _ := ms.doc("posts", "post:1")
+	if stored["title"] != "Published" {
+		t.Errorf("Expected the real change to be saved, got %v", stored)
+	}
+
+	created, err := posts.Create(&Post{ID: "post:2", Title: "New", AuthorID: "user:1", Headline: "NEW", CachedAt: "now"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.Headline != "NEW" || created.CachedAt != "now" {
+		t.Errorf("Expected the stripped fields to be restored on the result, got %+v", created)
+	}
+
+	assertNoSyntheticWrites(t, ms)
+}
+
+func TestSanitizerRunsBeforeValidation(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	schema := map[string]torm.ValidationRule{
+		"title":     {Type: "str", Required: true},
+		"authorId":  {Type: "str"},
+		"_cachedAt": {Type: "str"}, // Only checked if the field got past the sanitizer
+	}
+	posts := client.Model("posts", schema).WithSanitizer(postSanitizer)
+
+	ms.seed("posts", map[string]interface{}{"id": "post:1", "title": "Draft", "authorId": "user:1"})
+	doc, err := posts.FindByID("post:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc["authorId_doc"] = map[string]interface{}{"name": "Alice"}
+	doc["_cachedAt"] = 12345
+	doc["title"] = "Published"
+
+	updated, err := posts.Update("post:1", doc)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, ok := doc["_cachedAt"]; !ok {
+		t.Error("Expected the caller's map to keep its synthetic fields")
+	}
+	if updated["_cachedAt"] != 12345 || updated["title"] != "Published" {
+		t.Errorf("Expected the update result with stripped fields restored, got %v", updated)
+	}
+
+	assertNoSyntheticWrites(t, ms)
+}
+
+func TestSanitizerStrictMode(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	strict := torm.SanitizeOptions{Fields: []string{"headline"}, Strict: true}
+	posts := torm.NewCollection(client, "posts", func() *Post { return &Post{} }, torm.WithSanitizer(strict))
+
+	_, err := posts.Create(&Post{ID: "post:1", Title: "New", Headline: "NEW", CachedAt: "now"})
+	if !errors.Is(err, torm.ErrSyntheticField) {
+		t.Fatalf("Expected ErrSyntheticField, got %v", err)
+	}
+	if want := "torm: posts.Create: document carries synthetic fields: _cachedAt, headline in write to posts"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+	if torm.ErrorCategory(err) != torm.CategoryValidation {
+		t.Errorf("Expected a validation error, got %s", torm.ErrorCategory(err))
+	}
+	if err := posts.Save(&Post{ID: "post:1", Title: "New", Author: map[string]interface{}{"name": "Alice"}}); !errors.Is(err, torm.ErrSyntheticField) {
+		t.Errorf("Expected Save to be refused too, got %v", err)
+	}
+	if len(ms.requestLog()) != 0 {
+		t.Errorf("Expected refused writes to send nothing, got %d requests", len(ms.requestLog()))
+	}
+
+	// Clean documents pass
+	if _, err := posts.Create(&Post{ID: "post:2", Title: "Clean"}); err != nil {
+		t.Errorf("Expected a clean document to be written, got %v", err)
+	}
+}
+
+func TestSanitizerPrefixesCanBeDisabled(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	opts := torm.SanitizeOptions{Prefixes: []string{}, Strict: true}
+	posts := torm.NewCollection(client, "posts", func() *Post { return &Post{} }, torm.WithSanitizer(opts))
+
+	if _, err := posts.Create(&Post{ID: "post:1", Title: "New", CachedAt: "kept"}); err != nil {
+		t.Fatalf("Expected underscore fields to be allowed, got %v", err)
+	}
+	if stored, _ := ms.doc("posts", "post:1"); stored["_cachedAt"] != "kept" {
+		t.Errorf("Expected _cachedAt to be stored, got %v", stored)
+	}
+}
@@ -0,0 +1,202 @@
+package torm_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// delayRequests makes the mock answer requests to collection after delay
+func delayRequests(ms *mockServer, collection string, delay time.Duration) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if strings.HasPrefix(r.URL.Path, "/api/"+collection) {
+			time.Sleep(delay)
+		}
+		return false
+	})
+}
+
+// slowRecorder collects the operations passed to OnSlowOperation
+type slowRecorder struct {
+	mu  sync.Mutex
+	ops []torm.SlowOperation
+}
+
+func (r *slowRecorder) record(op torm.SlowOperation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ops = append(r.ops, op)
+}
+
+func (r *slowRecorder) list() []torm.SlowOperation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]torm.SlowOperation(nil), r.ops...)
+}
+
+func TestSlowQueryIsLoggedAndReported(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	delayRequests(ms, "products", 40*time.Millisecond)
+
+	var logs bytes.Buffer
+	recorder := &slowRecorder{}
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:         ms.URL,
+		Logger:          slog.New(slog.NewJSONHandler(&logs, nil)),
+		SlowThreshold:   20 * time.Millisecond,
+		OnSlowOperation: recorder.record,
+	})
+
+	_, err := client.Model("products", nil).Query().
+		Filter("price", torm.Gt, 1).
+		Filter("stock", torm.Gte, 0).
+		Limit(5).
+		Exec()
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if _, err := client.Model("users", nil).Count(); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+
+	ops := recorder.list()
+	if len(ops) != 1 {
+		t.Fatalf("Expected only the delayed query to be reported, got %+v", ops)
+	}
+	op := ops[0]
+	if op.Operation != torm.OpQuery || op.Collection != "products" || op.Filters != 2 || op.Limit != 5 || op.Attempts != 1 {
+		t.Errorf("Unexpected slow operation: %+v", op)
+	}
+	if op.Duration < 20*time.Millisecond || op.Threshold != 20*time.Millisecond || op.Err != nil {
+		t.Errorf("Unexpected timing: %+v", op)
+	}
+
+	logged := logs.String()
+	for _, want := range []string{`"level":"WARN"`, `"msg":"torm: slow operation"`, `"operation":"query"`,
+		`"collection":"products"`, `"filters":2`, `"limit":5`, `"attempts":1`} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("Expected log to contain %s, got %s", want, logged)
+		}
+	}
+
+	if got := client.SlowOperations(); len(got) != 1 || got[0].Operation != torm.OpQuery {
+		t.Errorf("Expected the query in the slow log, got %+v", got)
+	}
+}
+
+func TestSlowSuccessHasNoError(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+	delayRequests(ms, "users", 30*time.Millisecond)
+
+	var logs bytes.Buffer
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL:       ms.URL,
+		Logger:        slog.New(slog.NewJSONHandler(&logs, nil)),
+		SlowThreshold: 10 * time.Millisecond,
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	if err := users.Save(&TestUser{ID: "user:1", Name: "Bob"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	ops := client.SlowOperations()
+	if len(ops) != 1 || ops[0].Operation != torm.OpSave {
+		t.Fatalf("Expected the save in the slow log, got %+v", ops)
+	}
+	if ops[0].Err != nil || len(ops[0].History) != 0 {
+		t.Errorf("Expected no error on a slow success, got %v, %v", ops[0].Err, ops[0].History)
+	}
+	if logged := logs.String(); strings.Contains(logged, `"error"`) || strings.Contains(logged, `"history"`) {
+		t.Errorf("Expected no error in the log, got %s", logged)
+	}
+}
+
+func TestSlowThresholdPerCollection(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	delayRequests(ms, "", 30*time.Millisecond)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	factory := func() *TestProduct { return &TestProduct{} }
+	watched := torm.NewCollection(client, "products", factory, torm.WithSlowThreshold(10*time.Millisecond))
+	unwatched := torm.NewCollection(client, "users", factory)
+
+	if _, err := watched.FindFiltered(map[string]interface{}{"name": "Laptop"}); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if _, err := unwatched.Count(); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+
+	ops := client.SlowOperations()
+	if len(ops) != 1 || ops[0].Operation != torm.OpFind || ops[0].Collection != "products" || ops[0].Filters != 1 {
+		t.Fatalf("Expected only the watched find to be reported, got %+v", ops)
+	}
+
+	// A zero override disables the log despite the client threshold
+	client = torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, SlowThreshold: 10 * time.Millisecond})
+	quiet := torm.NewCollection(client, "products", factory, torm.WithSlowThreshold(0))
+	if _, err := quiet.Count(); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if ops := client.SlowOperations(); len(ops) != 0 {
+		t.Errorf("Expected nothing reported for a disabled collection, got %+v", ops)
+	}
+}
+
+func TestSlowOperationCountsAttempts(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		time.Sleep(15 * time.Millisecond)
+		if r.Method != http.MethodPost {
+			return false
+		}
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "exists", "existing_id": "product:1"})
+		return true
+	})
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, SlowThreshold: 10 * time.Millisecond})
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} })
+
+	// The create conflicts and is retried as a replace
+	if _, err := products.Upsert(&TestProduct{ID: "product:1", Name: "Laptop Pro", Price: 1299}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	ops := client.SlowOperations()
+	if len(ops) != 1 || ops[0].Operation != torm.OpCreate || ops[0].Attempts != 2 {
+		t.Fatalf("Expected a create with 2 attempts, got %+v", ops)
+	}
+}
+
+func TestSlowOperationsRingBuffer(t *testing.T) {
+	ms := newMockServer(t)
+	delayRequests(ms, "", 15*time.Millisecond)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, SlowThreshold: 10 * time.Millisecond, SlowLogSize: 3})
+	collections := []string{"c1", "c2", "c3", "c4", "c5"}
+	for _, name := range collections {
+		if _, err := client.Model(name, nil).Count(); err != nil {
+			t.Fatalf("Count of %s failed: %v", name, err)
+		}
+	}
+
+	ops := client.SlowOperations()
+	if len(ops) != 3 {
+		t.Fatalf("Expected the buffer to hold 3 operations, got %d", len(ops))
+	}
+	for i, want := range collections[2:] {
+		if ops[i].Collection != want || ops[i].Operation != torm.OpCount {
+			t.Errorf("Entry %d: expected a count of %s, got %+v", i, want, ops[i])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package torm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// TestPost is a document with a title and a generated slug
+type TestPost struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug,omitempty"`
+}
+
+func (p *TestPost) GetID() string   { return p.ID }
+func (p *TestPost) SetID(id string) { p.ID = id }
+func (p *TestPost) ToMap() map[string]interface{} {
+	doc := map[string]interface{}{"id": p.ID, "title": p.Title}
+	if p.Slug != "" {
+		doc["slug"] = p.Slug
+	}
+	return doc
+}
+
+func TestSlugUnicodeTitles(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	posts := client.Model("posts", nil).WithSlug("title", "slug", torm.SlugOptions{MaxLength: 24})
+
+	cases := map[string]string{
+		"Crème Brûlée: Größe & Ölfest!": "creme-brulee-groesse-oel",
+		"Привет, мир":                   "privet-mir",
+		"東京 2024 — Guide":               "東京-2024-guide",
+		"  --Hello__World--  ":          "hello-world",
+		"Café au lait":                 "cafe-au-lait",
+	}
+	for title, want := range cases {
+		doc, err := posts.Create(map[string]interface{}{"title": title})
+		if err != nil {
+			t.Fatalf("Create %q failed: %v", title, err)
+		}
+		if doc["slug"] != want {
+			t.Errorf("Slug of %q: expected %q, got %v", title, want, doc["slug"])
+		}
+	}
+
+	if _, err := posts.Create(map[string]interface{}{"title": "!!!"}); !errors.Is(err, torm.ErrValidation) {
+		t.Errorf("Expected validation error for a title without letters, got %v", err)
+	}
+	if doc, _ := posts.Create(map[string]interface{}{"title": "Any", "slug": "custom"}); doc["slug"] != "custom" {
+		t.Errorf("Expected a given slug to be kept, got %v", doc["slug"])
+	}
+}
+
+func TestSlugCollisionsAndBound(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	posts := torm.NewCollection(client, "posts", func() *TestPost { return &TestPost{} },
+		torm.WithSlug("title", "slug", torm.SlugOptions{MaxAttempts: 3}))
+
+	for i, want := range []string{"hello-world", "hello-world-2", "hello-world-3"} {
+		post, err := posts.Create(&TestPost{ID: "post:" + string(rune('a'+i)), Title: "Hello, World"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if post.Slug != want {
+			t.Errorf("Expected %s, got %s", want, post.Slug)
+		}
+	}
+
+	_, err := posts.Create(&TestPost{ID: "post:d", Title: "Hello World!"})
+	var exhausted *torm.SlugExhaustedError
+	if !errors.As(err, &exhausted) || exhausted.Attempts != 3 || exhausted.Base != "hello-world" {
+		t.Fatalf("Expected SlugExhaustedError after 3 attempts, got %v", err)
+	}
+	if _, ok := ms.doc("posts", "post:d"); ok {
+		t.Errorf("Expected nothing to be written when slugs run out")
+	}
+}
+
+func TestSlugOnUpdate(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	posts := torm.NewCollection(client, "posts", func() *TestPost { return &TestPost{} },
+		torm.WithSlug("title", "slug", torm.SlugOptions{OnUpdate: true}))
+
+	if _, err := posts.Create(&TestPost{ID: "post:1", Title: "First Post"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := posts.Create(&TestPost{ID: "post:2", Title: "Second"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// A new title that slugs the same keeps the document's own slug
+	if err := posts.Save(&TestPost{ID: "post:1", Title: "First post!", Slug: "first-post"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if stored, _ := ms.doc("posts", "post:1"); stored["slug"] != "first-post" {
+		t.Errorf("Expected own slug to be kept, got %v", stored["slug"])
+	}
+
+	// Renaming onto another document's slug gets a counter
+	if err := posts.Save(&TestPost{ID: "post:2", Title: "First Post", Slug: "second"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if stored, _ := ms.doc("posts", "post:2"); stored["slug"] != "first-post-2" {
+		t.Errorf("Expected renamed slug first-post-2, got %v", stored["slug"])
+	}
+
+	// Saving without a title change leaves the slug alone
+	if err := posts.Save(&TestPost{ID: "post:2", Title: "First Post", Slug: "first-post-2"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if stored, _ := ms.doc("posts", "post:2"); stored["slug"] != "first-post-2" {
+		t.Errorf("Expected unchanged slug, got %v", stored["slug"])
+	}
+
+	// Models regenerate on Update the same way
+	model := client.Model("posts", nil).WithSlug("title", "slug", torm.SlugOptions{OnUpdate: true})
+	doc, err := model.Update("post:1", map[string]interface{}{"id": "post:1", "title": "Renamed"})
+	if err != nil || doc["slug"] != "renamed" {
+		t.Errorf("Expected renamed slug, got %v, %v", doc, err)
+	}
+}
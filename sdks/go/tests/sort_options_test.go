@@ -0,0 +1,125 @@
+package torm_test
+
+import (
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedLogins seeds users missing lastLoginAt or holding null, with names
+// of mixed case
+func seedLogins(ms *mockServer) {
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "name": "bob", "lastLoginAt": 3},
+		map[string]interface{}{"id": "user:2", "name": "Alice"},
+		map[string]interface{}{"id": "user:3", "name": "carol", "lastLoginAt": 1},
+		map[string]interface{}{"id": "user:4", "name": "Dave", "lastLoginAt": nil},
+		map[string]interface{}{"id": "user:5", "name": "alice", "lastLoginAt": 2},
+	)
+}
+
+func TestSortOptions(t *testing.T) {
+	ms := newMockServer(t)
+	seedLogins(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", nil)
+
+	cases := []struct {
+		name string
+		key  torm.QuerySort
+		want string
+	}{
+		{"nulls low ascending", torm.QuerySort{Field: "lastLoginAt", Order: torm.Asc}, "user:2,user:4,user:3,user:5,user:1"},
+		{"nulls low descending", torm.QuerySort{Field: "lastLoginAt", Order: torm.Desc}, "user:1,user:5,user:3,user:2,user:4"},
+		{"nulls first ascending", torm.QuerySort{Field: "lastLoginAt", Order: torm.Asc, NullsFirst: true}, "user:2,user:4,user:3,user:5,user:1"},
+		{"nulls first descending", torm.QuerySort{Field: "lastLoginAt", Order: torm.Desc, NullsFirst: true}, "user:2,user:4,user:1,user:5,user:3"},
+		{"nulls last ascending", torm.QuerySort{Field: "lastLoginAt", Order: torm.Asc, NullsLast: true}, "user:3,user:5,user:1,user:2,user:4"},
+		{"nulls last descending", torm.QuerySort{Field: "lastLoginAt", Order: torm.Desc, NullsLast: true}, "user:1,user:5,user:3,user:2,user:4"},
+		{"by case ascending", torm.QuerySort{Field: "name", Order: torm.Asc}, "user:2,user:4,user:5,user:1,user:3"},
+		{"by case descending", torm.QuerySort{Field: "name", Order: torm.Desc}, "user:3,user:1,user:5,user:4,user:2"},
+		{"ignoring case ascending", torm.QuerySort{Field: "name", Order: torm.Asc, CaseInsensitive: true}, "user:2,user:5,user:1,user:3,user:4"},
+		{"ignoring case descending", torm.QuerySort{Field: "name", Order: torm.Desc, CaseInsensitive: true}, "user:4,user:3,user:1,user:5,user:2"},
+	}
+	for _, tc := range cases {
+		docs, err := users.Query().SortWith(tc.key).Exec()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := docIDs(docs); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSortDefaultsOfCollection(t *testing.T) {
+	ms := newMockServer(t)
+	seedLogins(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	schema := map[string]torm.ValidationRule{"name": {Type: "str"}, "lastLoginAt": {Type: "int"}}
+	users := client.Model("users", schema).WithSortDefaults(torm.SortDefaults{NullsLast: true, CaseInsensitive: true})
+
+	runs := []struct {
+		name string
+		qb   *torm.QueryBuilder
+		want string
+	}{
+		{"default placement", users.Query().Sort("lastLoginAt", torm.Asc), "user:3,user:5,user:1,user:2,user:4"},
+		{"explicit placement", users.Query().SortWith(torm.QuerySort{Field: "lastLoginAt", Order: torm.Asc, NullsFirst: true}), "user:2,user:4,user:3,user:5,user:1"},
+		{"string field", users.Query().Sort("name", torm.Asc), "user:2,user:5,user:1,user:3,user:4"},
+	}
+	for _, run := range runs {
+		docs, err := run.qb.Exec()
+		if err != nil {
+			t.Fatalf("%s: %v", run.name, err)
+		}
+		if got := docIDs(docs); got != run.want {
+			t.Errorf("%s: expected %s, got %s", run.name, run.want, got)
+		}
+	}
+
+	plan := users.Query().Sort("lastLoginAt", torm.Asc).Explain()
+	if key := plan.Sort[0]; !key.NullsLast || key.CaseInsensitive {
+		t.Errorf("Expected nulls last without case folding for a number field, got %+v", key)
+	}
+	if plan.DefaultOrder == nil || !plan.DefaultOrder.NullsLast {
+		t.Errorf("Expected the defaults on the tie-breaker too, got %+v", plan.DefaultOrder)
+	}
+	if key := users.Query().Sort("name", torm.Desc).Explain().Sort[0]; !key.NullsLast || !key.CaseInsensitive {
+		t.Errorf("Expected both defaults on a string field, got %+v", key)
+	}
+}
+
+func TestSortOptionsPayload(t *testing.T) {
+	ms := newMockServer(t)
+	seedLogins(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", nil)
+	key := torm.QuerySort{Field: "lastLoginAt", Order: torm.Asc, NullsLast: true}
+
+	// Without the capability the options stay on the client, which then
+	// pages the results itself
+	docs, err := users.Query().SortWith(key).Limit(2).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := docIDs(docs); got != "user:3,user:5" {
+		t.Errorf("Expected the first page sorted locally, got %s", got)
+	}
+	body := ms.requestLog()[0].Body
+	if sort := body["sort"].(map[string]interface{}); sort["nulls_last"] != nil || body["limit"] != nil {
+		t.Errorf("Expected no sort options or limit sent, got %v", body)
+	}
+
+	advertise(ms, "capabilities", []string{"sort_options"})
+	if !client.Supports(torm.CapabilitySortOptions) {
+		t.Fatal("Expected the capability to be advertised")
+	}
+	if _, err := users.Query().SortWith(key).Limit(2).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	log := ms.requestLog()
+	body = log[len(log)-1].Body
+	if sort := body["sort"].(map[string]interface{}); sort["nulls_last"] != true || body["limit"] != float64(2) {
+		t.Errorf("Expected the sort options and limit sent to a capable server, got %v", body)
+	}
+}
@@ -0,0 +1,156 @@
+package torm_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// transitionLog records state changes
+type transitionLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *transitionLog) record(old, new torm.ClientState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, old.String()+"->"+new.String())
+}
+
+func (l *transitionLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.events, ",")
+}
+
+// failAll makes the mock answer every request with 500 while failing is set
+func failAll(ms *mockServer, failing *atomic.Bool) {
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if failing.Load() {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "down"})
+			return true
+		}
+		return false
+	})
+}
+
+func TestClientStateTransitions(t *testing.T) {
+	ms := newMockServer(t)
+	var failing atomic.Bool
+	failAll(ms, &failing)
+
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Clock: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+		Health: torm.HealthOptions{DegradedAfter: 2, DownAfter: 4, Debounce: 10 * time.Second},
+	})
+	log := &transitionLog{}
+	client.OnStateChange(log.record)
+	users := client.Model("users", nil)
+	users.Find()
+
+	// Failures must persist through the debounce period before they are reported
+	failing.Store(true)
+	users.Find()
+	users.Find()
+	if client.State() != torm.StateHealthy || log.String() != "" {
+		t.Fatalf("Expected no transition within the debounce period, got %s", log)
+	}
+	advance(10 * time.Second)
+	users.Find()
+	if client.State() != torm.StateDegraded {
+		t.Fatalf("Expected degraded, got %s", client.State())
+	}
+
+	users.Find()
+	advance(10 * time.Second)
+	users.Find()
+	if client.State() != torm.StateDown {
+		t.Fatalf("Expected down, got %s", client.State())
+	}
+
+	// A flapping backend does not produce transitions
+	for i := 0; i < 4; i++ {
+		failing.Store(i%2 == 1)
+		users.Find()
+		advance(2 * time.Second)
+	}
+	if client.State() != torm.StateDown {
+		t.Fatalf("Expected flapping to keep the state, got %s", client.State())
+	}
+
+	// Sustained recovery
+	failing.Store(false)
+	users.Find()
+	advance(10 * time.Second)
+	users.Find()
+	if client.State() != torm.StateHealthy {
+		t.Fatalf("Expected healthy, got %s", client.State())
+	}
+
+	if got := log.String(); got != "healthy->degraded,degraded->down,down->healthy" {
+		t.Errorf("Unexpected transitions: %s", got)
+	}
+}
+
+func TestClientStateHealthProbes(t *testing.T) {
+	ms := newMockServer(t)
+	var failing atomic.Bool
+	failAll(ms, &failing)
+
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Health:  torm.HealthOptions{DegradedAfter: 1, DownAfter: 2},
+	})
+	log := &transitionLog{}
+	client.OnStateChange(log.record)
+
+	failing.Store(true)
+	if state := client.ProbeHealth(context.Background()); state != torm.StateDegraded {
+		t.Errorf("Expected degraded after one failed probe, got %s", state)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.MonitorHealth(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+	waitState := func(want torm.ClientState) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for client.State() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s, state is %s", want, client.State())
+			}
+			time.Sleep(2 * time.Millisecond)
+		}
+	}
+	waitState(torm.StateDown)
+	failing.Store(false)
+	waitState(torm.StateHealthy)
+	cancel()
+	<-done
+
+	if got := log.String(); got != "healthy->degraded,degraded->down,down->healthy" {
+		t.Errorf("Unexpected transitions: %s", got)
+	}
+}
@@ -0,0 +1,193 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestAcceptedWriteWithLocation(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, StrictResponses: true})
+
+	var polls atomic.Int64
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		switch {
+		case r.Method == http.MethodPost:
+			w.Header().Set("Location", "/jobs/7")
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": "7", "state": "queued"})
+		case r.URL.Path == "/jobs/7" && polls.Add(1) < 3:
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"state": "running"})
+		case r.URL.Path == "/jobs/7":
+			writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"id": "user:1", "name": "Alice"}})
+		default:
+			return false
+		}
+		return true
+	})
+
+	result, err := client.Model("users", nil).CreateDetailed(map[string]interface{}{"name": "Alice"})
+	if err != nil {
+		t.Fatalf("Expected 202 to succeed in strict mode, got %v", err)
+	}
+	if !result.Accepted || result.Status != http.StatusAccepted || result.Location != "/jobs/7" || len(result.Data) != 0 {
+		t.Fatalf("Unexpected accepted result: %+v", result)
+	}
+
+	doc, err := result.Wait(context.Background(), time.Millisecond)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if doc["id"] != "user:1" || polls.Load() != 3 {
+		t.Errorf("Expected document after three polls, got %v after %d", doc, polls.Load())
+	}
+}
+
+func TestAcceptedWriteWithoutLocation(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		w.WriteHeader(http.StatusAccepted)
+		return true
+	})
+
+	users := client.Model("users", nil)
+	doc, err := users.Create(map[string]interface{}{"name": "Alice"})
+	if err != nil || doc == nil || len(doc) != 0 {
+		t.Fatalf("Expected empty result for 202, got %v, %v", doc, err)
+	}
+
+	result, err := users.UpdateDetailed("user:1", map[string]interface{}{"name": "Bob"})
+	if err != nil || !result.Accepted {
+		t.Fatalf("Expected accepted update, got %+v, %v", result, err)
+	}
+	if _, err := result.Wait(context.Background(), time.Millisecond); !errors.Is(err, torm.ErrNoStatusLocation) {
+		t.Errorf("Expected ErrNoStatusLocation, got %v", err)
+	}
+
+	// Finished writes are returned as they are
+	done := &torm.WriteResult{Status: http.StatusOK, Data: map[string]interface{}{"id": "a"}}
+	if doc, err := done.Wait(context.Background(), 0); err != nil || doc["id"] != "a" {
+		t.Errorf("Expected finished result back, got %v, %v", doc, err)
+	}
+}
+
+func TestAcceptedWriteLocationOrigin(t *testing.T) {
+	primary := newMockServer(t)
+	standby := newMockServer(t)
+	foreign := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURLs: []string{primary.URL, standby.URL}, AuthToken: "secret"})
+
+	var foreignRequests atomic.Int64
+	foreign.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		foreignRequests.Add(1)
+		return false
+	})
+	var location atomic.Value
+	var authorized atomic.Bool
+	primary.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		switch {
+		case r.Method == http.MethodPost:
+			w.Header().Set("Location", location.Load().(string))
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/jobs/7":
+			authorized.Store(r.Header.Get("Authorization") == "Bearer secret")
+			writeJSON(w, http.StatusOK, map[string]interface{}{"id": "user:1"})
+		default:
+			return false
+		}
+		return true
+	})
+	wait := func(loc string) (map[string]interface{}, error) {
+		location.Store(loc)
+		result, err := client.Model("users", nil).CreateDetailed(map[string]interface{}{"name": "Alice"})
+		if err != nil || !result.Accepted {
+			t.Fatalf("Expected accepted write, got %+v, %v", result, err)
+		}
+		return result.Wait(context.Background(), time.Millisecond)
+	}
+
+	// A location on another server is refused before anything is sent
+	for _, loc := range []string{foreign.URL + "/jobs/7", "//" + strings.TrimPrefix(foreign.URL, "http://") + "/jobs/7"} {
+		if _, err := wait(loc); !errors.Is(err, torm.ErrForeignStatusLocation) {
+			t.Errorf("Expected ErrForeignStatusLocation for %s, got %v", loc, err)
+		}
+	}
+	if n := foreignRequests.Load(); n != 0 {
+		t.Errorf("Expected no request to the foreign server, got %d", n)
+	}
+
+	// A location under any base URL is polled through the client's endpoints
+	doc, err := wait(standby.URL + "/jobs/7")
+	if err != nil || doc["id"] != "user:1" || !authorized.Load() {
+		t.Errorf("Expected authorized poll of the status path, got %v, %v", doc, err)
+	}
+	if endpoint := client.LastEndpoint(); endpoint != primary.URL {
+		t.Errorf("Expected poll sent to the current endpoint %s, got %s", primary.URL, endpoint)
+	}
+}
+
+func TestNoContentWrites(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, StrictResponses: true})
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return true
+		}
+		return false
+	})
+
+	model := client.Model("users", nil)
+	if doc, err := model.Update("user:1", map[string]interface{}{"name": "Bob"}); err != nil || len(doc) != 0 {
+		t.Errorf("Model update: expected empty result for 204, got %v, %v", doc, err)
+	}
+	if ok, err := model.Delete("user:1"); err != nil || !ok {
+		t.Errorf("Model delete: expected success for 204, got %v, %v", ok, err)
+	}
+
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+	if err := users.Save(&TestUser{ID: "user:1", Name: "Bob"}); err != nil {
+		t.Errorf("Collection save: expected 204 to succeed, got %v", err)
+	}
+	if err := users.Delete("user:1"); err != nil {
+		t.Errorf("Collection delete: expected 204 to succeed, got %v", err)
+	}
+}
+
+func TestPartialContentPassthrough(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, StrictResponses: true})
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if strings.HasSuffix(r.URL.Path, "/query") {
+			writeJSON(w, http.StatusPartialContent, map[string]interface{}{
+				"count":     1,
+				"documents": []interface{}{map[string]interface{}{"id": "user:1", "name": "Alice"}},
+			})
+			return true
+		}
+		return false
+	})
+
+	docs, err := client.Model("users", nil).Query().Where("name", "Alice").Exec()
+	if err != nil || len(docs) != 1 || docs[0]["id"] != "user:1" {
+		t.Errorf("Expected 206 documents to pass through, got %v, %v", docs, err)
+	}
+
+	// Other failures still fail
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		writeJSON(w, http.StatusMultipleChoices, map[string]interface{}{})
+		return true
+	})
+	if _, err := client.Model("users", nil).Query().Exec(); err == nil || !strings.Contains(err.Error(), "status 300") {
+		t.Errorf("Expected 300 to fail, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package torm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// TestNotification is a document created from templates
+type TestNotification struct {
+	ID      string                 `json:"id"`
+	UserID  string                 `json:"user_id,omitempty"`
+	Kind    string                 `json:"kind,omitempty"`
+	Title   string                 `json:"title,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+func (n *TestNotification) GetID() string   { return n.ID }
+func (n *TestNotification) SetID(id string) { n.ID = id }
+func (n *TestNotification) ToMap() map[string]interface{} {
+	raw, _ := json.Marshal(n)
+	var doc map[string]interface{}
+	json.Unmarshal(raw, &doc)
+	return doc
+}
+
+func newNotifications(client *torm.Client) *torm.Collection[*TestNotification] {
+	return torm.NewCollection(client, "notifications", func() *TestNotification { return &TestNotification{} })
+}
+
+var alertTemplate = map[string]interface{}{
+	"kind":  "alert",
+	"title": "Heads up",
+	"payload": map[string]interface{}{
+		"level":   "info",
+		"channel": "email",
+		"meta":    map[string]interface{}{"a": 1, "b": 2},
+	},
+}
+
+func TestCreateFromTemplateDeepMerge(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	notifications := newNotifications(client)
+
+	template, err := notifications.DefineTemplate("alert", alertTemplate)
+	if err != nil || template.Version != 1 {
+		t.Fatalf("DefineTemplate failed: %v, %v", template, err)
+	}
+
+	created, err := notifications.CreateFromTemplate("alert", map[string]interface{}{
+		"id":      "notification:1",
+		"user_id": "user:1",
+		"title":   nil,
+		"payload": map[string]interface{}{
+			"level":   "warn",
+			"channel": nil,
+			"meta":    map[string]interface{}{"b": nil, "c": 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateFromTemplate failed: %v", err)
+	}
+	if created.Kind != "alert" || created.Title != "" || created.UserID != "user:1" {
+		t.Errorf("Unexpected created notification: %+v", created)
+	}
+
+	stored, _ := ms.doc("notifications", "notification:1")
+	raw, _ := json.Marshal(stored)
+	want := `{"id":"notification:1","kind":"alert","payload":{"level":"warn","meta":{"a":1,"c":3}},"user_id":"user:1"}`
+	if string(raw) != want {
+		t.Errorf("Unexpected stored document:\n got %s\nwant %s", raw, want)
+	}
+
+	// The template itself is left untouched by merges
+	again, err := notifications.CreateFromTemplate("alert", map[string]interface{}{"id": "notification:2"})
+	if err != nil || again.Title != "Heads up" || again.Payload["channel"] != "email" {
+		t.Errorf("Expected the base template unchanged, got %+v, %v", again, err)
+	}
+
+	if _, err := notifications.CreateFromTemplate("missing", nil); !errors.Is(err, torm.ErrUnknownTemplate) {
+		t.Errorf("Expected ErrUnknownTemplate, got %v", err)
+	}
+}
+
+func TestCreateManyFromTemplate(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	notifications := newNotifications(client)
+
+	if _, err := notifications.DefineTemplate("alert", alertTemplate); err != nil {
+		t.Fatalf("DefineTemplate failed: %v", err)
+	}
+
+	overrides := []map[string]interface{}{
+		{"id": "notification:1", "user_id": "user:1"},
+		{"id": "notification:2", "user_id": "user:2"},
+		{"id": "notification:3", "user_id": "user:3", "payload": map[string]interface{}{"level": "error"}},
+	}
+	created, err := notifications.CreateManyFromTemplate("alert", overrides)
+	if err != nil || len(created) != 3 {
+		t.Fatalf("CreateManyFromTemplate failed: %d created, %v", len(created), err)
+	}
+	if stored, _ := ms.doc("notifications", "notification:3"); stored["payload"].(map[string]interface{})["level"] != "error" {
+		t.Errorf("Unexpected third notification: %v", stored)
+	}
+	if n := ms.countRequests("GET", "/api/keys/torm:template:notifications:alert:version"); n != 1 {
+		t.Errorf("Expected one version check for the batch, got %d", n)
+	}
+	if n := ms.countRequests("GET", "/api/keys/torm:template:notifications:alert"); n != 1 {
+		t.Errorf("Expected the template fetched only for DefineTemplate, got %d", n)
+	}
+}
+
+func TestTemplateUpdates(t *testing.T) {
+	ms := newMockServer(t)
+	writer := newNotifications(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}))
+	reader := newNotifications(torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL}))
+
+	if _, err := writer.DefineTemplate("alert", alertTemplate); err != nil {
+		t.Fatalf("DefineTemplate failed: %v", err)
+	}
+	if _, err := reader.CreateFromTemplate("alert", map[string]interface{}{"id": "notification:1"}); err != nil {
+		t.Fatalf("CreateFromTemplate failed: %v", err)
+	}
+	if _, err := reader.CreateFromTemplate("alert", map[string]interface{}{"id": "notification:2"}); err != nil {
+		t.Fatalf("CreateFromTemplate failed: %v", err)
+	}
+	if n := ms.countRequests("GET", "/api/keys/torm:template:notifications:alert"); n != 2 {
+		t.Errorf("Expected the reader to fetch the template once, got %d fetches in total", n)
+	}
+
+	template, err := writer.DefineTemplate("alert", map[string]interface{}{"kind": "digest", "title": "Weekly"})
+	if err != nil || template.Version != 2 {
+		t.Fatalf("Expected version 2, got %v, %v", template, err)
+	}
+
+	// The reader's cached copy is stale and gets replaced
+	created, err := reader.CreateFromTemplate("alert", map[string]interface{}{"id": "notification:3"})
+	if err != nil || created.Kind != "digest" || created.Payload != nil {
+		t.Errorf("Expected the new template version, got %+v, %v", created, err)
+	}
+}
@@ -0,0 +1,144 @@
+package torm_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedTimedEvents seeds one event per timestamp, with amounts 1, 2, 3...
+func seedTimedEvents(ms *mockServer, at ...string) {
+	for i, ts := range at {
+		ms.seed("events", map[string]interface{}{
+			"id":     "event:" + string(rune('a'+i)),
+			"at":     ts,
+			"amount": float64(i + 1),
+		})
+	}
+}
+
+func TestBucketByTimeZeroFillsDays(t *testing.T) {
+	ms := newMockServer(t)
+	seedTimedEvents(ms,
+		"2026-03-01T08:00:00Z", "2026-03-01T23:59:59Z", // 1, 2
+		"2026-03-03T00:00:00Z", // 3
+		"2026-03-05T12:00:00Z", // 4
+		"2026-02-28T12:00:00Z", // 5, before the range
+		"2026-03-08T00:00:00Z", // 6, at the exclusive end
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	events := client.Model("events", nil)
+
+	from := time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	buckets, err := events.Query().BucketByTime("at", torm.IntervalDay, torm.BucketOptions{From: from, To: to, Sum: []string{"amount"}, Avg: []string{"amount"}})
+	if err != nil {
+		t.Fatalf("BucketByTime failed: %v", err)
+	}
+
+	counts := []int{2, 0, 1, 0, 1, 0, 0}
+	if len(buckets) != len(counts) {
+		t.Fatalf("Expected %d daily buckets, got %d", len(counts), len(buckets))
+	}
+	for i, bucket := range buckets {
+		start := time.Date(2026, 3, 1+i, 0, 0, 0, 0, time.UTC)
+		if !bucket.Start.Equal(start) || !bucket.End.Equal(start.AddDate(0, 0, 1)) {
+			t.Errorf("Bucket %d: expected [%s, %s), got [%s, %s)", i, start, start.AddDate(0, 0, 1), bucket.Start, bucket.End)
+		}
+		if bucket.Count != counts[i] {
+			t.Errorf("Bucket %d: expected %d events, got %d", i, counts[i], bucket.Count)
+		}
+	}
+	if first := buckets[0].Aggregates; first["sum(amount)"] != 3 || first["avg(amount)"] != 1.5 {
+		t.Errorf("Expected the first day to sum to 3 and average 1.5, got %v", first)
+	}
+	if empty := buckets[1].Aggregates; empty["sum(amount)"] != 0 {
+		t.Errorf("Expected an empty day to sum to 0, got %v", empty)
+	} else if _, ok := empty["avg(amount)"]; ok {
+		t.Errorf("Expected no average for an empty day, got %v", empty)
+	}
+
+	// The aggregation endpoint is probed once, then the scan is used directly
+	if _, err := events.Query().BucketByTime("at", torm.IntervalDay, torm.BucketOptions{From: from, To: to}); err != nil {
+		t.Fatal(err)
+	}
+	if probes := ms.countRequests("POST", "/api/events/_buckets"); probes != 1 {
+		t.Errorf("Expected the aggregation endpoint to be probed once, got %d", probes)
+	}
+}
+
+func TestBucketByTimeInLocation(t *testing.T) {
+	ms := newMockServer(t)
+	seedTimedEvents(ms,
+		"2026-03-02T03:00:00Z", // 22:00 on March 1st at UTC-5
+		"2026-03-02T06:00:00Z", // 01:00 on March 2nd
+		"2026-04-01T04:00:00Z", // 23:00 on March 31st
+	)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	events := client.Model("events", nil)
+	zone := time.FixedZone("UTC-5", -5*60*60)
+
+	days, err := events.Query().BucketByTime("at", torm.IntervalDay, torm.BucketOptions{Location: zone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 31 {
+		t.Fatalf("Expected the days from March 1st to 31st, got %d buckets", len(days))
+	}
+	if start := time.Date(2026, 3, 1, 0, 0, 0, 0, zone); !days[0].Start.Equal(start) || days[0].Count != 1 {
+		t.Errorf("Expected one event on the local March 1st, got %d from %s", days[0].Count, days[0].Start)
+	}
+	if days[1].Count != 1 || days[30].Count != 1 {
+		t.Errorf("Expected one event on March 2nd and 31st, got %d and %d", days[1].Count, days[30].Count)
+	}
+
+	months, err := events.Query().BucketByTime("at", torm.IntervalMonth, torm.BucketOptions{Location: zone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(months) != 1 || months[0].Count != 3 || !months[0].End.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, zone)) {
+		t.Errorf("Expected all three events in the local March, got %+v", months)
+	}
+
+	weeks, err := events.Query().BucketByTime("at", torm.IntervalWeek, torm.BucketOptions{Location: zone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sunday March 1st falls in the week starting Monday February 23rd
+	if start := time.Date(2026, 2, 23, 0, 0, 0, 0, zone); !weeks[0].Start.Equal(start) || weeks[0].Count != 1 || weeks[1].Count != 1 {
+		t.Errorf("Expected the first two weeks to hold one event each from %s, got %+v", start, weeks[:2])
+	}
+}
+
+func TestBucketByTimeUsesServerAggregation(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if !strings.HasSuffix(r.URL.Path, "/_buckets") {
+			return false
+		}
+		if body["interval"] != "hour" || body["from"] != "2026-03-01T10:00:00Z" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "unexpected request"})
+			return true
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"buckets": []interface{}{
+			map[string]interface{}{"start": "2026-03-01T11:00:00Z", "count": 4, "aggregates": map[string]interface{}{"sum(amount)": 10}},
+		}})
+		return true
+	})
+
+	from := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	buckets, err := client.Model("events", nil).Query().BucketByTime("at", torm.IntervalHour, torm.BucketOptions{From: from, To: from.Add(3 * time.Hour), Sum: []string{"amount"}})
+	if err != nil {
+		t.Fatalf("BucketByTime failed: %v", err)
+	}
+	if len(buckets) != 4 || buckets[0].Count != 0 || buckets[1].Count != 4 || buckets[1].Aggregates["sum(amount)"] != 10 {
+		t.Errorf("Expected the server's bucket zero-filled around, got %+v", buckets)
+	}
+	if queries := ms.countRequests("POST", "/api/events/query"); queries != 0 {
+		t.Errorf("Expected no scan when the server aggregates, got %d queries", queries)
+	}
+}
@@ -3,6 +3,7 @@ package torm_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/toonstore/torm-go"
 )
@@ -12,13 +13,21 @@ var (
 	testURL    string
 )
 
+// TestMain points the tests using testClient at the server at TORM_URL,
+// or at an in-memory mock server when it is unset
 func TestMain(m *testing.M) {
+	var ms *mockServer
 	testURL = os.Getenv("TORM_URL")
 	if testURL == "" {
-		testURL = "http://localhost:3001"
+		ms = startMockServer()
+		testURL = ms.URL
 	}
-	testClient = torm.NewClient(testURL)
-	os.Exit(m.Run())
+	testClient = torm.NewClient(&torm.ClientOptions{BaseURL: testURL, Timeout: 30 * time.Second})
+	code := m.Run()
+	if ms != nil {
+		ms.Close()
+	}
+	os.Exit(code)
 }
 
 // TestUser is a test model
@@ -79,7 +88,7 @@ func (p *TestProduct) ToMap() map[string]interface{} {
 }
 
 func TestClientCreation(t *testing.T) {
-	client := torm.NewClient(testURL)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: testURL})
 	if client == nil {
 		t.Fatal("Failed to create client")
 	}
@@ -215,17 +224,7 @@ func TestQueryWithFilter(t *testing.T) {
 	users.Create(&TestUser{ID: "test:user:8", Name: "Hannah", Email: "hannah@example.com", Age: 35})
 
 	// Query users older than 30
-	query := map[string]interface{}{
-		"filters": []map[string]interface{}{
-			{
-				"field":    "age",
-				"operator": "gt",
-				"value":    30,
-			},
-		},
-	}
-
-	results, err := users.Query(query)
+	results, err := users.Query().Filter("age", torm.Gt, 30).Exec()
 	if err != nil {
 		t.Fatalf("Failed to query users: %v", err)
 	}
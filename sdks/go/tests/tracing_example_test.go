@@ -0,0 +1,101 @@
+package torm_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+	"github.com/toonstore/torm-go/tests/otel/attribute"
+	"github.com/toonstore/torm-go/tests/otel/codes"
+	"github.com/toonstore/torm-go/tests/otel/propagation"
+	"github.com/toonstore/torm-go/tests/otel/trace"
+)
+
+// The OpenTelemetry adapter of the README, compiled against the stand-ins
+// in tests/otel. TestTracingReadmeMatchesExample keeps the two in step.
+
+type otelTracer struct{ tracer trace.Tracer }
+
+func (t otelTracer) Start(ctx context.Context, name string) (context.Context, torm.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, otelSpan{span}
+}
+
+func (t otelTracer) Inject(ctx context.Context, header http.Header) {
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type otelSpan struct{ span trace.Span }
+
+func (s otelSpan) End(attrs []torm.SpanAttribute, err error) {
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			s.span.SetAttributes(attribute.String(attr.Key, v))
+		case int:
+			s.span.SetAttributes(attribute.Int(attr.Key, v))
+		}
+	}
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func ExampleTracer() {
+	ms := startMockServer()
+	defer ms.Close()
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice"})
+	var traceparent string
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		traceparent = r.Header.Get("traceparent")
+		return false
+	})
+
+	provider := trace.NewProvider(os.Stdout)
+	client := torm.NewClient(&torm.ClientOptions{
+		BaseURL: ms.URL,
+		Tracer:  otelTracer{provider.Tracer("github.com/toonstore/torm-go")},
+	})
+	users := client.Model("users", nil)
+	users.FindByID("user:1")
+	fmt.Println("traceparent:", traceparent)
+	users.FindByID("user:2")
+	// Output:
+	// torm.users.find_by_id (client) torm.collection=users torm.operation=find_by_id torm.document_id=user:1 http.status_code=200
+	// traceparent: 00-00000000000000000000000000000001-0000000000000001-01
+	// torm.users.find_by_id (client) torm.collection=users torm.operation=find_by_id torm.document_id=user:2 http.status_code=404 error="torm: users.FindByID id=user:2: document not found"
+}
+
+func TestTracingReadmeMatchesExample(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	example, err := os.ReadFile("tracing_example_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	section := string(readme)
+	start := strings.Index(section, "### Tracing")
+	if start < 0 {
+		t.Fatal("README has no Tracing section")
+	}
+	section = section[start:]
+	begin := strings.Index(section, "```go\n")
+	end := strings.Index(section, "\nclient := ")
+	if begin < 0 || end < begin {
+		t.Fatal("Tracing section has no adapter snippet")
+	}
+	adapter := strings.TrimSpace(section[begin+len("```go\n") : end])
+	compiled := strings.ReplaceAll(string(example), "\t", "    ")
+	if !strings.Contains(compiled, adapter) {
+		t.Errorf("README adapter differs from the one in tracing_example_test.go:\n%s", adapter)
+	}
+}
@@ -0,0 +1,160 @@
+package torm_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// recordingTracer keeps every span it starts, numbering them from 1
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	id     int
+	parent int
+	name   string
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+type spanKey struct{}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, torm.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	span := &recordedSpan{id: len(r.spans) + 1, name: name}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.parent = parent.id
+	}
+	r.spans = append(r.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (r *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		header.Set("traceparent", fmt.Sprintf("00-4bf92f3577b34da6a3ce929d0e0e4736-%016x-01", span.id))
+	}
+}
+
+func (s *recordedSpan) End(attrs []torm.SpanAttribute, err error) {
+	s.attrs = make(map[string]interface{}, len(attrs))
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+	s.err, s.ended = err, true
+}
+
+func (r *recordingTracer) named(name string) []*recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []*recordedSpan
+	for _, span := range r.spans {
+		if span.name == name {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+func TestTracingSpans(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada", "age": float64(36)})
+	ms.seed("users", map[string]interface{}{"id": "user:2", "name": "Bob", "age": float64(41)})
+	tracer := &recordingTracer{}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Tracer: tracer})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	if _, err := users.FindByID("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	spans := tracer.named("torm.users.find_by_id")
+	if len(spans) != 1 || !spans[0].ended {
+		t.Fatalf("Expected one ended find_by_id span, got %+v", tracer.spans)
+	}
+	span := spans[0]
+	if span.attrs[torm.AttrCollection] != "users" || span.attrs[torm.AttrOperation] != "find_by_id" ||
+		span.attrs[torm.AttrDocumentID] != "user:1" || span.attrs[torm.AttrHTTPStatus] != http.StatusOK {
+		t.Errorf("Unexpected attributes %v", span.attrs)
+	}
+	log := ms.requestLog()
+	if got, want := log[len(log)-1].Header.Get("traceparent"), fmt.Sprintf("00-4bf92f3577b34da6a3ce929d0e0e4736-%016x-01", span.id); got != want {
+		t.Errorf("Expected the request to carry traceparent %q, got %q", want, got)
+	}
+
+	found, err := client.Model("users", nil).Query().Filter("age", torm.Gt, 30).Exec()
+	if err != nil || len(found) != 2 {
+		t.Fatalf("Expected two users, got %v, %v", found, err)
+	}
+	if spans := tracer.named("torm.users.query"); len(spans) != 1 || spans[0].attrs[torm.AttrResultCount] != 2 || spans[0].attrs[torm.AttrQuery] == nil {
+		t.Errorf("Expected a query span counting two results, got %+v", spans)
+	}
+
+	_, err = users.FindByID("user:404")
+	spans = tracer.named("torm.users.find_by_id")
+	if len(spans) != 2 || spans[1].err != err || spans[1].attrs[torm.AttrHTTPStatus] != http.StatusNotFound {
+		t.Errorf("Expected the failed lookup's span to carry its error and status, got %+v", spans[1])
+	}
+}
+
+func TestTracingNestsSpans(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Ada", "age": float64(36)})
+	tracer := &recordingTracer{}
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Tracer: tracer})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	// The caller's span is the parent of the operation's
+	ctx, parent := tracer.Start(context.Background(), "handler")
+	n, err := users.CountWith(ctx, torm.CountOptions{})
+	parent.End(nil, nil)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected one user, got %d, %v", n, err)
+	}
+	spans := tracer.named("torm.users.count")
+	if len(spans) != 1 || spans[0].parent != 1 || spans[0].attrs[torm.AttrResultCount] != 1 {
+		t.Fatalf("Expected a count span under the handler, got %+v", spans)
+	}
+
+	// Operations made by an operation are its children: matching encoded
+	// documents queries them
+	encoded := torm.NewCollection(client, "people", func() *TestUser { return &TestUser{} }, torm.WithCodec(envelopeCodec{version: 1}))
+	if _, err := encoded.Create(&TestUser{ID: "user:2", Name: "Bob", Age: 41}); err != nil {
+		t.Fatal(err)
+	}
+	found, err := encoded.FindFiltered(map[string]interface{}{"name": "Bob"})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Expected Bob, got %v, %v", found, err)
+	}
+	find, query := tracer.named("torm.people.find"), tracer.named("torm.people.query")
+	if len(find) != 1 || find[0].parent != 0 || find[0].attrs[torm.AttrResultCount] != 1 {
+		t.Fatalf("Expected a root find span, got %+v", find)
+	}
+	if len(query) != 1 || query[0].parent != find[0].id {
+		t.Fatalf("Expected the query span under the find, got %+v", query)
+	}
+	log := ms.requestLog()
+	if got, want := log[len(log)-1].Header.Get("traceparent"), fmt.Sprintf("00-4bf92f3577b34da6a3ce929d0e0e4736-%016x-01", query[0].id); got != want {
+		t.Errorf("Expected the query to carry its own span, got %q", got)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	users.FindByID("user:1")
+	for _, req := range ms.requestLog() {
+		if req.Header.Get("traceparent") != "" {
+			t.Errorf("Expected no traceparent without a Tracer, got %q", req.Header.Get("traceparent"))
+		}
+	}
+}
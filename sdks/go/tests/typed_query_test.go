@@ -0,0 +1,92 @@
+package torm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedQueryUsers(ms *mockServer) {
+	ms.seed("users",
+		map[string]interface{}{"id": "user:1", "name": "Alice", "age": 30, "active": true},
+		map[string]interface{}{"id": "user:2", "name": "Bob", "age": 25, "active": true},
+		map[string]interface{}{"id": "user:3", "name": "Carol", "age": 30, "active": false},
+		map[string]interface{}{"id": "user:4", "name": "Dave", "age": 41, "active": true},
+		map[string]interface{}{"id": "user:5", "name": "Erin", "age": 30, "active": true},
+	)
+}
+
+func TestTypedQueryExec(t *testing.T) {
+	ms := newMockServer(t)
+	seedQueryUsers(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	found, err := users.Query().Where("age", 30).Filter("active", torm.Eq, true).Sort("name", torm.Desc).Exec()
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if len(found) != 2 || found[0].Name != "Erin" || found[1].Name != "Alice" || found[1].Age != 30 {
+		t.Errorf("Expected Erin then Alice, got %+v", found)
+	}
+
+	page, err := users.Query().Filter("active", torm.Eq, true).Sort("age", torm.Asc).Skip(1).Limit(2).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].Age != 30 || page[1].Age != 30 {
+		t.Errorf("Expected the two 30-year-olds after Bob, got %+v", page)
+	}
+
+	n, err := users.Query().Where("age", 30).Count()
+	if err != nil || n != 3 {
+		t.Errorf("Expected 3 matches, got %d, %v", n, err)
+	}
+}
+
+func TestTypedQueryAppliesGuard(t *testing.T) {
+	ms := newMockServer(t)
+	seedQueryUsers(ms)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	hideBob := torm.WithGuard(func(op torm.Operation, doc map[string]interface{}) error {
+		if doc["name"] == "Bob" {
+			return errors.New("hidden")
+		}
+		return nil
+	})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} }, hideBob)
+
+	found, err := users.Query().Filter("age", torm.Lt, 40).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range found {
+		if user.Name == "Bob" {
+			t.Error("Expected the guard to withhold Bob")
+		}
+	}
+	if len(found) != 3 {
+		t.Errorf("Expected 3 users, got %d", len(found))
+	}
+}
+
+func TestTypedQueryDecodeFailure(t *testing.T) {
+	ms := newMockServer(t)
+	seedQueryUsers(ms)
+	ms.seed("users", map[string]interface{}{"id": "user:6", "name": "Frank", "age": "thirty"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	_, err := users.Query().Sort("name", torm.Asc).Exec()
+	var hydration *torm.HydrationError
+	if !errors.As(err, &hydration) {
+		t.Fatalf("Expected a HydrationError, got %v", err)
+	}
+	if hydration.ID != "user:6" || hydration.Index != 5 {
+		t.Errorf("Expected document 5 (user:6) to be reported, got %+v", hydration)
+	}
+	if torm.ErrorCategory(err) != torm.CategoryContract {
+		t.Errorf("Expected a contract error, got %s", torm.ErrorCategory(err))
+	}
+}
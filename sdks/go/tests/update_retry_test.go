@@ -0,0 +1,130 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+// interferingWriter changes a document's title before the first n calls
+// of the given method, as a concurrent writer would
+func interferingWriter(ms *mockServer, method string, n int) func() int {
+	var mu sync.Mutex
+	calls := 0
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != method || r.URL.Path != "/api/docs/doc:1" {
+			return false
+		}
+		mu.Lock()
+		calls++
+		interfere := calls <= n
+		mu.Unlock()
+		if interfere {
+			current, _ := ms.doc("docs", "doc:1")
+			ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": current["title"].(string) + "!"})
+		}
+		return false
+	})
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func appendTenant(doc *TenantDoc) (*TenantDoc, error) {
+	updated := *doc
+	updated.Title += " (" + doc.Tenant + ")"
+	return &updated, nil
+}
+
+func TestUpdateWithRetryConverges(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableDocumentETags()
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "Plan"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	docs := newTenantDocs(client)
+	puts := interferingWriter(ms, "PUT", 2)
+
+	seen := 0
+	result, err := docs.UpdateWithRetry(context.Background(), "doc:1", func(doc *TenantDoc) (*TenantDoc, error) {
+		seen++
+		return appendTenant(doc)
+	}, &torm.UpdateRetryOptions{Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("UpdateWithRetry failed: %v", err)
+	}
+	if result.Title != "Plan!! (a)" || seen != 3 || puts() != 3 {
+		t.Errorf("Expected the third attempt to apply over both changes, got %q after %d mutations and %d saves", result.Title, seen, puts())
+	}
+	if stored, _ := ms.doc("docs", "doc:1"); stored["title"] != "Plan!! (a)" {
+		t.Errorf("Unexpected stored document: %v", stored)
+	}
+}
+
+func TestUpdateWithRetryWithoutETags(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "Plan"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	docs := newTenantDocs(client)
+
+	// Every attempt reads twice; the change lands between the two reads of
+	// the first attempt
+	var mu sync.Mutex
+	reads := 0
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method == http.MethodGet {
+			mu.Lock()
+			reads++
+			if reads == 2 {
+				ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "Plan!"})
+			}
+			mu.Unlock()
+		}
+		return false
+	})
+
+	result, err := docs.UpdateWithRetry(context.Background(), "doc:1", func(doc *TenantDoc) (*TenantDoc, error) {
+		return appendTenant(doc)
+	}, &torm.UpdateRetryOptions{Backoff: time.Millisecond})
+	if err != nil || result.Title != "Plan! (a)" {
+		t.Fatalf("Expected the retry to apply over the change, got %+v: %v", result, err)
+	}
+	if n := ms.countRequests("PUT", "/api/docs/doc:1"); n != 1 {
+		t.Errorf("Expected the conflicting attempt not to write, got %d writes", n)
+	}
+}
+
+func TestUpdateWithRetryExhausted(t *testing.T) {
+	ms := newMockServer(t)
+	ms.enableDocumentETags()
+	ms.seed("docs", map[string]interface{}{"id": "doc:1", "tenant": "a", "title": "Plan"})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	docs := newTenantDocs(client)
+	interferingWriter(ms, "PUT", 100)
+
+	_, err := docs.UpdateWithRetry(context.Background(), "doc:1", appendTenant, &torm.UpdateRetryOptions{Attempts: 3, Backoff: time.Millisecond})
+	var exhausted *torm.ConflictExhaustedError
+	if !errors.As(err, &exhausted) || !errors.Is(err, torm.ErrVersionConflict) || !torm.IsConflict(err) {
+		t.Fatalf("Expected ConflictExhaustedError, got %v", err)
+	}
+	if exhausted.Attempts != 3 || exhausted.Version == "" || exhausted.LastSeen["title"] != "Plan!!" {
+		t.Errorf("Unexpected exhaustion detail: %+v", exhausted)
+	}
+
+	mutateErr := errors.New("refused")
+	_, err = docs.UpdateWithRetry(context.Background(), "doc:1", func(*TenantDoc) (*TenantDoc, error) {
+		return nil, mutateErr
+	}, nil)
+	if !errors.Is(err, mutateErr) {
+		t.Errorf("Expected the mutation error, got %v", err)
+	}
+	if _, err := docs.UpdateWithRetry(context.Background(), "doc:missing", appendTenant, nil); !torm.IsNotFound(err) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package torm_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func TestCollectionUpdate(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice", "email": "alice@example.com", "age": float64(30)})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	user := &TestUser{ID: "user:1", Name: "Alice", Email: "alice@example.com", Age: 31}
+	updated, err := users.Update("user:1", user)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated == user {
+		t.Error("Expected a fresh document from the factory")
+	}
+	if updated.Age != 31 || updated.Name != "Alice" {
+		t.Errorf("Expected the stored copy, got %+v", updated)
+	}
+	if n := ms.countRequests("PUT", "/api/users/user:1"); n != 1 {
+		t.Errorf("Expected one PUT, got %d", n)
+	}
+	if stored, _ := ms.doc("users", "user:1"); stored["age"] != float64(31) {
+		t.Errorf("Expected the server to store age 31, got %v", stored)
+	}
+}
+
+func TestCollectionUpdateKeepsMergedFields(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("users", map[string]interface{}{"id": "user:1", "name": "Alice", "website": "https://alice.dev"})
+	// A server that merges the payload into the stored document
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) bool {
+		if r.Method != http.MethodPut {
+			return false
+		}
+		merged, _ := ms.doc("users", "user:1")
+		for field, value := range body["data"].(map[string]interface{}) {
+			merged[field] = value
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "data": merged})
+		return true
+	})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	// Website is left empty, so ToMap omits it
+	updated, err := users.Update("user:1", &TestUser{Name: "Alice B."})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Website != "https://alice.dev" || updated.Name != "Alice B." || updated.ID != "user:1" {
+		t.Errorf("Expected the merged document, got %+v", updated)
+	}
+}
+
+func TestCollectionUpdateNotFound(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	_, err := users.Update("user:404", &TestUser{Name: "Nobody"})
+	if !errors.Is(err, torm.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	if want := "torm: users.Update id=user:404: document not found"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
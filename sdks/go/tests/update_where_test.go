@@ -0,0 +1,142 @@
+package torm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// seedAges seeds n users aged 20, half of them in the "trial" plan
+func seedAges(ms *mockServer, n int) {
+	for i := 1; i <= n; i++ {
+		plan := "paid"
+		if i%2 == 0 {
+			plan = "trial"
+		}
+		ms.seed("users", map[string]interface{}{"id": fmt.Sprintf("user:%02d", i), "name": "User", "age": float64(20), "plan": plan})
+	}
+}
+
+// birthday ages a user and marks it, so the update is not idempotent but
+// can be recognized as applied
+func birthday(u *TestUser) (*TestUser, error) {
+	u.Age++
+	u.Website = "https://example.com/migrated"
+	return u, nil
+}
+
+func TestUpdateWhere(t *testing.T) {
+	ms := newMockServer(t)
+	seedAges(ms, 10)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	var batches []torm.UpdateProgress
+	report, err := users.UpdateWhere(context.Background(), map[string]interface{}{"plan": "trial"}, birthday, &torm.UpdateWhereOptions{
+		BatchSize: 2,
+		PageSize:  3,
+		Progress:  func(p torm.UpdateProgress) { batches = append(batches, p) },
+	})
+	if err != nil {
+		t.Fatalf("UpdateWhere failed: %v", err)
+	}
+	if report.Matched != 5 || report.Updated != 5 || len(report.FailedIDs) != 0 || report.Resumed {
+		t.Errorf("Expected 5 trial users updated, got %+v", report)
+	}
+	if len(batches) != 3 || batches[2].LastID != "user:10" {
+		t.Errorf("Expected batches of 2, 2 and 1, got %+v", batches)
+	}
+	for i := 1; i <= 10; i++ {
+		doc, _ := ms.doc("users", fmt.Sprintf("user:%02d", i))
+		want := float64(20)
+		if i%2 == 0 {
+			want = 21
+		}
+		if doc["age"] != want {
+			t.Errorf("Expected user:%02d aged %v, got %v", i, want, doc["age"])
+		}
+	}
+}
+
+func TestUpdateWhereResumesFromCheckpoint(t *testing.T) {
+	ms := newMockServer(t)
+	seedAges(ms, 12)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	// The first run updates user:01 to user:06, checkpoints after user:05
+	// and fails on user:07
+	errDeploy := errors.New("deploy")
+	interrupted := func(u *TestUser) (*TestUser, error) {
+		if u.ID == "user:07" {
+			return nil, errDeploy
+		}
+		return birthday(u)
+	}
+	opts := &torm.UpdateWhereOptions{
+		BatchSize:  5,
+		Checkpoint: "birthdays",
+		Applied:    func(doc map[string]interface{}) bool { return doc["website"] != nil },
+	}
+	report, err := users.UpdateWhere(context.Background(), nil, interrupted, opts)
+	if !errors.Is(err, errDeploy) {
+		t.Fatalf("Expected the injected failure, got %v", err)
+	}
+	if report.Updated != 6 {
+		t.Errorf("Expected 6 updates before the failure, got %+v", report)
+	}
+	if _, found, _ := client.GetKey("torm:update-where:birthdays"); !found {
+		t.Fatal("Expected the checkpoint to be saved")
+	}
+
+	// A different update cannot take over the token
+	if _, err := users.UpdateWhere(context.Background(), map[string]interface{}{"plan": "trial"}, birthday, opts); err == nil {
+		t.Error("Expected a checkpoint of other filters to be rejected")
+	}
+
+	report, err = users.UpdateWhere(context.Background(), nil, birthday, opts)
+	if err != nil {
+		t.Fatalf("Resumed UpdateWhere failed: %v", err)
+	}
+	if !report.Resumed || report.Updated != 11 || report.Skipped != 1 {
+		t.Errorf("Expected 5 checkpointed and 6 resumed updates with user:06 skipped, got %+v", report)
+	}
+	for i := 1; i <= 12; i++ {
+		id := fmt.Sprintf("user:%02d", i)
+		if n := ms.countRequests("PUT", "/api/users/"+id); n != 1 {
+			t.Errorf("Expected %s written once, got %d", id, n)
+		}
+		if doc, _ := ms.doc("users", id); doc["age"] != float64(21) {
+			t.Errorf("Expected %s updated exactly once, got age %v", id, doc["age"])
+		}
+	}
+	if _, found, _ := client.GetKey("torm:update-where:birthdays"); found {
+		t.Error("Expected the checkpoint to be cleared on completion")
+	}
+}
+
+func TestUpdateWhereReportsFailedWrites(t *testing.T) {
+	ms := newMockServer(t)
+	seedAges(ms, 4)
+	ms.setIntercept(func(w http.ResponseWriter, r *http.Request, _ map[string]interface{}) bool {
+		if r.Method != http.MethodPut || r.URL.Path != "/api/users/user:02" {
+			return false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "disk full"})
+		return true
+	})
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := torm.NewCollection(client, "users", func() *TestUser { return &TestUser{} })
+
+	report, err := users.UpdateWhere(context.Background(), nil, birthday, &torm.UpdateWhereOptions{Checkpoint: "partial"})
+	if err != nil {
+		t.Fatalf("Expected failed writes not to stop the run, got %v", err)
+	}
+	if report.Updated != 3 || len(report.FailedIDs) != 1 || report.FailedIDs[0] != "user:02" {
+		t.Errorf("Expected user:02 reported as failed, got %+v", report)
+	}
+}
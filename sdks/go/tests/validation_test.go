@@ -0,0 +1,177 @@
+package torm_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+func ingestSchema() map[string]torm.ValidationRule {
+	return map[string]torm.ValidationRule{
+		"name":    {Type: "str", Required: true, MinLength: torm.IntPtr(2), MaxLength: torm.IntPtr(20)},
+		"email":   {Type: "str", Required: true, Email: true},
+		"website": {URL: true},
+		"code":    {Type: "str", Pattern: `^[A-Z]{3}-\d+$`},
+		"broken":  {Pattern: `[unclosed`},
+		"age":     {Type: "int", Min: torm.Float64Ptr(0), Max: torm.Float64Ptr(150)},
+		"score":   {Min: torm.Float64Ptr(0.5)},
+		"price":   {Type: "decimal", Scale: torm.IntPtr(2), MinDecimal: "0", Coerce: true},
+		"tags":    {Type: "array"},
+		"even":    {Validate: func(v interface{}) bool { n, ok := v.(int); return ok && n%2 == 0 }},
+	}
+}
+
+func ingestCorpus() []map[string]interface{} {
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{"name": "Alice", "email": "alice@example.com", "age": 30}
+	}
+	with := func(field string, value interface{}) map[string]interface{} {
+		doc := valid()
+		doc[field] = value
+		return doc
+	}
+	without := func(field string) map[string]interface{} {
+		doc := valid()
+		delete(doc, field)
+		return doc
+	}
+
+	return []map[string]interface{}{
+		valid(),
+		without("name"),
+		without("email"),
+		with("name", "A"),
+		with("name", strings.Repeat("a", 21)),
+		with("name", 42),
+		with("email", "not-an-email"),
+		with("website", "ftp://example.com"),
+		with("website", "https://example.com"),
+		with("code", "ABC-12"),
+		with("code", "abc-12"),
+		with("broken", "anything"),
+		with("broken", 3),
+		with("age", -1),
+		with("age", 151),
+		with("age", 3.5),
+		with("score", 0.25),
+		with("score", "high"),
+		with("price", 12.5),
+		with("price", "12.345"),
+		with("price", "-1"),
+		with("tags", []interface{}{"a"}),
+		with("tags", "a"),
+		with("even", 4),
+		with("even", 3),
+		{"name": "A", "email": "bad", "age": 200, "tags": 1},
+		{},
+	}
+}
+
+func copyDoc(doc map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		copied[k] = v
+	}
+	return copied
+}
+
+func errText(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+func TestCompiledValidationMatchesInterpreted(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{})
+	interpreted := client.Model("users", ingestSchema())
+	compiled := client.Model("users", ingestSchema()).CompileSchema()
+
+	for i, doc := range ingestCorpus() {
+		for _, profile := range []torm.ValidationProfile{torm.ValidationFastFail, torm.ValidationFull, torm.ValidationOff} {
+			a, b := copyDoc(doc), copyDoc(doc)
+			errA := interpreted.Validate(a, profile)
+			errB := compiled.Validate(b, profile)
+
+			if errText(errA) != errText(errB) {
+				t.Errorf("Document %d, profile %d: interpreted %q, compiled %q", i, profile, errText(errA), errText(errB))
+			}
+			if errA != nil && !errors.Is(errB, torm.ErrValidation) {
+				t.Errorf("Document %d: expected ErrValidation, got %v", i, errB)
+			}
+			if !reflect.DeepEqual(a, b) {
+				t.Errorf("Document %d, profile %d: coerced documents differ: %v vs %v", i, profile, a, b)
+			}
+		}
+	}
+}
+
+func TestValidationProfiles(t *testing.T) {
+	client := torm.NewClient(&torm.ClientOptions{})
+	users := client.Model("users", ingestSchema())
+	doc := map[string]interface{}{"name": "A", "email": "bad", "age": 200}
+
+	// Fields are checked in name order, so fast-fail always reports age
+	err := users.Validate(copyDoc(doc), torm.ValidationFastFail)
+	if errText(err) != "validation error: field 'age' must be at most 150" {
+		t.Errorf("Unexpected fast-fail error: %v", err)
+	}
+
+	err = users.Validate(copyDoc(doc), torm.ValidationFull)
+	for _, field := range []string{"age", "email", "name"} {
+		if !strings.Contains(errText(err), "field '"+field+"'") {
+			t.Errorf("Expected the full report to include %s, got %v", field, err)
+		}
+	}
+	if !errors.Is(err, torm.ErrValidation) {
+		t.Errorf("Expected ErrValidation, got %v", err)
+	}
+
+	if err := users.Validate(copyDoc(doc), torm.ValidationOff); err != nil {
+		t.Errorf("Expected no validation, got %v", err)
+	}
+}
+
+func TestCreateWithValidationProfile(t *testing.T) {
+	ms := newMockServer(t)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	users := client.Model("users", ingestSchema()).CompileSchema()
+
+	invalid := map[string]interface{}{"id": "user:1", "name": "A", "email": "alice@example.com"}
+	if _, err := users.CreateWith(copyDoc(invalid), torm.CreateOptions{}); !errors.Is(err, torm.ErrValidation) {
+		t.Errorf("Expected the default profile to reject the document, got %v", err)
+	}
+	if _, err := users.CreateWith(copyDoc(invalid), torm.CreateOptions{Validation: torm.ValidationOff}); err != nil {
+		t.Fatalf("Expected validation to be skipped, got %v", err)
+	}
+	if _, ok := ms.doc("users", "user:1"); !ok {
+		t.Error("Expected the unvalidated document to be stored")
+	}
+}
+
+func benchmarkValidation(b *testing.B, model *torm.Model) {
+	corpus := ingestCorpus()
+	docs := make([]map[string]interface{}, b.N)
+	for i := range docs {
+		docs[i] = copyDoc(corpus[i%len(corpus)])
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		model.Validate(docs[i], torm.ValidationFastFail)
+	}
+}
+
+func BenchmarkValidationInterpreted(b *testing.B) {
+	client := torm.NewClient(&torm.ClientOptions{})
+	benchmarkValidation(b, client.Model("users", ingestSchema()))
+}
+
+func BenchmarkValidationCompiled(b *testing.B) {
+	client := torm.NewClient(&torm.ClientOptions{})
+	benchmarkValidation(b, client.Model("users", ingestSchema()).CompileSchema())
+}
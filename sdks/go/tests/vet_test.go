@@ -0,0 +1,167 @@
+package torm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/toonstore/torm-go"
+)
+
+// vetRegistry builds models and collections with one known problem per rule
+func vetRegistry(t *testing.T, ms *mockServer) []torm.VetSubject {
+	t.Helper()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+
+	accounts := client.Model("accounts", map[string]torm.ValidationRule{
+		"email": {Type: "string", Pattern: `^(a+)+@example\.com$`},
+		"code":  {Type: "string", Pattern: `([`},
+	})
+	events := client.Model("events", map[string]torm.ValidationRule{
+		"name":       {Type: "string"},
+		"attendees":  {Type: "int"},
+		"created_at": {Type: "string"},
+		"updated_at": {Type: "string"},
+	})
+
+	products := torm.NewCollection(client, "products", func() *TestProduct { return &TestProduct{} })
+	index, err := products.LoadIndex(context.Background(), "sku")
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	t.Cleanup(index.Close)
+
+	define := func(name string, preset torm.QueryPreset) {
+		if err := products.DefineQuery(name, preset); err != nil {
+			t.Fatalf("DefineQuery %s failed: %v", name, err)
+		}
+	}
+	define("cheap", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		return qb.Filter("price", torm.Lt, 5)
+	})
+	define("bySku", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		return qb.Where("sku", "A-1").Limit(10)
+	})
+	define("byName", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		return qb.Where("name", "Anvil").Limit(10)
+	})
+	define("broken", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		panic("preset bug")
+	})
+
+	return []torm.VetSubject{accounts, events, products}
+}
+
+func vetKey(f torm.VetFinding) string {
+	return f.Rule + " " + f.Collection + " " + f.Query + " " + f.Field
+}
+
+func TestVetFindsEachProblemOnce(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	ms.seed("events",
+		map[string]interface{}{"id": "event:1", "name": "Launch", "attendees": 12},
+		map[string]interface{}{"id": "event:2", "name": 7, "attendees": "many"},
+		map[string]interface{}{"id": "event:3", "name": 8, "attendees": 3},
+	)
+
+	findings, err := torm.Vet(context.Background(), vetRegistry(t, ms), torm.VetOptions{
+		Indexes: map[string][]string{"products": {"name"}},
+		Sample:  10,
+	})
+	if err != nil {
+		t.Fatalf("Vet failed: %v", err)
+	}
+
+	expected := map[string]torm.VetSeverity{
+		"catastrophic-pattern accounts  email":  torm.VetWarning,
+		"invalid-pattern accounts  code":        torm.VetError,
+		"missing-timestamps accounts  ":         torm.VetInfo,
+		"type-drift events  attendees":          torm.VetWarning,
+		"type-drift events  name":               torm.VetWarning,
+		"broken-query products broken ":         torm.VetError,
+		"unbounded-query products cheap ":       torm.VetWarning,
+		"unindexed-filter products cheap price": torm.VetWarning,
+	}
+	seen := make(map[string]int)
+	for _, finding := range findings {
+		key := vetKey(finding)
+		seen[key]++
+		severity, ok := expected[key]
+		if !ok {
+			t.Errorf("Unexpected finding: %s", finding)
+			continue
+		}
+		if finding.Severity != severity {
+			t.Errorf("Expected %s to be %s, got %s", key, severity, finding.Severity)
+		}
+		if finding.Message == "" {
+			t.Errorf("Expected a message for %s", key)
+		}
+	}
+	for key := range expected {
+		if seen[key] != 1 {
+			t.Errorf("Expected %q exactly once, got %d", key, seen[key])
+		}
+	}
+
+	// Ordered by collection, then rule
+	for i := 1; i < len(findings); i++ {
+		a, b := findings[i-1], findings[i]
+		if a.Collection > b.Collection || (a.Collection == b.Collection && a.Rule > b.Rule) {
+			t.Errorf("Findings out of order: %s before %s", a, b)
+		}
+	}
+}
+
+func TestVetStaticByDefault(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+	subjects := vetRegistry(t, ms)
+	before := len(ms.requestLog())
+
+	findings, err := torm.Vet(context.Background(), subjects, torm.VetOptions{})
+	if err != nil {
+		t.Fatalf("Vet failed: %v", err)
+	}
+	if after := len(ms.requestLog()); after != before {
+		t.Errorf("Expected no requests without Sample, got %d", after-before)
+	}
+	for _, finding := range findings {
+		if finding.Rule == torm.VetTypeDrift {
+			t.Errorf("Expected no live findings, got %s", finding)
+		}
+	}
+
+	// Without the extra index hint, the name filter is reported too
+	count := 0
+	for _, finding := range findings {
+		if finding.Rule == torm.VetUnindexedFilter && finding.Query == "byName" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected byName to be reported once, got %d", count)
+	}
+}
+
+func TestVetDisabledRules(t *testing.T) {
+	ms := newMockServer(t)
+	seedProducts(ms)
+
+	findings, err := torm.Vet(context.Background(), vetRegistry(t, ms), torm.VetOptions{
+		Disable: []string{torm.VetUnindexedFilter, torm.VetMissingTimestamps, torm.VetTypeDrift},
+		Sample:  10,
+	})
+	if err != nil {
+		t.Fatalf("Vet failed: %v", err)
+	}
+	for _, finding := range findings {
+		switch finding.Rule {
+		case torm.VetUnindexedFilter, torm.VetMissingTimestamps, torm.VetTypeDrift:
+			t.Errorf("Expected %s to be disabled, got %s", finding.Rule, finding)
+		}
+	}
+	if len(findings) != 4 {
+		t.Errorf("Expected the 4 remaining findings, got %v", findings)
+	}
+}
@@ -0,0 +1,142 @@
+package torm_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func seedTenantDocs(ms *mockServer, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("doc:%03d", i+1)
+		ms.seed("docs", map[string]interface{}{"id": ids[i], "tenant": "a", "title": fmt.Sprintf("Doc %d", i+1)})
+	}
+	return ids
+}
+
+func countDocReads(ms *mockServer) int {
+	reads := 0
+	for _, req := range ms.requestLog() {
+		if req.Method == "GET" && strings.HasPrefix(req.Path, "/api/docs/") {
+			reads++
+		}
+	}
+	return reads
+}
+
+func TestWarmServesReadsFromCache(t *testing.T) {
+	ms := newMockServer(t)
+	ids := seedTenantDocs(ms, 250)
+	clock := newFakeClock()
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL, Clock: clock.Now})
+	docs := newTenantDocs(client, torm.WithReadCache(torm.ReadCacheOptions{Size: 500, TTL: time.Minute}))
+
+	report, err := docs.Warm(context.Background(), append(ids, "doc:missing"))
+	if err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if len(report.Warmed) != 250 || len(report.Missing) != 1 || report.Missing[0] != "doc:missing" {
+		t.Errorf("Unexpected report: %d warmed, missing %v", len(report.Warmed), report.Missing)
+	}
+	if n := ms.countRequests("POST", "/api/docs/query"); n != 3 {
+		t.Errorf("Expected 3 batched queries, got %d", n)
+	}
+
+	for _, id := range ids {
+		doc, err := docs.FindByID(id)
+		if err != nil || doc.ID != id {
+			t.Fatalf("FindByID(%s) = %+v, %v", id, doc, err)
+		}
+	}
+	if reads := countDocReads(ms); reads != 0 {
+		t.Errorf("Expected warmed reads to skip the network, got %d requests", reads)
+	}
+	if stats := docs.ReadCacheStats(); stats.Hits != 250 || stats.Entries != 250 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// Expired entries and written documents are read again
+	docs.Save(&TenantDoc{ID: ids[0], Tenant: "a", Title: "changed"})
+	if doc, _ := docs.FindByID(ids[0]); doc.Title != "changed" {
+		t.Errorf("Expected a write to evict the cached copy, got %+v", doc)
+	}
+	clock.Advance(time.Minute)
+	docs.FindByID(ids[1])
+	if reads := countDocReads(ms); reads != 2 {
+		t.Errorf("Expected the written and the expired document to be read, got %d requests", reads)
+	}
+}
+
+func TestWarmRespectsEvictionShare(t *testing.T) {
+	ms := newMockServer(t)
+	ids := seedTenantDocs(ms, 20)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	docs := newTenantDocs(client, torm.WithReadCache(torm.ReadCacheOptions{Size: 10, WarmShare: 0.3}))
+
+	for _, id := range ids[:10] {
+		if _, err := docs.FindByID(id); err != nil {
+			t.Fatalf("FindByID failed: %v", err)
+		}
+	}
+
+	report, err := docs.Warm(context.Background(), ids[10:])
+	if err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if len(report.Warmed) != 3 || len(report.Skipped) != 7 {
+		t.Errorf("Expected 3 warmed and 7 skipped, got %+v", report)
+	}
+
+	// The 7 most recently read documents are still cached
+	before := countDocReads(ms)
+	for _, id := range ids[3:10] {
+		docs.FindByID(id)
+	}
+	if reads := countDocReads(ms) - before; reads != 0 {
+		t.Errorf("Expected recent reads to stay cached, got %d requests", reads)
+	}
+}
+
+func TestAutoWarm(t *testing.T) {
+	ms := newMockServer(t)
+	seedTenantDocs(ms, 5)
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	docs := newTenantDocs(client, torm.WithReadCache(torm.ReadCacheOptions{}))
+	docs.DefineQuery("hot", func(qb *torm.QueryBuilder) *torm.QueryBuilder {
+		return qb.Filter("id", torm.Lte, "doc:002")
+	})
+
+	var mu sync.Mutex
+	runs := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		docs.AutoWarm(ctx, "hot", 5*time.Millisecond, func(report *torm.WarmReport, err error) {
+			if err != nil || len(report.Warmed) != 2 {
+				t.Errorf("Unexpected warm: %+v, %v", report, err)
+			}
+			mu.Lock()
+			runs++
+			mu.Unlock()
+		})
+		close(done)
+	}()
+	waitFor(t, "repeated warms", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs >= 3
+	})
+	cancel()
+	<-done
+
+	docs.FindByID("doc:001")
+	if reads := countDocReads(ms); reads != 0 {
+		t.Errorf("Expected the hot document to be cached, got %d requests", reads)
+	}
+}
@@ -0,0 +1,97 @@
+package torm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/toonstore/torm-go"
+)
+
+func stamped(id, updatedAt string) map[string]interface{} {
+	return map[string]interface{}{"id": id, "updatedAt": updatedAt}
+}
+
+// nextEvents reads n events or fails the test after a timeout
+func nextEvents(t *testing.T, w *torm.Watcher, n int) []torm.ChangeEvent {
+	t.Helper()
+
+	events := make([]torm.ChangeEvent, 0, n)
+	for len(events) < n {
+		select {
+		case event, ok := <-w.Events():
+			if !ok {
+				t.Fatalf("Watch ended after %d events", len(events))
+			}
+			events = append(events, event)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out after %d events, err %v", len(events), w.Err())
+		}
+	}
+	return events
+}
+
+func eventIDs(events []torm.ChangeEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func TestWatchResumeAfterRestart(t *testing.T) {
+	ms := newMockServer(t)
+	ms.seed("orders",
+		stamped("a", "2024-01-01T00:00:01Z"),
+		stamped("b", "2024-01-01T00:00:02Z"),
+		stamped("c", "2024-01-01T00:00:02Z"),
+	)
+
+	client := torm.NewClient(&torm.ClientOptions{BaseURL: ms.URL})
+	orders := torm.NewCollection(client, "orders", func() *TestUser { return &TestUser{} })
+	opts := torm.WatchOptions{Interval: 10 * time.Millisecond}
+
+	// The first process stops after handling two events, mid-way through a
+	// timestamp, and persists the token of the last one
+	ctx, cancel := context.WithCancel(context.Background())
+	first := orders.Watch(ctx, opts)
+	events := nextEvents(t, first, 2)
+	cancel()
+
+	if got := eventIDs(events); got[0] != "a" || got[1] != "b" {
+		t.Fatalf("Expected a, b from the first watcher, got %v", got)
+	}
+	saved := events[1].Token
+
+	// Changes made while no watcher is running
+	ms.seed("orders",
+		stamped("d", "2024-01-01T00:00:03Z"),
+		stamped("a", "2024-01-01T00:00:04.5Z"),
+	)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	opts.ResumeAfter = saved
+	second := orders.Watch(ctx, opts)
+
+	got := eventIDs(nextEvents(t, second, 3))
+	if got[0] != "c" || got[1] != "d" || got[2] != "a" {
+		t.Errorf("Expected catch-up of c, d, a, got %v", got)
+	}
+
+	ms.seed("orders", stamped("e", "2024-01-01T00:00:05Z"))
+	if got = eventIDs(nextEvents(t, second, 1)); got[0] != "e" {
+		t.Errorf("Expected live event e, got %v", got)
+	}
+
+	// Several more polls pass without anything new
+	select {
+	case event := <-second.Events():
+		t.Errorf("Unexpected duplicate event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if second.Token() <= saved {
+		t.Errorf("Expected token to advance past %q, got %q", saved, second.Token())
+	}
+}
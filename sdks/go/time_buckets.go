@@ -0,0 +1,289 @@
+package torm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// CapabilityTimeBuckets is the server's time-bucketed aggregation endpoint
+const CapabilityTimeBuckets Capability = "time_buckets"
+
+// TimeInterval is the width of the buckets of BucketByTime
+type TimeInterval string
+
+const (
+	IntervalHour  TimeInterval = "hour"
+	IntervalDay   TimeInterval = "day"
+	IntervalWeek  TimeInterval = "week" // Weeks start on Monday
+	IntervalMonth TimeInterval = "month"
+)
+
+// BucketOptions configures BucketByTime
+type BucketOptions struct {
+	// From and To bound the buckets returned. From is rounded down to the
+	// start of its bucket and To is exclusive. A zero From or To uses the
+	// first or last matching document.
+	From, To time.Time
+	// Location sets where days, weeks and months begin (default UTC)
+	Location *time.Location
+	// UnixMillis says the field holds Unix milliseconds rather than RFC
+	// 3339 strings, so the range is filtered in that form
+	UnixMillis bool
+	Sum        []string // Fields summed per bucket, as Aggregates["sum(field)"]
+	Avg        []string // Fields averaged per bucket, as Aggregates["avg(field)"]
+}
+
+// TimeBucket is the documents whose time falls in [Start, End)
+type TimeBucket struct {
+	Start      time.Time
+	End        time.Time
+	Count      int
+	Aggregates map[string]float64 // Keyed "sum(field)" and "avg(field)"; averages of empty buckets are left out
+}
+
+// BucketByTime counts the documents matching the query per interval of
+// field, oldest first, with buckets in the range that have no documents
+// filled with zero counts. Servers with the aggregation endpoint compute
+// the buckets; otherwise the matching documents are read once, with only
+// the needed fields, and bucketed here. The query's own sort, skip and
+// limit are ignored. Stored times are compared with the range as stored,
+// so RFC 3339 strings should be in UTC.
+func (qb *QueryBuilder) BucketByTime(field string, interval TimeInterval, opts BucketOptions) ([]TimeBucket, error) {
+	switch interval {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+	default:
+		return nil, fmt.Errorf("unknown time interval %q", interval)
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && !opts.From.Before(opts.To) {
+		return nil, fmt.Errorf("bucket range from %s to %s is empty", opts.From, opts.To)
+	}
+
+	counted, served, err := qb.serverBuckets(field, interval, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !served {
+		if counted, err = qb.scanBuckets(field, interval, opts); err != nil {
+			return nil, fmt.Errorf("%w (query: %s)", err, qb)
+		}
+	}
+	return fillBuckets(counted, interval, opts), nil
+}
+
+// bucketTally accumulates one bucket. Tallies are keyed by the Unix
+// nanoseconds of the bucket start.
+type bucketTally struct {
+	count int
+	sums  map[string]float64
+	seen  map[string]int // Values averaged per field
+}
+
+func newBucketTally() *bucketTally {
+	return &bucketTally{sums: make(map[string]float64), seen: make(map[string]int)}
+}
+
+// scanBuckets reads the matching documents a page at a time and tallies
+// them by bucket start
+func (qb *QueryBuilder) scanBuckets(field string, interval TimeInterval, opts BucketOptions) (map[int64]*bucketTally, error) {
+	scan := qb.Clone()
+	scan.fields = append([]string{field}, opts.Sum...)
+	scan.fields = append(scan.fields, opts.Avg...)
+	if !opts.From.IsZero() {
+		scan.Filter(field, Gte, bucketBound(truncateTime(opts.From, interval, opts.Location), opts.UnixMillis))
+	}
+	if !opts.To.IsZero() {
+		scan.Filter(field, Lt, bucketBound(opts.To, opts.UnixMillis))
+	}
+
+	tallies := make(map[int64]*bucketTally)
+	for offset := 0; ; offset += iterPageSize {
+		docs, err := scan.pageAt(offset, iterPageSize).exec()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			at, ok := documentTime(doc, field)
+			if !ok {
+				continue
+			}
+			start := truncateTime(at, interval, opts.Location).UnixNano()
+			tally := tallies[start]
+			if tally == nil {
+				tally = newBucketTally()
+				tallies[start] = tally
+			}
+			tally.count++
+			for _, f := range opts.Sum {
+				if v, ok := toFloat64(doc[f]); ok {
+					tally.sums["sum("+f+")"] += v
+				}
+			}
+			for _, f := range opts.Avg {
+				if v, ok := toFloat64(doc[f]); ok {
+					tally.sums["avg("+f+")"] += v
+					tally.seen[f]++
+				}
+			}
+		}
+		if len(docs) < iterPageSize {
+			return tallies, nil
+		}
+	}
+}
+
+// serverBuckets asks the server's aggregation endpoint for the buckets.
+// The boolean is false when the server has no such endpoint.
+func (qb *QueryBuilder) serverBuckets(field string, interval TimeInterval, opts BucketOptions) (map[int64]*bucketTally, bool, error) {
+	if supported, known := qb.client.capabilities.lookup(CapabilityTimeBuckets); known && !supported {
+		return nil, false, nil
+	}
+
+	body := map[string]interface{}{
+		"field":    field,
+		"interval": interval,
+		"timezone": opts.Location.String(),
+		"filters":  qb.filters,
+		"sum":      opts.Sum,
+		"avg":      opts.Avg,
+	}
+	if !opts.From.IsZero() {
+		body["from"] = truncateTime(opts.From, interval, opts.Location).Format(time.RFC3339Nano)
+	}
+	if !opts.To.IsZero() {
+		body["to"] = opts.To.Format(time.RFC3339Nano)
+	}
+	resp, err := qb.client.request("POST", qb.client.apiPath(qb.collection, "_buckets"), body)
+	if err != nil {
+		return nil, false, fmt.Errorf("bucket by time failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusMethodNotAllowed, resp.StatusCode == http.StatusNotImplemented:
+		qb.client.capabilities.record(CapabilityTimeBuckets, false)
+		return nil, false, nil
+	case !isSuccess(resp.StatusCode):
+		return nil, false, responseError("bucket by time", resp)
+	}
+	qb.client.capabilities.record(CapabilityTimeBuckets, true)
+
+	var result struct {
+		Buckets []struct {
+			Start      string             `json:"start"`
+			Count      int                `json:"count"`
+			Aggregates map[string]float64 `json:"aggregates"`
+		} `json:"buckets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	tallies := make(map[int64]*bucketTally, len(result.Buckets))
+	for _, bucket := range result.Buckets {
+		at, err := time.Parse(time.RFC3339Nano, bucket.Start)
+		if err != nil {
+			return nil, false, fmt.Errorf("bucket by time: bad bucket start %q: %w", bucket.Start, err)
+		}
+		tally := newBucketTally()
+		tally.count = bucket.Count
+		for name, value := range bucket.Aggregates {
+			tally.sums[name] = value
+		}
+		for _, f := range opts.Avg {
+			// Server averages are final, so they count as a single value
+			if _, ok := bucket.Aggregates["avg("+f+")"]; ok {
+				tally.seen[f] = 1
+			}
+		}
+		tallies[truncateTime(at, interval, opts.Location).UnixNano()] = tally
+	}
+	return tallies, true, nil
+}
+
+// fillBuckets lists the buckets of the range in order, with zero counts
+// for those without documents
+func fillBuckets(tallies map[int64]*bucketTally, interval TimeInterval, opts BucketOptions) []TimeBucket {
+	var first, last time.Time
+	for nanos := range tallies {
+		start := time.Unix(0, nanos).In(opts.Location)
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if last.IsZero() || start.After(last) {
+			last = start
+		}
+	}
+	if !opts.From.IsZero() {
+		first = truncateTime(opts.From, interval, opts.Location)
+	}
+	end := nextBucket(last, interval)
+	if !opts.To.IsZero() {
+		end = opts.To
+	}
+	if first.IsZero() {
+		return []TimeBucket{}
+	}
+
+	buckets := make([]TimeBucket, 0)
+	for start := first; start.Before(end); start = nextBucket(start, interval) {
+		bucket := TimeBucket{Start: start, End: nextBucket(start, interval), Aggregates: make(map[string]float64)}
+		tally := tallies[start.UnixNano()]
+		if tally == nil {
+			tally = newBucketTally()
+		}
+		bucket.Count = tally.count
+		for _, f := range opts.Sum {
+			bucket.Aggregates["sum("+f+")"] = tally.sums["sum("+f+")"]
+		}
+		for _, f := range opts.Avg {
+			if n := tally.seen[f]; n > 0 {
+				bucket.Aggregates["avg("+f+")"] = tally.sums["avg("+f+")"] / float64(n)
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// truncateTime returns the start of the bucket holding t, in loc
+func truncateTime(t time.Time, interval TimeInterval, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// nextBucket returns the start of the bucket after the one starting at start
+func nextBucket(start time.Time, interval TimeInterval) time.Time {
+	switch interval {
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// bucketBound is a range bound in the form the field is stored in
+func bucketBound(t time.Time, unixMillis bool) interface{} {
+	if unixMillis {
+		return t.UnixMilli()
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -2,79 +2,208 @@
 package torm
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
 	"time"
-
-	"github.com/go-resty/resty/v2"
 )
 
-// Client is the TORM client for connecting to ToonStore
-type Client struct {
-	baseURL string
-	client  *resty.Client
-}
-
-// NewClient creates a new TORM client
-func NewClient(baseURL string) *Client {
-	if baseURL == "" {
-		baseURL = "http://localhost:3001"
-	}
-
-	return &Client{
-		baseURL: baseURL,
-		client:  resty.New().SetBaseURL(baseURL).SetTimeout(30 * time.Second),
-	}
-}
-
-// Model represents a base model interface
-type Model interface {
+// Document is implemented by the structs a Collection stores. It was
+// called Model before that name went to the schema-driven Model struct.
+type Document interface {
 	GetID() string
 	SetID(string)
 	ToMap() map[string]interface{}
 }
 
 // Collection provides CRUD operations for a model
-type Collection[T Model] struct {
+type Collection[T Document] struct {
 	client     *Client
 	collection string
 	factory    func() T
+	options    *collectionOptions
+	confirm    string   // See Confirm
+	trace      *opTimer // Operation whose span requests belong to, see traced
+	priority   Priority // See WithPriority and WithCallPriority
+}
+
+// CollectionOption configures a Collection
+type CollectionOption func(*collectionOptions)
+
+// collectionOptions holds per-collection settings shared by all document types
+type collectionOptions struct {
+	guard            Guard
+	guardRejections  atomic.Int64
+	onConflict       ConflictPolicy
+	provisionMatcher CollectionNotFoundMatcher
+	provisioned      atomic.Bool
+	retention        *RetentionPolicy
+	strictResponses  bool
+	codecs           codecChain
+	queries          namedQueries
+	slugs            []slugRule
+	readRepair       *readRepairer
+	indexes          indexRegistry
+	indexLimit       int
+	templates        templateCache
+	pagination       *PaginationOptions
+	countWindow      *time.Duration
+	collection       string // Set by NewCollection, for diagnostics
+	mirror           *mirror
+	readCache        *readCache
+	defaultOrder     *QuerySort // See WithDefaultOrder
+	serverOrder      bool
+	sortDefaults     *SortDefaults // See WithSortDefaults
+	deadLetter       DeadLetterSink
+	audit            *auditTrail
+	slowThreshold    *time.Duration // See WithSlowThreshold
+	fidelity         *fidelityCheck
+	redact           []string // See WithRedaction
+	ids              IDOptions
+	sanitizer        *sanitizer
+	autoMerge        int                  // Patch attempts; see WithAutoMergePatches
+	parity           *filterParity        // See WithFilterParityChecks
+	priority         Priority             // See WithPriority
+	bulkConcurrency  *AdaptiveConcurrency // See WithBulkConcurrency
 }
 
 // NewCollection creates a new collection handler
-func NewCollection[T Model](client *Client, collection string, factory func() T) *Collection[T] {
-	return &Collection[T]{
+func NewCollection[T Document](client *Client, collection string, factory func() T, opts ...CollectionOption) *Collection[T] {
+	options := &collectionOptions{collection: collection}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	c := &Collection[T]{
 		client:     client,
 		collection: collection,
 		factory:    factory,
+		options:    options,
+		priority:   options.priority,
+	}
+	if options.retention != nil {
+		client.registerRetention(c)
+	}
+	if options.audit != nil {
+		client.registerAudit(options.audit.opts.Collection)
+	}
+	if store, ok := options.deadLetter.(DeadLetterStore); ok {
+		client.registerDeadLetters(store)
 	}
+	if options.readCache != nil {
+		options.readCache.clock = client.now
+		options.indexes.add(options.readCache)
+		client.invalidation.track(collection, &options.indexes)
+		client.registerReadCache(collection, options.readCache)
+	}
+
+	return c
 }
 
-// Create creates a new document
+// Create creates a new document, applying the collection's conflict policy
 func (c *Collection[T]) Create(data T) (T, error) {
-	var result T
+	return c.create(data, c.options.onConflict)
+}
 
-	resp, err := c.client.client.R().
-		SetBody(map[string]interface{}{"data": data.ToMap()}).
-		SetResult(&struct {
-			Success bool                   `json:"success"`
-			ID      string                 `json:"id"`
-			Data    map[string]interface{} `json:"data"`
-		}{}).
-		Post(fmt.Sprintf("/api/%s", c.collection))
+// Upsert creates a document, replacing the stored one when the server reports a conflict
+func (c *Collection[T]) Upsert(data T) (T, error) {
+	return c.create(data, OnConflictOverwrite)
+}
 
+// GetOrCreate creates a document unless one already exists, in which case the
+// stored document is returned. The boolean reports whether a document was created.
+func (c *Collection[T]) GetOrCreate(data T) (T, bool, error) {
+	result, err := c.create(data, OnConflictError)
+
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		id := data.GetID()
+		if id == "" {
+			id = conflict.ExistingID
+		}
+		existing, err := c.FindByID(id)
+		return existing, false, err
+	}
+
+	return result, err == nil, err
+}
+
+func (c *Collection[T]) create(data T, policy ConflictPolicy) (result T, err error) {
+	timer := c.timeOp(OpCreate)
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
+
+	payload := data.ToMap()
+	if err := c.checkFidelity(data, payload); err != nil {
+		return result, err
+	}
+	payload, stripped, err := c.options.sanitizer.strip(c.collection, payload)
 	if err != nil {
 		return result, err
 	}
+	if err := applySlugs(c.options.slugs, c.model().Query, payload, "", nil); err != nil {
+		return result, err
+	}
+	if err := c.options.checkGuard(OpCreate, payload); err != nil {
+		return result, err
+	}
+
+	stored, err := c.options.codecs.encode(payload)
+	if err != nil {
+		return result, err
+	}
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.call("POST", c.client.apiPath(c.collection), map[string]interface{}{"data": stored})
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	if resp.StatusCode() == http.StatusConflict {
+		conflict := parseConflict(c.collection, resp.apiError("create")).redact(c.redactor(), payload)
+		switch policy {
+		case OnConflictIgnore:
+			return data, nil
+		case OnConflictOverwrite:
+			id := data.GetID()
+			if id == "" {
+				id = conflict.ExistingID
+			}
+			if id != "" {
+				timer.op.Attempts++
+				return c.replace(id, payload, stripped)
+			}
+		}
+		return result, conflict
+	}
 
 	if !resp.IsSuccess() {
-		return result, fmt.Errorf("failed to create document: %s", resp.Status())
+		return result, resp.apiError("create").redact(c.redactor(), payload)
+	}
+
+	// Accepted and empty responses carry no stored copy to decode
+	if !hasDocument(resp.StatusCode(), resp.Body()) {
+		if err := c.written(OpCreate, payload); err != nil {
+			return data, err
+		}
+		if c.options.ids.Required && idString(payload["id"]) == "" {
+			return data, fmt.Errorf("%w: create in %s returned no document", ErrMissingID, c.collection)
+		}
+		return data, nil
+	}
+
+	if err := c.checkContract("create", resp.Body(), writeShape); err != nil {
+		return result, err
 	}
 
 	// Parse response
 	var response struct {
 		Success bool                   `json:"success"`
-		ID      string                 `json:"id"`
 		Data    map[string]interface{} `json:"data"`
 	}
 
@@ -82,64 +211,235 @@ func (c *Collection[T]) Create(data T) (T, error) {
 		return result, err
 	}
 
-	// Convert back to model
-	jsonData, _ := json.Marshal(response.Data)
+	// Convert back to model, from what was sent when the server echoes nothing
+	doc := payload
+	if response.Data != nil {
+		if doc, err = c.options.codecs.decode(response.Data); err != nil {
+			return result, err
+		}
+	}
+	id := createdID(resp.Body())
+	if id != "" && idString(doc["id"]) == "" {
+		doc["id"] = id
+	}
+	if err := c.written(OpCreate, doc); err != nil {
+		return result, err
+	}
+	restore(doc, stripped)
 	result = c.factory()
-	if err := json.Unmarshal(jsonData, &result); err != nil {
+	if err := hydrate(doc, &result); err != nil {
 		return result, err
 	}
 
-	return result, nil
+	return result, c.propagateID(id, data, result)
 }
 
-// FindByID finds a document by ID
-func (c *Collection[T]) FindByID(id string) (T, error) {
+// replace overwrites a stored document and decodes the server's copy,
+// putting back the fields the sanitizer stripped from payload
+func (c *Collection[T]) replace(id string, payload, stripped map[string]interface{}) (T, error) {
 	var result T
 
-	resp, err := c.client.client.R().
-		SetResult(&map[string]interface{}{}).
-		Get(fmt.Sprintf("/api/%s/%s", c.collection, id))
+	stored, err := c.options.codecs.encode(payload)
+	if err != nil {
+		return result, err
+	}
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.call("PUT", c.client.apiPath(c.collection, id), map[string]interface{}{"data": stored})
+	})
 
 	if err != nil {
 		return result, err
 	}
 
-	if resp.StatusCode() == 404 {
-		return result, fmt.Errorf("document not found")
+	if resp.StatusCode() == http.StatusConflict {
+		return result, parseConflict(c.collection, resp.apiError("update")).redact(c.redactor(), payload)
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return result, notFound(c.collection, id)
 	}
 
 	if !resp.IsSuccess() {
-		return result, fmt.Errorf("failed to find document: %s", resp.Status())
+		return result, resp.apiError("update").redact(c.redactor(), payload)
 	}
 
+	// Without a stored copy the written payload stands in for it
+	doc := payload
+	if hasDocument(resp.StatusCode(), resp.Body()) {
+		if err := c.checkContract("update", resp.Body(), writeShape); err != nil {
+			return result, err
+		}
+
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(resp.Body(), &response); err != nil {
+			return result, err
+		}
+
+		doc, err = c.options.codecs.decode(response.Data)
+		if err != nil {
+			return result, err
+		}
+	}
+	if err := c.written(OpUpdate, doc); err != nil {
+		return result, err
+	}
+	restore(doc, stripped)
+
 	result = c.factory()
-	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+	if err := hydrate(doc, &result); err != nil {
 		return result, err
 	}
 
 	return result, nil
 }
 
-// Find finds all documents matching filters
-func (c *Collection[T]) Find(filters map[string]interface{}) ([]T, error) {
-	var response struct {
-		Collection string                   `json:"collection"`
-		Count      int                      `json:"count"`
-		Documents  []map[string]interface{} `json:"documents"`
+// FindByID finds a document by ID
+func (c *Collection[T]) FindByID(id string) (result T, err error) {
+	timer := c.timeOp(OpFindByID).target(id)
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
+
+	doc, body, err := c.findDocument(id)
+	if err != nil {
+		return result, err
+	}
+	result = c.factory()
+	if body == nil {
+		err = hydrate(doc, &result)
+		return result, err
+	}
+	if err := hydrateJSON(body, &result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// findDocument reads the document id as the server stores it. Served by
+// the read cache, it comes back as doc alone. Otherwise body holds it, and
+// so does doc once codecs, read repair and the guard have been applied,
+// for collections that configure them.
+func (c *Collection[T]) findDocument(id string) (doc map[string]interface{}, body []byte, err error) {
+	cache := c.options.readCache
+	if doc, ok := cache.get(id); ok {
+		if err := c.options.checkGuard(OpFindByID, doc); err != nil {
+			return nil, nil, err
+		}
+		return doc, nil, nil
 	}
 
-	var resp *resty.Response
-	var err error
+	var header http.Header
+	if etag := cache.validator(id); etag != "" {
+		header = http.Header{"If-None-Match": {etag}}
+	}
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.callWithHeader("GET", c.client.apiPath(c.collection, id), nil, header)
+	})
+	if err == nil && resp.StatusCode() == http.StatusNotModified {
+		if doc, ok := cache.revalidate(id); ok {
+			if err := c.options.checkGuard(OpFindByID, doc); err != nil {
+				return nil, nil, err
+			}
+			return doc, nil, nil
+		}
+		// Evicted while revalidating
+		resp, err = c.send(func() (*bufferedResponse, error) {
+			return c.call("GET", c.client.apiPath(c.collection, id), nil)
+		})
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode() == 404 {
+		cache.remove(id)
+		return nil, nil, notFound(c.collection, id)
+	}
+
+	if !resp.IsSuccess() {
+		return nil, nil, resp.apiError("find")
+	}
+
+	if err := c.checkContract("find", resp.Body(), documentShape); err != nil {
+		return nil, nil, err
+	}
+
+	body = resp.Body()
+	if c.options.guard != nil || len(c.options.codecs) > 0 || c.options.readRepair != nil || c.options.readCache != nil {
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return nil, nil, err
+		}
+		doc, err = c.options.codecs.decode(doc)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc, err = c.model().repairDoc(doc)
+		if err != nil {
+			return nil, nil, err
+		}
+		cache.store(id, resp.Header().Get("ETag"), doc)
+		if err := c.options.checkGuard(OpFindByID, doc); err != nil {
+			return nil, nil, err
+		}
+		body, _ = json.Marshal(doc)
+	}
+	return doc, body, nil
+}
+
+// Find finds documents. Without options it lists the collection with GET
+// /api/{collection}; any option sends a query instead, e.g.
+//
+//	users.Find(WithFilters(map[string]interface{}{"active": true}), WithSort("age", Asc), WithLimit(10))
+//
+// Nil options are ignored.
+func (c *Collection[T]) Find(opts ...FindOption) ([]T, error) {
+	var o findOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+			o.set = true
+		}
+	}
+
+	switch {
+	case !o.set:
+		return c.FindFiltered(nil)
+	case o.sort == nil && o.limit == nil && o.skip == nil:
+		filters := o.filters
+		if filters == nil {
+			filters = map[string]interface{}{}
+		}
+		return c.FindFiltered(filters)
+	}
+	return c.findPaged(o)
+}
+
+// FindFiltered finds all documents matching filters, listing the whole
+// collection for nil filters. It is what Find took before it accepted
+// options.
+func (c *Collection[T]) FindFiltered(filters map[string]interface{}) (found []T, err error) {
+	timer := c.timeOp(OpFind).query(c.filterQuery(filters)).counting(func() int { return len(found) })
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
+	if filters != nil && len(c.options.codecs) > 0 {
+		return c.findEncoded(filters)
+	}
+
+	var response listPage
+
+	var resp *bufferedResponse
 
 	if filters != nil {
-		resp, err = c.client.client.R().
-			SetBody(map[string]interface{}{"filters": filters}).
-			SetResult(&response).
-			Post(fmt.Sprintf("/api/%s/query", c.collection))
+		resp, err = c.send(func() (*bufferedResponse, error) {
+			return c.call("POST", c.client.apiPath(c.collection, "query"), c.findQuery(filters))
+		})
 	} else {
-		resp, err = c.client.client.R().
-			SetResult(&response).
-			Get(fmt.Sprintf("/api/%s", c.collection))
+		resp, err = c.send(func() (*bufferedResponse, error) {
+			return c.call("GET", c.client.apiPath(c.collection), nil)
+		})
 	}
 
 	if err != nil {
@@ -147,7 +447,15 @@ func (c *Collection[T]) Find(filters map[string]interface{}) ([]T, error) {
 	}
 
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf("failed to find documents: %s", resp.Status())
+		return nil, resp.apiError("find")
+	}
+
+	operation := "list"
+	if filters != nil {
+		operation = "query"
+	}
+	if err := c.checkContract(operation, resp.Body(), listShape); err != nil {
+		return nil, err
 	}
 
 	// Parse response
@@ -155,37 +463,144 @@ func (c *Collection[T]) Find(filters map[string]interface{}) ([]T, error) {
 		return nil, err
 	}
 
-	// Convert to models
-	results := make([]T, 0, len(response.Documents))
-	for _, doc := range response.Documents {
-		jsonData, _ := json.Marshal(doc)
+	// Listings may be split into pages; queries are answered in one response
+	documents := response.Documents
+	var pageErr error
+	if filters == nil {
+		documents, pageErr = c.client.collectPages(c.client.apiPath(c.collection), response, c.pagination(), c.findPage)
+	}
+
+	if err := c.options.codecs.decodeAll(documents); err != nil {
+		return nil, err
+	}
+	// The server's matches are kept as they are, but may be checked
+	if filters != nil && c.options.parity.sample() {
+		c.filterQuery(filters).checkParity(documents, nil)
+	}
+	if c.options.readRepair != nil {
+		m := c.model()
+		for i, doc := range documents {
+			if documents[i], err = m.repairDoc(doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	c.model().Query().sortDocuments(documents)
+
+	op := OpFind
+	if filters != nil {
+		op = OpQuery
+	}
+	models, err := c.toModels(op, documents)
+	if err != nil {
+		return nil, err
+	}
+	return models, pageErr
+}
+
+// findPage reads one page of the collection listing
+func (c *Collection[T]) findPage(path string) (listPage, error) {
+	var page listPage
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.call("GET", path, nil)
+	})
+	if err != nil {
+		return page, err
+	}
+	if !resp.IsSuccess() {
+		return page, resp.apiError("find")
+	}
+	if err := c.checkContract("list", resp.Body(), listShape); err != nil {
+		return page, err
+	}
+
+	err = json.Unmarshal(resp.Body(), &page)
+	return page, err
+}
+
+// findQuery is the request body of a Find with filters
+func (c *Collection[T]) findQuery(filters map[string]interface{}) map[string]interface{} {
+	body := map[string]interface{}{"filters": filters}
+	if order := c.model().defaultOrder; order != nil {
+		body["sort"] = order
+	}
+	return body
+}
+
+// filterQuery is a query of Find's filters, in field order
+func (c *Collection[T]) filterQuery(filters map[string]interface{}) *QueryBuilder {
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	qb := c.model().Query()
+	for _, field := range fields {
+		qb.Where(field, filters[field])
+	}
+	return qb
+}
+
+// findEncoded matches filters against decoded documents, since the server
+// only sees the encoded form
+func (c *Collection[T]) findEncoded(filters map[string]interface{}) ([]T, error) {
+	docs, err := c.filterQuery(filters).Exec()
+	if err != nil {
+		return nil, err
+	}
+	return c.toModels(OpQuery, docs)
+}
+
+// toModels converts documents the guard allows into models. A guard that
+// panics fails the read.
+func (c *Collection[T]) toModels(op Operation, docs []map[string]interface{}) ([]T, error) {
+	results := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		if err := c.options.checkGuard(op, doc); err != nil {
+			var panicked *PanicError
+			if errors.As(err, &panicked) {
+				return nil, err
+			}
+			continue
+		}
+
 		model := c.factory()
-		if err := json.Unmarshal(jsonData, &model); err != nil {
+		if err := hydrate(doc, &model); err != nil {
 			continue
 		}
 		results = append(results, model)
 	}
-
 	return results, nil
 }
 
-// Count counts documents in collection
+// Count counts documents in collection, soft-deleted ones included.
+// Results are shared as configured by WithMicroCache; see CountWith.
 func (c *Collection[T]) Count() (int, error) {
+	return c.CountWith(context.Background(), CountOptions{IncludeDeleted: true})
+}
+
+func (c *Collection[T]) count() (int, error) {
 	var response struct {
 		Collection string `json:"collection"`
 		Count      int    `json:"count"`
 	}
 
-	resp, err := c.client.client.R().
-		SetResult(&response).
-		Get(fmt.Sprintf("/api/%s/count", c.collection))
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.call("GET", c.client.apiPath(c.collection, "count"), nil)
+	})
 
 	if err != nil {
 		return 0, err
 	}
 
 	if !resp.IsSuccess() {
-		return 0, fmt.Errorf("failed to count documents: %s", resp.Status())
+		return 0, resp.apiError("count")
+	}
+
+	if err := c.checkContract("count", resp.Body(), countShape); err != nil {
+		return 0, err
 	}
 
 	if err := json.Unmarshal(resp.Body(), &response); err != nil {
@@ -196,28 +611,47 @@ func (c *Collection[T]) Count() (int, error) {
 }
 
 // Save saves a document
-func (c *Collection[T]) Save(model T) error {
+func (c *Collection[T]) Save(model T) (err error) {
 	id := model.GetID()
+	timer := c.timeOp(OpSave).target(id)
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
 	data := model.ToMap()
+	if err := c.checkFidelity(model, data); err != nil {
+		return err
+	}
+	data, _, err = c.options.sanitizer.strip(c.collection, data)
+	if err != nil {
+		return err
+	}
 
-	var resp *resty.Response
-	var err error
+	if err := c.applySlugs(id, data); err != nil {
+		return err
+	}
+	if err := c.options.checkGuard(OpSave, data); err != nil {
+		return err
+	}
+	stored, err := c.options.codecs.encode(data)
+	if err != nil {
+		return err
+	}
+
+	var resp *bufferedResponse
 
 	if id != "" {
-		resp, err = c.client.client.R().
-			SetBody(map[string]interface{}{"data": data}).
-			Put(fmt.Sprintf("/api/%s/%s", c.collection, id))
+		resp, err = c.send(func() (*bufferedResponse, error) {
+			return c.call("PUT", c.client.apiPath(c.collection, id), map[string]interface{}{"data": stored})
+		})
 	} else {
-		resp, err = c.client.client.R().
-			SetBody(map[string]interface{}{"data": data}).
-			Post(fmt.Sprintf("/api/%s", c.collection))
+		resp, err = c.send(func() (*bufferedResponse, error) {
+			return c.call("POST", c.client.apiPath(c.collection), map[string]interface{}{"data": stored})
+		})
 
-		if err == nil && resp.IsSuccess() {
-			var result struct {
-				ID string `json:"id"`
-			}
-			if err := json.Unmarshal(resp.Body(), &result); err == nil {
-				model.SetID(result.ID)
+		if err == nil && resp.IsSuccess() && hasDocument(resp.StatusCode(), resp.Body()) {
+			if created := createdID(resp.Body()); created != "" {
+				model.SetID(created)
+			} else if c.options.ids.Required {
+				return fmt.Errorf("%w: save in %s", ErrMissingID, c.collection)
 			}
 		}
 	}
@@ -226,27 +660,110 @@ func (c *Collection[T]) Save(model T) error {
 		return err
 	}
 
+	if resp.StatusCode() == http.StatusConflict {
+		return parseConflict(c.collection, resp.apiError("save")).redact(c.redactor(), data)
+	}
+
 	if !resp.IsSuccess() {
-		return fmt.Errorf("failed to save document: %s", resp.Status())
+		return resp.apiError("save").redact(c.redactor(), data)
+	}
+
+	if saved, _ := data["id"].(string); saved == "" {
+		data["id"] = model.GetID()
+	}
+	if err := c.written(OpSave, data); err != nil {
+		return err
 	}
 
-	return nil
+	if !hasDocument(resp.StatusCode(), resp.Body()) {
+		return nil
+	}
+
+	operation := "update"
+	if id == "" {
+		operation = "create"
+	}
+	return c.checkContract(operation, resp.Body(), writeShape)
+}
+
+// Update replaces the document id with model and returns the stored copy,
+// decoded into a fresh T. When the server echoes a merged document, the
+// result comes from it, so fields model left at their zero value keep the
+// stored values. A missing document fails with an error IsNotFound
+// recognizes.
+func (c *Collection[T]) Update(id string, model T) (result T, err error) {
+	timer := c.timeOp(OpUpdate).target(id)
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
+	data := model.ToMap()
+	if err := c.checkFidelity(model, data); err != nil {
+		return result, err
+	}
+	data, stripped, err := c.options.sanitizer.strip(c.collection, data)
+	if err != nil {
+		return result, err
+	}
+	data["id"] = id
+	if err := c.applySlugs(id, data); err != nil {
+		return result, err
+	}
+	if err := c.options.checkGuard(OpUpdate, data); err != nil {
+		return result, err
+	}
+	return c.replace(id, data, stripped)
 }
 
 // Delete deletes a document
-func (c *Collection[T]) Delete(id string) error {
-	resp, err := c.client.client.R().
-		Delete(fmt.Sprintf("/api/%s/%s", c.collection, id))
+func (c *Collection[T]) Delete(id string) (err error) {
+	timer := c.timeOp(OpDelete).target(id)
+	defer timer.end(&err)
+	c = c.traced(context.Background(), timer)
+	// Deletes carry no payload, so the guard sees the stored document
+	if c.options.guard != nil {
+		resp, err := c.send(func() (*bufferedResponse, error) {
+			return c.call("GET", c.client.apiPath(c.collection, id), nil)
+		})
+		if err != nil {
+			return err
+		}
+
+		if resp.IsSuccess() {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(resp.Body(), &doc); err != nil {
+				return err
+			}
+			doc, err = c.options.codecs.decode(doc)
+			if err != nil {
+				return err
+			}
+			if err := c.options.checkGuard(OpDelete, doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.call("DELETE", c.client.apiPath(c.collection, id), nil)
+	})
 
 	if err != nil {
 		return err
 	}
 
+	if resp.StatusCode() == http.StatusNotFound {
+		return notFound(c.collection, id)
+	}
 	if !resp.IsSuccess() {
-		return fmt.Errorf("failed to delete document: %s", resp.Status())
+		return resp.apiError("delete")
+	}
+	if err := c.deleted(id); err != nil {
+		return err
 	}
 
-	return nil
+	if !hasDocument(resp.StatusCode(), resp.Body()) {
+		return nil
+	}
+	return c.checkContract("delete", resp.Body(), deleteShape)
 }
 
 // Migration represents a database migration
@@ -261,6 +778,9 @@ type Migration struct {
 type MigrationManager struct {
 	client     *Client
 	migrations []Migration
+	hooks      migrationHooks
+	run        *migrationRun // The migration being applied, if any
+	legacy     bool          // Also write the single-key record; see WithLegacyRecord
 }
 
 // NewMigrationManager creates a new migration manager
@@ -276,94 +796,28 @@ func (m *MigrationManager) AddMigration(migration Migration) {
 	m.migrations = append(m.migrations, migration)
 }
 
-// Migrate runs all pending migrations
+// Migrate runs all pending migrations and returns the names of those applied.
+// See MigrateDetailed for per-migration outcomes.
 func (m *MigrationManager) Migrate() ([]string, error) {
-	applied, err := m.getAppliedMigrations()
-	if err != nil {
+	result, err := m.MigrateDetailed()
+	if result == nil {
 		return nil, err
 	}
-
-	newlyApplied := make([]string, 0)
-
-	for _, migration := range m.migrations {
-		if _, exists := applied[migration.ID]; !exists {
-			// Run migration
-			if err := migration.Up(m.client); err != nil {
-				return newlyApplied, err
-			}
-
-			// Record migration
-			if err := m.saveMigration(map[string]interface{}{
-				"id":         migration.ID,
-				"name":       migration.Name,
-				"applied_at": time.Now().Format(time.RFC3339),
-			}); err != nil {
-				return newlyApplied, err
-			}
-
-			newlyApplied = append(newlyApplied, migration.Name)
-		}
-	}
-
-	return newlyApplied, nil
+	return result.Names(OutcomeApplied), err
 }
 
-// Rollback rolls back last N migrations
+// Rollback rolls back the last N migrations and returns the names of those
+// rolled back. See RollbackDetailed for per-migration outcomes.
 func (m *MigrationManager) Rollback(steps int) ([]string, error) {
-	applied, err := m.getAppliedMigrations()
-	if err != nil {
+	result, err := m.RollbackDetailed(steps)
+	if result == nil {
 		return nil, err
 	}
-
-	// Sort by applied_at descending
-	type appliedMigration struct {
-		ID        string
-		Name      string
-		AppliedAt string
-	}
-
-	sorted := make([]appliedMigration, 0, len(applied))
-	for id, data := range applied {
-		sorted = append(sorted, appliedMigration{
-			ID:        id,
-			Name:      data["name"].(string),
-			AppliedAt: data["applied_at"].(string),
-		})
-	}
-
-	rolledBack := make([]string, 0)
-
-	for i := 0; i < steps && i < len(sorted); i++ {
-		record := sorted[i]
-
-		// Find migration
-		var migration *Migration
-		for _, m := range m.migrations {
-			if m.ID == record.ID {
-				migration = &m
-				break
-			}
-		}
-
-		if migration != nil {
-			// Run down migration
-			if err := migration.Down(m.client); err != nil {
-				return rolledBack, err
-			}
-
-			// Remove migration record
-			if err := m.removeMigration(record.ID); err != nil {
-				return rolledBack, err
-			}
-
-			rolledBack = append(rolledBack, record.Name)
-		}
-	}
-
-	return rolledBack, nil
+	return result.Names(OutcomeRolledBack), err
 }
 
-// Status returns migration status
+// Status returns migration status as display strings.
+// See StatusDetailed for typed results.
 func (m *MigrationManager) Status() (map[string]string, error) {
 	applied, err := m.getAppliedMigrations()
 	if err != nil {
@@ -373,84 +827,18 @@ func (m *MigrationManager) Status() (map[string]string, error) {
 	status := make(map[string]string)
 
 	for _, migration := range m.migrations {
-		if data, exists := applied[migration.ID]; exists {
-			status[migration.ID] = fmt.Sprintf("Applied (%s)", data["applied_at"])
-		} else {
+		data, exists := applied[migration.ID]
+		switch {
+		case !exists:
 			status[migration.ID] = "Pending"
+		case isFailedRecord(data):
+			status[migration.ID] = fmt.Sprintf("Failed (%s)", data["error"])
+		case isRollingBackRecord(data):
+			status[migration.ID] = fmt.Sprintf("Rolling back (%s)", data["rolling_back_at"])
+		default:
+			status[migration.ID] = fmt.Sprintf("Applied (%s)", data["applied_at"])
 		}
 	}
 
 	return status, nil
 }
-
-func (m *MigrationManager) getAppliedMigrations() (map[string]map[string]interface{}, error) {
-	resp, err := m.client.client.R().
-		Get("/api/keys/torm:migrations")
-
-	if err != nil || !resp.IsSuccess() {
-		return make(map[string]map[string]interface{}), nil
-	}
-
-	var response struct {
-		Value string `json:"value"`
-	}
-
-	if err := json.Unmarshal(resp.Body(), &response); err != nil {
-		return make(map[string]map[string]interface{}), nil
-	}
-
-	var migrations map[string]map[string]interface{}
-	if err := json.Unmarshal([]byte(response.Value), &migrations); err != nil {
-		return make(map[string]map[string]interface{}), nil
-	}
-
-	return migrations, nil
-}
-
-func (m *MigrationManager) saveMigration(migration map[string]interface{}) error {
-	applied, _ := m.getAppliedMigrations()
-	applied[migration["id"].(string)] = migration
-
-	jsonData, err := json.Marshal(applied)
-	if err != nil {
-		return err
-	}
-
-	resp, err := m.client.client.R().
-		SetBody(map[string]interface{}{"value": string(jsonData)}).
-		Put("/api/keys/torm:migrations")
-
-	if err != nil {
-		return err
-	}
-
-	if !resp.IsSuccess() {
-		return fmt.Errorf("failed to save migration: %s", resp.Status())
-	}
-
-	return nil
-}
-
-func (m *MigrationManager) removeMigration(migrationID string) error {
-	applied, _ := m.getAppliedMigrations()
-	delete(applied, migrationID)
-
-	jsonData, err := json.Marshal(applied)
-	if err != nil {
-		return err
-	}
-
-	resp, err := m.client.client.R().
-		SetBody(map[string]interface{}{"value": string(jsonData)}).
-		Put("/api/keys/torm:migrations")
-
-	if err != nil {
-		return err
-	}
-
-	if !resp.IsSuccess() {
-		return fmt.Errorf("failed to remove migration: %s", resp.Status())
-	}
-
-	return nil
-}
@@ -0,0 +1,356 @@
+package tormtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/toonstore/torm-go"
+)
+
+// ErrUnsatisfiable is returned by Factory when no value it can generate
+// passes a field's rules; the error names the field
+var ErrUnsatisfiable = errors.New("tormtest: rules cannot be satisfied")
+
+// SequenceFunc returns the value of a field for the nth generated document,
+// counting from 1
+type SequenceFunc func(n int) interface{}
+
+// Emails is a sequence of unique addresses at domain
+func Emails(domain string) SequenceFunc {
+	return func(n int) interface{} {
+		return fmt.Sprintf("user%d@%s", n, domain)
+	}
+}
+
+// Factory generates documents satisfying a schema. Every field of the
+// schema is filled in, derived from the document's sequence number so that
+// generated documents differ from each other and runs are repeatable.
+// Patterns are generated for simple regular expressions: literals,
+// character classes, alternation and repetition.
+type Factory struct {
+	schema     map[string]torm.ValidationRule
+	fields     []string
+	validators map[string]*torm.Model
+
+	mu        sync.Mutex
+	n         int
+	sequences map[string]SequenceFunc
+}
+
+// NewFactory creates a factory for schema, as passed to Client.Model or
+// returned by Model.Schema
+func NewFactory(schema map[string]torm.ValidationRule) *Factory {
+	validation := torm.NewClient(nil)
+	f := &Factory{
+		schema:     schema,
+		validators: make(map[string]*torm.Model, len(schema)),
+		sequences:  make(map[string]SequenceFunc),
+	}
+	for field, rules := range schema {
+		f.fields = append(f.fields, field)
+		f.validators[field] = validation.Model(field, map[string]torm.ValidationRule{field: rules})
+	}
+	sort.Strings(f.fields)
+	return f
+}
+
+// Sequence sets field from fn instead of generating it from its rules.
+// Fields outside the schema are added to every document.
+func (f *Factory) Sequence(field string, fn SequenceFunc) *Factory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sequences[field] = fn
+	return f
+}
+
+// Build generates one document. Overrides replace generated fields and may
+// add fields outside the schema; they are not validated.
+func (f *Factory) Build(overrides map[string]interface{}) (map[string]interface{}, error) {
+	f.mu.Lock()
+	f.n++
+	n := f.n
+	f.mu.Unlock()
+
+	f.mu.Lock()
+	fields := append([]string(nil), f.fields...)
+	for field := range f.sequences {
+		if _, ok := f.schema[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	f.mu.Unlock()
+
+	doc := make(map[string]interface{}, len(fields)+len(overrides))
+	for _, field := range fields {
+		if _, ok := overrides[field]; ok {
+			continue
+		}
+		value, err := f.generate(field, n)
+		if err != nil {
+			return nil, err
+		}
+		doc[field] = value
+	}
+	for field, value := range overrides {
+		doc[field] = value
+	}
+	return doc, nil
+}
+
+// BuildN generates n documents with the same overrides
+func (f *Factory) BuildN(n int, overrides map[string]interface{}) ([]map[string]interface{}, error) {
+	docs := make([]map[string]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		doc, err := f.Build(overrides)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// CreateN generates n documents and creates them in model, returning the
+// stored documents. It stops at the first failure.
+func (f *Factory) CreateN(model *torm.Model, n int, overrides map[string]interface{}) ([]map[string]interface{}, error) {
+	docs, err := f.BuildN(n, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]map[string]interface{}, 0, n)
+	for _, doc := range docs {
+		stored, err := model.Create(doc)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, stored)
+	}
+	return created, nil
+}
+
+// generate returns a value for field that passes its rules
+func (f *Factory) generate(field string, n int) (interface{}, error) {
+	f.mu.Lock()
+	sequence := f.sequences[field]
+	f.mu.Unlock()
+
+	var value interface{}
+	var err error
+	if sequence != nil {
+		value = sequence(n)
+	} else {
+		value, err = generateValue(field, f.schema[field], n)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field '%s' %v", ErrUnsatisfiable, field, err)
+		}
+	}
+
+	validator, ok := f.validators[field]
+	if !ok {
+		return value, nil
+	}
+	doc := map[string]interface{}{field: value}
+	if err := validator.Validate(doc, torm.ValidationFastFail); err != nil {
+		return nil, fmt.Errorf("%w: field '%s' generated %v: %v", ErrUnsatisfiable, field, value, err)
+	}
+	return doc[field], nil
+}
+
+// generateValue derives the nth value for rules
+func generateValue(field string, rules torm.ValidationRule, n int) (interface{}, error) {
+	switch fieldType(rules) {
+	case "int":
+		lo, hi := bounds(rules)
+		min, max := math.Ceil(lo), math.Floor(hi)
+		if min > max {
+			return nil, fmt.Errorf("has no integer between %v and %v", lo, hi)
+		}
+		return int(min) + n%(int(max-min)+1), nil
+	case "float":
+		lo, hi := bounds(rules)
+		if lo > hi {
+			return nil, fmt.Errorf("has minimum %v above maximum %v", lo, hi)
+		}
+		return lo + (hi-lo)*float64(n%100)/100, nil
+	case "bool":
+		return n%2 == 0, nil
+	case "map":
+		return map[string]interface{}{}, nil
+	case "slice", "array":
+		return []interface{}{}, nil
+	case "decimal":
+		return generateDecimal(rules, n)
+	default:
+		return generateString(field, rules, n)
+	}
+}
+
+// fieldType returns the type rules expect, inferring it from the
+// constraints when no type is given
+func fieldType(rules torm.ValidationRule) string {
+	switch {
+	case rules.Type != "":
+		return rules.Type
+	case rules.Min != nil || rules.Max != nil:
+		return "float"
+	default:
+		return "str"
+	}
+}
+
+// bounds returns the numeric range of rules, defaulting to a span of 1000
+func bounds(rules torm.ValidationRule) (float64, float64) {
+	lo, hi := 0.0, 1000.0
+	switch {
+	case rules.Min != nil && rules.Max != nil:
+		lo, hi = *rules.Min, *rules.Max
+	case rules.Min != nil:
+		lo, hi = *rules.Min, *rules.Min+1000
+	case rules.Max != nil && *rules.Max < hi:
+		lo, hi = *rules.Max-1000, *rules.Max
+	}
+	return lo, hi
+}
+
+func generateDecimal(rules torm.ValidationRule, n int) (string, error) {
+	scale := 2
+	if rules.Scale != nil {
+		scale = *rules.Scale
+	}
+
+	lo, hi := new(big.Rat), new(big.Rat).SetInt64(1000)
+	if rules.MinDecimal != "" {
+		if _, ok := lo.SetString(rules.MinDecimal); !ok {
+			return "", fmt.Errorf("has invalid minimum %q", rules.MinDecimal)
+		}
+		if rules.MaxDecimal == "" {
+			hi.Add(lo, hi)
+		}
+	}
+	if rules.MaxDecimal != "" {
+		if _, ok := hi.SetString(rules.MaxDecimal); !ok {
+			return "", fmt.Errorf("has invalid maximum %q", rules.MaxDecimal)
+		}
+		if rules.MinDecimal == "" && hi.Sign() < 0 {
+			lo.Sub(hi, big.NewRat(1000, 1))
+		}
+	}
+	if lo.Cmp(hi) > 0 {
+		return "", fmt.Errorf("has minimum %s above maximum %s", rules.MinDecimal, rules.MaxDecimal)
+	}
+
+	// The nth percent of the range, rounded to scale and kept inside it
+	span := new(big.Rat).Sub(hi, lo)
+	value := new(big.Rat).Add(lo, span.Mul(span, big.NewRat(int64(n%100), 100)))
+	candidate := value.FloatString(scale)
+	if rounded, _ := new(big.Rat).SetString(candidate); rounded.Cmp(lo) < 0 || rounded.Cmp(hi) > 0 {
+		candidate = lo.FloatString(scale)
+	}
+	return candidate, nil
+}
+
+func generateString(field string, rules torm.ValidationRule, n int) (string, error) {
+	var value string
+	switch {
+	case rules.Pattern != "":
+		generated, err := reversePattern(rules.Pattern, n)
+		if err != nil {
+			return "", err
+		}
+		value = generated
+	case rules.Email:
+		value = fmt.Sprintf("%s%d@example.com", strings.ToLower(field), n)
+	case rules.URL:
+		value = fmt.Sprintf("https://example.com/%s/%d", field, n)
+	default:
+		value = fmt.Sprintf("%s-%d", field, n)
+		if rules.MinLength != nil && len(value) < *rules.MinLength {
+			value += strings.Repeat("x", *rules.MinLength-len(value))
+		}
+		if rules.MaxLength != nil && len(value) > *rules.MaxLength {
+			// Keep the sequence number so shortened values stay distinct
+			suffix := fmt.Sprint(n)
+			if len(suffix) > *rules.MaxLength {
+				suffix = suffix[len(suffix)-*rules.MaxLength:]
+			}
+			value = value[:*rules.MaxLength-len(suffix)] + suffix
+		}
+	}
+	return value, nil
+}
+
+// patternRunes are tried, in turn from an offset, when picking a character
+// from a class
+const patternRunes = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ-_."
+
+// reversePattern returns a string matching pattern, varying with n
+func reversePattern(pattern string, n int) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("has invalid pattern: %v", err)
+	}
+
+	var b strings.Builder
+	if err := writeMatch(&b, re.Simplify(), n); err != nil {
+		return "", err
+	}
+	if !regexp.MustCompile(pattern).MatchString(b.String()) {
+		return "", fmt.Errorf("has a pattern too complex to generate")
+	}
+	return b.String(), nil
+}
+
+func writeMatch(b *strings.Builder, re *syntax.Regexp, n int) error {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText:
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteByte(patternRunes[n%len(patternRunes)])
+	case syntax.OpCharClass:
+		b.WriteRune(classRune(re.Rune, n))
+	case syntax.OpCapture:
+		return writeMatch(b, re.Sub[0], n)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		return writeMatch(b, re.Sub[0], n)
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			if err := writeMatch(b, re.Sub[0], n+i); err != nil {
+				return err
+			}
+		}
+	case syntax.OpConcat:
+		for i, sub := range re.Sub {
+			if err := writeMatch(b, sub, n+i); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		return writeMatch(b, re.Sub[n%len(re.Sub)], n)
+	default:
+		return fmt.Errorf("has a pattern too complex to generate")
+	}
+	return nil
+}
+
+// classRune picks a readable character from the class given as range
+// pairs, falling back to its first character
+func classRune(ranges []rune, n int) rune {
+	for i := 0; i < len(patternRunes); i++ {
+		r := rune(patternRunes[(n+i)%len(patternRunes)])
+		for j := 0; j+1 < len(ranges); j += 2 {
+			if ranges[j] <= r && r <= ranges[j+1] {
+				return r
+			}
+		}
+	}
+	return ranges[0]
+}
@@ -0,0 +1,105 @@
+// Package tormtest provides fakes for code that depends on the torm interfaces
+package tormtest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/toonstore/torm-go"
+)
+
+// ErrNotFound is torm.ErrNotFound, which StubReader.FindByID wraps for
+// unknown ids like a collection does
+var ErrNotFound = torm.ErrNotFound
+
+// StubReader is an in-memory torm.CollectionReader. FindFiltered matches filters by
+// equality against each document's ToMap output.
+type StubReader[T torm.Document] struct {
+	mu   sync.Mutex
+	docs []T
+
+	// Err, when set, is returned by every call
+	Err error
+}
+
+var _ torm.CollectionReader[torm.Document] = (*StubReader[torm.Document])(nil)
+
+// NewStubReader creates a reader serving the given documents
+func NewStubReader[T torm.Document](docs ...T) *StubReader[T] {
+	return &StubReader[T]{docs: docs}
+}
+
+// Add stores more documents, replacing any with the same id
+func (s *StubReader[T]) Add(docs ...T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range docs {
+		replaced := false
+		for i, existing := range s.docs {
+			if existing.GetID() == doc.GetID() {
+				s.docs[i] = doc
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.docs = append(s.docs, doc)
+		}
+	}
+}
+
+// FindByID returns the document with the given id
+func (s *StubReader[T]) FindByID(id string) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zero T
+	if s.Err != nil {
+		return zero, s.Err
+	}
+
+	for _, doc := range s.docs {
+		if doc.GetID() == id {
+			return doc, nil
+		}
+	}
+	return zero, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// FindFiltered returns the documents whose fields equal every filter value
+func (s *StubReader[T]) FindFiltered(filters map[string]interface{}) ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	results := make([]T, 0)
+	for _, doc := range s.docs {
+		fields := doc.ToMap()
+		matched := true
+		for field, value := range filters {
+			if fmt.Sprintf("%v", fields[field]) != fmt.Sprintf("%v", value) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, doc)
+		}
+	}
+	return results, nil
+}
+
+// Count returns the number of stored documents
+func (s *StubReader[T]) Count() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	return len(s.docs), nil
+}
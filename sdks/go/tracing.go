@@ -0,0 +1,176 @@
+package torm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// Tracer traces Collection, Model and QueryBuilder operations, see
+// ClientOptions.Tracer. The SDK has no tracing dependency of its own; an
+// OpenTelemetry Tracer takes a few lines, as the README shows.
+type Tracer interface {
+	// Start starts the span of an operation, as a child of the span in ctx
+	// if there is one. Names read like "torm.users.find_by_id".
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Inject writes the span context of ctx into the headers of the
+	// operation's requests, e.g. as a W3C traceparent
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is the span of one operation, ended when the operation returns
+type Span interface {
+	End(attrs []SpanAttribute, err error)
+}
+
+// SpanAttribute is an attribute of an operation's span
+type SpanAttribute struct {
+	Key   string
+	Value interface{} // A string or an int
+}
+
+// Attributes of operation spans
+const (
+	AttrCollection  = "torm.collection"
+	AttrOperation   = "torm.operation"
+	AttrDocumentID  = "torm.document_id"
+	AttrQuery       = "torm.query"
+	AttrResultCount = "torm.result_count"
+	AttrHTTPStatus  = "http.status_code"
+)
+
+// traced starts the span of t as a child of parent's, or of the span in
+// ctx without a parent. It reports false when the client has no Tracer.
+func (t *opTimer) traced(ctx context.Context, parent *opTimer) bool {
+	tracer := t.client.tracer
+	if tracer == nil {
+		return false
+	}
+	if parent != nil && parent.spanCtx != nil {
+		ctx = parent.spanCtx
+	}
+	t.spanCtx, t.span = tracer.Start(ctx, "torm."+t.op.Collection+"."+snakeCase(t.name))
+	t.header = http.Header{}
+	tracer.Inject(t.spanCtx, t.header)
+	return true
+}
+
+// headers adds the propagation headers of t's span to header
+func (t *opTimer) headers(header http.Header) http.Header {
+	if t == nil || len(t.header) == 0 {
+		return header
+	}
+	all := make(http.Header, len(header)+len(t.header))
+	for name, values := range header {
+		all[name] = values
+	}
+	for name, values := range t.header {
+		all[name] = values
+	}
+	return all
+}
+
+// sent records the status of one of the operation's responses
+func (t *opTimer) sent(status int) {
+	if t != nil && t.span != nil {
+		t.status.Store(int32(status))
+	}
+}
+
+// counting has the span report how many documents a successful operation
+// returned, as counted by n when it ends
+func (t *opTimer) counting(n func() int) *opTimer {
+	t.count = n
+	return t
+}
+
+// endSpan ends t's span with what is known of the operation
+func (t *opTimer) endSpan(err error) {
+	if t.span == nil {
+		return
+	}
+	attrs := []SpanAttribute{
+		{AttrCollection, t.op.Collection},
+		{AttrOperation, string(t.op.Operation)},
+	}
+	if t.id != "" {
+		attrs = append(attrs, SpanAttribute{AttrDocumentID, t.id})
+	}
+	if t.describe != nil {
+		attrs = append(attrs, SpanAttribute{AttrQuery, t.describe()})
+	}
+	if t.count != nil && err == nil {
+		attrs = append(attrs, SpanAttribute{AttrResultCount, t.count()})
+	}
+	// Exhausted retries fail with the last status
+	status := int(t.status.Load())
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		status = apiErr.StatusCode
+	}
+	if status != 0 {
+		attrs = append(attrs, SpanAttribute{AttrHTTPStatus, status})
+	}
+	t.span.End(attrs, err)
+}
+
+// traced returns a copy of c whose requests belong to t's span, or c
+// itself when the client has no Tracer and ctx sets no priority
+func (c *Collection[T]) traced(ctx context.Context, t *opTimer) *Collection[T] {
+	priority, prioritized := callPriority(ctx)
+	if !t.traced(ctx, c.trace) && !prioritized {
+		return c
+	}
+	traced := *c
+	traced.trace = t
+	if prioritized {
+		traced.priority = priority
+	}
+	return &traced
+}
+
+// traced returns a copy of m whose requests belong to t's span, or m
+// itself when the client has no Tracer and ctx sets no priority
+func (m *Model) traced(ctx context.Context, t *opTimer) *Model {
+	priority, prioritized := callPriority(ctx)
+	if !t.traced(ctx, m.trace) && !prioritized {
+		return m
+	}
+	traced := *m
+	traced.trace = t
+	if prioritized {
+		traced.priority = priority
+	}
+	return &traced
+}
+
+// traced returns a copy of qb whose requests belong to t's span, or qb
+// itself when the client has no Tracer
+func (qb *QueryBuilder) traced(t *opTimer) *QueryBuilder {
+	if !t.traced(context.Background(), qb.trace) {
+		return qb
+	}
+	traced := *qb
+	traced.trace = t
+	return &traced
+}
+
+// snakeCase turns a method name such as "FindByID" into "find_by_id"
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A new word starts at an upper-case letter following a lower-case
+			// one, or at the last letter of an acronym followed by a lower-case one
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package torm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TypedQuery is a query of a collection whose results decode into the
+// collection's document type through its factory. The builder methods
+// chain like QueryBuilder's; Builder reaches the rest of them.
+type TypedQuery[T Document] struct {
+	coll *Collection[T]
+	qb   *QueryBuilder
+}
+
+// Query creates a query of the collection, e.g.
+//
+//	users.Query().Where("active", true).Sort("age", Asc).Limit(10).Exec()
+//
+// It carries the collection's codecs, read repair, default order, guard
+// and redaction like Find does.
+func (c *Collection[T]) Query() *TypedQuery[T] {
+	return &TypedQuery[T]{coll: c, qb: c.model().Query()}
+}
+
+// Filter adds a filter condition
+func (q *TypedQuery[T]) Filter(field string, operator QueryOperator, value interface{}) *TypedQuery[T] {
+	q.qb.Filter(field, operator, value)
+	return q
+}
+
+// Where adds an equality filter (shorthand for Filter with Eq)
+func (q *TypedQuery[T]) Where(field string, value interface{}) *TypedQuery[T] {
+	q.qb.Where(field, value)
+	return q
+}
+
+// Sort sets sort field and order
+func (q *TypedQuery[T]) Sort(field string, order SortOrder) *TypedQuery[T] {
+	q.qb.Sort(field, order)
+	return q
+}
+
+// Limit sets maximum number of results
+func (q *TypedQuery[T]) Limit(n int) *TypedQuery[T] {
+	q.qb.Limit(n)
+	return q
+}
+
+// Skip sets number of results to skip
+func (q *TypedQuery[T]) Skip(n int) *TypedQuery[T] {
+	q.qb.Skip(n)
+	return q
+}
+
+// Select restricts returned documents to the given fields. Fields left out
+// keep their zero value in the results.
+func (q *TypedQuery[T]) Select(fields ...string) *TypedQuery[T] {
+	q.qb.Select(fields...)
+	return q
+}
+
+// Hint passes an execution hint to the server; see QueryBuilder.Hint
+func (q *TypedQuery[T]) Hint(key string, value interface{}) *TypedQuery[T] {
+	q.qb.Hint(key, value)
+	return q
+}
+
+// UseIndex asks the server to run the query on the named index
+func (q *TypedQuery[T]) UseIndex(name string) *TypedQuery[T] {
+	q.qb.UseIndex(name)
+	return q
+}
+
+// ServerTimeout asks the server to give up on the query after d
+func (q *TypedQuery[T]) ServerTimeout(d time.Duration) *TypedQuery[T] {
+	q.qb.ServerTimeout(d)
+	return q
+}
+
+// Builder returns the underlying query. Changes to it apply to q.
+func (q *TypedQuery[T]) Builder() *QueryBuilder {
+	return q.qb
+}
+
+// Clone returns an independent copy of the query
+func (q *TypedQuery[T]) Clone() *TypedQuery[T] {
+	return &TypedQuery[T]{coll: q.coll, qb: q.qb.Clone()}
+}
+
+// String describes the query; see QueryBuilder.String
+func (q *TypedQuery[T]) String() string {
+	return q.qb.String()
+}
+
+// Explain describes the query without running it
+func (q *TypedQuery[T]) Explain() *QueryPlan {
+	return q.qb.Explain()
+}
+
+// Exec executes the query and decodes the documents the guard allows into
+// T. A document that does not decode fails the query with a
+// *HydrationError rather than being dropped; see ExecPartial to keep the
+// others.
+func (q *TypedQuery[T]) Exec() ([]T, error) {
+	docs, err := q.qb.Exec()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]T, 0, len(docs))
+	for i, doc := range docs {
+		if err := q.coll.options.checkGuard(OpQuery, doc); err != nil {
+			var panicked *PanicError
+			if errors.As(err, &panicked) {
+				return nil, err
+			}
+			continue
+		}
+
+		model := q.coll.factory()
+		if err := hydrate(doc, &model); err != nil {
+			id, _ := doc["id"].(string)
+			return nil, fmt.Errorf("%w (query: %s)", &HydrationError{Index: i, ID: id, Err: err}, q.qb)
+		}
+		results = append(results, model)
+	}
+	return results, nil
+}
+
+// Count counts matching documents; see QueryBuilder.Count
+func (q *TypedQuery[T]) Count() (int, error) {
+	return q.qb.Count()
+}
@@ -0,0 +1,236 @@
+package torm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrVersionConflict is returned when a versioned write finds that the
+// document changed after it was read
+var ErrVersionConflict = errors.New("torm: version conflict")
+
+// ConflictExhaustedError is returned by UpdateWithRetry when every attempt
+// found the document changed
+type ConflictExhaustedError struct {
+	Collection string
+	ID         string
+	Attempts   int
+	Version    string                 // Version of the last document read
+	LastSeen   map[string]interface{} // The last document read
+}
+
+func (e *ConflictExhaustedError) Error() string {
+	return fmt.Sprintf("update of %s in collection %s conflicted %d times", e.ID, e.Collection, e.Attempts)
+}
+
+// Unwrap makes errors.Is(err, ErrVersionConflict) match
+func (e *ConflictExhaustedError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// UpdateRetryOptions configures UpdateWithRetry
+type UpdateRetryOptions struct {
+	Attempts int           // Saves tried before giving up (default 5)
+	Backoff  time.Duration // Wait before the second attempt, doubled for each further one (default 20ms)
+}
+
+// UpdateWithRetry reads a document, applies mutate to it and saves the
+// result only if the document is still the version that was read. When
+// another writer got there first, the document is read again and mutate
+// applied to the new version, with backoff between attempts. mutate may
+// run several times, so it must derive its result from the value passed
+// in alone. Servers that send ETags check the version atomically with
+// If-Match; for others the document is read again just before the write.
+func (c *Collection[T]) UpdateWithRetry(ctx context.Context, id string, mutate func(current T) (T, error), opts *UpdateRetryOptions) (updated T, err error) {
+	tracked := c.timeOp(OpUpdate).target(id).as("UpdateWithRetry")
+	defer tracked.end(&err)
+	c = c.traced(ctx, tracked)
+	var zero T
+	if opts == nil {
+		opts = &UpdateRetryOptions{}
+	}
+	attempts := opts.Attempts
+	if attempts <= 0 {
+		attempts = 5
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = 20 * time.Millisecond
+	}
+
+	var current *versionedDoc
+	for attempt := 1; attempt <= attempts; attempt++ {
+		tracked.op.Attempts = attempt
+		if attempt > 1 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return zero, ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+
+		var err error
+		current, err = c.readVersion(id)
+		if err != nil {
+			return zero, err
+		}
+		if err := c.options.checkGuard(OpFindByID, current.doc); err != nil {
+			return zero, err
+		}
+
+		model := c.factory()
+		if err := hydrate(current.doc, &model); err != nil {
+			return zero, err
+		}
+		var updated T
+		if err := protect("update mutation", c.collection, id, func() { updated, err = mutate(model) }); err != nil {
+			return zero, err
+		}
+		if err != nil {
+			return zero, err
+		}
+
+		result, err := c.saveVersion(id, current, updated)
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		return result, err
+	}
+
+	return zero, &ConflictExhaustedError{
+		Collection: c.collection,
+		ID:         id,
+		Attempts:   attempts,
+		Version:    current.version(),
+		LastSeen:   current.doc,
+	}
+}
+
+// versionedDoc is a document as read for a versioned write
+type versionedDoc struct {
+	doc  map[string]interface{} // Decoded
+	etag string                 // Empty when the server sent none
+	hash string                 // Of the stored form, compared without ETags
+}
+
+func (v *versionedDoc) version() string {
+	if v.etag != "" {
+		return v.etag
+	}
+	return v.hash
+}
+
+// readVersion reads a document along with its version, bypassing the read
+// cache
+func (c *Collection[T]) readVersion(id string) (*versionedDoc, error) {
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.call("GET", c.client.apiPath(c.collection, id), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, notFound(c.collection, id)
+	}
+	if !resp.IsSuccess() {
+		return nil, resp.apiError("find")
+	}
+	if err := c.checkContract("find", resp.Body(), documentShape); err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(resp.Body(), &doc); err != nil {
+		return nil, err
+	}
+	// The hash covers the stored form, so key order is normalized first
+	stored, _ := json.Marshal(doc)
+	sum := sha256.Sum256(stored)
+
+	if doc, err = c.options.codecs.decode(doc); err != nil {
+		return nil, err
+	}
+	if doc, err = c.model().repairDoc(doc); err != nil {
+		return nil, err
+	}
+	return &versionedDoc{doc: doc, etag: resp.Header().Get("ETag"), hash: hex.EncodeToString(sum[:])}, nil
+}
+
+// saveVersion writes model over the document if it is still the version
+// read, returning ErrVersionConflict otherwise
+func (c *Collection[T]) saveVersion(id string, read *versionedDoc, model T) (T, error) {
+	var result T
+
+	data := model.ToMap()
+	if err := c.checkFidelity(model, data); err != nil {
+		return result, err
+	}
+	data, _, err := c.options.sanitizer.strip(c.collection, data)
+	if err != nil {
+		return result, err
+	}
+	data["id"] = id
+	if err := c.applySlugs(id, data); err != nil {
+		return result, err
+	}
+	if err := c.options.checkGuard(OpSave, data); err != nil {
+		return result, err
+	}
+	written, err := c.putVersion(id, read, data)
+	if !written {
+		return result, err
+	}
+	return model, err
+}
+
+// putVersion writes data over the document if it is still the version
+// read. The boolean reports whether the write reached the server; an error
+// with it set comes from the bookkeeping after the write.
+func (c *Collection[T]) putVersion(id string, read *versionedDoc, data map[string]interface{}) (bool, error) {
+	stored, err := c.options.codecs.encode(data)
+	if err != nil {
+		return false, err
+	}
+
+	var header http.Header
+	if read.etag != "" {
+		header = http.Header{"If-Match": {read.etag}}
+	} else {
+		latest, err := c.readVersion(id)
+		if err != nil {
+			return false, err
+		}
+		if latest.hash != read.hash {
+			return false, ErrVersionConflict
+		}
+	}
+
+	resp, err := c.send(func() (*bufferedResponse, error) {
+		return c.callWithHeader("PUT", c.client.apiPath(c.collection, id), map[string]interface{}{"data": stored}, header)
+	})
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case resp.StatusCode() == http.StatusPreconditionFailed:
+		return false, ErrVersionConflict
+	case resp.StatusCode() == http.StatusConflict:
+		return false, parseConflict(c.collection, resp.apiError("update")).redact(c.redactor(), data)
+	case !resp.IsSuccess():
+		return false, resp.apiError("update").redact(c.redactor(), data)
+	}
+
+	return true, c.written(OpUpdate, data)
+}
@@ -0,0 +1,348 @@
+package torm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// checkpointKeyPrefix namespaces the keys holding UpdateWhere checkpoints
+const checkpointKeyPrefix = "torm:update-where:"
+
+// UpdateWhereOptions configures UpdateWhere
+type UpdateWhereOptions struct {
+	BatchSize int                                   // Documents between progress reports and checkpoints (default 100)
+	PageSize  int                                   // Documents fetched per page while matching (default 1000)
+	Match     func(doc map[string]interface{}) bool // Extra client-side predicate
+	Progress  func(UpdateProgress)                  // Called after every batch
+
+	// Checkpoint makes the run resumable: progress is saved through the
+	// keys API under this token after every batch, and a run given the
+	// same token starts after the last saved document. The token is
+	// removed once the run completes.
+	Checkpoint string
+	// Applied reports whether a document already has the update, so it is
+	// skipped. A resumed run revisits the documents updated after the
+	// last checkpoint, so Applied is needed unless the update is
+	// idempotent.
+	Applied func(doc map[string]interface{}) bool
+	// Concurrency updates each batch with an adaptive worker pool; nil
+	// updates one document at a time
+	Concurrency *AdaptiveConcurrency
+}
+
+// UpdateProgress reports how far an UpdateWhere has come
+type UpdateProgress struct {
+	Matched int
+	Updated int
+	Skipped int
+	Failed  int
+	LastID  string // The last document processed, in id order
+}
+
+// UpdateReport summarizes an UpdateWhere. Counts of a resumed run include
+// those of the runs before it.
+type UpdateReport struct {
+	Matched   int
+	Updated   int
+	Skipped   int // Already applied, see UpdateWhereOptions.Applied
+	FailedIDs []string
+	// DeadLettered counts failed updates captured by the collection's
+	// dead-letter sink; see WithDeadLetter
+	DeadLettered int
+	Resumed      bool // Whether the run started from a checkpoint
+}
+
+// updateCheckpoint is the progress of an UpdateWhere saved under its token
+type updateCheckpoint struct {
+	Collection   string   `json:"collection"`
+	Filters      string   `json:"filters"`
+	LastID       string   `json:"last_id"`
+	Matched      int      `json:"matched"`
+	Updated      int      `json:"updated"`
+	Skipped      int      `json:"skipped"`
+	FailedIDs    []string `json:"failed_ids,omitempty"`
+	DeadLettered int      `json:"dead_lettered,omitempty"`
+}
+
+// UpdateWhere applies update to every document matching filters (and
+// opts.Match), in id order, saving each result with Update. Documents
+// that fail to save or are rejected by the guard are reported in
+// FailedIDs without stopping the run. An error from update, or the
+// context ending, stops the run and returns the partial report with the
+// error; with opts.Checkpoint set the run can then be resumed.
+func (c *Collection[T]) UpdateWhere(ctx context.Context, filters map[string]interface{}, update func(doc T) (T, error), opts *UpdateWhereOptions) (_ *UpdateReport, err error) {
+	timer := c.timeOp(OpUpdate).as("UpdateWhere")
+	defer timer.end(&err)
+	c = c.traced(ctx, timer)
+	if opts == nil {
+		opts = &UpdateWhereOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = 1000
+	}
+
+	fingerprint, err := json.Marshal(filters)
+	if err != nil {
+		return &UpdateReport{}, fmt.Errorf("encode filters: %w", err)
+	}
+	progress := updateCheckpoint{Collection: c.collection, Filters: string(fingerprint)}
+	report := &UpdateReport{}
+	if opts.Checkpoint != "" {
+		saved, found, err := c.loadCheckpoint(opts.Checkpoint)
+		if err != nil {
+			return report, err
+		}
+		if found {
+			if saved.Collection != progress.Collection || saved.Filters != progress.Filters {
+				return report, fmt.Errorf("update where: checkpoint %q belongs to another update", opts.Checkpoint)
+			}
+			progress = saved
+			report.Resumed = true
+		}
+	}
+	fill := func() {
+		report.Matched, report.Updated, report.Skipped = progress.Matched, progress.Updated, progress.Skipped
+		report.FailedIDs, report.DeadLettered = progress.FailedIDs, progress.DeadLettered
+	}
+	fill()
+
+	processed := 0
+	limiter := newAdaptiveLimiter(opts.Concurrency, c.client.now)
+	var batch []updateEntry
+	runBatch := func() error {
+		err := c.updateBatch(ctx, batch, update, opts, &progress, limiter)
+		batch = batch[:0]
+		fill()
+		return err
+	}
+	// fail stops the run with err, once the documents read before it are
+	// updated, as they would have been one at a time
+	fail := func(err error) (*UpdateReport, error) {
+		if batchErr := runBatch(); batchErr != nil {
+			return report, batchErr
+		}
+		return report, err
+	}
+	endBatch := func() error {
+		if err := runBatch(); err != nil {
+			return err
+		}
+		if opts.Checkpoint != "" {
+			if err := c.saveCheckpoint(opts.Checkpoint, progress); err != nil {
+				return err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(UpdateProgress{
+				Matched: progress.Matched,
+				Updated: progress.Updated,
+				Skipped: progress.Skipped,
+				Failed:  len(progress.FailedIDs),
+				LastID:  progress.LastID,
+			})
+		}
+		return nil
+	}
+
+	// Pages continue after the last document read, which is ahead of
+	// progress.LastID while a batch is pending
+	lastRead := progress.LastID
+	for {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+
+		qb := c.model().Query().
+			Sort("id", Asc).
+			Limit(pageSize)
+		for field, value := range filters {
+			qb.Where(field, value)
+		}
+		if lastRead != "" {
+			qb.Filter("id", Gt, lastRead)
+		}
+
+		docs, err := qb.Exec()
+		if err != nil {
+			return fail(err)
+		}
+
+		for _, doc := range docs {
+			id := fmt.Sprintf("%v", doc["id"])
+			lastRead = id
+			match := true
+			if opts.Match != nil {
+				if err := protect("update match", c.collection, id, func() { match = opts.Match(doc) }); err != nil {
+					return fail(err)
+				}
+			}
+			batch = append(batch, updateEntry{doc: doc, id: id, match: match})
+			if !match {
+				continue
+			}
+
+			if processed++; processed%batchSize == 0 {
+				if err := endBatch(); err != nil {
+					return report, err
+				}
+			}
+		}
+
+		if len(docs) < pageSize {
+			break
+		}
+	}
+
+	if processed%batchSize != 0 {
+		if err := endBatch(); err != nil {
+			return report, err
+		}
+	} else if err := runBatch(); err != nil {
+		return report, err
+	}
+	if opts.Checkpoint != "" {
+		if err := c.client.DeleteKey(checkpointKeyPrefix + opts.Checkpoint); err != nil {
+			return report, fmt.Errorf("update where: clearing checkpoint: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// updateEntry is a document read by UpdateWhere, waiting for its batch
+type updateEntry struct {
+	doc   map[string]interface{}
+	id    string
+	match bool // False for documents opts.Match rejected
+}
+
+// updateOutcome is what updating one matching document came to
+type updateOutcome struct {
+	done    bool  // Whether the document was processed
+	skipped bool  // Already applied
+	failed  error // A failed save, reported in FailedIDs
+	stop    error // Stops the run: an error from update or a panic
+}
+
+// updateBatch updates the matching documents of batch with limiter, then
+// records their outcomes in progress in id order. Errors from update and
+// the dead-letter sink stop the run, and are returned; documents not yet
+// started are then left for a resumed run, as are those after a
+// cancellation.
+func (c *Collection[T]) updateBatch(ctx context.Context, batch []updateEntry, update func(T) (T, error), opts *UpdateWhereOptions, progress *updateCheckpoint, limiter *adaptiveLimiter) error {
+	matching := make([]int, 0, len(batch))
+	for i, entry := range batch {
+		if entry.match {
+			matching = append(matching, i)
+		}
+	}
+	outcomes := make([]updateOutcome, len(batch))
+	var stopped atomic.Bool
+	limiter.run(len(matching), func(k int) error {
+		if stopped.Load() || ctx.Err() != nil {
+			return nil
+		}
+		i := matching[k]
+		outcomes[i] = c.updateOne(batch[i], update, opts)
+		if outcomes[i].stop != nil {
+			stopped.Store(true)
+		}
+		return outcomes[i].failed
+	})
+
+	for i, entry := range batch {
+		if !entry.match {
+			progress.LastID = entry.id
+			continue
+		}
+		outcome := outcomes[i]
+		if !outcome.done {
+			// Stopped by an error from a document started earlier
+			for _, later := range outcomes[i:] {
+				if later.stop != nil {
+					progress.Matched++
+					return later.stop
+				}
+			}
+			return ctx.Err()
+		}
+		progress.Matched++
+		switch {
+		case outcome.stop != nil:
+			return outcome.stop
+		case outcome.skipped:
+			progress.Skipped++
+		case outcome.failed == nil:
+			progress.Updated++
+		default:
+			progress.FailedIDs = append(progress.FailedIDs, entry.id)
+			dead := newDeadLetter(c.collection, OpUpdate, entry.doc, entry.id, outcome.failed, c.client.now()).redacted(c.redactor(), c.options.deadLetter)
+			if captured, err := captureDeadLetter(c.options.deadLetter, dead, outcome.failed); captured {
+				progress.DeadLettered++
+			} else if c.options.deadLetter != nil {
+				return err
+			}
+		}
+		progress.LastID = entry.id
+	}
+	return nil
+}
+
+// updateOne updates one matching document
+func (c *Collection[T]) updateOne(entry updateEntry, update func(T) (T, error), opts *UpdateWhereOptions) updateOutcome {
+	if opts.Applied != nil {
+		applied := false
+		if err := protect("update applied", c.collection, entry.id, func() { applied = opts.Applied(entry.doc) }); err != nil {
+			return updateOutcome{done: true, stop: err}
+		}
+		if applied {
+			return updateOutcome{done: true, skipped: true}
+		}
+	}
+
+	model := c.factory()
+	if err := hydrate(entry.doc, &model); err != nil {
+		return updateOutcome{done: true, failed: err}
+	}
+	var updated T
+	var err error
+	if panicked := protect("update", c.collection, entry.id, func() { updated, err = update(model) }); panicked != nil {
+		return updateOutcome{done: true, stop: panicked}
+	}
+	if err != nil {
+		return updateOutcome{done: true, stop: fmt.Errorf("update where: %s: %w", entry.id, err)}
+	}
+	_, err = c.Update(entry.id, updated)
+	return updateOutcome{done: true, failed: err}
+}
+
+// loadCheckpoint reads the checkpoint saved under token
+func (c *Collection[T]) loadCheckpoint(token string) (updateCheckpoint, bool, error) {
+	var saved updateCheckpoint
+	raw, found, err := c.client.GetKey(checkpointKeyPrefix + token)
+	if err != nil || !found {
+		return saved, false, err
+	}
+	if err := json.Unmarshal([]byte(raw), &saved); err != nil {
+		return saved, false, fmt.Errorf("update where: reading checkpoint %q: %w", token, err)
+	}
+	return saved, true, nil
+}
+
+// saveCheckpoint saves progress under token
+func (c *Collection[T]) saveCheckpoint(token string, progress updateCheckpoint) error {
+	raw, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	if err := c.client.SetKey(checkpointKeyPrefix+token, string(raw)); err != nil {
+		return fmt.Errorf("update where: saving checkpoint %q: %w", token, err)
+	}
+	return nil
+}
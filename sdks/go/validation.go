@@ -1,14 +1,19 @@
 package torm
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 )
 
+// ErrValidation is wrapped by every schema validation failure
+var ErrValidation = errors.New("validation error")
+
 // ValidationRule defines validation rules for a field
 type ValidationRule struct {
-	Type      string                 `json:"type,omitempty"` // str, int, float, bool, map, slice
+	Type      string                 `json:"type,omitempty"` // str, int, float, bool, map, slice, decimal
 	Required  bool                   `json:"required,omitempty"`
 	Min       *float64               `json:"min,omitempty"`        // For numbers
 	Max       *float64               `json:"max,omitempty"`        // For numbers
@@ -18,67 +23,168 @@ type ValidationRule struct {
 	Email     bool                   `json:"email,omitempty"`      // Email validation
 	URL       bool                   `json:"url,omitempty"`        // URL validation
 	Validate  func(interface{}) bool `json:"-"`                    // Custom validator
+
+	// Decimal rules, for string-encoded amounts such as money
+	Scale      *int   `json:"scale,omitempty"`       // Max decimal places
+	MinDecimal string `json:"min_decimal,omitempty"` // Inclusive lower bound, e.g. "0.01"
+	MaxDecimal string `json:"max_decimal,omitempty"` // Inclusive upper bound
+	Coerce     bool   `json:"coerce,omitempty"`      // Accept numbers and store the canonical fixed-scale string
+
+	// Sensitive masks the field's value wherever the SDK shows document
+	// content; see WithRedaction. Validation messages never echo values.
+	Sensitive bool `json:"sensitive,omitempty"`
 }
 
-// validateData validates data against schema
-func (m *Model) validateData(data map[string]interface{}, partial bool) error {
+// ValidationProfile selects how thoroughly a write is validated
+type ValidationProfile int
+
+const (
+	ValidationFastFail ValidationProfile = iota // Stop at the first failing field (default)
+	ValidationFull                              // Report every failing field
+	ValidationOff                               // Skip schema validation
+)
+
+// Validate checks data against the model's schema as a create would,
+// without writing it. Fields are checked in name order, so the failure
+// reported by ValidationFastFail is deterministic.
+func (m *Model) Validate(data map[string]interface{}, profile ValidationProfile) error {
+	return m.validateWith(data, false, profile)
+}
+
+// Schema returns a copy of the model's validation rules
+func (m *Model) Schema() map[string]ValidationRule {
+	if m.schema == nil {
+		return nil
+	}
+	schema := make(map[string]ValidationRule, len(m.schema))
 	for field, rules := range m.schema {
-		value, exists := data[field]
+		schema[field] = rules
+	}
+	return schema
+}
 
-		// Required check
-		if rules.Required && !partial && !exists {
-			return fmt.Errorf("validation error: field '%s' is required", field)
-		}
+// validateData validates data against schema, stopping at the first failure
+func (m *Model) validateData(data map[string]interface{}, partial bool) error {
+	return m.validateWith(data, partial, ValidationFastFail)
+}
 
-		// Skip if value doesn't exist and not required
-		if !exists {
-			continue
-		}
+func (m *Model) validateWith(data map[string]interface{}, partial bool, profile ValidationProfile) error {
+	if profile == ValidationOff || m.schema == nil {
+		return nil
+	}
+	err := m.validateSchema(data, partial, profile)
 
-		// Type check
-		if rules.Type != "" {
-			if err := checkType(value, rules.Type); err != nil {
-				return fmt.Errorf("validation error: field '%s' %v", field, err)
+	var panicked *PanicError
+	if errors.As(err, &panicked) {
+		panicked.Collection, panicked.ID = m.collection, docID(data)
+	}
+	return err
+}
+
+func (m *Model) validateSchema(data map[string]interface{}, partial bool, profile ValidationProfile) error {
+	if m.compiled != nil {
+		return m.compiled.validate(data, partial, profile)
+	}
+
+	fields := make([]string, 0, len(m.schema))
+	for field := range m.schema {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var errs []error
+	for _, field := range fields {
+		if err := validateField(field, m.schema[field], data, partial); err != nil {
+			if profile == ValidationFastFail {
+				return err
 			}
+			errs = append(errs, err)
 		}
+	}
+	return joinValidation(errs)
+}
 
-		// String validations
-		if str, ok := value.(string); ok {
-			if rules.MinLength != nil && len(str) < *rules.MinLength {
-				return fmt.Errorf("validation error: field '%s' must be at least %d characters",
-					field, *rules.MinLength)
-			}
-			if rules.MaxLength != nil && len(str) > *rules.MaxLength {
-				return fmt.Errorf("validation error: field '%s' must be at most %d characters",
-					field, *rules.MaxLength)
-			}
-			if rules.Email && !isEmail(str) {
-				return fmt.Errorf("validation error: field '%s' must be a valid email", field)
-			}
-			if rules.URL && !isURL(str) {
-				return fmt.Errorf("validation error: field '%s' must be a valid URL", field)
-			}
-			if rules.Pattern != "" {
-				matched, err := regexp.MatchString(rules.Pattern, str)
-				if err != nil || !matched {
-					return fmt.Errorf("validation error: field '%s' does not match pattern", field)
-				}
-			}
+// joinValidation combines the failures of a full validation; a single
+// failure is returned as is
+func joinValidation(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// validateField checks one field against its rules
+func validateField(field string, rules ValidationRule, data map[string]interface{}, partial bool) error {
+	value, exists := data[field]
+
+	// Required check
+	if rules.Required && !partial && !exists {
+		return fmt.Errorf("%w: field '%s' is required", ErrValidation, field)
+	}
+
+	// Skip if value doesn't exist and not required
+	if !exists {
+		return nil
+	}
+
+	// Type check
+	if rules.Type == "decimal" {
+		normalized, err := rules.checkDecimal(value)
+		if err != nil {
+			return fmt.Errorf("%w: field '%s' %v", ErrValidation, field, err)
+		}
+		if rules.Coerce {
+			data[field] = normalized
+			value = normalized
+		}
+	} else if rules.Type != "" {
+		if err := checkType(value, rules.Type); err != nil {
+			return fmt.Errorf("%w: field '%s' %v", ErrValidation, field, err)
 		}
+	}
 
-		// Number validations
-		if num, ok := toFloat64(value); ok {
-			if rules.Min != nil && num < *rules.Min {
-				return fmt.Errorf("validation error: field '%s' must be at least %v", field, *rules.Min)
-			}
-			if rules.Max != nil && num > *rules.Max {
-				return fmt.Errorf("validation error: field '%s' must be at most %v", field, *rules.Max)
+	// String validations
+	if str, ok := value.(string); ok {
+		if rules.MinLength != nil && len(str) < *rules.MinLength {
+			return fmt.Errorf("%w: field '%s' must be at least %d characters", ErrValidation,
+				field, *rules.MinLength)
+		}
+		if rules.MaxLength != nil && len(str) > *rules.MaxLength {
+			return fmt.Errorf("%w: field '%s' must be at most %d characters", ErrValidation,
+				field, *rules.MaxLength)
+		}
+		if rules.Email && !isEmail(str) {
+			return fmt.Errorf("%w: field '%s' must be a valid email", ErrValidation, field)
+		}
+		if rules.URL && !isURL(str) {
+			return fmt.Errorf("%w: field '%s' must be a valid URL", ErrValidation, field)
+		}
+		if rules.Pattern != "" {
+			matched, err := regexp.MatchString(rules.Pattern, str)
+			if err != nil || !matched {
+				return fmt.Errorf("%w: field '%s' does not match pattern", ErrValidation, field)
 			}
 		}
+	}
 
-		// Custom validation
-		if rules.Validate != nil && !rules.Validate(value) {
-			return fmt.Errorf("validation error: field '%s' failed custom validation", field)
+	// Number validations
+	if num, ok := toFloat64(value); ok {
+		if rules.Min != nil && num < *rules.Min {
+			return fmt.Errorf("%w: field '%s' must be at least %v", ErrValidation, field, *rules.Min)
+		}
+		if rules.Max != nil && num > *rules.Max {
+			return fmt.Errorf("%w: field '%s' must be at most %v", ErrValidation, field, *rules.Max)
+		}
+	}
+
+	// Custom validation
+	if rules.Validate != nil {
+		valid := false
+		if err := protect("validator of "+field, "", "", func() { valid = rules.Validate(value) }); err != nil {
+			return err
+		}
+		if !valid {
+			return fmt.Errorf("%w: field '%s' failed custom validation", ErrValidation, field)
 		}
 	}
 
@@ -122,10 +228,12 @@ func checkType(value interface{}, expectedType string) error {
 	return nil
 }
 
+// emailPattern is the pattern email rules match
+const emailPattern = `^[^\s@]+@[^\s@]+\.[^\s@]+$`
+
 // isEmail checks if string is a valid email
 func isEmail(email string) bool {
-	pattern := `^[^\s@]+@[^\s@]+\.[^\s@]+$`
-	matched, _ := regexp.MatchString(pattern, email)
+	matched, _ := regexp.MatchString(emailPattern, email)
 	return matched
 }
 
@@ -0,0 +1,270 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"regexp/syntax"
+	"sort"
+)
+
+// VetSeverity ranks a Vet finding
+type VetSeverity string
+
+const (
+	VetError   VetSeverity = "error"   // Will misbehave
+	VetWarning VetSeverity = "warning" // Likely to hurt at scale
+	VetInfo    VetSeverity = "info"    // Worth a look
+)
+
+// Vet rule ids, for VetFinding.Rule and VetOptions.Disable
+const (
+	VetInvalidPattern      = "invalid-pattern"      // A schema pattern does not compile
+	VetCatastrophicPattern = "catastrophic-pattern" // A schema pattern nests unbounded repetition
+	VetMissingTimestamps   = "missing-timestamps"   // A schema has no timestamp fields
+	VetUnboundedQuery      = "unbounded-query"      // A named query sets no limit
+	VetUnindexedFilter     = "unindexed-filter"     // A named query filters on a field without an index
+	VetBrokenQuery         = "broken-query"         // A named query preset fails to build
+	VetTypeDrift           = "type-drift"           // Stored values do not match the schema type (live)
+)
+
+// VetFinding is one problem found by Vet
+type VetFinding struct {
+	Severity   VetSeverity
+	Rule       string
+	Collection string
+	Field      string // Empty when the finding is not about a field
+	Query      string // Named query the finding is about, if any
+	Message    string
+}
+
+func (f VetFinding) String() string {
+	location := f.Collection
+	if f.Query != "" {
+		location += " query " + f.Query
+	}
+	if f.Field != "" {
+		location += " field " + f.Field
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", f.Severity, f.Rule, location, f.Message)
+}
+
+// VetOptions configures Vet
+type VetOptions struct {
+	Disable []string // Rule ids to skip
+	// Indexes lists the indexed fields of each collection on the server;
+	// the fields of indexes loaded with LoadIndex and id always count
+	Indexes map[string][]string
+	// TimestampFields are the fields a schema should have (default
+	// "created_at" and "updated_at")
+	TimestampFields []string
+	// Sample enables the live checks: up to Sample documents of every
+	// model with a schema are read and compared with it
+	Sample int
+}
+
+// VetSubject is something Vet inspects: a *Model or a *Collection
+type VetSubject interface {
+	vetSubject() vetSubject
+}
+
+// vetSubject is what Vet can learn about a model or collection
+type vetSubject struct {
+	model   *Model // For schema rules and sampling
+	queries func() ([]string, func(name string) (*QueryBuilder, error))
+	indexed []string
+}
+
+func (m *Model) vetSubject() vetSubject {
+	return vetSubject{model: m}
+}
+
+func (c *Collection[T]) vetSubject() vetSubject {
+	return vetSubject{
+		model:   c.model(),
+		queries: func() ([]string, func(string) (*QueryBuilder, error)) { return c.NamedQueries(), c.NamedQuery },
+		indexed: c.options.indexes.fields(),
+	}
+}
+
+// Vet checks models and collections for patterns that tend to cause
+// trouble in production, such as unbounded named queries or schema
+// patterns prone to catastrophic backtracking in other regex engines. The
+// checks are static unless opts.Sample enables sampling stored data;
+// ctx bounds those reads. Findings are ordered by collection, rule and
+// field.
+func Vet(ctx context.Context, subjects []VetSubject, opts VetOptions) ([]VetFinding, error) {
+	disabled := make(map[string]bool, len(opts.Disable))
+	for _, rule := range opts.Disable {
+		disabled[rule] = true
+	}
+	timestamps := opts.TimestampFields
+	if len(timestamps) == 0 {
+		timestamps = []string{"created_at", "updated_at"}
+	}
+
+	var findings []VetFinding
+	report := func(f VetFinding) {
+		if !disabled[f.Rule] {
+			findings = append(findings, f)
+		}
+	}
+
+	for _, subject := range subjects {
+		s := subject.vetSubject()
+		collection := s.model.collection
+
+		if schema := s.model.schema; schema != nil {
+			vetSchema(collection, schema, timestamps, report)
+			if opts.Sample > 0 && !disabled[VetTypeDrift] {
+				if err := ctx.Err(); err != nil {
+					return findings, err
+				}
+				if err := vetSample(s.model, opts.Sample, report); err != nil {
+					return findings, err
+				}
+			}
+		}
+
+		if s.queries != nil {
+			indexed := map[string]bool{"id": true}
+			for _, field := range append(s.indexed, opts.Indexes[collection]...) {
+				indexed[field] = true
+			}
+			names, build := s.queries()
+			for _, name := range names {
+				vetQuery(collection, name, build, indexed, report)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Collection != b.Collection {
+			return a.Collection < b.Collection
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.Query != b.Query {
+			return a.Query < b.Query
+		}
+		return a.Field < b.Field
+	})
+	return findings, nil
+}
+
+func vetSchema(collection string, schema map[string]ValidationRule, timestamps []string, report func(VetFinding)) {
+	for field, rules := range schema {
+		if rules.Pattern == "" {
+			continue
+		}
+		re, err := syntax.Parse(rules.Pattern, syntax.Perl)
+		if err != nil {
+			report(VetFinding{Severity: VetError, Rule: VetInvalidPattern, Collection: collection, Field: field,
+				Message: fmt.Sprintf("pattern %q does not compile: %v", rules.Pattern, err)})
+			continue
+		}
+		if nestedRepeat(re, false) {
+			report(VetFinding{Severity: VetWarning, Rule: VetCatastrophicPattern, Collection: collection, Field: field,
+				Message: fmt.Sprintf("pattern %q nests unbounded repetition, which backtracking engines can take exponential time on", rules.Pattern)})
+		}
+	}
+
+	missing := make([]string, 0, len(timestamps))
+	for _, field := range timestamps {
+		if _, ok := schema[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		report(VetFinding{Severity: VetInfo, Rule: VetMissingTimestamps, Collection: collection,
+			Message: fmt.Sprintf("schema has no %v fields", missing)})
+	}
+}
+
+// nestedRepeat reports whether an unbounded repetition appears inside
+// another one
+func nestedRepeat(re *syntax.Regexp, inRepeat bool) bool {
+	unbounded := re.Op == syntax.OpStar || re.Op == syntax.OpPlus || (re.Op == syntax.OpRepeat && re.Max == -1)
+	if unbounded && inRepeat {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if nestedRepeat(sub, inRepeat || unbounded) {
+			return true
+		}
+	}
+	return false
+}
+
+func vetQuery(collection, name string, build func(string) (*QueryBuilder, error), indexed map[string]bool, report func(VetFinding)) {
+	qb, err := build(name)
+	if err != nil {
+		report(VetFinding{Severity: VetError, Rule: VetBrokenQuery, Collection: collection, Query: name, Message: err.Error()})
+		return
+	}
+
+	if qb.limitVal == nil {
+		report(VetFinding{Severity: VetWarning, Rule: VetUnboundedQuery, Collection: collection, Query: name,
+			Message: "query sets no limit, so it returns every match"})
+	}
+
+	seen := make(map[string]bool)
+	for _, filter := range qb.filters {
+		if indexed[filter.Field] || seen[filter.Field] {
+			continue
+		}
+		seen[filter.Field] = true
+		report(VetFinding{Severity: VetWarning, Rule: VetUnindexedFilter, Collection: collection, Field: filter.Field, Query: name,
+			Message: "filter on a field without an index scans the collection"})
+	}
+}
+
+// vetSample compares up to n stored documents with the model's schema,
+// reporting each drifting field once
+func vetSample(m *Model, n int, report func(VetFinding)) error {
+	docs, err := m.Query().Limit(n).Exec()
+	if err != nil {
+		return fmt.Errorf("vet %s: sampling failed: %w", m.collection, err)
+	}
+
+	fields := make([]string, 0, len(m.schema))
+	for field := range m.schema {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		rules := m.schema[field]
+		if rules.Type == "" || rules.Type == "decimal" {
+			continue
+		}
+		drifted, example := 0, interface{}(nil)
+		for _, doc := range docs {
+			value, ok := doc[field]
+			if !ok || value == nil {
+				continue
+			}
+			if checkType(jsonType(value, rules.Type), rules.Type) != nil {
+				if drifted == 0 {
+					example = value
+				}
+				drifted++
+			}
+		}
+		if drifted > 0 {
+			report(VetFinding{Severity: VetWarning, Rule: VetTypeDrift, Collection: m.collection, Field: field,
+				Message: fmt.Sprintf("%d of %d sampled documents do not hold a %s, e.g. %#v", drifted, len(docs), rules.Type, example)})
+		}
+	}
+	return nil
+}
+
+// jsonType converts a decoded JSON number to an int when the schema expects
+// one and it is whole, since JSON has a single number type
+func jsonType(value interface{}, typ string) interface{} {
+	if f, ok := value.(float64); ok && typ == "int" && f == float64(int64(f)) {
+		return int64(f)
+	}
+	return value
+}
@@ -0,0 +1,150 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	warmBatchSize   = 100 // Ids fetched per query while warming
+	warmConcurrency = 4   // Queries in flight while warming
+)
+
+// WarmReport lists what a Warm or WarmQuery did with each document
+type WarmReport struct {
+	Warmed  []string
+	Missing []string // Ids the server does not have
+	Failed  []string // Ids whose batch could not be read
+	// Skipped documents were read but not cached, since caching them would
+	// have evicted more than ReadCacheOptions.WarmShare of the cache
+	Skipped []string
+}
+
+// Warm reads the documents with the given ids into the read cache, so the
+// first FindByID of each after a deploy is served from memory. Ids are
+// fetched in batches with bounded concurrency; a failed batch is reported
+// in Failed without stopping the others. Warming needs WithReadCache.
+func (c *Collection[T]) Warm(ctx context.Context, ids []string) (*WarmReport, error) {
+	c = c.prioritizedBy(ctx)
+	rc := c.options.readCache
+	if rc == nil {
+		return nil, fmt.Errorf("warm %s: the collection has no read cache", c.collection)
+	}
+
+	batches := make([][]string, 0, len(ids)/warmBatchSize+1)
+	for start := 0; start < len(ids); start += warmBatchSize {
+		end := start + warmBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batches = append(batches, ids[start:end])
+	}
+
+	found := make([]map[string]map[string]interface{}, len(batches))
+	failed := make([]bool, len(batches))
+	limiter := newAdaptiveLimiter(&AdaptiveConcurrency{Min: warmConcurrency, Max: warmConcurrency}, c.client.now)
+	limiter.run(len(batches), func(i int) error {
+		if err := ctx.Err(); err != nil {
+			failed[i] = true
+			return nil
+		}
+		values := make([]interface{}, len(batches[i]))
+		for j, id := range batches[i] {
+			values[j] = id
+		}
+		docs, err := c.model().Query().Filter("id", In, values).Exec()
+		if err != nil {
+			failed[i] = true
+			return err
+		}
+		found[i] = make(map[string]map[string]interface{}, len(docs))
+		for _, doc := range docs {
+			if id, ok := doc["id"].(string); ok {
+				found[i][id] = doc
+			}
+		}
+		return nil
+	})
+
+	report := &WarmReport{}
+	run := rc.startWarm()
+	for i, batch := range batches {
+		for _, id := range batch {
+			doc, ok := found[i][id]
+			switch {
+			case failed[i]:
+				report.Failed = append(report.Failed, id)
+			case !ok:
+				report.Missing = append(report.Missing, id)
+			case rc.warm(run, id, doc):
+				report.Warmed = append(report.Warmed, id)
+			default:
+				report.Skipped = append(report.Skipped, id)
+			}
+		}
+	}
+	return report, ctx.Err()
+}
+
+// WarmQuery reads the documents qb matches into the read cache
+func (c *Collection[T]) WarmQuery(ctx context.Context, qb *QueryBuilder) (*WarmReport, error) {
+	c = c.prioritizedBy(ctx)
+	rc := c.options.readCache
+	if rc == nil {
+		return nil, fmt.Errorf("warm %s: the collection has no read cache", c.collection)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	docs, err := qb.Exec()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &WarmReport{}
+	run := rc.startWarm()
+	for _, doc := range docs {
+		id, ok := doc["id"].(string)
+		if !ok {
+			continue
+		}
+		if rc.warm(run, id, doc) {
+			report.Warmed = append(report.Warmed, id)
+		} else {
+			report.Skipped = append(report.Skipped, id)
+		}
+	}
+	return report, nil
+}
+
+// AutoWarm warms the documents of the named query (see DefineQuery) now
+// and every interval until ctx is done, so hot documents stay cached past
+// the TTL. Failed runs are retried on the next interval; onWarm, when not
+// nil, is called with the outcome of every run.
+func (c *Collection[T]) AutoWarm(ctx context.Context, query string, interval time.Duration, onWarm func(*WarmReport, error)) {
+	c = c.prioritizedBy(ctx)
+	warm := func() {
+		qb, err := c.NamedQuery(query)
+		var report *WarmReport
+		if err == nil {
+			report, err = c.WarmQuery(ctx, qb)
+		}
+		if onWarm != nil && ctx.Err() == nil {
+			onWarm(report, err)
+		}
+	}
+
+	warm()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			warm()
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package torm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// resumeTimeLayout is fixed-width so tokens order lexically by time
+const resumeTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// ResumeToken marks a position in a collection's change stream. Tokens
+// compare lexically in change order, so they can be persisted as plain
+// strings and passed back through WatchOptions.ResumeAfter.
+type ResumeToken string
+
+// ChangeEvent is a document change observed by a Watcher or ChangedSince
+type ChangeEvent struct {
+	ID       string
+	Document map[string]interface{}
+	Token    ResumeToken
+	Deleted  bool // A soft-delete tombstone, see ChangedSinceOptions.Tombstones
+}
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	Field       string        // Timestamp field ordering changes (default "updatedAt")
+	Interval    time.Duration // Poll interval (default 1s)
+	ResumeAfter ResumeToken   // Replay changes after this token before going live
+}
+
+// Watcher delivers the changes of a collection until its context ends
+type Watcher struct {
+	events chan ChangeEvent
+
+	mu    sync.Mutex
+	token ResumeToken
+	err   error
+}
+
+// Watch polls the collection for documents whose timestamp field moves past
+// the last delivered token. With ResumeAfter set, the first poll catches up
+// on every change since that token. Each change is delivered exactly once
+// per token, including across the catch-up/live boundary.
+func (c *Collection[T]) Watch(ctx context.Context, opts WatchOptions) *Watcher {
+	c = c.prioritizedBy(ctx)
+	if opts.Field == "" {
+		opts.Field = "updatedAt"
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+
+	w := &Watcher{
+		events: make(chan ChangeEvent),
+		token:  opts.ResumeAfter,
+	}
+
+	c.client.trackWatcher(c.collection, 1)
+	go func() {
+		defer close(w.events)
+		defer c.client.trackWatcher(c.collection, -1)
+
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			if !c.pollChanges(ctx, w, opts.Field) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return w
+}
+
+// Events returns the channel of changes; it is closed when the watch ends
+func (w *Watcher) Events() <-chan ChangeEvent {
+	return w.events
+}
+
+// Token returns the token of the last delivered event, for persisting
+func (w *Watcher) Token() ResumeToken {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.token
+}
+
+// Err returns the error of the most recent poll, if it failed. Failed polls
+// are retried on the next interval.
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// pollChanges delivers every change after the watcher's token in token
+// order. It returns false once the context ends.
+func (c *Collection[T]) pollChanges(ctx context.Context, w *Watcher, field string) bool {
+	docs, err := c.model().Query().Sort(field, Asc).Exec()
+
+	w.mu.Lock()
+	w.err = err
+	after := w.token
+	w.mu.Unlock()
+
+	if err != nil {
+		return ctx.Err() == nil
+	}
+
+	changes := make([]ChangeEvent, 0)
+	for _, doc := range docs {
+		token, ok := resumeTokenFor(doc, field)
+		if !ok || token <= after {
+			continue
+		}
+		if c.options.checkGuard(OpFind, doc) != nil {
+			continue
+		}
+		changes = append(changes, ChangeEvent{
+			ID:       fmt.Sprintf("%v", doc["id"]),
+			Document: doc,
+			Token:    token,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Token < changes[j].Token
+	})
+
+	for _, change := range changes {
+		select {
+		case <-ctx.Done():
+			return false
+		case w.events <- change:
+		}
+
+		w.mu.Lock()
+		w.token = change.Token
+		w.mu.Unlock()
+	}
+
+	return ctx.Err() == nil
+}
+
+// resumeTokenFor builds a document's token from its timestamp field and id
+func resumeTokenFor(doc map[string]interface{}, field string) (ResumeToken, bool) {
+	at, ok := documentTime(doc, field)
+	if !ok {
+		return "", false
+	}
+
+	return ResumeToken(at.UTC().Format(resumeTimeLayout) + "/" + fmt.Sprintf("%v", doc["id"])), true
+}
+
+// documentTime reads a timestamp field stored as an RFC 3339 string or as
+// Unix milliseconds
+func documentTime(doc map[string]interface{}, field string) (time.Time, bool) {
+	switch v := doc[field].(type) {
+	case string:
+		at, err := time.Parse(time.RFC3339Nano, v)
+		return at, err == nil
+	case float64:
+		return time.UnixMilli(int64(v)), true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,93 @@
+package torm
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrWriteProtected is returned for a write to a write-protected client
+// that was not confirmed with the client's token. See
+// ClientOptions.WriteConfirmation.
+var ErrWriteProtected = errors.New("torm: client is write-protected")
+
+// confirmHeader carries a confirmation token from Confirm to the request
+// path. It is removed before the request is sent.
+const confirmHeader = "X-Torm-Confirm"
+
+// WriteProtected reports whether writes need a confirmation token
+func (c *Client) WriteProtected() bool {
+	return c.writeConfirmation != ""
+}
+
+// Confirm returns a copy of the collection whose writes carry token, as a
+// write-protected client requires. The copy shares everything else with c,
+// so confirm each mutating call separately:
+//
+//	users.Confirm(token).Delete(id)
+func (c *Collection[T]) Confirm(token string) *Collection[T] {
+	confirmed := *c
+	confirmed.confirm = token
+	return &confirmed
+}
+
+// Confirm returns a copy of the model whose writes carry token, as a
+// write-protected client requires
+func (m *Model) Confirm(token string) *Model {
+	confirmed := *m
+	confirmed.confirm = token
+	return &confirmed
+}
+
+// call sends a request of the collection, confirmed as set by Confirm
+func (c *Collection[T]) call(method, path string, body interface{}) (*bufferedResponse, error) {
+	return c.callWithHeader(method, path, body, nil)
+}
+
+// callWithHeader is call with extra request headers
+func (c *Collection[T]) callWithHeader(method, path string, body interface{}, header http.Header) (*bufferedResponse, error) {
+	resp, err := c.client.callWithHeader(method, path, body, c.trace.headers(prioritized(confirmed(header, c.confirm), c.priority)))
+	if resp != nil {
+		c.trace.sent(resp.statusCode)
+	}
+	return resp, err
+}
+
+// request sends a request of the model, confirmed as set by Confirm
+func (m *Model) request(method, path string, body interface{}) (*http.Response, error) {
+	resp, err := m.client.requestJSON(method, path, body, m.trace.headers(prioritized(confirmed(nil, m.confirm), m.priority)))
+	if resp != nil {
+		m.trace.sent(resp.StatusCode)
+	}
+	return resp, err
+}
+
+// confirmed adds a confirmation token to header, if there is one
+func confirmed(header http.Header, token string) http.Header {
+	if token == "" {
+		return header
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(confirmHeader, token)
+	return header
+}
+
+// checkWrite refuses unconfirmed writes of a write-protected client and
+// strips the confirmation token from req
+func (c *Client) checkWrite(req *http.Request) error {
+	token := req.Header.Get(confirmHeader)
+	req.Header.Del(confirmHeader)
+	if c.writeConfirmation == "" || requestClass(req) != RequestWrite {
+		return nil
+	}
+	if token == "" {
+		return fmt.Errorf("%w: %s %s needs a confirmation token", ErrWriteProtected, req.Method, req.URL.Path)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.writeConfirmation)) != 1 {
+		return fmt.Errorf("%w: wrong confirmation token for %s %s", ErrWriteProtected, req.Method, req.URL.Path)
+	}
+	return nil
+}